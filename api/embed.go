@@ -0,0 +1,23 @@
+// Package api embeds the OpenAPI description of the HTTP API, the Swagger UI page
+// that renders it and the API changelog; see controller.GetOpenAPISpec and
+// controller.GetChangelog.
+package api
+
+import _ "embed"
+
+// Spec is the OpenAPI 3 description of the user API, maintained by hand next to the
+// routes in internal/handler
+//
+//go:embed openapi.yaml
+var Spec []byte
+
+// SwaggerUI is a page rendering the spec next to it with Swagger UI
+//
+//go:embed swagger.html
+var SwaggerUI []byte
+
+// Changelog lists the changes to the API per version, maintained by hand next to
+// the spec; see internal/changelog
+//
+//go:embed changelog.yaml
+var Changelog []byte
@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"cruder/api"
+	"cruder/internal/controller"
+	"cruder/internal/handler"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+type spec struct {
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	// Paths maps each path to its operations, and "parameters" to the parameters
+	// they share
+	Paths map[string]map[string]any `yaml:"paths"`
+}
+
+// pathParam matches gin's :param segments
+var pathParam = regexp.MustCompile(`:([a-z_]+)`)
+
+func TestSpecCoversRoutes(t *testing.T) {
+	// Given: The spec and the API routes with every optional feature mounted
+	var doc spec
+	if err := yaml.Unmarshal(api.Spec, &doc); err != nil {
+		t.Fatalf("spec is not valid YAML: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	router := handler.New(gin.New(), &controller.Controller{
+		Auth:           &controller.AuthController{},
+		PersonalTokens: &controller.PersonalTokenController{},
+		Webhooks:       &controller.WebhookController{},
+	}, handler.Options{})
+
+	// When: Comparing the operations of both
+	documented := make(map[string]bool)
+	operationIDs := make(map[string]string)
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			if method == "parameters" {
+				continue
+			}
+			if responses, _ := op.(map[string]any)["responses"].(map[string]any); len(responses) == 0 {
+				t.Errorf("%s %s documents no responses", strings.ToUpper(method), path)
+			}
+			// The client SDKs name their methods after the operation IDs
+			id, _ := op.(map[string]any)["operationId"].(string)
+			if id == "" {
+				t.Errorf("%s %s has no operationId", strings.ToUpper(method), path)
+			} else if other, ok := operationIDs[id]; ok {
+				t.Errorf("%s %s reuses the operationId of %s", strings.ToUpper(method), path, other)
+			}
+			operationIDs[id] = strings.ToUpper(method) + " " + path
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	var missing []string
+	for _, route := range router.Routes() {
+		path, ok := strings.CutPrefix(route.Path, "/api/v1")
+		if !ok {
+			continue
+		}
+		operation := route.Method + " " + pathParam.ReplaceAllString(path, "{$1}")
+		if !documented[operation] {
+			missing = append(missing, operation)
+		}
+		delete(documented, operation)
+	}
+
+	// Then: Every route is documented and every documented operation exists
+	sort.Strings(missing)
+	for _, operation := range missing {
+		t.Errorf("route %s is not in openapi.yaml", operation)
+	}
+	for operation := range documented {
+		t.Errorf("openapi.yaml documents %s, which is not routed", operation)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "../api/v1" {
+		t.Errorf("expected the server URL to be relative to /swagger, got %+v", doc.Servers)
+	}
+}
+
+func TestSwaggerRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := handler.New(gin.New(), &controller.Controller{}, handler.Options{BasePath: "/user-service"})
+
+	for path, contentType := range map[string]string{
+		"/user-service/swagger/index.html":   "text/html; charset=utf-8",
+		"/user-service/swagger/openapi.yaml": "application/yaml; charset=utf-8",
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != contentType {
+			t.Errorf("GET %s: expected 200 %s, got %d %s", path, contentType, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,1126 @@
+// Code generated by "cruder sdk generate" from api/openapi.yaml; DO NOT EDIT.
+//
+// Client of the cruder user service API, version 1. Pass the root URL of the service,
+// including server.base_path, and an API key or a bearer token:
+//
+//	const client = new Client({ baseUrl: "https://users.example.com", apiKey: "..." });
+//	const users = await client.listUsers({ email_domain: "example.com" });
+
+export interface ClientOptions {
+  /** Root URL of the service, including server.base_path, e.g. https://users.example.com */
+  baseUrl: string;
+  /** Sent as X-API-Key */
+  apiKey?: string;
+  /** A JWT or personal token, sent as Authorization: Bearer */
+  token?: string;
+  /** Headers sent with every request */
+  headers?: Record<string, string>;
+  /** Used instead of the global fetch, e.g. to add retries */
+  fetch?: typeof fetch;
+}
+
+/** An error answer of the API; branch on code, as messages may change. */
+export class ApiError extends globalThis.Error {
+  constructor(
+    readonly status: number,
+    readonly code: string,
+    message: string,
+    readonly details?: unknown,
+    /** The X-Request-ID of the response, which also appears in the server logs */
+    readonly requestId?: string,
+  ) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+
+type ParamValue = string | number | boolean | undefined;
+
+interface ApiRequest {
+  method: string;
+  path: string;
+  query?: Record<string, ParamValue>;
+  headers?: Record<string, ParamValue>;
+  json?: unknown;
+  form?: object;
+  binary?: boolean;
+}
+
+export interface Error {
+  code: string;
+  message: string;
+  /**
+   * The failed field checks (FieldError items) for invalid_user; a
+   * `reason` object for forbidden_by_policy and read_only
+   */
+  details?: FieldError[] | Record<string, unknown>;
+}
+
+export interface FieldError {
+  field?: string;
+  code?: "required" | "invalid_format" | "taken" | "reserved" | "domain_not_allowed" | "invalid";
+  message?: string;
+}
+
+export interface UserSuggestion {
+  uuid: string;
+  username: string;
+  full_name: string;
+}
+
+export interface SearchResult {
+  type: "user";
+  /** The entity's identifier, the UUID for users */
+  id: string;
+  /** 1 for an exact match of a name, 0.75 for a prefix, 0.5 for the start of a word, 0.25 otherwise */
+  score: number;
+  /** The entity, shaped by its type */
+  item: User;
+}
+
+export interface User {
+  id?: number;
+  uuid?: string;
+  username?: string;
+  email?: string;
+  full_name?: string;
+  custom_fields?: Record<string, unknown>;
+  /** Data residency region the user is stored in; omitted for users created without residency */
+  region?: string;
+  created_at?: string;
+  /** When the user last changed, soft deletion and restoring included; created_at until then */
+  updated_at?: string;
+  /** Counts the user's changes, starting at 1; also sent as the ETag */
+  version?: number;
+  deleted_at?: string;
+}
+
+export interface UserInput {
+  username: string;
+  email: string;
+  full_name?: string;
+  custom_fields?: Record<string, unknown>;
+}
+
+export interface UserPatch {
+  username?: string;
+  email?: string;
+  full_name?: string;
+  /** Values to set; null removes a field */
+  custom_fields?: Record<string, unknown>;
+  /** The version the update was made against, if not sent in If-Match */
+  version?: number;
+}
+
+export interface DeletedUserPage {
+  items?: DeletedUserPageItem[];
+  page?: number;
+  per_page?: number;
+  total?: number;
+}
+
+export interface DeletedUserPageItem extends User {
+  purge_at?: string;
+  days_remaining?: number;
+}
+
+export interface ValidationResult {
+  valid?: boolean;
+  errors?: FieldError[];
+}
+
+export interface BulkResult {
+  succeeded?: number;
+  failed?: number;
+  results?: BulkResultResult[];
+}
+
+export interface BulkResultResult {
+  index?: number;
+  /** Set by bulk delete */
+  uuid?: string;
+  status?: number;
+  user?: User;
+  code?: string;
+  error?: string;
+  errors?: FieldError[];
+}
+
+export interface ImportReport {
+  rows?: number;
+  created?: number;
+  failed?: number;
+  failures?: ImportReportFailure[];
+}
+
+export interface ImportReportFailure {
+  line?: number;
+  status?: number;
+  code?: string;
+  error?: string;
+  errors?: FieldError[];
+}
+
+export interface UserQuery {
+  custom_fields?: Record<string, string>;
+  email_domain?: string;
+  full_name?: string;
+  created_from?: string;
+  created_to?: string;
+  updated_from?: string;
+  updated_to?: string;
+  q?: string;
+  sort?: string;
+}
+
+export interface SavedView {
+  name?: string;
+  description?: string;
+  query?: UserQuery;
+  created_by?: string;
+  created_at?: string;
+}
+
+export interface ScheduledDeletion {
+  user_uuid?: string;
+  effective_at?: string;
+  requested_by?: string;
+  created_at?: string;
+}
+
+export interface PendingChange {
+  id?: number;
+  kind?: "delete_user" | "update_user";
+  user_uuid?: string;
+  payload?: UserInput;
+  status?: "pending" | "approved" | "rejected";
+  requested_by?: string;
+  reviewed_by?: string;
+  created_at?: string;
+  reviewed_at?: string;
+}
+
+export interface Webhook {
+  id?: string;
+  url?: string;
+  events?: WebhookEvent[];
+  description?: string;
+  /** Only returned when the webhook is created */
+  secret?: string;
+  active?: boolean;
+  created_by?: string;
+  created_at?: string;
+  updated_at?: string;
+}
+
+export interface WebhookInput {
+  url: string;
+  events: WebhookEvent[];
+  description?: string;
+  active?: boolean;
+}
+
+export type WebhookEvent = "user.created" | "user.updated" | "user.deleted" | "user.restored";
+
+export interface WebhookDelivery {
+  id?: number;
+  webhook_id?: string;
+  event_id?: string;
+  event_type?: WebhookEvent;
+  status?: "pending" | "delivered" | "failed";
+  attempts?: number;
+  next_attempt_at?: string;
+  /** HTTP status of the last attempt */
+  last_status?: number;
+  last_error?: string;
+  delivered_at?: string;
+  failed_at?: string;
+  created_at?: string;
+}
+
+export interface Note {
+  id?: number;
+  user_uuid?: string;
+  author?: string;
+  body?: string;
+  created_at?: string;
+  updated_at?: string;
+}
+
+export interface NoteInput {
+  body: string;
+}
+
+export interface Document {
+  id?: string;
+  user_uuid?: string;
+  filename?: string;
+  content_type?: string;
+  size_bytes?: number;
+  sha256?: string;
+  uploaded_by?: string;
+  created_at?: string;
+}
+
+export interface Export {
+  id?: string;
+  status?: "pending" | "running" | "completed";
+  requested_by?: string;
+  chunks?: number;
+  rows_written?: number;
+  size_bytes?: number;
+  sha256?: string;
+  created_at?: string;
+  updated_at?: string;
+  completed_at?: string;
+}
+
+export interface Consent {
+  id?: number;
+  user_uuid?: string;
+  document?: string;
+  version?: string;
+  ip_address?: string;
+  accepted_at?: string;
+}
+
+export interface PendingConsentPage {
+  document?: string;
+  version?: string;
+  items?: PendingConsentPageItem[];
+  page?: number;
+  per_page?: number;
+  total?: number;
+}
+
+export interface PendingConsentPageItem extends User {
+  accepted_version?: string | null;
+  accepted_at?: string;
+}
+
+export interface Token {
+  access_token?: string;
+  token_type?: string;
+  expires_in?: number;
+  expires_at?: string;
+}
+
+export interface PersonalToken {
+  id?: string;
+  name?: string;
+  prefix?: string;
+  /** Only returned when the token is created */
+  token?: string;
+  scopes?: string[];
+  tenant?: string;
+  expires_at?: string;
+  last_used_at?: string;
+  created_at?: string;
+}
+
+export interface Changelog {
+  /** The build of the server, as reported by /version */
+  server_version?: string;
+  versions?: ChangelogVersion[];
+}
+
+export interface ChangelogVersion {
+  version?: string;
+  /** Absent for unreleased */
+  date?: string;
+  changes?: ChangelogVersionChange[];
+}
+
+export interface ChangelogVersionChange {
+  type?: "added" | "changed" | "deprecated" | "removed" | "fixed" | "security";
+  area?: string;
+  summary?: string;
+  /** Existing clients must be updated */
+  breaking?: boolean;
+}
+
+export interface AggregateUsersResponse {
+  group_by?: string;
+  buckets?: AggregateUsersResponseBucket[];
+}
+
+export interface AggregateUsersResponseBucket {
+  key?: string | null;
+  count?: number;
+}
+
+export interface CreateViewRequest {
+  name: string;
+  description?: string;
+  query?: UserQuery;
+}
+
+export interface Message {
+  message?: string;
+}
+
+export interface BulkCreateUsersRequest {
+  users: UserInput[];
+}
+
+export interface BulkDeleteUsersRequest {
+  uuids: string[];
+}
+
+export interface ImportUsersRequest {
+  file: Blob;
+  /** Defaults to the file extension (.csv, .jsonl or .ndjson) */
+  format?: "csv" | "jsonl";
+}
+
+export interface ScheduleDeletionRequest {
+  effective_at: string;
+}
+
+export interface RecordConsentRequest {
+  document: string;
+  version: string;
+}
+
+export interface UploadDocumentRequest {
+  file: Blob;
+}
+
+export interface LoginRequest {
+  username: string;
+  password: string;
+}
+
+export interface CreatePersonalTokenRequest {
+  name: string;
+  scopes?: string[];
+  expires_at?: string;
+}
+
+/** Query and header parameters of listUsers */
+export interface ListUsersParams {
+  /** Users whose email is at this domain, ignoring case */
+  email_domain?: string;
+  /** Users whose full name contains the value, ignoring case */
+  full_name?: string;
+  /** Users created at or after this time; an RFC 3339 timestamp or a date (midnight UTC) */
+  created_from?: string;
+  /** Users created before this time; an RFC 3339 timestamp or a date (midnight UTC) */
+  created_to?: string;
+  /** Users last changed at or after this time; an RFC 3339 timestamp or a date (midnight UTC) */
+  updated_from?: string;
+  /** Users last changed before this time; an RFC 3339 timestamp or a date (midnight UTC) */
+  updated_to?: string;
+  /** Users whose username, email or full name contains the value, ignoring case */
+  q?: string;
+  /** Comma-separated fields, each optionally prefixed with `-` for descending order; id, username, email, full_name, created_at or updated_at */
+  sort?: string;
+  /** Also list soft-deleted users; needs the admin scope */
+  include_deleted?: boolean;
+}
+
+/** Query and header parameters of createUser */
+export interface CreateUserParams {
+  /**
+   * Create a new account even when a deleted user has the username. Without it
+   * the create fails with 409 deleted_user_exists, naming the deleted user so
+   * it can be restored instead.
+   */
+  replace_deleted?: boolean;
+  /** Unique per request, e.g. a UUID; at most 255 characters */
+  idempotency_key?: string;
+}
+
+/** Query and header parameters of aggregateUsers */
+export interface AggregateUsersParams {
+  /** `created_month` or `cf.<name>` for a custom field. Users have no status or tags, so `status` and `tag` are rejected with `invalid_group_by`; define them as custom fields and group by `cf.status` or `cf.tag`. */
+  group_by: string;
+}
+
+/** Query and header parameters of sampleUsers */
+export interface SampleUsersParams {
+  n?: number;
+}
+
+/** Query and header parameters of searchUsers */
+export interface SearchUsersParams {
+  q: string;
+  limit?: number;
+  consistency_token?: string;
+}
+
+/** Query and header parameters of autocompleteUsers */
+export interface AutocompleteUsersParams {
+  prefix: string;
+  limit?: number;
+}
+
+/** Query and header parameters of listDeletedUsers */
+export interface ListDeletedUsersParams {
+  page?: number;
+  per_page?: number;
+}
+
+/** Query and header parameters of listPendingConsents */
+export interface ListPendingConsentsParams {
+  document: string;
+  page?: number;
+  per_page?: number;
+}
+
+/** Query and header parameters of validateUser */
+export interface ValidateUserParams {
+  /**
+   * Create a new account even when a deleted user has the username. Without it
+   * the create fails with 409 deleted_user_exists, naming the deleted user so
+   * it can be restored instead.
+   */
+  replace_deleted?: boolean;
+}
+
+/** Query and header parameters of bulkCreateUsers */
+export interface BulkCreateUsersParams {
+  /**
+   * Create a new account even when a deleted user has the username. Without it
+   * the create fails with 409 deleted_user_exists, naming the deleted user so
+   * it can be restored instead.
+   */
+  replace_deleted?: boolean;
+}
+
+/** Query and header parameters of bulkDeleteUsers */
+export interface BulkDeleteUsersParams {
+  mode?: "all_or_nothing" | "partial";
+}
+
+/** Query and header parameters of importUsers */
+export interface ImportUsersParams {
+  /**
+   * Create a new account even when a deleted user has the username. Without it
+   * the create fails with 409 deleted_user_exists, naming the deleted user so
+   * it can be restored instead.
+   */
+  replace_deleted?: boolean;
+}
+
+/** Query and header parameters of updateUser */
+export interface UpdateUserParams {
+  /** The ETag of the user the update was made against, e.g. "3" */
+  if_match?: string;
+}
+
+/** Query and header parameters of search */
+export interface SearchParams {
+  q: string;
+  /** Comma-separated entity types to search, all by default */
+  types?: string;
+  limit?: number;
+}
+
+/** Query and header parameters of listChanges */
+export interface ListChangesParams {
+  status?: "pending" | "approved" | "rejected";
+}
+
+/** Query and header parameters of getChangelog */
+export interface GetChangelogParams {
+  /** Only versions after this one, e.g. the version a client was built against */
+  from?: string;
+  /** Only versions up to and including this one, e.g. the server's */
+  to?: string;
+}
+
+/** Calls the API; every method throws an ApiError when the server answers with an error. */
+export class Client {
+  private readonly baseUrl: string;
+  private readonly fetch: typeof fetch;
+
+  constructor(private readonly options: ClientOptions) {
+    this.baseUrl = options.baseUrl.replace(/\/+$/, "") + "/api/v1";
+    this.fetch = options.fetch ?? globalThis.fetch.bind(globalThis);
+  }
+
+  private async request<T>(req: ApiRequest): Promise<T> {
+    const url = new URL(this.baseUrl + req.path);
+    for (const [name, value] of Object.entries(req.query ?? {})) {
+      if (value !== undefined) url.searchParams.set(name, String(value));
+    }
+
+    const headers: Record<string, string> = { ...this.options.headers };
+    if (!req.binary) headers["Accept"] = "application/json";
+    if (this.options.apiKey) headers["X-API-Key"] = this.options.apiKey;
+    if (this.options.token) headers["Authorization"] = `Bearer ${this.options.token}`;
+    for (const [name, value] of Object.entries(req.headers ?? {})) {
+      if (value !== undefined) headers[name] = String(value);
+    }
+
+    let body: string | FormData | undefined;
+    if (req.json !== undefined) {
+      headers["Content-Type"] = "application/json";
+      body = JSON.stringify(req.json);
+    } else if (req.form !== undefined) {
+      const form = new FormData();
+      for (const [name, value] of Object.entries(req.form)) {
+        if (value !== undefined) form.append(name, value instanceof Blob ? value : String(value));
+      }
+      body = form;
+    }
+
+    const res = await this.fetch(url, { method: req.method, headers, body });
+    if (!res.ok) {
+      const err = await res.json().catch(() => ({}));
+      const requestId = res.headers.get("X-Request-ID") ?? undefined;
+      throw new ApiError(res.status, err.code ?? "unknown", err.message ?? res.statusText, err.details, requestId);
+    }
+    if (req.binary) return (await res.blob()) as T;
+    const text = await res.text();
+    return (text ? JSON.parse(text) : undefined) as T;
+  }
+
+  /**
+   * List users
+   *
+   * Filter by custom fields with `cf.<name>=<value>` query parameters; every
+   * filter must match. Fields hidden from the caller by the field policy are
+   * omitted from the users and cannot be filtered on.
+   */
+  listUsers(params: ListUsersParams = {}): Promise<User[]> {
+    return this.request({
+      method: "GET",
+      path: "/users/",
+      query: {
+        email_domain: params.email_domain,
+        full_name: params.full_name,
+        created_from: params.created_from,
+        created_to: params.created_to,
+        updated_from: params.updated_from,
+        updated_to: params.updated_to,
+        q: params.q,
+        sort: params.sort,
+        include_deleted: params.include_deleted,
+      },
+    });
+  }
+
+  /**
+   * Create a user
+   *
+   * Send an `Idempotency-Key` to retry safely after a network failure: for 24
+   * hours, a retry of the same request with the same key gets the first
+   * response, marked with `Idempotent-Replayed: true`, instead of creating the
+   * user again or failing with 409. Server errors are not kept, so a retry
+   * after one runs the request again.
+   *
+   * A username of a deleted user is answered with 409 `deleted_user_exists`,
+   * whose `details` hold the `uuid` and `deleted_at` of that user, so the
+   * client can offer `POST /users/{uuid}/restore`; see `replace_deleted`.
+   */
+  createUser(body: UserInput, params: CreateUserParams = {}): Promise<User> {
+    return this.request({
+      method: "POST",
+      path: "/users/",
+      query: { replace_deleted: params.replace_deleted },
+      headers: { "Idempotency-Key": params.idempotency_key },
+      json: body,
+    });
+  }
+
+  /** Get a user by username */
+  getUserByUsername(username: string): Promise<User> {
+    return this.request({
+      method: "GET",
+      path: `/users/username/${encodeURIComponent(String(username))}`,
+    });
+  }
+
+  /** Get a user by ID */
+  getUserById(id: number): Promise<User> {
+    return this.request({ method: "GET", path: `/users/id/${encodeURIComponent(String(id))}` });
+  }
+
+  /** Count users per value of a field */
+  aggregateUsers(params: AggregateUsersParams): Promise<AggregateUsersResponse> {
+    return this.request({
+      method: "GET",
+      path: "/users/aggregate",
+      query: { group_by: params.group_by },
+    });
+  }
+
+  /** Pick users at random */
+  sampleUsers(params: SampleUsersParams = {}): Promise<User[]> {
+    return this.request({ method: "GET", path: "/users/sample", query: { n: params.n } });
+  }
+
+  /**
+   * Search users by username, email or full name
+   *
+   * Matching ignores case, accents and repeated spaces; exact and prefix username
+   * matches come first. Send the `X-Consistency-Token` of an earlier write to see
+   * its effect.
+   */
+  searchUsers(params: SearchUsersParams): Promise<User[]> {
+    return this.request({
+      method: "GET",
+      path: "/users/search",
+      query: { q: params.q, limit: params.limit },
+      headers: { "X-Consistency-Token": params.consistency_token },
+    });
+  }
+
+  /**
+   * Suggest users whose username or full name starts with a prefix
+   *
+   * For picker widgets. The prefix is normalized like a search term; username
+   * matches come first. Answers may be up to `search.autocomplete_cache_ttl` old.
+   */
+  autocompleteUsers(params: AutocompleteUsersParams): Promise<UserSuggestion[]> {
+    return this.request({
+      method: "GET",
+      path: "/users/autocomplete",
+      query: { prefix: params.prefix, limit: params.limit },
+    });
+  }
+
+  /** List soft-deleted users (admin) */
+  listDeletedUsers(params: ListDeletedUsersParams = {}): Promise<DeletedUserPage> {
+    return this.request({
+      method: "GET",
+      path: "/users/deleted",
+      query: { page: params.page, per_page: params.per_page },
+    });
+  }
+
+  /** Start a CSV export of all users (admin) */
+  startExport(): Promise<Export> {
+    return this.request({ method: "POST", path: "/users/exports" });
+  }
+
+  /** Get the progress of an export (admin) */
+  getExport(id: string): Promise<Export> {
+    return this.request({
+      method: "GET",
+      path: `/users/exports/${encodeURIComponent(String(id))}`,
+    });
+  }
+
+  /**
+   * Download a completed export (admin)
+   *
+   * Honours `Range` and `If-Range`, so interrupted downloads can resume.
+   */
+  downloadExport(id: string): Promise<Blob> {
+    return this.request({
+      method: "GET",
+      path: `/users/exports/${encodeURIComponent(String(id))}/download`,
+      binary: true,
+    });
+  }
+
+  /** List users who have not accepted the current version of a document (admin) */
+  listPendingConsents(params: ListPendingConsentsParams): Promise<PendingConsentPage> {
+    return this.request({
+      method: "GET",
+      path: "/users/consents/pending",
+      query: { document: params.document, page: params.page, per_page: params.per_page },
+    });
+  }
+
+  /** List saved views */
+  listViews(): Promise<SavedView[]> {
+    return this.request({ method: "GET", path: "/users/views" });
+  }
+
+  /** Save a user query under a name */
+  createView(body: CreateViewRequest): Promise<SavedView> {
+    return this.request({ method: "POST", path: "/users/views", json: body });
+  }
+
+  /** Run a saved view */
+  runView(name: string): Promise<User[]> {
+    return this.request({
+      method: "GET",
+      path: `/users/views/${encodeURIComponent(String(name))}`,
+    });
+  }
+
+  /** Delete a saved view */
+  deleteView(name: string): Promise<Message> {
+    return this.request({
+      method: "DELETE",
+      path: `/users/views/${encodeURIComponent(String(name))}`,
+    });
+  }
+
+  /**
+   * Check a user without creating it
+   *
+   * Reports every failed check, including format problems, with 200. A username
+   * of a deleted user is reported with code `deleted`.
+   */
+  validateUser(body: UserInput, params: ValidateUserParams = {}): Promise<ValidationResult> {
+    return this.request({
+      method: "POST",
+      path: "/users/validate",
+      query: { replace_deleted: params.replace_deleted },
+      json: body,
+    });
+  }
+
+  /**
+   * Create up to 500 users
+   *
+   * Each item succeeds or fails on its own; the result of each has the status it
+   * would have got as a request of its own. An item failing with
+   * `deleted_user_exists` carries the deleted user's `uuid`.
+   */
+  bulkCreateUsers(body: BulkCreateUsersRequest, params: BulkCreateUsersParams = {}): Promise<BulkResult> {
+    return this.request({
+      method: "POST",
+      path: "/users/bulk",
+      query: { replace_deleted: params.replace_deleted },
+      json: body,
+    });
+  }
+
+  /**
+   * Soft-delete up to 500 users
+   *
+   * Deletes the users in one transaction. In all_or_nothing mode, the default, a
+   * UUID that is not found keeps every user and the others report 409
+   * bulk_rolled_back; in partial mode the others are deleted. Refused with 409
+   * approval_required while approvals are enabled.
+   */
+  bulkDeleteUsers(body: BulkDeleteUsersRequest, params: BulkDeleteUsersParams = {}): Promise<BulkResult> {
+    return this.request({
+      method: "DELETE",
+      path: "/users/bulk",
+      query: { mode: params.mode },
+      json: body,
+    });
+  }
+
+  /**
+   * Create users from a CSV or JSON Lines file (admin scope)
+   *
+   * A CSV file starts with a header naming its columns: username and email,
+   * optionally full_name (id and uuid are ignored). A JSON Lines file holds one
+   * user object per line. Rows are created 500 at a time; a bad row fails on its
+   * own and is reported with its line number and the status a create request
+   * would have got.
+   */
+  importUsers(body: ImportUsersRequest, params: ImportUsersParams = {}): Promise<ImportReport> {
+    return this.request({
+      method: "POST",
+      path: "/users/import",
+      query: { replace_deleted: params.replace_deleted },
+      form: body,
+    });
+  }
+
+  /**
+   * Update a user
+   *
+   * Updates only the fields present in the body; custom fields are merged into the
+   * stored ones and a null value removes one. With approvals enabled, an email
+   * change is held for a second admin and answered with 202.
+   *
+   * The update must name the version of the user it was made against, in
+   * `If-Match` as returned in `ETag`, or as `version` in the body; `If-Match: *`
+   * updates any version. A user changed since is answered with 412, so the
+   * client can read it again instead of overwriting the other change.
+   */
+  updateUser(uuid: string, body: UserPatch, params: UpdateUserParams = {}): Promise<Message | PendingChange> {
+    return this.request({
+      method: "PATCH",
+      path: `/users/${encodeURIComponent(String(uuid))}`,
+      headers: { "If-Match": params.if_match },
+      json: body,
+    });
+  }
+
+  /**
+   * Soft-delete a user
+   *
+   * With approvals enabled, the deletion is held for a second admin and answered with 202.
+   */
+  deleteUser(uuid: string): Promise<PendingChange | undefined> {
+    return this.request({ method: "DELETE", path: `/users/${encodeURIComponent(String(uuid))}` });
+  }
+
+  /** Restore a soft-deleted user (admin) */
+  restoreUser(uuid: string): Promise<User> {
+    return this.request({
+      method: "POST",
+      path: `/users/${encodeURIComponent(String(uuid))}/restore`,
+    });
+  }
+
+  /** Get the scheduled deletion of a user */
+  getScheduledDeletion(uuid: string): Promise<ScheduledDeletion> {
+    return this.request({
+      method: "GET",
+      path: `/users/${encodeURIComponent(String(uuid))}/schedule-delete`,
+    });
+  }
+
+  /** Schedule the deletion of a user */
+  scheduleDeletion(uuid: string, body: ScheduleDeletionRequest): Promise<ScheduledDeletion> {
+    return this.request({
+      method: "POST",
+      path: `/users/${encodeURIComponent(String(uuid))}/schedule-delete`,
+      json: body,
+    });
+  }
+
+  /** Cancel the scheduled deletion of a user */
+  cancelScheduledDeletion(uuid: string): Promise<Message> {
+    return this.request({
+      method: "DELETE",
+      path: `/users/${encodeURIComponent(String(uuid))}/schedule-delete`,
+    });
+  }
+
+  /** List the documents a user accepted, newest first */
+  listConsents(uuid: string): Promise<Consent[]> {
+    return this.request({
+      method: "GET",
+      path: `/users/${encodeURIComponent(String(uuid))}/consents`,
+    });
+  }
+
+  /** Record that a user accepted the current version of a document */
+  recordConsent(uuid: string, body: RecordConsentRequest): Promise<Consent> {
+    return this.request({
+      method: "POST",
+      path: `/users/${encodeURIComponent(String(uuid))}/consents`,
+      json: body,
+    });
+  }
+
+  /** List internal notes on a user, oldest first (admin) */
+  listNotes(uuid: string): Promise<Note[]> {
+    return this.request({
+      method: "GET",
+      path: `/users/${encodeURIComponent(String(uuid))}/notes`,
+    });
+  }
+
+  /** Add an internal note (admin) */
+  createNote(uuid: string, body: NoteInput): Promise<Note> {
+    return this.request({
+      method: "POST",
+      path: `/users/${encodeURIComponent(String(uuid))}/notes`,
+      json: body,
+    });
+  }
+
+  /** Replace the body of a note (admin) */
+  updateNote(uuid: string, noteId: number, body: NoteInput): Promise<Note> {
+    return this.request({
+      method: "PATCH",
+      path: `/users/${encodeURIComponent(String(uuid))}/notes/${encodeURIComponent(String(noteId))}`,
+      json: body,
+    });
+  }
+
+  /** Delete a note (admin) */
+  deleteNote(uuid: string, noteId: number): Promise<Message> {
+    return this.request({
+      method: "DELETE",
+      path: `/users/${encodeURIComponent(String(uuid))}/notes/${encodeURIComponent(String(noteId))}`,
+    });
+  }
+
+  /** List the files attached to a user (documents scope) */
+  listDocuments(uuid: string): Promise<Document[]> {
+    return this.request({
+      method: "GET",
+      path: `/users/${encodeURIComponent(String(uuid))}/documents`,
+    });
+  }
+
+  /** Attach a file to a user (documents scope) */
+  uploadDocument(uuid: string, body: UploadDocumentRequest): Promise<Document> {
+    return this.request({
+      method: "POST",
+      path: `/users/${encodeURIComponent(String(uuid))}/documents`,
+      form: body,
+    });
+  }
+
+  /** Download a document (documents scope) */
+  downloadDocument(uuid: string, documentId: string): Promise<Blob> {
+    return this.request({
+      method: "GET",
+      path: `/users/${encodeURIComponent(String(uuid))}/documents/${encodeURIComponent(String(documentId))}`,
+      binary: true,
+    });
+  }
+
+  /** Delete a document (documents scope) */
+  deleteDocument(uuid: string, documentId: string): Promise<Message> {
+    return this.request({
+      method: "DELETE",
+      path: `/users/${encodeURIComponent(String(uuid))}/documents/${encodeURIComponent(String(documentId))}`,
+    });
+  }
+
+  /**
+   * Search every entity type (admin)
+   *
+   * Looks for `q` in every searchable entity type, currently users, and merges
+   * the matches by score. Matching ignores case, accents and repeated spaces.
+   * Each result names its `type`; `item` is the entity as its own endpoints
+   * return it, with fields hidden by the field policy omitted.
+   */
+  search(params: SearchParams): Promise<SearchResult[]> {
+    return this.request({
+      method: "GET",
+      path: "/search",
+      query: { q: params.q, types: params.types, limit: params.limit },
+    });
+  }
+
+  /** List changes held for approval (admin) */
+  listChanges(params: ListChangesParams = {}): Promise<PendingChange[]> {
+    return this.request({ method: "GET", path: "/approvals", query: { status: params.status } });
+  }
+
+  /** Get a change held for approval (admin) */
+  getChange(id: number): Promise<PendingChange> {
+    return this.request({ method: "GET", path: `/approvals/${encodeURIComponent(String(id))}` });
+  }
+
+  /**
+   * Approve and apply a change (admin)
+   *
+   * The admin who requested a change cannot approve it.
+   */
+  approveChange(id: number): Promise<PendingChange> {
+    return this.request({
+      method: "POST",
+      path: `/approvals/${encodeURIComponent(String(id))}/approve`,
+    });
+  }
+
+  /** Reject a change (admin) */
+  rejectChange(id: number): Promise<PendingChange> {
+    return this.request({
+      method: "POST",
+      path: `/approvals/${encodeURIComponent(String(id))}/reject`,
+    });
+  }
+
+  /**
+   * List webhooks (admin)
+   *
+   * Only available when webhooks are enabled.
+   */
+  listWebhooks(): Promise<Webhook[]> {
+    return this.request({ method: "GET", path: "/webhooks" });
+  }
+
+  /**
+   * Register a webhook (admin)
+   *
+   * Deliveries of the chosen events are signed with the webhook's secret, which
+   * is only returned here.
+   */
+  createWebhook(body: WebhookInput): Promise<Webhook> {
+    return this.request({ method: "POST", path: "/webhooks", json: body });
+  }
+
+  /** Get a webhook (admin) */
+  getWebhook(id: string): Promise<Webhook> {
+    return this.request({ method: "GET", path: `/webhooks/${encodeURIComponent(String(id))}` });
+  }
+
+  /**
+   * Replace the settings of a webhook (admin)
+   *
+   * The secret stays the same.
+   */
+  updateWebhook(id: string, body: WebhookInput): Promise<Webhook> {
+    return this.request({
+      method: "PUT",
+      path: `/webhooks/${encodeURIComponent(String(id))}`,
+      json: body,
+    });
+  }
+
+  /** Delete a webhook and its deliveries (admin) */
+  deleteWebhook(id: string): Promise<void> {
+    return this.request({ method: "DELETE", path: `/webhooks/${encodeURIComponent(String(id))}` });
+  }
+
+  /** List the latest deliveries of a webhook (admin) */
+  listWebhookDeliveries(id: string): Promise<WebhookDelivery[]> {
+    return this.request({
+      method: "GET",
+      path: `/webhooks/${encodeURIComponent(String(id))}/deliveries`,
+    });
+  }
+
+  /**
+   * Exchange a username and password for a JWT
+   *
+   * Only available when auth.jwt has accounts and a signing key.
+   */
+  login(body: LoginRequest): Promise<Token> {
+    return this.request({ method: "POST", path: "/auth/login", json: body });
+  }
+
+  /**
+   * List your personal tokens
+   *
+   * Needs a JWT; only available when auth.personal_tokens is enabled.
+   */
+  listPersonalTokens(): Promise<PersonalToken[]> {
+    return this.request({ method: "GET", path: "/me/tokens" });
+  }
+
+  /**
+   * Create a personal token
+   *
+   * Needs a JWT. The token can only hold scopes you hold, and its secret is only returned here.
+   */
+  createPersonalToken(body: CreatePersonalTokenRequest): Promise<PersonalToken> {
+    return this.request({ method: "POST", path: "/me/tokens", json: body });
+  }
+
+  /** Revoke a personal token */
+  revokePersonalToken(id: string): Promise<void> {
+    return this.request({ method: "DELETE", path: `/me/tokens/${encodeURIComponent(String(id))}` });
+  }
+
+  /**
+   * List the changes to the API per server version
+   *
+   * Maintained in api/changelog.yaml and embedded at build time. Versions are
+   * listed newest first; `unreleased` holds changes not yet part of a release
+   * and is newer than every release. A suffix such as the `-3-g4f1c2e9` of a
+   * build past a tag is ignored when comparing, so the server_version of
+   * /version can be passed as a bound.
+   */
+  getChangelog(params: GetChangelogParams = {}): Promise<Changelog> {
+    return this.request({
+      method: "GET",
+      path: "/changelog",
+      query: { from: params.from, to: params.to },
+    });
+  }
+
+  /**
+   * Download this spec as a Postman collection
+   *
+   * A Postman collection (v2.1, which Insomnia imports as well) with a request
+   * per operation, grouped by tag and filled with example bodies. It uses the
+   * collection variables `baseUrl`, pointing at this server, `apiKey`, sent as
+   * `X-API-Key`, and `token`, sent as a bearer token to the routes that need a
+   * JWT; fill in `apiKey` or `token` after importing it.
+   */
+  getPostmanCollection(): Promise<Record<string, unknown>> {
+    return this.request({ method: "GET", path: "/openapi/postman" });
+  }
+}
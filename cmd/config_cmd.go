@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cruder/internal/config"
+	"cruder/internal/tuning"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `Usage: cruder config <command> [flags]
+
+Commands:
+  validate            Load the configuration (file + environment) and report problems
+  print [--redacted]  Print the effective configuration; secrets are redacted by default
+  encrypt             Read a value from stdin and print it encrypted for config.yaml
+  genkey              Print a new random master key for CRUDER_MASTER_KEY
+  hash-password       Read a password from stdin and print its bcrypt hash for auth.jwt.accounts
+
+Flags:
+  --config PATH       Configuration file (default config.yaml)
+`
+
+// runConfigCommand implements the "config" subcommands and returns the exit code
+func runConfigCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, configUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", configPath, "configuration file")
+	redacted := fs.Bool("redacted", true, "redact secret values")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	switch args[0] {
+	case "validate":
+		return configValidate(*path, stdout, stderr)
+	case "print":
+		return configPrint(*path, *redacted, stdout, stderr)
+	case "encrypt":
+		return configEncrypt(stdin, stdout, stderr)
+	case "genkey":
+		key, err := config.GenerateMasterKey()
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to generate key: %v\n", err)
+			return 1
+		}
+		_, _ = fmt.Fprintln(stdout, key)
+		return 0
+	case "hash-password":
+		return configHashPassword(stdin, stdout, stderr)
+	default:
+		_, _ = fmt.Fprintf(stderr, "unknown config command %q\n\n%s", args[0], configUsage)
+		return 2
+	}
+}
+
+func configValidate(path string, stdout, stderr io.Writer) int {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "configuration invalid: %v\n", err)
+		return 1
+	}
+
+	var problems []string
+	if err := cfg.Validate(); err != nil {
+		problems = append(problems, strings.Split(err.Error(), "\n")...)
+	}
+	if cfg.Runtime.MemoryLimit != "" {
+		if _, err := tuning.ParseByteSize(cfg.Runtime.MemoryLimit); err != nil {
+			problems = append(problems, "runtime.memory_limit: "+err.Error())
+		}
+	}
+	if _, err := cfg.DSN(); err != nil {
+		problems = append(problems, "database credentials: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		for _, p := range problems {
+			_, _ = fmt.Fprintf(stderr, "  - %s\n", p)
+		}
+		_, _ = fmt.Fprintln(stderr, "configuration invalid")
+		return 1
+	}
+
+	_, _ = fmt.Fprintln(stdout, "configuration valid")
+	return 0
+}
+
+func configPrint(path string, redacted bool, stdout, stderr io.Writer) int {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	// Explain where the database connection comes from
+	_, _ = fmt.Fprintf(stdout, "# config file: %s\n", path)
+	dsn, err := cfg.DSN()
+	switch {
+	case err != nil:
+		_, _ = fmt.Fprintf(stdout, "# database dsn: unavailable (%v)\n", err)
+	case os.Getenv("POSTGRES_DSN") != "":
+		_, _ = fmt.Fprintf(stdout, "# database dsn (from POSTGRES_DSN, file settings ignored): %s\n", printableDSN(dsn, redacted))
+	default:
+		_, _ = fmt.Fprintf(stdout, "# database dsn (from config file + DB_* environment): %s\n", printableDSN(dsn, redacted))
+	}
+	for _, env := range []string{"DB_HOST", "DB_PORT", "DB_NAME", "DB_SSLMODE", "SERVER_ADDRESS", "ADMIN_ADDRESS", "BASE_PATH", "PORT"} {
+		if v, ok := os.LookupEnv(env); ok {
+			_, _ = fmt.Fprintf(stdout, "# override: %s=%s\n", env, v)
+		}
+	}
+
+	out := cfg
+	if redacted {
+		out = cfg.Redacted()
+	}
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(out); err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to encode configuration: %v\n", err)
+		return 1
+	}
+	_ = enc.Close()
+	return 0
+}
+
+func configEncrypt(stdin io.Reader, stdout, stderr io.Writer) int {
+	key, err := config.EnvKeyProvider{}.MasterKey()
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+
+	value, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		_, _ = fmt.Fprintf(stderr, "failed to read value: %v\n", err)
+		return 1
+	}
+	value = strings.TrimRight(value, "\r\n")
+	if value == "" {
+		_, _ = fmt.Fprintln(stderr, "nothing to encrypt: pass the value on stdin")
+		return 1
+	}
+
+	encrypted, err := config.EncryptValue(key, value)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to encrypt: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintln(stdout, encrypted)
+	return 0
+}
+
+func configHashPassword(stdin io.Reader, stdout, stderr io.Writer) int {
+	password, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		_, _ = fmt.Fprintf(stderr, "failed to read password: %v\n", err)
+		return 1
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		_, _ = fmt.Fprintln(stderr, "nothing to hash: pass the password on stdin")
+		return 1
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to hash password: %v\n", err)
+		return 1
+	}
+	_, _ = fmt.Fprintln(stdout, string(hash))
+	return 0
+}
+
+func printableDSN(dsn string, redacted bool) string {
+	if redacted {
+		return config.RedactDSN(dsn)
+	}
+	return dsn
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"cruder/internal/datamigrations"
+	"cruder/internal/repository"
+)
+
+const dataMigrateUsage = `Usage: cruder data-migrate <command> [flags]
+
+Commands:
+  status              List the data migrations and their progress
+  run NAME...         Run the named migrations, resuming where an earlier run stopped
+
+Flags:
+  --config PATH       Configuration file (default config.yaml)
+  --batch-size N      Rows per batch and transaction (default 1000)
+  --pause DURATION    Wait between batches, e.g. 100ms, to spare the database
+
+The schema migrations must be applied first. With data residency enabled, every
+region's database is migrated too. Stopping a run (Ctrl+C) is safe.
+`
+
+// runDataMigrateCommand implements the "data-migrate" subcommands and returns the
+// exit code
+func runDataMigrateCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		_, _ = fmt.Fprint(stderr, dataMigrateUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("data-migrate "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", configPath, "configuration file")
+	batchSize := fs.Int("batch-size", datamigrations.DefaultBatchSize, "rows per batch")
+	pause := fs.Duration("pause", 0, "wait between batches")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if args[0] != "status" && args[0] != "run" {
+		_, _ = fmt.Fprintf(stderr, "unknown data-migrate command %q\n\n%s", args[0], dataMigrateUsage)
+		return 2
+	}
+	if args[0] == "run" && fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(stderr, "nothing to run: name the migrations, see \"cruder data-migrate status\"")
+		return 2
+	}
+
+	dbs, err := dataMigrateDatabases(*path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	defer func() {
+		for _, db := range dbs {
+			_ = db.conn.Close()
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for _, db := range dbs {
+		runner := datamigrations.NewRunner(db.conn, datamigrations.All, *batchSize, *pause)
+		if args[0] == "status" {
+			if err := printDataMigrations(ctx, runner, db.name, stdout); err != nil {
+				_, _ = fmt.Fprintf(stderr, "failed to read the data migrations of the %s database: %v\n", db.name, err)
+				return 1
+			}
+			continue
+		}
+		for _, name := range fs.Args() {
+			if err := runner.Run(ctx, name); err != nil {
+				_, _ = fmt.Fprintf(stderr, "failed in the %s database: %v\n", db.name, err)
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+type namedDB struct {
+	name string
+	conn *sql.DB
+}
+
+// dataMigrateDatabases connects to the home database and to those of the data
+// residency regions
+func dataMigrateDatabases(path string) ([]namedDB, error) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	dsn, err := cfg.DSN()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load database configuration: %w", err)
+	}
+	home, err := repository.NewPostgresConnection(dsn, connectRetry(cfg))
+	if err != nil {
+		return nil, err
+	}
+	dbs := []namedDB{{name: "home", conn: home.DB()}}
+	if cfg.Residency.Enabled {
+		for name := range cfg.Residency.Regions {
+			regionDSN, err := cfg.RegionDSN(name)
+			if err != nil {
+				return dbs, fmt.Errorf("failed to load residency configuration: %w", err)
+			}
+			conn, err := repository.NewPostgresConnection(regionDSN, connectRetry(cfg))
+			if err != nil {
+				return dbs, fmt.Errorf("failed to connect to the database of region %s: %w", name, err)
+			}
+			dbs = append(dbs, namedDB{name: "region " + name, conn: conn.DB()})
+		}
+	}
+	return dbs, nil
+}
+
+func printDataMigrations(ctx context.Context, runner *datamigrations.Runner, database string, stdout io.Writer) error {
+	statuses, err := runner.Statuses(ctx)
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(stdout, "# %s database\n", database)
+	w := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSTATE\tSEEN\tCHANGED\tUPDATED\tDESCRIPTION")
+	for _, s := range statuses {
+		state, updated := "pending", "-"
+		switch {
+		case s.CompletedAt != nil:
+			state = "completed"
+		case s.StartedAt != nil:
+			state = "in progress"
+		}
+		if s.UpdatedAt != nil {
+			updated = s.UpdatedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%s\n", s.Name, state, s.RowsSeen, s.RowsChanged, updated, s.Description)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/doctor"
+	"cruder/internal/ratelimit"
+	"cruder/internal/repository"
+)
+
+// runDoctorCommand implements "cruder doctor": it checks the configuration and
+// every dependency it names, prints a readiness report and returns 1 when a check
+// failed
+func runDoctorCommand(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	path := fs.String("config", configPath, "configuration file")
+	timeout := fs.Duration("timeout", 5*time.Second, "time allowed for each check")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := loadConfig(*path)
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	results := doctor.Run(ctx, []doctor.Check{{Name: "configuration", Run: func(context.Context) (doctor.Status, string) {
+		if err := cfg.Validate(); err != nil {
+			return doctor.Fail, strings.ReplaceAll(err.Error(), "\n", "; ")
+		}
+		return doctor.OK, *path + " is valid"
+	}}}, *timeout)
+
+	for _, target := range doctorDatabases(cfg) {
+		if target.err != nil {
+			results = append(results, doctor.Result{Name: target.name, Status: doctor.Fail, Detail: target.err.Error()})
+			continue
+		}
+		db, result := doctorConnect(ctx, target.name, target.dsn, *timeout)
+		results = append(results, result)
+		if db == nil {
+			continue
+		}
+		checks := []doctor.Check{{Name: target.name + ": server", Run: doctor.Server(db)}}
+		if target.schema {
+			checks = append(checks,
+				doctor.Check{Name: target.name + ": permissions", Run: doctor.Permissions(db)},
+				doctor.Check{Name: target.name + ": pg_trgm", Run: doctor.Extension(db, "pg_trgm")},
+				doctor.Check{Name: target.name + ": gen_random_uuid", Run: doctor.UUIDFunction(db)},
+				doctor.Check{Name: target.name + ": migrations", Run: doctor.Migrations(db)},
+			)
+		}
+		if target.name == "database" {
+			checks = append(checks, doctor.Check{Name: "clock", Run: doctor.ClockSkew(db)})
+			if cfg.CDC.Enabled {
+				checks = append(checks, doctor.Check{Name: "change data capture", Run: func(ctx context.Context) (doctor.Status, string) {
+					problems, err := repository.CheckCDC(ctx, db, cfg.CDC.Publication)
+					if err != nil {
+						return doctor.Failed(err)
+					}
+					if len(problems) > 0 {
+						return doctor.Warn, strings.Join(problems, "; ")
+					}
+					return doctor.OK, "ready for logical replication"
+				}})
+			}
+		}
+		results = append(results, doctor.Run(ctx, checks, *timeout)...)
+		_ = db.Close()
+	}
+
+	if cfg.RateLimit.Enabled && cfg.RateLimit.Backend == "redis" {
+		results = append(results, doctor.Run(ctx, []doctor.Check{{Name: "redis", Run: func(ctx context.Context) (doctor.Status, string) {
+			url, err := cfg.RateLimitRedisURL()
+			if err != nil {
+				return doctor.Failed(err)
+			}
+			store, err := ratelimit.NewRedisStore(ctx, url)
+			if err != nil {
+				return doctor.Failed(err)
+			}
+			_ = store.Close()
+			return doctor.OK, "rate limit backend reachable"
+		}}}, *timeout)...)
+	}
+
+	doctor.Report(stdout, results)
+	if !doctor.Ready(results) {
+		return 1
+	}
+	return 0
+}
+
+// doctorDatabase is a database the service connects to; schema is set for those
+// the migrations are applied to
+type doctorDatabase struct {
+	name   string
+	dsn    string
+	err    error
+	schema bool
+}
+
+func doctorDatabases(cfg *config.Config) []doctorDatabase {
+	dsn, err := cfg.DSN()
+	targets := []doctorDatabase{{name: "database", dsn: dsn, err: err, schema: true}}
+	if cfg.Residency.Enabled {
+		for name := range cfg.Residency.Regions {
+			dsn, err := cfg.RegionDSN(name)
+			targets = append(targets, doctorDatabase{name: "region " + name, dsn: dsn, err: err, schema: true})
+		}
+	}
+	if cfg.DualWrite.Enabled {
+		dsn, err := cfg.DualWriteDSN()
+		targets = append(targets, doctorDatabase{name: "dual-write secondary", dsn: dsn, err: err})
+	}
+	return targets
+}
+
+// doctorConnect opens dsn and pings it; the database is nil when that failed
+func doctorConnect(ctx context.Context, name, dsn string, timeout time.Duration) (*sql.DB, doctor.Result) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, doctor.Result{Name: name, Status: doctor.Fail, Detail: err.Error()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, doctor.Result{Name: name, Status: doctor.Fail, Detail: "cannot connect to " + config.RedactDSN(dsn) + ": " + err.Error()}
+	}
+	return db, doctor.Result{Name: name, Status: doctor.OK, Detail: "connected to " + config.RedactDSN(dsn)}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"cruder/internal/config"
+	"cruder/internal/service"
+)
+
+// validationHooks returns the business rules run before users are created or
+// updated. Deployments register their own hooks here instead of changing the user
+// service, for example:
+//
+//	service.ValidationHookFunc(func(user, existing *model.User) ([]model.FieldError, error) {
+//		if !strings.HasSuffix(user.Email, "@corp.example.com") {
+//			return []model.FieldError{{Field: "email", Code: "corporate_email", Message: "use your corporate email address"}}, nil
+//		}
+//		return nil, nil
+//	}),
+func validationHooks(cfg *config.Config) []service.ValidationHook {
+	return nil
+}
@@ -1,45 +1,629 @@
 package main
 
 import (
+	"context"
+	"cruder/internal/analytics"
+	"cruder/internal/anomaly"
+	"cruder/internal/auth"
 	"cruder/internal/config"
 	"cruder/internal/controller"
+	"cruder/internal/events"
+	"cruder/internal/flags"
+	"cruder/internal/grpcapi"
 	"cruder/internal/handler"
+	"cruder/internal/httpclient"
+	"cruder/internal/invalidation"
+	"cruder/internal/jobs"
+	"cruder/internal/logging"
+	"cruder/internal/middleware"
+	"cruder/internal/migrations"
+	"cruder/internal/mirror"
+	"cruder/internal/outbox"
+	"cruder/internal/plugin"
+	"cruder/internal/policy"
+	"cruder/internal/ratelimit"
 	"cruder/internal/repository"
+	"cruder/internal/reputation"
+	"cruder/internal/server"
 	"cruder/internal/service"
+	"cruder/internal/storage"
+	"cruder/internal/tracing"
+	"cruder/internal/tuning"
+	"cruder/internal/version"
+	"cruder/internal/webhook"
+	"database/sql"
+	"errors"
+	"flag"
 	"log"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const configPath = "config.yaml"
+
 func main() {
+	// Operator subcommands, e.g. "cruder config validate"
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "data-migrate" {
+		os.Exit(runDataMigrateCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctorCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "sdk" {
+		os.Exit(runSDKCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	migrateOnly := flag.Bool("migrate-only", false, "apply pending database migrations and exit")
+	migrateDown := flag.Bool("migrate-down", false, "roll back the latest database migration and exit")
+	flag.Parse()
+
+	log.Println(version.Get().Banner())
+
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %v", err)
+	}
+	logLevel, err := logging.Setup(cfg.Logging, os.Stderr)
+	if err != nil {
+		log.Fatalf("failed to set up logging: %v", err)
+	}
+
+	// Apply GOMAXPROCS/GOGC/GOMEMLIMIT before anything else allocates
+	settings, err := tuning.Apply(cfg.Runtime)
+	if err != nil {
+		log.Fatalf("failed to apply runtime settings: %v", err)
+	}
+	tuning.Log(settings)
+
+	// Spans are exported in the background; the shutdown below flushes the last batch
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatalf("failed to set up tracing: %v", err)
+	}
+	if cfg.Tracing.Enabled {
+		log.Printf("exporting traces of %.0f%% of requests to %s", cfg.Tracing.SampleRatio*100, cfg.Tracing.Endpoint)
+	}
+
 	// Load database configuration
 	// Supports backward compatibility: uses POSTGRES_DSN if set,
 	// otherwise builds DSN from config.yaml + environment variables
-	dsn, err := config.GetDSN("config.yaml")
+	dsn, err := cfg.DSN()
 	if err != nil {
 		log.Fatalf("failed to load database configuration: %v", err)
 	}
 
-	// Load API key from environment variable
-	apiKey := os.Getenv("X_API_KEY")
-	if apiKey == "" {
-		// Default API key for development/testing
-		apiKey = "dev-api-key-12345"
-		log.Println("Warning: Using default API key. Set X_API_KEY environment variable for production.")
+	// Load API keys from X_API_KEY and X_API_KEYS; with managed keys they are only
+	// needed to create the first ones
+	envKeys, err := cfg.EnvAPIKeys()
+	if err != nil {
+		log.Fatalf("invalid API keys: %v", err)
+	}
+	if len(envKeys) == 0 && len(cfg.Auth.APIKeys) == 0 && !cfg.Auth.ManagedKeys.Enabled && !cfg.Auth.JWT.Enabled {
+		// Default API key for development/testing, only when no credential is
+		// configured at all; it is public, so it is never an admin
+		envKeys = []config.APIKeyConfig{{Name: "default", Key: "dev-api-key-12345"}}
+		log.Println("WARNING: no API keys, managed keys or JWT authentication configured; accepting the public development key dev-api-key-12345 without the admin scope. Set X_API_KEY, X_API_KEYS or auth.api_keys for production.")
 	}
 
-	dbConn, err := repository.NewPostgresConnection(dsn)
+	dbConn, err := repository.NewPostgresConnection(dsn, connectRetry(cfg))
 	if err != nil {
 		log.Fatalf("failed to connect to database: %v", err)
 	}
 
+	// Each residency region keeps its users in its own database, with the same schema
+	regionDBs := map[string]*sql.DB{}
+	if cfg.Residency.Enabled {
+		for name := range cfg.Residency.Regions {
+			regionDSN, err := cfg.RegionDSN(name)
+			if err != nil {
+				log.Fatalf("failed to load residency configuration: %v", err)
+			}
+			conn, err := repository.NewPostgresConnection(regionDSN, connectRetry(cfg))
+			if err != nil {
+				log.Fatalf("failed to connect to the database of region %s: %v", name, err)
+			}
+			regionDBs[name] = conn.DB()
+		}
+	}
+
+	// Migrations run before anything reads the schema
+	switch {
+	case *migrateDown:
+		if err := migrations.Down(context.Background(), dbConn.DB()); err != nil {
+			log.Fatalf("failed to roll back migration: %v", err)
+		}
+		for name, db := range regionDBs {
+			if err := migrations.Down(context.Background(), db); err != nil {
+				log.Fatalf("failed to roll back migration in region %s: %v", name, err)
+			}
+		}
+		return
+	case *migrateOnly || cfg.Database.AutoMigrate:
+		if err := migrations.Up(context.Background(), dbConn.DB()); err != nil {
+			log.Fatalf("failed to apply migrations: %v", err)
+		}
+		for name, db := range regionDBs {
+			if err := migrations.Up(context.Background(), db); err != nil {
+				log.Fatalf("failed to apply migrations in region %s: %v", name, err)
+			}
+		}
+		if *migrateOnly {
+			return
+		}
+	}
+
+	if cfg.CDC.Enabled {
+		// Misconfiguration degrades the change feed but not the API, so only warn
+		problems, err := repository.CheckCDC(context.Background(), dbConn.DB(), cfg.CDC.Publication)
+		if err != nil {
+			log.Printf("Warning: failed to check change data capture settings: %v", err)
+		}
+		for _, problem := range problems {
+			log.Printf("Warning: change data capture: %s", problem)
+		}
+	}
+
 	repositories := repository.NewRepository(dbConn.DB())
-	services := service.NewService(repositories)
-	controllers := controller.NewController(services)
-	r := gin.Default()
-	handler.New(r, controllers.Users, apiKey)
-	if err := r.Run(); err != nil {
-		log.Fatalf("failed to run server: %v", err)
+	if cfg.DualWrite.Enabled {
+		secondaryDSN, err := cfg.DualWriteDSN()
+		if err != nil {
+			log.Fatalf("failed to load dual-write configuration: %v", err)
+		}
+		secondary, err := repository.NewPostgresConnection(secondaryDSN, connectRetry(cfg))
+		if err != nil {
+			log.Fatalf("failed to connect to dual-write secondary database: %v", err)
+		}
+		repositories.WithDualWrite(repository.NewDualWriter(dbConn.DB(), secondary.DB(), cfg.DualWrite.Timeout))
+		log.Println("dual-write enabled: user changes are copied to the secondary database")
+	}
+	// regions stays nil without residency, and jobs then run once on the home database
+	var regions *repository.Regions
+	if cfg.Residency.Enabled {
+		regions = repository.NewRegions(cfg.Residency.HomeRegion, dbConn.DB(), regionDBs)
+		repositories.WithRegions(regions)
+		log.Printf("data residency enabled: users are kept in regions %v", regions.Names())
+	}
+	// User changes are recorded in each region's outbox by triggers, in the
+	// transaction that makes them, and relayed to the message bus from there.
+	// Without the bus nothing relays them, so the triggers are turned off.
+	var publisher outbox.Publisher
+	if cfg.MessageBus.Enabled {
+		if err := regions.Each(context.Background(), repositories.Outbox.Capture); err != nil {
+			log.Fatalf("failed to enable the user outbox: %v", err)
+		}
+		busURL, err := cfg.MessageBusURL()
+		if err != nil {
+			log.Fatalf("failed to configure message bus: %v", err)
+		}
+		// The relay publishes a failed batch again, so the client does not retry
+		busClient := cfg.HTTPClient
+		busClient.Timeout = cfg.MessageBus.Timeout
+		busClient.MaxRetries = 0
+		publisher, err = outbox.NewPublisher(cfg.MessageBus, busURL, httpclient.New("message-bus", busClient))
+		if err != nil {
+			log.Fatalf("failed to create message bus publisher: %v", err)
+		}
+		log.Printf("publishing user changes to %s topic %s", cfg.MessageBus.Driver, cfg.MessageBus.Topic)
+	} else if err := regions.Each(context.Background(), repositories.Outbox.Release); err != nil {
+		log.Fatalf("failed to disable the user outbox: %v", err)
+	}
+	store, err := storage.NewLocal(cfg.Storage.LocalPath)
+	if err != nil {
+		log.Fatalf("failed to open storage: %v", err)
+	}
+	// Plugins register before the services are built so their hooks and subscribers apply
+	bus := events.NewBus()
+	plugins, err := plugin.Load(cfg.Plugins, bus)
+	if err != nil {
+		log.Fatalf("failed to load plugins: %v", err)
+	}
+	if names := plugins.Names(); len(names) > 0 {
+		log.Printf("loaded plugins: %v", names)
+	}
+	hooks := append(validationHooks(cfg), plugins.ValidationHooks()...)
+	caches := invalidation.NewBus()
+	if cfg.Cache.Broadcast {
+		if err := caches.Listen(dbConn.DB(), dsn); err != nil {
+			log.Fatalf("failed to listen for cache invalidations: %v", err)
+		}
+	}
+	services := service.NewService(repositories, cfg, store, caches, service.WithValidationHooks(hooks...), service.WithEvents(bus))
+	// JWT bearer tokens are accepted next to API keys; accounts in config.yaml log in for them
+	var tokens *auth.Tokens
+	if cfg.Auth.JWT.Enabled {
+		tokens, err = auth.NewTokens(cfg.Auth.JWT, cfg.JWTSecret())
+		if err != nil {
+			log.Fatalf("failed to load JWT keys: %v", err)
+		}
+		// Rotated keys live in the database, their private halves sealed with the master key
+		if cfg.Auth.JWT.Rotation.Enabled {
+			masterKey, err := config.EnvKeyProvider{}.MasterKey()
+			if err != nil {
+				log.Fatalf("signing key rotation needs the master key: %v", err)
+			}
+			keyRing := auth.NewKeyRing(repositories.SigningKeys, masterKey, cfg.Auth.JWT.TTL, cfg.Auth.JWT.Rotation.CheckInterval)
+			caches.Subscribe(invalidation.SigningKeys, keyRing.Invalidate)
+			tokens.WithKeyRing(keyRing)
+			services.SigningKeys = service.NewSigningKeyService(repositories.SigningKeys, tokens, masterKey, cfg.Auth.JWT, caches)
+			// A fresh deployment needs a key before the first login
+			if key, err := services.SigningKeys.Rotate(context.Background()); err != nil {
+				log.Fatalf("failed to rotate signing keys: %v", err)
+			} else if key != nil {
+				log.Printf("created signing key %s (%s)", key.ID, key.Status)
+			}
+		}
+		if tokens.CanIssue() && len(cfg.Auth.JWT.Accounts) > 0 {
+			services.Auth = service.NewAuthService(cfg.Auth.JWT.Accounts, tokens)
+		}
+		log.Printf("accepting %s bearer tokens (API keys accepted: %t)", cfg.Auth.JWT.Algorithm, !cfg.Auth.JWT.Required)
+	}
+	controllers := controller.NewController(services, cfg)
+	// The search read table follows user events; rebuilds repair anything the bus lost
+	bus.Subscribe(events.AllEvents, services.UserSearch.Consume)
+	// Sibling services drop the users they cache when told of a change
+	if cfg.Cache.NotifyUserChanges {
+		bus.Subscribe(events.AllEvents, events.NotifyUserChanges(dbConn.DB()))
+	}
+	// Webhooks get user events through a queue in the database, so deliveries survive restarts
+	if cfg.Webhooks.Enabled {
+		bus.Subscribe(events.AllEvents, webhook.Enqueue(repositories.Webhooks))
+	}
+
+	// Background jobs stop before the process exits
+	var jobLocker jobs.Locker
+	if cfg.Jobs.Exclusive {
+		jobLocker = jobs.NewAdvisoryLocker(dbConn.DB())
+	}
+	jobRunner := jobs.NewRunner(jobLocker)
+	// Jobs over user data run once per residency region
+	jobRunner.Every("scheduled-deletions", cfg.Users.DeletionCheckInterval, func() error {
+		return regions.Each(context.Background(), func(ctx context.Context) error {
+			deleted, err := services.ScheduledDeletions.RunDue(ctx)
+			for _, uuid := range deleted {
+				log.Printf("deleted user %s as scheduled", uuid)
+			}
+			return err
+		})
+	})
+	if services.SigningKeys != nil {
+		jobRunner.Every("signing-key-rotation", cfg.Auth.JWT.Rotation.CheckInterval, func() error {
+			key, err := services.SigningKeys.Rotate(context.Background())
+			if key != nil {
+				log.Printf("created signing key %s, signing from %s", key.ID, key.ActivatesAt.Format(time.RFC3339))
+			}
+			return err
+		})
+	}
+	// Soft-deleted users leave the recycle bin for good after users.purge_after
+	jobRunner.Every("recycle-bin-purge", cfg.Users.DeletionCheckInterval, func() error {
+		return regions.Each(context.Background(), func(ctx context.Context) error {
+			purged, err := services.RecycleBin.Purge(ctx)
+			for _, uuid := range purged {
+				log.Printf("purged deleted user %s", uuid)
+			}
+			return err
+		})
+	})
+	// Exports are generated in chunks; each run stops after exports.poll_interval and
+	// the next one resumes from the last checkpoint
+	jobRunner.Every("exports", cfg.Exports.PollInterval, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Exports.PollInterval)
+		defer cancel()
+		return regions.Each(ctx, func(ctx context.Context) error {
+			if err := services.Exports.RunPending(ctx); err != nil {
+				return err
+			}
+			purged, err := services.Exports.Purge(context.WithoutCancel(ctx))
+			for _, id := range purged {
+				log.Printf("removed expired export %s", id)
+			}
+			return err
+		})
+	})
+	// The previous month's billing report is written once the month is over
+	jobRunner.Every("billing-report", cfg.Billing.CheckInterval, func() error {
+		report, err := services.Billing.GenerateDue(context.Background())
+		if report != nil {
+			log.Printf("generated billing report for %s", report.Month)
+		}
+		return err
+	})
+	if cfg.Webhooks.Enabled {
+		// The worker retries with its own backoff, so the client does not
+		webhookClient := cfg.HTTPClient
+		webhookClient.Timeout = cfg.Webhooks.Timeout
+		webhookClient.MaxRetries = 0
+		worker := webhook.NewWorker(repositories.Webhooks, httpclient.New("webhook", webhookClient), cfg.Webhooks)
+		jobRunner.Every("webhook-deliveries", cfg.Webhooks.PollInterval, func() error {
+			_, err := worker.Run(context.Background())
+			return err
+		})
+	}
+	if publisher != nil {
+		relay := outbox.NewRelay(repositories.Outbox, publisher, cfg.MessageBus.BatchSize, cfg.MessageBus.Timeout)
+		jobRunner.Every("outbox-relay", cfg.MessageBus.PollInterval, func() error {
+			return regions.Each(context.Background(), func(ctx context.Context) error {
+				_, err := relay.Run(ctx)
+				return err
+			})
+		})
+	}
+	// Idempotency keys answer retries for idempotency.ttl, then make room
+	jobRunner.Every("idempotency-key-purge", cfg.Idempotency.PurgeInterval, func() error {
+		purged, err := repositories.Idempotency.Purge(context.Background(), cfg.Idempotency.TTL)
+		if purged > 0 {
+			log.Printf("purged %d expired idempotency keys", purged)
+		}
+		return err
+	})
+	rebuildSearch := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Search.RebuildInterval)
+		defer cancel()
+		return regions.Each(ctx, services.UserSearch.Rebuild)
+	}
+	jobRunner.Once("user-search-rebuild", rebuildSearch)
+	jobRunner.Every("user-search-rebuild", cfg.Search.RebuildInterval, rebuildSearch)
+	// Heartbeats run on every instance, so they use a runner without locks
+	instanceRunner := jobs.NewRunner(nil)
+	heartbeat := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Cluster.HeartbeatInterval)
+		defer cancel()
+		return services.Cluster.Heartbeat(ctx, jobRunner.Held())
+	}
+	instanceRunner.Once("cluster-heartbeat", heartbeat)
+	instanceRunner.Every("cluster-heartbeat", cfg.Cluster.HeartbeatInterval, heartbeat)
+
+	// Requests are logged by middleware.Logger rather than gin's text logger
+	r := gin.New()
+	r.Use(gin.Recovery())
+	if err := handler.TrustProxies(r, cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("invalid server.trusted_proxies: %v", err)
+	}
+	// Keys from the environment are accepted with full access, alongside keys from
+	// config.yaml; requests are logged with the name of the key they used
+	apiKeys := append(envKeys, cfg.Auth.APIKeys...)
+
+	routeOpts := handler.Options{
+		APIKeys:        apiKeys,
+		Signature:      cfg.Auth.Signature,
+		Nonces:         middleware.NewMemoryNonceStore(),
+		BasePath:       cfg.Server.BasePath,
+		SLO:            cfg.SLO,
+		Plugins:        plugins,
+		ReadOnly:       middleware.NewReadOnlyMode(middleware.ReadOnlyState{Enabled: cfg.ReadOnly.Enabled, Reason: cfg.ReadOnly.Reason}, cfg.ReadOnly.RetryAfter),
+		RequestTimeout: cfg.Server.RequestTimeout,
+		MaxBodyBytes:   cfg.Server.MaxBodyBytes,
+		DocumentTypes:  cfg.Documents.AllowedTypes,
+		LogLevel:       logLevel,
+		Idempotency:    repositories.Idempotency,
+		IdempotencyTTL: cfg.Idempotency.TTL,
+	}
+	if tokens != nil {
+		routeOpts.Tokens = tokens
+		routeOpts.RequireJWT = cfg.Auth.JWT.Required
+	}
+	if services.PersonalTokens != nil {
+		routeOpts.PersonalTokens = services.PersonalTokens
+	}
+	if services.APIKeys != nil {
+		routeOpts.ManagedKeys = services.APIKeys
+	}
+	if cfg.Mirror.Enabled {
+		// Shadow traffic is best effort: no retries, and a failing shadow trips the breaker
+		mirrorClient := cfg.HTTPClient
+		mirrorClient.Timeout = cfg.Mirror.Timeout
+		mirrorClient.MaxRetries = 0
+		routeOpts.Mirror = mirror.New(httpclient.New("mirror", mirrorClient), mirror.Options{
+			Target:      cfg.Mirror.Target,
+			Percentage:  cfg.Mirror.Percentage,
+			Methods:     cfg.Mirror.Methods,
+			Timeout:     cfg.Mirror.Timeout,
+			MaxInFlight: cfg.Mirror.MaxInFlight,
+		})
+		routeOpts.MirrorMaxBody = cfg.Mirror.MaxBodyBytes
+		log.Printf("mirroring %.1f%% of requests to %s", cfg.Mirror.Percentage, cfg.Mirror.Target)
+	}
+	if len(cfg.Flags) > 0 {
+		percentages := make(map[string]float64, len(cfg.Flags))
+		for name, flag := range cfg.Flags {
+			percentages[name] = flag.Percentage
+		}
+		routeOpts.Flags = flags.New(percentages)
+	}
+	if cfg.Residency.Enabled {
+		routeOpts.Residency = &cfg.Residency
+	}
+	if cfg.Consistency.ReadYourWrites {
+		routeOpts.Consistency = repositories.Positions
+	}
+	var redisLimits *ratelimit.RedisStore
+	if cfg.RateLimit.Enabled {
+		routeOpts.RateLimit = cfg.RateLimit
+		routeOpts.RateLimits = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Backend == "redis" {
+			redisURL, err := cfg.RateLimitRedisURL()
+			if err != nil {
+				log.Fatalf("failed to load rate limit configuration: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			redisLimits, err = ratelimit.NewRedisStore(ctx, redisURL)
+			cancel()
+			if err != nil {
+				log.Fatalf("failed to connect to the rate limit backend: %v", err)
+			}
+			routeOpts.RateLimits = redisLimits
+		}
+		log.Printf("rate limiting to %d requests per %s per client (%s backend)", cfg.RateLimit.Requests, cfg.RateLimit.Window, cfg.RateLimit.Backend)
+	}
+	if cfg.Users.EnumerationProtection.Enabled {
+		// Username lookups are counted in the rate limit backend when there is one
+		routeOpts.Enumeration = &cfg.Users.EnumerationProtection
+		routeOpts.LookupLimits = routeOpts.RateLimits
+		if routeOpts.LookupLimits == nil {
+			routeOpts.LookupLimits = ratelimit.NewMemoryStore()
+		}
+	}
+	if cfg.Bots.Enabled {
+		check := &middleware.BotCheck{
+			Block:         cfg.Bots.Mode == "block",
+			Rates:         routeOpts.RateLimits,
+			MaxRate:       cfg.Bots.MaxRate,
+			RateWindow:    cfg.Bots.RateWindow,
+			HoneypotField: cfg.Bots.HoneypotField,
+		}
+		if check.Rates == nil {
+			check.Rates = ratelimit.NewMemoryStore()
+		}
+		var providers reputation.Chain
+		if len(cfg.Bots.Blocklists) > 0 {
+			blocklists, err := reputation.NewList(cfg.Bots.Blocklists)
+			if err != nil {
+				log.Fatalf("failed to load blocklists: %v", err)
+			}
+			providers = append(providers, blocklists)
+			// Every instance checks its own copy of the lists
+			instanceRunner.Every("blocklist-refresh", cfg.Bots.BlocklistRefresh, blocklists.Reload)
+			log.Printf("loaded %d blocked addresses and networks", blocklists.Len())
+		}
+		if cfg.Bots.ReputationURL != "" {
+			// Reputation is asked on the request path, so it gets its own timeout and no retries
+			reputationClient := cfg.HTTPClient
+			reputationClient.Timeout = cfg.Bots.ReputationTimeout
+			reputationClient.MaxRetries = 0
+			providers = append(providers, reputation.NewHTTP(httpclient.New("reputation", reputationClient), cfg.Bots.ReputationURL))
+		}
+		if len(providers) > 0 {
+			check.Reputation = providers
+		}
+		routeOpts.Bots = check
+		log.Printf("checking registrations for bots (%s mode)", cfg.Bots.Mode)
+	}
+	if cfg.ReadOnly.Enabled {
+		log.Printf("starting in read-only mode: mutating requests are rejected")
+	}
+	if cfg.Policy.Enabled {
+		// Authorization sits on the request path, so it gets its own timeout and no retries
+		policyClient := cfg.HTTPClient
+		policyClient.Timeout = cfg.Policy.Timeout
+		policyClient.MaxRetries = 0
+		routeOpts.Policy = policy.NewOPA(httpclient.New("policy", policyClient), cfg.Policy.URL)
+		routeOpts.PolicyFailOpen = cfg.Policy.FailOpen
+	}
+	if cfg.Anomaly.Enabled {
+		var notifier anomaly.Notifier
+		if cfg.Anomaly.WebhookURL != "" {
+			notifier = anomaly.NewWebhookNotifier(httpclient.New("anomaly-webhook", cfg.HTTPClient), cfg.Anomaly.WebhookURL, cfg.Anomaly.WebhookFormat)
+		}
+		routeOpts.Mutations = anomaly.NewDetector(cfg.Anomaly, notifier)
+	}
+
+	var usage *analytics.Collector
+	if cfg.Analytics.Enabled {
+		usage = analytics.NewCollector(repositories.Usage, cfg.Analytics.FlushInterval)
+		usage.Start()
+		routeOpts.Usage = usage
+	}
+	handler.New(r, controllers, routeOpts)
+
+	// Operational endpoints live on a separate internal listener when configured;
+	// that listener is not behind the ingress, so it ignores the base path
+	var adminHandler http.Handler
+	if cfg.Server.AdminAddress != "" {
+		admin := gin.New()
+		admin.Use(gin.Recovery(), middleware.RequestID(), middleware.RealIP(), middleware.Logger(), middleware.Errors())
+		adminOpts := routeOpts
+		adminOpts.BasePath = ""
+		handler.NewAdmin(admin, controllers, adminOpts)
+		adminHandler = admin
+	} else {
+		adminOpts := routeOpts
+		adminOpts.AdminOnMainListener = true
+		handler.NewAdmin(r, controllers, adminOpts)
+	}
+
+	srv := server.New(cfg.Server, r, adminHandler)
+	if cfg.Server.GRPC.Enabled {
+		// The gRPC API calls the same services as the REST API, and authenticates and
+		// authorizes calls with the same settings
+		srv.WithGRPC(grpcapi.NewServer(services.Users, controller.NewFieldPolicy(cfg.FieldPolicy), services.Approvals, grpcapi.Options{
+			APIKeys:        routeOpts.APIKeys,
+			ManagedKeys:    routeOpts.ManagedKeys,
+			Tokens:         routeOpts.Tokens,
+			RequireJWT:     routeOpts.RequireJWT,
+			PersonalTokens: routeOpts.PersonalTokens,
+			Policy:         routeOpts.Policy,
+			PolicyFailOpen: routeOpts.PolicyFailOpen,
+			BasePath:       routeOpts.BasePath,
+			ReadOnly:       routeOpts.ReadOnly,
+			Residency:      routeOpts.Residency,
+			RateLimits:     routeOpts.RateLimits,
+			RateLimit:      routeOpts.RateLimit,
+			Enumeration:    routeOpts.Enumeration,
+			LookupLimits:   routeOpts.LookupLimits,
+			RequestTimeout: routeOpts.RequestTimeout,
+			Reflection:     cfg.Server.GRPC.Reflection,
+		}))
+	}
+	runErr := srv.Run()
+	jobRunner.Stop()
+	instanceRunner.Stop()
+	leaveCtx, cancel := context.WithTimeout(context.Background(), cfg.Cluster.HeartbeatInterval)
+	if err := services.Cluster.Leave(leaveCtx); err != nil {
+		log.Printf("failed to leave the cluster listing: %v", err)
+	}
+	cancel()
+	bus.Wait()
+	if redisLimits != nil {
+		if err := redisLimits.Close(); err != nil {
+			log.Printf("failed to close rate limit backend: %v", err)
+		}
+	}
+	if err := caches.Close(); err != nil {
+		log.Printf("failed to close cache invalidation listener: %v", err)
+	}
+	if publisher != nil {
+		if err := publisher.Close(); err != nil {
+			log.Printf("failed to close message bus publisher: %v", err)
+		}
+	}
+	// Persist counts collected since the last flush before exiting
+	if usage != nil {
+		if err := usage.Close(); err != nil {
+			log.Printf("failed to flush usage analytics: %v", err)
+		}
+	}
+	traceCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(traceCtx); err != nil {
+		log.Printf("failed to flush traces: %v", err)
+	}
+	cancel()
+	if runErr != nil {
+		log.Fatalf("failed to run server: %v", runErr)
+	}
+}
+
+// connectRetry is how long to wait for each database at startup
+func connectRetry(cfg *config.Config) repository.ConnectRetry {
+	return repository.ConnectRetry{
+		Attempts: cfg.Database.ConnectRetry.Attempts,
+		Interval: cfg.Database.ConnectRetry.Interval,
+		MaxWait:  cfg.Database.ConnectRetry.MaxWait,
+	}
+}
+
+// loadConfig loads application configuration; the file is optional when POSTGRES_DSN is set
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || os.Getenv("POSTGRES_DSN") == "" {
+			return nil, err
+		}
+		cfg = config.Default()
 	}
+	return cfg, nil
 }
@@ -0,0 +1,6 @@
+package main
+
+// Plugins are compiled in with a blank import of their package, which registers
+// them with plugin.Register; they are then enabled by name in plugins.enabled:
+//
+//	import _ "example.com/team/cruder-audit-trail"
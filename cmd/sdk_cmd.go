@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"cruder/api"
+	"cruder/internal/sdkgen"
+)
+
+const sdkUsage = `Usage: cruder sdk generate [flags]
+
+Writes the TypeScript and Python clients of the API, generated from its OpenAPI
+spec, to clients/. Run it after changing api/openapi.yaml; go test ./internal/sdkgen
+fails while the committed clients are out of date.
+
+Flags:
+  --spec PATH         OpenAPI spec to generate from (default: the one built into the binary)
+  --out DIR           Directory to write the clients to (default clients)
+  --lang LANGS        Comma-separated languages (default typescript,python)
+`
+
+// runSDKCommand implements "cruder sdk generate" and returns the exit code
+func runSDKCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "generate" {
+		_, _ = fmt.Fprint(stderr, sdkUsage)
+		return 2
+	}
+
+	fs := flag.NewFlagSet("sdk generate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	specPath := fs.String("spec", "", "OpenAPI spec to generate from")
+	out := fs.String("out", "clients", "directory to write the clients to")
+	langs := fs.String("lang", strings.Join(sdkgen.Languages, ","), "comma-separated languages")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	spec := api.Spec
+	if *specPath != "" {
+		var err error
+		if spec, err = os.ReadFile(*specPath); err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to read spec: %v\n", err)
+			return 1
+		}
+	}
+	files, err := sdkgen.Generate(spec, strings.Split(*langs, ","))
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "failed to generate clients: %v\n", err)
+		return 1
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		path := filepath.Join(*out, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to create %s: %v\n", filepath.Dir(path), err)
+			return 1
+		}
+		if err := os.WriteFile(path, files[name], 0o600); err != nil {
+			_, _ = fmt.Fprintf(stderr, "failed to write %s: %v\n", path, err)
+			return 1
+		}
+		_, _ = fmt.Fprintf(stdout, "wrote %s\n", path)
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+)
+
+// Sink persists aggregated usage; repository.UsageRepository implements it
+type Sink interface {
+	Add(ctx context.Context, records []model.UsageRecord) error
+}
+
+type usageKey struct {
+	day    time.Time
+	apiKey string
+	method string
+	route  string
+}
+
+// Collector aggregates requests per API key, route and UTC day in memory and
+// periodically merges the totals into the sink, so recording a request never
+// touches the database. Counts that fail to flush are kept for the next attempt.
+type Collector struct {
+	sink     Sink
+	interval time.Duration
+	now      func() time.Time
+
+	mu     sync.Mutex
+	counts map[usageKey]*model.UsageRecord
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewCollector creates a collector that flushes to sink every interval once started
+func NewCollector(sink Sink, interval time.Duration) *Collector {
+	return &Collector{
+		sink:     sink,
+		interval: interval,
+		now:      time.Now,
+		counts:   make(map[usageKey]*model.UsageRecord),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Record counts one request; statuses of 400 and above count as errors
+func (c *Collector) Record(apiKey, method, route string, status int, duration time.Duration) {
+	now := c.now().UTC()
+	key := usageKey{
+		day:    time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		apiKey: apiKey,
+		method: method,
+		route:  route,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rec, ok := c.counts[key]
+	if !ok {
+		rec = &model.UsageRecord{Day: key.day, APIKey: apiKey, Method: method, Route: route}
+		c.counts[key] = rec
+	}
+	rec.Requests++
+	if status >= 400 {
+		rec.Errors++
+	}
+	rec.TotalDurationMs += duration.Milliseconds()
+}
+
+// Flush writes the pending counts to the sink
+func (c *Collector) Flush() error {
+	c.mu.Lock()
+	pending := c.counts
+	c.counts = make(map[usageKey]*model.UsageRecord)
+	c.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	records := make([]model.UsageRecord, 0, len(pending))
+	for _, rec := range pending {
+		records = append(records, *rec)
+	}
+	if err := c.sink.Add(context.Background(), records); err != nil {
+		c.restore(pending)
+		return err
+	}
+	return nil
+}
+
+// restore merges counts that could not be flushed back into the pending set
+func (c *Collector) restore(pending map[usageKey]*model.UsageRecord) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, rec := range pending {
+		if cur, ok := c.counts[key]; ok {
+			cur.Requests += rec.Requests
+			cur.Errors += rec.Errors
+			cur.TotalDurationMs += rec.TotalDurationMs
+			continue
+		}
+		c.counts[key] = rec
+	}
+}
+
+// Start flushes in the background until Close is called
+func (c *Collector) Start() {
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Flush(); err != nil {
+					log.Printf("failed to flush usage analytics: %v", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops the background loop started by Start and flushes the remaining counts
+func (c *Collector) Close() error {
+	close(c.stop)
+	<-c.done
+	return c.Flush()
+}
@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+)
+
+type fakeSink struct {
+	records []model.UsageRecord
+	err     error
+}
+
+func (s *fakeSink) Add(_ context.Context, records []model.UsageRecord) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func TestCollector_AggregatesPerKeyRouteAndDay(t *testing.T) {
+	// Given: A collector with a fixed clock
+	sink := &fakeSink{}
+	c := NewCollector(sink, time.Minute)
+	c.now = func() time.Time { return time.Date(2026, 10, 14, 23, 59, 0, 0, time.UTC) }
+
+	// When: Recording requests for two keys
+	c.Record("partner", "GET", "/api/v1/users/", 200, 10*time.Millisecond)
+	c.Record("partner", "GET", "/api/v1/users/", 500, 30*time.Millisecond)
+	c.Record("default", "GET", "/api/v1/users/", 200, 5*time.Millisecond)
+	if err := c.Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	// Then: Requests are aggregated into one record per key
+	if len(sink.records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(sink.records))
+	}
+	for _, rec := range sink.records {
+		if rec.APIKey != "partner" {
+			continue
+		}
+		if rec.Requests != 2 || rec.Errors != 1 || rec.TotalDurationMs != 40 {
+			t.Errorf("unexpected partner record: %+v", rec)
+		}
+		if !rec.Day.Equal(time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected day 2026-10-14, got %v", rec.Day)
+		}
+	}
+}
+
+func TestCollector_KeepsCountsWhenFlushFails(t *testing.T) {
+	// Given: A sink that fails once
+	sink := &fakeSink{err: errors.New("database unavailable")}
+	c := NewCollector(sink, time.Minute)
+	c.Record("partner", "GET", "/api/v1/users/", 200, time.Millisecond)
+
+	// When: The first flush fails, more requests arrive and the next flush succeeds
+	if err := c.Flush(); err == nil {
+		t.Fatal("expected flush error")
+	}
+	c.Record("partner", "GET", "/api/v1/users/", 200, time.Millisecond)
+	sink.err = nil
+	if err := c.Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %v", err)
+	}
+
+	// Then: No request is lost
+	if len(sink.records) != 1 || sink.records[0].Requests != 2 {
+		t.Errorf("expected one record with 2 requests, got %+v", sink.records)
+	}
+}
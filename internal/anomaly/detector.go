@@ -0,0 +1,155 @@
+package anomaly
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var alertsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "mutation_anomaly_alerts_total",
+	Help: "Alerts raised for unusual per-key mutation volume.",
+}, []string{"action"})
+
+// baselineWeight is the weight of the newest window in the moving-average baseline
+const baselineWeight = 0.2
+
+// Alert describes a key whose mutation volume crossed a threshold
+type Alert struct {
+	APIKey   string        `json:"api_key"`
+	Action   string        `json:"action"`
+	Count    int           `json:"count"`
+	Window   time.Duration `json:"-"`
+	Baseline float64       `json:"baseline"`
+	Reason   string        `json:"reason"`
+	At       time.Time     `json:"at"`
+}
+
+// Notifier delivers alerts, e.g. to a webhook or Slack
+type Notifier interface {
+	Notify(alert Alert) error
+}
+
+type counterKey struct {
+	apiKey string
+	action string
+}
+
+type counter struct {
+	windowStart time.Time
+	count       int
+	baseline    float64
+	alertedAt   time.Time
+}
+
+// Detector counts mutations per API key and action in fixed windows and raises an
+// alert when a window exceeds the absolute limit, or exceeds the key's own moving
+// average by the configured factor. Each key and action alerts at most once per
+// cooldown so a runaway script produces one page, not thousands.
+type Detector struct {
+	cfg      config.AnomalyConfig
+	notifier Notifier
+	now      func() time.Time
+
+	mu       sync.Mutex
+	counters map[counterKey]*counter
+}
+
+// NewDetector creates a detector; notifier may be nil to only log alerts
+func NewDetector(cfg config.AnomalyConfig, notifier Notifier) *Detector {
+	return &Detector{
+		cfg:      cfg,
+		notifier: notifier,
+		now:      time.Now,
+		counters: make(map[counterKey]*counter),
+	}
+}
+
+// RecordMutation counts one successful mutation; actions without a threshold are ignored
+func (d *Detector) RecordMutation(apiKey, action string) {
+	threshold, ok := d.cfg.Thresholds[action]
+	if !ok {
+		return
+	}
+
+	alert, fire := d.record(apiKey, action, threshold)
+	if !fire {
+		return
+	}
+
+	alertsTotal.WithLabelValues(action).Inc()
+	log.Printf("mutation anomaly: key %q made %d %s requests in %s (baseline %.1f): %s",
+		alert.APIKey, alert.Count, alert.Action, alert.Window, alert.Baseline, alert.Reason)
+	if d.notifier != nil {
+		// Delivery retries must not hold up the request being served
+		go func() {
+			if err := d.notifier.Notify(alert); err != nil {
+				log.Printf("failed to deliver mutation anomaly alert: %v", err)
+			}
+		}()
+	}
+}
+
+func (d *Detector) record(apiKey, action string, threshold config.AnomalyThreshold) (Alert, bool) {
+	now := d.now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := counterKey{apiKey: apiKey, action: action}
+	c, ok := d.counters[key]
+	if !ok {
+		c = &counter{windowStart: now}
+		d.counters[key] = c
+	}
+	d.roll(c, now)
+	c.count++
+
+	var reason string
+	switch {
+	case threshold.Max > 0 && c.count > threshold.Max:
+		reason = fmt.Sprintf("more than %d per window", threshold.Max)
+	case threshold.Factor > 0 && c.baseline > 0 && c.count >= threshold.MinCount &&
+		float64(c.count) > threshold.Factor*c.baseline:
+		reason = fmt.Sprintf("more than %.1fx the usual volume", threshold.Factor)
+	default:
+		return Alert{}, false
+	}
+
+	if !c.alertedAt.IsZero() && now.Sub(c.alertedAt) < d.cfg.Cooldown {
+		return Alert{}, false
+	}
+	c.alertedAt = now
+
+	return Alert{
+		APIKey:   apiKey,
+		Action:   action,
+		Count:    c.count,
+		Window:   d.cfg.Window,
+		Baseline: c.baseline,
+		Reason:   reason,
+		At:       now,
+	}, true
+}
+
+// roll closes elapsed windows and folds their counts into the baseline; idle
+// windows count as zero so a quiet key's baseline decays
+func (d *Detector) roll(c *counter, now time.Time) {
+	elapsed := int(now.Sub(c.windowStart) / d.cfg.Window)
+	if elapsed <= 0 {
+		return
+	}
+	c.baseline = baselineWeight*float64(c.count) + (1-baselineWeight)*c.baseline
+	for i := 1; i < elapsed && c.baseline > 0.01; i++ {
+		c.baseline *= 1 - baselineWeight
+	}
+	c.count = 0
+	c.windowStart = c.windowStart.Add(time.Duration(elapsed) * d.cfg.Window)
+}
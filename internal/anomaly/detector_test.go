@@ -0,0 +1,93 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+)
+
+type chanNotifier chan Alert
+
+func (n chanNotifier) Notify(alert Alert) error {
+	n <- alert
+	return nil
+}
+
+func newTestDetector(threshold config.AnomalyThreshold) (*Detector, chanNotifier, *time.Time) {
+	cfg := config.AnomalyConfig{
+		Window:     time.Minute,
+		Cooldown:   time.Hour,
+		Thresholds: map[string]config.AnomalyThreshold{"delete": threshold},
+	}
+	notifier := make(chanNotifier, 10)
+	d := NewDetector(cfg, notifier)
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	return d, notifier, &now
+}
+
+func expectAlerts(t *testing.T, notifier chanNotifier, want int) []Alert {
+	t.Helper()
+	var alerts []Alert
+	timeout := time.After(100 * time.Millisecond)
+	for {
+		select {
+		case a := <-notifier:
+			alerts = append(alerts, a)
+		case <-timeout:
+			if len(alerts) != want {
+				t.Fatalf("expected %d alerts, got %d: %+v", want, len(alerts), alerts)
+			}
+			return alerts
+		}
+	}
+}
+
+func TestDetector_AlertsOnceAboveMax(t *testing.T) {
+	// Given: A limit of 5 deletes per minute
+	d, notifier, _ := newTestDetector(config.AnomalyThreshold{Max: 5})
+
+	// When: A script deletes 20 users within the window
+	for i := 0; i < 20; i++ {
+		d.RecordMutation("cleanup-script", "delete")
+	}
+
+	// Then: One alert is sent thanks to the cooldown
+	alerts := expectAlerts(t, notifier, 1)
+	if alerts[0].APIKey != "cleanup-script" || alerts[0].Count != 6 {
+		t.Errorf("unexpected alert: %+v", alerts[0])
+	}
+}
+
+func TestDetector_AlertsOnDeviationFromBaseline(t *testing.T) {
+	// Given: A key that usually deletes 2 users per minute
+	d, notifier, now := newTestDetector(config.AnomalyThreshold{Factor: 5, MinCount: 5})
+	for minute := 0; minute < 10; minute++ {
+		d.RecordMutation("partner", "delete")
+		d.RecordMutation("partner", "delete")
+		*now = now.Add(time.Minute)
+	}
+	expectAlerts(t, notifier, 0)
+
+	// When: It suddenly deletes 15 in one minute
+	for i := 0; i < 15; i++ {
+		d.RecordMutation("partner", "delete")
+	}
+
+	// Then: The deviation is reported
+	alerts := expectAlerts(t, notifier, 1)
+	if alerts[0].Baseline <= 0 {
+		t.Errorf("expected a positive baseline, got %+v", alerts[0])
+	}
+}
+
+func TestDetector_IgnoresActionsWithoutThreshold(t *testing.T) {
+	d, notifier, _ := newTestDetector(config.AnomalyThreshold{Max: 1})
+
+	for i := 0; i < 10; i++ {
+		d.RecordMutation("partner", "create")
+	}
+
+	expectAlerts(t, notifier, 0)
+}
@@ -0,0 +1,48 @@
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"cruder/internal/httpclient"
+)
+
+// WebhookNotifier posts alerts as JSON to a URL. With the "slack" format the body is
+// a Slack incoming-webhook message; with "json" it is the Alert itself.
+type WebhookNotifier struct {
+	client *httpclient.Client
+	url    string
+	format string
+}
+
+// NewWebhookNotifier creates a notifier that delivers alerts through client
+func NewWebhookNotifier(client *httpclient.Client, url, format string) *WebhookNotifier {
+	return &WebhookNotifier{client: client, url: url, format: format}
+}
+
+// Notify implements Notifier
+func (n *WebhookNotifier) Notify(alert Alert) error {
+	var payload interface{} = alert
+	if n.format == "slack" {
+		payload = map[string]string{
+			"text": fmt.Sprintf(":rotating_light: API key *%s* made %d %s requests within %s (usual: %.1f) - %s",
+				alert.APIKey, alert.Count, alert.Action, alert.Window, alert.Baseline, alert.Reason),
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(context.Background(), n.url, "application/json", body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// Package apierror defines the errors the API reports to clients. Every error
+// response has the same body, {"code": ..., "message": ..., "details": ...}: code
+// is a stable machine-readable identifier, message is meant for humans and may
+// change, and details, when present, carries structured data such as the failing
+// fields of a validation error.
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of errors; test for them with errors.Is. Each maps to one HTTP status.
+var (
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	// ErrPreconditionFailed and ErrPreconditionRequired answer conditional
+	// requests: a stale If-Match, and a missing one where it is required
+	ErrPreconditionFailed   = errors.New("precondition failed")
+	ErrPreconditionRequired = errors.New("precondition required")
+	ErrTooLarge             = errors.New("too large")
+	// ErrUnprocessable is a well-formed request that cannot be processed as sent
+	ErrUnprocessable = errors.New("unprocessable")
+	ErrUnsupported   = errors.New("unsupported media type")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrInternal      = errors.New("internal error")
+	ErrUnavailable   = errors.New("unavailable")
+)
+
+// statuses maps each kind to the status it is answered with
+var statuses = map[error]int{
+	ErrValidation:           http.StatusBadRequest,
+	ErrUnauthorized:         http.StatusUnauthorized,
+	ErrForbidden:            http.StatusForbidden,
+	ErrNotFound:             http.StatusNotFound,
+	ErrConflict:             http.StatusConflict,
+	ErrPreconditionFailed:   http.StatusPreconditionFailed,
+	ErrPreconditionRequired: http.StatusPreconditionRequired,
+	ErrTooLarge:             http.StatusRequestEntityTooLarge,
+	ErrUnprocessable:        http.StatusUnprocessableEntity,
+	ErrUnsupported:          http.StatusUnsupportedMediaType,
+	ErrRateLimited:          http.StatusTooManyRequests,
+	ErrInternal:             http.StatusInternalServerError,
+	ErrUnavailable:          http.StatusServiceUnavailable,
+}
+
+// InternalCode is the code of errors that are not an *Error; their message is not
+// shown to clients
+const InternalCode = "internal_error"
+
+// Error is an error meant for the client
+type Error struct {
+	kind    error
+	Code    string
+	Message string
+	Details any
+}
+
+// New creates an error of the given kind
+func New(kind error, code, message string) *Error {
+	return &Error{kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// Unwrap returns the kind of the error
+func (e *Error) Unwrap() error { return e.kind }
+
+// WithDetails returns a copy of e carrying details
+func (e *Error) WithDetails(details any) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// Validation reports a request the API cannot accept as sent (400)
+func Validation(code, message string) *Error { return New(ErrValidation, code, message) }
+
+// Unauthorized reports missing or invalid credentials (401)
+func Unauthorized(code, message string) *Error { return New(ErrUnauthorized, code, message) }
+
+// Forbidden reports a caller not allowed to do what it asked (403)
+func Forbidden(code, message string) *Error { return New(ErrForbidden, code, message) }
+
+// NotFound reports a resource that does not exist (404)
+func NotFound(code, message string) *Error { return New(ErrNotFound, code, message) }
+
+// Conflict reports a request clashing with the current state (409)
+func Conflict(code, message string) *Error { return New(ErrConflict, code, message) }
+
+// PreconditionFailed reports a resource changed since the version the client named (412)
+func PreconditionFailed(code, message string) *Error {
+	return New(ErrPreconditionFailed, code, message)
+}
+
+// PreconditionRequired reports a request that must name the version it was made against (428)
+func PreconditionRequired(code, message string) *Error {
+	return New(ErrPreconditionRequired, code, message)
+}
+
+// TooLarge reports a request body over a size limit (413)
+func TooLarge(code, message string) *Error { return New(ErrTooLarge, code, message) }
+
+// Unsupported reports a content type the API does not accept (415)
+func Unsupported(code, message string) *Error { return New(ErrUnsupported, code, message) }
+
+// Unprocessable reports a well-formed request the API will not process as sent (422)
+func Unprocessable(code, message string) *Error { return New(ErrUnprocessable, code, message) }
+
+// RateLimited reports a client over its request quota (429)
+func RateLimited(code, message string) *Error { return New(ErrRateLimited, code, message) }
+
+// Unavailable reports a dependency the request needs being unreachable (503)
+func Unavailable(code, message string) *Error { return New(ErrUnavailable, code, message) }
+
+// Internal reports a failure that is not the client's fault (500); unlike other
+// errors its message is shown to the client, so it must not leak internals
+func Internal(code, message string) *Error { return New(ErrInternal, code, message) }
+
+// Body is the JSON body of every error response
+type Body struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+}
+
+// Status returns the HTTP status err is answered with: that of its kind, or 500
+// for errors that are not an *Error
+func Status(err error) int {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		if status, ok := statuses[apiErr.kind]; ok {
+			return status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Render returns the status and body err is answered with. Errors that are not an
+// *Error become internal_error with a generic message.
+func Render(err error) (int, Body) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		return http.StatusInternalServerError, Body{Code: InternalCode, Message: "internal server error"}
+	}
+	return Status(apiErr), Body{Code: apiErr.Code, Message: apiErr.Message, Details: apiErr.Details}
+}
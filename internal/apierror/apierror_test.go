@@ -0,0 +1,61 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		kind   error
+		status int
+		code   string
+	}{
+		{"validation", Validation("invalid_body", "invalid request body"), ErrValidation, http.StatusBadRequest, "invalid_body"},
+		{"not found", NotFound("user_not_found", "users not found"), ErrNotFound, http.StatusNotFound, "user_not_found"},
+		{"conflict", Conflict("email_taken", "email already exists"), ErrConflict, http.StatusConflict, "email_taken"},
+		{"wrapped", fmt.Errorf("update: %w", Conflict("email_taken", "email already exists")), ErrConflict, http.StatusConflict, "email_taken"},
+		{"precondition failed", PreconditionFailed("version_mismatch", "user has changed"), ErrPreconditionFailed, http.StatusPreconditionFailed, "version_mismatch"},
+		{"precondition required", PreconditionRequired("if_match_required", "If-Match is required"), ErrPreconditionRequired, http.StatusPreconditionRequired, "if_match_required"},
+		{"unprocessable", Unprocessable("idempotency_key_reused", "Idempotency-Key was used for another request"), ErrUnprocessable, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+		{"unavailable", Unavailable("read_only", "service is in read-only mode"), ErrUnavailable, http.StatusServiceUnavailable, "read_only"},
+		{"plain error", errors.New("pq: connection refused"), nil, http.StatusInternalServerError, InternalCode},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			status, body := Render(tc.err)
+
+			// Then: The kind decides the status and the code is kept
+			if status != tc.status || body.Code != tc.code {
+				t.Errorf("expected %d %s, got %d %s", tc.status, tc.code, status, body.Code)
+			}
+			if tc.kind != nil && !errors.Is(tc.err, tc.kind) {
+				t.Errorf("expected %v to be %v", tc.err, tc.kind)
+			}
+			if tc.kind == nil && body.Message == tc.err.Error() {
+				t.Errorf("internal message leaked: %q", body.Message)
+			}
+		})
+	}
+}
+
+func TestWithDetails(t *testing.T) {
+	// Given: A shared error value
+	base := Validation("invalid_user", "username is reserved")
+
+	// When
+	detailed := base.WithDetails([]string{"username"})
+
+	// Then: Details go on a copy, leaving the shared value untouched
+	if base.Details != nil {
+		t.Errorf("expected base without details, got %v", base.Details)
+	}
+	if _, body := Render(detailed); body.Details == nil || body.Code != "invalid_user" {
+		t.Errorf("expected details on the copy, got %+v", body)
+	}
+}
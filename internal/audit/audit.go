@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// Event records who did what to which resource. Audit events are written to the
+// log as JSON with an "Audit:" prefix so they can be routed to long-term storage.
+type Event struct {
+	Action     string            `json:"action"`
+	Actor      string            `json:"actor"`
+	ClientIP   string            `json:"client.address,omitempty"`
+	Resource   string            `json:"resource"`
+	ResourceID string            `json:"resource_id,omitempty"`
+	UserUUID   string            `json:"user_uuid,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// Record writes the event to the audit log
+func Record(e Event) {
+	entry := struct {
+		Timestamp string `json:"timestamp"`
+		Event
+	}{time.Now().Format(time.RFC3339Nano), e}
+
+	jsonData, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error marshaling audit event: %v", err)
+		return
+	}
+	log.Printf("Audit: %s", string(jsonData))
+}
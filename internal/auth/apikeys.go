@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// APIKeyPrefix starts every API key managed through the admin API, telling them
+// apart from keys in the configuration
+const APIKeyPrefix = "cak_"
+
+// ErrInvalidAPIKey reports a managed API key that is unknown, revoked or expired
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// NewAPIKey generates an API key and returns it with its hash and the prefix shown
+// in key listings
+func NewAPIKey() (key, hash, prefix string, err error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", "", err
+	}
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b[:])
+	return key, HashAPIKey(key), key[:len(APIKeyPrefix)+6], nil
+}
+
+// HashAPIKey returns the hash keys are stored and looked up by, the one personal
+// tokens use
+func HashAPIKey(key string) string {
+	return HashPersonalToken(key)
+}
+
+// IsManagedAPIKey reports whether an X-API-Key value is a managed key
+func IsManagedAPIKey(key string) bool {
+	return strings.HasPrefix(key, APIKeyPrefix)
+}
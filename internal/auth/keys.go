@@ -0,0 +1,278 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing key states, derived by KeyStates
+const (
+	// KeyPending keys are published but do not sign yet
+	KeyPending = "pending"
+	// KeyActive is the key new tokens are signed with
+	KeyActive = "active"
+	// KeyRetiring keys were replaced and verify the tokens they signed until those expire
+	KeyRetiring = "retiring"
+	// KeyExpired keys verify nothing anymore and are deleted by the next rotation
+	KeyExpired = "expired"
+	// KeyRevoked keys verify nothing, whatever their age
+	KeyRevoked = "revoked"
+)
+
+// keyBits is the size of generated RSA keys
+const keyBits = 2048
+
+// reloadAfter bounds how often an unknown key ID reloads the keys, so tokens with
+// made-up IDs cannot hammer the database
+const reloadAfter = 10 * time.Second
+
+// JWK is the public half of an RS256 key as published in a JWKS (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// JWKS is the document served at /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func newJWK(kid string, key *rsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     kid,
+		N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// Thumbprint returns the RFC 7638 thumbprint of key, used as its key ID
+func Thumbprint(key *rsa.PublicKey) string {
+	jwk := newJWK("", key)
+	// The members must be in lexical order, without whitespace
+	canonical, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{jwk.E, jwk.KeyType, jwk.N})
+	sum := sha256.Sum256(canonical)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// GenerateSigningKey creates a key that starts signing at activatesAt. Its private
+// half is encrypted with masterKey, like an encrypted config value.
+func GenerateSigningKey(masterKey []byte, activatesAt time.Time) (*model.SigningKey, error) {
+	private, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return nil, err
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := config.EncryptValue(masterKey, string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER})))
+	if err != nil {
+		return nil, err
+	}
+	return &model.SigningKey{
+		ID:          Thumbprint(&private.PublicKey),
+		PublicKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		PrivateKey:  wrapped,
+		ActivatesAt: activatesAt,
+	}, nil
+}
+
+// KeyStates sorts keys by activation and sets their Status at now. The newest key
+// whose activation has passed signs; each key it replaced keeps verifying until
+// the tokens it signed, valid for ttl, have expired. A revoked key still ends its
+// predecessor's turn, so revoking the active key leaves none until a replacement
+// is created.
+func KeyStates(keys []model.SigningKey, now time.Time, ttl time.Duration) {
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].ActivatesAt.Before(keys[j].ActivatesAt) })
+	var successor *time.Time
+	for i := len(keys) - 1; i >= 0; i-- {
+		k := &keys[i]
+		switch {
+		case k.ActivatesAt.After(now):
+			k.Status = KeyPending
+			if k.RevokedAt != nil {
+				k.Status = KeyRevoked
+			}
+			continue
+		case k.RevokedAt != nil:
+			k.Status = KeyRevoked
+		case successor == nil:
+			k.Status = KeyActive
+		case now.Before(successor.Add(ttl + leeway)):
+			k.Status = KeyRetiring
+		default:
+			k.Status = KeyExpired
+		}
+		successor = &k.ActivatesAt
+	}
+}
+
+// KeySource lists the stored signing keys; repository.SigningKeyRepository implements it
+type KeySource interface {
+	List(ctx context.Context) ([]model.SigningKey, error)
+}
+
+// KeyRing holds the stored signing keys of a service that rotates its own keys.
+// Keys are reloaded every refresh interval, right after Invalidate, or when a token
+// names a key the ring does not know, so keys created by another instance apply
+// without a restart.
+type KeyRing struct {
+	source    KeySource
+	masterKey []byte
+	ttl       time.Duration
+	refresh   time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	keys     []model.SigningKey
+	public   map[string]*rsa.PublicKey
+	private  map[string]*rsa.PrivateKey
+	loadedAt time.Time
+}
+
+// NewKeyRing creates a ring for tokens valid for ttl; masterKey decrypts the private keys
+func NewKeyRing(source KeySource, masterKey []byte, ttl, refresh time.Duration) *KeyRing {
+	return &KeyRing{source: source, masterKey: masterKey, ttl: ttl, refresh: refresh, now: time.Now}
+}
+
+// Invalidate makes the next use reload the keys
+func (r *KeyRing) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loadedAt = time.Time{}
+}
+
+// signing returns the active key
+func (r *KeyRing) signing() (string, *rsa.PrivateKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys, err := r.load(false)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, k := range keys {
+		if k.Status == KeyActive && r.private[k.ID] != nil {
+			return k.ID, r.private[k.ID], nil
+		}
+	}
+	return "", nil, errors.New("no active signing key")
+}
+
+// verifying returns the public key with the ID if tokens may still be verified with it
+func (r *KeyRing) verifying(kid string) (*rsa.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys, err := r.load(false)
+	if err != nil {
+		return nil, err
+	}
+	if _, known := r.public[kid]; !known && r.now().Sub(r.loadedAt) >= reloadAfter {
+		if keys, err = r.load(true); err != nil {
+			return nil, err
+		}
+	}
+	for _, k := range keys {
+		if k.ID == kid && verifies(k.Status) {
+			return r.public[kid], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown or retired signing key %q", kid)
+}
+
+// published returns the keys tokens may be verified with, oldest first
+func (r *KeyRing) published() ([]JWK, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys, err := r.load(false)
+	if err != nil {
+		return nil, err
+	}
+	var jwks []JWK
+	for _, k := range keys {
+		if verifies(k.Status) {
+			jwks = append(jwks, newJWK(k.ID, r.public[k.ID]))
+		}
+	}
+	return jwks, nil
+}
+
+// load returns the keys with their status at now, reloading them when stale or
+// forced; r.mu must be held. Keys that cannot be decoded are logged and skipped.
+func (r *KeyRing) load(force bool) ([]model.SigningKey, error) {
+	if force || r.loadedAt.IsZero() || r.now().Sub(r.loadedAt) >= r.refresh {
+		// The keys are shared by all requests, so loading them belongs to none
+		stored, err := r.source.List(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing keys: %w", err)
+		}
+		keys := make([]model.SigningKey, 0, len(stored))
+		public := make(map[string]*rsa.PublicKey, len(stored))
+		private := make(map[string]*rsa.PrivateKey, len(stored))
+		for _, k := range stored {
+			pub, err := jwt.ParseRSAPublicKeyFromPEM([]byte(k.PublicKey))
+			if err != nil {
+				log.Printf("skipping signing key %s: %v", k.ID, err)
+				continue
+			}
+			public[k.ID] = pub
+			if k.RevokedAt == nil {
+				priv, err := r.decrypt(k)
+				if err != nil {
+					log.Printf("signing key %s can only verify: %v", k.ID, err)
+				}
+				private[k.ID] = priv
+			}
+			keys = append(keys, k)
+		}
+		r.keys, r.public, r.private, r.loadedAt = keys, public, private, r.now()
+	}
+	keys := append([]model.SigningKey(nil), r.keys...)
+	KeyStates(keys, r.now(), r.ttl)
+	return keys, nil
+}
+
+func (r *KeyRing) decrypt(k model.SigningKey) (*rsa.PrivateKey, error) {
+	plain, err := config.DecryptValue(r.masterKey, k.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return jwt.ParseRSAPrivateKeyFromPEM([]byte(plain))
+}
+
+// verifies reports whether tokens signed with a key in the state are accepted
+func verifies(status string) bool {
+	return status == KeyPending || status == KeyActive || status == KeyRetiring
+}
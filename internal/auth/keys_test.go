@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+)
+
+func TestKeyStates(t *testing.T) {
+	now := time.Now()
+	revoked := now.Add(-time.Minute)
+	at := func(d time.Duration) time.Time { return now.Add(d) }
+
+	// Given: Keys in every stage of their life, out of order
+	keys := []model.SigningKey{
+		{ID: "next", ActivatesAt: at(time.Hour)},
+		{ID: "current", ActivatesAt: at(-10 * time.Minute)},
+		{ID: "previous", ActivatesAt: at(-30 * 24 * time.Hour)},
+		{ID: "ancient", ActivatesAt: at(-60 * 24 * time.Hour)},
+		{ID: "compromised", ActivatesAt: at(-45 * 24 * time.Hour), RevokedAt: &revoked},
+	}
+
+	// When
+	KeyStates(keys, now, time.Hour)
+
+	// Then: The newest activated key signs, its predecessor verifies for one token
+	// lifetime and older ones are expired
+	want := map[string]string{
+		"next":        KeyPending,
+		"current":     KeyActive,
+		"previous":    KeyRetiring,
+		"ancient":     KeyExpired,
+		"compromised": KeyRevoked,
+	}
+	for _, k := range keys {
+		if k.Status != want[k.ID] {
+			t.Errorf("%s: expected %s, got %s", k.ID, want[k.ID], k.Status)
+		}
+	}
+	if keys[0].ID != "ancient" || keys[len(keys)-1].ID != "next" {
+		t.Errorf("expected keys sorted by activation, got %s first and %s last", keys[0].ID, keys[len(keys)-1].ID)
+	}
+}
+
+func TestKeyStates_RevokedActiveKey(t *testing.T) {
+	// Given: The active key was revoked
+	now := time.Now()
+	keys := []model.SigningKey{
+		{ID: "previous", ActivatesAt: now.Add(-30 * 24 * time.Hour)},
+		{ID: "current", ActivatesAt: now.Add(-10 * time.Minute), RevokedAt: &now},
+	}
+
+	// When
+	KeyStates(keys, now, time.Hour)
+
+	// Then: Signing does not fall back to the key it replaced
+	for _, k := range keys {
+		if k.Status == KeyActive {
+			t.Errorf("expected no active key, got %s", k.ID)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// PersonalTokenPrefix starts every personal token, telling them apart from JWTs
+// and making leaked tokens easy to find with secret scanners
+const PersonalTokenPrefix = "cpat_"
+
+// ErrInvalidToken reports a personal token that is unknown, expired or whose owner
+// was deleted
+var ErrInvalidToken = errors.New("invalid token")
+
+// displayLength is how much of a personal token is kept to identify it
+const displayLength = len(PersonalTokenPrefix) + 6
+
+// NewPersonalToken generates a personal token and returns it with its hash and the
+// prefix shown in token listings
+func NewPersonalToken() (token, hash, prefix string, err error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", "", err
+	}
+	token = PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(b[:])
+	return token, HashPersonalToken(token), token[:displayLength], nil
+}
+
+// HashPersonalToken returns the hash tokens are stored and looked up by. Tokens are
+// random, so a fast hash is enough.
+func HashPersonalToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsPersonalToken reports whether a bearer token is a personal token
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
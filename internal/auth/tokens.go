@@ -0,0 +1,189 @@
+// Package auth issues and verifies the JWT bearer tokens accepted next to API keys
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"cruder/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// leeway tolerates clock differences between the issuer and this server
+const leeway = 30 * time.Second
+
+// Claims are the contents of a token
+type Claims struct {
+	Scopes []string `json:"scopes,omitempty"`
+	Tenant string   `json:"tenant,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Tokens signs and verifies tokens with the configured algorithm
+type Tokens struct {
+	algorithm string
+	issuer    string
+	audience  string
+	ttl       time.Duration
+	// signKey is nil when tokens can only be verified, e.g. RS256 with only a public key
+	signKey   any
+	verifyKey any
+	// fileKID is the key ID of an RS256 public key file, under which it is published
+	fileKID string
+	// keys holds rotated keys, which take over signing; nil without rotation
+	keys *KeyRing
+}
+
+// NewTokens loads the keys of cfg; secret is the HS256 secret
+func NewTokens(cfg config.JWTConfig, secret string) (*Tokens, error) {
+	t := &Tokens{algorithm: cfg.Algorithm, issuer: cfg.Issuer, audience: cfg.Audience, ttl: cfg.TTL}
+	switch cfg.Algorithm {
+	case "HS256":
+		if secret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		t.signKey, t.verifyKey = []byte(secret), []byte(secret)
+	case "RS256":
+		if cfg.PublicKeyFile == "" && !cfg.Rotation.Enabled {
+			return nil, errors.New("RS256 requires a public key file")
+		}
+		if cfg.PublicKeyFile != "" {
+			public, err := readPublicKey(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			t.verifyKey, t.fileKID = public, Thumbprint(public)
+		}
+		if cfg.PrivateKeyFile != "" {
+			private, err := readPrivateKey(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			t.signKey = private
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", cfg.Algorithm)
+	}
+	return t, nil
+}
+
+// WithKeyRing signs new tokens with the ring's active key, named in the kid header,
+// and accepts tokens signed with any of its keys that is not retired or revoked.
+// Tokens without a kid header are still verified with the configured key files.
+func (t *Tokens) WithKeyRing(keys *KeyRing) {
+	t.keys = keys
+}
+
+// CanIssue reports whether tokens can be signed, not just verified
+func (t *Tokens) CanIssue() bool {
+	return t.signKey != nil || t.keys != nil
+}
+
+// JWKS returns the public keys tokens are verified with: the public key file and
+// the ring's published keys. HS256 secrets are never published.
+func (t *Tokens) JWKS() (JWKS, error) {
+	jwks := JWKS{Keys: []JWK{}}
+	if public, ok := t.verifyKey.(*rsa.PublicKey); ok {
+		jwks.Keys = append(jwks.Keys, newJWK(t.fileKID, public))
+	}
+	if t.keys != nil {
+		keys, err := t.keys.published()
+		if err != nil {
+			return JWKS{}, err
+		}
+		jwks.Keys = append(jwks.Keys, keys...)
+	}
+	return jwks, nil
+}
+
+// Issue signs a token for subject, valid for the configured TTL
+func (t *Tokens) Issue(subject string, scopes []string, tenant string) (string, time.Time, error) {
+	if !t.CanIssue() {
+		return "", time.Time{}, errors.New("no signing key configured")
+	}
+	now := time.Now()
+	expires := now.Add(t.ttl)
+	claims := Claims{
+		Scopes: scopes,
+		Tenant: tenant,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    t.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+	}
+	if t.audience != "" {
+		claims.Audience = jwt.ClaimStrings{t.audience}
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(t.algorithm), claims)
+	key := t.signKey
+	if t.keys != nil {
+		kid, private, err := t.keys.signing()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		token.Header["kid"] = kid
+		key = private
+	}
+	signed, err := token.SignedString(key)
+	return signed, expires, err
+}
+
+// Verify checks the signature, algorithm, expiry and, when configured, issuer and
+// audience of token and returns its claims
+func (t *Tokens) Verify(token string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{t.algorithm}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if t.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(t.issuer))
+	}
+	if t.audience != "" {
+		opts = append(opts, jwt.WithAudience(t.audience))
+	}
+	var claims Claims
+	if _, err := jwt.ParseWithClaims(token, &claims, t.verificationKey, opts...); err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &claims, nil
+}
+
+// verificationKey picks the key named by the kid header among the rotated keys,
+// or the configured key for tokens without one
+func (t *Tokens) verificationKey(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if t.keys != nil && kid != "" && kid != t.fileKID {
+		return t.keys.verifying(kid)
+	}
+	if t.verifyKey == nil {
+		return nil, errors.New("token has no key ID")
+	}
+	return t.verifyKey, nil
+}
+
+func readPublicKey(path string) (*rsa.PublicKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	return jwt.ParseRSAPublicKeyFromPEM(pem)
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read private key: %w", err)
+	}
+	return jwt.ParseRSAPrivateKeyFromPEM(pem)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newTestTokens(t *testing.T, cfg config.JWTConfig) *Tokens {
+	t.Helper()
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = "HS256"
+	}
+	if cfg.TTL == 0 {
+		cfg.TTL = time.Hour
+	}
+	tokens, err := NewTokens(cfg, testSecret)
+	if err != nil {
+		t.Fatalf("NewTokens: %v", err)
+	}
+	return tokens
+}
+
+func TestTokens_IssueAndVerify(t *testing.T) {
+	// Given tokens with an issuer and audience
+	tokens := newTestTokens(t, config.JWTConfig{Issuer: "cruder", Audience: "users-api"})
+
+	// When a token is issued and verified
+	token, expires, err := tokens.Issue("ci", []string{"admin"}, "acme")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	claims, err := tokens.Verify(token)
+
+	// Then the claims come back
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if claims.Subject != "ci" || claims.Tenant != "acme" || len(claims.Scopes) != 1 || claims.Scopes[0] != "admin" {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if time.Until(expires) < 59*time.Minute {
+		t.Errorf("expected the token to live for the TTL, expires at %v", expires)
+	}
+}
+
+func TestTokens_VerifyRejects(t *testing.T) {
+	tokens := newTestTokens(t, config.JWTConfig{Issuer: "cruder"})
+	sign := func(method jwt.SigningMethod, key any, claims jwt.Claims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return token
+	}
+	valid := func() jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Subject:   "ci",
+			Issuer:    "cruder",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		}
+	}
+
+	expired := valid()
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	noExpiry := valid()
+	noExpiry.ExpiresAt = nil
+	otherIssuer := valid()
+	otherIssuer.Issuer = "elsewhere"
+	noSubject := valid()
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"garbage", "not-a-token"},
+		{"wrong secret", sign(jwt.SigningMethodHS256, []byte("another secret of sufficient size"), valid())},
+		{"other algorithm", sign(jwt.SigningMethodHS512, []byte(testSecret), valid())},
+		{"unsigned", sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid())},
+		{"expired", sign(jwt.SigningMethodHS256, []byte(testSecret), expired)},
+		{"no expiry", sign(jwt.SigningMethodHS256, []byte(testSecret), noExpiry)},
+		{"other issuer", sign(jwt.SigningMethodHS256, []byte(testSecret), otherIssuer)},
+		{"no subject", sign(jwt.SigningMethodHS256, []byte(testSecret), noSubject)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tokens.Verify(tt.token); err == nil {
+				t.Error("expected the token to be rejected")
+			}
+		})
+	}
+}
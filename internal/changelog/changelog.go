@@ -0,0 +1,151 @@
+// Package changelog reads the API changelog embedded from api/changelog.yaml, so
+// clients can tell what changed between the server versions they talk to.
+package changelog
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unreleased names the changes not part of a release yet; it sorts after every
+// released version
+const Unreleased = "unreleased"
+
+// Version is a release and the changes it made to the API
+type Version struct {
+	// Version is a release tag such as v1.4.0, or Unreleased
+	Version string `yaml:"version" json:"version"`
+	// Date is the release day, YYYY-MM-DD; empty for Unreleased
+	Date    string   `yaml:"date" json:"date,omitempty"`
+	Changes []Change `yaml:"changes" json:"changes"`
+}
+
+// Change is one change to the API
+type Change struct {
+	// Type is added, changed, deprecated, removed, fixed or security
+	Type string `yaml:"type" json:"type"`
+	// Area is the part of the API changed, such as users or webhooks
+	Area    string `yaml:"area" json:"area"`
+	Summary string `yaml:"summary" json:"summary"`
+	// Breaking is set when existing clients must be updated
+	Breaking bool `yaml:"breaking" json:"breaking"`
+}
+
+var changeTypes = map[string]bool{
+	"added": true, "changed": true, "deprecated": true, "removed": true, "fixed": true, "security": true,
+}
+
+// Parse reads a changelog, checking that its versions are valid and listed newest
+// first and that its changes are complete
+func Parse(data []byte) ([]Version, error) {
+	var doc struct {
+		Versions []Version `yaml:"versions"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse changelog: %w", err)
+	}
+
+	for i, v := range doc.Versions {
+		if v.Version == Unreleased {
+			if i != 0 {
+				return nil, fmt.Errorf("changelog: %s must be the first version", Unreleased)
+			}
+			if v.Date != "" {
+				return nil, fmt.Errorf("changelog: %s has no date", Unreleased)
+			}
+		} else {
+			if _, err := ParseVersion(v.Version); err != nil || !strings.HasPrefix(v.Version, "v") || strings.Contains(v.Version, "-") {
+				return nil, fmt.Errorf("changelog: invalid version %q", v.Version)
+			}
+			if _, err := time.Parse(time.DateOnly, v.Date); err != nil {
+				return nil, fmt.Errorf("changelog: %s: invalid date %q", v.Version, v.Date)
+			}
+		}
+		if i > 0 && Compare(doc.Versions[i-1].Version, v.Version) <= 0 {
+			return nil, fmt.Errorf("changelog: %s must be listed after %s", doc.Versions[i-1].Version, v.Version)
+		}
+		if len(v.Changes) == 0 {
+			return nil, fmt.Errorf("changelog: %s lists no changes", v.Version)
+		}
+		for _, c := range v.Changes {
+			if !changeTypes[c.Type] {
+				return nil, fmt.Errorf("changelog: %s: invalid change type %q", v.Version, c.Type)
+			}
+			if c.Area == "" || c.Summary == "" {
+				return nil, fmt.Errorf("changelog: %s: change needs an area and a summary", v.Version)
+			}
+		}
+	}
+	return doc.Versions, nil
+}
+
+// Between returns the versions after from and up to and including to, newest
+// first. An empty bound is open; versions are compared with Compare.
+func Between(versions []Version, from, to string) []Version {
+	out := []Version{}
+	for _, v := range versions {
+		if from != "" && Compare(v.Version, from) <= 0 {
+			continue
+		}
+		if to != "" && Compare(v.Version, to) > 0 {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// ParseVersion returns the major, minor and patch numbers of vX.Y.Z, with or without
+// the v. A suffix, as in the v1.4.0-3-g4f1c2e9 git describe gives builds past a
+// tag, is accepted and ignored: such a build has the changes of v1.4.0.
+func ParseVersion(s string) ([3]int, error) {
+	var parts [3]int
+	core, _, _ := strings.Cut(strings.TrimPrefix(s, "v"), "-")
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return parts, fmt.Errorf("invalid version %q", s)
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || f != strconv.Itoa(n) {
+			return parts, fmt.Errorf("invalid version %q", s)
+		}
+		parts[i] = n
+	}
+	return parts, nil
+}
+
+// Valid reports whether s is a version Between accepts as a bound
+func Valid(s string) bool {
+	if s == Unreleased {
+		return true
+	}
+	_, err := ParseVersion(s)
+	return err == nil
+}
+
+// Compare orders two versions valid by Valid, returning -1, 0 or +1; Unreleased is
+// newer than every release
+func Compare(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == Unreleased:
+		return 1
+	case b == Unreleased:
+		return -1
+	}
+	pa, _ := ParseVersion(a)
+	pb, _ := ParseVersion(b)
+	for i := range pa {
+		if c := cmp.Compare(pa[i], pb[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
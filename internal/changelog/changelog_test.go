@@ -0,0 +1,94 @@
+package changelog
+
+import (
+	"strings"
+	"testing"
+
+	"cruder/api"
+)
+
+func TestParse_Embedded(t *testing.T) {
+	// When: Parsing the changelog the server embeds
+	versions, err := Parse(api.Changelog)
+
+	// Then: It is valid
+	if err != nil {
+		t.Fatalf("api/changelog.yaml: %v", err)
+	}
+	if len(versions) == 0 {
+		t.Error("api/changelog.yaml lists no versions")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{"unsorted", `
+versions:
+  - { version: v1.0.0, date: 2026-01-01, changes: [{ type: added, area: users, summary: a }] }
+  - { version: v1.1.0, date: 2026-02-01, changes: [{ type: added, area: users, summary: b }] }`, "must be listed after"},
+		{"unreleased not first", `
+versions:
+  - { version: v1.0.0, date: 2026-01-01, changes: [{ type: added, area: users, summary: a }] }
+  - { version: unreleased, changes: [{ type: added, area: users, summary: b }] }`, "must be the first"},
+		{"no v", `
+versions:
+  - { version: 1.0.0, date: 2026-01-01, changes: [{ type: added, area: users, summary: a }] }`, "invalid version"},
+		{"no date", `
+versions:
+  - { version: v1.0.0, changes: [{ type: added, area: users, summary: a }] }`, "invalid date"},
+		{"unknown type", `
+versions:
+  - { version: v1.0.0, date: 2026-01-01, changes: [{ type: improved, area: users, summary: a }] }`, "invalid change type"},
+		{"no summary", `
+versions:
+  - { version: v1.0.0, date: 2026-01-01, changes: [{ type: added, area: users }] }`, "needs an area and a summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			_, err := Parse([]byte(tt.doc))
+
+			// Then
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	// Given: A changelog with an unreleased version and three releases
+	versions := []Version{{Version: Unreleased}, {Version: "v1.10.0"}, {Version: "v1.2.0"}, {Version: "v1.1.0"}}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"all", "", "", "unreleased v1.10.0 v1.2.0 v1.1.0"},
+		{"after a release", "v1.1.0", "", "unreleased v1.10.0 v1.2.0"},
+		{"up to a release", "", "v1.2.0", "v1.2.0 v1.1.0"},
+		{"between", "v1.1.0", "v1.10.0", "v1.10.0 v1.2.0"},
+		{"numeric order", "v1.9.0", "", "unreleased v1.10.0"},
+		{"build past a tag", "v1.2.0", "v1.10.0-3-g4f1c2e9", "v1.10.0"},
+		{"from unreleased", Unreleased, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			var got []string
+			for _, v := range Between(versions, tt.from, tt.to) {
+				got = append(got, v.Version)
+			}
+
+			// Then: The versions after from and up to to are returned, newest first
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, strings.Join(got, " "))
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,11 +16,678 @@ type DatabaseConfig struct {
 	Port    int    `yaml:"port"`
 	Name    string `yaml:"name"`
 	SSLMode string `yaml:"sslmode"`
+	// AutoMigrate applies pending migrations, embedded in the binary, at startup
+	AutoMigrate bool `yaml:"auto_migrate"`
+	// ConnectRetry waits for a database that is still starting
+	ConnectRetry ConnectRetryConfig `yaml:"connect_retry"`
+}
+
+// ConnectRetryConfig retries the first connection to a database at startup
+type ConnectRetryConfig struct {
+	// Attempts is how many times to connect before giving up; 1 does not retry
+	Attempts int `yaml:"attempts"`
+	// Interval is the pause after the first failed attempt, doubled after each
+	// further one
+	Interval time.Duration `yaml:"interval"`
+	// MaxWait caps the time spent on all attempts
+	MaxWait time.Duration `yaml:"max_wait"`
+}
+
+// HTTPClientConfig holds settings for outbound HTTP calls made to integrations
+type HTTPClientConfig struct {
+	Timeout          time.Duration `yaml:"timeout"`
+	MaxRetries       int           `yaml:"max_retries"`
+	BackoffBase      time.Duration `yaml:"backoff_base"`
+	BackoffMax       time.Duration `yaml:"backoff_max"`
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+}
+
+// ServerConfig holds HTTP listener configuration
+type ServerConfig struct {
+	// Network is "tcp" (default) or "unix"
+	Network string `yaml:"network"`
+	// Address is the TCP listen address; defaults to ":$PORT" or ":8080"
+	Address string `yaml:"address"`
+	// AdminAddress is an internal TCP address for /metrics, /debug/pprof and
+	// /api/v1/admin; when empty those routes are served on the main listener
+	AdminAddress string `yaml:"admin_address"`
+	// GRPC serves the user API over gRPC on a listener of its own
+	GRPC GRPCConfig `yaml:"grpc"`
+	// BasePath prefixes every route, e.g. "/user-service" behind a shared ingress
+	BasePath string `yaml:"base_path"`
+	// TrustedProxies lists proxy IPs/CIDRs whose X-Forwarded-For and X-Real-IP
+	// headers are honored; empty trusts no proxy and uses the peer address
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// SocketPath and SocketMode apply when Network is "unix"
+	SocketPath string `yaml:"socket_path"`
+	SocketMode string `yaml:"socket_mode"`
+	// SystemdActivation inherits the listener from systemd (LISTEN_FDS) when available
+	SystemdActivation bool `yaml:"systemd_activation"`
+	// GracefulUpgrade re-executes the binary on SIGHUP, handing over the listener
+	GracefulUpgrade bool          `yaml:"graceful_upgrade"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// RequestTimeout is the deadline of each API request; database queries get the
+	// time left as their statement_timeout
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// MaxBodyBytes is the largest JSON request body accepted; multipart uploads
+	// have limits of their own
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+}
+
+// GRPCConfig serves the user service over gRPC, for internal clients generated
+// from api/proto. Calls are authenticated and authorized like REST requests.
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Address is the TCP listen address; GRPC_ADDRESS overrides
+	Address string `yaml:"address"`
+	// Reflection lets tools such as grpcurl list the services and their messages
+	Reflection bool `yaml:"reflection"`
+}
+
+// APIKeyConfig describes one accepted X-API-Key and its restrictions
+type APIKeyConfig struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key" secret:"true"`
+	// AllowedOrigins restricts browser use of the key to these origins
+	// (e.g. "https://app.example.com" or "https://*.example.com")
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// AllowedReferrers restricts the key to requests whose Referer starts with one of
+	// these prefixes
+	AllowedReferrers []string `yaml:"allowed_referrers"`
+	// SigningSecret enables HMAC request signatures for this key
+	SigningSecret string `yaml:"signing_secret" secret:"true"`
+	// RequireSignature rejects unsigned requests made with this key
+	RequireSignature bool `yaml:"require_signature"`
+	// Scopes grant optional capabilities to the key: "debug" allows the X-Debug
+	// header, "admin" implies every scope
+	Scopes []string `yaml:"scopes"`
+	// Tenant names the organisation the key belongs to, for policy decisions
+	Tenant string `yaml:"tenant"`
+}
+
+// SignatureConfig holds settings for HMAC-signed requests
+type SignatureConfig struct {
+	// MaxClockSkew is how far the signed timestamp may differ from server time
+	MaxClockSkew time.Duration `yaml:"max_clock_skew"`
+	// NonceTTL is how long used nonces are remembered; at least twice MaxClockSkew
+	NonceTTL time.Duration `yaml:"nonce_ttl"`
+}
+
+// AuthConfig holds authentication settings
+type AuthConfig struct {
+	// APIKeys are accepted in addition to the X_API_KEY environment variable
+	APIKeys   []APIKeyConfig  `yaml:"api_keys"`
+	Signature SignatureConfig `yaml:"signature"`
+	JWT       JWTConfig       `yaml:"jwt"`
+	// PersonalTokens lets users create their own bearer tokens at /api/v1/me/tokens
+	PersonalTokens PersonalTokensConfig `yaml:"personal_tokens"`
+	// ManagedKeys stores API keys in the database, managed at /api/v1/admin/api-keys
+	ManagedKeys ManagedKeysConfig `yaml:"managed_keys"`
+}
+
+// ManagedKeysConfig controls API keys created through the admin API. Only a hash of
+// each key is stored. With managed keys enabled the X_API_KEY environment variable
+// is optional: set it to bootstrap the first keys, then unset it.
+type ManagedKeysConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// CacheTTL is how long each instance remembers a verified key; revocations
+	// reach other instances at once with cache.broadcast, and within CacheTTL without
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// RotationGrace is how long a rotated key keeps working next to its replacement,
+	// so clients can switch without downtime
+	RotationGrace time.Duration `yaml:"rotation_grace"`
+}
+
+// PersonalTokensConfig controls the access tokens users create for automation. A
+// token authenticates as its owner with the scopes chosen when it was created.
+type PersonalTokensConfig struct {
+	// Enabled accepts personal tokens and mounts /api/v1/me/tokens; it needs
+	// auth.jwt, as users sign in with a JWT to manage their tokens
+	Enabled bool `yaml:"enabled"`
+	// DefaultTTL applies to tokens created without an expiry; MaxTTL bounds the
+	// expiry a user may choose
+	DefaultTTL time.Duration `yaml:"default_ttl"`
+	MaxTTL     time.Duration `yaml:"max_ttl"`
+	// MaxPerUser is how many tokens a user may hold, expired ones included
+	MaxPerUser int `yaml:"max_per_user"`
+}
+
+// JWTConfig enables bearer tokens in the Authorization header next to X-API-Key
+type JWTConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Algorithm is HS256 (shared secret) or RS256 (key pair)
+	Algorithm string `yaml:"algorithm"`
+	// Secret signs and verifies HS256 tokens; the JWT_SECRET environment variable
+	// takes precedence
+	Secret string `yaml:"secret" secret:"true"`
+	// PublicKeyFile verifies RS256 tokens; PrivateKeyFile, if set, signs the tokens
+	// issued by /api/v1/auth/login. Both are PEM files.
+	PublicKeyFile  string `yaml:"public_key_file"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	// Issuer and Audience are set on issued tokens and, when not empty, required on
+	// accepted ones
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// TTL is how long issued tokens are valid
+	TTL time.Duration `yaml:"ttl"`
+	// Required rejects X-API-Key once every client has moved to tokens
+	Required bool `yaml:"required"`
+	// Accounts may exchange a username and password for a token at /api/v1/auth/login
+	Accounts []AccountConfig `yaml:"accounts"`
+	// Rotation replaces the RS256 signing key on a schedule
+	Rotation KeyRotationConfig `yaml:"rotation"`
+}
+
+// KeyRotationConfig lets the service generate its own RS256 signing keys and replace
+// them on a schedule. Keys are stored in the database with their private half
+// encrypted by the master key and published at /.well-known/jwks.json.
+type KeyRotationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Every is how long a key signs tokens before the next one takes over
+	Every time.Duration `yaml:"every"`
+	// PublishAhead is how long a new key is published before it signs, so verifiers
+	// caching the JWKS know it by then
+	PublishAhead time.Duration `yaml:"publish_ahead"`
+	// CheckInterval is how often keys are rotated when due and reloaded from the
+	// database by every instance
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// AccountConfig is a person who can log in for a token
+type AccountConfig struct {
+	Username string `yaml:"username"`
+	// PasswordHash is a bcrypt hash, e.g. from "./main config hash-password"
+	PasswordHash string `yaml:"password_hash" secret:"true"`
+	// Scopes and Tenant are copied into the account's tokens, as for API keys
+	Scopes []string `yaml:"scopes"`
+	Tenant string   `yaml:"tenant"`
+}
+
+// RuntimeConfig holds Go runtime tuning applied at startup
+type RuntimeConfig struct {
+	// GOMAXPROCS overrides the CPU count; 0 derives it from the container CPU quota
+	GOMAXPROCS int `yaml:"gomaxprocs"`
+	// GOGC sets the GC target percentage; 0 keeps the Go default (100)
+	GOGC int `yaml:"gogc"`
+	// MemoryLimit is a soft memory limit such as "768MiB"; empty derives it from the
+	// container memory limit multiplied by MemoryLimitRatio
+	MemoryLimit      string  `yaml:"memory_limit"`
+	MemoryLimitRatio float64 `yaml:"memory_limit_ratio"`
+}
+
+// SLOConfig holds per-route latency budgets. Requests that exceed their budget are
+// logged as slow-request events and counted as violations.
+type SLOConfig struct {
+	// Default applies to routes without their own entry; 0 disables the check for them
+	Default time.Duration `yaml:"default"`
+	// Routes maps "METHOD /route/template" (without the base path) to its budget,
+	// e.g. "GET /api/v1/users/id/:id": 100ms
+	Routes map[string]time.Duration `yaml:"routes"`
+}
+
+// AnalyticsConfig controls per-key API usage aggregation
+type AnalyticsConfig struct {
+	// Enabled turns on request counting into the api_usage table
+	Enabled bool `yaml:"enabled"`
+	// FlushInterval is how often in-memory counts are written to the database
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// AnomalyThreshold limits how many mutations of one kind a key may make per window
+type AnomalyThreshold struct {
+	// Max alerts when a window holds more than this many mutations; 0 disables it
+	Max int `yaml:"max"`
+	// Factor alerts when a window exceeds the key's moving average by this multiple;
+	// 0 disables it
+	Factor float64 `yaml:"factor"`
+	// MinCount is the smallest window count the Factor check applies to
+	MinCount int `yaml:"min_count"`
+}
+
+// AnomalyConfig controls alerts on unusual per-key create/delete volume
+type AnomalyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Window is the counting interval
+	Window time.Duration `yaml:"window"`
+	// Cooldown is the minimum time between alerts for the same key and action
+	Cooldown time.Duration `yaml:"cooldown"`
+	// WebhookURL receives alerts; empty only logs them
+	WebhookURL string `yaml:"webhook_url" secret:"true"`
+	// WebhookFormat is "slack" (incoming webhook message) or "json"
+	WebhookFormat string `yaml:"webhook_format"`
+	// Thresholds are keyed by action: create, update or delete
+	Thresholds map[string]AnomalyThreshold `yaml:"thresholds"`
+}
+
+// UsersConfig holds user lifecycle settings
+type UsersConfig struct {
+	// PurgeAfter is how long soft-deleted users stay in the recycle bin
+	PurgeAfter time.Duration `yaml:"purge_after"`
+	// DeletionCheckInterval is how often scheduled deletions are checked for due entries
+	DeletionCheckInterval time.Duration `yaml:"deletion_check_interval"`
+	// ReservedUsernames cannot be registered; matched case-insensitively
+	ReservedUsernames []string `yaml:"reserved_usernames"`
+	// AllowedEmailDomains, when set, is the only list of accepted email domains;
+	// BlockedEmailDomains are always rejected. Subdomains match their parent domain.
+	AllowedEmailDomains []string `yaml:"allowed_email_domains"`
+	BlockedEmailDomains []string `yaml:"blocked_email_domains"`
+	// EmailNormalization decides which emails count as the same address
+	EmailNormalization EmailNormalizationConfig `yaml:"email_normalization"`
+	// MaxUsers caps the live users of the deployment, counted across regions; 0
+	// sets no limit. The count is checked in the transaction of each create or
+	// restore, so concurrent requests cannot overshoot it in one database.
+	MaxUsers int `yaml:"max_users"`
+	// EnumerationProtection keeps lookups from revealing which users exist
+	EnumerationProtection EnumerationProtectionConfig `yaml:"enumeration_protection"`
+}
+
+// EnumerationProtectionConfig makes lookups of a single user by username or ID
+// answer alike, and take as long, whether the user exists, does not, or may not
+// be seen by the caller
+type EnumerationProtectionConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MinResponseTime is how long every lookup takes at least; it hides the
+	// lookups' own latency only while above it
+	MinResponseTime time.Duration `yaml:"min_response_time"`
+	// UsernameLookups are allowed per client IP in each UsernameLookupWindow
+	UsernameLookups      int           `yaml:"username_lookups"`
+	UsernameLookupWindow time.Duration `yaml:"username_lookup_window"`
+}
+
+// EmailNormalizationConfig widens the normalization emails are compared in for
+// uniqueness and lookups; they are always trimmed and lower-cased, and stored as
+// entered
+type EmailNormalizationConfig struct {
+	// StripPlusTags ignores a "+tag" in the local part: a+news@example.com is a@example.com
+	StripPlusTags bool `yaml:"strip_plus_tags"`
+	// StripGmailDots ignores dots in the local part of gmail.com and googlemail.com
+	// addresses, which Gmail delivers to the same mailbox
+	StripGmailDots bool `yaml:"strip_gmail_dots"`
+}
+
+// StorageConfig selects where uploaded files are kept
+type StorageConfig struct {
+	// Backend is the storage implementation; only "local" is available
+	Backend string `yaml:"backend"`
+	// LocalPath is the root directory of the local backend
+	LocalPath string `yaml:"local_path"`
+}
+
+// DocumentsConfig limits files attached to users
+type DocumentsConfig struct {
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// AllowedTypes are media types accepted after content sniffing
+	AllowedTypes []string `yaml:"allowed_types"`
+}
+
+// ExportsConfig controls CSV exports of all users
+type ExportsConfig struct {
+	// ChunkRows is how many users are written between checkpoints
+	ChunkRows int `yaml:"chunk_rows"`
+	// PollInterval is how often the export job looks for exports to generate; each
+	// run works for at most this long and the next one resumes from the checkpoint
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// Retention is how long a finished export stays downloadable
+	Retention time.Duration `yaml:"retention"`
+}
+
+// BillingConfig controls the monthly billing reports written to storage
+type BillingConfig struct {
+	// CheckInterval is how often the billing job looks for a missing report of the
+	// previous month; the first check after a month ends generates it
+	CheckInterval time.Duration `yaml:"check_interval"`
+}
+
+// WebhooksConfig controls the webhooks registered at /api/v1/webhooks and the
+// worker that delivers user events to them
+type WebhooksConfig struct {
+	// Enabled mounts /api/v1/webhooks and queues and sends deliveries
+	Enabled bool `yaml:"enabled"`
+	// PollInterval is how often the worker looks for due deliveries
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// BatchSize is how many deliveries the worker claims at a time; they are sent one
+	// after another, so a claim lasts BatchSize times Timeout
+	BatchSize int `yaml:"batch_size"`
+	// Timeout bounds one attempt
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxAttempts is how often a delivery is tried before it is given up
+	MaxAttempts int `yaml:"max_attempts"`
+	// BackoffBase is the wait before the first retry; it doubles with every further
+	// attempt, up to BackoffMax
+	BackoffBase time.Duration `yaml:"backoff_base"`
+	BackoffMax  time.Duration `yaml:"backoff_max"`
+	// Retention is how long delivered and given-up deliveries are kept
+	Retention time.Duration `yaml:"retention"`
+}
+
+// ImportsConfig limits files uploaded to POST /users/import
+type ImportsConfig struct {
+	MaxSizeMB int `yaml:"max_size_mb"`
+}
+
+// ConsentsConfig lists the documents users accept, such as the terms of service
+type ConsentsConfig struct {
+	// Documents maps each document to its current version; only the current version
+	// can be accepted, and users who have not accepted it are reported as pending
+	Documents map[string]string `yaml:"documents"`
+}
+
+// FieldPolicyConfig restricts which callers can read user fields
+type FieldPolicyConfig struct {
+	// Fields maps a user field ("email", "custom_fields.<name>") to the scopes
+	// allowed to read it; callers without one of them get the field omitted
+	Fields map[string][]string `yaml:"fields"`
+}
+
+// CDCConfig describes the change data capture pipeline reading the users table
+type CDCConfig struct {
+	// Enabled checks at startup that the database is set up for logical replication
+	Enabled bool `yaml:"enabled"`
+	// Publication is the pgoutput publication the connector reads; empty when the
+	// connector creates its own
+	Publication string `yaml:"publication"`
+}
+
+// MessageBusConfig publishes user changes to Kafka or NATS. Changes are recorded
+// in an outbox table in the transaction that makes them and published from there,
+// at least once.
+type MessageBusConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Driver is "kafka", publishing through a Kafka REST Proxy, or "nats",
+	// publishing to JetStream
+	Driver string `yaml:"driver"`
+	// URL is the REST Proxy's base URL, or nats://host:port (tls://host:port for
+	// TLS); credentials go in its user info. The MESSAGE_BUS_URL environment
+	// variable takes precedence.
+	URL string `yaml:"url" secret:"true"`
+	// Topic is the Kafka topic, or the NATS subject, which a JetStream stream must
+	// capture
+	Topic string `yaml:"topic"`
+	// PollInterval is how often the outbox is checked for changes to publish
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// BatchSize is how many changes are published at a time
+	BatchSize int `yaml:"batch_size"`
+	// Timeout bounds publishing one batch
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// DualWriteConfig copies user writes to a second database during a migration window
+type DualWriteConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DSN of the secondary database; the DUAL_WRITE_DSN environment variable takes precedence
+	DSN string `yaml:"dsn" secret:"true"`
+	// Timeout bounds each write to the secondary database
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// ResidencyConfig keeps each tenant's users in the database cluster of its region
+type ResidencyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// HomeRegion names the region of database.dsn; users of tenants not listed in
+	// Tenants, and requests without a tenant, stay there
+	HomeRegion string `yaml:"home_region"`
+	// Regions holds the database of every other region, by name
+	Regions map[string]RegionConfig `yaml:"regions"`
+	// Tenants maps a tenant to the region its users' data must stay in
+	Tenants map[string]string `yaml:"tenants"`
+}
+
+// RegionConfig is the database cluster of one region
+type RegionConfig struct {
+	// DSN of the region's database; the REGION_<NAME>_DSN environment variable takes
+	// precedence, e.g. REGION_EU_DSN for region eu
+	DSN string `yaml:"dsn" secret:"true"`
+}
+
+// CacheConfig controls how in-memory caches, such as compiled rules, stay in step
+// across instances
+type CacheConfig struct {
+	// Broadcast sends invalidations to every instance on the same database through
+	// PostgreSQL LISTEN/NOTIFY; without it other instances catch up on their refresh
+	// interval
+	Broadcast bool `yaml:"broadcast"`
+	// NotifyUserChanges sends a compact notification for every user change on the
+	// cruder_user_changed channel, for sibling services that cache users; see
+	// package userchanges
+	NotifyUserChanges bool `yaml:"notify_user_changes"`
+}
+
+// ConsistencyConfig enables read-your-writes tokens for clients of lagging read models
+type ConsistencyConfig struct {
+	// ReadYourWrites returns an X-Consistency-Token header on successful mutations;
+	// reads echoing it skip read models that have not caught up with it
+	ReadYourWrites bool `yaml:"read_your_writes"`
+}
+
+// JobsConfig controls background jobs such as scheduled deletions
+type JobsConfig struct {
+	// Exclusive runs each job on one instance at a time, coordinated through
+	// PostgreSQL advisory locks
+	Exclusive bool `yaml:"exclusive"`
+}
+
+// RateLimitConfig caps how many requests each API key, or client IP for
+// unauthenticated requests, may make per window
+type RateLimitConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Requests allowed per client in each window
+	Requests int           `yaml:"requests"`
+	Window   time.Duration `yaml:"window"`
+	// Backend is "memory" (default), which limits each instance on its own, or
+	// "redis", which shares the counters so the limit holds across replicas
+	Backend string `yaml:"backend"`
+	// RedisURL, e.g. redis://:password@redis:6379/0; the RATE_LIMIT_REDIS_URL
+	// environment variable takes precedence
+	RedisURL string `yaml:"redis_url" secret:"true"`
+	// FailOpen allows requests while the backend is unreachable
+	FailOpen bool `yaml:"fail_open"`
+}
+
+// LoggingConfig controls the structured application log written to stderr
+type LoggingConfig struct {
+	// Level is the lowest level logged: debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is json or text for production, or pretty: colored single lines for
+	// reading in a terminal during development
+	Format string `yaml:"format"`
+	// Sampling thins out repetitive debug and info records
+	Sampling LogSamplingConfig `yaml:"sampling"`
+}
+
+// LogSamplingConfig limits how often the same debug or info message is logged;
+// warnings and errors are never sampled
+type LogSamplingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// First records with the same message are logged in each interval, then only
+	// every Thereafter-th
+	First      int           `yaml:"first"`
+	Thereafter int           `yaml:"thereafter"`
+	Interval   time.Duration `yaml:"interval"`
+}
+
+// TracingConfig exports OpenTelemetry traces over OTLP/HTTP
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Endpoint is the collector URL, e.g. http://otel-collector:4318; spans are
+	// posted to its /v1/traces path
+	Endpoint string `yaml:"endpoint"`
+	// SampleRatio is the share of new traces recorded, up to 1 (the default);
+	// requests that carry a sampled traceparent are always recorded
+	SampleRatio float64 `yaml:"sample_ratio"`
+	// ServiceName identifies this service in the tracing backend
+	ServiceName string `yaml:"service_name"`
+}
+
+// ClusterConfig controls how instances report themselves for /api/v1/admin/cluster
+type ClusterConfig struct {
+	// HeartbeatInterval is how often each instance records that it is alive; an
+	// instance missing three heartbeats is no longer listed
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+}
+
+// SearchConfig controls the user_search read table behind /api/v1/users/search
+type SearchConfig struct {
+	// RebuildInterval is how often the table is rebuilt from users, repairing changes
+	// the event consumer missed
+	RebuildInterval time.Duration `yaml:"rebuild_interval"`
+	// AutocompleteCacheTTL is how long each instance reuses an answer of
+	// /api/v1/users/autocomplete; changes made through other instances may take
+	// this long to show
+	AutocompleteCacheTTL time.Duration `yaml:"autocomplete_cache_ttl"`
+}
+
+// IdempotencyConfig controls how long Idempotency-Key responses are kept
+type IdempotencyConfig struct {
+	// TTL is how long a key answers retries with its first response; after it the
+	// key may be used for a new request
+	TTL time.Duration `yaml:"ttl"`
+	// PurgeInterval is how often expired keys are deleted
+	PurgeInterval time.Duration `yaml:"purge_interval"`
+}
+
+// MirrorConfig copies a share of live requests to a shadow deployment
+type MirrorConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Target is the shadow base URL, e.g. https://shadow.internal.example.com
+	Target string `yaml:"target"`
+	// Percentage of requests to mirror, 0-100
+	Percentage float64 `yaml:"percentage"`
+	// Methods limits mirroring to these HTTP methods; empty mirrors all
+	Methods []string `yaml:"methods"`
+	// Timeout bounds each mirrored request
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxInFlight caps concurrent mirrored requests; excess ones are dropped
+	MaxInFlight int `yaml:"max_in_flight"`
+	// MaxBodyBytes skips mirroring of requests with larger bodies
+	MaxBodyBytes int64 `yaml:"max_body_bytes"`
+}
+
+// ReadOnlyConfig sets the initial state of the emergency read-only mode, which can
+// also be switched at runtime on the admin API
+type ReadOnlyConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Reason  string `yaml:"reason"`
+	// RetryAfter is sent in the Retry-After header of rejected requests
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+// ApprovalsConfig enables the maker-checker workflow for sensitive user changes
+type ApprovalsConfig struct {
+	// Enabled holds deletes and email changes until a second admin approves them
+	Enabled bool `yaml:"enabled"`
+}
+
+// PolicyConfig delegates authorization decisions to an Open Policy Agent server
+type PolicyConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// URL is the OPA data API path of the decision, e.g.
+	// http://localhost:8181/v1/data/cruder/authz
+	URL string `yaml:"url"`
+	// Timeout bounds each decision; requests are not retried
+	Timeout time.Duration `yaml:"timeout"`
+	// FailOpen lets requests through when OPA cannot be reached
+	FailOpen bool `yaml:"fail_open"`
+}
+
+// FlagsConfig holds the rollout of each feature flag, by flag name
+type FlagsConfig map[string]FlagConfig
+
+// FlagConfig rolls a feature flag out to a share of callers
+type FlagConfig struct {
+	// Percentage of callers, by API key or user, the flag is on for, 0-100
+	Percentage float64 `yaml:"percentage"`
+}
+
+// BotsConfig flags or blocks automated registrations: requests without a
+// User-Agent, from addresses known for abuse, faster than a person, or filling a
+// honeypot field
+type BotsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Mode is "flag", which logs and counts suspicious requests, or "block", which
+	// also rejects them
+	Mode string `yaml:"mode"`
+	// MaxRate is how many registrations a client IP may make in RateWindow before
+	// more are suspicious; 0 counts none
+	MaxRate    int           `yaml:"max_rate"`
+	RateWindow time.Duration `yaml:"rate_window"`
+	// HoneypotField is a form field hidden from people, sent in the JSON body;
+	// empty checks none
+	HoneypotField string `yaml:"honeypot_field"`
+	// Blocklists are files of addresses and networks known for abuse, read again
+	// every BlocklistRefresh
+	Blocklists       []string      `yaml:"blocklists"`
+	BlocklistRefresh time.Duration `yaml:"blocklist_refresh"`
+	// ReputationURL is a reputation service asked about each client IP; empty asks
+	// none. ReputationTimeout bounds each question.
+	ReputationURL     string        `yaml:"reputation_url"`
+	ReputationTimeout time.Duration `yaml:"reputation_timeout"`
+}
+
+// RulesConfig controls scripted user rules managed at /api/v1/admin/rules
+type RulesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Timeout bounds the evaluation of a single rule
+	Timeout time.Duration `yaml:"timeout"`
+	// RefreshInterval is how often rules changed on other replicas are picked up
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// PluginsConfig selects compiled-in plugins and passes them settings
+type PluginsConfig struct {
+	// Enabled lists plugins to load, in order; each must be compiled in
+	Enabled []string `yaml:"enabled"`
+	// Settings holds free-form values per plugin name
+	Settings map[string]map[string]string `yaml:"settings"`
 }
 
 // Config holds all application configuration
 type Config struct {
-	Database DatabaseConfig `yaml:"database"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Users       UsersConfig       `yaml:"users"`
+	Server      ServerConfig      `yaml:"server"`
+	Auth        AuthConfig        `yaml:"auth"`
+	HTTPClient  HTTPClientConfig  `yaml:"http_client"`
+	Runtime     RuntimeConfig     `yaml:"runtime"`
+	SLO         SLOConfig         `yaml:"slo"`
+	Analytics   AnalyticsConfig   `yaml:"analytics"`
+	Anomaly     AnomalyConfig     `yaml:"anomaly"`
+	Storage     StorageConfig     `yaml:"storage"`
+	Documents   DocumentsConfig   `yaml:"documents"`
+	Exports     ExportsConfig     `yaml:"exports"`
+	Billing     BillingConfig     `yaml:"billing"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks"`
+	Imports     ImportsConfig     `yaml:"imports"`
+	Consents    ConsentsConfig    `yaml:"consents"`
+	Rules       RulesConfig       `yaml:"rules"`
+	Policy      PolicyConfig      `yaml:"policy"`
+	Bots        BotsConfig        `yaml:"bot_detection"`
+	FieldPolicy FieldPolicyConfig `yaml:"field_policy"`
+	Approvals   ApprovalsConfig   `yaml:"approvals"`
+	ReadOnly    ReadOnlyConfig    `yaml:"read_only"`
+	Mirror      MirrorConfig      `yaml:"mirror"`
+	Flags       FlagsConfig       `yaml:"feature_flags"`
+	DualWrite   DualWriteConfig   `yaml:"dual_write"`
+	Residency   ResidencyConfig   `yaml:"residency"`
+	CDC         CDCConfig         `yaml:"cdc"`
+	MessageBus  MessageBusConfig  `yaml:"message_bus"`
+	Search      SearchConfig      `yaml:"search"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Cache       CacheConfig       `yaml:"cache"`
+	Consistency ConsistencyConfig `yaml:"consistency"`
+	Jobs        JobsConfig        `yaml:"jobs"`
+	Cluster     ClusterConfig     `yaml:"cluster"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit"`
+	Logging     LoggingConfig     `yaml:"logging"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+	Plugins     PluginsConfig     `yaml:"plugins"`
+}
+
+// Default returns a configuration with defaults only, used when no config file is present
+func Default() *Config {
+	cfg := &Config{}
+	cfg.applyDefaults()
+	return cfg
 }
 
 // Load reads configuration from config.yaml and applies environment variable overrides
@@ -32,10 +701,22 @@ func Load(configPath string) (*Config, error) {
 	}
 
 	// Parse YAML
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Decrypt enc:/!vault values before decoding into the typed config
+	if err := decryptNodes(&root, keyProvider); err != nil {
+		return nil, fmt.Errorf("failed to decrypt config file: %w", err)
+	}
+
+	if len(root.Content) > 0 {
+		if err := root.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
 	// Apply environment variable overrides
 	if host := os.Getenv("DB_HOST"); host != "" {
 		cfg.Database.Host = host
@@ -57,9 +738,312 @@ func Load(configPath string) (*Config, error) {
 		cfg.Database.SSLMode = sslmode
 	}
 
+	if addr := os.Getenv("SERVER_ADDRESS"); addr != "" {
+		cfg.Server.Address = addr
+	}
+
+	if addr := os.Getenv("ADMIN_ADDRESS"); addr != "" {
+		cfg.Server.AdminAddress = addr
+	}
+
+	if addr := os.Getenv("GRPC_ADDRESS"); addr != "" {
+		cfg.Server.GRPC.Address = addr
+	}
+
+	if basePath := os.Getenv("BASE_PATH"); basePath != "" {
+		cfg.Server.BasePath = basePath
+	}
+
+	cfg.applyDefaults()
+
 	return cfg, nil
 }
 
+// applyDefaults fills in values that were not set in config.yaml
+func (c *Config) applyDefaults() {
+	if c.Server.Network == "" {
+		c.Server.Network = "tcp"
+	}
+	if c.Server.Address == "" {
+		port := os.Getenv("PORT")
+		if port == "" {
+			port = "8080"
+		}
+		c.Server.Address = ":" + port
+	}
+	c.Server.BasePath = normalizeBasePath(c.Server.BasePath)
+	if c.Server.GRPC.Address == "" {
+		c.Server.GRPC.Address = ":50051"
+	}
+	if c.Server.SocketMode == "" {
+		c.Server.SocketMode = "0660"
+	}
+	if c.Server.ShutdownTimeout == 0 {
+		c.Server.ShutdownTimeout = 15 * time.Second
+	}
+	if c.Server.RequestTimeout == 0 {
+		c.Server.RequestTimeout = 30 * time.Second
+	}
+	if c.Server.MaxBodyBytes == 0 {
+		c.Server.MaxBodyBytes = 1 << 20
+	}
+	if c.Auth.Signature.MaxClockSkew == 0 {
+		c.Auth.Signature.MaxClockSkew = 5 * time.Minute
+	}
+	if c.Auth.Signature.NonceTTL < 2*c.Auth.Signature.MaxClockSkew {
+		c.Auth.Signature.NonceTTL = 2 * c.Auth.Signature.MaxClockSkew
+	}
+	if c.Database.ConnectRetry.Attempts == 0 {
+		c.Database.ConnectRetry.Attempts = 10
+	}
+	if c.Database.ConnectRetry.Interval == 0 {
+		c.Database.ConnectRetry.Interval = 500 * time.Millisecond
+	}
+	if c.Database.ConnectRetry.MaxWait == 0 {
+		c.Database.ConnectRetry.MaxWait = time.Minute
+	}
+	if c.HTTPClient.Timeout == 0 {
+		c.HTTPClient.Timeout = 10 * time.Second
+	}
+	if c.HTTPClient.MaxRetries == 0 {
+		c.HTTPClient.MaxRetries = 3
+	}
+	if c.HTTPClient.BackoffBase == 0 {
+		c.HTTPClient.BackoffBase = 200 * time.Millisecond
+	}
+	if c.HTTPClient.BackoffMax == 0 {
+		c.HTTPClient.BackoffMax = 5 * time.Second
+	}
+	if c.HTTPClient.BreakerThreshold == 0 {
+		c.HTTPClient.BreakerThreshold = 5
+	}
+	if c.HTTPClient.BreakerCooldown == 0 {
+		c.HTTPClient.BreakerCooldown = 30 * time.Second
+	}
+	if c.Runtime.MemoryLimitRatio == 0 {
+		c.Runtime.MemoryLimitRatio = 0.9
+	}
+	if c.Users.PurgeAfter == 0 {
+		c.Users.PurgeAfter = 30 * 24 * time.Hour
+	}
+	if c.Users.DeletionCheckInterval == 0 {
+		c.Users.DeletionCheckInterval = time.Minute
+	}
+	if c.Users.EnumerationProtection.MinResponseTime == 0 {
+		c.Users.EnumerationProtection.MinResponseTime = 250 * time.Millisecond
+	}
+	if c.Users.EnumerationProtection.UsernameLookups == 0 {
+		c.Users.EnumerationProtection.UsernameLookups = 30
+	}
+	if c.Users.EnumerationProtection.UsernameLookupWindow == 0 {
+		c.Users.EnumerationProtection.UsernameLookupWindow = time.Minute
+	}
+	if c.Search.RebuildInterval == 0 {
+		c.Search.RebuildInterval = time.Hour
+	}
+	if c.Search.AutocompleteCacheTTL == 0 {
+		c.Search.AutocompleteCacheTTL = 30 * time.Second
+	}
+	if c.Idempotency.TTL == 0 {
+		c.Idempotency.TTL = 24 * time.Hour
+	}
+	if c.Idempotency.PurgeInterval == 0 {
+		c.Idempotency.PurgeInterval = time.Hour
+	}
+	if c.Cluster.HeartbeatInterval == 0 {
+		c.Cluster.HeartbeatInterval = 10 * time.Second
+	}
+	if c.RateLimit.Requests == 0 {
+		c.RateLimit.Requests = 600
+	}
+	if c.RateLimit.Window == 0 {
+		c.RateLimit.Window = time.Minute
+	}
+	if c.RateLimit.Backend == "" {
+		c.RateLimit.Backend = "memory"
+	}
+	if c.Tracing.SampleRatio == 0 {
+		c.Tracing.SampleRatio = 1
+	}
+	if c.Logging.Level == "" {
+		c.Logging.Level = "info"
+	}
+	if c.Logging.Format == "" {
+		c.Logging.Format = "json"
+	}
+	if c.Logging.Sampling.First == 0 {
+		c.Logging.Sampling.First = 100
+	}
+	if c.Logging.Sampling.Thereafter == 0 {
+		c.Logging.Sampling.Thereafter = 100
+	}
+	if c.Logging.Sampling.Interval == 0 {
+		c.Logging.Sampling.Interval = time.Second
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "cruder"
+	}
+	if c.Auth.JWT.Algorithm == "" {
+		c.Auth.JWT.Algorithm = "HS256"
+	}
+	if c.Auth.JWT.TTL == 0 {
+		c.Auth.JWT.TTL = time.Hour
+	}
+	if c.Auth.JWT.Rotation.Every == 0 {
+		c.Auth.JWT.Rotation.Every = 30 * 24 * time.Hour
+	}
+	if c.Auth.JWT.Rotation.PublishAhead == 0 {
+		c.Auth.JWT.Rotation.PublishAhead = time.Hour
+	}
+	if c.Auth.JWT.Rotation.CheckInterval == 0 {
+		c.Auth.JWT.Rotation.CheckInterval = 5 * time.Minute
+	}
+	if c.Auth.PersonalTokens.DefaultTTL == 0 {
+		c.Auth.PersonalTokens.DefaultTTL = 30 * 24 * time.Hour
+	}
+	if c.Auth.PersonalTokens.MaxTTL == 0 {
+		c.Auth.PersonalTokens.MaxTTL = 365 * 24 * time.Hour
+	}
+	if c.Auth.PersonalTokens.MaxPerUser == 0 {
+		c.Auth.PersonalTokens.MaxPerUser = 20
+	}
+	if c.Auth.ManagedKeys.CacheTTL == 0 {
+		c.Auth.ManagedKeys.CacheTTL = time.Minute
+	}
+	if c.Auth.ManagedKeys.RotationGrace == 0 {
+		c.Auth.ManagedKeys.RotationGrace = 24 * time.Hour
+	}
+	if c.DualWrite.Timeout == 0 {
+		c.DualWrite.Timeout = 2 * time.Second
+	}
+	if c.Mirror.Timeout == 0 {
+		c.Mirror.Timeout = 5 * time.Second
+	}
+	if c.Mirror.MaxInFlight == 0 {
+		c.Mirror.MaxInFlight = 100
+	}
+	if c.Mirror.MaxBodyBytes == 0 {
+		c.Mirror.MaxBodyBytes = 1 << 20
+	}
+	if c.ReadOnly.RetryAfter == 0 {
+		c.ReadOnly.RetryAfter = time.Minute
+	}
+	if c.Policy.Timeout == 0 {
+		c.Policy.Timeout = 500 * time.Millisecond
+	}
+	if c.Rules.Timeout == 0 {
+		c.Rules.Timeout = 50 * time.Millisecond
+	}
+	if c.Rules.RefreshInterval == 0 {
+		c.Rules.RefreshInterval = 30 * time.Second
+	}
+	if c.Analytics.FlushInterval == 0 {
+		c.Analytics.FlushInterval = time.Minute
+	}
+	if c.Storage.Backend == "" {
+		c.Storage.Backend = "local"
+	}
+	if c.Storage.LocalPath == "" {
+		c.Storage.LocalPath = "data/storage"
+	}
+	if c.Documents.MaxSizeMB == 0 {
+		c.Documents.MaxSizeMB = 10
+	}
+	if c.Documents.AllowedTypes == nil {
+		c.Documents.AllowedTypes = []string{"application/pdf", "image/png", "image/jpeg", "text/plain"}
+	}
+	if c.Imports.MaxSizeMB == 0 {
+		c.Imports.MaxSizeMB = 10
+	}
+	if c.Exports.ChunkRows == 0 {
+		c.Exports.ChunkRows = 10000
+	}
+	if c.Exports.PollInterval == 0 {
+		c.Exports.PollInterval = 30 * time.Second
+	}
+	if c.Exports.Retention == 0 {
+		c.Exports.Retention = 24 * time.Hour
+	}
+	if c.Billing.CheckInterval == 0 {
+		c.Billing.CheckInterval = time.Hour
+	}
+	if c.Bots.Mode == "" {
+		c.Bots.Mode = "flag"
+	}
+	if c.Bots.MaxRate == 0 {
+		c.Bots.MaxRate = 5
+	}
+	if c.Bots.RateWindow == 0 {
+		c.Bots.RateWindow = 10 * time.Second
+	}
+	if c.Bots.BlocklistRefresh == 0 {
+		c.Bots.BlocklistRefresh = time.Hour
+	}
+	if c.Bots.ReputationTimeout == 0 {
+		c.Bots.ReputationTimeout = 500 * time.Millisecond
+	}
+	if c.MessageBus.Driver == "" {
+		c.MessageBus.Driver = "kafka"
+	}
+	if c.MessageBus.Topic == "" {
+		c.MessageBus.Topic = "cruder.users"
+	}
+	if c.MessageBus.PollInterval == 0 {
+		c.MessageBus.PollInterval = time.Second
+	}
+	if c.MessageBus.BatchSize == 0 {
+		c.MessageBus.BatchSize = 100
+	}
+	if c.MessageBus.Timeout == 0 {
+		c.MessageBus.Timeout = 10 * time.Second
+	}
+	if c.Webhooks.PollInterval == 0 {
+		c.Webhooks.PollInterval = 5 * time.Second
+	}
+	if c.Webhooks.BatchSize == 0 {
+		c.Webhooks.BatchSize = 20
+	}
+	if c.Webhooks.Timeout == 0 {
+		c.Webhooks.Timeout = 10 * time.Second
+	}
+	if c.Webhooks.MaxAttempts == 0 {
+		c.Webhooks.MaxAttempts = 10
+	}
+	if c.Webhooks.BackoffBase == 0 {
+		c.Webhooks.BackoffBase = 30 * time.Second
+	}
+	if c.Webhooks.BackoffMax == 0 {
+		c.Webhooks.BackoffMax = time.Hour
+	}
+	if c.Webhooks.Retention == 0 {
+		c.Webhooks.Retention = 7 * 24 * time.Hour
+	}
+	if c.Anomaly.Window == 0 {
+		c.Anomaly.Window = time.Minute
+	}
+	if c.Anomaly.Cooldown == 0 {
+		c.Anomaly.Cooldown = 15 * time.Minute
+	}
+	if c.Anomaly.WebhookFormat == "" {
+		c.Anomaly.WebhookFormat = "slack"
+	}
+	if c.Anomaly.Thresholds == nil {
+		c.Anomaly.Thresholds = map[string]AnomalyThreshold{
+			"create": {Max: 300, Factor: 10, MinCount: 30},
+			"delete": {Max: 50, Factor: 5, MinCount: 10},
+		}
+	}
+}
+
+// normalizeBasePath turns "user-service/" into "/user-service"; "/" becomes empty
+func normalizeBasePath(p string) string {
+	p = strings.Trim(strings.TrimSpace(p), "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
+}
+
 // BuildDSN constructs PostgreSQL connection string from configuration and credentials
 func (c *Config) BuildDSN(username, password string) string {
 	return fmt.Sprintf(
@@ -73,22 +1057,101 @@ func (c *Config) BuildDSN(username, password string) string {
 	)
 }
 
-// GetDSN returns the PostgreSQL connection string with backward compatibility
+// DualWriteDSN returns the connection string of the dual-write secondary database
+func (c *Config) DualWriteDSN() (string, error) {
+	if dsn := os.Getenv("DUAL_WRITE_DSN"); dsn != "" {
+		return dsn, nil
+	}
+	if c.DualWrite.DSN == "" {
+		return "", fmt.Errorf("DUAL_WRITE_DSN environment variable or dual_write.dsn is required when dual_write is enabled")
+	}
+	return c.DualWrite.DSN, nil
+}
+
+// RegionDSN returns the connection string of a residency region's database
+func (c *Config) RegionDSN(name string) (string, error) {
+	env := "REGION_" + strings.ToUpper(name) + "_DSN"
+	if dsn := os.Getenv(env); dsn != "" {
+		return dsn, nil
+	}
+	if dsn := c.Residency.Regions[name].DSN; dsn != "" {
+		return dsn, nil
+	}
+	return "", fmt.Errorf("%s environment variable or residency.regions.%s.dsn is required", env, name)
+}
+
+// EnvAPIKeys returns the admin keys set in the environment: X_API_KEY, named
+// "default", and the comma-separated X_API_KEYS, whose entries are "key" or
+// "key:label". Unlabelled X_API_KEYS entries are named env-1, env-2... by position.
+// Listing the old and new key together rotates a key without downtime.
+func (c *Config) EnvAPIKeys() ([]APIKeyConfig, error) {
+	var keys []APIKeyConfig
+	if key := os.Getenv("X_API_KEY"); key != "" {
+		keys = append(keys, APIKeyConfig{Name: "default", Key: key, Scopes: []string{"admin"}})
+	}
+	list := os.Getenv("X_API_KEYS")
+	if strings.TrimSpace(list) == "" {
+		return keys, nil
+	}
+	names := map[string]bool{"default": len(keys) > 0}
+	for i, entry := range strings.Split(list, ",") {
+		key, label, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		key, label = strings.TrimSpace(key), strings.TrimSpace(label)
+		if key == "" {
+			return nil, fmt.Errorf("X_API_KEYS entry %d has no key", i+1)
+		}
+		if label == "" {
+			label = "env-" + strconv.Itoa(i+1)
+		}
+		if names[label] {
+			return nil, fmt.Errorf("X_API_KEYS label %q is not unique", label)
+		}
+		names[label] = true
+		keys = append(keys, APIKeyConfig{Name: label, Key: key, Scopes: []string{"admin"}})
+	}
+	return keys, nil
+}
+
+// JWTSecret returns the HS256 secret
+func (c *Config) JWTSecret() string {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return secret
+	}
+	return c.Auth.JWT.Secret
+}
+
+// RateLimitRedisURL returns the Redis URL of the shared rate limit backend
+func (c *Config) RateLimitRedisURL() (string, error) {
+	if url := os.Getenv("RATE_LIMIT_REDIS_URL"); url != "" {
+		return url, nil
+	}
+	if c.RateLimit.RedisURL == "" {
+		return "", fmt.Errorf("RATE_LIMIT_REDIS_URL environment variable or rate_limit.redis_url is required for the redis backend")
+	}
+	return c.RateLimit.RedisURL, nil
+}
+
+// MessageBusURL returns the URL of the Kafka REST Proxy or NATS server
+func (c *Config) MessageBusURL() (string, error) {
+	if url := os.Getenv("MESSAGE_BUS_URL"); url != "" {
+		return url, nil
+	}
+	if c.MessageBus.URL == "" {
+		return "", fmt.Errorf("MESSAGE_BUS_URL environment variable or message_bus.url is required when message_bus is enabled")
+	}
+	return c.MessageBus.URL, nil
+}
+
+// DSN returns the PostgreSQL connection string for this configuration
 // Priority:
 // 1. POSTGRES_DSN environment variable (for backward compatibility)
 // 2. Build DSN from config.yaml + environment variables
-func GetDSN(configPath string) (string, error) {
+func (c *Config) DSN() (string, error) {
 	// Check for backward compatibility with existing POSTGRES_DSN
 	if dsn := os.Getenv("POSTGRES_DSN"); dsn != "" {
 		return dsn, nil
 	}
 
-	// Load configuration from file
-	cfg, err := Load(configPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to load configuration: %w", err)
-	}
-
 	// Get credentials from environment variables
 	username := os.Getenv("DB_USER")
 	password := os.Getenv("DB_PASSWORD")
@@ -102,5 +1165,22 @@ func GetDSN(configPath string) (string, error) {
 	}
 
 	// Build and return DSN
-	return cfg.BuildDSN(username, password), nil
+	return c.BuildDSN(username, password), nil
+}
+
+// GetDSN returns the PostgreSQL connection string with backward compatibility,
+// loading configPath only when POSTGRES_DSN is not set
+func GetDSN(configPath string) (string, error) {
+	// Check for backward compatibility with existing POSTGRES_DSN
+	if dsn := os.Getenv("POSTGRES_DSN"); dsn != "" {
+		return dsn, nil
+	}
+
+	// Load configuration from file
+	cfg, err := Load(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	return cfg.DSN()
 }
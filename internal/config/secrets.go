@@ -0,0 +1,190 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Encrypted values in config.yaml are written either as a string with the "enc:"
+// prefix or with the !vault tag:
+//
+//	password: enc:3q2+7w...
+//	password: !vault 3q2+7w...
+//
+// The payload is base64(nonce || AES-256-GCM ciphertext).
+const (
+	encryptedPrefix = "enc:"
+	vaultTag        = "!vault"
+)
+
+// Master key sources, checked in order
+const (
+	masterKeyEnv     = "CRUDER_MASTER_KEY"
+	masterKeyFileEnv = "CRUDER_MASTER_KEY_FILE"
+)
+
+// ErrNoMasterKey is returned when config.yaml holds encrypted values but no master key is available
+var ErrNoMasterKey = errors.New("config contains encrypted values but no master key is set (" + masterKeyEnv + " or " + masterKeyFileEnv + ")")
+
+// KeyProvider supplies the 32-byte master key used to decrypt config values
+type KeyProvider interface {
+	MasterKey() ([]byte, error)
+}
+
+// EnvKeyProvider reads a base64 key from CRUDER_MASTER_KEY, or from the file named by
+// CRUDER_MASTER_KEY_FILE. The file variant suits keys delivered by a KMS/secret store
+// CSI driver or an init container that decrypts them.
+type EnvKeyProvider struct{}
+
+// MasterKey implements KeyProvider
+func (EnvKeyProvider) MasterKey() ([]byte, error) {
+	encoded := os.Getenv(masterKeyEnv)
+	if encoded == "" {
+		if path := os.Getenv(masterKeyFileEnv); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read master key file: %w", err)
+			}
+			encoded = string(data)
+		}
+	}
+	if strings.TrimSpace(encoded) == "" {
+		return nil, ErrNoMasterKey
+	}
+	return decodeMasterKey(encoded)
+}
+
+// keyProvider is used by Load; tests may replace it
+var keyProvider KeyProvider = EnvKeyProvider{}
+
+func decodeMasterKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// GenerateMasterKey returns a new random base64-encoded master key
+func GenerateMasterKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// EncryptValue encrypts plaintext for use in config.yaml and returns it with the "enc:" prefix
+func EncryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue reverses EncryptValue, for values the service encrypted itself
+func DecryptValue(key []byte, value string) (string, error) {
+	payload, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", errors.New("value is not encrypted")
+	}
+	return decryptValue(key, payload)
+}
+
+// decryptValue reverses EncryptValue; the payload must not include the prefix
+func decryptValue(key []byte, payload string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(payload))
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not valid base64: %w", err)
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is too short")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt value (wrong master key?)")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptNodes replaces encrypted scalars in the YAML tree with their plaintext.
+// The master key is only requested when at least one encrypted value is present.
+func decryptNodes(root *yaml.Node, provider KeyProvider) error {
+	var key []byte
+	var walk func(n *yaml.Node, path string) error
+	walk = func(n *yaml.Node, path string) error {
+		if n.Kind == yaml.ScalarNode {
+			payload, ok := encryptedPayload(n)
+			if !ok {
+				return nil
+			}
+			if key == nil {
+				k, err := provider.MasterKey()
+				if err != nil {
+					return err
+				}
+				key = k
+			}
+			plain, err := decryptValue(key, payload)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			n.Tag = "!!str"
+			n.Value = plain
+			n.Style = 0
+			return nil
+		}
+
+		for i, child := range n.Content {
+			childPath := path
+			// Mapping nodes alternate key/value; name values after their key
+			if n.Kind == yaml.MappingNode && i%2 == 1 {
+				childPath = strings.TrimPrefix(path+"."+n.Content[i-1].Value, ".")
+			}
+			if err := walk(child, childPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(root, "")
+}
+
+func encryptedPayload(n *yaml.Node) (string, bool) {
+	if n.Tag == vaultTag {
+		return n.Value, true
+	}
+	if strings.HasPrefix(n.Value, encryptedPrefix) {
+		return strings.TrimPrefix(n.Value, encryptedPrefix), true
+	}
+	return "", false
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type staticKeyProvider struct {
+	key []byte
+	err error
+}
+
+func (p staticKeyProvider) MasterKey() ([]byte, error) {
+	return p.key, p.err
+}
+
+func withKeyProvider(t *testing.T, p KeyProvider) {
+	t.Helper()
+	previous := keyProvider
+	keyProvider = p
+	t.Cleanup(func() { keyProvider = previous })
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func testMasterKey(t *testing.T) []byte {
+	t.Helper()
+	encoded, err := GenerateMasterKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	key, _ := base64.StdEncoding.DecodeString(encoded)
+	return key
+}
+
+func TestLoad_DecryptsEncryptedValues(t *testing.T) {
+	// Given: A config with an enc: value and a !vault value
+	key := testMasterKey(t)
+	withKeyProvider(t, staticKeyProvider{key: key})
+
+	encKey, err := EncryptValue(key, "partner-secret")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	encHost, _ := EncryptValue(key, "db.internal")
+	path := writeConfig(t, `
+database:
+  host: !vault `+encHost[len(encryptedPrefix):]+`
+  port: 5432
+auth:
+  api_keys:
+    - name: partner
+      key: "`+encKey+`"
+`)
+
+	// When: Loading the config
+	cfg, err := Load(path)
+
+	// Then: Values should be decrypted
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Database.Host != "db.internal" {
+		t.Errorf("expected decrypted host, got %q", cfg.Database.Host)
+	}
+	if len(cfg.Auth.APIKeys) != 1 || cfg.Auth.APIKeys[0].Key != "partner-secret" {
+		t.Errorf("expected decrypted api key, got %+v", cfg.Auth.APIKeys)
+	}
+}
+
+func TestLoad_EncryptedValueWithoutMasterKey(t *testing.T) {
+	// Given: An encrypted value and no master key
+	withKeyProvider(t, staticKeyProvider{err: ErrNoMasterKey})
+	path := writeConfig(t, "database:\n  host: enc:AAAA\n")
+
+	// When: Loading the config
+	_, err := Load(path)
+
+	// Then: Loading should fail with ErrNoMasterKey
+	if !errors.Is(err, ErrNoMasterKey) {
+		t.Errorf("expected ErrNoMasterKey, got %v", err)
+	}
+}
+
+func TestLoad_PlainConfigDoesNotNeedMasterKey(t *testing.T) {
+	// Given: A config without encrypted values and no master key
+	withKeyProvider(t, staticKeyProvider{err: ErrNoMasterKey})
+	path := writeConfig(t, "database:\n  host: localhost\n")
+
+	// When: Loading the config
+	cfg, err := Load(path)
+
+	// Then: It should load normally
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Database.Host != "localhost" {
+		t.Errorf("expected host localhost, got %q", cfg.Database.Host)
+	}
+}
+
+func TestLoad_WrongMasterKey(t *testing.T) {
+	// Given: A value encrypted with a different key
+	encrypted, _ := EncryptValue(testMasterKey(t), "secret")
+	withKeyProvider(t, staticKeyProvider{key: testMasterKey(t)})
+	path := writeConfig(t, "database:\n  host: "+encrypted+"\n")
+
+	// When: Loading the config
+	_, err := Load(path)
+
+	// Then: Decryption should fail
+	if err == nil {
+		t.Error("expected decryption error")
+	}
+}
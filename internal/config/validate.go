@@ -0,0 +1,520 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cruder/internal/residency"
+)
+
+// RedactedValue replaces secret values in Redacted output
+const RedactedValue = "[REDACTED]"
+
+// userResponseFields are the user JSON fields field_policy may restrict
+var userResponseFields = []string{"id", "uuid", "username", "email", "full_name", "custom_fields"}
+
+var passwordInDSN = regexp.MustCompile(`(password=)\S+|(://[^:/@]+:)[^@]+(@)`)
+
+// Validate checks the configuration for values that would fail at runtime and
+// returns all problems found, joined into one error
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Database.Host == "" {
+		add("database.host is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		add("database.port must be between 1 and 65535, got %d", c.Database.Port)
+	}
+	if c.Database.Name == "" {
+		add("database.name is required")
+	}
+	switch c.Database.SSLMode {
+	case "", "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		add("database.sslmode %q is not a valid PostgreSQL sslmode", c.Database.SSLMode)
+	}
+	if c.Database.ConnectRetry.Attempts < 1 {
+		add("database.connect_retry.attempts must be at least 1")
+	}
+	if c.Database.ConnectRetry.Interval <= 0 || c.Database.ConnectRetry.MaxWait <= 0 {
+		add("database.connect_retry.interval and max_wait must be positive")
+	}
+
+	switch c.Server.Network {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+			add("server.address %q: %v", c.Server.Address, err)
+		}
+	case "unix":
+		if c.Server.SocketPath == "" {
+			add("server.socket_path is required when server.network is unix")
+		}
+	default:
+		add("server.network must be tcp or unix, got %q", c.Server.Network)
+	}
+	if c.Server.AdminAddress != "" {
+		if _, _, err := net.SplitHostPort(c.Server.AdminAddress); err != nil {
+			add("server.admin_address %q: %v", c.Server.AdminAddress, err)
+		}
+	}
+	if c.Server.GRPC.Enabled {
+		if _, _, err := net.SplitHostPort(c.Server.GRPC.Address); err != nil {
+			add("server.grpc.address %q: %v", c.Server.GRPC.Address, err)
+		}
+	}
+	if _, err := strconv.ParseUint(c.Server.SocketMode, 8, 32); err != nil {
+		add("server.socket_mode %q is not an octal file mode", c.Server.SocketMode)
+	}
+	if c.Server.RequestTimeout <= 0 {
+		add("server.request_timeout must be positive")
+	}
+	if c.Server.MaxBodyBytes < 0 {
+		add("server.max_body_bytes must not be negative")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				add("server.trusted_proxies: %q is not an IP or CIDR", proxy)
+			}
+		}
+	}
+
+	// Keys from the environment are named too, and their names are taken
+	names := make(map[string]bool)
+	envKeys, err := c.EnvAPIKeys()
+	if err != nil {
+		add("%v", err)
+	}
+	for _, key := range envKeys {
+		names[key.Name] = true
+	}
+	for i, key := range c.Auth.APIKeys {
+		if key.Name == "" {
+			add("auth.api_keys[%d].name is required", i)
+		} else if names[key.Name] || key.Name == "default" {
+			add("auth.api_keys[%d].name %q is not unique", i, key.Name)
+		}
+		names[key.Name] = true
+		if len(key.Key) < 16 {
+			add("auth.api_keys[%d].key must be at least 16 characters", i)
+		}
+		if key.RequireSignature && key.SigningSecret == "" {
+			add("auth.api_keys[%d].require_signature needs a signing_secret", i)
+		}
+	}
+
+	if jwt := c.Auth.JWT; jwt.Enabled {
+		switch jwt.Algorithm {
+		case "HS256":
+			if len(c.JWTSecret()) < 32 {
+				add("auth.jwt.secret (or JWT_SECRET) must be at least 32 characters for HS256")
+			}
+		case "RS256":
+			if jwt.PublicKeyFile == "" && !jwt.Rotation.Enabled {
+				add("auth.jwt.public_key_file is required for RS256")
+			}
+			if len(jwt.Accounts) > 0 && jwt.PrivateKeyFile == "" && !jwt.Rotation.Enabled {
+				add("auth.jwt.private_key_file is required to issue tokens to accounts with RS256")
+			}
+		default:
+			add("auth.jwt.algorithm must be HS256 or RS256, got %q", jwt.Algorithm)
+		}
+		if jwt.TTL <= 0 {
+			add("auth.jwt.ttl must be positive")
+		}
+		if rotation := jwt.Rotation; rotation.Enabled {
+			if jwt.Algorithm != "RS256" {
+				add("auth.jwt.rotation needs algorithm RS256")
+			}
+			if rotation.PublishAhead <= 0 || rotation.Every <= rotation.PublishAhead {
+				add("auth.jwt.rotation.publish_ahead must be positive and shorter than rotation.every")
+			}
+			// Every instance must load a new key before it starts signing
+			if rotation.CheckInterval <= 0 || rotation.CheckInterval > rotation.PublishAhead {
+				add("auth.jwt.rotation.check_interval must be positive and at most rotation.publish_ahead")
+			}
+		}
+		accounts := make(map[string]bool)
+		for i, account := range jwt.Accounts {
+			if account.Username == "" {
+				add("auth.jwt.accounts[%d].username is required", i)
+			} else if accounts[account.Username] {
+				add("auth.jwt.accounts[%d].username %q is not unique", i, account.Username)
+			}
+			accounts[account.Username] = true
+			if !strings.HasPrefix(account.PasswordHash, "$2") {
+				add("auth.jwt.accounts[%d].password_hash must be a bcrypt hash", i)
+			}
+		}
+	}
+	if tokens := c.Auth.PersonalTokens; tokens.Enabled {
+		if !c.Auth.JWT.Enabled {
+			add("auth.personal_tokens needs auth.jwt to be enabled")
+		}
+		if tokens.DefaultTTL <= 0 || tokens.MaxTTL <= 0 {
+			add("auth.personal_tokens.default_ttl and max_ttl must be positive")
+		} else if tokens.DefaultTTL > tokens.MaxTTL {
+			add("auth.personal_tokens.default_ttl must not exceed max_ttl")
+		}
+		if tokens.MaxPerUser < 1 {
+			add("auth.personal_tokens.max_per_user must be at least 1")
+		}
+	}
+	if keys := c.Auth.ManagedKeys; keys.Enabled {
+		if keys.CacheTTL <= 0 || keys.RotationGrace <= 0 {
+			add("auth.managed_keys.cache_ttl and rotation_grace must be positive")
+		}
+	}
+
+	if c.Runtime.GOMAXPROCS < 0 {
+		add("runtime.gomaxprocs must not be negative")
+	}
+	if c.Runtime.MemoryLimitRatio <= 0 || c.Runtime.MemoryLimitRatio > 1 {
+		add("runtime.memory_limit_ratio must be in (0, 1], got %v", c.Runtime.MemoryLimitRatio)
+	}
+
+	if c.Users.PurgeAfter <= 0 {
+		add("users.purge_after must be positive")
+	}
+	if c.Users.MaxUsers < 0 {
+		add("users.max_users must not be negative")
+	}
+	if c.Users.DeletionCheckInterval <= 0 {
+		add("users.deletion_check_interval must be positive")
+	}
+	if e := c.Users.EnumerationProtection; e.Enabled {
+		if e.MinResponseTime <= 0 || e.UsernameLookupWindow <= 0 {
+			add("users.enumeration_protection.min_response_time and username_lookup_window must be positive")
+		}
+		if e.UsernameLookups < 1 {
+			add("users.enumeration_protection.username_lookups must be at least 1")
+		}
+	}
+	if c.SLO.Default < 0 {
+		add("slo.default must not be negative")
+	}
+	routes := make([]string, 0, len(c.SLO.Routes))
+	for route := range c.SLO.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method != strings.ToUpper(method) || !strings.HasPrefix(path, "/") {
+			add("slo.routes: %q must look like \"GET /api/v1/users/\"", route)
+		}
+		if c.SLO.Routes[route] <= 0 {
+			add("slo.routes[%q] must be positive", route)
+		}
+	}
+
+	if c.Storage.Backend != "local" {
+		add("storage.backend must be local, got %q", c.Storage.Backend)
+	}
+	if c.Documents.MaxSizeMB < 1 {
+		add("documents.max_size_mb must be at least 1")
+	}
+	if c.Imports.MaxSizeMB < 1 {
+		add("imports.max_size_mb must be at least 1")
+	}
+	if c.Exports.ChunkRows < 1 {
+		add("exports.chunk_rows must be at least 1")
+	}
+	if c.Exports.PollInterval <= 0 {
+		add("exports.poll_interval must be positive")
+	}
+	if c.Exports.Retention <= 0 {
+		add("exports.retention must be positive")
+	}
+	if c.Billing.CheckInterval <= 0 {
+		add("billing.check_interval must be positive")
+	}
+	if hooks := c.Webhooks; hooks.Enabled {
+		if hooks.PollInterval <= 0 || hooks.Timeout <= 0 || hooks.Retention <= 0 {
+			add("webhooks.poll_interval, timeout and retention must be positive")
+		}
+		if hooks.BatchSize < 1 || hooks.MaxAttempts < 1 {
+			add("webhooks.batch_size and max_attempts must be at least 1")
+		}
+		if hooks.BackoffBase <= 0 || hooks.BackoffMax < hooks.BackoffBase {
+			add("webhooks.backoff_base must be positive and not exceed backoff_max")
+		}
+	}
+	documents := make([]string, 0, len(c.Consents.Documents))
+	for document := range c.Consents.Documents {
+		documents = append(documents, document)
+	}
+	sort.Strings(documents)
+	for _, document := range documents {
+		if document == "" || len(document) > 50 {
+			add("consents.documents: %q must be 1 to 50 characters", document)
+		}
+		if version := c.Consents.Documents[document]; version == "" || len(version) > 50 {
+			add("consents.documents[%q] must be a version of 1 to 50 characters", document)
+		}
+	}
+	if c.Anomaly.Window <= 0 {
+		add("anomaly.window must be positive")
+	}
+	switch c.Anomaly.WebhookFormat {
+	case "slack", "json":
+	default:
+		add("anomaly.webhook_format must be slack or json, got %q", c.Anomaly.WebhookFormat)
+	}
+	for action, threshold := range c.Anomaly.Thresholds {
+		switch action {
+		case "create", "update", "delete":
+		default:
+			add("anomaly.thresholds: unknown action %q (expected create, update or delete)", action)
+		}
+		if threshold.Max < 0 || threshold.Factor < 0 || threshold.MinCount < 0 {
+			add("anomaly.thresholds[%q] must not be negative", action)
+		}
+	}
+	if bots := c.Bots; bots.Enabled {
+		if bots.Mode != "flag" && bots.Mode != "block" {
+			add("bot_detection.mode must be flag or block, got %q", bots.Mode)
+		}
+		if bots.MaxRate < 0 {
+			add("bot_detection.max_rate must not be negative")
+		}
+		if bots.RateWindow <= 0 || bots.BlocklistRefresh <= 0 || bots.ReputationTimeout <= 0 {
+			add("bot_detection.rate_window, blocklist_refresh and reputation_timeout must be positive")
+		}
+		if bots.ReputationURL != "" {
+			if u, err := url.Parse(bots.ReputationURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("bot_detection.reputation_url must be an http or https URL")
+			}
+		}
+	}
+	if bus := c.MessageBus; bus.Enabled {
+		if bus.Driver != "kafka" && bus.Driver != "nats" {
+			add("message_bus.driver must be kafka or nats, got %q", bus.Driver)
+		}
+		if bus.Topic == "" {
+			add("message_bus.topic is required")
+		}
+		if bus.PollInterval <= 0 || bus.Timeout <= 0 {
+			add("message_bus.poll_interval and timeout must be positive")
+		}
+		if bus.BatchSize < 1 {
+			add("message_bus.batch_size must be at least 1")
+		}
+	}
+	if c.DualWrite.Timeout <= 0 {
+		add("dual_write.timeout must be positive")
+	}
+	if c.Residency.Enabled {
+		if !residency.ValidName(c.Residency.HomeRegion) {
+			add("residency.home_region must be a lower-case name of up to 32 letters, digits and underscores, got %q", c.Residency.HomeRegion)
+		}
+		for name := range c.Residency.Regions {
+			switch {
+			case !residency.ValidName(name):
+				add("residency.regions: invalid region name %q", name)
+			case name == c.Residency.HomeRegion:
+				add("residency.regions must not list the home region %q; it uses database.dsn", name)
+			}
+		}
+		for tenant, region := range c.Residency.Tenants {
+			if _, ok := c.Residency.Regions[region]; !ok && region != c.Residency.HomeRegion {
+				add("residency.tenants[%q]: unknown region %q", tenant, region)
+			}
+		}
+		if c.DualWrite.Enabled {
+			add("residency cannot be combined with dual_write")
+		}
+		if c.Consistency.ReadYourWrites {
+			add("residency cannot be combined with consistency.read_your_writes")
+		}
+	}
+	if c.Mirror.Enabled {
+		if u, err := url.Parse(c.Mirror.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("mirror.target must be an http(s) URL when mirroring is enabled")
+		}
+	}
+	if c.Mirror.Percentage < 0 || c.Mirror.Percentage > 100 {
+		add("mirror.percentage must be between 0 and 100")
+	}
+	for name, flag := range c.Flags {
+		if flag.Percentage < 0 || flag.Percentage > 100 {
+			add("feature_flags.%s.percentage must be between 0 and 100", name)
+		}
+	}
+	if c.Mirror.Timeout <= 0 {
+		add("mirror.timeout must be positive")
+	}
+	if c.Mirror.MaxInFlight <= 0 {
+		add("mirror.max_in_flight must be positive")
+	}
+	if c.Mirror.MaxBodyBytes < 0 {
+		add("mirror.max_body_bytes must not be negative")
+	}
+	if c.ReadOnly.RetryAfter < 0 {
+		add("read_only.retry_after must not be negative")
+	}
+	if c.Policy.Enabled && c.Policy.URL == "" {
+		add("policy.url is required when policy is enabled")
+	}
+	if c.Policy.Timeout <= 0 {
+		add("policy.timeout must be positive")
+	}
+	fields := make([]string, 0, len(c.FieldPolicy.Fields))
+	for field := range c.FieldPolicy.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		scopes := c.FieldPolicy.Fields[field]
+		name, custom := strings.CutPrefix(field, "custom_fields.")
+		if !custom && !slices.Contains(userResponseFields, field) || custom && name == "" {
+			add("field_policy.fields: unknown user field %q", field)
+		}
+		if len(scopes) == 0 {
+			add("field_policy.fields.%s must list at least one scope", field)
+		}
+	}
+	if c.Search.RebuildInterval <= 0 {
+		add("search.rebuild_interval must be positive")
+	}
+	if c.Search.AutocompleteCacheTTL < 0 {
+		add("search.autocomplete_cache_ttl must not be negative")
+	}
+	if c.Idempotency.TTL <= 0 {
+		add("idempotency.ttl must be positive")
+	}
+	if c.Idempotency.PurgeInterval <= 0 {
+		add("idempotency.purge_interval must be positive")
+	}
+	if c.Cluster.HeartbeatInterval <= 0 {
+		add("cluster.heartbeat_interval must be positive")
+	}
+	if c.RateLimit.Requests <= 0 {
+		add("rate_limit.requests must be positive")
+	}
+	if c.RateLimit.Window <= 0 {
+		add("rate_limit.window must be positive")
+	}
+	if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "redis" {
+		add("rate_limit.backend must be memory or redis, got %q", c.RateLimit.Backend)
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		add("logging.level must be debug, info, warn or error, got %q", c.Logging.Level)
+	}
+	switch c.Logging.Format {
+	case "json", "text", "pretty":
+	default:
+		add("logging.format must be json, text or pretty, got %q", c.Logging.Format)
+	}
+	if c.Logging.Sampling.Enabled {
+		if c.Logging.Sampling.First < 0 || c.Logging.Sampling.Thereafter < 0 {
+			add("logging.sampling.first and thereafter must not be negative")
+		}
+		if c.Logging.Sampling.Interval <= 0 {
+			add("logging.sampling.interval must be positive")
+		}
+	}
+	if c.Tracing.Enabled {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("tracing.endpoint must be an http(s) URL when tracing is enabled")
+		}
+	}
+	if c.Tracing.SampleRatio <= 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be in (0, 1], got %v", c.Tracing.SampleRatio)
+	}
+	if c.Rules.Timeout <= 0 {
+		add("rules.timeout must be positive")
+	}
+	if c.Rules.RefreshInterval <= 0 {
+		add("rules.refresh_interval must be positive")
+	}
+	seenPlugins := make(map[string]bool, len(c.Plugins.Enabled))
+	for _, name := range c.Plugins.Enabled {
+		if seenPlugins[name] {
+			add("plugins.enabled lists %q twice", name)
+		}
+		seenPlugins[name] = true
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a deep copy of the configuration with every field tagged
+// `secret:"true"` replaced by RedactedValue
+func (c *Config) Redacted() *Config {
+	out := reflect.New(reflect.TypeOf(*c)).Elem()
+	copyRedacted(out, reflect.ValueOf(*c))
+	cfg := out.Interface().(Config)
+	return &cfg
+}
+
+func copyRedacted(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Struct:
+		for i := 0; i < src.NumField(); i++ {
+			field := src.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String {
+				if src.Field(i).String() != "" {
+					dst.Field(i).SetString(RedactedValue)
+				}
+				continue
+			}
+			copyRedacted(dst.Field(i), src.Field(i))
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeSlice(src.Type(), src.Len(), src.Len()))
+		for i := 0; i < src.Len(); i++ {
+			copyRedacted(dst.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.MakeMapWithSize(src.Type(), src.Len()))
+		for _, k := range src.MapKeys() {
+			v := reflect.New(src.Type().Elem()).Elem()
+			copyRedacted(v, src.MapIndex(k))
+			dst.SetMapIndex(k, v)
+		}
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		dst.Set(reflect.New(src.Type().Elem()))
+		copyRedacted(dst.Elem(), src.Elem())
+	default:
+		dst.Set(src)
+	}
+}
+
+// RedactDSN hides the password in a key=value or URL style connection string
+func RedactDSN(dsn string) string {
+	return passwordInDSN.ReplaceAllStringFunc(dsn, func(m string) string {
+		if strings.HasPrefix(m, "password=") {
+			return "password=" + RedactedValue
+		}
+		at := strings.LastIndex(m, "@")
+		colon := strings.LastIndex(m[:at], ":")
+		return m[:colon+1] + RedactedValue + m[at:]
+	})
+}
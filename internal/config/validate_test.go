@@ -0,0 +1,111 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate_ReportsAllProblems(t *testing.T) {
+	// Given: A config with several invalid values
+	cfg := Default()
+	cfg.Database.Port = 0
+	cfg.Server.Network = "udp"
+	cfg.Server.TrustedProxies = []string{"not-an-ip"}
+	cfg.Auth.APIKeys = []APIKeyConfig{{Name: "short", Key: "abc"}}
+	cfg.SLO.Routes = map[string]time.Duration{"/api/v1/users/": time.Second}
+	cfg.FieldPolicy.Fields = map[string][]string{"password": {"admin"}}
+	cfg.Flags = FlagsConfig{"v2_response_shape": {Percentage: 150}}
+
+	// When: Validating
+	err := cfg.Validate()
+
+	// Then: Every problem should be reported
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"database.host", "database.port", "server.network", "trusted_proxies", "auth.api_keys[0].key", "slo.routes", "field_policy.fields", "feature_flags.v2_response_shape.percentage"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestRedacted_HidesSecrets(t *testing.T) {
+	// Given: A config with secret values
+	cfg := Default()
+	cfg.Auth.APIKeys = []APIKeyConfig{{Name: "partner", Key: "super-secret-key", SigningSecret: "hmac"}}
+
+	// When: Redacting
+	redacted := cfg.Redacted()
+
+	// Then: Secrets are hidden in the copy but not in the original
+	if redacted.Auth.APIKeys[0].Key != RedactedValue || redacted.Auth.APIKeys[0].SigningSecret != RedactedValue {
+		t.Errorf("expected secrets to be redacted, got %+v", redacted.Auth.APIKeys[0])
+	}
+	if redacted.Auth.APIKeys[0].Name != "partner" {
+		t.Errorf("expected name to be kept, got %q", redacted.Auth.APIKeys[0].Name)
+	}
+	if cfg.Auth.APIKeys[0].Key != "super-secret-key" {
+		t.Error("expected original config to be unchanged")
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := map[string]string{
+		"host=db port=5432 user=app password=hunter2 dbname=x": "host=db port=5432 user=app password=[REDACTED] dbname=x",
+		"postgresql://app:hunter2@db:5432/x?sslmode=disable":   "postgresql://app:[REDACTED]@db:5432/x?sslmode=disable",
+	}
+	for input, expected := range tests {
+		if got := RedactDSN(input); got != expected {
+			t.Errorf("RedactDSN(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
+
+func TestEnvAPIKeys(t *testing.T) {
+	// Given: A key in X_API_KEY and a labelled and an unlabelled one in X_API_KEYS
+	t.Setenv("X_API_KEY", "legacy-key")
+	t.Setenv("X_API_KEYS", "new-key:rotation-2026, old-key")
+
+	// When
+	keys, err := Default().EnvAPIKeys()
+
+	// Then: Each key is named by its label, or its position, with full access
+	if err != nil {
+		t.Fatalf("EnvAPIKeys: %v", err)
+	}
+	want := map[string]string{"default": "legacy-key", "rotation-2026": "new-key", "env-2": "old-key"}
+	if len(keys) != len(want) {
+		t.Fatalf("expected %d keys, got %+v", len(want), keys)
+	}
+	for _, key := range keys {
+		if want[key.Name] != key.Key || len(key.Scopes) != 1 || key.Scopes[0] != "admin" {
+			t.Errorf("unexpected key %+v", key)
+		}
+	}
+}
+
+func TestValidate_RejectsClashingEnvAPIKeys(t *testing.T) {
+	// Given: An X_API_KEYS label also used by a key in config.yaml, and an empty entry
+	t.Setenv("X_API_KEYS", "first-key:partner")
+	cfg := Default()
+	cfg.Auth.APIKeys = []APIKeyConfig{{Name: "partner", Key: "a-key-of-sixteen-chars"}}
+
+	// When
+	err := cfg.Validate()
+
+	// Then
+	if err == nil || !strings.Contains(err.Error(), `auth.api_keys[0].name "partner" is not unique`) {
+		t.Errorf("expected a name clash, got %v", err)
+	}
+
+	// When: An entry has no key
+	t.Setenv("X_API_KEYS", "first-key:partner,:other")
+	_, err = Default().EnvAPIKeys()
+
+	// Then
+	if err == nil || !strings.Contains(err.Error(), "entry 2 has no key") {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+}
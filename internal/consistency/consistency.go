@@ -0,0 +1,33 @@
+// Package consistency carries read-your-writes tokens from clients to read models.
+// A token is the primary's write position, a PostgreSQL WAL LSN such as "0/16B3748",
+// taken after a mutation; a read carrying it must not be served from data that
+// does not yet include that position.
+package consistency
+
+import (
+	"context"
+	"regexp"
+)
+
+// Header carries tokens in mutation responses and in the requests echoing them
+const Header = "X-Consistency-Token"
+
+var tokenFormat = regexp.MustCompile(`^[0-9A-Fa-f]{1,8}/[0-9A-Fa-f]{1,8}$`)
+
+type tokenKey struct{}
+
+// Valid reports whether token is a well-formed LSN
+func Valid(token string) bool {
+	return tokenFormat.MatchString(token)
+}
+
+// WithToken attaches a client's token to ctx
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext returns the token attached to ctx, or "" when the caller sent none
+func TokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey{}).(string)
+	return token
+}
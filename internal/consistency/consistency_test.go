@@ -0,0 +1,33 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{"0/16B3748", true},
+		{"1A/0", true},
+		{"16B3748", false},
+		{"0/16B3748'; DROP TABLE users; --", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := Valid(tt.token); got != tt.want {
+			t.Errorf("Valid(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestTokenFromContext(t *testing.T) {
+	if got := TokenFromContext(context.Background()); got != "" {
+		t.Errorf("expected no token, got %q", got)
+	}
+	if got := TokenFromContext(WithToken(context.Background(), "0/1")); got != "0/1" {
+		t.Errorf("expected 0/1, got %q", got)
+	}
+}
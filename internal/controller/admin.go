@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"cruder/internal/tuning"
+
+	"github.com/gin-gonic/gin"
+)
+
+// usageDateLayout is the format of the from/to query parameters
+const usageDateLayout = "2006-01-02"
+
+type AdminController struct {
+	usage   service.UsageService
+	cluster service.ClusterService
+	users   service.UserService
+}
+
+func NewAdminController(usage service.UsageService, cluster service.ClusterService, users service.UserService) *AdminController {
+	return &AdminController{usage: usage, cluster: cluster, users: users}
+}
+
+// GET /api/v1/admin/runtime
+func (c *AdminController) GetRuntime(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, tuning.Effective())
+}
+
+// GET /api/v1/admin/cluster
+func (c *AdminController) GetCluster(ctx *gin.Context) {
+	cluster, err := c.cluster.Members(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, cluster)
+}
+
+// GET /api/v1/admin/stats
+func (c *AdminController) GetStats(ctx *gin.Context) {
+	quota, err := c.users.Quota(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, model.Stats{Users: quota})
+}
+
+// GET /api/v1/admin/analytics?from=2026-10-01&to=2026-10-14&api_key=partner&format=csv
+// Defaults to the last 30 days of all keys, as JSON.
+func (c *AdminController) GetUsage(ctx *gin.Context) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	from, err := parseUsageDate(ctx.Query("from"), today.AddDate(0, 0, -29))
+	if err != nil {
+		respondError(ctx, invalidParam("invalid from date, expected YYYY-MM-DD"))
+		return
+	}
+	to, err := parseUsageDate(ctx.Query("to"), today)
+	if err != nil {
+		respondError(ctx, invalidParam("invalid to date, expected YYYY-MM-DD"))
+		return
+	}
+
+	records, err := c.usage.Report(ctx.Request.Context(), from, to, ctx.Query("api_key"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	if ctx.Query("format") != "csv" {
+		ctx.JSON(http.StatusOK, records)
+		return
+	}
+
+	filename := "usage-" + from.Format(usageDateLayout) + "-" + to.Format(usageDateLayout) + ".csv"
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Status(http.StatusOK)
+
+	w := csv.NewWriter(ctx.Writer)
+	_ = w.Write([]string{"day", "api_key", "method", "route", "requests", "errors", "avg_duration_ms"})
+	for _, rec := range records {
+		_ = w.Write([]string{
+			rec.Day.Format(usageDateLayout),
+			rec.APIKey,
+			rec.Method,
+			rec.Route,
+			strconv.FormatInt(rec.Requests, 10),
+			strconv.FormatInt(rec.Errors, 10),
+			strconv.FormatFloat(rec.AvgDurationMs(), 'f', 1, 64),
+		})
+	}
+	w.Flush()
+}
+
+func parseUsageDate(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.Parse(usageDateLayout, value)
+}
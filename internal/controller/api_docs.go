@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"cruder/api"
+	"cruder/internal/sdkgen"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /swagger/openapi.yaml
+func GetOpenAPISpec(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "application/yaml; charset=utf-8", api.Spec)
+}
+
+// GET /swagger/index.html loads Swagger UI from unpkg.com and points it at the spec
+func GetSwaggerUI(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", api.SwaggerUI)
+}
+
+// specModel reads the embedded spec once; go test ./internal/sdkgen keeps it from
+// failing
+var specModel = sync.OnceValues(func() (*sdkgen.API, error) {
+	return sdkgen.Load(api.Spec)
+})
+
+// GET /api/v1/openapi/postman converts the spec into a Postman collection, which
+// Insomnia imports as well, with its baseUrl pointing at this server
+func GetPostmanCollection(ctx *gin.Context) {
+	spec, err := specModel()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	scheme := "http"
+	if ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	baseURL := scheme + "://" + ctx.Request.Host + strings.TrimSuffix(ctx.Request.URL.Path, "/openapi/postman")
+	collection, err := sdkgen.Postman(spec, baseURL)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Disposition", `attachment; filename="cruder.postman_collection.json"`)
+	ctx.Data(http.StatusOK, "application/json; charset=utf-8", collection)
+}
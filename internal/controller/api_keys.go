@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController lets admins manage the API keys stored in the database
+type APIKeyController struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyController(service service.APIKeyService) *APIKeyController {
+	return &APIKeyController{service: service}
+}
+
+// GET /api/v1/admin/api-keys
+func (c *APIKeyController) ListKeys(ctx *gin.Context) {
+	keys, err := c.service.List(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// POST /api/v1/admin/api-keys {"name": "...", "scopes": [...], "tenant": "...", "expires_at": "..."}
+func (c *APIKeyController) CreateKey(ctx *gin.Context) {
+	var req model.CreateAPIKeyRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	key, err := c.service.Create(ctx.Request.Context(), principalName(ctx), req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditAPIKey(ctx, "api_key.create", key.ID, map[string]string{"name": key.Name, "prefix": key.Prefix})
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// POST /api/v1/admin/api-keys/:id/rotate
+func (c *APIKeyController) RotateKey(ctx *gin.Context) {
+	key, err := c.service.Rotate(ctx.Request.Context(), principalName(ctx), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditAPIKey(ctx, "api_key.rotate", ctx.Param("id"), map[string]string{"replacement": key.ID, "prefix": key.Prefix})
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, key)
+}
+
+// POST /api/v1/admin/api-keys/:id/revoke
+func (c *APIKeyController) RevokeKey(ctx *gin.Context) {
+	if err := c.service.Revoke(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditAPIKey(ctx, "api_key.revoke", ctx.Param("id"), nil)
+	ctx.Status(http.StatusNoContent)
+}
+
+func auditAPIKey(ctx *gin.Context, action, keyID string, details map[string]string) {
+	audit.Record(audit.Event{
+		Action:     action,
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "api_key",
+		ResourceID: keyID,
+		Details:    details,
+	})
+}
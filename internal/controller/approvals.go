@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ApprovalController lets admins review deletes and email changes held for approval
+type ApprovalController struct {
+	service service.ApprovalService
+}
+
+func NewApprovalController(service service.ApprovalService) *ApprovalController {
+	return &ApprovalController{service: service}
+}
+
+// GET /api/v1/approvals?status=pending
+func (c *ApprovalController) ListChanges(ctx *gin.Context) {
+	changes, err := c.service.List(ctx.Request.Context(), ctx.Query("status"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changes)
+}
+
+// GET /api/v1/approvals/:id
+func (c *ApprovalController) GetChange(ctx *gin.Context) {
+	id, ok := changeID(ctx)
+	if !ok {
+		return
+	}
+
+	change, err := c.service.Get(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, change)
+}
+
+// POST /api/v1/approvals/:id/approve
+func (c *ApprovalController) ApproveChange(ctx *gin.Context) {
+	id, ok := changeID(ctx)
+	if !ok {
+		return
+	}
+
+	change, err := c.service.Approve(ctx.Request.Context(), id, principalName(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, change)
+}
+
+// POST /api/v1/approvals/:id/reject
+func (c *ApprovalController) RejectChange(ctx *gin.Context) {
+	id, ok := changeID(ctx)
+	if !ok {
+		return
+	}
+
+	change, err := c.service.Reject(ctx.Request.Context(), id, principalName(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, change)
+}
+
+func changeID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		respondError(ctx, invalidParam("invalid change id"))
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuthController struct {
+	service service.AuthService
+}
+
+func NewAuthController(service service.AuthService) *AuthController {
+	return &AuthController{service: service}
+}
+
+type loginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// POST /api/v1/auth/login {"username": "...", "password": "..."}
+func (c *AuthController) Login(ctx *gin.Context) {
+	var req loginRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBodyHint(err, "username and password are required"))
+		return
+	}
+
+	token, err := c.service.Login(req.Username, req.Password)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, token)
+}
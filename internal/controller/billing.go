@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BillingController serves the monthly billing reports to admins
+type BillingController struct {
+	service service.BillingService
+}
+
+func NewBillingController(service service.BillingService) *BillingController {
+	return &BillingController{service: service}
+}
+
+// GET /api/v1/admin/billing/:month
+func (c *BillingController) GetReport(ctx *gin.Context) {
+	month, ok := billingMonth(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.service.Get(ctx.Request.Context(), month)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// POST /api/v1/admin/billing/:month generates the report again, e.g. after usage
+// was corrected, or a partial one of the current month
+func (c *BillingController) GenerateReport(ctx *gin.Context) {
+	month, ok := billingMonth(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.service.Generate(ctx.Request.Context(), month)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	audit.Record(audit.Event{
+		Action:     "billing_report.generate",
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "billing_report",
+		ResourceID: report.Month,
+	})
+	ctx.JSON(http.StatusOK, report)
+}
+
+func billingMonth(ctx *gin.Context) (time.Time, bool) {
+	month, err := time.Parse(service.BillingMonthLayout, ctx.Param("month"))
+	if err != nil {
+		respondError(ctx, invalidParam("invalid month, expected YYYY-MM"))
+		return time.Time{}, false
+	}
+	return month, true
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// errTrailingData is returned for a body with more than one JSON value
+var errTrailingData = errors.New("unexpected data after the JSON value")
+
+// decodeJSON decodes the request body into v strictly, so a misspelt or unsupported
+// field is reported rather than silently dropped, as is anything after the value
+func decodeJSON(ctx *gin.Context, v any) error {
+	return decodeStrict(ctx.Request.Body, v)
+}
+
+// bindJSON is decodeJSON followed by the binding rules of v, in place of
+// ctx.ShouldBindJSON, which decodes leniently
+func bindJSON(ctx *gin.Context, v any) error {
+	if err := decodeJSON(ctx, v); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(v)
+}
+
+// bindRaw is bindJSON for a value already read, such as an item of a bulk request
+func bindRaw(data []byte, v any) error {
+	if err := decodeStrict(bytes.NewReader(data), v); err != nil {
+		return err
+	}
+	return binding.Validator.ValidateStruct(v)
+}
+
+func decodeStrict(r io.Reader, v any) error {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// Only whitespace may follow; a body cut off by middleware.BodyLimit is
+	// reported as such
+	var rest json.RawMessage
+	switch err := dec.Decode(&rest); {
+	case err == nil:
+		return errTrailingData
+	case !errors.Is(err, io.EOF):
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return err
+		}
+		return errTrailingData
+	}
+	return nil
+}
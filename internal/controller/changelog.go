@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+	"sync"
+
+	"cruder/api"
+	"cruder/internal/changelog"
+	"cruder/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// changelogVersions parses the embedded changelog once; go test ./internal/changelog
+// keeps it from failing
+var changelogVersions = sync.OnceValues(func() ([]changelog.Version, error) {
+	return changelog.Parse(api.Changelog)
+})
+
+// GET /api/v1/changelog?from=v1.2.0&to=v1.4.0 lists the API changes after from and
+// up to to, newest first; either bound may be left out
+func GetChangelog(ctx *gin.Context) {
+	from, to := ctx.Query("from"), ctx.Query("to")
+	for _, bound := range []string{from, to} {
+		if bound != "" && !changelog.Valid(bound) {
+			_ = ctx.Error(invalidParam("from and to must be versions such as v1.4.0, or unreleased"))
+			return
+		}
+	}
+	versions, err := changelogVersions()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{
+		"server_version": version.Get().Version,
+		"versions":       changelog.Between(versions, from, to),
+	})
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsentController records which versions of the terms of service and other
+// documents users accepted, and reports who still has to accept the current one
+type ConsentController struct {
+	service service.ConsentService
+}
+
+func NewConsentController(service service.ConsentService) *ConsentController {
+	return &ConsentController{service: service}
+}
+
+// POST /api/v1/users/:uuid/consents {"document": "terms_of_service", "version": "2026-10-01"}
+func (c *ConsentController) RecordConsent(ctx *gin.Context) {
+	var req model.ConsentRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBodyHint(err, "document and version are required"))
+		return
+	}
+
+	consent, created, err := c.service.Record(ctx.Request.Context(), ctx.Param("uuid"), req, middleware.ClientIP(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	if !created {
+		ctx.JSON(http.StatusOK, consent)
+		return
+	}
+	ctx.JSON(http.StatusCreated, consent)
+}
+
+// GET /api/v1/users/:uuid/consents
+func (c *ConsentController) ListConsents(ctx *gin.Context) {
+	consents, err := c.service.List(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, consents)
+}
+
+// GET /api/v1/users/consents/pending?document=terms_of_service&page=1&per_page=50 (admin)
+func (c *ConsentController) ListPending(ctx *gin.Context) {
+	document := ctx.Query("document")
+	if document == "" {
+		respondError(ctx, invalidParam("document is required"))
+		return
+	}
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(ctx, invalidParam("invalid page"))
+		return
+	}
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(service.DefaultPageSize)))
+	if err != nil || perPage < 1 {
+		respondError(ctx, invalidParam("invalid per_page"))
+		return
+	}
+
+	result, err := c.service.Pending(ctx.Request.Context(), document, page, perPage)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
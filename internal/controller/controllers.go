@@ -1,13 +1,82 @@
 package controller
 
-import "cruder/internal/service"
+import (
+	"cruder/internal/config"
+	"cruder/internal/service"
+)
 
 type Controller struct {
-	Users *UserController
+	Users              *UserController
+	Admin              *AdminController
+	RecycleBin         *RecycleBinController
+	ScheduledDeletions *ScheduledDeletionController
+	Notes              *NoteController
+	Consents           *ConsentController
+	Documents          *DocumentController
+	Exports            *ExportController
+	Imports            *ImportController
+	CustomFields       *CustomFieldController
+	SavedViews         *SavedViewController
+	Rules              *RuleController
+	Approvals          *ApprovalController
+	UserSearch         *UserSearchController
+	Search             *SearchController
+	Billing            *BillingController
+	// Auth is nil unless JWT login is configured
+	Auth *AuthController
+	// PersonalTokens is nil unless personal tokens are enabled
+	PersonalTokens *PersonalTokenController
+	// SigningKeys is nil unless signing keys are rotated
+	SigningKeys *SigningKeyController
+	// APIKeys is nil unless managed API keys are enabled
+	APIKeys *APIKeyController
+	// Webhooks is nil unless webhooks are enabled
+	Webhooks *WebhookController
 }
 
-func NewController(services *service.Service) *Controller {
+func NewController(services *service.Service, cfg *config.Config) *Controller {
+	fields := NewFieldPolicy(cfg.FieldPolicy)
+	var auth *AuthController
+	if services.Auth != nil {
+		auth = NewAuthController(services.Auth)
+	}
+	var personalTokens *PersonalTokenController
+	if services.PersonalTokens != nil {
+		personalTokens = NewPersonalTokenController(services.PersonalTokens)
+	}
+	var signingKeys *SigningKeyController
+	if services.SigningKeys != nil {
+		signingKeys = NewSigningKeyController(services.SigningKeys)
+	}
+	var apiKeys *APIKeyController
+	if services.APIKeys != nil {
+		apiKeys = NewAPIKeyController(services.APIKeys)
+	}
+	var webhooks *WebhookController
+	if services.Webhooks != nil {
+		webhooks = NewWebhookController(services.Webhooks)
+	}
 	return &Controller{
-		Users: NewUserController(services.Users),
+		Auth:               auth,
+		APIKeys:            apiKeys,
+		Webhooks:           webhooks,
+		SigningKeys:        signingKeys,
+		PersonalTokens:     personalTokens,
+		Users:              NewUserController(services.Users, fields, services.Approvals),
+		Admin:              NewAdminController(services.Usage, services.Cluster, services.Users),
+		RecycleBin:         NewRecycleBinController(services.RecycleBin),
+		ScheduledDeletions: NewScheduledDeletionController(services.ScheduledDeletions),
+		Notes:              NewNoteController(services.Notes),
+		Consents:           NewConsentController(services.Consents),
+		Documents:          NewDocumentController(services.Documents),
+		Exports:            NewExportController(services.Exports),
+		Imports:            NewImportController(services.Imports, int64(cfg.Imports.MaxSizeMB)<<20),
+		CustomFields:       NewCustomFieldController(services.CustomFields),
+		SavedViews:         NewSavedViewController(services.SavedViews, fields),
+		Rules:              NewRuleController(services.Rules),
+		Approvals:          NewApprovalController(services.Approvals),
+		UserSearch:         NewUserSearchController(services.UserSearch, fields),
+		Search:             NewSearchController(services.Search, fields),
+		Billing:            NewBillingController(services.Billing),
 	}
 }
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CustomFieldController manages the admin-defined custom field schema for users
+type CustomFieldController struct {
+	service service.CustomFieldService
+}
+
+func NewCustomFieldController(service service.CustomFieldService) *CustomFieldController {
+	return &CustomFieldController{service: service}
+}
+
+// GET /api/v1/admin/custom-fields
+func (c *CustomFieldController) ListCustomFields(ctx *gin.Context) {
+	fields, err := c.service.List(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, fields)
+}
+
+// POST /api/v1/admin/custom-fields
+func (c *CustomFieldController) CreateCustomField(ctx *gin.Context) {
+	var field model.CustomField
+	if err := bindJSON(ctx, &field); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	if err := c.service.Create(ctx.Request.Context(), &field); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, field)
+}
+
+// PUT /api/v1/admin/custom-fields/:name
+func (c *CustomFieldController) UpdateCustomField(ctx *gin.Context) {
+	var field model.CustomField
+	field.Name = ctx.Param("name")
+	if err := bindJSON(ctx, &field); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	field.Name = ctx.Param("name")
+
+	if err := c.service.Update(ctx.Request.Context(), &field); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, field)
+}
+
+// DELETE /api/v1/admin/custom-fields/:name
+func (c *CustomFieldController) DeleteCustomField(ctx *gin.Context) {
+	if err := c.service.Delete(ctx.Request.Context(), ctx.Param("name")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "custom field deleted successfully"})
+}
@@ -0,0 +1,18 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /.well-known/cruder-configuration serves doc, which is fixed for the life of
+// the process
+func GetDiscovery(doc model.Discovery) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, doc)
+	}
+}
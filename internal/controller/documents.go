@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"cruder/internal/apierror"
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for multipart headers around the uploaded file
+const multipartOverhead = 1 << 20
+
+// DocumentController serves files attached to users. Every access is audit logged.
+type DocumentController struct {
+	service service.DocumentService
+}
+
+func NewDocumentController(service service.DocumentService) *DocumentController {
+	return &DocumentController{service: service}
+}
+
+// GET /api/v1/users/:uuid/documents
+func (c *DocumentController) ListDocuments(ctx *gin.Context) {
+	uuid := ctx.Param("uuid")
+	docs, err := c.service.List(ctx.Request.Context(), uuid)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditDocument(ctx, "document.list", uuid, "", nil)
+	ctx.JSON(http.StatusOK, docs)
+}
+
+// POST /api/v1/users/:uuid/documents (multipart/form-data, field "file")
+func (c *DocumentController) UploadDocument(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.service.MaxSize()+multipartOverhead)
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondError(ctx, apierror.TooLarge("document_too_large", "document too large"))
+			return
+		}
+		respondError(ctx, apierror.Validation("invalid_body", "expected a multipart upload with a \"file\" field"))
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	uuid := ctx.Param("uuid")
+	doc, err := c.service.Upload(ctx.Request.Context(), uuid, header.Filename, header.Size, file, principalName(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditDocument(ctx, "document.upload", uuid, doc.ID, map[string]string{
+		"filename":     doc.Filename,
+		"content_type": doc.ContentType,
+		"size_bytes":   strconv.FormatInt(doc.SizeBytes, 10),
+		"sha256":       doc.SHA256,
+	})
+	ctx.JSON(http.StatusCreated, doc)
+}
+
+// GET /api/v1/users/:uuid/documents/:document_id
+func (c *DocumentController) DownloadDocument(ctx *gin.Context) {
+	uuid, id := ctx.Param("uuid"), ctx.Param("document_id")
+	doc, content, err := c.service.Open(ctx.Request.Context(), uuid, id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	defer func() { _ = content.Close() }()
+
+	auditDocument(ctx, "document.download", uuid, id, nil)
+
+	ctx.Header("Content-Type", doc.ContentType)
+	ctx.Header("Content-Length", strconv.FormatInt(doc.SizeBytes, 10))
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": doc.Filename}))
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "private, no-store")
+	ctx.Status(http.StatusOK)
+	if _, err := io.Copy(ctx.Writer, content); err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "failed to stream document", "document_id", id, "error", err)
+	}
+}
+
+// DELETE /api/v1/users/:uuid/documents/:document_id
+func (c *DocumentController) DeleteDocument(ctx *gin.Context) {
+	uuid, id := ctx.Param("uuid"), ctx.Param("document_id")
+	if err := c.service.Delete(ctx.Request.Context(), uuid, id); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditDocument(ctx, "document.delete", uuid, id, nil)
+	ctx.JSON(http.StatusOK, gin.H{"message": "document deleted successfully"})
+}
+
+func auditDocument(ctx *gin.Context, action, userUUID, documentID string, details map[string]string) {
+	audit.Record(audit.Event{
+		Action:     action,
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "document",
+		ResourceID: documentID,
+		UserUUID:   userUUID,
+		Details:    details,
+	})
+}
+
+// principalName returns the name of the authenticated API key, or "unknown"
+func principalName(ctx *gin.Context) string {
+	if p := middleware.GetPrincipal(ctx); p != nil {
+		return p.Name
+	}
+	return "unknown"
+}
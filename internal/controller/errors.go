@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// errInvalidBody answers requests whose body cannot be decoded or bound
+var errInvalidBody = apierror.Validation("invalid_body", "invalid request body")
+
+// invalidBody answers a body bindJSON failed on: one cut off by
+// middleware.BodyLimit with 413, one with a field the endpoint does not know with a
+// message naming it, and any other with errInvalidBody
+func invalidBody(err error) *apierror.Error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return apierror.TooLarge("body_too_large", "request body too large")
+	}
+	// encoding/json has no error type for unknown fields
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return apierror.Validation("invalid_body", "unknown field "+field)
+	}
+	return errInvalidBody
+}
+
+// invalidBodyHint is invalidBody, with message instead of the generic one of
+// errInvalidBody
+func invalidBodyHint(err error, message string) *apierror.Error {
+	if apiErr := invalidBody(err); apiErr != errInvalidBody {
+		return apiErr
+	}
+	return apierror.Validation("invalid_body", message)
+}
+
+// UserNotFound is the answer to a lookup of a user that does not exist
+var UserNotFound = serviceError(service.ErrUserNotFound)
+
+// serviceErrors gives the status kind and code of the errors services report.
+// Their messages are passed on to clients unchanged.
+var serviceErrors = map[error]struct {
+	kind error
+	code string
+}{
+	service.ErrUserNotFound:             {apierror.ErrNotFound, "user_not_found"},
+	service.ErrUsernameTaken:            {apierror.ErrConflict, "username_taken"},
+	service.ErrEmailTaken:               {apierror.ErrConflict, "email_taken"},
+	service.ErrVersionMismatch:          {apierror.ErrPreconditionFailed, "version_mismatch"},
+	service.ErrInvalidGroupBy:           {apierror.ErrValidation, "invalid_group_by"},
+	service.ErrInvalidCredentials:       {apierror.ErrUnauthorized, "invalid_credentials"},
+	service.ErrChangeNotFound:           {apierror.ErrNotFound, "change_not_found"},
+	service.ErrChangePending:            {apierror.ErrConflict, "change_pending"},
+	service.ErrChangeReviewed:           {apierror.ErrConflict, "change_reviewed"},
+	service.ErrOwnChange:                {apierror.ErrForbidden, "own_change"},
+	service.ErrInvalidStatus:            {apierror.ErrValidation, "invalid_status"},
+	service.ErrUnknownDocument:          {apierror.ErrValidation, "unknown_document"},
+	service.ErrConsentVersionNotCurrent: {apierror.ErrValidation, "consent_version_not_current"},
+	service.ErrCustomFieldNotFound:      {apierror.ErrNotFound, "custom_field_not_found"},
+	service.ErrCustomFieldExists:        {apierror.ErrConflict, "custom_field_exists"},
+	service.ErrDocumentNotFound:         {apierror.ErrNotFound, "document_not_found"},
+	service.ErrDocumentTooLarge:         {apierror.ErrTooLarge, "document_too_large"},
+	service.ErrUnsupportedContentType:   {apierror.ErrUnsupported, "unsupported_content_type"},
+	service.ErrExportNotFound:           {apierror.ErrNotFound, "export_not_found"},
+	service.ErrExportNotReady:           {apierror.ErrConflict, "export_not_ready"},
+	service.ErrUnsupportedImport:        {apierror.ErrUnsupported, "unsupported_import_format"},
+	service.ErrInvalidImportRow:         {apierror.ErrValidation, "invalid_row"},
+	service.ErrNoteNotFound:             {apierror.ErrNotFound, "note_not_found"},
+	service.ErrNoteBodyRequired:         {apierror.ErrValidation, "invalid_note"},
+	service.ErrNoteBodyTooLong:          {apierror.ErrValidation, "invalid_note"},
+	service.ErrTokenNotFound:            {apierror.ErrNotFound, "token_not_found"},
+	service.ErrTooManyTokens:            {apierror.ErrConflict, "too_many_tokens"},
+	service.ErrInvalidTokenName:         {apierror.ErrValidation, "invalid_token_name"},
+	service.ErrTokenExpiryPast:          {apierror.ErrValidation, "invalid_token_expiry"},
+	service.ErrTokenExpiryTooLong:       {apierror.ErrValidation, "invalid_token_expiry"},
+	service.ErrSigningKeyNotFound:       {apierror.ErrNotFound, "signing_key_not_found"},
+	service.ErrAPIKeyNotFound:           {apierror.ErrNotFound, "api_key_not_found"},
+	service.ErrInvalidAPIKeyName:        {apierror.ErrValidation, "invalid_api_key_name"},
+	service.ErrAPIKeyExpiryPast:         {apierror.ErrValidation, "invalid_api_key_expiry"},
+	service.ErrWebhookNotFound:          {apierror.ErrNotFound, "webhook_not_found"},
+	service.ErrInvalidWebhookURL:        {apierror.ErrValidation, "invalid_webhook_url"},
+	service.ErrInvalidWebhookEvents:     {apierror.ErrValidation, "invalid_webhook_events"},
+	service.ErrDescriptionTooLong:       {apierror.ErrValidation, "invalid_webhook_description"},
+	service.ErrBillingReportNotFound:    {apierror.ErrNotFound, "billing_report_not_found"},
+	service.ErrReportMonthInFuture:      {apierror.ErrValidation, "invalid_report_month"},
+	service.ErrRuleNotFound:             {apierror.ErrNotFound, "rule_not_found"},
+	service.ErrRuleExists:               {apierror.ErrConflict, "rule_exists"},
+	service.ErrInvalidViewName:          {apierror.ErrValidation, "invalid_view_name"},
+	service.ErrSavedViewNotFound:        {apierror.ErrNotFound, "saved_view_not_found"},
+	service.ErrSavedViewExists:          {apierror.ErrConflict, "saved_view_exists"},
+	service.ErrEffectiveDateNotFuture:   {apierror.ErrValidation, "invalid_effective_date"},
+	service.ErrNoDeletionScheduled:      {apierror.ErrNotFound, "deletion_not_scheduled"},
+	service.ErrInvalidDateRange:         {apierror.ErrValidation, "invalid_date_range"},
+	service.ErrDateRangeTooLarge:        {apierror.ErrValidation, "date_range_too_large"},
+	service.ErrInvalidBulkSize:          {apierror.ErrValidation, "invalid_bulk_size"},
+	service.ErrBulkRolledBack:           {apierror.ErrConflict, "bulk_rolled_back"},
+	service.ErrUserQuotaExceeded:        {apierror.ErrForbidden, "user_quota_exceeded"},
+}
+
+// deletedUserConflict names the deleted user a create collided with, so the client
+// can offer to restore it with POST /api/v1/users/:uuid/restore
+type deletedUserConflict struct {
+	UUID      string    `json:"uuid"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// serviceError turns an error returned by a service into the error the client is
+// told about. Errors it does not know are returned unchanged and answered with 500.
+func serviceError(err error) error {
+	var validationErr *service.ValidationError
+	var fieldErr *service.CustomFieldError
+	var ruleErr *service.RuleError
+	var importErr *service.ImportFileError
+	var sortErr *model.SortError
+	var deletedErr *service.DeletedUserError
+	switch {
+	case errors.As(err, &validationErr):
+		return apierror.Validation("invalid_user", err.Error()).WithDetails(validationErr.Errors)
+	case errors.As(err, &fieldErr):
+		return apierror.Validation("invalid_custom_field", err.Error())
+	case errors.As(err, &ruleErr):
+		return apierror.Validation("invalid_rule", err.Error())
+	case errors.As(err, &importErr):
+		return apierror.Validation("invalid_import", err.Error())
+	case errors.As(err, &sortErr):
+		return invalidParam(err.Error())
+	case errors.As(err, &deletedErr):
+		return apierror.Conflict("deleted_user_exists", err.Error()).WithDetails(deletedUserConflict{
+			UUID:      deletedErr.User.UUID,
+			DeletedAt: deletedErr.User.DeletedAt,
+		})
+	}
+	for sentinel, known := range serviceErrors {
+		if errors.Is(err, sentinel) {
+			return apierror.New(known.kind, known.code, sentinel.Error())
+		}
+	}
+	return err
+}
+
+// APIError is serviceError for servers other than the REST API, such as the gRPC
+// server, so they tell clients the same codes
+func APIError(err error) error {
+	return serviceError(err)
+}
+
+// respondError ends the request with err, which the Errors middleware renders;
+// service errors are mapped with serviceError first
+func respondError(ctx *gin.Context, err error) {
+	_ = ctx.Error(serviceError(err))
+}
+
+// invalidParam reports a malformed path or query parameter
+func invalidParam(message string) *apierror.Error {
+	return apierror.Validation("invalid_parameter", message)
+}
@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cruder/internal/apierror"
+	"cruder/internal/model"
+	"cruder/internal/service"
+)
+
+func TestServiceError(t *testing.T) {
+	cases := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"sentinel", service.ErrUserNotFound, http.StatusNotFound, "user_not_found", "users not found"},
+		{"wrapped sentinel", fmt.Errorf("restore 42: %w", service.ErrEmailTaken), http.StatusConflict, "email_taken", "email already exists"},
+		{"same message, not the sentinel", errors.New("users not found"), http.StatusInternalServerError, apierror.InternalCode, "internal server error"},
+		{"sort", &model.SortError{Field: "password", Reason: "is not sortable"}, http.StatusBadRequest, "invalid_parameter", `sort field "password" is not sortable`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			status, body := apierror.Render(serviceError(tc.err))
+
+			// Then: Only the sentinel itself decides the answer, with its own message
+			if status != tc.status || body.Code != tc.code || body.Message != tc.message {
+				t.Errorf("expected %d %s %q, got %d %s %q", tc.status, tc.code, tc.message, status, body.Code, body.Message)
+			}
+		})
+	}
+}
+
+func TestInvalidBody(t *testing.T) {
+	cases := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"too large", &http.MaxBytesError{Limit: 16}, http.StatusRequestEntityTooLarge, "request body too large"},
+		{"unknown field", errors.New(`json: unknown field "nickname"`), http.StatusBadRequest, `unknown field "nickname"`},
+		{"malformed", errors.New("unexpected EOF"), http.StatusBadRequest, "invalid request body"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			status, body := apierror.Render(invalidBody(tc.err))
+
+			// Then: Unknown fields are named, and the client is told when to send less
+			if status != tc.status || body.Message != tc.message {
+				t.Errorf("expected %d %q, got %d %q", tc.status, tc.message, status, body.Message)
+			}
+		})
+	}
+}
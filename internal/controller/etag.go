@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"cruder/internal/apierror"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setUserETag tags a response carrying one user with its version, for clients to
+// send back in If-Match when they update it
+func setUserETag(ctx *gin.Context, user *model.User) {
+	if user.Version > 0 {
+		ctx.Header("ETag", strconv.Quote(strconv.FormatInt(user.Version, 10)))
+	}
+}
+
+// ifMatchVersion sets the version patch was made against from the If-Match header,
+// which takes precedence over a version in the body. One of them is required;
+// If-Match: * updates whatever the current version is.
+func ifMatchVersion(ctx *gin.Context, patch *model.UserPatch) error {
+	tag := strings.TrimSpace(ctx.GetHeader("If-Match"))
+	switch {
+	case tag == "" && patch.Version == nil:
+		return apierror.PreconditionRequired("if_match_required", "If-Match with the version of the user is required")
+	case tag == "":
+		return nil
+	case tag == "*":
+		patch.Version = nil
+		return nil
+	}
+	// Weak tags compare like strong ones: the version is all there is to compare
+	opaque := strings.TrimPrefix(tag, "W/")
+	if len(opaque) < 2 || opaque[0] != '"' || opaque[len(opaque)-1] != '"' {
+		return invalidParam("invalid If-Match")
+	}
+	version, err := strconv.ParseInt(opaque[1:len(opaque)-1], 10, 64)
+	if err != nil || version < 1 {
+		return invalidParam("invalid If-Match")
+	}
+	patch.Version = &version
+	return nil
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportController serves CSV exports of all users. Starting and downloading an
+// export is audit logged.
+type ExportController struct {
+	service service.ExportService
+}
+
+func NewExportController(service service.ExportService) *ExportController {
+	return &ExportController{service: service}
+}
+
+// POST /api/v1/users/exports
+func (c *ExportController) StartExport(ctx *gin.Context) {
+	export, err := c.service.Start(ctx.Request.Context(), principalName(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditExport(ctx, "export.start", export.ID, nil)
+	ctx.Header("Location", ctx.Request.URL.Path+"/"+export.ID)
+	ctx.JSON(http.StatusAccepted, export)
+}
+
+// GET /api/v1/users/exports/:id
+func (c *ExportController) GetExport(ctx *gin.Context) {
+	export, err := c.service.Get(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, export)
+}
+
+// GET /api/v1/users/exports/:id/download honours Range and If-Range, so an
+// interrupted download continues from the last byte received
+func (c *ExportController) DownloadExport(ctx *gin.Context) {
+	id := ctx.Param("id")
+	export, content, err := c.service.Open(ctx.Request.Context(), id)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	defer func() { _ = content.Close() }()
+
+	var details map[string]string
+	if r := ctx.GetHeader("Range"); r != "" {
+		details = map[string]string{"range": r}
+	}
+	auditExport(ctx, "export.download", id, details)
+
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "users-" + id + ".csv"}))
+	ctx.Header("ETag", `"`+export.SHA256+`"`)
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "private, no-store")
+	if seeker, ok := content.(io.ReadSeeker); ok {
+		http.ServeContent(ctx.Writer, ctx.Request, "", *export.CompletedAt, seeker)
+		return
+	}
+
+	// Backends that cannot seek serve the whole file
+	ctx.Header("Content-Length", strconv.FormatInt(export.SizeBytes, 10))
+	ctx.Status(http.StatusOK)
+	if _, err := io.Copy(ctx.Writer, content); err != nil {
+		slog.ErrorContext(ctx.Request.Context(), "failed to stream export", "export_id", id, "error", err)
+	}
+}
+
+func auditExport(ctx *gin.Context, action, exportID string, details map[string]string) {
+	audit.Record(audit.Event{
+		Action:     action,
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "export",
+		ResourceID: exportID,
+		Details:    details,
+	})
+}
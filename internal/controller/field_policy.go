@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"cruder/internal/apierror"
+	"cruder/internal/config"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FieldPolicy hides user fields from callers that lack the scopes to read them, so
+// one endpoint can serve every audience. Fields are JSON names such as "email" or
+// "custom_fields.<name>"; unlisted fields are visible to everyone. A nil FieldPolicy
+// hides nothing.
+type FieldPolicy struct {
+	fields map[string][]string
+}
+
+// NewFieldPolicy returns nil when no field is restricted
+func NewFieldPolicy(cfg config.FieldPolicyConfig) *FieldPolicy {
+	if len(cfg.Fields) == 0 {
+		return nil
+	}
+	return &FieldPolicy{fields: cfg.Fields}
+}
+
+// hidden lists the fields the caller may not read, sorted for stable output
+func (fp *FieldPolicy) hidden(ctx *gin.Context) []string {
+	return fp.Hidden(middleware.GetPrincipal(ctx))
+}
+
+// Hidden lists the fields principal may not read, sorted for stable output
+func (fp *FieldPolicy) Hidden(principal *middleware.Principal) []string {
+	if fp == nil {
+		return nil
+	}
+	var out []string
+	for field, scopes := range fp.fields {
+		if !slices.ContainsFunc(scopes, principal.HasScope) {
+			out = append(out, field)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// hiddenQuery returns the first hidden field the query filters on, since filtering
+// would reveal its values; it returns "" when the query is allowed. The q search
+// reads username, email and full_name.
+func (fp *FieldPolicy) hiddenQuery(ctx *gin.Context, query model.UserQuery) string {
+	return fp.HiddenQuery(middleware.GetPrincipal(ctx), query)
+}
+
+// HiddenQuery is hiddenQuery for principal
+func (fp *FieldPolicy) HiddenQuery(principal *middleware.Principal, query model.UserQuery) string {
+	for _, field := range fp.Hidden(principal) {
+		if name, ok := strings.CutPrefix(field, "custom_fields."); ok {
+			if _, filtered := query.CustomFields[name]; filtered {
+				return field
+			}
+			continue
+		}
+		switch field {
+		case "custom_fields":
+			if len(query.CustomFields) > 0 {
+				return field
+			}
+		case "email":
+			if query.EmailDomain != "" || query.Q != "" {
+				return field
+			}
+		case "full_name":
+			if query.FullName != "" || query.Q != "" {
+				return field
+			}
+		case "username":
+			if query.Q != "" {
+				return field
+			}
+		}
+	}
+	return ""
+}
+
+// hiddenGroup reports whether grouping by groupBy would reveal a hidden field
+func (fp *FieldPolicy) hiddenGroup(ctx *gin.Context, groupBy string) bool {
+	name, ok := strings.CutPrefix(groupBy, "cf.")
+	if !ok {
+		return false
+	}
+	hidden := fp.hidden(ctx)
+	return slices.Contains(hidden, "custom_fields") || slices.Contains(hidden, "custom_fields."+name)
+}
+
+// redact maps a user, or a slice of users, to its response form for the caller
+func (fp *FieldPolicy) redact(ctx *gin.Context, v any) any {
+	hidden := fp.hidden(ctx)
+	if len(hidden) == 0 {
+		return v
+	}
+	out, err := redactFields(v, hidden)
+	if err != nil {
+		// Never fall back to the unredacted value
+		return apierror.Body{Code: apierror.InternalCode, Message: "failed to render response"}
+	}
+	return out
+}
+
+// redactFields removes hidden fields from the JSON form of v
+func redactFields(v any, hidden []string) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber() // keep int64 IDs exact
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	strip := func(obj map[string]any) {
+		for _, field := range hidden {
+			if name, ok := strings.CutPrefix(field, "custom_fields."); ok {
+				if values, ok := obj["custom_fields"].(map[string]any); ok {
+					delete(values, name)
+				}
+				continue
+			}
+			delete(obj, field)
+		}
+	}
+	switch d := doc.(type) {
+	case map[string]any:
+		strip(d)
+	case []any:
+		for _, item := range d {
+			if obj, ok := item.(map[string]any); ok {
+				strip(obj)
+			}
+		}
+	}
+	return doc, nil
+}
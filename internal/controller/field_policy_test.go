@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/config"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedactFields(t *testing.T) {
+	// Given: Users with an email and a restricted custom field
+	users := []model.User{
+		{ID: 9007199254740993, Username: "jdoe", Email: "jdoe@example.com", CustomFields: map[string]any{"ssn": "123", "plan": "pro"}},
+	}
+
+	// When: email and custom_fields.ssn are hidden
+	out, err := redactFields(users, []string{"custom_fields.ssn", "email"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: Only the hidden fields are removed and IDs keep their precision
+	raw, _ := json.Marshal(out)
+	expected := `[{"custom_fields":{"plan":"pro"},"full_name":"","id":9007199254740993,"username":"jdoe","uuid":""}]`
+	if string(raw) != expected {
+		t.Errorf("expected %s, got %s", expected, raw)
+	}
+}
+
+func TestFieldPolicy_Hidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fp := NewFieldPolicy(config.FieldPolicyConfig{Fields: map[string][]string{
+		"email":             {"users:pii"},
+		"custom_fields.ssn": {"users:pii", "hr"},
+	}})
+	keys := []config.APIKeyConfig{
+		{Name: "viewer", Key: "viewer-key"},
+		{Name: "hr", Key: "hr-key", Scopes: []string{"hr"}},
+		{Name: "ops", Key: "ops-key", Scopes: []string{"admin"}},
+	}
+
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{"viewer-key", `["custom_fields.ssn","email"]`},
+		{"hr-key", `["email"]`},
+		{"ops-key", `null`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			// Given: A request authenticated with the key under test
+			router := gin.New()
+			var hidden []string
+			router.GET("/", middleware.APIKeyAuth(keys), func(c *gin.Context) { hidden = fp.hidden(c) })
+
+			// When: The hidden fields are resolved
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Then: Fields are hidden unless the caller holds one of their scopes
+			if raw, _ := json.Marshal(hidden); string(raw) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, raw)
+			}
+		})
+	}
+}
+
+func TestFieldPolicy_HiddenQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	fp := NewFieldPolicy(config.FieldPolicyConfig{Fields: map[string][]string{"email": {"users:pii"}}})
+	keys := []config.APIKeyConfig{{Name: "viewer", Key: "viewer-key"}}
+
+	tests := []struct {
+		name     string
+		query    model.UserQuery
+		expected string
+	}{
+		{"full name filter", model.UserQuery{FullName: "doe"}, ""},
+		{"email domain filter", model.UserQuery{EmailDomain: "example.com"}, "email"},
+		{"free-text search reads the email", model.UserQuery{Q: "doe"}, "email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: A caller that may not read emails
+			router := gin.New()
+			var field string
+			router.GET("/", middleware.APIKeyAuth(keys), func(c *gin.Context) { field = fp.hiddenQuery(c, tt.query) })
+
+			// When
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-API-Key", "viewer-key")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Then: Filters that would reveal the email are refused
+			if field != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, field)
+			}
+		})
+	}
+}
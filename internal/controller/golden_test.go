@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+)
+
+// Run "go test ./internal/controller -run TestGoldenResponses -update" after an
+// intended change of the wire format, and review the diff of testdata/golden
+var updateGolden = flag.Bool("update", false, "rewrite the golden files of TestGoldenResponses")
+
+// Values that differ from run to run are replaced before comparing
+var (
+	goldenUUID      = regexp.MustCompile(`[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	goldenTimestamp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`)
+)
+
+// goldenHeaders are the response headers recorded in the golden files
+var goldenHeaders = []string{"Content-Type", "Location", "ETag"}
+
+// goldenResponse is what a golden file holds: the status, headers and body of one
+// response, with the body as indented JSON
+type goldenResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+func TestGoldenResponses(t *testing.T) {
+	const created = `{"username":"asmith","email":"asmith@example.com","full_name":"Anna Smith"}`
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		ifMatch string
+		err     error
+	}{
+		{"list_users", http.MethodGet, "/api/v1/users/", "", "", nil},
+		{"get_user_by_id", http.MethodGet, "/api/v1/users/id/1", "", "", nil},
+		{"get_user_by_username", http.MethodGet, "/api/v1/users/username/jdoe", "", "", nil},
+		{"create_user", http.MethodPost, "/api/v1/users/", created, "", nil},
+		{"bulk_create_users", http.MethodPost, "/api/v1/users/bulk", `{"users":[` + created + `,{"username":"jdoe","email":"jdoe@example.com"},{"username":1}]}`, "", nil},
+		{"update_user", http.MethodPatch, "/api/v1/users/0f8fad5b-d9cb-469f-a165-70867728950e", `{"full_name":"John Doe"}`, `"1"`, nil},
+		{"delete_user", http.MethodDelete, "/api/v1/users/0f8fad5b-d9cb-469f-a165-70867728950e", "", "", nil},
+		{"error_invalid_body", http.MethodPost, "/api/v1/users/", `{"username":`, "", nil},
+		{"error_invalid_user", http.MethodPost, "/api/v1/users/", created, "", &service.ValidationError{Errors: []model.FieldError{
+			{Field: "email", Code: "domain_not_allowed", Message: "email domain example.com is not allowed"},
+		}}},
+		{"error_not_found", http.MethodGet, "/api/v1/users/id/2", "", "", nil},
+		{"error_conflict", http.MethodPost, "/api/v1/users/", created, "", service.ErrUsernameTaken},
+		{"error_version_mismatch", http.MethodPatch, "/api/v1/users/0f8fad5b-d9cb-469f-a165-70867728950e", `{"full_name":"John Doe"}`, `"2"`, nil},
+		{"error_if_match_required", http.MethodPatch, "/api/v1/users/0f8fad5b-d9cb-469f-a165-70867728950e", `{"full_name":"John Doe"}`, "", nil},
+		{"error_internal", http.MethodGet, "/api/v1/users/", "", "", errors.New("connection reset")},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given: A service holding one user
+			svc := newMockUserService(model.User{ID: 1, UUID: "0f8fad5b-d9cb-469f-a165-70867728950e", Username: "jdoe",
+				Email: "jdoe@example.com", FullName: "John Doe", CustomFields: map[string]any{"team": "core"},
+				CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), UpdatedAt: time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC), Version: 1})
+			svc.err = tc.err
+			router := newUserTestRouter(svc)
+
+			// When
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Then: The response matches its golden file
+			got := goldenJSON(t, w)
+			path := filepath.Join("testdata", "golden", tc.name+".json")
+			if *updateGolden {
+				if err := os.WriteFile(path, got, 0o644); err != nil {
+					t.Fatalf("failed to write %s: %v", path, err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read %s (run with -update to create it): %v", path, err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("response differs from %s; if the change is intended, run with -update\ngot:\n%s\nwant:\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// goldenJSON renders a response as its golden file holds it
+func goldenJSON(t *testing.T, w *httptest.ResponseRecorder) []byte {
+	t.Helper()
+	response := goldenResponse{Status: w.Code, Headers: map[string]string{}}
+	for _, name := range goldenHeaders {
+		if value := w.Header().Get(name); value != "" {
+			response.Headers[name] = normalizeGolden(value)
+		}
+	}
+	if body := bytes.TrimSpace(w.Body.Bytes()); len(body) > 0 && string(body) != "null" {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, []byte(normalizeGolden(string(body))), "", "  "); err != nil {
+			t.Fatalf("response body is not JSON: %v: %s", err, body)
+		}
+		response.Body = indented.Bytes()
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(response); err != nil {
+		t.Fatalf("failed to render response: %v", err)
+	}
+	return out.Bytes()
+}
+
+func normalizeGolden(s string) string {
+	s = goldenUUID.ReplaceAllString(s, "<uuid>")
+	return goldenTimestamp.ReplaceAllString(s, "<timestamp>")
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"cruder/internal/apierror"
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"cruder/internal/timing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importExtensions maps file extensions to import formats
+var importExtensions = map[string]string{
+	".csv":    service.ImportCSV,
+	".jsonl":  service.ImportJSONL,
+	".ndjson": service.ImportJSONL,
+}
+
+// ImportController creates users from uploaded files. Every import is audit logged.
+type ImportController struct {
+	service service.ImportService
+	maxSize int64
+}
+
+// NewImportController creates the controller; uploads over maxSize bytes are refused
+func NewImportController(service service.ImportService, maxSize int64) *ImportController {
+	return &ImportController{service: service, maxSize: maxSize}
+}
+
+// POST /api/v1/users/import (multipart/form-data, field "file") creates the users
+// of a CSV or JSON Lines file and reports the lines that failed. The format is the
+// "format" field, or else follows the file extension.
+func (c *ImportController) ImportUsers(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, c.maxSize+multipartOverhead)
+	file, header, err := ctx.Request.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			respondError(ctx, apierror.TooLarge("import_too_large", "import file too large"))
+			return
+		}
+		respondError(ctx, apierror.Validation("invalid_body", "expected a multipart upload with a \"file\" field"))
+		return
+	}
+	defer func() { _ = file.Close() }()
+
+	format := strings.ToLower(ctx.PostForm("format"))
+	if format == "" {
+		format = importExtensions[strings.ToLower(path.Ext(header.Filename))]
+	}
+
+	if !bindReplaceDeleted(ctx) {
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	result, err := c.service.Import(ctx.Request.Context(), file, format)
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	report := model.ImportReport{
+		Rows:     result.Rows,
+		Created:  result.Created,
+		Failed:   len(result.Failures),
+		Failures: make([]model.ImportFailure, len(result.Failures)),
+	}
+	for i, failure := range result.Failures {
+		item := bulkCreateResult(i, failure.Err)
+		report.Failures[i] = model.ImportFailure{Line: failure.Line, Status: item.Status, Code: item.Code, Error: item.Error, Errors: item.Errors}
+	}
+
+	audit.Record(audit.Event{
+		Action:   "user.import",
+		Actor:    principalName(ctx),
+		ClientIP: middleware.ClientIP(ctx),
+		Resource: "user",
+		Details: map[string]string{
+			"filename": header.Filename,
+			"format":   format,
+			"rows":     strconv.Itoa(report.Rows),
+			"created":  strconv.Itoa(report.Created),
+			"failed":   strconv.Itoa(report.Failed),
+		},
+	})
+	middleware.CountMutations(ctx, report.Created)
+	ctx.JSON(http.StatusOK, report)
+}
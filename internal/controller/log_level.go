@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/audit"
+	"cruder/internal/logging"
+	"cruder/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogLevelController changes the log level at runtime, e.g. to debug an incident
+// without a redeploy
+type LogLevelController struct {
+	level *logging.LevelSwitch
+}
+
+func NewLogLevelController(level *logging.LevelSwitch) *LogLevelController {
+	return &LogLevelController{level: level}
+}
+
+// GET /api/v1/admin/log-level
+func (c *LogLevelController) GetLogLevel(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.level.State())
+}
+
+// PUT /api/v1/admin/log-level
+func (c *LogLevelController) SetLogLevel(ctx *gin.Context) {
+	var req struct {
+		Level string `json:"level" binding:"required"`
+		// Duration, e.g. "30m", reverts to the configured level after it
+		Duration string `json:"duration"`
+	}
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		respondError(ctx, apierror.Validation("invalid_body", "level must be debug, info, warn or error"))
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+			respondError(ctx, apierror.Validation("invalid_body", "duration must be a positive duration such as 30m"))
+			return
+		}
+	}
+
+	state := c.level.Set(level, principalName(ctx), duration)
+	audit.Record(audit.Event{
+		Action:   "log_level.set",
+		Actor:    state.ChangedBy,
+		ClientIP: middleware.ClientIP(ctx),
+		Resource: "log_level",
+		Details:  map[string]string{"level": state.Level, "duration": req.Duration},
+	})
+
+	ctx.JSON(http.StatusOK, state)
+}
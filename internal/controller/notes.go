@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NoteController serves internal notes on users. Its routes are admin-only and notes
+// are never included in other user responses.
+type NoteController struct {
+	service service.NoteService
+}
+
+func NewNoteController(service service.NoteService) *NoteController {
+	return &NoteController{service: service}
+}
+
+type noteRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// GET /api/v1/users/:uuid/notes
+func (c *NoteController) ListNotes(ctx *gin.Context) {
+	notes, err := c.service.List(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, notes)
+}
+
+// POST /api/v1/users/:uuid/notes
+func (c *NoteController) CreateNote(ctx *gin.Context) {
+	var req noteRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	note, err := c.service.Create(ctx.Request.Context(), ctx.Param("uuid"), principalName(ctx), req.Body)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, note)
+}
+
+// PATCH /api/v1/users/:uuid/notes/:note_id
+func (c *NoteController) UpdateNote(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("note_id"), 10, 64)
+	if err != nil {
+		respondError(ctx, invalidParam("invalid note id"))
+		return
+	}
+	var req noteRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	note, err := c.service.Update(ctx.Request.Context(), ctx.Param("uuid"), id, req.Body)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, note)
+}
+
+// DELETE /api/v1/users/:uuid/notes/:note_id
+func (c *NoteController) DeleteNote(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("note_id"), 10, 64)
+	if err != nil {
+		respondError(ctx, invalidParam("invalid note id"))
+		return
+	}
+
+	if err := c.service.Delete(ctx.Request.Context(), ctx.Param("uuid"), id); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "note deleted successfully"})
+}
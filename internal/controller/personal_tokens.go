@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/apierror"
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PersonalTokenController lets users manage the tokens they automate with. Only
+// callers signed in with a JWT may do so, so a leaked personal token cannot be used
+// to mint more; API keys do not belong to a user.
+type PersonalTokenController struct {
+	service service.PersonalTokenService
+}
+
+func NewPersonalTokenController(service service.PersonalTokenService) *PersonalTokenController {
+	return &PersonalTokenController{service: service}
+}
+
+// GET /api/v1/me/tokens
+func (c *PersonalTokenController) ListTokens(ctx *gin.Context) {
+	p, ok := signedInUser(ctx)
+	if !ok {
+		return
+	}
+	tokens, err := c.service.List(ctx.Request.Context(), p.Name)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// POST /api/v1/me/tokens {"name": "...", "scopes": [...], "expires_at": "..."}
+func (c *PersonalTokenController) CreateToken(ctx *gin.Context) {
+	p, ok := signedInUser(ctx)
+	if !ok {
+		return
+	}
+	var req model.CreatePersonalTokenRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	// A token never grants more than its creator holds
+	for _, scope := range req.Scopes {
+		if !p.HasScope(scope) {
+			respondError(ctx, apierror.Forbidden("scope_not_held", "cannot grant the "+scope+" scope"))
+			return
+		}
+	}
+
+	token, err := c.service.Create(ctx.Request.Context(), p.Name, p.Tenant, req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditPersonalToken(ctx, "personal_token.create", token.ID, map[string]string{"name": token.Name, "prefix": token.Prefix})
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, token)
+}
+
+// DELETE /api/v1/me/tokens/:id
+func (c *PersonalTokenController) RevokeToken(ctx *gin.Context) {
+	p, ok := signedInUser(ctx)
+	if !ok {
+		return
+	}
+	if err := c.service.Revoke(ctx.Request.Context(), p.Name, ctx.Param("id")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditPersonalToken(ctx, "personal_token.revoke", ctx.Param("id"), nil)
+	ctx.Status(http.StatusNoContent)
+}
+
+// signedInUser returns the caller if they signed in with a JWT, and responds with
+// 403 otherwise
+func signedInUser(ctx *gin.Context) (*middleware.Principal, bool) {
+	p := middleware.GetPrincipal(ctx)
+	if p == nil || p.Type != "jwt" {
+		respondError(ctx, apierror.Forbidden("sign_in_required", "personal tokens are managed by signed-in users"))
+		return nil, false
+	}
+	return p, true
+}
+
+func auditPersonalToken(ctx *gin.Context, action, tokenID string, details map[string]string) {
+	audit.Record(audit.Event{
+		Action:     action,
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "personal_token",
+		ResourceID: tokenID,
+		Details:    details,
+	})
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnlyController switches the emergency read-only mode at runtime
+type ReadOnlyController struct {
+	mode *middleware.ReadOnlyMode
+}
+
+func NewReadOnlyController(mode *middleware.ReadOnlyMode) *ReadOnlyController {
+	return &ReadOnlyController{mode: mode}
+}
+
+// GET /api/v1/admin/read-only
+func (c *ReadOnlyController) GetReadOnly(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, c.mode.State())
+}
+
+// PUT /api/v1/admin/read-only
+func (c *ReadOnlyController) SetReadOnly(ctx *gin.Context) {
+	var req struct {
+		Enabled *bool  `json:"enabled" binding:"required"`
+		Reason  string `json:"reason"`
+	}
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	now := time.Now().UTC()
+	state := middleware.ReadOnlyState{Enabled: *req.Enabled, Reason: req.Reason, ChangedBy: principalName(ctx), ChangedAt: &now}
+	c.mode.Set(state)
+	audit.Record(audit.Event{
+		Action:   "read_only.set",
+		Actor:    state.ChangedBy,
+		ClientIP: middleware.ClientIP(ctx),
+		Resource: "read_only_mode",
+		Details:  map[string]string{"enabled": strconv.FormatBool(state.Enabled), "reason": state.Reason},
+	})
+
+	ctx.JSON(http.StatusOK, state)
+}
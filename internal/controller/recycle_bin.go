@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RecycleBinController struct {
+	service service.RecycleBinService
+}
+
+func NewRecycleBinController(service service.RecycleBinService) *RecycleBinController {
+	return &RecycleBinController{service: service}
+}
+
+// GET /api/v1/users/deleted?page=1&per_page=50 (admin)
+func (c *RecycleBinController) ListDeleted(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(ctx, invalidParam("invalid page"))
+		return
+	}
+	perPage, err := strconv.Atoi(ctx.DefaultQuery("per_page", strconv.Itoa(service.DefaultPageSize)))
+	if err != nil || perPage < 1 {
+		respondError(ctx, invalidParam("invalid per_page"))
+		return
+	}
+
+	result, err := c.service.List(ctx.Request.Context(), page, perPage)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
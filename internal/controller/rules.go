@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RuleController manages the scripted validation and transformation rules for users
+type RuleController struct {
+	service service.RuleService
+}
+
+func NewRuleController(service service.RuleService) *RuleController {
+	return &RuleController{service: service}
+}
+
+// GET /api/v1/admin/rules
+func (c *RuleController) ListRules(ctx *gin.Context) {
+	rules, err := c.service.List(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rules)
+}
+
+// POST /api/v1/admin/rules
+func (c *RuleController) CreateRule(ctx *gin.Context) {
+	var rule model.Rule
+	if err := bindJSON(ctx, &rule); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	if err := c.service.Create(ctx.Request.Context(), &rule); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, rule)
+}
+
+// PUT /api/v1/admin/rules/:id
+func (c *RuleController) UpdateRule(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		respondError(ctx, invalidParam("invalid rule id"))
+		return
+	}
+	var rule model.Rule
+	if err := bindJSON(ctx, &rule); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	rule.ID = id
+
+	if err := c.service.Update(ctx.Request.Context(), &rule); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, rule)
+}
+
+// DELETE /api/v1/admin/rules/:id
+func (c *RuleController) DeleteRule(ctx *gin.Context) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		respondError(ctx, invalidParam("invalid rule id"))
+		return
+	}
+
+	if err := c.service.Delete(ctx.Request.Context(), id); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "rule deleted successfully"})
+}
@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"cruder/internal/timing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SavedViewController serves named user queries that clients share, e.g. dashboard segments
+type SavedViewController struct {
+	service service.SavedViewService
+	fields  *FieldPolicy
+}
+
+func NewSavedViewController(service service.SavedViewService, fields *FieldPolicy) *SavedViewController {
+	return &SavedViewController{service: service, fields: fields}
+}
+
+// GET /api/v1/users/views
+func (c *SavedViewController) ListViews(ctx *gin.Context) {
+	views, err := c.service.List(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, views)
+}
+
+// POST /api/v1/users/views
+func (c *SavedViewController) CreateView(ctx *gin.Context) {
+	var view model.SavedView
+	if err := bindJSON(ctx, &view); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	view.CreatedBy = principalName(ctx)
+
+	if err := c.service.Create(ctx.Request.Context(), &view); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, view)
+}
+
+// GET /api/v1/users/views/:name runs the view and returns the matching users
+func (c *SavedViewController) RunView(ctx *gin.Context) {
+	stop := timing.Track(ctx.Request.Context(), "service")
+	users, err := c.service.Run(ctx.Request.Context(), ctx.Param("name"))
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, users))
+}
+
+// DELETE /api/v1/users/views/:name
+func (c *SavedViewController) DeleteView(ctx *gin.Context) {
+	if err := c.service.Delete(ctx.Request.Context(), ctx.Param("name")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "saved view deleted successfully"})
+}
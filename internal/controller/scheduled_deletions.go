@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduledDeletionController struct {
+	service service.ScheduledDeletionService
+}
+
+func NewScheduledDeletionController(service service.ScheduledDeletionService) *ScheduledDeletionController {
+	return &ScheduledDeletionController{service: service}
+}
+
+type scheduleDeleteRequest struct {
+	EffectiveAt time.Time `json:"effective_at" binding:"required"`
+}
+
+// POST /api/v1/users/:uuid/schedule-delete
+func (c *ScheduledDeletionController) Schedule(ctx *gin.Context) {
+	var req scheduleDeleteRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBodyHint(err, "invalid request body, expected {\"effective_at\": \"<RFC 3339 time>\"}"))
+		return
+	}
+
+	deletion, err := c.service.Schedule(ctx.Request.Context(), ctx.Param("uuid"), req.EffectiveAt, principalName(ctx))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, deletion)
+}
+
+// GET /api/v1/users/:uuid/schedule-delete
+func (c *ScheduledDeletionController) Get(ctx *gin.Context) {
+	deletion, err := c.service.Get(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deletion)
+}
+
+// DELETE /api/v1/users/:uuid/schedule-delete
+func (c *ScheduledDeletionController) Cancel(ctx *gin.Context) {
+	if err := c.service.Cancel(ctx.Request.Context(), ctx.Param("uuid")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "scheduled deletion canceled"})
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"cruder/internal/service"
+	"cruder/internal/timing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	service service.SearchService
+	fields  *FieldPolicy
+}
+
+func NewSearchController(service service.SearchService, fields *FieldPolicy) *SearchController {
+	return &SearchController{service: service, fields: fields}
+}
+
+// GET /api/v1/search?q=smith&types=user&limit=20
+func (c *SearchController) Search(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(service.DefaultSearchLimit)))
+	if err != nil || limit < 1 {
+		respondError(ctx, invalidParam("invalid limit"))
+		return
+	}
+	var types []string
+	if t := ctx.Query("types"); t != "" {
+		types = strings.Split(t, ",")
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	results, err := c.service.Search(ctx.Request.Context(), ctx.Query("q"), types, limit)
+	stop()
+	switch {
+	case errors.Is(err, service.ErrEmptySearchTerm):
+		respondError(ctx, invalidParam("q is required"))
+		return
+	case errors.Is(err, service.ErrUnknownSearchType):
+		respondError(ctx, invalidParam(err.Error()))
+		return
+	case err != nil:
+		respondError(ctx, err)
+		return
+	}
+
+	// Users are shown as the user endpoints show them to the caller
+	for i := range results {
+		if results[i].Type == service.SearchTypeUser {
+			results[i].Item = c.fields.redact(ctx, results[i].Item)
+		}
+	}
+	ctx.JSON(http.StatusOK, results)
+}
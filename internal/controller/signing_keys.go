@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksCacheControl lets verifiers cache the JWKS for less than the default
+// rotation.publish_ahead, so they know a new key before it signs
+const jwksCacheControl = "public, max-age=300"
+
+// SigningKeyController publishes the JWT signing keys and lets admins revoke them
+type SigningKeyController struct {
+	service service.SigningKeyService
+}
+
+func NewSigningKeyController(service service.SigningKeyService) *SigningKeyController {
+	return &SigningKeyController{service: service}
+}
+
+// GET /.well-known/jwks.json
+func (c *SigningKeyController) GetJWKS(ctx *gin.Context) {
+	jwks, err := c.service.JWKS(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.Header("Cache-Control", jwksCacheControl)
+	ctx.JSON(http.StatusOK, jwks)
+}
+
+// GET /api/v1/admin/signing-keys
+func (c *SigningKeyController) ListKeys(ctx *gin.Context) {
+	keys, err := c.service.List(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, keys)
+}
+
+// POST /api/v1/admin/signing-keys/:kid/revoke
+func (c *SigningKeyController) RevokeKey(ctx *gin.Context) {
+	kid := ctx.Param("kid")
+	if err := c.service.Revoke(ctx.Request.Context(), kid); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	audit.Record(audit.Event{
+		Action:     "signing_key.revoke",
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "signing_key",
+		ResourceID: kid,
+	})
+	ctx.Status(http.StatusNoContent)
+}
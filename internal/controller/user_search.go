@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"cruder/internal/service"
+	"cruder/internal/timing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserSearchController struct {
+	service service.UserSearchService
+	fields  *FieldPolicy
+}
+
+func NewUserSearchController(service service.UserSearchService, fields *FieldPolicy) *UserSearchController {
+	return &UserSearchController{service: service, fields: fields}
+}
+
+// GET /api/v1/users/search?q=smith&limit=20
+func (c *UserSearchController) Search(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(service.DefaultSearchLimit)))
+	if err != nil || limit < 1 {
+		respondError(ctx, invalidParam("invalid limit"))
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	users, err := c.service.Search(ctx.Request.Context(), ctx.Query("q"), limit)
+	stop()
+	if err != nil {
+		if errors.Is(err, service.ErrEmptySearchTerm) {
+			respondError(ctx, invalidParam("q is required"))
+			return
+		}
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, users))
+}
+
+// GET /api/v1/users/autocomplete?prefix=jo&limit=10
+func (c *UserSearchController) Autocomplete(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(service.DefaultAutocompleteLimit)))
+	if err != nil || limit < 1 || limit > service.MaxAutocompleteLimit {
+		respondError(ctx, invalidParam(fmt.Sprintf("limit must be between 1 and %d", service.MaxAutocompleteLimit)))
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	suggestions, err := c.service.Autocomplete(ctx.Request.Context(), ctx.Query("prefix"), limit)
+	stop()
+	switch {
+	case errors.Is(err, service.ErrEmptySearchTerm):
+		respondError(ctx, invalidParam("prefix is required"))
+	case errors.Is(err, service.ErrPrefixTooLong):
+		respondError(ctx, invalidParam(fmt.Sprintf("prefix must be at most %d characters", service.MaxAutocompletePrefix)))
+	case err != nil:
+		respondError(ctx, err)
+	default:
+		ctx.JSON(http.StatusOK, suggestions)
+	}
+}
@@ -1,104 +1,397 @@
 package controller
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"cruder/internal/apierror"
+	"cruder/internal/middleware"
 	"cruder/internal/model" // Task3
 	"cruder/internal/service"
+	"cruder/internal/timing"
 
 	"github.com/gin-gonic/gin"
 	//"log"
 )
 
 type UserController struct {
-	service service.UserService
+	service   service.UserService
+	fields    *FieldPolicy
+	approvals service.ApprovalService
 }
 
-func NewUserController(service service.UserService) *UserController {
-	return &UserController{service: service}
+// NewUserController creates the controller; fields may be nil to return users
+// unredacted, approvals nil to apply deletes and email changes directly
+func NewUserController(service service.UserService, fields *FieldPolicy, approvals service.ApprovalService) *UserController {
+	return &UserController{service: service, fields: fields, approvals: approvals}
 }
 
 func (c *UserController) GetAllUsers(ctx *gin.Context) {
-	users, err := c.service.GetAll()
+	query, ok := bindUserQuery(ctx)
+	if !ok {
+		return
+	}
+	if field := c.fields.hiddenQuery(ctx, query); field != "" {
+		respondError(ctx, apierror.Forbidden("field_hidden", "not allowed to filter by "+field))
+		return
+	}
+	// Soft-deleted users are listed for admins only, like the recycle bin
+	if ctx.Query("include_deleted") != "" {
+		include, err := strconv.ParseBool(ctx.Query("include_deleted"))
+		if err != nil {
+			respondError(ctx, invalidParam("invalid include_deleted"))
+			return
+		}
+		if include && !middleware.GetPrincipal(ctx).HasScope("admin") {
+			respondError(ctx, apierror.Forbidden("missing_scope", "include_deleted requires the admin scope"))
+			return
+		}
+		query.IncludeDeleted = include
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	var users []model.User
+	var err error
+	if query.Plain() {
+		users, err = c.service.GetAll(ctx.Request.Context())
+	} else {
+		users, err = c.service.Find(ctx.Request.Context(), query)
+	}
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	if users == nil {
+		users = []model.User{}
+	}
+
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, users))
+}
+
+// bindUserQuery reads cf.<name>=value custom field filters, the email_domain,
+// full_name, created_from, created_to, updated_from, updated_to and q filters and
+// sort from the query string; it responds with 400 and returns false when a date
+// is malformed. The sort is checked by the service.
+func bindUserQuery(ctx *gin.Context) (model.UserQuery, bool) {
+	query := model.UserQuery{
+		Sort:        ctx.Query("sort"),
+		EmailDomain: strings.TrimPrefix(strings.TrimSpace(ctx.Query("email_domain")), "@"),
+		FullName:    strings.TrimSpace(ctx.Query("full_name")),
+		Q:           strings.TrimSpace(ctx.Query("q")),
+	}
+	var ok bool
+	if query.CreatedFrom, ok = queryTime(ctx, "created_from"); !ok {
+		return query, false
+	}
+	if query.CreatedTo, ok = queryTime(ctx, "created_to"); !ok {
+		return query, false
+	}
+	if query.UpdatedFrom, ok = queryTime(ctx, "updated_from"); !ok {
+		return query, false
+	}
+	if query.UpdatedTo, ok = queryTime(ctx, "updated_to"); !ok {
+		return query, false
+	}
+	for key, values := range ctx.Request.URL.Query() {
+		if name, ok := strings.CutPrefix(key, "cf."); ok && len(values) > 0 {
+			if query.CustomFields == nil {
+				query.CustomFields = make(map[string]string)
+			}
+			query.CustomFields[name] = values[0]
+		}
+	}
+	return query, true
+}
+
+// queryTime reads the query parameter param as an RFC 3339 timestamp or a date,
+// which stands for midnight UTC; it returns nil when the parameter is absent and
+// responds with 400 and returns false when it is malformed
+func queryTime(ctx *gin.Context, param string) (*time.Time, bool) {
+	value := ctx.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, value); err != nil {
+			respondError(ctx, invalidParam("invalid "+param+", expected RFC 3339 or YYYY-MM-DD"))
+			return nil, false
+		}
+	}
+	return &t, true
+}
+
+// GET /api/v1/users/aggregate?group_by=created_month|cf.<name>
+func (c *UserController) AggregateUsers(ctx *gin.Context) {
+	if c.fields.hiddenGroup(ctx, ctx.Query("group_by")) {
+		respondError(ctx, apierror.Forbidden("field_hidden", "not allowed to group by "+ctx.Query("group_by")))
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	buckets, err := c.service.Aggregate(ctx.Request.Context(), ctx.Query("group_by"))
+	stop()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, users)
+	ctx.JSON(http.StatusOK, gin.H{"group_by": ctx.Query("group_by"), "buckets": buckets})
+}
+
+// GET /api/v1/users/sample?n=100
+func (c *UserController) SampleUsers(ctx *gin.Context) {
+	n, err := strconv.Atoi(ctx.DefaultQuery("n", strconv.Itoa(service.DefaultSampleSize)))
+	if err != nil {
+		respondError(ctx, invalidParam("invalid sample size"))
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	users, err := c.service.Sample(ctx.Request.Context(), n)
+	stop()
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSampleSize) {
+			respondError(ctx, invalidParam(fmt.Sprintf("n must be between 1 and %d", service.MaxSampleSize)))
+			return
+		}
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, users))
 }
 
 func (c *UserController) GetUserByUsername(ctx *gin.Context) {
 	username := ctx.Param("username")
 
-	user, err := c.service.GetByUsername(username)
+	stop := timing.Track(ctx.Request.Context(), "service")
+	user, err := c.service.GetByUsername(ctx.Request.Context(), username)
+	stop()
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	setUserETag(ctx, user)
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, user))
 }
 
 func (c *UserController) GetUserByID(ctx *gin.Context) {
 	idStr := ctx.Param("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondError(ctx, invalidParam("invalid id"))
 		return
 	}
 
-	user, err := c.service.GetByID(id)
+	stop := timing.Track(ctx.Request.Context(), "service")
+	user, err := c.service.GetByID(ctx.Request.Context(), id)
+	stop()
 	// log.Printf("DEBUG: ID=%d, user=%v, err=%v", id, user, err)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		respondError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, user)
+	setUserETag(ctx, user)
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, user))
 }
 
 // POST /api/v1/users - CREATE
 func (c *UserController) CreateUser(ctx *gin.Context) {
 	var user model.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	if err := bindJSON(ctx, &user); err != nil {
+		respondError(ctx, invalidBody(err))
 		return
 	}
 
-	if err := c.service.Create(&user); err != nil {
-		if err.Error() == "username already exists" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	if !bindReplaceDeleted(ctx) {
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	err := c.service.Create(ctx.Request.Context(), &user)
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	// FullPath includes the configured base path, so the link survives a proxy prefix
+	ctx.Header("Location", strings.TrimSuffix(ctx.FullPath(), "/")+"/id/"+strconv.FormatInt(user.ID, 10))
+	setUserETag(ctx, &user)
+	ctx.JSON(http.StatusCreated, c.fields.redact(ctx, user))
+}
+
+type bulkCreateRequest struct {
+	Users []json.RawMessage `json:"users"`
+}
+
+// POST /api/v1/users/bulk {"users": [{...}, ...]} creates each valid user and
+// reports every item's outcome; one bad item does not stop the others
+func (c *UserController) BulkCreateUsers(ctx *gin.Context) {
+	var req bulkCreateRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	if len(req.Users) == 0 || len(req.Users) > service.MaxBulkSize {
+		respondError(ctx, apierror.Validation("invalid_bulk_size", fmt.Sprintf("users must hold 1 to %d items", service.MaxBulkSize)))
+		return
+	}
+
+	if !bindReplaceDeleted(ctx) {
+		return
+	}
+
+	// Items are bound one by one, as strictly as single creates, so a malformed
+	// item fails alone and an unknown field is named in its result
+	results := make([]model.BulkItemResult, len(req.Users))
+	users := make([]*model.User, 0, len(req.Users))
+	positions := make([]int, 0, len(req.Users))
+	for i, raw := range req.Users {
+		var user model.User
+		if err := bindRaw(raw, &user); err != nil {
+			results[i] = model.BulkItemResult{Index: i, Status: http.StatusBadRequest, Code: "invalid_user", Error: invalidBodyHint(err, "invalid user").Message}
+			continue
+		}
+		users = append(users, &user)
+		positions = append(positions, i)
+	}
+
+	if len(users) > 0 {
+		stop := timing.Track(ctx.Request.Context(), "service")
+		errs, err := c.service.CreateMany(ctx.Request.Context(), users)
+		stop()
+		if err != nil {
+			respondError(ctx, err)
 			return
 		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		for j, user := range users {
+			results[positions[j]] = bulkCreateResult(positions[j], errs[j])
+			if errs[j] == nil {
+				results[positions[j]].User = c.fields.redact(ctx, user)
+			}
+		}
+	}
+
+	response := model.BulkResult{Results: results}
+	for _, result := range results {
+		if result.Status == http.StatusCreated {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	middleware.CountMutations(ctx, response.Succeeded)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// bindReplaceDeleted applies ?replace_deleted=true, which creates new accounts with
+// the usernames of deleted users instead of offering their restore
+func bindReplaceDeleted(ctx *gin.Context) bool {
+	if ctx.Query("replace_deleted") == "" {
+		return true
+	}
+	replace, err := strconv.ParseBool(ctx.Query("replace_deleted"))
+	if err != nil {
+		respondError(ctx, invalidParam("invalid replace_deleted"))
+		return false
+	}
+	if replace {
+		ctx.Request = ctx.Request.WithContext(service.WithReplaceDeleted(ctx.Request.Context()))
+	}
+	return true
+}
+
+// bulkCreateResult maps the outcome of one created user like CreateUser does
+func bulkCreateResult(index int, err error) model.BulkItemResult {
+	result := model.BulkItemResult{Index: index, Status: http.StatusCreated}
+	if err == nil {
+		return result
+	}
+	result.Error = err.Error()
+	mapped := serviceError(err)
+	result.Status = apierror.Status(mapped)
+	var apiErr *apierror.Error
+	if errors.As(mapped, &apiErr) {
+		result.Code = apiErr.Code
+	}
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		result.Errors = validationErr.Errors
+	}
+	var deletedErr *service.DeletedUserError
+	if errors.As(err, &deletedErr) {
+		result.UUID = deletedErr.User.UUID
+	}
+	return result
+}
+
+// POST /api/v1/users/validate checks a would-be user without creating it. The body is
+// decoded without binding rules so format problems are reported with the rest.
+func (c *UserController) ValidateUser(ctx *gin.Context) {
+	var user model.User
+	if err := decodeJSON(ctx, &user); err != nil {
+		respondError(ctx, invalidBody(err))
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, user)
+	if !bindReplaceDeleted(ctx) {
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	result, err := c.service.Validate(ctx.Request.Context(), &user)
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
 }
 
 // PATCH /api/v1/users/:uuid - UPDATE
 func (c *UserController) UpdateUser(ctx *gin.Context) {
 	uuid := ctx.Param("uuid")
 
-	var user model.User
-	if err := ctx.ShouldBindJSON(&user); err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+	// Only the fields present in the body are updated
+	var patch model.UserPatch
+	if err := bindJSON(ctx, &patch); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	// Updates name the version they were made against, so concurrent edits are not lost
+	if err := ifMatchVersion(ctx, &patch); err != nil {
+		respondError(ctx, err)
 		return
 	}
 
-	if err := c.service.Update(uuid, &user); err != nil {
-		if err.Error() == "users not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		if err.Error() == "username already exists" {
-			ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// An email change waits for a second admin's approval when approvals are enabled
+	stop := timing.Track(ctx.Request.Context(), "service")
+	var change *model.PendingChange
+	var err error
+	if c.approvals != nil {
+		change, err = c.approvals.RequestUpdate(ctx.Request.Context(), uuid, patch, principalName(ctx))
+	}
+	if err == nil && change == nil {
+		err = c.service.Update(ctx.Request.Context(), uuid, patch)
+	}
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	if change != nil {
+		ctx.JSON(http.StatusAccepted, change)
 		return
 	}
 
@@ -109,14 +402,98 @@ func (c *UserController) UpdateUser(ctx *gin.Context) {
 func (c *UserController) DeleteUser(ctx *gin.Context) {
 	uuid := ctx.Param("uuid")
 
-	if err := c.service.Delete(uuid); err != nil {
-		if err.Error() == "users not found" {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	stop := timing.Track(ctx.Request.Context(), "service")
+	var change *model.PendingChange
+	var err error
+	if c.approvals != nil {
+		change, err = c.approvals.RequestDelete(ctx.Request.Context(), uuid, principalName(ctx))
+	}
+	if err == nil && change == nil {
+		err = c.service.Delete(ctx.Request.Context(), uuid)
+	}
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	if change != nil {
+		ctx.JSON(http.StatusAccepted, change)
 		return
 	}
 
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+type bulkDeleteRequest struct {
+	UUIDs []string `json:"uuids"`
+}
+
+// DELETE /api/v1/users/bulk?mode=all_or_nothing|partial {"uuids": [...]} deletes the
+// users in one transaction and reports every UUID's outcome. In all_or_nothing mode,
+// the default, one user that cannot be deleted keeps them all.
+func (c *UserController) BulkDeleteUsers(ctx *gin.Context) {
+	var allOrNothing bool
+	switch ctx.DefaultQuery("mode", "all_or_nothing") {
+	case "all_or_nothing":
+		allOrNothing = true
+	case "partial":
+	default:
+		respondError(ctx, invalidParam("mode must be all_or_nothing or partial"))
+		return
+	}
+	// Deletes wait for a second admin when approvals are on; a batch would skip that
+	if c.approvals != nil {
+		respondError(ctx, apierror.Conflict("approval_required", "deletes need approval; delete users one by one"))
+		return
+	}
+	var req bulkDeleteRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+	if len(req.UUIDs) == 0 || len(req.UUIDs) > service.MaxBulkSize {
+		respondError(ctx, apierror.Validation("invalid_bulk_size", fmt.Sprintf("uuids must hold 1 to %d items", service.MaxBulkSize)))
+		return
+	}
+
+	stop := timing.Track(ctx.Request.Context(), "service")
+	errs, err := c.service.DeleteMany(ctx.Request.Context(), req.UUIDs, allOrNothing)
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	response := model.BulkResult{Results: make([]model.BulkItemResult, len(req.UUIDs))}
+	for i, uuid := range req.UUIDs {
+		result := model.BulkItemResult{Index: i, UUID: uuid, Status: http.StatusNoContent}
+		if errs[i] != nil {
+			result.Error = errs[i].Error()
+			mapped := serviceError(errs[i])
+			result.Status = apierror.Status(mapped)
+			var apiErr *apierror.Error
+			if errors.As(mapped, &apiErr) {
+				result.Code = apiErr.Code
+			}
+			response.Failed++
+		} else {
+			response.Succeeded++
+		}
+		response.Results[i] = result
+	}
+	middleware.CountMutations(ctx, response.Succeeded)
+	ctx.JSON(http.StatusOK, response)
+}
+
+// POST /api/v1/users/:uuid/restore
+func (c *UserController) RestoreUser(ctx *gin.Context) {
+	stop := timing.Track(ctx.Request.Context(), "service")
+	user, err := c.service.Restore(ctx.Request.Context(), ctx.Param("uuid"))
+	stop()
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, c.fields.redact(ctx, user))
+}
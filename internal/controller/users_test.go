@@ -0,0 +1,303 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mockUserService keeps users in memory, keyed by UUID. Setting err makes every
+// method fail with it, to exercise the error mapping; methods the tests do not
+// call are left to the embedded interface and panic.
+type mockUserService struct {
+	service.UserService
+	users map[string]*model.User
+	err   error
+}
+
+func newMockUserService(users ...model.User) *mockUserService {
+	m := &mockUserService{users: map[string]*model.User{}}
+	for i := range users {
+		m.users[users[i].UUID] = &users[i]
+	}
+	return m
+}
+
+func (m *mockUserService) GetAll(ctx context.Context) ([]model.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	var users []model.User
+	for _, user := range m.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (m *mockUserService) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, service.ErrUserNotFound
+}
+
+func (m *mockUserService) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	for _, user := range m.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, service.ErrUserNotFound
+}
+
+func (m *mockUserService) Create(ctx context.Context, user *model.User) error {
+	if m.err != nil {
+		return m.err
+	}
+	for _, existing := range m.users {
+		if existing.Username == user.Username {
+			return service.ErrUsernameTaken
+		}
+	}
+	user.ID = int64(len(m.users) + 1)
+	user.UUID = fmt.Sprintf("00000000-0000-4000-8000-%012d", user.ID)
+	user.CreatedAt = time.Now().UTC()
+	user.UpdatedAt = user.CreatedAt
+	user.Version = 1
+	m.users[user.UUID] = user
+	return nil
+}
+
+func (m *mockUserService) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	errs := make([]error, len(users))
+	for i, user := range users {
+		errs[i] = m.Create(ctx, user)
+	}
+	return errs, nil
+}
+
+func (m *mockUserService) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	if m.err != nil {
+		return m.err
+	}
+	user, ok := m.users[uuid]
+	if !ok {
+		return service.ErrUserNotFound
+	}
+	if patch.Version != nil && *patch.Version != user.Version {
+		return service.ErrVersionMismatch
+	}
+	updated := patch.Apply(*user)
+	updated.UpdatedAt = time.Now().UTC()
+	updated.Version++
+	m.users[uuid] = &updated
+	return nil
+}
+
+func (m *mockUserService) Delete(ctx context.Context, uuid string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.users[uuid]; !ok {
+		return service.ErrUserNotFound
+	}
+	delete(m.users, uuid)
+	return nil
+}
+
+// newUserTestRouter serves the user routes with svc behind them, with the error
+// middleware of the real router but no authentication
+func newUserTestRouter(svc service.UserService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Errors())
+
+	ctrl := NewUserController(svc, nil, nil)
+	users := router.Group("/api/v1/users")
+	users.GET("/", ctrl.GetAllUsers)
+	users.GET("/username/:username", ctrl.GetUserByUsername)
+	users.GET("/id/:id", ctrl.GetUserByID)
+	users.POST("/", ctrl.CreateUser)
+	users.POST("/bulk", ctrl.BulkCreateUsers)
+	users.PATCH("/:uuid", ctrl.UpdateUser)
+	users.DELETE("/:uuid", ctrl.DeleteUser)
+	return router
+}
+
+// userRouteCase is one request to the user routes and the answer expected
+type userRouteCase struct {
+	name    string
+	method  string
+	path    string
+	body    string
+	ifMatch string // sent as If-Match
+	err     error  // returned by every service method
+	status  int
+	code    string            // error code of the body, if any
+	headers map[string]string // expected response headers
+}
+
+func runUserRouteCases(t *testing.T, cases []userRouteCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given: A service holding one user
+			svc := newMockUserService(model.User{ID: 1, UUID: "uuid-jdoe", Username: "jdoe", Email: "jdoe@example.com", Version: 2})
+			svc.err = tc.err
+			router := newUserTestRouter(svc)
+
+			// When
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Then
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.code != "" {
+				var body apierror.Body
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("expected an error body, got %s", w.Body.String())
+				}
+				if body.Code != tc.code {
+					t.Errorf("expected code %s, got %s", tc.code, body.Code)
+				}
+			}
+			for name, value := range tc.headers {
+				if got := w.Header().Get(name); got != value {
+					t.Errorf("expected header %s %q, got %q", name, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestUserController_Get(t *testing.T) {
+	runUserRouteCases(t, []userRouteCase{
+		{name: "by id", method: http.MethodGet, path: "/api/v1/users/id/1", status: http.StatusOK,
+			headers: map[string]string{"Content-Type": "application/json; charset=utf-8", "ETag": `"2"`}},
+		{name: "malformed id", method: http.MethodGet, path: "/api/v1/users/id/abc", status: http.StatusBadRequest, code: "invalid_parameter"},
+		{name: "unknown id", method: http.MethodGet, path: "/api/v1/users/id/2", status: http.StatusNotFound, code: "user_not_found"},
+		{name: "by username", method: http.MethodGet, path: "/api/v1/users/username/jdoe", status: http.StatusOK},
+		{name: "list", method: http.MethodGet, path: "/api/v1/users/", status: http.StatusOK},
+		{name: "include_deleted needs admin", method: http.MethodGet, path: "/api/v1/users/?include_deleted=true", status: http.StatusForbidden, code: "missing_scope"},
+		{name: "malformed updated_from", method: http.MethodGet, path: "/api/v1/users/?updated_from=yesterday", status: http.StatusBadRequest, code: "invalid_parameter"},
+		{name: "malformed include_deleted", method: http.MethodGet, path: "/api/v1/users/?include_deleted=maybe", status: http.StatusBadRequest, code: "invalid_parameter"},
+		{name: "internal errors stay internal", method: http.MethodGet, path: "/api/v1/users/id/1", err: errors.New("connection reset"), status: http.StatusInternalServerError, code: apierror.InternalCode},
+	})
+}
+
+func TestUserController_Create(t *testing.T) {
+	const body = `{"username":"asmith","email":"asmith@example.com"}`
+	runUserRouteCases(t, []userRouteCase{
+		{name: "created", method: http.MethodPost, path: "/api/v1/users/", body: body, status: http.StatusCreated,
+			headers: map[string]string{"Location": "/api/v1/users/id/2"}},
+		{name: "malformed body", method: http.MethodPost, path: "/api/v1/users/", body: `{"username":`, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "unknown field", method: http.MethodPost, path: "/api/v1/users/", body: `{"username":"asmith","email":"asmith@example.com","nickname":"al"}`, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "trailing data", method: http.MethodPost, path: "/api/v1/users/", body: body + `{"username":"bsmith"}`, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "trailing whitespace", method: http.MethodPost, path: "/api/v1/users/", body: body + "\n", status: http.StatusCreated},
+		{name: "username taken", method: http.MethodPost, path: "/api/v1/users/", body: body, err: service.ErrUsernameTaken, status: http.StatusConflict, code: "username_taken"},
+		{name: "invalid user", method: http.MethodPost, path: "/api/v1/users/", body: body,
+			err:    &service.ValidationError{Errors: []model.FieldError{{Field: "email", Code: "invalid_format", Message: "invalid email"}}},
+			status: http.StatusBadRequest, code: "invalid_user"},
+	})
+}
+
+func TestUserController_CreateWithHoneypot(t *testing.T) {
+	// Given: Registrations checked by bot detection with a honeypot field
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Errors())
+	ctrl := NewUserController(newMockUserService(), nil, nil)
+	router.POST("/api/v1/users/", middleware.BotDetection(middleware.BotCheck{HoneypotField: "website"}), ctrl.CreateUser)
+
+	// When: A form submits the honeypot field empty, as people leave it
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/", strings.NewReader(`{"username":"asmith","email":"asmith@example.com","website":""}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: The user is created; the field is not taken for an unknown one
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUserController_BulkCreateUnknownField(t *testing.T) {
+	// Given: A bulk create whose second item has a field users do not have
+	router := newUserTestRouter(newMockUserService())
+	body := `{"users":[{"username":"asmith","email":"asmith@example.com"},{"username":"bsmith","email":"bsmith@example.com","nickname":"b"}]}`
+
+	// When: Posting it
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Then: The first item is created and the second is rejected naming the field
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var result model.BulkResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Succeeded != 1 || result.Failed != 1 {
+		t.Fatalf("expected 1 created and 1 failed, got %+v", result)
+	}
+	item := result.Results[1]
+	if item.Status != http.StatusBadRequest || item.Code != "invalid_user" || item.Error != `unknown field "nickname"` {
+		t.Errorf("expected the unknown field to be reported, got %+v", item)
+	}
+}
+
+func TestUserController_UpdateAndDelete(t *testing.T) {
+	runUserRouteCases(t, []userRouteCase{
+		{name: "updated", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"full_name":"John Doe"}`, ifMatch: `"2"`, status: http.StatusOK},
+		{name: "updated with a weak tag", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"full_name":"John Doe"}`, ifMatch: `W/"2"`, status: http.StatusOK},
+		{name: "updated at any version", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"full_name":"John Doe"}`, ifMatch: "*", status: http.StatusOK},
+		{name: "updated with the version in the body", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"full_name":"John Doe","version":2}`, status: http.StatusOK},
+		{name: "stale version", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"full_name":"John Doe"}`, ifMatch: `"1"`, status: http.StatusPreconditionFailed, code: "version_mismatch"},
+		{name: "If-Match overrides the body", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"version":2}`, ifMatch: `"1"`, status: http.StatusPreconditionFailed, code: "version_mismatch"},
+		{name: "missing If-Match", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"full_name":"John Doe"}`, status: http.StatusPreconditionRequired, code: "if_match_required"},
+		{name: "malformed If-Match", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{}`, ifMatch: "2", status: http.StatusBadRequest, code: "invalid_parameter"},
+		{name: "update of an unknown user", method: http.MethodPatch, path: "/api/v1/users/uuid-nobody", body: `{}`, ifMatch: `"1"`, status: http.StatusNotFound, code: "user_not_found"},
+		{name: "update with a malformed body", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `[]`, ifMatch: `"2"`, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "update with an unknown field", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"fullname":"John Doe"}`, ifMatch: `"2"`, status: http.StatusBadRequest, code: "invalid_body"},
+		{name: "email taken", method: http.MethodPatch, path: "/api/v1/users/uuid-jdoe", body: `{"email":"x@example.com"}`, ifMatch: `"2"`, err: service.ErrEmailTaken, status: http.StatusConflict, code: "email_taken"},
+		{name: "deleted", method: http.MethodDelete, path: "/api/v1/users/uuid-jdoe", status: http.StatusNoContent},
+		{name: "delete of an unknown user", method: http.MethodDelete, path: "/api/v1/users/uuid-nobody", status: http.StatusNotFound, code: "user_not_found"},
+	})
+}
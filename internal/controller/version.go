@@ -0,0 +1,14 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/version"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /version
+func GetVersion(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, version.Get())
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"net/http"
+
+	"cruder/internal/audit"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController lets admins register the URLs user events are delivered to
+type WebhookController struct {
+	service service.WebhookService
+}
+
+func NewWebhookController(service service.WebhookService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+// GET /api/v1/webhooks
+func (c *WebhookController) ListWebhooks(ctx *gin.Context) {
+	webhooks, err := c.service.List(ctx.Request.Context())
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, webhooks)
+}
+
+// GET /api/v1/webhooks/:id
+func (c *WebhookController) GetWebhook(ctx *gin.Context) {
+	webhook, err := c.service.Get(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// POST /api/v1/webhooks {"url": "...", "events": [...], "description": "...", "active": true}
+func (c *WebhookController) CreateWebhook(ctx *gin.Context) {
+	var req model.WebhookRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	webhook, err := c.service.Create(ctx.Request.Context(), principalName(ctx), req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditWebhook(ctx, "webhook.create", webhook.ID, map[string]string{"url": webhook.URL})
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusCreated, webhook)
+}
+
+// PUT /api/v1/webhooks/:id
+func (c *WebhookController) UpdateWebhook(ctx *gin.Context) {
+	var req model.WebhookRequest
+	if err := bindJSON(ctx, &req); err != nil {
+		respondError(ctx, invalidBody(err))
+		return
+	}
+
+	webhook, err := c.service.Update(ctx.Request.Context(), ctx.Param("id"), req)
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditWebhook(ctx, "webhook.update", webhook.ID, map[string]string{"url": webhook.URL})
+	ctx.JSON(http.StatusOK, webhook)
+}
+
+// DELETE /api/v1/webhooks/:id
+func (c *WebhookController) DeleteWebhook(ctx *gin.Context) {
+	if err := c.service.Delete(ctx.Request.Context(), ctx.Param("id")); err != nil {
+		respondError(ctx, err)
+		return
+	}
+
+	auditWebhook(ctx, "webhook.delete", ctx.Param("id"), nil)
+	ctx.Status(http.StatusNoContent)
+}
+
+// GET /api/v1/webhooks/:id/deliveries
+func (c *WebhookController) ListDeliveries(ctx *gin.Context) {
+	deliveries, err := c.service.Deliveries(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		respondError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+func auditWebhook(ctx *gin.Context, action, webhookID string, details map[string]string) {
+	audit.Record(audit.Event{
+		Action:     action,
+		Actor:      principalName(ctx),
+		ClientIP:   middleware.ClientIP(ctx),
+		Resource:   "webhook",
+		ResourceID: webhookID,
+		Details:    details,
+	})
+}
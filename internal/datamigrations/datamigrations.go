@@ -0,0 +1,193 @@
+// Package datamigrations runs data migrations: Go code that rewrites existing rows,
+// such as backfills, once the schema migrations have added what they need. A
+// migration walks a table in key order, one small transaction per batch, and each
+// batch stores its progress in data_migrations in the same transaction, so a run
+// that is stopped or fails resumes after the last committed batch.
+//
+// Data migrations only go forward: a mistake is fixed by another migration.
+package datamigrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Migration rewrites rows in batches
+type Migration struct {
+	// Name identifies the migration in data_migrations and on the command line; it
+	// must not change once the migration has run anywhere
+	Name        string
+	Description string
+	// Batch looks at up to limit rows with keys above cursor, in key order, and
+	// changes those that need it. It runs in tx, which also records its result.
+	Batch func(ctx context.Context, tx *sql.Tx, cursor int64, limit int) (BatchResult, error)
+}
+
+// BatchResult is what one batch did. A batch that sees fewer rows than its limit
+// ends the migration.
+type BatchResult struct {
+	// Cursor is the key of the last row looked at
+	Cursor  int64
+	Seen    int
+	Changed int
+}
+
+// Status is the progress of a migration in one database
+type Status struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Cursor      int64      `json:"cursor"`
+	RowsSeen    int64      `json:"rows_seen"`
+	RowsChanged int64      `json:"rows_changed"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// Defaults of a Runner
+const (
+	DefaultBatchSize = 1000
+	MaxBatchSize     = 100000
+)
+
+// ErrUnknownMigration is returned for a name that no migration has
+var ErrUnknownMigration = errors.New("unknown data migration")
+
+// Runner runs migrations against one database
+type Runner struct {
+	db         *sql.DB
+	migrations []Migration
+	batchSize  int
+	// pause between batches leaves room for the application's own queries
+	pause time.Duration
+}
+
+// NewRunner creates a runner of migrations against db; batchSize and pause fall
+// back to DefaultBatchSize and no pause when not positive
+func NewRunner(db *sql.DB, migrations []Migration, batchSize int, pause time.Duration) *Runner {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	return &Runner{db: db, migrations: migrations, batchSize: min(batchSize, MaxBatchSize), pause: max(pause, 0)}
+}
+
+// Statuses reports every migration, in order, including those that never ran
+func (r *Runner) Statuses(ctx context.Context) ([]Status, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT name, cursor, rows_seen, rows_changed, started_at, updated_at, completed_at FROM data_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	stored := map[string]Status{}
+	for rows.Next() {
+		var s Status
+		if err := rows.Scan(&s.Name, &s.Cursor, &s.RowsSeen, &s.RowsChanged, &s.StartedAt, &s.UpdatedAt, &s.CompletedAt); err != nil {
+			return nil, err
+		}
+		stored[s.Name] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(r.migrations))
+	for i, m := range r.migrations {
+		statuses[i] = stored[m.Name]
+		statuses[i].Name = m.Name
+		statuses[i].Description = m.Description
+	}
+	return statuses, nil
+}
+
+// Run runs the named migration until it completes, resuming where an earlier run
+// stopped. A completed migration is not run again.
+func (r *Runner) Run(ctx context.Context, name string) error {
+	m, ok := r.find(name)
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownMigration, name)
+	}
+	if _, err := r.db.ExecContext(ctx,
+		`INSERT INTO data_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING`, name); err != nil {
+		return err
+	}
+
+	for {
+		progress, done, err := r.batch(ctx, m)
+		if err != nil {
+			return fmt.Errorf("data migration %s: %w", name, err)
+		}
+		if done {
+			log.Printf("data migration %s: completed, %d rows seen, %d changed", name, progress.RowsSeen, progress.RowsChanged)
+			return nil
+		}
+		log.Printf("data migration %s: %d rows seen, %d changed, at key %d", name, progress.RowsSeen, progress.RowsChanged, progress.Cursor)
+
+		if r.pause > 0 {
+			timer := time.NewTimer(r.pause)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// batch runs one batch of m and records its progress. The progress row is locked
+// first, so two runners of the same migration take turns instead of repeating work.
+func (r *Runner) batch(ctx context.Context, m Migration) (Status, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Status{}, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var progress Status
+	err = tx.QueryRowContext(ctx,
+		`SELECT cursor, rows_seen, rows_changed, completed_at FROM data_migrations WHERE name = $1 FOR UPDATE`, m.Name).
+		Scan(&progress.Cursor, &progress.RowsSeen, &progress.RowsChanged, &progress.CompletedAt)
+	if err != nil {
+		return Status{}, false, err
+	}
+	if progress.CompletedAt != nil {
+		return progress, true, nil
+	}
+
+	result, err := m.Batch(ctx, tx, progress.Cursor, r.batchSize)
+	if err != nil {
+		return Status{}, false, err
+	}
+	done := result.Seen < r.batchSize
+	if result.Seen > 0 {
+		progress.Cursor = result.Cursor
+	}
+	progress.RowsSeen += int64(result.Seen)
+	progress.RowsChanged += int64(result.Changed)
+
+	_, err = tx.ExecContext(ctx, `UPDATE data_migrations
+		SET cursor = $2, rows_seen = $3, rows_changed = $4, updated_at = CURRENT_TIMESTAMP,
+		    completed_at = CASE WHEN $5 THEN CURRENT_TIMESTAMP END
+		WHERE name = $1`, m.Name, progress.Cursor, progress.RowsSeen, progress.RowsChanged, done)
+	if err != nil {
+		return Status{}, false, err
+	}
+	return progress, done, tx.Commit()
+}
+
+func (r *Runner) find(name string) (Migration, bool) {
+	for _, m := range r.migrations {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
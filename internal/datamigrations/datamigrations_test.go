@@ -0,0 +1,99 @@
+package datamigrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"cruder/internal/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+func TestAll(t *testing.T) {
+	names := map[string]bool{}
+	for _, m := range All {
+		if m.Name == "" || m.Batch == nil {
+			t.Errorf("migration %q needs a name and a batch", m.Name)
+		}
+		if names[m.Name] {
+			t.Errorf("migration name %q is used twice", m.Name)
+		}
+		names[m.Name] = true
+	}
+}
+
+func TestRun_UnknownMigration(t *testing.T) {
+	runner := NewRunner(nil, All, 0, 0)
+
+	err := runner.Run(context.Background(), "nope")
+
+	if !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("expected ErrUnknownMigration, got %v", err)
+	}
+}
+
+// TestRun_Resumes needs a database; set TEST_DATABASE_URL to run it
+func TestRun_Resumes(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	if err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	// Given: A migration over 5 rows that fails on its second batch, once
+	const name = "test_resumes"
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM data_migrations WHERE name = $1`, name) })
+	var cursors []int64
+	failed := false
+	m := Migration{Name: name, Batch: func(ctx context.Context, tx *sql.Tx, cursor int64, limit int) (BatchResult, error) {
+		if cursor == 2 && !failed {
+			failed = true
+			return BatchResult{}, errors.New("connection lost")
+		}
+		cursors = append(cursors, cursor)
+		seen := min(5-int(cursor), limit)
+		return BatchResult{Cursor: cursor + int64(seen), Seen: seen, Changed: seen}, nil
+	}}
+	runner := NewRunner(db, []Migration{m}, 2, 0)
+
+	// When: Running it, then running it again
+	if err := runner.Run(ctx, name); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if err := runner.Run(ctx, name); err != nil {
+		t.Fatalf("expected the second run to complete, got %v", err)
+	}
+
+	// Then: The second run continued after the last committed batch
+	if len(cursors) != 3 || cursors[0] != 0 || cursors[1] != 2 || cursors[2] != 4 {
+		t.Errorf("expected batches after 0, 2 and 4, got %v", cursors)
+	}
+	statuses, err := runner.Statuses(ctx)
+	if err != nil {
+		t.Fatalf("Statuses: %v", err)
+	}
+	if s := statuses[0]; s.CompletedAt == nil || s.RowsSeen != 5 || s.Cursor != 5 {
+		t.Errorf("expected 5 rows seen and completed, got %+v", s)
+	}
+
+	// When: Running the completed migration once more
+	if err := runner.Run(ctx, name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: No batch runs
+	if len(cursors) != 3 {
+		t.Errorf("expected no more batches, got %v", cursors)
+	}
+}
@@ -0,0 +1,39 @@
+package datamigrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+// All lists the data migrations of the service, oldest first. New ones are added
+// at the end; a migration that has run somewhere is never removed or renamed.
+var All = []Migration{
+	normalizeEmails,
+}
+
+// normalizeEmails trims and lower-cases stored emails. A live user whose email
+// would then clash with another live user keeps it, to be resolved by hand: the
+// unique index would fail the batch otherwise. Within a batch the lower id wins.
+var normalizeEmails = Migration{
+	Name:        "normalize_emails",
+	Description: "trim and lower-case user emails",
+	Batch: func(ctx context.Context, tx *sql.Tx, cursor int64, limit int) (BatchResult, error) {
+		var result BatchResult
+		err := tx.QueryRowContext(ctx, `WITH batch AS (
+			SELECT id, email, deleted_at FROM users WHERE id > $1 ORDER BY id LIMIT $2
+		), changed AS (
+			UPDATE users u SET email = lower(trim(b.email))
+			FROM batch b
+			WHERE u.id = b.id AND b.email <> lower(trim(b.email))
+			  AND (b.deleted_at IS NOT NULL OR (
+			    NOT EXISTS (SELECT 1 FROM users o
+			                WHERE o.email = lower(trim(b.email)) AND o.deleted_at IS NULL AND o.id <> b.id)
+			    AND NOT EXISTS (SELECT 1 FROM batch o
+			                    WHERE lower(trim(o.email)) = lower(trim(b.email)) AND o.deleted_at IS NULL AND o.id < b.id)))
+			RETURNING u.id
+		)
+		SELECT COALESCE(MAX(id), $1), COUNT(*), (SELECT COUNT(*) FROM changed) FROM batch`, cursor, limit).
+			Scan(&result.Cursor, &result.Seen, &result.Changed)
+		return result, err
+	},
+}
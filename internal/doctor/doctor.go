@@ -0,0 +1,214 @@
+// Package doctor runs the pre-flight checks of "cruder doctor": whether the
+// service's dependencies are reachable and set up before it is first deployed.
+package doctor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cruder/internal/migrations"
+)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	OK   Status = "ok"
+	Warn Status = "warn"
+	Fail Status = "fail"
+)
+
+// Result is what one check found
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+// Check inspects one dependency. Each check gets its own timeout.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string)
+}
+
+// Run runs the checks in order, each for at most timeout
+func Run(ctx context.Context, checks []Check, timeout time.Duration) []Result {
+	results := make([]Result, len(checks))
+	for i, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		status, detail := check.Run(checkCtx)
+		cancel()
+		results[i] = Result{Name: check.Name, Status: status, Detail: detail}
+	}
+	return results
+}
+
+// Ready reports whether no check failed; warnings do not block a deployment
+func Ready(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return false
+		}
+	}
+	return true
+}
+
+// Report writes one line per result and a verdict
+func Report(w io.Writer, results []Result) {
+	width := 0
+	for _, r := range results {
+		width = max(width, len(r.Name))
+	}
+	for _, r := range results {
+		_, _ = fmt.Fprintf(w, "[%-4s] %-*s  %s\n", strings.ToUpper(string(r.Status)), width, r.Name, r.Detail)
+	}
+	if Ready(results) {
+		_, _ = fmt.Fprintln(w, "ready")
+	} else {
+		_, _ = fmt.Fprintln(w, "not ready")
+	}
+}
+
+// Failed wraps err as a failed check
+func Failed(err error) (Status, string) {
+	return Fail, err.Error()
+}
+
+// minServerVersion is the oldest PostgreSQL with gen_random_uuid() built in;
+// older servers need the pgcrypto extension for it
+const minServerVersion = 130000
+
+// Server reports the PostgreSQL version db runs
+func Server(db *sql.DB) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		var version string
+		var number int
+		if err := db.QueryRowContext(ctx, `SELECT current_setting('server_version'), current_setting('server_version_num')::int`).
+			Scan(&version, &number); err != nil {
+			return Failed(err)
+		}
+		if number < minServerVersion {
+			return Warn, "PostgreSQL " + version + "; versions before 13 need the pgcrypto extension"
+		}
+		return OK, "PostgreSQL " + version
+	}
+}
+
+// Permissions checks that the connecting role can create tables, which the
+// migrations do, and work with the users table once it exists
+func Permissions(db *sql.DB) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		var role string
+		var canCreate, usersExist bool
+		if err := db.QueryRowContext(ctx, `SELECT current_user,
+			has_schema_privilege(current_schema(), 'CREATE'),
+			to_regclass('users') IS NOT NULL`).Scan(&role, &canCreate, &usersExist); err != nil {
+			return Failed(err)
+		}
+		if usersExist {
+			var canWrite bool
+			if err := db.QueryRowContext(ctx,
+				`SELECT has_table_privilege('users', 'SELECT, INSERT, UPDATE, DELETE')`).Scan(&canWrite); err != nil {
+				return Failed(err)
+			}
+			if !canWrite {
+				return Fail, "role " + role + " cannot read and write the users table"
+			}
+		}
+		if !canCreate {
+			return Warn, "role " + role + " cannot create tables; migrations must be run by another role"
+		}
+		return OK, "role " + role + " can create tables and use them"
+	}
+}
+
+// Extension checks that name is installed, or can be: the migrations create the
+// extensions they need
+func Extension(db *sql.DB, name string) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		var available bool
+		var installed sql.NullString
+		if err := db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = $1),
+			        (SELECT extversion FROM pg_extension WHERE extname = $1)`, name).Scan(&available, &installed); err != nil {
+			return Failed(err)
+		}
+		switch {
+		case installed.Valid:
+			return OK, name + " " + installed.String + " installed"
+		case available:
+			return Warn, name + " not installed yet; the migrations create it, which needs the CREATE privilege on the database"
+		default:
+			return Fail, name + " is not available on the server; install the PostgreSQL contrib package"
+		}
+	}
+}
+
+// UUIDFunction checks that gen_random_uuid(), used for user UUIDs, can be called
+func UUIDFunction(db *sql.DB) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		var found bool
+		if err := db.QueryRowContext(ctx, `SELECT to_regproc('gen_random_uuid') IS NOT NULL`).Scan(&found); err != nil {
+			return Failed(err)
+		}
+		if !found {
+			return Fail, "gen_random_uuid() is missing; run CREATE EXTENSION pgcrypto"
+		}
+		return OK, "gen_random_uuid() available"
+	}
+}
+
+// Migrations reports schema migrations the database lacks
+func Migrations(db *sql.DB) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		version, pending, err := migrations.Pending(ctx, db)
+		if err != nil {
+			return Failed(err)
+		}
+		if pending > 0 {
+			return Warn, fmt.Sprintf("schema at version %d, %d migrations pending; run --migrate-only or enable database.auto_migrate", version, pending)
+		}
+		return OK, fmt.Sprintf("schema at version %d, up to date", version)
+	}
+}
+
+// Clock thresholds of ClockSkew
+const (
+	skewWarn = time.Second
+	skewFail = time.Minute
+)
+
+// ClockSkew compares this host's clock with the database's. Token expiry,
+// signed requests and scheduled jobs assume they agree.
+func ClockSkew(db *sql.DB) func(ctx context.Context) (Status, string) {
+	return func(ctx context.Context) (Status, string) {
+		sent := time.Now()
+		var dbNow time.Time
+		if err := db.QueryRowContext(ctx, `SELECT clock_timestamp()`).Scan(&dbNow); err != nil {
+			return Failed(err)
+		}
+		return skewStatus(Skew(sent, time.Now(), dbNow))
+	}
+}
+
+// Skew estimates how far a remote clock that read remote between sent and
+// received is ahead of the local one, assuming it read it halfway through
+func Skew(sent, received, remote time.Time) time.Duration {
+	return remote.Sub(sent.Add(received.Sub(sent) / 2))
+}
+
+func skewStatus(skew time.Duration) (Status, string) {
+	abs := skew.Abs()
+	detail := fmt.Sprintf("database clock differs by %s", skew.Round(time.Millisecond))
+	switch {
+	case abs > skewFail:
+		return Fail, detail + "; sync the clocks with NTP"
+	case abs > skewWarn:
+		return Warn, detail
+	}
+	return OK, detail
+}
@@ -0,0 +1,57 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSkew(t *testing.T) {
+	// Given: A query sent at 12:00:00 and answered 200ms later
+	sent := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	received := sent.Add(200 * time.Millisecond)
+
+	// When: The database read 12:00:03.1
+	skew := Skew(sent, received, sent.Add(3100*time.Millisecond))
+
+	// Then: Its clock is 3s ahead, the round trip split evenly
+	if skew != 3*time.Second {
+		t.Errorf("expected 3s, got %s", skew)
+	}
+	if status, _ := skewStatus(skew); status != Warn {
+		t.Errorf("expected a warning for 3s, got %s", status)
+	}
+	if status, _ := skewStatus(-2 * time.Minute); status != Fail {
+		t.Errorf("expected a failure for a clock 2 minutes behind, got %s", status)
+	}
+}
+
+func TestReport(t *testing.T) {
+	// Given: Checks that pass, warn and fail
+	checks := []Check{
+		{Name: "database", Run: func(context.Context) (Status, string) { return OK, "connected" }},
+		{Name: "migrations", Run: func(context.Context) (Status, string) { return Warn, "2 pending" }},
+		{Name: "redis", Run: func(ctx context.Context) (Status, string) {
+			<-ctx.Done()
+			return Failed(ctx.Err())
+		}},
+	}
+
+	// When: Running them, the last one until it times out
+	results := Run(context.Background(), checks, 10*time.Millisecond)
+	var out bytes.Buffer
+	Report(&out, results)
+
+	// Then: Every check is listed and the failure makes the report not ready
+	want := "[OK  ] database    connected\n" +
+		"[WARN] migrations  2 pending\n" +
+		"[FAIL] redis       context deadline exceeded\n" +
+		"not ready\n"
+	if out.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, out.String())
+	}
+	if !Ready(results[:2]) || Ready(results) {
+		t.Error("expected warnings to be ready and failures not")
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+)
+
+// User lifecycle event types
+const (
+	UserCreated  = "user.created"
+	UserUpdated  = "user.updated"
+	UserDeleted  = "user.deleted"
+	UserRestored = "user.restored"
+)
+
+// AllEvents subscribes a handler to every event type
+const AllEvents = "*"
+
+// Event is a change to a user that has been committed to the database
+type Event struct {
+	Type     string      `json:"type"`
+	UserUUID string      `json:"user_uuid"`
+	User     *model.User `json:"user,omitempty"` // nil for deletions
+	At       time.Time   `json:"at"`
+	// RequestID is the ID of the request that made the change, for log correlation
+	RequestID string `json:"request_id,omitempty"`
+	// Region is the data residency region of the user, "" for the home region
+	Region string `json:"region,omitempty"`
+}
+
+// Publisher receives events from the services
+type Publisher interface {
+	Publish(e Event)
+}
+
+// Handler processes one event
+type Handler func(e Event)
+
+// Bus delivers events to in-process subscribers. Each handler runs in its own
+// goroutine, so a slow or failing subscriber never delays the request that caused
+// the event; panics are logged and swallowed. Delivery is best effort and is lost
+// if the process exits first.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+	inflight sync.WaitGroup
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[string][]Handler)}
+}
+
+// Subscribe registers fn for eventType, or for every event with AllEvents
+func (b *Bus) Subscribe(eventType string, fn Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], fn)
+}
+
+// Publish implements Publisher
+func (b *Bus) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := append(append([]Handler(nil), b.handlers[e.Type]...), b.handlers[AllEvents]...)
+	b.mu.RUnlock()
+
+	for _, fn := range handlers {
+		b.inflight.Add(1)
+		go func(fn Handler) {
+			defer b.inflight.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("event handler for %s panicked: %v", e.Type, r)
+				}
+			}()
+			fn(e)
+		}(fn)
+	}
+}
+
+// Wait blocks until every handler started so far has returned
+func (b *Bus) Wait() {
+	b.inflight.Wait()
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"cruder/pkg/userchanges"
+)
+
+func TestBus_DeliversToSubscribers(t *testing.T) {
+	// Given: one handler for creations and one for everything
+	bus := NewBus()
+	var mu sync.Mutex
+	got := map[string][]string{}
+	record := func(name string) Handler {
+		return func(e Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = append(got[name], e.Type)
+		}
+	}
+	bus.Subscribe(UserCreated, record("created"))
+	bus.Subscribe(AllEvents, record("all"))
+
+	// When
+	bus.Publish(Event{Type: UserCreated, UserUUID: "a"})
+	bus.Publish(Event{Type: UserDeleted, UserUUID: "a"})
+	bus.Wait()
+
+	// Then
+	if len(got["created"]) != 1 || got["created"][0] != UserCreated {
+		t.Errorf("expected one creation, got %v", got["created"])
+	}
+	if len(got["all"]) != 2 {
+		t.Errorf("expected both events for the wildcard handler, got %v", got["all"])
+	}
+}
+
+func TestBus_RecoversFromPanickingHandler(t *testing.T) {
+	bus := NewBus()
+	delivered := make(chan struct{}, 1)
+	bus.Subscribe(UserUpdated, func(e Event) { panic("boom") })
+	bus.Subscribe(UserUpdated, func(e Event) { delivered <- struct{}{} })
+
+	bus.Publish(Event{Type: UserUpdated})
+	bus.Wait()
+
+	select {
+	case <-delivered:
+	default:
+		t.Error("expected the second handler to run")
+	}
+}
+
+func TestUserChange(t *testing.T) {
+	at := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		eventType string
+		want      string
+		ok        bool
+	}{
+		{UserCreated, userchanges.Created, true},
+		{UserUpdated, userchanges.Updated, true},
+		{UserDeleted, userchanges.Deleted, true},
+		{UserRestored, userchanges.Restored, true},
+		{"user.exported", "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.eventType, func(t *testing.T) {
+			// When
+			change, ok := userChange(Event{Type: tc.eventType, UserUUID: "a", Region: "eu", At: at})
+
+			// Then: Only the user's identity and the kind of change travel
+			if ok != tc.ok {
+				t.Fatalf("expected ok %t, got %t", tc.ok, ok)
+			}
+			if ok && (change != userchanges.Change{Type: tc.want, UUID: "a", Region: "eu", At: at}) {
+				t.Errorf("unexpected change %+v", change)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cruder/internal/metrics"
+	"cruder/pkg/userchanges"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// userChangeTimeout bounds the NOTIFY sent for one event
+const userChangeTimeout = 2 * time.Second
+
+var userChangesFailed = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "user_change_notifications_failed_total",
+	Help: "User change notifications that could not be sent to sibling services.",
+})
+
+// userChangeTypes maps event types to the change types of package userchanges
+var userChangeTypes = map[string]string{
+	UserCreated:  userchanges.Created,
+	UserUpdated:  userchanges.Updated,
+	UserDeleted:  userchanges.Deleted,
+	UserRestored: userchanges.Restored,
+}
+
+// NotifyUserChanges returns a handler that forwards user events to the services
+// subscribed with package userchanges on db's database. A failed notification is
+// logged and counted; subscribers that missed it keep their stale entry until it
+// expires.
+func NotifyUserChanges(db userchanges.Execer) Handler {
+	return func(e Event) {
+		change, ok := userChange(e)
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), userChangeTimeout)
+		defer cancel()
+		if err := userchanges.Notify(ctx, db, change); err != nil {
+			userChangesFailed.Inc()
+			log.Printf("failed to notify the change of user %s: %v", e.UserUUID, err)
+		}
+	}
+}
+
+// userChange is the notification for e; ok is false for events that are not user changes
+func userChange(e Event) (userchanges.Change, bool) {
+	changeType, ok := userChangeTypes[e.Type]
+	if !ok {
+		return userchanges.Change{}, false
+	}
+	return userchanges.Change{Type: changeType, UUID: e.UserUUID, Region: e.Region, At: e.At}, true
+}
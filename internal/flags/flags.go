@@ -0,0 +1,47 @@
+// Package flags evaluates the feature flags of the configuration, so a new
+// behaviour, such as a new response shape, can reach a share of callers before
+// everyone. Each caller is hashed with the flag name into one of 10000 buckets: a
+// caller gets the same answer on every request and replica, raising the percentage
+// only adds callers, and each flag picks a different share.
+package flags
+
+import "hash/fnv"
+
+// buckets is how finely percentages are applied, down to 0.01%
+const buckets = 10000
+
+// Set holds the rollout percentage of each flag
+type Set struct {
+	percentages map[string]float64
+}
+
+// New creates a Set from the percentage of callers each flag is on for, 0-100
+func New(percentages map[string]float64) *Set {
+	s := &Set{percentages: make(map[string]float64, len(percentages))}
+	for name, p := range percentages {
+		s.percentages[name] = p
+	}
+	return s
+}
+
+// Enabled reports whether flag is on for subject, a stable identifier of the
+// caller such as its API key. Unknown flags are off, as is every flag of a nil Set.
+func (s *Set) Enabled(flag, subject string) bool {
+	if s == nil {
+		return false
+	}
+	p, ok := s.percentages[flag]
+	if !ok || p <= 0 {
+		return false
+	}
+	return float64(Bucket(flag, subject)) < p*buckets/100
+}
+
+// Bucket returns the bucket, from 0 to 9999, subject falls in for flag
+func Bucket(flag, subject string) int {
+	h := fnv.New64a()
+	h.Write([]byte(flag))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+	return int(h.Sum64() % buckets)
+}
@@ -0,0 +1,89 @@
+package flags
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnabled(t *testing.T) {
+	set := New(map[string]float64{"off": 0, "all": 100, "v2_response_shape": 5})
+
+	tests := []struct {
+		name string
+		flag string
+		want bool
+	}{
+		{name: "off for everyone at 0%", flag: "off", want: false},
+		{name: "on for everyone at 100%", flag: "all", want: true},
+		{name: "unknown flags are off", flag: "missing", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range 100 {
+				if got := set.Enabled(tt.flag, fmt.Sprintf("api_key:client-%d", i)); got != tt.want {
+					t.Fatalf("Enabled(%q) = %v, want %v", tt.flag, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEnabled_RollsOutToThePercentage(t *testing.T) {
+	// Given: A flag on for 5% of callers
+	set := New(map[string]float64{"v2_response_shape": 5})
+
+	// When: Evaluating it for many callers, twice
+	on := 0
+	for i := range 20000 {
+		subject := fmt.Sprintf("api_key:client-%d", i)
+		first := set.Enabled("v2_response_shape", subject)
+		if set.Enabled("v2_response_shape", subject) != first {
+			t.Fatalf("expected the same answer for %s on every call", subject)
+		}
+		if first {
+			on++
+		}
+	}
+
+	// Then: About 5% of them get it
+	if on < 800 || on > 1200 {
+		t.Errorf("expected about 1000 of 20000 callers, got %d", on)
+	}
+}
+
+func TestEnabled_RaisingThePercentageKeepsCallers(t *testing.T) {
+	// Given: The same flag at 5% and at 25%
+	small := New(map[string]float64{"v2_response_shape": 5})
+	large := New(map[string]float64{"v2_response_shape": 25})
+
+	// Then: Every caller in the 5% is still in the 25%
+	for i := range 5000 {
+		subject := fmt.Sprintf("user:%d", i)
+		if small.Enabled("v2_response_shape", subject) && !large.Enabled("v2_response_shape", subject) {
+			t.Fatalf("expected %s to keep the flag when the rollout grows", subject)
+		}
+	}
+}
+
+func TestEnabled_NilSet(t *testing.T) {
+	var set *Set
+	if set.Enabled("v2_response_shape", "api_key:client") {
+		t.Error("expected every flag of a nil Set to be off")
+	}
+}
+
+func TestBucket_DiffersPerFlag(t *testing.T) {
+	// Given: Many callers bucketed for two flags
+	same := 0
+	for i := range 1000 {
+		subject := fmt.Sprintf("api_key:client-%d", i)
+		if Bucket("a", subject) == Bucket("b", subject) {
+			same++
+		}
+	}
+
+	// Then: The flags do not pick the same callers
+	if same > 10 {
+		t.Errorf("expected flags to bucket callers independently, %d of 1000 matched", same)
+	}
+}
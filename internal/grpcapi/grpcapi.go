@@ -0,0 +1,80 @@
+// Package grpcapi serves the user API over gRPC, for internal services that want
+// generated clients and protobuf instead of JSON over HTTP. It calls the services
+// the REST controllers call, and authenticates, authorizes and reports errors as
+// the REST API does, so the two cannot drift apart. The API is described by
+// api/proto/users/v1/users.proto; pkg/userspb holds the generated code.
+package grpcapi
+
+import (
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/controller"
+	"cruder/internal/middleware"
+	"cruder/internal/policy"
+	"cruder/internal/ratelimit"
+	"cruder/internal/service"
+	"cruder/pkg/userspb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// Options configures the gRPC server like handler.Options configures the router
+type Options struct {
+	// APIKeys, ManagedKeys, Tokens, RequireJWT and PersonalTokens authenticate calls
+	// from the x-api-key and authorization metadata, as on REST. Keys restricted to
+	// origins or requiring signed requests are refused, as calls carry neither.
+	APIKeys        []config.APIKeyConfig
+	ManagedKeys    middleware.APIKeyVerifier
+	Tokens         middleware.TokenVerifier
+	RequireJWT     bool
+	PersonalTokens middleware.PersonalTokenVerifier
+	// Policy authorizes each call as the REST request it stands for, e.g.
+	// DeleteUser as DELETE /api/v1/users/:uuid; nil skips the check
+	Policy         policy.Decider
+	PolicyFailOpen bool
+	// BasePath prefixes the routes given to the policy, as on REST
+	BasePath string
+	// ReadOnly rejects mutating calls while enabled; nil never rejects
+	ReadOnly *middleware.ReadOnlyMode
+	// Residency routes each call to its tenant's region; nil keeps every call in
+	// the home database
+	Residency *config.ResidencyConfig
+	// RateLimits counts calls per caller, in the same buckets as REST requests when
+	// the store is shared; nil disables rate limiting
+	RateLimits ratelimit.Store
+	RateLimit  config.RateLimitConfig
+	// Enumeration hides which users exist from GetUser by ID or username, throttling
+	// username lookups per client IP in LookupLimits, as on REST; nil leaves lookups
+	// as they are
+	Enumeration  *config.EnumerationProtectionConfig
+	LookupLimits ratelimit.Store
+	// RequestTimeout bounds each call that has no earlier deadline; zero sets none
+	RequestTimeout time.Duration
+	// Reflection registers the reflection service for tools such as grpcurl
+	Reflection bool
+}
+
+// NewServer creates the gRPC server of the user API. fields hides user fields from
+// callers as on REST; approvals may be nil to apply deletes and email changes
+// directly.
+func NewServer(users service.UserService, fields *controller.FieldPolicy, approvals service.ApprovalService, opts Options) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		observe,
+		recovery,
+		opts.deadline,
+		opts.authenticate,
+		opts.residency,
+		opts.rateLimit,
+		opts.uniformLookup,
+		opts.authorize,
+		opts.readOnly,
+		opts.usernameLookupLimit,
+	))
+	userspb.RegisterUserServiceServer(srv, &userServer{users: users, fields: fields, approvals: approvals})
+	if opts.Reflection {
+		reflection.Register(srv)
+	}
+	return srv
+}
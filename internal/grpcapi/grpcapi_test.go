@@ -0,0 +1,322 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/controller"
+	"cruder/internal/model"
+	"cruder/internal/policy"
+	"cruder/internal/ratelimit"
+	"cruder/internal/service"
+	"cruder/pkg/userspb"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// mockUserService keeps users in memory, keyed by UUID; methods the tests do not
+// call are left to the embedded interface and panic
+type mockUserService struct {
+	service.UserService
+	users map[string]*model.User
+}
+
+func (m *mockUserService) GetByUUID(_ context.Context, uuid string) (*model.User, error) {
+	if user, ok := m.users[uuid]; ok {
+		return user, nil
+	}
+	return nil, service.ErrUserNotFound
+}
+
+func (m *mockUserService) GetByUsername(_ context.Context, username string) (*model.User, error) {
+	for _, user := range m.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, service.ErrUserNotFound
+}
+
+func (m *mockUserService) Create(_ context.Context, user *model.User) error {
+	for _, existing := range m.users {
+		if existing.Username == user.Username {
+			return service.ErrUsernameTaken
+		}
+	}
+	if user.Email == "invalid" {
+		return &service.ValidationError{Errors: []model.FieldError{{Field: "email", Code: "invalid_email", Message: "email is invalid"}}}
+	}
+	user.ID = int64(len(m.users) + 1)
+	user.UUID = fmt.Sprintf("00000000-0000-0000-0000-%012d", user.ID)
+	user.Version = 1
+	m.users[user.UUID] = user
+	return nil
+}
+
+func (m *mockUserService) Update(_ context.Context, uuid string, patch model.UserPatch) error {
+	user, ok := m.users[uuid]
+	if !ok {
+		return service.ErrUserNotFound
+	}
+	if patch.Version != nil && *patch.Version != user.Version {
+		return service.ErrVersionMismatch
+	}
+	if patch.FullName != nil {
+		user.FullName = *patch.FullName
+	}
+	user.Version++
+	return nil
+}
+
+func (m *mockUserService) Delete(_ context.Context, uuid string) error {
+	if _, ok := m.users[uuid]; !ok {
+		return service.ErrUserNotFound
+	}
+	delete(m.users, uuid)
+	return nil
+}
+
+// stubDecider denies the requests for which deny returns true
+type stubDecider struct {
+	deny   func(policy.Input) bool
+	inputs []policy.Input
+}
+
+func (d *stubDecider) Decide(_ context.Context, input policy.Input) (policy.Decision, error) {
+	d.inputs = append(d.inputs, input)
+	return policy.Decision{Allow: !d.deny(input)}, nil
+}
+
+func newClient(t *testing.T, fields config.FieldPolicyConfig, opts Options) userspb.UserServiceClient {
+	t.Helper()
+	users := &mockUserService{users: map[string]*model.User{}}
+	srv := NewServer(users, controller.NewFieldPolicy(fields), nil, opts)
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return userspb.NewUserServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+// reason returns the code of the API error carried by err
+func reason(err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+var testKeys = []config.APIKeyConfig{
+	{Name: "admin", Key: "admin-key", Scopes: []string{"admin"}},
+	{Name: "reader", Key: "reader-key", Scopes: []string{"read"}},
+	{Name: "widget", Key: "widget-key", AllowedOrigins: []string{"https://example.com"}},
+}
+
+func TestUserService_CRUD(t *testing.T) {
+	// Given: A server hiding emails from callers without the pii scope
+	client := newClient(t, config.FieldPolicyConfig{Fields: map[string][]string{"email": {"pii"}}}, Options{APIKeys: testKeys})
+	ctx := withKey("admin-key")
+
+	// When: Creating a user
+	fields, _ := structpb.NewStruct(map[string]any{"team": "ops"})
+	created, err := client.CreateUser(ctx, &userspb.CreateUserRequest{Username: "jsmith", Email: "j@example.com", FullName: "John Smith", CustomFields: fields})
+
+	// Then: It is returned with its UUID and custom fields
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Uuid == "" || created.CustomFields.Fields["team"].GetStringValue() != "ops" {
+		t.Errorf("unexpected user %v", created)
+	}
+
+	// When/Then: Reading it back by UUID and by username
+	for _, req := range []*userspb.GetUserRequest{
+		{Key: &userspb.GetUserRequest_Uuid{Uuid: created.Uuid}},
+		{Key: &userspb.GetUserRequest_Username{Username: "jsmith"}},
+	} {
+		got, err := client.GetUser(ctx, req)
+		if err != nil || got.Email != "j@example.com" {
+			t.Errorf("get %v: %v %v", req, got, err)
+		}
+	}
+
+	// When/Then: A caller without the pii scope reads it without the email
+	got, err := client.GetUser(withKey("reader-key"), &userspb.GetUserRequest{Key: &userspb.GetUserRequest_Uuid{Uuid: created.Uuid}})
+	if err != nil || got.Email != "" || got.Username != "jsmith" {
+		t.Errorf("reader got %v, %v", got, err)
+	}
+
+	// When/Then: Updating against the current version returns the updated user
+	updated, err := client.UpdateUser(ctx, &userspb.UpdateUserRequest{Uuid: created.Uuid, FullName: wrapperspb.String("Jane Smith"), Version: wrapperspb.Int64(1)})
+	if err != nil || updated.User.FullName != "Jane Smith" || updated.User.Version != 2 {
+		t.Errorf("update: %v, %v", updated, err)
+	}
+	// ...and against a stale one is aborted
+	_, err = client.UpdateUser(ctx, &userspb.UpdateUserRequest{Uuid: created.Uuid, FullName: wrapperspb.String("J"), Version: wrapperspb.Int64(1)})
+	if status.Code(err) != codes.Aborted || reason(err) != "version_mismatch" {
+		t.Errorf("stale update: %v", err)
+	}
+
+	// When/Then: Deleting it, after which it is not found
+	if _, err := client.DeleteUser(ctx, &userspb.DeleteUserRequest{Uuid: created.Uuid}); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+	_, err = client.GetUser(ctx, &userspb.GetUserRequest{Key: &userspb.GetUserRequest_Uuid{Uuid: created.Uuid}})
+	if status.Code(err) != codes.NotFound || reason(err) != "user_not_found" {
+		t.Errorf("get deleted: %v", err)
+	}
+}
+
+func TestUserService_Errors(t *testing.T) {
+	// Given: A server with a user
+	client := newClient(t, config.FieldPolicyConfig{}, Options{APIKeys: testKeys})
+	if _, err := client.CreateUser(withKey("admin-key"), &userspb.CreateUserRequest{Username: "jsmith", Email: "j@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		req    *userspb.CreateUserRequest
+		code   codes.Code
+		reason string
+	}{
+		{"no key", context.Background(), &userspb.CreateUserRequest{Username: "a", Email: "a@example.com"}, codes.Unauthenticated, "api_key_required"},
+		{"unknown key", withKey("nope"), &userspb.CreateUserRequest{Username: "a", Email: "a@example.com"}, codes.PermissionDenied, "invalid_api_key"},
+		{"browser key", withKey("widget-key"), &userspb.CreateUserRequest{Username: "a", Email: "a@example.com"}, codes.PermissionDenied, "api_key_not_allowed"},
+		{"missing email", withKey("admin-key"), &userspb.CreateUserRequest{Username: "a"}, codes.InvalidArgument, "invalid_request"},
+		{"taken", withKey("admin-key"), &userspb.CreateUserRequest{Username: "jsmith", Email: "a@example.com"}, codes.AlreadyExists, "username_taken"},
+		{"invalid", withKey("admin-key"), &userspb.CreateUserRequest{Username: "a", Email: "invalid"}, codes.InvalidArgument, "invalid_user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When: Creating a user
+			_, err := client.CreateUser(tt.ctx, tt.req)
+
+			// Then: The call fails with the code, and the REST error code as reason
+			if status.Code(err) != tt.code || reason(err) != tt.reason {
+				t.Errorf("expected %s %s, got %v (%s)", tt.code, tt.reason, err, reason(err))
+			}
+		})
+	}
+
+	// When/Then: A validation error lists the failing fields
+	_, err := client.CreateUser(withKey("admin-key"), &userspb.CreateUserRequest{Username: "a", Email: "invalid"})
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range status.Convert(err).Details() {
+		if bad, ok := detail.(*errdetails.BadRequest); ok {
+			violations = bad.FieldViolations
+		}
+	}
+	if len(violations) != 1 || violations[0].Field != "email" || violations[0].Reason != "invalid_email" {
+		t.Errorf("unexpected violations %v", violations)
+	}
+
+	// When/Then: A malformed UUID is rejected before reaching the database
+	_, err = client.DeleteUser(withKey("admin-key"), &userspb.DeleteUserRequest{Uuid: "not-a-uuid"})
+	if status.Code(err) != codes.InvalidArgument || reason(err) != "invalid_request" {
+		t.Errorf("malformed uuid: %v", err)
+	}
+}
+
+func TestUserService_Policy(t *testing.T) {
+	// Given: A policy denying deletes
+	decider := &stubDecider{deny: func(input policy.Input) bool { return input.Method == "DELETE" }}
+	client := newClient(t, config.FieldPolicyConfig{}, Options{APIKeys: testKeys, Policy: decider, BasePath: "/user-service"})
+	ctx := withKey("admin-key")
+	created, err := client.CreateUser(ctx, &userspb.CreateUserRequest{Username: "jsmith", Email: "j@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When: Deleting the user
+	_, err = client.DeleteUser(ctx, &userspb.DeleteUserRequest{Uuid: created.Uuid})
+
+	// Then: The call is denied, and the policy saw it as the REST request
+	if status.Code(err) != codes.PermissionDenied || reason(err) != "forbidden_by_policy" {
+		t.Errorf("expected forbidden_by_policy, got %v", err)
+	}
+	input := decider.inputs[len(decider.inputs)-1]
+	if input.Route != "/user-service/api/v1/users/:uuid" || input.Path != "/user-service/api/v1/users/"+created.Uuid ||
+		input.Resource == nil || input.Resource.ID != created.Uuid || input.Principal == nil || input.Principal.Name != "admin" {
+		t.Errorf("unexpected policy input %+v", input)
+	}
+}
+
+func TestUserService_UniformLookup(t *testing.T) {
+	// Given: Enumeration protection, and a policy denying lookups of jsmith
+	decider := &stubDecider{deny: func(input policy.Input) bool { return input.Params["username"] == "jsmith" }}
+	client := newClient(t, config.FieldPolicyConfig{}, Options{APIKeys: testKeys, Policy: decider,
+		Enumeration:  &config.EnumerationProtectionConfig{MinResponseTime: time.Millisecond, UsernameLookups: 10, UsernameLookupWindow: time.Minute},
+		LookupLimits: ratelimit.NewMemoryStore()})
+	ctx := withKey("admin-key")
+	if _, err := client.CreateUser(ctx, &userspb.CreateUserRequest{Username: "jsmith", Email: "j@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// When: Looking up the forbidden user and one that does not exist
+	_, forbidden := client.GetUser(ctx, &userspb.GetUserRequest{Key: &userspb.GetUserRequest_Username{Username: "jsmith"}})
+	_, missing := client.GetUser(ctx, &userspb.GetUserRequest{Key: &userspb.GetUserRequest_Username{Username: "nobody"}})
+
+	// Then: Both get the same answer
+	if status.Code(forbidden) != codes.NotFound || reason(forbidden) != "user_not_found" {
+		t.Errorf("expected the forbidden lookup to be user_not_found, got %v", forbidden)
+	}
+	if status.Convert(forbidden).Message() != status.Convert(missing).Message() || status.Code(forbidden) != status.Code(missing) || reason(forbidden) != reason(missing) {
+		t.Errorf("expected the same answer, got %v and %v", forbidden, missing)
+	}
+}
+
+func TestUserService_RateLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"per caller", Options{APIKeys: testKeys, RateLimits: ratelimit.NewMemoryStore(), RateLimit: config.RateLimitConfig{Requests: 2, Window: time.Minute}}},
+		{"username lookups per IP", Options{APIKeys: testKeys,
+			Enumeration: &config.EnumerationProtectionConfig{UsernameLookups: 2, UsernameLookupWindow: time.Minute}, LookupLimits: ratelimit.NewMemoryStore()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: A server allowing two lookups a minute
+			client := newClient(t, config.FieldPolicyConfig{}, tt.opts)
+			req := &userspb.GetUserRequest{Key: &userspb.GetUserRequest_Username{Username: "nobody"}}
+
+			// When: Looking up three times
+			var err error
+			for range 3 {
+				_, err = client.GetUser(withKey("reader-key"), req)
+			}
+
+			// Then: The third lookup is refused
+			if status.Code(err) != codes.ResourceExhausted || reason(err) != "rate_limit_exceeded" {
+				t.Errorf("expected rate_limit_exceeded, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/auth"
+	"cruder/internal/controller"
+	"cruder/internal/logging"
+	"cruder/internal/metrics"
+	"cruder/internal/middleware"
+	"cruder/internal/policy"
+	"cruder/internal/ratelimit"
+	"cruder/internal/residency"
+	"cruder/internal/version"
+	"cruder/pkg/userspb"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	callsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "grpc_server_handled_total",
+		Help: "Total number of gRPC calls served, by method and status code.",
+	}, []string{"method", "code"})
+	callDuration = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "grpc_server_handling_seconds",
+		Help:    "gRPC call latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// requestIDKey is the metadata key of the request ID, as X-Request-ID on REST
+const requestIDKey = "x-request-id"
+
+// observe logs, counts and times every call, tying its records together with a
+// request ID, and turns the error it ends with into a gRPC status
+func observe(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	id := middleware.RequestIDOrNew(firstValue(ctx, requestIDKey))
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+	ctx = logging.WithRequestFields(logging.WithRequestID(ctx, id))
+	logging.SetRoute(ctx, info.FullMethod)
+
+	resp, err := handler(ctx, req)
+	st := toStatus(err)
+	code := status.Code(st)
+
+	method := path.Base(info.FullMethod)
+	callsTotal.WithLabelValues(method, code.String()).Inc()
+	callDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+
+	build := version.Get()
+	attrs := []slog.Attr{
+		slog.Int64("rpc.server.duration", time.Since(start).Milliseconds()),
+		slog.String("rpc.system", "grpc"),
+		slog.String("rpc.method", info.FullMethod),
+		slog.String("rpc.grpc.status_code", code.String()),
+		slog.String("client.address", clientAddress(ctx)),
+		slog.String("service.version", build.Version),
+		slog.String("service.commit", build.ShortCommit()),
+	}
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("error", err))
+	default:
+		level = slog.LevelWarn
+	}
+	slog.LogAttrs(ctx, level, "Incoming call", attrs...)
+	return resp, st
+}
+
+// recovery answers a call whose handler panicked with Internal, logging the stack
+func recovery(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "panic serving call", "rpc.method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+			err = apierror.Internal(apierror.InternalCode, "internal server error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+// deadline bounds the call by RequestTimeout, unless the client set a sooner one
+func (o Options) deadline(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.RequestTimeout <= 0 {
+		return handler(ctx, req)
+	}
+	ctx, cancel := context.WithTimeout(ctx, o.RequestTimeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+// authenticate identifies the caller as the router's authentication does: a
+// personal token, then a managed API key, then a JWT or configured API key
+func (o Options) authenticate(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	p, err := o.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(middleware.WithPrincipal(ctx, p), req)
+}
+
+func (o Options) principal(ctx context.Context) (*middleware.Principal, error) {
+	token, hasToken := bearerToken(ctx)
+	if hasToken && o.PersonalTokens != nil && auth.IsPersonalToken(token) {
+		t, err := o.PersonalTokens.Verify(ctx, token)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidToken) {
+				slog.ErrorContext(ctx, "failed to verify personal token", "error", err)
+				return nil, apierror.Unavailable("token_verification_unavailable", "failed to verify token")
+			}
+			return nil, apierror.Unauthorized("invalid_token", "invalid token")
+		}
+		return &middleware.Principal{Name: t.Username, Type: "personal_token", Scopes: t.Scopes, Tenant: t.Tenant}, nil
+	}
+
+	apiKey := firstValue(ctx, "x-api-key")
+	if o.ManagedKeys != nil && !o.RequireJWT && auth.IsManagedAPIKey(apiKey) {
+		key, err := o.ManagedKeys.Verify(ctx, apiKey)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidAPIKey) {
+				slog.ErrorContext(ctx, "failed to verify API key", "error", err)
+				return nil, apierror.Unavailable("api_key_verification_unavailable", "failed to verify API key")
+			}
+			return nil, apierror.Forbidden("invalid_api_key", "Invalid API key")
+		}
+		return &middleware.Principal{Name: key.Name, Type: "api_key", Scopes: key.Scopes, Tenant: key.Tenant}, nil
+	}
+
+	if o.Tokens != nil && (hasToken || o.RequireJWT) {
+		if !hasToken {
+			return nil, apierror.Unauthorized("token_required", "bearer token required")
+		}
+		claims, err := o.Tokens.Verify(token)
+		if err != nil {
+			return nil, apierror.Unauthorized("invalid_token", "invalid token")
+		}
+		return &middleware.Principal{Name: claims.Subject, Type: "jwt", Scopes: claims.Scopes, Tenant: claims.Tenant}, nil
+	}
+
+	if apiKey == "" {
+		return nil, apierror.Unauthorized("api_key_required", "API key required")
+	}
+	key := middleware.FindAPIKey(o.APIKeys, apiKey)
+	if key == nil {
+		return nil, apierror.Forbidden("invalid_api_key", "Invalid API key")
+	}
+	// Calls carry no Origin or Referer and are not signed, so keys that require
+	// them cannot be honored
+	if len(key.AllowedOrigins) > 0 || len(key.AllowedReferrers) > 0 || key.SigningSecret != "" {
+		return nil, apierror.Forbidden("api_key_not_allowed", "API key cannot be used over gRPC")
+	}
+	return &middleware.Principal{Name: key.Name, Type: "api_key", Scopes: key.Scopes, Tenant: key.Tenant}, nil
+}
+
+// residency routes the call to the database of its tenant's region
+func (o Options) residency(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.Residency == nil {
+		return handler(ctx, req)
+	}
+	region := o.Residency.HomeRegion
+	if p := middleware.PrincipalFromContext(ctx); p != nil {
+		if r, ok := o.Residency.Tenants[p.Tenant]; ok {
+			region = r
+		}
+	}
+	return handler(residency.WithRegion(ctx, region), req)
+}
+
+// rateLimit allows each caller RateLimit.Requests calls per RateLimit.Window, as
+// the REST rate limit does
+func (o Options) rateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.RateLimits == nil {
+		return handler(ctx, req)
+	}
+	key := "ip:" + clientAddress(ctx)
+	if p := middleware.PrincipalFromContext(ctx); p != nil {
+		key = p.Type + ":" + p.Name
+	}
+	if err := o.hit(ctx, o.RateLimits, key, o.RateLimit.Requests, o.RateLimit.Window); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// usernameLookupLimit allows each client IP Enumeration.UsernameLookups lookups by
+// username per window, counted with the REST ones when the store is shared
+func (o Options) usernameLookupLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.Enumeration == nil {
+		return handler(ctx, req)
+	}
+	if r, ok := req.(*userspb.GetUserRequest); !ok || r.GetUsername() == "" {
+		return handler(ctx, req)
+	}
+	if err := o.hit(ctx, o.LookupLimits, "username-lookup:ip:"+clientAddress(ctx), o.Enumeration.UsernameLookups, o.Enumeration.UsernameLookupWindow); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// hit counts a call of key, and returns the error to answer it with once key has
+// made more than limit calls in window
+func (o Options) hit(ctx context.Context, store ratelimit.Store, key string, limit int, window time.Duration) error {
+	count, reset, err := store.Hit(ctx, key, window)
+	if err != nil {
+		slog.ErrorContext(ctx, "rate limit check failed", "error", err)
+		if o.RateLimit.FailOpen {
+			return nil
+		}
+		return apierror.Unavailable("rate_limit_unavailable", "rate limiting unavailable")
+	}
+	if count > int64(limit) {
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(reset.Seconds())))))
+		return apierror.RateLimited("rate_limit_exceeded", "rate limit exceeded")
+	}
+	return nil
+}
+
+// uniformLookup keeps GetUser by ID or username from telling whether the user
+// exists, as middleware.UniformLookup does on REST: a forbidden or not-found
+// answer becomes user_not_found, and every answer takes Enumeration.MinResponseTime
+// at least. It runs before authorize so it sees its denials.
+func (o Options) uniformLookup(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.Enumeration == nil {
+		return handler(ctx, req)
+	}
+	r, ok := req.(*userspb.GetUserRequest)
+	if !ok || r.GetUuid() != "" {
+		return handler(ctx, req)
+	}
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	time.Sleep(time.Until(start.Add(o.Enumeration.MinResponseTime)))
+	if errors.Is(err, apierror.ErrForbidden) || errors.Is(err, apierror.ErrNotFound) {
+		return nil, controller.UserNotFound
+	}
+	return resp, err
+}
+
+// authorize asks the policy engine about the REST request the call stands for, so
+// one policy covers both APIs
+func (o Options) authorize(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.Policy == nil {
+		return handler(ctx, req)
+	}
+	input := o.policyInput(ctx, req)
+	decision, err := o.Policy.Decide(ctx, input)
+	if err != nil {
+		slog.ErrorContext(ctx, "policy decision failed", "rpc.method", info.FullMethod, "error", err)
+		if o.PolicyFailOpen {
+			return handler(ctx, req)
+		}
+		return nil, apierror.Unavailable("policy_unavailable", "authorization service unavailable")
+	}
+	if !decision.Allow {
+		err := apierror.Forbidden("forbidden_by_policy", "forbidden by policy")
+		if decision.Reason != "" {
+			err = err.WithDetails(map[string]string{"reason": decision.Reason})
+		}
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// readOnly rejects mutating calls while the read-only mode is enabled
+func (o Options) readOnly(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if o.ReadOnly == nil {
+		return handler(ctx, req)
+	}
+	if method, _, _ := restRequest(req); method == "GET" {
+		return handler(ctx, req)
+	}
+	if !o.ReadOnly.State().Enabled {
+		return handler(ctx, req)
+	}
+	return nil, apierror.Unavailable("read_only", "service is in read-only mode")
+}
+
+// policyInput describes the call as its REST request
+func (o Options) policyInput(ctx context.Context, req any) policy.Input {
+	method, route, params := restRequest(req)
+	route = o.BasePath + route
+	input := policy.Input{
+		Method:   method,
+		Route:    route,
+		Path:     route,
+		Params:   params,
+		ClientIP: clientAddress(ctx),
+	}
+	for name, value := range params {
+		input.Path = strings.Replace(input.Path, ":"+name, value, 1)
+	}
+	if p := middleware.PrincipalFromContext(ctx); p != nil {
+		scopes := p.Scopes
+		if scopes == nil {
+			scopes = []string{}
+		}
+		input.Principal = &policy.Principal{Name: p.Name, Type: p.Type, Scopes: scopes, Tenant: p.Tenant}
+	}
+	if uuid := params["uuid"]; uuid != "" {
+		input.Resource = &policy.Resource{Type: "user", ID: uuid, Owner: uuid}
+	}
+	return input
+}
+
+// restRequest returns the method, route and route parameters of the REST request
+// a call's request stands for
+func restRequest(req any) (method, route string, params map[string]string) {
+	const users = "/api/v1/users"
+	switch r := req.(type) {
+	case *userspb.GetUserRequest:
+		switch key := r.Key.(type) {
+		case *userspb.GetUserRequest_Id:
+			return "GET", users + "/id/:id", map[string]string{"id": strconv.FormatInt(key.Id, 10)}
+		case *userspb.GetUserRequest_Username:
+			return "GET", users + "/username/:username", map[string]string{"username": key.Username}
+		default:
+			return "GET", users + "/:uuid", map[string]string{"uuid": r.GetUuid()}
+		}
+	case *userspb.ListUsersRequest:
+		return "GET", users + "/", map[string]string{}
+	case *userspb.CreateUserRequest:
+		return "POST", users + "/", map[string]string{}
+	case *userspb.UpdateUserRequest:
+		return "PATCH", users + "/:uuid", map[string]string{"uuid": r.Uuid}
+	case *userspb.DeleteUserRequest:
+		return "DELETE", users + "/:uuid", map[string]string{"uuid": r.Uuid}
+	}
+	return "POST", "", map[string]string{}
+}
+
+// firstValue returns the first value of the metadata key, or ""
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(firstValue(ctx, "authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// clientAddress is the IP of the peer; gRPC clients are internal, so no proxy
+// headers are trusted
+func clientAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
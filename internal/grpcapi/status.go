@@ -0,0 +1,91 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"cruder/internal/apierror"
+	"cruder/internal/model"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo detail of every error
+const errorDomain = "cruder"
+
+// codeOf maps each apierror kind to the gRPC code it is answered with
+var codeOf = map[error]codes.Code{
+	apierror.ErrValidation:           codes.InvalidArgument,
+	apierror.ErrUnauthorized:         codes.Unauthenticated,
+	apierror.ErrForbidden:            codes.PermissionDenied,
+	apierror.ErrNotFound:             codes.NotFound,
+	apierror.ErrConflict:             codes.AlreadyExists,
+	apierror.ErrPreconditionFailed:   codes.Aborted,
+	apierror.ErrPreconditionRequired: codes.FailedPrecondition,
+	apierror.ErrTooLarge:             codes.InvalidArgument,
+	apierror.ErrUnprocessable:        codes.FailedPrecondition,
+	apierror.ErrUnsupported:          codes.InvalidArgument,
+	apierror.ErrRateLimited:          codes.ResourceExhausted,
+	apierror.ErrInternal:             codes.Internal,
+	apierror.ErrUnavailable:          codes.Unavailable,
+}
+
+// toStatus turns err into the status the client is told about. An *apierror.Error
+// keeps its message and carries its code as the reason of an ErrorInfo, with the
+// failing fields of a validation error as a BadRequest; any other error becomes
+// a generic Internal so internal details do not reach the client.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "call canceled")
+	}
+
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		return status.Error(codes.Internal, "internal server error")
+	}
+	code := codes.Internal
+	for kind, c := range codeOf {
+		if errors.Is(apiErr, kind) {
+			code = c
+			break
+		}
+	}
+	st := status.New(code, apiErr.Message)
+	info := &errdetails.ErrorInfo{Reason: apiErr.Code, Domain: errorDomain}
+	withInfo, err := st.WithDetails(info)
+	if err != nil {
+		return st.Err()
+	}
+	if fields, ok := apiErr.Details.([]model.FieldError); ok {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(fields))
+		for i, f := range fields {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: f.Field, Description: f.Message, Reason: f.Code}
+		}
+		if withFields, err := withInfo.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			withInfo = withFields
+		}
+	}
+	return withInfo.Err()
+}
+
+// invalidArgument reports a request the API cannot accept as sent
+func invalidArgument(message string) error {
+	return apierror.Validation("invalid_request", message)
+}
+
+// invalidUUID reports a uuid that is not one, as the REST routes do, rather than
+// passing it on to Postgres, which fails on it
+func invalidUUID() error {
+	return invalidArgument("uuid must be a UUID such as 3fa85f64-5717-4562-b3fc-2c963f66afa6")
+}
@@ -0,0 +1,217 @@
+package grpcapi
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/controller"
+	"cruder/internal/middleware"
+	"cruder/internal/model"
+	"cruder/internal/service"
+	"cruder/pkg/userspb"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userServer implements the RPCs as the UserController implements the REST routes
+type userServer struct {
+	userspb.UnimplementedUserServiceServer
+	users     service.UserService
+	fields    *controller.FieldPolicy
+	approvals service.ApprovalService
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *userspb.GetUserRequest) (*userspb.User, error) {
+	var user *model.User
+	var err error
+	switch key := req.Key.(type) {
+	case *userspb.GetUserRequest_Uuid:
+		if !model.IsUUID(key.Uuid) {
+			return nil, invalidUUID()
+		}
+		user, err = s.users.GetByUUID(ctx, key.Uuid)
+	case *userspb.GetUserRequest_Id:
+		user, err = s.users.GetByID(ctx, key.Id)
+	case *userspb.GetUserRequest_Username:
+		user, err = s.users.GetByUsername(ctx, key.Username)
+	default:
+		return nil, invalidArgument("one of uuid, id or username is required")
+	}
+	if err != nil {
+		return nil, controller.APIError(err)
+	}
+	return s.toProto(ctx, user)
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *userspb.ListUsersRequest) (*userspb.ListUsersResponse, error) {
+	query := model.UserQuery{
+		CustomFields: req.CustomFields,
+		EmailDomain:  req.EmailDomain,
+		FullName:     req.FullName,
+		Q:            req.Q,
+		Sort:         req.Sort,
+	}
+	if field := s.fields.HiddenQuery(middleware.PrincipalFromContext(ctx), query); field != "" {
+		return nil, apierror.Forbidden("field_hidden", "not allowed to filter by "+field)
+	}
+
+	var users []model.User
+	var err error
+	if query.Plain() {
+		users, err = s.users.GetAll(ctx)
+	} else {
+		users, err = s.users.Find(ctx, query)
+	}
+	if err != nil {
+		return nil, controller.APIError(err)
+	}
+
+	resp := &userspb.ListUsersResponse{Users: make([]*userspb.User, len(users))}
+	for i := range users {
+		if resp.Users[i], err = s.toProto(ctx, &users[i]); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *userspb.CreateUserRequest) (*userspb.User, error) {
+	if req.Username == "" || req.Email == "" {
+		return nil, invalidArgument("username and email are required")
+	}
+	user := model.User{Username: req.Username, Email: req.Email, FullName: req.FullName}
+	if req.CustomFields != nil {
+		user.CustomFields = req.CustomFields.AsMap()
+	}
+	if err := s.users.Create(ctx, &user); err != nil {
+		return nil, controller.APIError(err)
+	}
+	return s.toProto(ctx, &user)
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *userspb.UpdateUserRequest) (*userspb.UpdateUserResponse, error) {
+	if !model.IsUUID(req.Uuid) {
+		return nil, invalidUUID()
+	}
+	// Only the fields set in the request are updated
+	var patch model.UserPatch
+	if req.Username != nil {
+		patch.Username = &req.Username.Value
+	}
+	if req.Email != nil {
+		patch.Email = &req.Email.Value
+	}
+	if req.FullName != nil {
+		patch.FullName = &req.FullName.Value
+	}
+	if req.CustomFields != nil {
+		patch.CustomFields = req.CustomFields.AsMap()
+	}
+	if req.Version != nil {
+		patch.Version = &req.Version.Value
+	}
+
+	// An email change waits for a second admin's approval when approvals are enabled
+	var change *model.PendingChange
+	var err error
+	if s.approvals != nil {
+		change, err = s.approvals.RequestUpdate(ctx, req.Uuid, patch, principalName(ctx))
+	}
+	if err == nil && change == nil {
+		err = s.users.Update(ctx, req.Uuid, patch)
+	}
+	if err != nil {
+		return nil, controller.APIError(err)
+	}
+	if change != nil {
+		return &userspb.UpdateUserResponse{PendingChangeId: change.ID}, nil
+	}
+
+	user, err := s.users.GetByUUID(ctx, req.Uuid)
+	if err != nil {
+		return nil, controller.APIError(err)
+	}
+	out, err := s.toProto(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &userspb.UpdateUserResponse{User: out}, nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *userspb.DeleteUserRequest) (*userspb.DeleteUserResponse, error) {
+	if !model.IsUUID(req.Uuid) {
+		return nil, invalidUUID()
+	}
+	var change *model.PendingChange
+	var err error
+	if s.approvals != nil {
+		change, err = s.approvals.RequestDelete(ctx, req.Uuid, principalName(ctx))
+	}
+	if err == nil && change == nil {
+		err = s.users.Delete(ctx, req.Uuid)
+	}
+	if err != nil {
+		return nil, controller.APIError(err)
+	}
+	if change != nil {
+		return &userspb.DeleteUserResponse{PendingChangeId: change.ID}, nil
+	}
+	return &userspb.DeleteUserResponse{}, nil
+}
+
+// toProto maps a user to its message for the caller, without the fields the
+// caller may not read. The message fields are named like the JSON ones the field
+// policy lists.
+func (s *userServer) toProto(ctx context.Context, user *model.User) (*userspb.User, error) {
+	out := &userspb.User{
+		Id:        user.ID,
+		Uuid:      user.UUID,
+		Username:  user.Username,
+		Email:     user.Email,
+		FullName:  user.FullName,
+		Region:    user.Region,
+		CreatedAt: timestamp(user.CreatedAt),
+		UpdatedAt: timestamp(user.UpdatedAt),
+		Version:   user.Version,
+	}
+	if user.CustomFields != nil {
+		fields, err := structpb.NewStruct(user.CustomFields)
+		if err != nil {
+			return nil, apierror.Internal("render_failed", "failed to render user")
+		}
+		out.CustomFields = fields
+	}
+
+	msg := out.ProtoReflect()
+	for _, field := range s.fields.Hidden(middleware.PrincipalFromContext(ctx)) {
+		if name, ok := strings.CutPrefix(field, "custom_fields."); ok {
+			if out.CustomFields != nil {
+				delete(out.CustomFields.Fields, name)
+			}
+			continue
+		}
+		if fd := msg.Descriptor().Fields().ByName(protoreflect.Name(field)); fd != nil {
+			msg.Clear(fd)
+		}
+	}
+	return out, nil
+}
+
+// timestamp leaves times that are not known unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func principalName(ctx context.Context) string {
+	if p := middleware.PrincipalFromContext(ctx); p != nil {
+		return p.Name
+	}
+	return "unknown"
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"net/http/pprof"
+
+	"cruder/internal/controller"
+	"cruder/internal/metrics"
+	"cruder/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewAdmin registers operational endpoints: /metrics, /debug/pprof and /api/v1/admin.
+// It is mounted on the internal admin listener, or on the main router when no admin
+// address is configured.
+func NewAdmin(router *gin.Engine, controllers *controller.Controller, opts Options) *gin.Engine {
+	root := router.Group(opts.BasePath)
+
+	// On the main listener metrics and profiles face the same clients as the API, so
+	// heap dumps and CPU profiles need an admin credential too
+	ops := root
+	if opts.AdminOnMainListener {
+		ops = root.Group("", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces), middleware.RequireScope("admin"))
+	}
+	ops.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	debug := ops.Group("/debug/pprof")
+	{
+		debug.GET("/", gin.WrapF(pprof.Index))
+		debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+		debug.GET("/profile", gin.WrapF(pprof.Profile))
+		debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+		debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+		debug.GET("/trace", gin.WrapF(pprof.Trace))
+		debug.GET("/:profile", func(c *gin.Context) {
+			pprof.Handler(c.Param("profile")).ServeHTTP(c.Writer, c.Request)
+		})
+	}
+
+	adminGroup := root.Group("/api/v1/admin", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces), middleware.RequireScope("admin"))
+	adminGroup.Use(opts.authorize()...)
+	{
+		adminGroup.GET("/runtime", controllers.Admin.GetRuntime)
+		adminGroup.GET("/analytics", controllers.Admin.GetUsage)
+		adminGroup.GET("/cluster", controllers.Admin.GetCluster)
+		adminGroup.GET("/stats", controllers.Admin.GetStats)
+		adminGroup.GET("/billing/:month", controllers.Billing.GetReport)
+		adminGroup.POST("/billing/:month", controllers.Billing.GenerateReport)
+		if opts.ReadOnly != nil {
+			readOnly := controller.NewReadOnlyController(opts.ReadOnly)
+			adminGroup.GET("/read-only", readOnly.GetReadOnly)
+			adminGroup.PUT("/read-only", readOnly.SetReadOnly)
+		}
+		if opts.LogLevel != nil {
+			logLevel := controller.NewLogLevelController(opts.LogLevel)
+			adminGroup.GET("/log-level", logLevel.GetLogLevel)
+			adminGroup.PUT("/log-level", logLevel.SetLogLevel)
+		}
+
+		fields := adminGroup.Group("/custom-fields")
+		{
+			fields.GET("", controllers.CustomFields.ListCustomFields)
+			fields.POST("", controllers.CustomFields.CreateCustomField)
+			fields.PUT("/:name", controllers.CustomFields.UpdateCustomField)
+			fields.DELETE("/:name", controllers.CustomFields.DeleteCustomField)
+		}
+
+		if controllers.SigningKeys != nil {
+			adminGroup.GET("/signing-keys", controllers.SigningKeys.ListKeys)
+			adminGroup.POST("/signing-keys/:kid/revoke", controllers.SigningKeys.RevokeKey)
+		}
+
+		if controllers.APIKeys != nil {
+			keys := adminGroup.Group("/api-keys", middleware.UUIDParams("id"))
+			keys.GET("", controllers.APIKeys.ListKeys)
+			keys.POST("", controllers.APIKeys.CreateKey)
+			keys.POST("/:id/rotate", controllers.APIKeys.RotateKey)
+			keys.POST("/:id/revoke", controllers.APIKeys.RevokeKey)
+		}
+
+		rules := adminGroup.Group("/rules")
+		{
+			rules.GET("", controllers.Rules.ListRules)
+			rules.POST("", controllers.Rules.CreateRule)
+			rules.PUT("/:id", controllers.Rules.UpdateRule)
+			rules.DELETE("/:id", controllers.Rules.DeleteRule)
+		}
+	}
+	return router
+}
@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/config"
+	"cruder/internal/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+var adminTestKeys = []config.APIKeyConfig{
+	{Name: "ops", Key: "admin-key", Scopes: []string{"admin"}},
+	{Name: "app", Key: "app-key"},
+}
+
+func TestNewAdmin_OnMainListener(t *testing.T) {
+	// Given: The operational routes mounted on the main router
+	gin.SetMode(gin.TestMode)
+	router := NewAdmin(gin.New(), &controller.Controller{}, Options{APIKeys: adminTestKeys, AdminOnMainListener: true})
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+	}{
+		{"anonymous profile index", "/debug/pprof/", "", http.StatusUnauthorized},
+		{"anonymous metrics", "/metrics", "", http.StatusUnauthorized},
+		{"key without the admin scope", "/debug/pprof/", "app-key", http.StatusForbidden},
+		{"admin key", "/metrics", "admin-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Then: Only admins read metrics and profiles
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestNewAdmin_OnAdminListener(t *testing.T) {
+	// Given: The operational routes on their own listener, out of public reach
+	gin.SetMode(gin.TestMode)
+	router := NewAdmin(gin.New(), &controller.Controller{}, Options{APIKeys: adminTestKeys})
+
+	// When: Prometheus scrapes the metrics without credentials
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Then
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+}
+
+func TestNewAdmin_MalformedAPIKeyID(t *testing.T) {
+	// Given: Managed API keys, whose IDs are UUIDs
+	gin.SetMode(gin.TestMode)
+	router := NewAdmin(gin.New(), &controller.Controller{APIKeys: &controller.APIKeyController{}}, Options{APIKeys: adminTestKeys})
+
+	for _, path := range []string{"/api/v1/admin/api-keys/42/rotate", "/api/v1/admin/api-keys/42/revoke"} {
+		// When: Acting on a key by a malformed ID
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-API-Key", "admin-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// Then: It is rejected before reaching the database
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"cruder/internal/controller"
+	"cruder/internal/model"
+	"cruder/internal/version"
+)
+
+// discovery describes the routes New registers and the auth and limits opts
+// configure, so it stays in step with the router
+func discovery(controllers *controller.Controller, opts Options) model.Discovery {
+	api := opts.BasePath + "/api/v1"
+	doc := model.Discovery{
+		Version:     version.Get().Version,
+		APIVersions: []model.DiscoveryAPIVersion{{Version: "v1", Path: api, Status: "current"}},
+		Endpoints: map[string]string{
+			"users":     api + "/users",
+			"approvals": api + "/approvals",
+			"changelog": api + "/changelog",
+			"openapi":   opts.BasePath + "/swagger/openapi.yaml",
+			"postman":   api + "/openapi/postman",
+			"version":   opts.BasePath + "/version",
+		},
+		ContentTypes: model.DiscoveryContentTypes{
+			Requests:  []string{"application/json", "multipart/form-data"},
+			Responses: []string{"application/json", "text/csv"},
+			Documents: opts.DocumentTypes,
+		},
+	}
+	if controllers.Auth != nil {
+		doc.Endpoints["login"] = api + "/auth/login"
+	}
+	if controllers.SigningKeys != nil {
+		doc.Endpoints["jwks"] = opts.BasePath + "/.well-known/jwks.json"
+	}
+	if controllers.PersonalTokens != nil {
+		doc.Endpoints["personal_tokens"] = api + "/me/tokens"
+	}
+	if controllers.Webhooks != nil {
+		doc.Endpoints["webhooks"] = api + "/webhooks"
+	}
+	if len(opts.Plugins.Names()) > 0 {
+		doc.Endpoints["plugins"] = api + "/plugins"
+	}
+	if doc.ContentTypes.Documents == nil {
+		doc.ContentTypes.Documents = []string{}
+	}
+
+	if opts.Tokens == nil || !opts.RequireJWT {
+		doc.Auth.Modes = append(doc.Auth.Modes, "api_key")
+		doc.Auth.APIKeyHeader = "X-API-Key"
+	}
+	if opts.Tokens != nil {
+		doc.Auth.Modes = append(doc.Auth.Modes, "jwt")
+	}
+	if opts.PersonalTokens != nil {
+		doc.Auth.Modes = append(doc.Auth.Modes, "personal_token")
+	}
+	for _, key := range opts.APIKeys {
+		if key.SigningSecret != "" {
+			doc.Auth.RequestSigning = true
+			break
+		}
+	}
+
+	if opts.RateLimits != nil {
+		doc.RateLimit = model.DiscoveryRateLimit{
+			Enabled:       true,
+			Requests:      opts.RateLimit.Requests,
+			WindowSeconds: int(opts.RateLimit.Window.Seconds()),
+		}
+	}
+	return doc
+}
@@ -1,28 +1,381 @@
 package handler
 
 import (
+	"net/http"
+	"time"
+
+	"cruder/internal/config"
 	"cruder/internal/controller"
+	"cruder/internal/logging"
 	"cruder/internal/middleware"
+	"cruder/internal/plugin"
+	"cruder/internal/policy"
+	"cruder/internal/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
-func New(router *gin.Engine, userController *controller.UserController, apiKey string) *gin.Engine {
-	// Apply JSON logger middleware to all routes
-	router.Use(middleware.JSONLogger())
+// Options configures route registration
+type Options struct {
+	// APIKeys are the keys accepted in the X-API-Key header
+	APIKeys []config.APIKeyConfig
+	// Tokens verifies JWT bearer tokens; nil accepts API keys only. RequireJWT
+	// rejects API keys once every client has moved to tokens.
+	Tokens     middleware.TokenVerifier
+	RequireJWT bool
+	// PersonalTokens verifies the tokens users create at /api/v1/me/tokens; nil
+	// accepts none
+	PersonalTokens middleware.PersonalTokenVerifier
+	// ManagedKeys verifies the API keys created at /api/v1/admin/api-keys; nil
+	// accepts only APIKeys
+	ManagedKeys middleware.APIKeyVerifier
+	// Signature configures HMAC-signed requests; Nonces records used nonces
+	Signature config.SignatureConfig
+	Nonces    middleware.NonceStore
+	// BasePath prefixes every route (e.g. "/user-service"); empty mounts at the root
+	BasePath string
+	// AdminOnMainListener is set when NewAdmin mounts on the main router, as no
+	// admin listener is configured; /metrics and /debug/pprof then need an admin
+	// credential like the admin API
+	AdminOnMainListener bool
+	// SLO holds per-route latency budgets
+	SLO config.SLOConfig
+	// Usage receives per-request analytics; nil disables collection
+	Usage middleware.UsageRecorder
+	// Mutations receives successful user mutations for anomaly detection; nil disables it
+	Mutations middleware.MutationRecorder
+	// Plugins adds extension middleware and routes; nil loads none
+	Plugins *plugin.Host
+	// Policy authorizes authenticated requests; nil skips the check.
+	// PolicyFailOpen allows requests while the policy engine is unreachable.
+	Policy         policy.Decider
+	PolicyFailOpen bool
+	// Mirror copies sampled requests to a shadow deployment; nil disables it.
+	// Bodies over MirrorMaxBody bytes are not mirrored.
+	Mirror        middleware.RequestMirror
+	MirrorMaxBody int64
+	// Flags decides which feature flags are on for each caller; nil turns every flag off
+	Flags middleware.FlagSet
+	// ReadOnly rejects mutating API requests while enabled; nil never rejects.
+	// The admin API stays writable so the mode can be switched off again.
+	ReadOnly *middleware.ReadOnlyMode
+	// LogLevel is changed at runtime through the admin API; nil leaves it fixed
+	LogLevel *logging.LevelSwitch
+	// Consistency issues read-your-writes tokens on mutations; nil disables them
+	Consistency middleware.PositionSource
+	// RequestTimeout is the deadline of each request, and so of its database queries;
+	// zero sets none
+	RequestTimeout time.Duration
+	// MaxBodyBytes is the largest request body accepted, except for multipart
+	// uploads; zero accepts any size
+	MaxBodyBytes int64
+	// Residency routes each request to its tenant's region; nil keeps every request in
+	// the home database
+	Residency *config.ResidencyConfig
+	// RateLimits counts requests per client; nil disables rate limiting
+	RateLimits ratelimit.Store
+	RateLimit  config.RateLimitConfig
+	// Enumeration hides which users exist from lookups by username or ID, throttling
+	// username lookups per client IP in LookupLimits; nil leaves lookups as they are
+	Enumeration  *config.EnumerationProtectionConfig
+	LookupLimits ratelimit.Store
+	// Bots checks registrations for signs of automation; nil checks none
+	Bots *middleware.BotCheck
+	// Idempotency stores responses to POST /api/v1/users/ sent with an
+	// Idempotency-Key, for IdempotencyTTL; nil ignores the header
+	Idempotency    middleware.IdempotencyStore
+	IdempotencyTTL time.Duration
+	// DocumentTypes are the media types accepted for user documents, as announced
+	// by the discovery document
+	DocumentTypes []string
+}
+
+// authenticate returns the middleware accepting the configured auth schemes
+func (o Options) authenticate() gin.HandlerFunc {
+	authenticate := middleware.Authenticate(o.APIKeys, o.Tokens, !o.RequireJWT)
+	if o.ManagedKeys != nil && !o.RequireJWT {
+		authenticate = middleware.ManagedAPIKeyAuth(o.ManagedKeys, authenticate)
+	}
+	if o.PersonalTokens == nil {
+		return authenticate
+	}
+	return middleware.PersonalTokenAuth(o.PersonalTokens, authenticate)
+}
+
+// rateLimit returns the rate limit middleware, or nothing when no store is configured
+func (o Options) rateLimit() []gin.HandlerFunc {
+	if o.RateLimits == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.RateLimit(o.RateLimits, o.RateLimit.Requests, o.RateLimit.Window, o.RateLimit.FailOpen)}
+}
+
+// readOnly returns the read-only middleware, or nothing when no switch is configured
+func (o Options) readOnly() []gin.HandlerFunc {
+	if o.ReadOnly == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.ReadOnly(o.ReadOnly)}
+}
+
+// authorize returns the policy middleware, or nothing when no policy is configured
+func (o Options) authorize() []gin.HandlerFunc {
+	if o.Policy == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.Authorize(o.Policy, o.PolicyFailOpen)}
+}
+
+// residency returns the region routing middleware, or nothing when residency is off
+func (o Options) residency() []gin.HandlerFunc {
+	if o.Residency == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.Residency(o.Residency.Tenants, o.Residency.HomeRegion)}
+}
+
+// uniformLookups returns the middleware hiding which users exist from lookups, or
+// nothing when enumeration protection is off
+func (o Options) uniformLookups() []gin.HandlerFunc {
+	if o.Enumeration == nil {
+		return nil
+	}
+	users := o.BasePath + "/api/v1/users"
+	return []gin.HandlerFunc{middleware.UniformLookup(controller.UserNotFound, o.Enumeration.MinResponseTime,
+		users+"/username/:username", users+"/id/:id")}
+}
+
+// usernameLookupLimit returns the per-IP limit of username lookups, or nothing
+// when enumeration protection is off
+func (o Options) usernameLookupLimit() []gin.HandlerFunc {
+	if o.Enumeration == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.RateLimitByIP(o.LookupLimits, "username-lookup",
+		o.Enumeration.UsernameLookups, o.Enumeration.UsernameLookupWindow, o.RateLimit.FailOpen)}
+}
+
+// botDetection returns the bot detection of registrations, or nothing when it is off
+func (o Options) botDetection() []gin.HandlerFunc {
+	if o.Bots == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.BotDetection(*o.Bots)}
+}
+
+// idempotent returns the Idempotency-Key middleware, or nothing when no store is configured
+func (o Options) idempotent() []gin.HandlerFunc {
+	if o.Idempotency == nil {
+		return nil
+	}
+	return []gin.HandlerFunc{middleware.Idempotency(o.Idempotency, o.IdempotencyTTL)}
+}
+
+// TrustProxies configures which peers may set X-Forwarded-For/X-Real-IP.
+// An empty list trusts no proxy, so the TCP peer address is used as client IP.
+func TrustProxies(router *gin.Engine, proxies []string) error {
+	router.ForwardedByClientIP = true
+	router.RemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+	return router.SetTrustedProxies(proxies)
+}
+
+func New(router *gin.Engine, controllers *controller.Controller, opts Options) *gin.Engine {
+	userController := controllers.Users
 
-	v1 := router.Group("/api/v1")
+	// Record the start time, assign the request ID, resolve the client IP and start
+	// the trace first so every later middleware sees the same values, then apply the
+	// request logger and metrics middleware to all routes. Errors renders the errors
+	// handlers record, inside the logger so the final status is logged.
+	router.Use(middleware.RequestStart(), middleware.RequestID(), middleware.RealIP(), middleware.Tracing(), middleware.Logger(), middleware.Metrics(), middleware.LatencyBudget(opts.SLO, opts.BasePath), middleware.CORS(opts.APIKeys), middleware.Errors())
+
+	if opts.RequestTimeout > 0 {
+		router.Use(middleware.Deadline(opts.RequestTimeout))
+	}
+	if opts.MaxBodyBytes > 0 {
+		// Imports and documents are uploads, which limit their own size
+		router.Use(middleware.BodyLimit(opts.MaxBodyBytes, opts.BasePath+"/api/v1/users/import", opts.BasePath+"/api/v1/users/:uuid/documents"))
+	}
+	if opts.Mirror != nil {
+		router.Use(middleware.Mirror(opts.Mirror, opts.MirrorMaxBody))
+	}
+	if opts.Flags != nil {
+		router.Use(middleware.Flags(opts.Flags))
+	}
+	if opts.Consistency != nil {
+		router.Use(middleware.Consistency(opts.Consistency))
+	}
+	if opts.Usage != nil {
+		router.Use(middleware.Analytics(opts.Usage))
+	}
+
+	// Build information is public so load balancers and ops can identify the build
+	router.GET(opts.BasePath+"/version", controller.GetVersion)
+	// So are the token signing keys, for other services verifying our tokens
+	if controllers.SigningKeys != nil {
+		router.GET(opts.BasePath+"/.well-known/jwks.json", controllers.SigningKeys.GetJWKS)
+	}
+	// So is what client SDKs configure themselves from
+	router.GET(opts.BasePath+"/.well-known/cruder-configuration", controller.GetDiscovery(discovery(controllers, opts)))
+	// So is the API description, for consumers to discover it
+	router.GET(opts.BasePath+"/swagger/openapi.yaml", controller.GetOpenAPISpec)
+	router.GET(opts.BasePath+"/swagger/index.html", controller.GetSwaggerUI)
+	router.GET(opts.BasePath+"/swagger", func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, opts.BasePath+"/swagger/index.html")
+	})
+
+	v1 := router.Group(opts.BasePath + "/api/v1")
 	{
-		// Apply API key authentication to all user routes
-		userGroup := v1.Group("/users", middleware.APIKeyAuth(apiKey))
+		// The changelog and the Postman collection are public, like the spec they
+		// accompany
+		v1.GET("/changelog", controller.GetChangelog)
+		v1.GET("/openapi/postman", controller.GetPostmanCollection)
+
+		// Login is only rate limited by client IP, as the caller is not authenticated yet
+		if controllers.Auth != nil {
+			v1.POST("/auth/login", append(opts.rateLimit(), controllers.Auth.Login)...)
+		}
+
+		// Apply API key or token authentication to all user routes; Debug must come last
+		userGroup := v1.Group("/users", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces))
+		userGroup.Use(opts.residency()...)
+		userGroup.Use(opts.rateLimit()...)
+		userGroup.Use(opts.uniformLookups()...)
+		userGroup.Use(opts.authorize()...)
+		userGroup.Use(middleware.UUIDParams("uuid", "document_id"))
+		userGroup.Use(opts.readOnly()...)
+		if opts.Mutations != nil {
+			userGroup.Use(middleware.MutationMonitor(opts.Mutations))
+		}
+		userGroup.Use(opts.Plugins.Middleware()...)
+		userGroup.Use(middleware.Debug())
 		{
 			userGroup.GET("/", userController.GetAllUsers)
-			userGroup.GET("/username/:username", userController.GetUserByUsername)
+			userGroup.GET("/username/:username", append(opts.usernameLookupLimit(), userController.GetUserByUsername)...)
 			userGroup.GET("/id/:id", userController.GetUserByID)
+			userGroup.GET("/aggregate", userController.AggregateUsers)
+			userGroup.GET("/sample", userController.SampleUsers)
+			userGroup.GET("/search", controllers.UserSearch.Search)
+			userGroup.GET("/autocomplete", controllers.UserSearch.Autocomplete)
+			userGroup.GET("/deleted", middleware.RequireScope("admin"), controllers.RecycleBin.ListDeleted)
+
+			exports := userGroup.Group("/exports", middleware.RequireScope("admin"), middleware.UUIDParams("id"))
+			{
+				exports.POST("", controllers.Exports.StartExport)
+				exports.GET("/:id", controllers.Exports.GetExport)
+				exports.GET("/:id/download", controllers.Exports.DownloadExport)
+			}
+
+			userGroup.GET("/consents/pending", middleware.RequireScope("admin"), controllers.Consents.ListPending)
+
+			views := userGroup.Group("/views")
+			{
+				views.GET("", controllers.SavedViews.ListViews)
+				views.POST("", controllers.SavedViews.CreateView)
+				views.GET("/:name", controllers.SavedViews.RunView)
+				views.DELETE("/:name", controllers.SavedViews.DeleteView)
+			}
+
+			userGroup.POST("/validate", userController.ValidateUser)
+			userGroup.POST("/bulk", userController.BulkCreateUsers)
+			userGroup.DELETE("/bulk", userController.BulkDeleteUsers)
+			userGroup.POST("/import", middleware.RequireScope("admin"), controllers.Imports.ImportUsers)
+			// Registrations are checked for bots before an Idempotency-Key can answer them
+			createMiddlewares := append(opts.botDetection(), opts.idempotent()...)
+			userGroup.POST("/", append(createMiddlewares, userController.CreateUser)...) // Task3
+			userGroup.PATCH("/:uuid", userController.UpdateUser)                         // Task3
+			userGroup.DELETE("/:uuid", userController.DeleteUser)                        // Task3
+			userGroup.POST("/:uuid/restore", middleware.RequireScope("admin"), userController.RestoreUser)
+
+			userGroup.POST("/:uuid/schedule-delete", controllers.ScheduledDeletions.Schedule)
+			userGroup.GET("/:uuid/schedule-delete", controllers.ScheduledDeletions.Get)
+			userGroup.DELETE("/:uuid/schedule-delete", controllers.ScheduledDeletions.Cancel)
+
+			// Internal notes are for support staff only
+			notes := userGroup.Group("/:uuid/notes", middleware.RequireScope("admin"))
+			{
+				notes.GET("", controllers.Notes.ListNotes)
+				notes.POST("", controllers.Notes.CreateNote)
+				notes.PATCH("/:note_id", controllers.Notes.UpdateNote)
+				notes.DELETE("/:note_id", controllers.Notes.DeleteNote)
+			}
+
+			userGroup.GET("/:uuid/consents", controllers.Consents.ListConsents)
+			userGroup.POST("/:uuid/consents", controllers.Consents.RecordConsent)
+
+			// Contracts and ID scans need the documents scope
+			documents := userGroup.Group("/:uuid/documents", middleware.RequireScope("documents"))
+			{
+				documents.GET("", controllers.Documents.ListDocuments)
+				documents.POST("", controllers.Documents.UploadDocument)
+				documents.GET("/:document_id", controllers.Documents.DownloadDocument)
+				documents.DELETE("/:document_id", controllers.Documents.DeleteDocument)
+			}
+		}
+
+		// Signed-in users manage the tokens they automate with
+		if controllers.PersonalTokens != nil {
+			me := v1.Group("/me", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces))
+			me.Use(opts.residency()...)
+			me.Use(opts.rateLimit()...)
+			me.Use(opts.authorize()...)
+			me.Use(opts.readOnly()...)
+			{
+				me.GET("/tokens", controllers.PersonalTokens.ListTokens)
+				me.POST("/tokens", controllers.PersonalTokens.CreateToken)
+				me.DELETE("/tokens/:id", middleware.UUIDParams("id"), controllers.PersonalTokens.RevokeToken)
+			}
+		}
+
+		// The admin UI's search box looks across every searchable entity
+		search := v1.Group("/search", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces), middleware.RequireScope("admin"))
+		search.Use(opts.residency()...)
+		search.Use(opts.rateLimit()...)
+		search.Use(opts.authorize()...)
+		search.Use(opts.readOnly()...)
+		{
+			search.GET("", controllers.Search.Search)
+		}
+
+		// Pending deletes and email changes are reviewed by a second admin
+		approvals := v1.Group("/approvals", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces), middleware.RequireScope("admin"))
+		approvals.Use(opts.residency()...)
+		approvals.Use(opts.rateLimit()...)
+		approvals.Use(opts.authorize()...)
+		approvals.Use(opts.readOnly()...)
+		{
+			approvals.GET("", controllers.Approvals.ListChanges)
+			approvals.GET("/:id", controllers.Approvals.GetChange)
+			approvals.POST("/:id/approve", controllers.Approvals.ApproveChange)
+			approvals.POST("/:id/reject", controllers.Approvals.RejectChange)
+		}
+
+		// Admins register the URLs user events are delivered to
+		if controllers.Webhooks != nil {
+			webhooks := v1.Group("/webhooks", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces), middleware.RequireScope("admin"))
+			webhooks.Use(opts.rateLimit()...)
+			webhooks.Use(opts.authorize()...)
+			webhooks.Use(opts.readOnly()...)
+			webhooks.Use(middleware.UUIDParams("id"))
+			{
+				webhooks.GET("", controllers.Webhooks.ListWebhooks)
+				webhooks.POST("", controllers.Webhooks.CreateWebhook)
+				webhooks.GET("/:id", controllers.Webhooks.GetWebhook)
+				webhooks.PUT("/:id", controllers.Webhooks.UpdateWebhook)
+				webhooks.DELETE("/:id", controllers.Webhooks.DeleteWebhook)
+				webhooks.GET("/:id/deliveries", controllers.Webhooks.ListDeliveries)
+			}
+		}
 
-			userGroup.POST("/", userController.CreateUser)        // Task3
-			userGroup.PATCH("/:uuid", userController.UpdateUser)  // Task3
-			userGroup.DELETE("/:uuid", userController.DeleteUser) // Task3
+		// Plugin routes share authentication and plugin middleware with the user API
+		if len(opts.Plugins.Names()) > 0 {
+			pluginGroup := v1.Group("/plugins", opts.authenticate(), middleware.RequestSignature(opts.Signature, opts.Nonces))
+			pluginGroup.Use(opts.residency()...)
+			pluginGroup.Use(opts.rateLimit()...)
+			pluginGroup.Use(opts.authorize()...)
+			pluginGroup.Use(opts.readOnly()...)
+			pluginGroup.Use(opts.Plugins.Middleware()...)
+			pluginGroup.Use(middleware.Debug())
+			opts.Plugins.MountRoutes(pluginGroup)
 		}
 	}
 	return router
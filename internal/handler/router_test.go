@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/controller"
+	"cruder/internal/flags"
+	"cruder/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNew_MalformedWebhookID(t *testing.T) {
+	// Given: Webhooks, whose IDs are UUIDs
+	gin.SetMode(gin.TestMode)
+	router := New(gin.New(), &controller.Controller{Webhooks: &controller.WebhookController{}}, Options{APIKeys: adminTestKeys})
+
+	for _, tt := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/webhooks/42"},
+		{http.MethodDelete, "/api/v1/webhooks/42"},
+		{http.MethodGet, "/api/v1/webhooks/42/deliveries"},
+	} {
+		// When: Addressing a webhook by a malformed ID
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("X-API-Key", "admin-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		// Then: It is rejected before reaching the database
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: expected status 400, got %d", tt.method, tt.path, w.Code)
+		}
+	}
+}
+
+func TestNew_FeatureFlags(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name  string
+		flags middleware.FlagSet
+		want  string
+	}{
+		{"rolled out to every caller", flags.New(map[string]float64{"v2_response_shape": 100}), "v2"},
+		{"rolled out to none", flags.New(map[string]float64{"v2_response_shape": 0}), "v1"},
+		{"no flags configured", nil, "v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: An authenticated handler behind a flag
+			opts := Options{APIKeys: adminTestKeys, Flags: tt.flags}
+			router := New(gin.New(), &controller.Controller{}, opts)
+			router.GET("/flagged", opts.authenticate(), func(c *gin.Context) {
+				if middleware.FlagEnabled(c, "v2_response_shape") {
+					c.String(http.StatusOK, "v2")
+					return
+				}
+				c.String(http.StatusOK, "v1")
+			})
+
+			// When: An API key calls it
+			req := httptest.NewRequest(http.MethodGet, "/flagged", nil)
+			req.Header.Set("X-API-Key", "app-key")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Then: The flagged behaviour follows the rollout
+			if w.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
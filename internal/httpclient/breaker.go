@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"sync"
+	"time"
+)
+
+// breaker is a consecutive-failure circuit breaker. Once open it rejects calls until
+// the cooldown elapses, then lets a single probe through (half-open).
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.probing = false
+}
+
+// abandon ends a call that neither succeeded nor failed, letting another probe through
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *breaker) failure(now time.Time, threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.probing || (threshold > 0 && b.failures >= threshold) {
+		b.openUntil = now.Add(cooldown)
+		b.probing = false
+	}
+}
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cruder/internal/config"
+)
+
+// ErrCircuitOpen is returned when a host has failed too often and calls are short-circuited
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Observer receives callbacks around every outbound attempt, used for tracing and metrics
+type Observer interface {
+	// AttemptStarted is called before each attempt and may return a derived request
+	// (e.g. with trace headers injected)
+	AttemptStarted(req *http.Request, attempt int) *http.Request
+	// AttemptFinished is called after each attempt with either a response or an error
+	AttemptFinished(req *http.Request, attempt int, resp *http.Response, err error, duration time.Duration)
+}
+
+// Client is a shared outbound HTTP client with timeouts, retries with jitter and
+// per-host circuit breaking. Integrations should use it instead of http.DefaultClient.
+type Client struct {
+	name      string
+	http      *http.Client
+	cfg       config.HTTPClientConfig
+	observers []Observer
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// New creates a Client; name identifies the integration in observer callbacks, errors
+// and the http_client_* metrics, which are recorded before the given observers run
+func New(name string, cfg config.HTTPClientConfig, observers ...Observer) *Client {
+	return &Client{
+		name:      name,
+		http:      &http.Client{Timeout: cfg.Timeout},
+		cfg:       cfg,
+		observers: append([]Observer{metricsObserver{name: name}}, observers...),
+		breakers:  make(map[string]*breaker),
+	}
+}
+
+// Name returns the integration name the client was created with
+func (c *Client) Name() string {
+	return c.name
+}
+
+// Do sends the request, retrying network errors, 429 and 5xx gateway responses.
+// Only idempotent requests are retried: those with an idempotent method or an
+// Idempotency-Key header. Requests with a body are only retried when req.GetBody
+// is set (http.NewRequest sets it for bytes/strings readers). A request ended by
+// its own context does not count against the host's circuit breaker.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	b := c.breaker(req.URL.Host)
+	if !b.allow(time.Now()) {
+		return nil, fmt.Errorf("%s: %s: %w", c.name, req.URL.Host, ErrCircuitOpen)
+	}
+
+	maxRetries := c.cfg.MaxRetries
+	if maxRetries < 0 || !idempotent(req) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		maxRetries = 0
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && !b.allow(time.Now()) {
+			return nil, fmt.Errorf("%s: %s: %w (last error: %v)", c.name, req.URL.Host, ErrCircuitOpen, lastErr)
+		}
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("%s: failed to rewind request body: %w", c.name, err)
+			}
+			req.Body = body
+		}
+
+		resp, err := c.attempt(req, attempt)
+		if err != nil && req.Context().Err() != nil {
+			// The caller gave up; that says nothing about the host
+			b.abandon()
+			return resp, err
+		}
+		retryable := isRetryable(resp, err)
+		if !retryable {
+			b.success()
+			return resp, err
+		}
+
+		b.failure(time.Now(), c.cfg.BreakerThreshold, c.cfg.BreakerCooldown)
+		if attempt >= maxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if resp != nil {
+			drain(resp)
+			lastErr = fmt.Errorf("%s: unexpected status %d", c.name, resp.StatusCode)
+		} else {
+			lastErr = err
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, fmt.Errorf("%s: %w (last error: %v)", c.name, req.Context().Err(), lastErr)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Post is a convenience wrapper sending body with the given content type
+func (c *Client) Post(ctx context.Context, url, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytesReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	return c.Do(req)
+}
+
+func (c *Client) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	for _, o := range c.observers {
+		req = o.AttemptStarted(req, attempt)
+	}
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	duration := time.Since(start)
+
+	for _, o := range c.observers {
+		o.AttemptFinished(req, attempt, resp, err, duration)
+	}
+	return resp, err
+}
+
+// backoff returns a full-jitter exponential delay, honoring Retry-After when present
+func (c *Client) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, c.cfg.BackoffMax)
+		}
+	}
+
+	ceiling := c.cfg.BackoffBase << attempt
+	if ceiling <= 0 || ceiling > c.cfg.BackoffMax {
+		ceiling = c.cfg.BackoffMax
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+func (c *Client) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+// idempotent reports whether sending req twice has the same effect as sending it once
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func bytesReader(body []byte) io.Reader {
+	if body == nil {
+		return nil
+	}
+	return bytes.NewReader(body)
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
@@ -0,0 +1,211 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func testConfig() config.HTTPClientConfig {
+	return config.HTTPClientConfig{
+		Timeout:          time.Second,
+		MaxRetries:       3,
+		BackoffBase:      time.Millisecond,
+		BackoffMax:       5 * time.Millisecond,
+		BreakerThreshold: 10,
+		BreakerCooldown:  time.Minute,
+	}
+}
+
+func TestDo_RetriesUntilSuccess(t *testing.T) {
+	// Given: A server that fails twice with 503 before succeeding
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	client := New("test", testConfig())
+
+	// When: Posting a body with an Idempotency-Key to the server
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"a":1}`))
+	req.Header.Set("Idempotency-Key", "key-1")
+	resp, err := client.Do(req)
+
+	// Then: The client should retry transparently and return the successful response
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status 200, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestDo_DoesNotRetryClientErrors(t *testing.T) {
+	// Given: A server that always answers 400
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	client := New("test", testConfig())
+
+	// When: Sending a request
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+
+	// Then: The 400 should be returned without retries
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestDo_DoesNotRetryNonIdempotentRequests(t *testing.T) {
+	// Given: A server that always answers 503
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := New("test", testConfig())
+
+	// When: Posting a body without an Idempotency-Key
+	resp, err := client.Post(context.Background(), srv.URL, "application/json", []byte(`{"a":1}`))
+
+	// Then: The POST should be sent once, as it may have taken effect
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if calls.Load() != 1 {
+		t.Errorf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestDo_CallerDeadlineDoesNotOpenCircuit(t *testing.T) {
+	// Given: A slow server and a breaker threshold of 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.BreakerThreshold = 1
+	client := New("test", cfg)
+
+	// When: A caller gives up on a request before the server answers
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the caller's deadline, got %v", err)
+	}
+
+	// Then: The host is still called
+	if b := client.breaker(req.URL.Host); !b.allow(time.Now()) {
+		t.Error("expected the circuit to stay closed")
+	}
+}
+
+func TestDo_OpensCircuitAfterThreshold(t *testing.T) {
+	// Given: A server that always fails and a breaker threshold of 2
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.MaxRetries = 0
+	cfg.BreakerThreshold = 2
+	client := New("test", cfg)
+
+	// When: Sending three requests
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("expected response, got %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	_, err := client.Do(req)
+
+	// Then: The third request should be short-circuited
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected 2 calls to reach the server, got %d", calls.Load())
+	}
+}
+
+type countingObserver struct {
+	started, finished int
+}
+
+func (o *countingObserver) AttemptStarted(req *http.Request, attempt int) *http.Request {
+	o.started++
+	return req
+}
+
+func (o *countingObserver) AttemptFinished(req *http.Request, attempt int, resp *http.Response, err error, d time.Duration) {
+	o.finished++
+}
+
+func TestDo_NotifiesObservers(t *testing.T) {
+	// Given: A healthy server and an observer
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	obs := &countingObserver{}
+	client := New("test", testConfig(), obs)
+
+	// When: Sending a request
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := client.Do(req)
+
+	// Then: The observer should see exactly one attempt
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	_ = resp.Body.Close()
+	if obs.started != 1 || obs.finished != 1 {
+		t.Errorf("expected 1 started/finished, got %d/%d", obs.started, obs.finished)
+	}
+	if n := testutil.ToFloat64(attemptsTotal.WithLabelValues("test", http.MethodGet, "204")); n < 1 {
+		t.Errorf("expected the attempt to be counted, got %v", n)
+	}
+}
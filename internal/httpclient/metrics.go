@@ -0,0 +1,42 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"cruder/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	attemptsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_requests_total",
+		Help: "Outbound HTTP attempts by client, method and status code, or error.",
+	}, []string{"client", "method", "code"})
+	attemptDuration = promauto.With(metrics.Registry).NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_client_request_duration_seconds",
+		Help:    "Outbound HTTP attempt latency in seconds.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"client", "method"})
+)
+
+// metricsObserver counts every attempt of a client; New adds it to each one
+type metricsObserver struct {
+	name string
+}
+
+func (o metricsObserver) AttemptStarted(req *http.Request, attempt int) *http.Request {
+	return req
+}
+
+func (o metricsObserver) AttemptFinished(req *http.Request, attempt int, resp *http.Response, err error, duration time.Duration) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	attemptsTotal.WithLabelValues(o.name, req.Method, code).Inc()
+	attemptDuration.WithLabelValues(o.name, req.Method).Observe(duration.Seconds())
+}
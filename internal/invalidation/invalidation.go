@@ -0,0 +1,183 @@
+// Package invalidation tells every instance to drop cached data after a change.
+// Messages travel over PostgreSQL LISTEN/NOTIFY on one channel, so instances that
+// share a database need no extra infrastructure.
+package invalidation
+
+import (
+	"context"
+	"cruder/internal/metrics"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Cache topics
+const (
+	// Rules is the compiled rule set of the rules engine
+	Rules = "rules"
+	// SigningKeys are the rotated JWT signing keys
+	SigningKeys = "signing_keys"
+	// APIKeys are the verified API keys managed through the admin API
+	APIKeys = "api_keys"
+)
+
+// Channel is the PostgreSQL notification channel shared by all instances
+const Channel = "cruder_invalidate"
+
+// notifyTimeout bounds the NOTIFY sent after a change
+const notifyTimeout = 2 * time.Second
+
+var (
+	invalidationsReceived = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_invalidations_received_total",
+		Help: "Cache invalidations received from other instances, by topic.",
+	}, []string{"topic"})
+	invalidationsFailed = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "cache_invalidation_broadcast_failures_total",
+		Help: "Cache invalidations that could not be sent to other instances, by topic.",
+	}, []string{"topic"})
+)
+
+// Invalidator is told about changes that make cached data stale
+type Invalidator interface {
+	Invalidate(topic string)
+}
+
+// Bus runs invalidation handlers by topic. On its own it only reaches this process;
+// after Listen every instance connected to the same database runs its handlers too.
+type Bus struct {
+	instance string
+
+	mu       sync.RWMutex
+	handlers map[string][]func()
+
+	db       *sql.DB
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{instance: newInstanceID(), handlers: make(map[string][]func())}
+}
+
+// Subscribe registers fn to drop the cache named topic
+func (b *Bus) Subscribe(topic string, fn func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = append(b.handlers[topic], fn)
+}
+
+// Invalidate implements Invalidator: it drops topic in this process right away, then
+// tells the other instances. A failed broadcast is logged; their caches then expire
+// on their own schedule.
+func (b *Bus) Invalidate(topic string) {
+	b.run(topic)
+
+	b.mu.RLock()
+	db := b.db
+	b.mu.RUnlock()
+	if db == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if _, err := db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, b.instance+" "+topic); err != nil {
+		invalidationsFailed.WithLabelValues(topic).Inc()
+		log.Printf("failed to broadcast invalidation of %s: %v", topic, err)
+	}
+}
+
+// Listen sends invalidations through db and receives those of other instances on a
+// dedicated connection opened with dsn. The connection is re-established when lost;
+// every cache is dropped after a reconnect since notifications may have been missed.
+func (b *Bus) Listen(db *sql.DB, dsn string) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("cache invalidation listener: %v", err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		_ = listener.Close()
+		return err
+	}
+
+	b.mu.Lock()
+	b.db, b.listener, b.done = db, listener, make(chan struct{})
+	b.mu.Unlock()
+	go b.receive(listener, b.done)
+	return nil
+}
+
+// Close stops listening; later invalidations only reach this process
+func (b *Bus) Close() error {
+	b.mu.Lock()
+	listener, done := b.listener, b.done
+	b.db, b.listener = nil, nil
+	b.mu.Unlock()
+	if listener == nil {
+		return nil
+	}
+	err := listener.Close()
+	<-done
+	return err
+}
+
+func (b *Bus) receive(listener *pq.Listener, done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case n, ok := <-listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				b.runAll()
+				continue
+			}
+			instance, topic, _ := strings.Cut(n.Extra, " ")
+			if instance == b.instance {
+				continue
+			}
+			invalidationsReceived.WithLabelValues(topic).Inc()
+			b.run(topic)
+		case <-time.After(90 * time.Second):
+			// Detects a silently dropped connection so it gets re-established
+			go func() { _ = listener.Ping() }()
+		}
+	}
+}
+
+func (b *Bus) run(topic string) {
+	b.mu.RLock()
+	handlers := append([]func(){}, b.handlers[topic]...)
+	b.mu.RUnlock()
+	for _, fn := range handlers {
+		fn()
+	}
+}
+
+func (b *Bus) runAll() {
+	b.mu.RLock()
+	topics := make([]string, 0, len(b.handlers))
+	for topic := range b.handlers {
+		topics = append(topics, topic)
+	}
+	b.mu.RUnlock()
+	for _, topic := range topics {
+		b.run(topic)
+	}
+}
+
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
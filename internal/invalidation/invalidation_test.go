@@ -0,0 +1,38 @@
+package invalidation
+
+import "testing"
+
+func TestBus_InvalidatesLocally(t *testing.T) {
+	// Given: a bus that is not listening, with handlers for two topics
+	bus := NewBus()
+	var rules, other int
+	bus.Subscribe(Rules, func() { rules++ })
+	bus.Subscribe("other", func() { other++ })
+
+	// When
+	bus.Invalidate(Rules)
+
+	// Then: only the handler of that topic runs, synchronously
+	if rules != 1 || other != 0 {
+		t.Errorf("expected only the rules handler to run, got rules=%d other=%d", rules, other)
+	}
+}
+
+func TestBus_RunAllDropsEveryCache(t *testing.T) {
+	bus := NewBus()
+	var rules, other int
+	bus.Subscribe(Rules, func() { rules++ })
+	bus.Subscribe("other", func() { other++ })
+
+	bus.runAll()
+
+	if rules != 1 || other != 1 {
+		t.Errorf("expected every handler to run once, got rules=%d other=%d", rules, other)
+	}
+}
+
+func TestBus_CloseWithoutListen(t *testing.T) {
+	if err := NewBus().Close(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
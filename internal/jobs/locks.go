@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+)
+
+// lockPrefix keeps job lock keys apart from other advisory lock users
+const lockPrefix = "cruder.job."
+
+// Locker hands out cluster-wide locks on job names
+type Locker interface {
+	// TryLock takes the lock for name without waiting; it returns nil when another
+	// instance holds it
+	TryLock(ctx context.Context, name string) (Lock, error)
+}
+
+// Lock is a held job lock
+type Lock interface {
+	// Held reports whether the lock is still held
+	Held(ctx context.Context) bool
+	Release()
+}
+
+// AdvisoryLocker implements Locker with PostgreSQL session advisory locks. Each
+// held lock keeps a connection out of the pool, since the lock lives as long as
+// the session that took it and is dropped by the server when that session dies.
+type AdvisoryLocker struct {
+	db *sql.DB
+}
+
+func NewAdvisoryLocker(db *sql.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string) (Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx,
+		`SELECT pg_try_advisory_lock(hashtext($1))`, lockPrefix+name).Scan(&locked); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !locked {
+		_ = conn.Close()
+		return nil, nil
+	}
+	return &advisoryLock{conn: conn, name: name}, nil
+}
+
+type advisoryLock struct {
+	conn *sql.Conn
+	name string
+}
+
+func (a *advisoryLock) Held(ctx context.Context) bool {
+	return a.conn.PingContext(ctx) == nil
+}
+
+func (a *advisoryLock) Release() {
+	if _, err := a.conn.ExecContext(context.Background(),
+		`SELECT pg_advisory_unlock(hashtext($1))`, lockPrefix+a.name); err != nil {
+		log.Printf("failed to release job lock %s, closing its connection: %v", a.name, err)
+		// A pooled session still holding the lock would keep it forever; discard it
+		_ = a.conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	_ = a.conn.Close()
+}
@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"cruder/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// lockTimeout bounds taking or checking a job lock
+const lockTimeout = 5 * time.Second
+
+var (
+	jobLockHeld = promauto.With(metrics.Registry).NewGaugeVec(prometheus.GaugeOpts{
+		Name: "job_lock_held",
+		Help: "1 while this instance holds the cluster-wide lock of a job and runs it.",
+	}, []string{"job"})
+	jobRunsSkipped = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "job_runs_skipped_total",
+		Help: "Job runs left to the instance holding the job's lock.",
+	}, []string{"job"})
+)
+
+// Runner runs periodic background jobs until it is stopped. Each job runs on its own
+// goroutine; a run that is still going when the next tick arrives delays that tick
+// instead of overlapping it.
+//
+// With a Locker, a job only runs on the instance holding its lock. The lock is kept
+// between runs, so one instance stays in charge until it stops or loses its
+// database session; the others try to take over on every tick.
+type Runner struct {
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	locker Locker
+
+	mu    sync.Mutex
+	locks map[string]Lock
+}
+
+// NewRunner creates a runner with no jobs; locker may be nil to run every job on
+// every instance
+func NewRunner(locker Locker) *Runner {
+	return &Runner{stop: make(chan struct{}), locker: locker, locks: make(map[string]Lock)}
+}
+
+// Every runs fn every interval, logging its errors under name
+func (r *Runner) Every(name string, interval time.Duration, fn func() error) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.run(name, fn)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Once runs fn in the background once, logging its error under name
+func (r *Runner) Once(name string, fn func() error) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run(name, fn)
+	}()
+}
+
+// Stop signals every job to stop, waits for running jobs to finish and releases
+// the locks held
+func (r *Runner) Stop() {
+	close(r.stop)
+	r.wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, lock := range r.locks {
+		lock.Release()
+		jobLockHeld.WithLabelValues(name).Set(0)
+	}
+	r.locks = make(map[string]Lock)
+}
+
+// Held returns the names of the jobs whose lock this instance holds, sorted
+func (r *Runner) Held() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.locks))
+	for name := range r.locks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Runner) run(name string, fn func() error) {
+	if !r.owns(name) {
+		jobRunsSkipped.WithLabelValues(name).Inc()
+		return
+	}
+	if err := fn(); err != nil {
+		log.Printf("job %s failed: %v", name, err)
+	}
+}
+
+// owns reports whether this instance may run job name, taking its lock if free
+func (r *Runner) owns(name string) bool {
+	if r.locker == nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+
+	if lock := r.locks[name]; lock != nil {
+		if lock.Held(ctx) {
+			return true
+		}
+		log.Printf("lost lock of job %s", name)
+		lock.Release()
+		delete(r.locks, name)
+		jobLockHeld.WithLabelValues(name).Set(0)
+	}
+
+	lock, err := r.locker.TryLock(ctx, name)
+	if err != nil {
+		log.Printf("failed to take lock of job %s: %v", name, err)
+		return false
+	}
+	if lock == nil {
+		jobLockHeld.WithLabelValues(name).Set(0)
+		return false
+	}
+	log.Printf("took lock of job %s; running it on this instance", name)
+	r.locks[name] = lock
+	jobLockHeld.WithLabelValues(name).Set(1)
+	return true
+}
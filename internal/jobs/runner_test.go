@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeLocker struct {
+	free  bool
+	taken int
+}
+
+func (l *fakeLocker) TryLock(ctx context.Context, name string) (Lock, error) {
+	if !l.free {
+		return nil, nil
+	}
+	l.free = false
+	l.taken++
+	return &fakeLock{locker: l, held: true}, nil
+}
+
+type fakeLock struct {
+	locker *fakeLocker
+	held   bool
+}
+
+func (l *fakeLock) Held(ctx context.Context) bool { return l.held }
+
+// Release frees the lock unless its session was already lost
+func (l *fakeLock) Release() {
+	if l.held {
+		l.locker.free = true
+	}
+}
+
+func TestRunner_RunsOnlyWithLock(t *testing.T) {
+	// Given: another instance holds the lock
+	locker := &fakeLocker{}
+	runner := NewRunner(locker)
+	runs := 0
+	job := func() error { runs++; return nil }
+
+	// When/Then: the job is skipped
+	runner.run("purge", job)
+	if runs != 0 {
+		t.Fatalf("expected no run without the lock, got %d", runs)
+	}
+
+	// Given: the lock is released
+	locker.free = true
+
+	// When/Then: the job runs, and the lock is kept for later runs
+	runner.run("purge", job)
+	runner.run("purge", job)
+	if runs != 2 || locker.taken != 1 {
+		t.Fatalf("expected 2 runs under one lock, got %d runs and %d locks", runs, locker.taken)
+	}
+	if held := runner.Held(); len(held) != 1 || held[0] != "purge" {
+		t.Fatalf("expected the purge lock to be held, got %v", held)
+	}
+
+	// When: the lock's session is lost and another instance takes it
+	runner.locks["purge"].(*fakeLock).held = false
+	locker.free = false
+	runner.run("purge", job)
+
+	// Then: the job is skipped again
+	if runs != 2 {
+		t.Errorf("expected no run after losing the lock, got %d", runs)
+	}
+	if held := runner.Held(); len(held) != 0 {
+		t.Errorf("expected no lock to be held, got %v", held)
+	}
+}
+
+func TestRunner_StopReleasesLocks(t *testing.T) {
+	locker := &fakeLocker{free: true}
+	runner := NewRunner(locker)
+	runner.run("purge", func() error { return nil })
+
+	runner.Stop()
+
+	if !locker.free {
+		t.Error("expected the lock to be released on stop")
+	}
+}
+
+func TestRunner_WithoutLocker(t *testing.T) {
+	runner := NewRunner(nil)
+	runs := 0
+	runner.run("purge", func() error { runs++; return nil })
+	if runs != 1 {
+		t.Errorf("expected the job to run, got %d runs", runs)
+	}
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// requestFields are facts about a request learned while it is handled, such as its
+// caller. Every context derived from the request's shares them, so records logged
+// before authentication lack the caller but later ones, and the request log, have it.
+type requestFields struct {
+	mu        sync.RWMutex
+	route     string
+	principal string
+	tenant    string
+}
+
+type requestFieldsKey struct{}
+
+// WithRequestFields prepares ctx to carry the route and caller of its request;
+// SetRoute and SetCaller fill them in
+func WithRequestFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestFieldsKey{}, &requestFields{})
+}
+
+// SetRoute records the route template of the request, e.g. /api/v1/users/:uuid.
+// It does nothing on a context without WithRequestFields.
+func SetRoute(ctx context.Context, route string) {
+	if f, ok := ctx.Value(requestFieldsKey{}).(*requestFields); ok {
+		f.mu.Lock()
+		f.route = route
+		f.mu.Unlock()
+	}
+}
+
+// SetCaller records the principal - the API key name, token subject or personal
+// token owner - and tenant of the request. It does nothing on a context without
+// WithRequestFields.
+func SetCaller(ctx context.Context, principal, tenant string) {
+	if f, ok := ctx.Value(requestFieldsKey{}).(*requestFields); ok {
+		f.mu.Lock()
+		f.principal, f.tenant = principal, tenant
+		f.mu.Unlock()
+	}
+}
+
+// requestAttrs returns the fields known so far as http.route, principal and
+// tenant; unknown ones are left out
+func requestAttrs(ctx context.Context) []slog.Attr {
+	f, ok := ctx.Value(requestFieldsKey{}).(*requestFields)
+	if !ok {
+		return nil
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var attrs []slog.Attr
+	if f.route != "" {
+		attrs = append(attrs, slog.String("http.route", f.route))
+	}
+	if f.principal != "" {
+		attrs = append(attrs, slog.String("principal", f.principal))
+	}
+	if f.tenant != "" {
+		attrs = append(attrs, slog.String("tenant", f.tenant))
+	}
+	return attrs
+}
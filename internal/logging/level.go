@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LevelState describes the level of a LevelSwitch
+type LevelState struct {
+	Level string `json:"level"`
+	// ChangedBy and ChangedAt are unset while the configured level applies
+	ChangedBy string     `json:"changed_by,omitempty"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+	// RevertAt is when the configured level comes back, for temporary changes
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// LevelSwitch is the runtime log level, shared by the logger and the admin
+// endpoint that changes it
+type LevelSwitch struct {
+	level      slog.LevelVar
+	configured slog.Level
+
+	mu    sync.Mutex
+	state LevelState
+	// revert restores the configured level after a temporary change; generation
+	// tells a stale revert from the current one
+	revert     *time.Timer
+	generation int
+}
+
+// NewLevelSwitch creates the switch at the configured level
+func NewLevelSwitch(configured string) (*LevelSwitch, error) {
+	level, err := ParseLevel(configured)
+	if err != nil {
+		return nil, err
+	}
+	s := &LevelSwitch{configured: level}
+	s.level.Set(level)
+	s.state = LevelState{Level: levelName(level)}
+	return s, nil
+}
+
+// Level implements slog.Leveler
+func (s *LevelSwitch) Level() slog.Level {
+	return s.level.Level()
+}
+
+// State returns the current state
+func (s *LevelSwitch) State() LevelState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Set changes the level on behalf of changedBy. With a positive duration the
+// configured level comes back after it, so a debug session cannot be forgotten.
+func (s *LevelSwitch) Set(level slog.Level, changedBy string, duration time.Duration) LevelState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.revert != nil {
+		s.revert.Stop()
+		s.revert = nil
+	}
+	s.generation++
+
+	now := time.Now().UTC()
+	s.state = LevelState{Level: levelName(level), ChangedBy: changedBy, ChangedAt: &now}
+	if duration > 0 {
+		revertAt := now.Add(duration)
+		s.state.RevertAt = &revertAt
+		generation := s.generation
+		s.revert = time.AfterFunc(duration, func() { s.reset(generation) })
+	}
+	s.level.Set(level)
+	return s.state
+}
+
+// reset restores the configured level, unless generation was superseded by a
+// later Set
+func (s *LevelSwitch) reset(generation int) {
+	s.mu.Lock()
+	if s.generation != generation {
+		s.mu.Unlock()
+		return
+	}
+	s.revert = nil
+	s.state = LevelState{Level: levelName(s.configured)}
+	s.level.Set(s.configured)
+	s.mu.Unlock()
+	slog.Info("log level reverted to the configured level", "level", levelName(s.configured))
+}
+
+// levelName returns the name ParseLevel reads back, e.g. "debug"
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
@@ -0,0 +1,116 @@
+// Package logging sets up structured logging with log/slog. Records logged with a
+// request's context carry its request ID, its route, caller and tenant once known
+// and, when it is traced, its trace ID.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"cruder/internal/config"
+	"cruder/internal/tracing"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID attaches the ID of the request ctx belongs to
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID attached to ctx, or "" outside a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Setup makes a logger writing to w the default. The standard log package writes
+// through it too, so log.Printf calls become records at info level. The returned
+// switch changes the level of the default logger at runtime.
+func Setup(cfg config.LoggingConfig, w io.Writer) (*LevelSwitch, error) {
+	level, err := NewLevelSwitch(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := newLogger(cfg, w, level)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return level, nil
+}
+
+// New creates a logger writing cfg.Format records of at least cfg.Level to w
+func New(cfg config.LoggingConfig, w io.Writer) (*slog.Logger, error) {
+	level, err := NewLevelSwitch(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	return newLogger(cfg, w, level)
+}
+
+// ParseLevel reads debug, info, warn or error, ignoring case
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q", name)
+}
+
+func newLogger(cfg config.LoggingConfig, w io.Writer, level slog.Leveler) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	switch cfg.Format {
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "pretty":
+		// NO_COLOR (https://no-color.org) turns the colors off, e.g. when piping to a file
+		handler = newPrettyHandler(w, level, os.Getenv("NO_COLOR") == "")
+	default:
+		return nil, fmt.Errorf("invalid log format %q", cfg.Format)
+	}
+	handler = contextHandler{handler}
+	if cfg.Sampling.Enabled {
+		handler = samplingHandler{Handler: handler, sampler: newSampler(cfg.Sampling)}
+	}
+	return slog.New(handler), nil
+}
+
+// contextHandler adds the request and trace IDs and the request fields found in a
+// record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		r.AddAttrs(slog.String("trace_id", traceID))
+	}
+	r.AddAttrs(requestAttrs(ctx)...)
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
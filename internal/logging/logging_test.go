@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+)
+
+func TestPrettyFormat(t *testing.T) {
+	// Given: A pretty logger with colors turned off
+	t.Setenv("NO_COLOR", "1")
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: "debug", Format: "pretty"}, &buf)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// When: Logging with attributes, a group and a value that needs quoting
+	logger.With("component", "repository").WithGroup("http").
+		Warn("slow query", "route", "/users/:uuid", "error", "context deadline exceeded")
+
+	// Then: One readable line, with grouped keys dotted
+	line := buf.String()
+	want := ` WRN slow query component=repository http.route=/users/:uuid http.error="context deadline exceeded"` + "\n"
+	if !strings.HasSuffix(line, want) || strings.Count(line, "\n") != 1 {
+		t.Errorf("expected a line ending in %q, got %q", want, line)
+	}
+	if strings.Contains(line, "\033[") {
+		t.Errorf("expected no colors with NO_COLOR set, got %q", line)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	// Given: A logger keeping the first 2 records per message, then every 3rd
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: "info", Format: "json", Sampling: config.LogSamplingConfig{
+		Enabled: true, First: 2, Thereafter: 3, Interval: time.Hour,
+	}}, &buf)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// When: The same info message is logged 8 times, along with warnings
+	for i := 0; i < 8; i++ {
+		logger.Info("Incoming request")
+		logger.Warn("slow query")
+	}
+
+	// Then: Records 1, 2, 5 and 8 of the info message are kept; warnings all are
+	if n := strings.Count(buf.String(), "Incoming request"); n != 4 {
+		t.Errorf("expected 4 sampled info records, got %d", n)
+	}
+	if n := strings.Count(buf.String(), "slow query"); n != 8 {
+		t.Errorf("expected every warning, got %d", n)
+	}
+}
+
+func TestSampler_NewInterval(t *testing.T) {
+	// Given: A sampler that has used up its first records
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	s := newSampler(config.LogSamplingConfig{First: 1, Thereafter: 100, Interval: time.Second})
+	s.now = func() time.Time { return now }
+	s.allow("msg")
+	if s.allow("msg") {
+		t.Fatal("expected the second record to be dropped")
+	}
+
+	// When: The next interval starts
+	now = now.Add(time.Second)
+
+	// Then: The message is logged again
+	if !s.allow("msg") {
+		t.Error("expected the count to start over")
+	}
+}
+
+func TestLevelSwitch(t *testing.T) {
+	// Given: A switch configured at info
+	level, err := NewLevelSwitch("info")
+	if err != nil {
+		t.Fatalf("NewLevelSwitch: %v", err)
+	}
+
+	// When: Debug is turned on for a short while
+	state := level.Set(slog.LevelDebug, "ops", 20*time.Millisecond)
+
+	// Then: It applies right away and is undone after the duration
+	if level.Level() != slog.LevelDebug || state.Level != "debug" || state.ChangedBy != "ops" || state.RevertAt == nil {
+		t.Fatalf("expected debug set by ops with a revert time, got %+v", state)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for level.Level() != slog.LevelInfo && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := level.State(); level.Level() != slog.LevelInfo || got.Level != "info" || got.ChangedBy != "" {
+		t.Errorf("expected the configured level back, got %+v", got)
+	}
+
+	// When: A permanent change follows a temporary one
+	level.Set(slog.LevelDebug, "ops", 20*time.Millisecond)
+	level.Set(slog.LevelWarn, "ops", 0)
+	time.Sleep(50 * time.Millisecond)
+
+	// Then: The earlier revert does not undo it
+	if level.Level() != slog.LevelWarn {
+		t.Errorf("expected warn to stay, got %v", level.Level())
+	}
+}
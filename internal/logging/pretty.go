@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ANSI escape sequences of the pretty format
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiBlue   = "\033[34m"
+	ansiCyan   = "\033[36m"
+)
+
+// prettyHandler writes one short, optionally colored line per record for reading
+// in a terminal: time, level, message, then the attributes as key=value
+type prettyHandler struct {
+	w     io.Writer
+	mu    *sync.Mutex
+	level slog.Leveler
+	color bool
+	// attrs holds the attributes added by WithAttrs, formatted already
+	attrs []byte
+	// prefix qualifies attribute keys with the groups opened by WithGroup
+	prefix string
+}
+
+func newPrettyHandler(w io.Writer, level slog.Leveler, color bool) *prettyHandler {
+	return &prettyHandler{w: w, mu: &sync.Mutex{}, level: level, color: color}
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	if !r.Time.IsZero() {
+		buf = h.paint(buf, ansiDim, r.Time.Format("15:04:05.000"))
+		buf = append(buf, ' ')
+	}
+	buf = h.appendLevel(buf, r.Level)
+	buf = append(buf, ' ')
+	buf = append(buf, r.Message...)
+	buf = append(buf, h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, h.prefix, a)
+		return true
+	})
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf)
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := *h
+	next.attrs = append([]byte(nil), h.attrs...)
+	for _, a := range attrs {
+		next.attrs = h.appendAttr(next.attrs, h.prefix, a)
+	}
+	return &next
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	next := *h
+	next.prefix = h.prefix + name + "."
+	return &next
+}
+
+func (h *prettyHandler) appendLevel(buf []byte, level slog.Level) []byte {
+	switch {
+	case level >= slog.LevelError:
+		return h.paint(buf, ansiRed, "ERR")
+	case level >= slog.LevelWarn:
+		return h.paint(buf, ansiYellow, "WRN")
+	case level >= slog.LevelInfo:
+		return h.paint(buf, ansiGreen, "INF")
+	default:
+		return h.paint(buf, ansiBlue, "DBG")
+	}
+}
+
+// appendAttr writes a as " key=value"; groups are flattened into dotted keys
+func (h *prettyHandler) appendAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, member := range a.Value.Group() {
+			buf = h.appendAttr(buf, prefix, member)
+		}
+		return buf
+	}
+
+	buf = append(buf, ' ')
+	buf = h.paint(buf, ansiCyan, prefix+a.Key+"=")
+	var value string
+	if a.Value.Kind() == slog.KindTime {
+		value = a.Value.Time().Format(time.RFC3339Nano)
+	} else {
+		value = a.Value.String()
+	}
+	if value == "" || strings.ContainsAny(value, " \"=\t\r\n") {
+		value = strconv.Quote(value)
+	}
+	return append(buf, value...)
+}
+
+// paint appends s, in color when the handler uses colors
+func (h *prettyHandler) paint(buf []byte, color, s string) []byte {
+	if !h.color {
+		return append(buf, s...)
+	}
+	buf = append(buf, color...)
+	buf = append(buf, s...)
+	return append(buf, ansiReset...)
+}
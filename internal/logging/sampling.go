@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var sampledRecords = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "log_records_sampled_total",
+	Help: "Log records dropped by sampling, by level.",
+}, []string{"level"})
+
+// sampler lets through the first records with each message in every interval and
+// then only every thereafter-th one
+type sampler struct {
+	first      int
+	thereafter int
+	interval   time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	start  time.Time
+	counts map[string]int
+}
+
+func newSampler(cfg config.LogSamplingConfig) *sampler {
+	return &sampler{
+		first:      cfg.First,
+		thereafter: cfg.Thereafter,
+		interval:   cfg.Interval,
+		now:        time.Now,
+		counts:     make(map[string]int),
+	}
+}
+
+// allow counts a record with message and reports whether it is logged
+func (s *sampler) allow(message string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := s.now(); now.Sub(s.start) >= s.interval {
+		s.start = now
+		clear(s.counts)
+	}
+	s.counts[message]++
+	n := s.counts[message]
+	return n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0)
+}
+
+// samplingHandler thins out debug and info records; warnings and errors are
+// always logged
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+func (h samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !h.sampler.allow(r.Message) {
+		sampledRecords.WithLabelValues(levelName(r.Level)).Inc()
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h samplingHandler) WithGroup(name string) slog.Handler {
+	return samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageRecorder receives one call per served request; analytics.Collector implements it
+type UsageRecorder interface {
+	Record(apiKey, method, route string, status int, duration time.Duration)
+}
+
+// Analytics reports every matched request to usage, attributed to the API key that
+// authenticated it or to "anonymous"
+func Analytics(usage UsageRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		apiKey := "anonymous"
+		if p := GetPrincipal(c); p != nil {
+			apiKey = p.Name
+		}
+		usage.Record(apiKey, c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
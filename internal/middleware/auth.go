@@ -1,36 +1,184 @@
 package middleware
 
 import (
-	"net/http"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"cruder/internal/apierror"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
 
-// APIKeyAuth creates a middleware that validates X-API-Key header
-func APIKeyAuth(validAPIKey string) gin.HandlerFunc {
+const apiKeyContextKey = "api_key"
+
+// APIKeyAuth creates a middleware that validates X-API-Key header against the
+// configured keys and enforces their origin/referrer restrictions
+func APIKeyAuth(keys []config.APIKeyConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract X-API-Key from request header
 		apiKey := c.GetHeader("X-API-Key")
 
 		// Check if API key is missing
 		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "API key required",
-			})
-			c.Abort()
+			AbortWithError(c, apierror.Unauthorized("api_key_required", "API key required"))
 			return
 		}
 
 		// Check if API key is invalid
-		if apiKey != validAPIKey {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error": "Invalid API key",
-			})
-			c.Abort()
+		key := FindAPIKey(keys, apiKey)
+		if key == nil {
+			AbortWithError(c, apierror.Forbidden("invalid_api_key", "Invalid API key"))
+			return
+		}
+
+		// Check that a restricted key is used from one of its allowed sites
+		if !originAllowed(key, c.GetHeader("Origin"), c.GetHeader("Referer")) {
+			AbortWithError(c, apierror.Forbidden("origin_not_allowed", "API key not allowed from this origin"))
 			return
 		}
 
 		// API key is valid, continue with the request
+		c.Set(apiKeyContextKey, key)
+		setPrincipal(c, &Principal{Name: key.Name, Type: "api_key", Scopes: key.Scopes, Tenant: key.Tenant})
+		c.Next()
+	}
+}
+
+// TokenVerifier checks bearer tokens and returns their claims
+type TokenVerifier interface {
+	Verify(token string) (*auth.Claims, error)
+}
+
+// JWTAuth creates a middleware that validates the bearer token in the Authorization
+// header and authenticates the request as the token's subject
+func JWTAuth(tokens TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			c.Header("WWW-Authenticate", "Bearer")
+			AbortWithError(c, apierror.Unauthorized("token_required", "bearer token required"))
+			return
+		}
+
+		claims, err := tokens.Verify(token)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			AbortWithError(c, apierror.Unauthorized("invalid_token", "invalid token"))
+			return
+		}
+
+		setPrincipal(c, &Principal{Name: claims.Subject, Type: "jwt", Scopes: claims.Scopes, Tenant: claims.Tenant})
+		c.Next()
+	}
+}
+
+// Authenticate accepts either scheme: a bearer token when the request carries one,
+// an X-API-Key otherwise. tokens may be nil to accept API keys only; with
+// allowAPIKeys false only tokens are accepted.
+func Authenticate(keys []config.APIKeyConfig, tokens TokenVerifier, allowAPIKeys bool) gin.HandlerFunc {
+	apiKeys := APIKeyAuth(keys)
+	if tokens == nil {
+		return apiKeys
+	}
+	jwt := JWTAuth(tokens)
+	return func(c *gin.Context) {
+		if _, ok := bearerToken(c); ok || !allowAPIKeys {
+			jwt(c)
+			return
+		}
+		apiKeys(c)
+	}
+}
+
+// PersonalTokenVerifier checks the access tokens users create for themselves; Verify
+// returns auth.ErrInvalidToken for tokens that must be rejected
+type PersonalTokenVerifier interface {
+	Verify(ctx context.Context, token string) (*model.PersonalToken, error)
+}
+
+// PersonalTokenAuth authenticates requests bearing a personal token as the token's
+// owner, with the token's scopes; other requests are passed to next
+func PersonalTokenAuth(tokens PersonalTokenVerifier, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret, ok := bearerToken(c)
+		if !ok || !auth.IsPersonalToken(secret) {
+			next(c)
+			return
+		}
+
+		token, err := tokens.Verify(c.Request.Context(), secret)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidToken) {
+				slog.ErrorContext(c.Request.Context(), "failed to verify personal token", "error", err)
+				AbortWithError(c, apierror.Unavailable("token_verification_unavailable", "failed to verify token"))
+				return
+			}
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			AbortWithError(c, apierror.Unauthorized("invalid_token", "invalid token"))
+			return
+		}
+
+		setPrincipal(c, &Principal{Name: token.Username, Type: "personal_token", Scopes: token.Scopes, Tenant: token.Tenant})
 		c.Next()
 	}
 }
+
+// APIKeyVerifier checks the API keys managed through the admin API; Verify returns
+// auth.ErrInvalidAPIKey for keys that must be rejected
+type APIKeyVerifier interface {
+	Verify(ctx context.Context, key string) (*model.APIKey, error)
+}
+
+// ManagedAPIKeyAuth authenticates requests bearing a managed X-API-Key as the key,
+// with its scopes and tenant; other requests are passed to next
+func ManagedAPIKeyAuth(keys APIKeyVerifier, next gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader("X-API-Key")
+		if !auth.IsManagedAPIKey(secret) {
+			next(c)
+			return
+		}
+
+		key, err := keys.Verify(c.Request.Context(), secret)
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidAPIKey) {
+				slog.ErrorContext(c.Request.Context(), "failed to verify API key", "error", err)
+				AbortWithError(c, apierror.Unavailable("api_key_verification_unavailable", "failed to verify API key"))
+				return
+			}
+			AbortWithError(c, apierror.Forbidden("invalid_api_key", "Invalid API key"))
+			return
+		}
+
+		// Managed keys have no origin restrictions or signing secret
+		c.Set(apiKeyContextKey, &config.APIKeyConfig{Name: key.Name, Scopes: key.Scopes, Tenant: key.Tenant})
+		setPrincipal(c, &Principal{Name: key.Name, Type: "api_key", Scopes: key.Scopes, Tenant: key.Tenant})
+		c.Next()
+	}
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// FindAPIKey returns the configured key matching apiKey, or nil. It compares in
+// constant time so response timing does not leak key prefixes.
+func FindAPIKey(keys []config.APIKeyConfig, apiKey string) *config.APIKeyConfig {
+	var found *config.APIKeyConfig
+	for i := range keys {
+		if keys[i].Key != "" && subtle.ConstantTimeCompare([]byte(keys[i].Key), []byte(apiKey)) == 1 {
+			found = &keys[i]
+		}
+	}
+	return found
+}
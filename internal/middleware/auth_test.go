@@ -0,0 +1,290 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newAuthRouter(keys []config.APIKeyConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(keys))
+	router.GET("/protected", APIKeyAuth(keys), func(c *gin.Context) {
+		c.String(http.StatusOK, GetPrincipal(c).Name)
+	})
+	return router
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	keys := []config.APIKeyConfig{
+		{Name: "server", Key: "server-key"},
+		{Name: "widget", Key: "widget-key", AllowedOrigins: []string{"https://*.partner.example.com"}},
+		{Name: "embed", Key: "embed-key", AllowedReferrers: []string{"https://blog.example.com/posts/"}},
+	}
+	router := newAuthRouter(keys)
+
+	tests := []struct {
+		name     string
+		key      string
+		origin   string
+		referer  string
+		expected int
+	}{
+		{"missing key", "", "", "", http.StatusUnauthorized},
+		{"invalid key", "nope", "", "", http.StatusForbidden},
+		{"unrestricted key from anywhere", "server-key", "https://evil.example.org", "", http.StatusOK},
+		{"restricted key from allowed subdomain", "widget-key", "https://app.partner.example.com", "", http.StatusOK},
+		{"restricted key from other origin", "widget-key", "https://evil.example.org", "", http.StatusForbidden},
+		{"restricted key without origin", "widget-key", "", "", http.StatusForbidden},
+		{"restricted key with referer fallback", "widget-key", "", "https://app.partner.example.com/page", http.StatusOK},
+		{"referrer prefix match", "embed-key", "", "https://blog.example.com/posts/42", http.StatusOK},
+		{"referrer prefix mismatch", "embed-key", "", "https://blog.example.com/admin", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d (%s)", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	keys := []config.APIKeyConfig{
+		{Name: "widget", Key: "widget-key", AllowedOrigins: []string{"https://partner.example.com"}},
+	}
+	router := newAuthRouter(keys)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/protected", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Allowed origin gets CORS headers
+	w := preflight("https://partner.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://partner.example.com" {
+		t.Errorf("expected allow-origin header, got %q", got)
+	}
+
+	// Unknown origin is rejected
+	w = preflight("https://evil.example.org")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("expected no allow-origin header, got %q", got)
+	}
+}
+
+type fakeVerifier map[string]*auth.Claims
+
+func (f fakeVerifier) Verify(token string) (*auth.Claims, error) {
+	if claims, ok := f[token]; ok {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys := []config.APIKeyConfig{{Name: "server", Key: "server-key"}}
+	tokens := fakeVerifier{"good-token": {RegisteredClaims: jwt.RegisteredClaims{Subject: "ci"}}}
+	newRouter := func(allowAPIKeys bool) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/protected", Authenticate(keys, tokens, allowAPIKeys), func(c *gin.Context) {
+			p := GetPrincipal(c)
+			c.String(http.StatusOK, p.Type+":"+p.Name)
+		})
+		return router
+	}
+
+	tests := []struct {
+		name         string
+		allowAPIKeys bool
+		key          string
+		bearer       string
+		expected     int
+		principal    string
+	}{
+		{"token", true, "", "good-token", http.StatusOK, "jwt:ci"},
+		{"api key during migration", true, "server-key", "", http.StatusOK, "api_key:server"},
+		{"token wins over api key", true, "server-key", "good-token", http.StatusOK, "jwt:ci"},
+		{"invalid token", true, "server-key", "bad-token", http.StatusUnauthorized, ""},
+		{"nothing", true, "", "", http.StatusUnauthorized, ""},
+		{"api key once tokens are required", false, "server-key", "", http.StatusUnauthorized, ""},
+		{"token once tokens are required", false, "", "good-token", http.StatusOK, "jwt:ci"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+
+			newRouter(tt.allowAPIKeys).ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d (%s)", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.principal != "" && w.Body.String() != tt.principal {
+				t.Errorf("expected principal %s, got %s", tt.principal, w.Body.String())
+			}
+			if w.Code == http.StatusUnauthorized && tt.bearer != "" && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected a WWW-Authenticate challenge")
+			}
+		})
+	}
+}
+
+type fakePersonalTokens map[string]*model.PersonalToken
+
+func (f fakePersonalTokens) Verify(_ context.Context, token string) (*model.PersonalToken, error) {
+	if t, ok := f[token]; ok {
+		return t, nil
+	}
+	if token == "cpat_broken" {
+		return nil, errors.New("connection refused")
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+func TestPersonalTokenAuth(t *testing.T) {
+	keys := []config.APIKeyConfig{{Name: "server", Key: "server-key"}}
+	jwtTokens := fakeVerifier{"good-token": {RegisteredClaims: jwt.RegisteredClaims{Subject: "ci"}}}
+	personal := fakePersonalTokens{"cpat_good": {Username: "jdoe", Scopes: []string{"documents"}}}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", PersonalTokenAuth(personal, Authenticate(keys, jwtTokens, true)), func(c *gin.Context) {
+		p := GetPrincipal(c)
+		c.String(http.StatusOK, p.Type+":"+p.Name+":"+strings.Join(p.Scopes, ","))
+	})
+
+	tests := []struct {
+		name      string
+		key       string
+		bearer    string
+		expected  int
+		principal string
+	}{
+		{"personal token", "", "cpat_good", http.StatusOK, "personal_token:jdoe:documents"},
+		{"unknown personal token", "server-key", "cpat_unknown", http.StatusUnauthorized, ""},
+		{"verification failure", "", "cpat_broken", http.StatusServiceUnavailable, ""},
+		{"jwt passes through", "", "good-token", http.StatusOK, "jwt:ci:"},
+		{"api key passes through", "server-key", "", http.StatusOK, "api_key:server:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d (%s)", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.principal != "" && w.Body.String() != tt.principal {
+				t.Errorf("expected principal %s, got %s", tt.principal, w.Body.String())
+			}
+		})
+	}
+}
+
+type fakeManagedKeys map[string]*model.APIKey
+
+func (f fakeManagedKeys) Verify(_ context.Context, key string) (*model.APIKey, error) {
+	if k, ok := f[key]; ok {
+		return k, nil
+	}
+	if key == "cak_broken" {
+		return nil, errors.New("connection refused")
+	}
+	return nil, auth.ErrInvalidAPIKey
+}
+
+func TestManagedAPIKeyAuth(t *testing.T) {
+	keys := []config.APIKeyConfig{{Name: "server", Key: "server-key"}}
+	managed := fakeManagedKeys{"cak_good": {Name: "billing", Scopes: []string{"export"}, Tenant: "acme"}}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", ManagedAPIKeyAuth(managed, APIKeyAuth(keys)), func(c *gin.Context) {
+		p := GetPrincipal(c)
+		c.String(http.StatusOK, p.Type+":"+p.Name+":"+strings.Join(p.Scopes, ",")+":"+p.Tenant)
+	})
+
+	tests := []struct {
+		name      string
+		key       string
+		expected  int
+		principal string
+	}{
+		{"managed key", "cak_good", http.StatusOK, "api_key:billing:export:acme"},
+		{"unknown managed key", "cak_unknown", http.StatusForbidden, ""},
+		{"verification failure", "cak_broken", http.StatusServiceUnavailable, ""},
+		{"configured key passes through", "server-key", http.StatusOK, "api_key:server::"},
+		{"missing key passes through", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expected {
+				t.Fatalf("expected status %d, got %d (%s)", tt.expected, w.Code, w.Body.String())
+			}
+			if tt.principal != "" && w.Body.String() != tt.principal {
+				t.Errorf("expected principal %s, got %s", tt.principal, w.Body.String())
+			}
+		})
+	}
+}
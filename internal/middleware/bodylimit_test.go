@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimit(16, "/users/import"))
+	handle := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusCreated)
+	}
+	router.POST("/users", handle)
+	router.POST("/users/import", handle)
+
+	cases := []struct {
+		name        string
+		path        string
+		body        string
+		contentType string
+		chunked     bool
+		status      int
+	}{
+		{"within the limit", "/users", `{"username":"a"}`, "application/json", false, http.StatusCreated},
+		{"declared too large", "/users", `{"username":"asmith"}`, "application/json", false, http.StatusRequestEntityTooLarge},
+		{"too large without a length", "/users", `{"username":"asmith"}`, "application/json", true, http.StatusRequestEntityTooLarge},
+		{"upload route", "/users/import", strings.Repeat("x", 64), "multipart/form-data; boundary=x", false, http.StatusCreated},
+		{"multipart Content-Type on a JSON route", "/users", strings.Repeat("x", 64), "multipart/form-data; boundary=x", false, http.StatusRequestEntityTooLarge},
+		{"multipart Content-Type without a length", "/users", strings.Repeat("x", 64), "multipart/form-data; boundary=x", true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// When: Posting the body
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			if tc.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Then: Bodies over the limit are refused or cut off, except on upload routes
+			if w.Code != tc.status {
+				t.Errorf("expected status %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/netip"
+	"strings"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/metrics"
+	"cruder/internal/ratelimit"
+	"cruder/internal/reputation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Reasons a request is taken for automated
+const (
+	BotNoUserAgent = "missing_user_agent"
+	BotBadIP       = "bad_ip"
+	BotRate        = "request_rate"
+	BotHoneypot    = "honeypot"
+)
+
+// maxHoneypotBody bounds the body read for the honeypot field; larger bodies are
+// not checked
+const maxHoneypotBody = 64 << 10
+
+var (
+	botDetections = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "bot_detections_total",
+		Help: "Requests taken for automated, by reason and action (flagged, blocked).",
+	}, []string{"reason", "action"})
+	botCheckErrors = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "bot_check_errors_total",
+		Help: "Bot checks that could not be made, by check (reputation, rate); the request is not held against.",
+	}, []string{"check"})
+)
+
+// BotCheck configures BotDetection
+type BotCheck struct {
+	// Block rejects suspicious requests with 403; otherwise they are logged and let through
+	Block bool
+	// Reputation lists addresses known for abuse; nil checks none
+	Reputation reputation.Provider
+	// MaxRate is how many requests a client IP may make in RateWindow, counted in
+	// Rates, before more are suspicious; 0 counts none
+	Rates      ratelimit.Store
+	MaxRate    int
+	RateWindow time.Duration
+	// HoneypotField is a JSON field forms hide from people, so a request filling
+	// it is automated; empty checks none
+	HoneypotField string
+}
+
+// BotDetection checks requests for signs of automation: no User-Agent, a client
+// IP known for abuse, more requests from the IP than a person makes, or a filled
+// honeypot field. Suspicious requests are counted and logged, and rejected with
+// 403 when check.Block is set. Checks that fail, such as an unreachable
+// reputation service, let the request through.
+func BotDetection(check BotCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reasons := botReasons(c, check)
+		if len(reasons) == 0 {
+			c.Next()
+			return
+		}
+
+		action := "flagged"
+		if check.Block {
+			action = "blocked"
+		}
+		for _, reason := range reasons {
+			botDetections.WithLabelValues(reason, action).Inc()
+		}
+		slog.WarnContext(c.Request.Context(), "suspicious request", "http.route", c.FullPath(), "client.address", ClientIP(c),
+			"user_agent.original", c.Request.UserAgent(), "reasons", reasons, "action", action)
+		if check.Block {
+			AbortWithError(c, apierror.Forbidden("suspicious_request", "request blocked as automated"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func botReasons(c *gin.Context, check BotCheck) []string {
+	var reasons []string
+	if strings.TrimSpace(c.Request.UserAgent()) == "" {
+		reasons = append(reasons, BotNoUserAgent)
+	}
+	ip := ClientIP(c)
+	if addr, err := netip.ParseAddr(ip); err == nil && check.Reputation != nil {
+		verdict, err := check.Reputation.Check(c.Request.Context(), addr)
+		if err != nil {
+			botCheckErrors.WithLabelValues("reputation").Inc()
+			slog.ErrorContext(c.Request.Context(), "reputation check failed", "error", err)
+		} else if verdict.Listed {
+			reasons = append(reasons, BotBadIP)
+		}
+	}
+	if check.MaxRate > 0 {
+		count, _, err := check.Rates.Hit(c.Request.Context(), "bots:ip:"+ip, check.RateWindow)
+		if err != nil {
+			botCheckErrors.WithLabelValues("rate").Inc()
+			slog.ErrorContext(c.Request.Context(), "bot rate check failed", "error", err)
+		} else if count > int64(check.MaxRate) {
+			reasons = append(reasons, BotRate)
+		}
+	}
+	if check.HoneypotField != "" && honeypotFilled(c, check.HoneypotField) {
+		reasons = append(reasons, BotHoneypot)
+	}
+	return reasons
+}
+
+// honeypotFilled reports whether the JSON body sets field to anything but an
+// empty value. The body is left for the handler to read, without the field: it is
+// not part of the user, and handlers reject fields they do not know.
+func honeypotFilled(c *gin.Context, field string) bool {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") {
+		return false
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxHoneypotBody+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil || len(body) > maxHoneypotBody {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return false
+	}
+	value, ok := fields[field]
+	if !ok {
+		return false
+	}
+	delete(fields, field)
+	if stripped, err := json.Marshal(fields); err == nil {
+		c.Request.Body = io.NopCloser(bytes.NewReader(stripped))
+		c.Request.ContentLength = int64(len(stripped))
+	}
+	switch value := strings.TrimSpace(string(value)); value {
+	case "", "null", `""`, "false", "0":
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/ratelimit"
+	"cruder/internal/reputation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// staticReputation lists the addresses it holds, and fails when down
+type staticReputation struct {
+	listed map[string]bool
+	down   bool
+}
+
+func (r staticReputation) Check(_ context.Context, addr netip.Addr) (reputation.Verdict, error) {
+	if r.down {
+		return reputation.Verdict{}, errors.New("connection refused")
+	}
+	return reputation.Verdict{Listed: r.listed[addr.String()]}, nil
+}
+
+func botRouter(check BotCheck) (*gin.Engine, *string) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Errors())
+	var body string
+	router.POST("/users", BotDetection(check), func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		body = string(data)
+		c.Status(http.StatusCreated)
+	})
+	return router, &body
+}
+
+func register(router *gin.Engine, ip, userAgent, body string) int {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	r.RemoteAddr = ip + ":40000"
+	r.Header.Set("Content-Type", "application/json")
+	if userAgent != "" {
+		r.Header.Set("User-Agent", userAgent)
+	}
+	router.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestBotDetection_Block(t *testing.T) {
+	// Given: Blocking bot detection with a bad IP, a rate of 2 and a honeypot field
+	router, _ := botRouter(BotCheck{
+		Block:         true,
+		Reputation:    staticReputation{listed: map[string]bool{"203.0.113.66": true}},
+		Rates:         ratelimit.NewMemoryStore(),
+		MaxRate:       2,
+		RateWindow:    time.Minute,
+		HoneypotField: "website",
+	})
+	const browser = "Mozilla/5.0"
+	const user = `{"username":"jsmith","email":"j@example.com"}`
+
+	tests := []struct {
+		name      string
+		ip        string
+		userAgent string
+		body      string
+		want      int
+	}{
+		{"person", "192.0.2.1", browser, user, http.StatusCreated},
+		{"empty honeypot", "192.0.2.2", browser, `{"username":"jsmith","website":""}`, http.StatusCreated},
+		{"no user agent", "192.0.2.3", "", user, http.StatusForbidden},
+		{"bad ip", "203.0.113.66", browser, user, http.StatusForbidden},
+		{"filled honeypot", "192.0.2.4", browser, `{"username":"jsmith","website":"http://spam.example"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When: Registering
+			// Then: Only suspicious registrations are rejected
+			if got := register(router, tt.ip, tt.userAgent, tt.body); got != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	// When/Then: An IP registering faster than allowed is rejected after max_rate
+	codes := []int{}
+	for range 3 {
+		codes = append(codes, register(router, "192.0.2.5", browser, user))
+	}
+	if codes[0] != http.StatusCreated || codes[1] != http.StatusCreated || codes[2] != http.StatusForbidden {
+		t.Errorf("expected 201, 201, 403; got %v", codes)
+	}
+}
+
+func TestBotDetection_FlagStripsHoneypotAndFailsOpen(t *testing.T) {
+	// Given: Flagging bot detection whose reputation service is down
+	router, body := botRouter(BotCheck{Reputation: staticReputation{down: true}, HoneypotField: "website"})
+	const sent = `{"username":"jsmith","website":"filled"}`
+
+	// When: A bot fills the honeypot
+	code := register(router, "192.0.2.1", "curl/8.0", sent)
+
+	// Then: The registration is only flagged and the handler reads the body without
+	// the honeypot field
+	if code != http.StatusCreated || *body != `{"username":"jsmith"}` {
+		t.Errorf("got %d with body %q", code, *body)
+	}
+}
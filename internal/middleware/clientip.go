@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+type clientIPKey struct{}
+
+const clientIPContextKey = "client_ip"
+
+// RealIP resolves the client address once per request. Forwarding headers are only
+// honored when the peer is one of the trusted proxies configured on the engine.
+// The result is available through ClientIP and ClientIPFromContext.
+func RealIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		c.Set(clientIPContextKey, ip)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientIPKey{}, ip))
+		c.Next()
+	}
+}
+
+// ClientIP returns the resolved client address for the request
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPContextKey); ip != "" {
+		return ip
+	}
+	return c.ClientIP()
+}
+
+// ClientIPFromContext returns the client address stored by RealIP, for code that only
+// has the request context
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey{}).(string)
+	return ip
+}
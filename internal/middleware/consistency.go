@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"cruder/internal/apierror"
+	"cruder/internal/consistency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PositionSource reports the primary's current write position; repository.PositionRepository implements it
+type PositionSource interface {
+	CurrentPosition(ctx context.Context) (string, error)
+}
+
+// Consistency issues read-your-writes tokens. Successful POST/PUT/PATCH/DELETE
+// responses carry the primary's write position in X-Consistency-Token; a token
+// echoed on later requests is put on the request context, where read models check
+// it. Malformed tokens are rejected with 400.
+func Consistency(source PositionSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.GetHeader(consistency.Header); token != "" {
+			if !consistency.Valid(token) {
+				AbortWithError(c, apierror.Validation("invalid_consistency_token", "invalid "+consistency.Header))
+				return
+			}
+			c.Request = c.Request.WithContext(consistency.WithToken(c.Request.Context(), token))
+		}
+		if _, ok := mutationActions[c.Request.Method]; ok {
+			c.Writer = &consistencyWriter{ResponseWriter: c.Writer, ctx: c.Request.Context(), source: source}
+		}
+		c.Next()
+	}
+}
+
+// consistencyWriter stamps the token when a successful status is set, which
+// handlers do after their writes and before any of the body is sent
+type consistencyWriter struct {
+	gin.ResponseWriter
+	ctx     context.Context
+	source  PositionSource
+	stamped bool
+}
+
+func (w *consistencyWriter) WriteHeader(code int) {
+	if !w.stamped && code >= http.StatusOK && code < http.StatusMultipleChoices {
+		w.stamped = true
+		position, err := w.source.CurrentPosition(w.ctx)
+		if err != nil {
+			slog.ErrorContext(w.ctx, "failed to read write position", "header", consistency.Header, "error", err)
+		} else {
+			w.Header().Set(consistency.Header, position)
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/consistency"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fixedPosition string
+
+func (p fixedPosition) CurrentPosition(ctx context.Context) (string, error) {
+	return string(p), nil
+}
+
+func TestConsistency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Consistency(fixedPosition("0/16B3748")))
+	var seen string
+	router.GET("/users", func(c *gin.Context) {
+		seen = consistency.TokenFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, []string{})
+	})
+	router.POST("/users", func(c *gin.Context) { c.JSON(http.StatusCreated, gin.H{}) })
+	router.DELETE("/users", func(c *gin.Context) { c.JSON(http.StatusNotFound, gin.H{}) })
+
+	request := func(method, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/users", nil)
+		if token != "" {
+			req.Header.Set(consistency.Header, token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Successful mutations are stamped with the write position
+	if got := request(http.MethodPost, "").Header().Get(consistency.Header); got != "0/16B3748" {
+		t.Errorf("expected token 0/16B3748 on create, got %q", got)
+	}
+	// Failed mutations and reads are not
+	if got := request(http.MethodDelete, "").Header().Get(consistency.Header); got != "" {
+		t.Errorf("expected no token on a failed delete, got %q", got)
+	}
+	if got := request(http.MethodGet, "").Header().Get(consistency.Header); got != "" {
+		t.Errorf("expected no token on a read, got %q", got)
+	}
+
+	// An echoed token reaches the handler's context
+	if w := request(http.MethodGet, "0/16B3748"); w.Code != http.StatusOK || seen != "0/16B3748" {
+		t.Errorf("expected token on the request context, got status %d token %q", w.Code, seen)
+	}
+	// A malformed one is rejected
+	if w := request(http.MethodGet, "latest"); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a malformed token, got %d", w.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"cruder/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS answers browser preflight requests and adds CORS headers for origins that at
+// least one API key allows. Whether the key actually sent may be used from that
+// origin is decided later by APIKeyAuth.
+func CORS(keys []config.APIKeyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Origin")
+		allowed := false
+		for i := range keys {
+			if matchOrigin(keys[i].AllowedOrigins, origin) {
+				allowed = true
+				break
+			}
+		}
+
+		if allowed {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Expose-Headers", "Location, ETag, Idempotent-Replayed, X-Consistency-Token, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset, Retry-After")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			if !allowed {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Header("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "X-API-Key, Authorization, Content-Type, X-Consistency-Token, If-Match, Idempotency-Key")
+			c.Header("Access-Control-Max-Age", "600")
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// originAllowed reports whether a request may use key given its Origin and Referer.
+// Keys without restrictions are accepted from anywhere.
+func originAllowed(key *config.APIKeyConfig, origin, referer string) bool {
+	if len(key.AllowedOrigins) > 0 {
+		// Browsers omit Origin on some same-origin GETs; fall back to the Referer's origin
+		if origin == "" {
+			origin = refererOrigin(referer)
+		}
+		if !matchOrigin(key.AllowedOrigins, origin) {
+			return false
+		}
+	}
+
+	if len(key.AllowedReferrers) > 0 {
+		matched := false
+		for _, prefix := range key.AllowedReferrers {
+			if referer != "" && strings.HasPrefix(referer, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchOrigin supports exact origins and a leading "*." wildcard for subdomains
+func matchOrigin(patterns []string, origin string) bool {
+	if origin == "" {
+		return false
+	}
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+		if pattern == origin {
+			return true
+		}
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if ok && strings.HasPrefix(origin, scheme+"://") && strings.HasSuffix(origin, "."+host) {
+			return true
+		}
+	}
+	return false
+}
+
+func refererOrigin(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
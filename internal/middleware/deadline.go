@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Deadline bounds the request context by timeout. Handlers pass the context down
+// to the database, which turns the time left into each transaction's
+// statement_timeout, so queries stop once the client would have given up.
+func Deadline(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
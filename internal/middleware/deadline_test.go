@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeadline(t *testing.T) {
+	// Given: A router with a 2s request deadline
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Deadline(2 * time.Second))
+	var remaining time.Duration
+	router.GET("/users", func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		if !ok {
+			t.Fatal("expected the request context to have a deadline")
+		}
+		remaining = time.Until(deadline)
+		c.Status(http.StatusOK)
+	})
+
+	// When: A request is handled
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	// Then: Handlers see the deadline
+	if remaining <= 0 || remaining > 2*time.Second {
+		t.Errorf("expected up to 2s left, got %v", remaining)
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	"cruder/internal/timing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DebugHeader requests verbose logging and a timing breakdown for one request
+const DebugHeader = "X-Debug"
+
+// TimingHeader carries the timing breakdown of a debug request
+const TimingHeader = "X-Timing"
+
+const (
+	timingContextKey = "request_timing"
+	debugContextKey  = "debug"
+)
+
+// RequestStart attaches a timing recorder to every request so later middleware and
+// lower layers can report phase durations; it must be the first middleware
+func RequestStart() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec := timing.NewRecorder(time.Now())
+		c.Set(timingContextKey, rec)
+		c.Request = c.Request.WithContext(timing.WithRecorder(c.Request.Context(), rec))
+		c.Next()
+	}
+}
+
+// Debug must be the last middleware before the handlers: it records the time spent
+// until it runs as the "middleware" phase and the handler itself as "handler".
+// When the X-Debug header is set by a principal with the "debug" or "admin" scope,
+// the timings are also returned in the X-Timing header and the request is logged
+// at debug level. Requests from other principals are processed normally and the
+// header is ignored.
+func Debug() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rec := RequestTiming(c)
+		if rec == nil {
+			c.Next()
+			return
+		}
+		rec.Add("middleware", rec.Total())
+
+		if debugRequested(c.GetHeader(DebugHeader)) && GetPrincipal(c).HasScope("debug") {
+			c.Set(debugContextKey, true)
+			c.Writer = &timingWriter{ResponseWriter: c.Writer, rec: rec}
+		}
+
+		stop := timing.Track(c.Request.Context(), "handler")
+		c.Next()
+		stop()
+	}
+}
+
+// RequestTiming returns the timing recorder of the request, or nil when RequestStart
+// is not installed
+func RequestTiming(c *gin.Context) *timing.Recorder {
+	if v, ok := c.Get(timingContextKey); ok {
+		if rec, ok := v.(*timing.Recorder); ok {
+			return rec
+		}
+	}
+	return nil
+}
+
+// DebugTiming returns the recorder of a debug request, or nil for normal requests
+func DebugTiming(c *gin.Context) *timing.Recorder {
+	if c.GetBool(debugContextKey) {
+		return RequestTiming(c)
+	}
+	return nil
+}
+
+func debugRequested(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// timingWriter adds the X-Timing header right before the response headers are sent
+type timingWriter struct {
+	gin.ResponseWriter
+	rec     *timing.Recorder
+	written bool
+}
+
+func (w *timingWriter) setTiming() {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.Header().Set(TimingHeader, w.rec.Header())
+	}
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	w.setTiming()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) WriteHeaderNow() {
+	w.setTiming()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timingWriter) Write(data []byte) (int, error) {
+	w.setTiming()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timingWriter) WriteString(s string) (int, error) {
+	w.setTiming()
+	return w.ResponseWriter.WriteString(s)
+}
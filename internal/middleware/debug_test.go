@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/timing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDebug_TimingHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := []config.APIKeyConfig{
+		{Name: "ops", Key: "ops-key", Scopes: []string{"admin"}},
+		{Name: "support", Key: "support-key", Scopes: []string{"debug"}},
+		{Name: "app", Key: "app-key"},
+	}
+	router := gin.New()
+	router.Use(RequestStart())
+	router.GET("/users", APIKeyAuth(keys), Debug(), func(c *gin.Context) {
+		stop := timing.Track(c.Request.Context(), "service")
+		time.Sleep(time.Millisecond)
+		stop()
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		debug      string
+		wantTiming bool
+	}{
+		{"admin scope", "ops-key", "1", true},
+		{"debug scope", "support-key", "true", true},
+		{"unscoped key is ignored", "app-key", "1", false},
+		{"header not set", "ops-key", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set("X-API-Key", tt.key)
+			if tt.debug != "" {
+				req.Header.Set(DebugHeader, tt.debug)
+			}
+			w := httptest.NewRecorder()
+
+			// When
+			router.ServeHTTP(w, req)
+
+			// Then
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+			got := w.Header().Get(TimingHeader)
+			if !tt.wantTiming {
+				if got != "" {
+					t.Errorf("expected no %s header, got %q", TimingHeader, got)
+				}
+				return
+			}
+			for _, phase := range []string{"total;dur=", "middleware;dur=", "service;dur="} {
+				if !strings.Contains(got, phase) {
+					t.Errorf("expected %q in %s header, got %q", phase, TimingHeader, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"cruder/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UniformLookup keeps lookups of a single user from telling whether the user
+// exists: on the given routes, a forbidden or not-found answer becomes notFound,
+// and every answer is held back until minDuration has passed since the request
+// started, so found, missing and forbidden users take as long. It must run
+// before authorization so it sees its denials.
+func UniformLookup(notFound error, minDuration time.Duration, routes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(routes, c.FullPath()) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		if rec := RequestTiming(c); rec != nil {
+			start = start.Add(-rec.Total())
+		}
+
+		held := &heldWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = held
+		c.Next()
+		c.Writer = held.ResponseWriter
+
+		hidden := false
+		if held.written {
+			hidden = held.status == http.StatusForbidden || held.status == http.StatusNotFound
+		} else if last := c.Errors.Last(); last != nil {
+			hidden = errors.Is(last.Err, apierror.ErrForbidden) || errors.Is(last.Err, apierror.ErrNotFound)
+		}
+		time.Sleep(time.Until(start.Add(minDuration)))
+
+		switch {
+		case hidden:
+			_ = c.Error(notFound)
+			writeError(c, notFound)
+		case held.written:
+			c.Writer.WriteHeader(held.status)
+			c.Writer.WriteHeaderNow()
+			_, _ = c.Writer.Write(held.body.Bytes())
+		}
+		// Other errors are answered by Errors as usual
+	}
+}
+
+// heldWriter keeps the response until UniformLookup decides what to send
+type heldWriter struct {
+	gin.ResponseWriter
+	status  int
+	written bool
+	body    bytes.Buffer
+}
+
+func (w *heldWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *heldWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *heldWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *heldWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *heldWriter) Status() int {
+	return w.status
+}
+
+func (w *heldWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *heldWriter) Written() bool {
+	return w.written
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cruder/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUniformLookup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	notFound := apierror.NotFound("user_not_found", "users not found")
+	router := gin.New()
+	router.Use(Errors())
+	router.Use(UniformLookup(notFound, 50*time.Millisecond, "/users/username/:username"))
+	// A policy that only lets callers see "visible"
+	router.Use(func(c *gin.Context) {
+		if name := c.Param("username"); name == "secret" {
+			AbortWithError(c, apierror.Forbidden("forbidden_by_policy", "forbidden by policy").WithDetails(gin.H{"reason": "other tenant"}))
+		}
+	})
+	router.GET("/users/username/:username", func(c *gin.Context) {
+		if c.Param("username") != "visible" {
+			_ = c.Error(apierror.NotFound("user_not_found", "users not found"))
+			return
+		}
+		c.Header("ETag", `"1"`)
+		c.JSON(http.StatusOK, gin.H{"username": "visible"})
+	})
+	router.GET("/users/search", func(c *gin.Context) {
+		_ = c.Error(apierror.Forbidden("forbidden_by_policy", "forbidden by policy"))
+	})
+
+	request := func(path string) (*httptest.ResponseRecorder, time.Duration) {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w, time.Since(start)
+	}
+
+	// Given: A user the caller may see, one it may not, and one that does not exist
+	// When: Looking each up
+	found, foundTook := request("/users/username/visible")
+	forbidden, forbiddenTook := request("/users/username/secret")
+	missing, missingTook := request("/users/username/nobody")
+
+	// Then: The hidden and missing users are answered alike, and all take the minimum time
+	if found.Code != http.StatusOK || found.Body.String() != `{"username":"visible"}` || found.Header().Get("ETag") != `"1"` {
+		t.Errorf("found user answered %d %s", found.Code, found.Body)
+	}
+	if forbidden.Code != http.StatusNotFound || forbidden.Body.String() != missing.Body.String() || missing.Code != http.StatusNotFound {
+		t.Errorf("forbidden answered %d %s, missing %d %s", forbidden.Code, forbidden.Body, missing.Code, missing.Body)
+	}
+	for _, took := range []time.Duration{foundTook, forbiddenTook, missingTook} {
+		if took < 50*time.Millisecond {
+			t.Errorf("lookup answered after %v, want at least 50ms", took)
+		}
+	}
+
+	// When/Then: Other routes are left alone
+	if other, took := request("/users/search"); other.Code != http.StatusForbidden || took >= 50*time.Millisecond {
+		t.Errorf("other route answered %d after %v", other.Code, took)
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"cruder/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Errors answers requests whose handlers recorded an error with ctx.Error and
+// wrote nothing, using the last error recorded. *apierror.Error values are
+// answered with their status and code; any other error is logged and answered
+// with a generic 500 so internal details do not reach the client.
+func Errors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		last := c.Errors.Last()
+		if last == nil || c.Writer.Written() {
+			return
+		}
+		writeError(c, last.Err)
+	}
+}
+
+// AbortWithError stops the chain and answers the request with err right away; it
+// is meant for middleware, which must not depend on Errors running around them
+func AbortWithError(c *gin.Context, err error) {
+	_ = c.Error(err)
+	writeError(c, err)
+	c.Abort()
+}
+
+func writeError(c *gin.Context, err error) {
+	status, body := apierror.Render(err)
+	if body.Code == apierror.InternalCode {
+		slog.ErrorContext(c.Request.Context(), "request failed", "http.route", c.FullPath(), "error", err)
+	}
+	c.JSON(status, body)
+}
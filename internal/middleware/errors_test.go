@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Given: A router whose handlers record errors instead of writing responses
+	router := gin.New()
+	router.Use(Errors())
+	router.GET("/missing", func(c *gin.Context) {
+		_ = c.Error(apierror.NotFound("user_not_found", "users not found"))
+	})
+	router.GET("/invalid", func(c *gin.Context) {
+		_ = c.Error(apierror.Validation("invalid_user", "email is invalid").WithDetails([]string{"email"}))
+	})
+	router.GET("/internal", func(c *gin.Context) {
+		_ = c.Error(errors.New("pq: connection refused"))
+	})
+	router.GET("/written", func(c *gin.Context) {
+		_ = c.Error(errors.New("logged only"))
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/aborted", func(c *gin.Context) {
+		AbortWithError(c, apierror.RateLimited("rate_limit_exceeded", "rate limit exceeded"))
+	})
+
+	cases := []struct {
+		path    string
+		status  int
+		code    string
+		message string
+		details bool
+	}{
+		{path: "/missing", status: http.StatusNotFound, code: "user_not_found", message: "users not found"},
+		{path: "/invalid", status: http.StatusBadRequest, code: "invalid_user", message: "email is invalid", details: true},
+		{path: "/internal", status: http.StatusInternalServerError, code: apierror.InternalCode, message: "internal server error"},
+		{path: "/aborted", status: http.StatusTooManyRequests, code: "rate_limit_exceeded", message: "rate limit exceeded"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			// When
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			// Then: The error is rendered as the envelope with its status
+			if rr.Code != tc.status {
+				t.Fatalf("expected status %d, got %d (%s)", tc.status, rr.Code, rr.Body.String())
+			}
+			var body map[string]any
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("invalid body %s: %v", rr.Body.String(), err)
+			}
+			if body["code"] != tc.code || body["message"] != tc.message {
+				t.Errorf("expected %s/%q, got %s", tc.code, tc.message, rr.Body.String())
+			}
+			if _, ok := body["details"]; ok != tc.details {
+				t.Errorf("expected details present %v, got %s", tc.details, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("/written", func(t *testing.T) {
+		// When: A handler records an error but writes its own response
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/written", nil))
+
+		// Then: The response is left alone
+		if rr.Code != http.StatusOK || rr.Body.String() != `{"ok":true}` {
+			t.Errorf("expected the handler's response, got %d %s", rr.Code, rr.Body.String())
+		}
+	})
+}
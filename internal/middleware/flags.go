@@ -0,0 +1,41 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// FlagSet decides which feature flags are on for a caller
+type FlagSet interface {
+	// Enabled reports whether flag is on for subject, a stable identifier of the caller
+	Enabled(flag, subject string) bool
+}
+
+const flagsContextKey = "feature_flags"
+
+// Flags makes flags available to FlagEnabled in the handlers of the request
+func Flags(flags FlagSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(flagsContextKey, flags)
+		c.Next()
+	}
+}
+
+// FlagEnabled reports whether flag is on for the caller of c. Authenticated callers
+// are identified by their principal, so an API key or user gets the same answer on
+// every request; anonymous ones by client IP. Every flag is off without Flags.
+func FlagEnabled(c *gin.Context, flag string) bool {
+	v, ok := c.Get(flagsContextKey)
+	if !ok {
+		return false
+	}
+	flags, ok := v.(FlagSet)
+	if !ok {
+		return false
+	}
+	return flags.Enabled(flag, flagSubject(c))
+}
+
+func flagSubject(c *gin.Context) string {
+	if p := GetPrincipal(c); p != nil {
+		return p.Type + ":" + p.Name
+	}
+	return "ip:" + ClientIP(c)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// subjectFlags turns a flag on for a single subject
+type subjectFlags struct {
+	subject string
+}
+
+func (f subjectFlags) Enabled(flag, subject string) bool {
+	return flag == "v2_response_shape" && subject == f.subject
+}
+
+func TestFlagEnabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		principal *Principal
+		flags     FlagSet
+		want      bool
+	}{
+		{name: "API key in the rollout", principal: &Principal{Name: "partner", Type: "api_key"}, flags: subjectFlags{"api_key:partner"}, want: true},
+		{name: "API key outside the rollout", principal: &Principal{Name: "other", Type: "api_key"}, flags: subjectFlags{"api_key:partner"}, want: false},
+		{name: "user in the rollout", principal: &Principal{Name: "jsmith", Type: "jwt"}, flags: subjectFlags{"jwt:jsmith"}, want: true},
+		{name: "anonymous callers by client IP", flags: subjectFlags{"ip:192.0.2.1"}, want: true},
+		{name: "off without flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: A router evaluating a flag for the caller
+			router := gin.New()
+			if tt.flags != nil {
+				router.Use(Flags(tt.flags))
+			}
+			if tt.principal != nil {
+				router.Use(func(c *gin.Context) { setPrincipal(c, tt.principal) })
+			}
+			var got bool
+			router.GET("/", func(c *gin.Context) {
+				got = FlagEnabled(c, "v2_response_shape")
+			})
+
+			// When: Making a request
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Then: The flag is on only for the subject it was rolled out to
+			if got != tt.want {
+				t.Errorf("FlagEnabled = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/metrics"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Idempotency headers
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentRequestBytes = 1 << 20
+	// idempotencyStoreTimeout bounds storing the outcome, which must happen even
+	// when the client has gone away
+	idempotencyStoreTimeout = 5 * time.Second
+)
+
+// idempotentHeaders are the response headers replayed along with the body
+var idempotentHeaders = []string{"Content-Type", "Location", "ETag"}
+
+var idempotentReplays = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "idempotent_replays_total",
+	Help: "Requests answered with the stored response of an earlier request with the same Idempotency-Key.",
+})
+
+// IdempotencyStore keeps the responses of requests sent with an Idempotency-Key;
+// repository.IdempotencyRepository implements it
+type IdempotencyStore interface {
+	Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*model.IdempotencyKey, error)
+	Complete(ctx context.Context, key string, response model.IdempotentResponse) error
+	Release(ctx context.Context, key string) error
+}
+
+// Idempotency makes retries of a request sent with an Idempotency-Key return the
+// first response instead of running again, for ttl. Keys are scoped to the caller,
+// so it must run after authentication. A key reused for a different request is
+// rejected with 422, and a retry arriving while the first request still runs with
+// 409. Server errors are not stored, so a retry after one runs the request again.
+// Requests without the header pass through.
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientKey := c.GetHeader(IdempotencyKeyHeader)
+		if clientKey == "" {
+			c.Next()
+			return
+		}
+		if len(clientKey) > maxIdempotencyKeyLength {
+			AbortWithError(c, apierror.Validation("invalid_idempotency_key", "Idempotency-Key is longer than 255 characters"))
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotentRequestBytes+1))
+		if err != nil {
+			AbortWithError(c, apierror.Validation("invalid_body", "failed to read request body"))
+			return
+		}
+		if len(body) > maxIdempotentRequestBytes {
+			AbortWithError(c, apierror.TooLarge("body_too_large", "request body too large for Idempotency-Key"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key := clientKey
+		if p := GetPrincipal(c); p != nil {
+			key = p.Type + ":" + p.Name + ":" + clientKey
+		}
+		fingerprint := requestFingerprint(c.Request, body)
+		stored, err := store.Claim(c.Request.Context(), key, fingerprint, ttl)
+		switch {
+		case err != nil:
+			slog.ErrorContext(c.Request.Context(), "failed to claim idempotency key", "error", err)
+			AbortWithError(c, apierror.Unavailable("idempotency_unavailable", "Idempotency-Key cannot be checked right now"))
+			return
+		case stored == nil:
+		case stored.Fingerprint != fingerprint:
+			AbortWithError(c, apierror.Unprocessable("idempotency_key_reused", "Idempotency-Key was used for a different request"))
+			return
+		case stored.Response == nil:
+			AbortWithError(c, apierror.Conflict("idempotency_key_in_use", "a request with this Idempotency-Key is still being processed"))
+			return
+		default:
+			idempotentReplays.Inc()
+			replay(c, stored.Response)
+			return
+		}
+
+		// The outcome is stored after the client may have given up, which is when it matters
+		ctx := context.WithoutCancel(c.Request.Context())
+		recorder := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			// Also reached when the handler panics
+			storeCtx, cancel := context.WithTimeout(ctx, idempotencyStoreTimeout)
+			defer cancel()
+			if err := store.Release(storeCtx, key); err != nil {
+				slog.ErrorContext(ctx, "failed to release idempotency key", "error", err)
+			}
+		}()
+
+		c.Next()
+
+		// Errors are rendered here rather than by Errors, so the stored body has them
+		if last := c.Errors.Last(); last != nil && !c.Writer.Written() {
+			writeError(c, last.Err)
+		}
+		status := c.Writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		response := model.IdempotentResponse{Status: status, Headers: map[string]string{}, Body: recorder.body.Bytes()}
+		for _, name := range idempotentHeaders {
+			if value := c.Writer.Header().Get(name); value != "" {
+				response.Headers[name] = value
+			}
+		}
+		storeCtx, cancel := context.WithTimeout(ctx, idempotencyStoreTimeout)
+		defer cancel()
+		if err := store.Complete(storeCtx, key, response); err != nil {
+			slog.ErrorContext(ctx, "failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// requestFingerprint identifies a request by method, path and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	_, _ = io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func replay(c *gin.Context, response *model.IdempotentResponse) {
+	for name, value := range response.Headers {
+		c.Header(name, value)
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Status(response.Status)
+	_, _ = c.Writer.Write(response.Body)
+	c.Abort()
+}
+
+// recordingWriter keeps a copy of the response body
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
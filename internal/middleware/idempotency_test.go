@@ -0,0 +1,171 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyStore keeps keys in a map; keys never expire
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]*model.IdempotencyKey
+}
+
+func (s *memoryIdempotencyStore) Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*model.IdempotencyKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.keys[key]; ok {
+		return stored, nil
+	}
+	s.keys[key] = &model.IdempotencyKey{Fingerprint: fingerprint}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(ctx context.Context, key string, response model.IdempotentResponse) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[key].Response = &response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, key)
+	return nil
+}
+
+// newIdempotencyRouter serves POST /users with a handler that counts its runs and
+// fails with err when set
+func newIdempotencyRouter(store IdempotencyStore, runs *int, err *error) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Errors())
+	router.POST("/users", Idempotency(store, time.Hour), func(c *gin.Context) {
+		*runs++
+		if *err != nil {
+			_ = c.Error(*err)
+			return
+		}
+		c.Header("Location", "/users/id/1")
+		c.JSON(http.StatusCreated, gin.H{"id": *runs})
+	})
+	return router
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	// Given: A request that created a user
+	store := &memoryIdempotencyStore{keys: map[string]*model.IdempotencyKey{}}
+	var runs int
+	var err error
+	router := newIdempotencyRouter(store, &runs, &err)
+	first := postWithKey(router, "key-1", `{"username":"jdoe"}`)
+
+	// When: It is retried with the same key
+	retry := postWithKey(router, "key-1", `{"username":"jdoe"}`)
+
+	// Then: The retry gets the first response without running the handler again
+	if runs != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", runs)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the first response, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get("Location") != "/users/id/1" || retry.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("unexpected headers %v", retry.Header())
+	}
+	if first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Error("expected the first response not to be marked as replayed")
+	}
+}
+
+func TestIdempotency_Rejections(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: map[string]*model.IdempotencyKey{
+		"running": {Fingerprint: requestFingerprint(httptest.NewRequest(http.MethodPost, "/users", nil), []byte(`{}`))},
+	}}
+	var runs int
+	var err error
+	router := newIdempotencyRouter(store, &runs, &err)
+	postWithKey(router, "key-1", `{"username":"jdoe"}`)
+
+	cases := []struct {
+		name   string
+		key    string
+		body   string
+		status int
+		code   string
+	}{
+		{"another request", "key-1", `{"username":"asmith"}`, http.StatusUnprocessableEntity, "idempotency_key_reused"},
+		{"first still running", "running", `{}`, http.StatusConflict, "idempotency_key_in_use"},
+		{"key too long", strings.Repeat("k", 256), `{}`, http.StatusBadRequest, "invalid_idempotency_key"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := postWithKey(router, tc.key, tc.body)
+			if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.code) {
+				t.Errorf("expected %d %s, got %d %s", tc.status, tc.code, w.Code, w.Body.String())
+			}
+		})
+	}
+	if runs != 1 {
+		t.Errorf("expected only the first request to run, ran %d times", runs)
+	}
+}
+
+func TestIdempotency_StoresClientErrorsButNotServerErrors(t *testing.T) {
+	// Given: A request failing with a server error, then one with a client error
+	store := &memoryIdempotencyStore{keys: map[string]*model.IdempotencyKey{}}
+	var runs int
+	err := errors.New("connection reset")
+	router := newIdempotencyRouter(store, &runs, &err)
+	postWithKey(router, "key-1", `{}`)
+	err = apierror.Conflict("username_taken", "username already exists")
+	conflict := postWithKey(router, "key-1", `{}`)
+
+	// When: Retrying once more after the error is gone
+	err = nil
+	retry := postWithKey(router, "key-1", `{}`)
+
+	// Then: The server error let the request run again; the conflict is kept
+	if runs != 2 {
+		t.Errorf("expected the handler to run twice, ran %d times", runs)
+	}
+	if conflict.Code != http.StatusConflict || retry.Code != http.StatusConflict || retry.Body.String() != conflict.Body.String() {
+		t.Errorf("expected the conflict to be replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	store := &memoryIdempotencyStore{keys: map[string]*model.IdempotencyKey{}}
+	var runs int
+	var err error
+	router := newIdempotencyRouter(store, &runs, &err)
+
+	postWithKey(router, "", `{}`)
+	postWithKey(router, "", `{}`)
+
+	if runs != 2 || len(store.keys) != 0 {
+		t.Errorf("expected requests without a key to pass through, ran %d times with %d keys", runs, len(store.keys))
+	}
+}
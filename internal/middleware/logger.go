@@ -1,65 +1,87 @@
 package middleware
 
 import (
-	"encoding/json"
-	"log"
+	"log/slog"
+	"net/http"
+	"strings"
 	"time"
 
+	"cruder/internal/logging"
+	"cruder/internal/version"
+
 	"github.com/gin-gonic/gin"
 )
 
-// JSONLogger is a middleware that logs all incoming HTTP requests in JSON format
-func JSONLogger() gin.HandlerFunc {
+// Logger logs every request once it is handled, at error level for 5xx responses
+// and warn level for 4xx. The fields of the original JSON request log are kept,
+// including http.log.level. The request and trace IDs are added by the logging
+// handler from the request context, and so are the route template, principal and
+// tenant, which Logger makes every record logged during the request carry.
+func Logger() gin.HandlerFunc {
+	build := version.Get()
+
 	return func(c *gin.Context) {
-		// Record start time
 		start := time.Now()
+		ctx := logging.WithRequestFields(c.Request.Context())
+		logging.SetRoute(ctx, c.FullPath())
+		c.Request = c.Request.WithContext(ctx)
 
-		// Process request
 		c.Next()
 
-		// Calculate request duration in milliseconds
-		duration := time.Since(start).Milliseconds()
+		status := c.Writer.Status()
+		level := getLogLevel(status)
+		debug := DebugTiming(c)
+		if debug != nil {
+			level = "debug"
+		}
+		attrs := []slog.Attr{
+			slog.Int64("http.server.request.duration", time.Since(start).Milliseconds()),
+			slog.String("http.log.level", level),
+			slog.String("http.request.method", c.Request.Method),
+			slog.Int("http.response.status_code", status),
+			slog.String("http.request.message", "Incoming request:"),
+			slog.String("server.address", c.Request.URL.Path),
+			slog.String("http.request.host", c.Request.Host),
+			slog.String("client.address", ClientIP(c)),
+			slog.String("service.version", build.Version),
+			slog.String("service.commit", build.ShortCommit()),
+		}
 
-		// Extract route parameters
-		params := make(map[string]interface{})
+		// Route parameters naming a user are logged as user_id
 		for _, param := range c.Params {
-			// Map parameter names like "username" to "user_id" format
 			key := param.Key
 			if key == "username" || key == "id" || key == "uuid" {
-				params["user_id"] = param.Value
-			} else {
-				params[key] = param.Value
+				key = "user_id"
 			}
+			attrs = append(attrs, slog.String(key, param.Value))
 		}
 
-		// Build log entry
-		logEntry := map[string]interface{}{
-			"timestamp":                    time.Now().Format(time.RFC3339Nano),
-			"http.server.request.duration": duration,
-			"http.log.level":               getLogLevel(c.Writer.Status()),
-			"http.request.method":          c.Request.Method,
-			"http.response.status_code":    c.Writer.Status(),
-			"http.route":                   c.FullPath(),
-			"http.request.message":         "Incoming request:",
-			"server.address":               c.Request.URL.Path,
-			"http.request.host":            c.Request.Host,
+		// Debug requests are logged with request details and timings
+		if debug != nil {
+			attrs = append(attrs,
+				slog.String("http.request.query", c.Request.URL.RawQuery),
+				slog.Any("http.request.headers", debugHeaders(c.Request.Header)),
+				slog.Int("http.response.body.size", c.Writer.Size()),
+				slog.Any("timing", debug.Phases()),
+			)
 		}
 
-		// Add route parameters to the log entry
-		for key, value := range params {
-			logEntry[key] = value
-		}
+		slog.LogAttrs(c.Request.Context(), logLevel(status), "Incoming request", attrs...)
+	}
+}
 
-		// Marshal to JSON
-		jsonData, err := json.Marshal(logEntry)
-		if err != nil {
-			log.Printf("Error marshaling log entry: %v", err)
-			return
+// debugHeaders flattens request headers for logging, hiding credentials
+func debugHeaders(header http.Header) map[string]string {
+	out := make(map[string]string, len(header))
+	for name, values := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "X-Api-Key", "Authorization", "Cookie", "X-Signature":
+			out[name] = "[REDACTED]"
+		default:
+			out[name] = strings.Join(values, ", ")
 		}
-
-		// Log the JSON output with standard log prefix
-		log.Printf("Incoming request: %s", string(jsonData))
 	}
+	return out
 }
 
 // getLogLevel determines the log level based on HTTP status code
@@ -73,3 +95,15 @@ func getLogLevel(statusCode int) string {
 		return "info"
 	}
 }
+
+// logLevel is the slog level matching getLogLevel
+func logLevel(statusCode int) slog.Level {
+	switch {
+	case statusCode >= 500:
+		return slog.LevelError
+	case statusCode >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
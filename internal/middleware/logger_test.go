@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/config"
+	"cruder/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLogger_EnrichesRecords(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger, err := logging.New(config.LoggingConfig{Level: "info", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	// Given: A key of a tenant, and a handler logging with the request context
+	keys := []config.APIKeyConfig{{Name: "acme-sync", Key: "acme-key", Tenant: "acme"}}
+	router := gin.New()
+	router.Use(Logger())
+	router.GET("/users/:uuid", APIKeyAuth(keys), func(c *gin.Context) {
+		slog.InfoContext(c.Request.Context(), "loading user")
+		c.Status(http.StatusOK)
+	})
+
+	// When
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("X-API-Key", "acme-key")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Then: The handler's line and the request log carry the route, principal and tenant
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var record map[string]any
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if record["http.route"] != "/users/:uuid" || record["principal"] != "acme-sync" || record["tenant"] != "acme" {
+			t.Errorf("expected route, principal and tenant in %s", line)
+		}
+	}
+}
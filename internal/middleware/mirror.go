@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMirror copies requests to a shadow deployment
+type RequestMirror interface {
+	// Sample reports whether the request should be mirrored
+	Sample(r *http.Request) bool
+	// Mirror sends a copy of the request with its body; it must not block
+	Mirror(r *http.Request, body []byte)
+}
+
+// Mirror hands sampled requests to mirror before they are handled. Bodies over
+// maxBody bytes are not mirrored; the handler still receives them in full.
+func Mirror(mirror RequestMirror, maxBody int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !mirror.Sample(c.Request) {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > maxBody {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			read, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBody+1))
+			// Whatever was read is put back in front of the rest of the body
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(read), c.Request.Body), c.Request.Body}
+			if err != nil || int64(len(read)) > maxBody {
+				c.Next()
+				return
+			}
+			body = read
+		}
+		mirror.Mirror(c.Request, body)
+		c.Next()
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
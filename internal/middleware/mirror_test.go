@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type recordingMirror struct {
+	bodies []string
+}
+
+func (m *recordingMirror) Sample(r *http.Request) bool { return true }
+
+func (m *recordingMirror) Mirror(r *http.Request, body []byte) {
+	m.bodies = append(m.bodies, string(body))
+}
+
+func TestMirror(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name         string
+		body         string
+		wantMirrored bool
+	}{
+		{"small body is mirrored", `{"a":1}`, true},
+		{"large body is not mirrored", strings.Repeat("x", 20), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: A mirror limited to 10-byte bodies
+			m := &recordingMirror{}
+			router := gin.New()
+			router.Use(Mirror(m, 10))
+			var handled string
+			router.POST("/", func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				handled = string(body)
+			})
+
+			// When: A request is sent without a Content-Length
+			req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader(tt.body)))
+			req.ContentLength = -1
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			// Then: The handler always sees the full body
+			if handled != tt.body {
+				t.Errorf("expected handler to read %q, got %q", tt.body, handled)
+			}
+			if mirrored := len(m.bodies) == 1; mirrored != tt.wantMirrored {
+				t.Fatalf("expected mirrored %v, got %v", tt.wantMirrored, m.bodies)
+			}
+			if tt.wantMirrored && m.bodies[0] != tt.body {
+				t.Errorf("expected mirrored body %q, got %q", tt.body, m.bodies[0])
+			}
+		})
+	}
+}
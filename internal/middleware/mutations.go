@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MutationRecorder receives one call per successful mutation; anomaly.Detector implements it
+type MutationRecorder interface {
+	RecordMutation(apiKey, action string)
+}
+
+const mutationCountKey = "mutation_count"
+
+var mutationActions = map[string]string{
+	http.MethodPost:   "create",
+	http.MethodPut:    "update",
+	http.MethodPatch:  "update",
+	http.MethodDelete: "delete",
+}
+
+// CountMutations tells MutationMonitor how many users a bulk request changed;
+// other requests count as one
+func CountMutations(c *gin.Context, n int) {
+	c.Set(mutationCountKey, n)
+}
+
+// MutationMonitor reports successful POST/PUT/PATCH/DELETE requests to recorder,
+// attributed to the authenticated API key
+func MutationMonitor(recorder MutationRecorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		action, ok := mutationActions[c.Request.Method]
+		if !ok || c.Writer.Status() >= http.StatusBadRequest {
+			return
+		}
+		p := GetPrincipal(c)
+		if p == nil {
+			return
+		}
+		n := 1
+		if count, ok := c.Get(mutationCountKey); ok {
+			n = count.(int)
+		}
+		for range n {
+			recorder.RecordMutation(p.Name, action)
+		}
+	}
+}
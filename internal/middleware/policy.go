@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"log/slog"
+
+	"cruder/internal/apierror"
+	"cruder/internal/metrics"
+	"cruder/internal/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var policyDecisionsTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "policy_decisions_total",
+	Help: "Authorization decisions by the policy engine.",
+}, []string{"result"})
+
+// Authorize asks decider whether the authenticated request may proceed and
+// rejects denied requests with 403. It must run after authentication. When the
+// policy engine cannot be reached the request fails with 503, or is let through
+// when failOpen is set.
+func Authorize(decider policy.Decider, failOpen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decision, err := decider.Decide(c.Request.Context(), policyInput(c))
+		if err != nil {
+			policyDecisionsTotal.WithLabelValues("error").Inc()
+			slog.ErrorContext(c.Request.Context(), "policy decision failed", "http.request.method", c.Request.Method, "http.route", c.FullPath(), "error", err)
+			if failOpen {
+				c.Next()
+				return
+			}
+			AbortWithError(c, apierror.Unavailable("policy_unavailable", "authorization service unavailable"))
+			return
+		}
+
+		if !decision.Allow {
+			policyDecisionsTotal.WithLabelValues("deny").Inc()
+			err := apierror.Forbidden("forbidden_by_policy", "forbidden by policy")
+			if decision.Reason != "" {
+				err = err.WithDetails(gin.H{"reason": decision.Reason})
+			}
+			AbortWithError(c, err)
+			return
+		}
+		policyDecisionsTotal.WithLabelValues("allow").Inc()
+		c.Next()
+	}
+}
+
+func policyInput(c *gin.Context) policy.Input {
+	input := policy.Input{
+		Method:   c.Request.Method,
+		Route:    c.FullPath(),
+		Path:     c.Request.URL.Path,
+		Params:   make(map[string]string, len(c.Params)),
+		ClientIP: c.ClientIP(),
+	}
+	for _, p := range c.Params {
+		input.Params[p.Key] = p.Value
+	}
+	if p := GetPrincipal(c); p != nil {
+		input.Principal = &policy.Principal{Name: p.Name, Type: p.Type, Scopes: p.Scopes, Tenant: p.Tenant}
+	}
+	// Routes below /users/:uuid act on that user, who owns the resource
+	if uuid := c.Param("uuid"); uuid != "" {
+		input.Resource = &policy.Resource{Type: "user", ID: uuid, Owner: uuid}
+		if id := c.Param("note_id"); id != "" {
+			input.Resource = &policy.Resource{Type: "note", ID: id, Owner: uuid}
+		}
+		if id := c.Param("document_id"); id != "" {
+			input.Resource = &policy.Resource{Type: "document", ID: id, Owner: uuid}
+		}
+	}
+	if input.Principal != nil && input.Principal.Scopes == nil {
+		input.Principal.Scopes = []string{}
+	}
+	return input
+}
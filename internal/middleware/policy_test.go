@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+type stubDecider struct {
+	decision policy.Decision
+	err      error
+	input    policy.Input
+}
+
+func (d *stubDecider) Decide(ctx context.Context, input policy.Input) (policy.Decision, error) {
+	d.input = input
+	return d.decision, d.err
+}
+
+func newPolicyRouter(decider policy.Decider, failOpen bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/users/:uuid/notes/:note_id", func(c *gin.Context) {
+		setPrincipal(c, &Principal{Name: "partner", Type: "api_key", Scopes: []string{"users:read"}, Tenant: "acme"})
+		c.Next()
+	}, Authorize(decider, failOpen), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name     string
+		decider  *stubDecider
+		failOpen bool
+		expected int
+	}{
+		{"allowed", &stubDecider{decision: policy.Decision{Allow: true}}, false, http.StatusOK},
+		{"denied", &stubDecider{decision: policy.Decision{Reason: "tenant mismatch"}}, false, http.StatusForbidden},
+		{"engine unavailable", &stubDecider{err: errors.New("connection refused")}, false, http.StatusServiceUnavailable},
+		{"engine unavailable with fail open", &stubDecider{err: errors.New("connection refused")}, true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: A route guarded by the policy middleware
+			router := newPolicyRouter(tt.decider, tt.failOpen)
+
+			// When: The route is requested
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/u-1/notes/7", nil))
+
+			// Then: The decision determines the status
+			if w.Code != tt.expected {
+				t.Errorf("expected status %d, got %d (%s)", tt.expected, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthorize_Input(t *testing.T) {
+	// Given: A decider that records its input
+	decider := &stubDecider{decision: policy.Decision{Allow: true}}
+	router := newPolicyRouter(decider, false)
+
+	// When: A note of a user is requested
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/u-1/notes/7", nil))
+
+	// Then: The input carries principal, route and resource owner
+	in := decider.input
+	if in.Principal == nil || in.Principal.Name != "partner" || in.Principal.Tenant != "acme" {
+		t.Errorf("unexpected principal %+v", in.Principal)
+	}
+	if in.Route != "/users/:uuid/notes/:note_id" || in.Path != "/users/u-1/notes/7" {
+		t.Errorf("unexpected route %q and path %q", in.Route, in.Path)
+	}
+	if in.Resource == nil || *in.Resource != (policy.Resource{Type: "note", ID: "7", Owner: "u-1"}) {
+		t.Errorf("unexpected resource %+v", in.Resource)
+	}
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+
+	"cruder/internal/apierror"
+	"cruder/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Principal identifies the authenticated caller of a request
+type Principal struct {
+	// Name is the label of the API key, the subject of the token, or the owner of
+	// the personal token that authenticated the request
+	Name string `json:"name"`
+	// Type is the authentication scheme: "api_key", "jwt" or "personal_token"
+	Type string `json:"type"`
+	// Scopes are the capabilities granted to the caller
+	Scopes []string `json:"scopes,omitempty"`
+	// Tenant is the organisation the key belongs to, if configured
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// HasScope reports whether the caller was granted scope; "admin" implies every scope.
+// It is safe to call on a nil Principal.
+func (p *Principal) HasScope(scope string) bool {
+	if p == nil {
+		return false
+	}
+	for _, s := range p.Scopes {
+		if s == scope || s == "admin" {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+const principalContextKey = "principal"
+
+// setPrincipal stores p on both the gin context and the request context, and
+// names it in the request's log records
+func setPrincipal(c *gin.Context, p *Principal) {
+	c.Set(principalContextKey, p)
+	c.Request = c.Request.WithContext(WithPrincipal(c.Request.Context(), p))
+}
+
+// WithPrincipal returns ctx carrying p, for servers other than the gin router, and
+// names p in the log records of ctx's request
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	logging.SetCaller(ctx, p.Name, p.Tenant)
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// GetPrincipal returns the authenticated caller, or nil for anonymous requests
+func GetPrincipal(c *gin.Context) *Principal {
+	if v, ok := c.Get(principalContextKey); ok {
+		if p, ok := v.(*Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// PrincipalFromContext returns the caller stored on the request context, or nil
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// RequireScope rejects requests whose principal lacks scope with 403
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !GetPrincipal(c).HasScope(scope) {
+			AbortWithError(c, apierror.Forbidden("missing_scope", "API key lacks the "+scope+" scope"))
+			return
+		}
+		c.Next()
+	}
+}
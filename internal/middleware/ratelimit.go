@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/metrics"
+	"cruder/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Rate limit response headers
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+var (
+	rateLimited = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "rate_limited_requests_total",
+		Help: "Requests rejected because the client exceeded its rate limit.",
+	})
+	rateLimitErrors = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "rate_limit_backend_errors_total",
+		Help: "Requests whose rate limit could not be checked because the backend failed.",
+	})
+)
+
+// RateLimit allows each client limit requests per window and rejects the rest with
+// 429. Clients are told apart by API key name or token subject, or by IP before
+// authentication, so it should run after Authenticate. With failOpen, requests pass
+// while the store is unreachable; otherwise they are rejected with 503.
+func RateLimit(store ratelimit.Store, limit int, window time.Duration, failOpen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + ClientIP(c)
+		if p := GetPrincipal(c); p != nil {
+			key = p.Type + ":" + p.Name
+		}
+		if hit(c, store, key, limit, window, failOpen) {
+			c.Next()
+		}
+	}
+}
+
+// RateLimitByIP allows each client IP limit requests per window to a route, counted
+// apart from RateLimit under name, whichever credentials they carry; it throttles
+// probing that is spread over many keys
+func RateLimitByIP(store ratelimit.Store, name string, limit int, window time.Duration, failOpen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hit(c, store, name+":ip:"+ClientIP(c), limit, window, failOpen) {
+			c.Next()
+		}
+	}
+}
+
+// hit counts a request of key and reports whether it may proceed; otherwise the
+// request has been answered
+func hit(c *gin.Context, store ratelimit.Store, key string, limit int, window time.Duration, failOpen bool) bool {
+	count, reset, err := store.Hit(c.Request.Context(), key, window)
+	if err != nil {
+		rateLimitErrors.Inc()
+		slog.ErrorContext(c.Request.Context(), "rate limit check failed", "error", err)
+		if failOpen {
+			return true
+		}
+		AbortWithError(c, apierror.Unavailable("rate_limit_unavailable", "rate limiting unavailable"))
+		return false
+	}
+
+	resetSeconds := strconv.Itoa(int(math.Ceil(reset.Seconds())))
+	c.Header(RateLimitLimitHeader, strconv.Itoa(limit))
+	c.Header(RateLimitRemainingHeader, strconv.FormatInt(max(int64(limit)-count, 0), 10))
+	c.Header(RateLimitResetHeader, resetSeconds)
+	if count > int64(limit) {
+		rateLimited.Inc()
+		c.Header("Retry-After", resetSeconds)
+		AbortWithError(c, apierror.RateLimited("rate_limit_exceeded", "rate limit exceeded"))
+		return false
+	}
+	return true
+}
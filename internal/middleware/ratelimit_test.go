@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"cruder/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	return 0, 0, errors.New("connection refused")
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		setPrincipal(c, &Principal{Name: c.GetHeader("X-Test-Key")})
+	})
+	router.Use(RateLimit(ratelimit.NewMemoryStore(), 2, time.Minute, false))
+	router.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		r.Header.Set("X-Test-Key", key)
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// Given: A client with a limit of 2 requests per minute
+	// When/Then: Its first two requests pass with the remaining count
+	for remaining := 1; remaining >= 0; remaining-- {
+		w := request("partner")
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+		if got := w.Header().Get(RateLimitRemainingHeader); got != strconv.Itoa(remaining) {
+			t.Errorf("expected %d remaining, got %q", remaining, got)
+		}
+	}
+
+	// When/Then: The third is rejected until the window ends
+	w := request("partner")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+
+	// When/Then: Other clients have their own budget
+	if w := request("other"); w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for another key, got %d", w.Code)
+	}
+}
+
+func TestRateLimit_BackendDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		name     string
+		failOpen bool
+		want     int
+	}{
+		{"fail closed", false, http.StatusServiceUnavailable},
+		{"fail open", true, http.StatusOK},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RateLimit(failingRateLimitStore{}, 2, time.Minute, tt.failOpen))
+			router.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			if w.Code != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		setPrincipal(c, &Principal{Name: c.GetHeader("X-Test-Key")})
+	})
+	router.GET("/users/username/:username", RateLimitByIP(ratelimit.NewMemoryStore(), "username-lookup", 2, time.Minute, false), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	// Given: An IP allowed 2 lookups per minute
+	// When: It looks up three usernames, each with another key
+	codes := []int{}
+	for _, key := range []string{"a", "b", "c"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/users/username/jsmith", nil)
+		r.Header.Set("X-Test-Key", key)
+		router.ServeHTTP(w, r)
+		codes = append(codes, w.Code)
+	}
+
+	// Then: Switching keys does not get past the limit
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("expected 200, 200, 429; got %v", codes)
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var readOnlyGauge = promauto.With(metrics.Registry).NewGauge(prometheus.GaugeOpts{
+	Name: "read_only_mode",
+	Help: "1 while the emergency read-only mode rejects mutating requests.",
+})
+
+// ReadOnlyState describes the emergency read-only mode
+type ReadOnlyState struct {
+	Enabled bool   `json:"enabled"`
+	Reason  string `json:"reason,omitempty"`
+	// ChangedBy and ChangedAt are unset when the state comes from configuration
+	ChangedBy string     `json:"changed_by,omitempty"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+}
+
+// ReadOnlyMode is the runtime switch of the emergency read-only mode, shared by the
+// middleware and the admin endpoint that toggles it
+type ReadOnlyMode struct {
+	mu         sync.RWMutex
+	state      ReadOnlyState
+	retryAfter time.Duration
+}
+
+// NewReadOnlyMode creates the switch in its initial state; retryAfter is sent to
+// rejected clients
+func NewReadOnlyMode(initial ReadOnlyState, retryAfter time.Duration) *ReadOnlyMode {
+	m := &ReadOnlyMode{retryAfter: retryAfter}
+	m.Set(initial)
+	return m
+}
+
+// State returns the current state
+func (m *ReadOnlyMode) State() ReadOnlyState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set replaces the state
+func (m *ReadOnlyMode) Set(state ReadOnlyState) {
+	m.mu.Lock()
+	m.state = state
+	m.mu.Unlock()
+	if state.Enabled {
+		readOnlyGauge.Set(1)
+	} else {
+		readOnlyGauge.Set(0)
+	}
+}
+
+// ReadOnly rejects mutating requests (anything but GET, HEAD and OPTIONS) with 503
+// while mode is enabled; reads keep working
+func ReadOnly(mode *ReadOnlyMode) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		state := mode.State()
+		if !state.Enabled {
+			c.Next()
+			return
+		}
+
+		if mode.retryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(mode.retryAfter.Seconds())))
+		}
+		err := apierror.Unavailable("read_only", "service is in read-only mode")
+		if state.Reason != "" {
+			err = err.WithDetails(gin.H{"reason": state.Reason})
+		}
+		AbortWithError(c, err)
+	}
+}
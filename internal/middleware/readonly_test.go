@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestReadOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mode := NewReadOnlyMode(ReadOnlyState{}, 30*time.Second)
+	router := gin.New()
+	router.Use(ReadOnly(mode))
+	router.GET("/users", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/users", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	request := func(method string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/users", nil))
+		return w
+	}
+
+	// Given: Read-only mode is off
+	// When/Then: Writes go through
+	if w := request(http.MethodPost); w.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", w.Code)
+	}
+
+	// Given: Read-only mode is switched on at runtime
+	mode.Set(ReadOnlyState{Enabled: true, Reason: "database failover"})
+
+	// When/Then: Writes are rejected with 503 and reads keep working
+	w := request(http.MethodPost)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("expected Retry-After 30, got %q", got)
+	}
+	if w := request(http.MethodGet); w.Code != http.StatusOK {
+		t.Errorf("expected reads to work, got %d", w.Code)
+	}
+
+	// Given: It is switched off again
+	mode.Set(ReadOnlyState{})
+
+	// Then
+	if w := request(http.MethodPost); w.Code != http.StatusCreated {
+		t.Errorf("expected status 201, got %d", w.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"cruder/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the ID that ties together the log lines of one request
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds IDs accepted from clients and proxies
+const maxRequestIDLen = 128
+
+// RequestID takes the request's ID from X-Request-ID, or generates one when the
+// header is missing or unusable, and returns it in the response header. Records
+// logged with the request context carry it as request_id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := RequestIDOrNew(c.GetHeader(RequestIDHeader))
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID of the request, or "" when RequestID is not installed
+func GetRequestID(c *gin.Context) string {
+	return logging.RequestID(c.Request.Context())
+}
+
+// RequestIDOrNew returns id when it is usable as a request ID, and a generated
+// one otherwise
+func RequestIDOrNew(id string) string {
+	if !validRequestID(id) {
+		return newRequestID()
+	}
+	return id
+}
+
+// validRequestID accepts short IDs of characters safe to log and echo in a header
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/config"
+	"cruder/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	logger, err := logging.New(config.LoggingConfig{Level: "info", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	// Given: A router that logs requests and a handler logging with the request context
+	router := gin.New()
+	router.Use(RequestID(), Logger())
+	router.GET("/users", func(c *gin.Context) {
+		slog.InfoContext(c.Request.Context(), "listing users")
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		name     string
+		header   string
+		expectID string
+	}{
+		{name: "client ID is kept", header: "req-42.a:b_c", expectID: "req-42.a:b_c"},
+		{name: "missing ID is generated"},
+		{name: "unsafe ID is replaced", header: "bad id\r\ninjected"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			if tc.header != "" {
+				req.Header.Set(RequestIDHeader, tc.header)
+			}
+			rr := httptest.NewRecorder()
+
+			// When
+			router.ServeHTTP(rr, req)
+
+			// Then: The response and every log line carry the same ID
+			id := rr.Header().Get(RequestIDHeader)
+			if tc.expectID != "" && id != tc.expectID {
+				t.Errorf("expected request ID %q, got %q", tc.expectID, id)
+			}
+			if tc.expectID == "" && (len(id) != 32 || id == tc.header) {
+				t.Errorf("expected a generated request ID, got %q", id)
+			}
+			lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+			if len(lines) != 2 {
+				t.Fatalf("expected 2 log lines, got %d:\n%s", len(lines), buf.String())
+			}
+			for _, line := range lines {
+				var record map[string]any
+				if err := json.Unmarshal(line, &record); err != nil {
+					t.Fatalf("log line is not JSON: %s", line)
+				}
+				if record["request_id"] != id {
+					t.Errorf("expected request_id %q in %s", id, line)
+				}
+			}
+		})
+	}
+}
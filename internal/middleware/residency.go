@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"cruder/internal/residency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Residency routes the request to the database of its tenant's region, so the
+// repositories of user data read and write there. Callers without a tenant, or
+// with one not in tenants, use the home region. It must run after authentication.
+func Residency(tenants map[string]string, home string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		region := home
+		if p := GetPrincipal(c); p != nil {
+			if r, ok := tenants[p.Tenant]; ok {
+				region = r
+			}
+		}
+		c.Request = c.Request.WithContext(residency.WithRegion(c.Request.Context(), region))
+		c.Next()
+	}
+}
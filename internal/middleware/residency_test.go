@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cruder/internal/residency"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResidency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name      string
+		principal *Principal
+		want      string
+	}{
+		{"mapped tenant", &Principal{Name: "acme", Tenant: "acme-gmbh"}, "eu"},
+		{"unmapped tenant", &Principal{Name: "globex", Tenant: "globex"}, "us"},
+		{"no tenant", &Principal{Name: "default"}, "us"},
+		{"anonymous", nil, "us"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given: Residency behind a middleware standing in for authentication
+			var got string
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tc.principal != nil {
+					setPrincipal(c, tc.principal)
+				}
+			}, Residency(map[string]string{"acme-gmbh": "eu"}, "us"))
+			router.GET("/users", func(c *gin.Context) {
+				got = residency.Region(c.Request.Context())
+				c.Status(http.StatusOK)
+			})
+
+			// When
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+			// Then: The request is routed to the tenant's region, or home
+			if got != tc.want {
+				t.Errorf("expected region %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
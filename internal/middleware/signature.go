@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cruder/internal/apierror"
+	"cruder/internal/config"
+	"cruder/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Headers carried by HMAC-signed requests
+const (
+	SignatureHeader          = "X-Signature"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+)
+
+// maxSignedBodyBytes bounds how much of the body is buffered for signature checks
+const maxSignedBodyBytes = 10 << 20
+
+var signatureRejections = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "signed_requests_rejected_total",
+	Help: "HMAC-signed requests rejected, by reason (missing, stale, invalid, replay).",
+}, []string{"reason"})
+
+// NonceStore remembers nonces for a limited time
+type NonceStore interface {
+	// Remember records nonce and reports false if it was already seen within ttl
+	Remember(nonce string, ttl time.Duration) (bool, error)
+}
+
+// RequestSignature verifies HMAC signatures for API keys that have a signing secret
+// and rejects replays. It must run after APIKeyAuth or Authenticate.
+//
+// The signature is hex(HMAC-SHA256(secret, METHOD\nPATH?QUERY\nTIMESTAMP\nNONCE\nhex(SHA256(body)))).
+func RequestSignature(cfg config.SignatureConfig, nonces NonceStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get(apiKeyContextKey)
+		key, _ := v.(*config.APIKeyConfig)
+		signature := c.GetHeader(SignatureHeader)
+
+		if key == nil || key.SigningSecret == "" || (signature == "" && !key.RequireSignature) {
+			c.Next()
+			return
+		}
+
+		timestamp := c.GetHeader(SignatureTimestampHeader)
+		nonce := c.GetHeader(SignatureNonceHeader)
+		if signature == "" || timestamp == "" || nonce == "" {
+			rejectSignature(c, "missing", "request signature required")
+			return
+		}
+
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			rejectSignature(c, "stale", "invalid signature timestamp")
+			return
+		}
+		if skew := time.Since(time.Unix(unix, 0)); skew > cfg.MaxClockSkew || skew < -cfg.MaxClockSkew {
+			rejectSignature(c, "stale", "signature timestamp outside allowed window")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSignedBodyBytes))
+		if err != nil {
+			rejectSignature(c, "invalid", "failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		expected := SignRequest(key.SigningSecret, c.Request.Method, c.Request.URL.RequestURI(), timestamp, nonce, body)
+		provided, err := hex.DecodeString(strings.TrimSpace(signature))
+		if err != nil || !hmac.Equal(provided, expected) {
+			rejectSignature(c, "invalid", "invalid request signature")
+			return
+		}
+
+		// Only valid signatures consume a nonce, so garbage cannot pre-burn them
+		fresh, err := nonces.Remember(key.Name+":"+nonce, cfg.NonceTTL)
+		if err != nil {
+			AbortWithError(c, apierror.Unavailable("replay_protection_unavailable", "replay protection unavailable"))
+			return
+		}
+		if !fresh {
+			rejectSignature(c, "replay", "request replay detected")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// SignRequest computes the raw HMAC for a request; clients use the same construction
+func SignRequest(secret, method, requestURI, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{
+		strings.ToUpper(method),
+		requestURI,
+		timestamp,
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")))
+	return mac.Sum(nil)
+}
+
+func rejectSignature(c *gin.Context, reason, message string) {
+	signatureRejections.WithLabelValues(reason).Inc()
+	AbortWithError(c, apierror.Unauthorized("invalid_signature", message))
+}
+
+// MemoryNonceStore is an in-process NonceStore. With several replicas, route a key's
+// traffic consistently or use a shared store.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	seen      map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewMemoryNonceStore creates an empty in-memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{seen: make(map[string]time.Time)}
+}
+
+// Remember implements NonceStore
+func (s *MemoryNonceStore) Remember(nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Sweep expired entries at most once per TTL to keep memory bounded
+	if now.Sub(s.lastSweep) > ttl {
+		for n, expires := range s.seen {
+			if now.After(expires) {
+				delete(s.seen, n)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expires, ok := s.seen[nonce]; ok && now.Before(expires) {
+		return false, nil
+	}
+	s.seen[nonce] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newSignedRouter(keys []config.APIKeyConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := config.SignatureConfig{MaxClockSkew: time.Minute, NonceTTL: 2 * time.Minute}
+	router := gin.New()
+	router.POST("/signed", APIKeyAuth(keys), RequestSignature(cfg, NewMemoryNonceStore()), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func signedRequest(secret, nonce string, ts time.Time, body string) *http.Request {
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/signed?x=1", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "machine-key")
+	req.Header.Set(SignatureTimestampHeader, timestamp)
+	req.Header.Set(SignatureNonceHeader, nonce)
+	req.Header.Set(SignatureHeader, hex.EncodeToString(
+		SignRequest(secret, http.MethodPost, "/signed?x=1", timestamp, nonce, []byte(body))))
+	return req
+}
+
+func TestRequestSignature(t *testing.T) {
+	keys := []config.APIKeyConfig{{Name: "machine", Key: "machine-key", SigningSecret: "s3cret", RequireSignature: true}}
+	router := newSignedRouter(keys)
+
+	serve := func(req *http.Request) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// A correctly signed request is accepted once
+	if code := serve(signedRequest("s3cret", "n-1", time.Now(), `{"a":1}`)); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for valid signature, got %d", code)
+	}
+
+	// Replaying the same nonce is rejected
+	if code := serve(signedRequest("s3cret", "n-1", time.Now(), `{"a":1}`)); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for replay, got %d", code)
+	}
+
+	// A wrong secret is rejected
+	if code := serve(signedRequest("wrong", "n-2", time.Now(), `{"a":1}`)); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for invalid signature, got %d", code)
+	}
+
+	// A stale timestamp is rejected
+	if code := serve(signedRequest("s3cret", "n-3", time.Now().Add(-time.Hour), `{"a":1}`)); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for stale timestamp, got %d", code)
+	}
+
+	// An unsigned request is rejected when the key requires signatures
+	req := httptest.NewRequest(http.MethodPost, "/signed", strings.NewReader(`{}`))
+	req.Header.Set("X-API-Key", "machine-key")
+	if code := serve(req); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for unsigned request, got %d", code)
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var sloViolations = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "http_request_slo_violations_total",
+	Help: "Requests that exceeded the latency budget of their route.",
+}, []string{"method", "route"})
+
+// LatencyBudget logs a slow_request event and counts a violation for every request
+// that takes longer than the budget configured for its route. Route keys in cfg are
+// relative to basePath. The event includes phase timings when RequestStart and
+// Debug are installed.
+func LatencyBudget(cfg config.SLOConfig, basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		budget, ok := cfg.Routes[c.Request.Method+" "+strings.TrimPrefix(route, basePath)]
+		if !ok {
+			budget = cfg.Default
+		}
+		elapsed := time.Since(start)
+		if budget <= 0 || elapsed <= budget {
+			return
+		}
+
+		sloViolations.WithLabelValues(c.Request.Method, route).Inc()
+
+		attrs := []slog.Attr{
+			slog.String("event", "slow_request"),
+			slog.String("http.request.method", c.Request.Method),
+			slog.String("http.route", route),
+			slog.Int("http.response.status_code", c.Writer.Status()),
+			slog.Int64("slo.budget_ms", budget.Milliseconds()),
+			slog.Float64("slo.duration_ms", float64(elapsed.Microseconds())/1000),
+			slog.String("client.address", ClientIP(c)),
+		}
+		if rec := RequestTiming(c); rec != nil {
+			attrs = append(attrs, slog.Any("timing", rec.Phases()))
+		}
+		if p := GetPrincipal(c); p != nil {
+			attrs = append(attrs, slog.String("principal", p.Name))
+		}
+		slog.LogAttrs(c.Request.Context(), slog.LevelWarn, "Slow request", attrs...)
+	}
+}
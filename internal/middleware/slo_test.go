@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestLatencyBudget_CountsViolations(t *testing.T) {
+	// Given: A fast route within its budget and a slow route over it
+	gin.SetMode(gin.TestMode)
+	cfg := config.SLOConfig{
+		Default: time.Hour,
+		Routes:  map[string]time.Duration{"GET /slow": time.Millisecond},
+	}
+	router := gin.New()
+	router.Use(RequestStart(), LatencyBudget(cfg, "/svc"))
+	router.GET("/svc/slow", func(c *gin.Context) {
+		time.Sleep(5 * time.Millisecond)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/svc/fast", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	slow := sloViolations.WithLabelValues(http.MethodGet, "/svc/slow")
+	fast := sloViolations.WithLabelValues(http.MethodGet, "/svc/fast")
+	slowBefore, fastBefore := testutil.ToFloat64(slow), testutil.ToFloat64(fast)
+
+	// When: Calling both routes
+	for _, path := range []string{"/svc/slow", "/svc/fast"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Then: Only the slow route is counted
+	if got := testutil.ToFloat64(slow) - slowBefore; got != 1 {
+		t.Errorf("expected 1 violation for /svc/slow, got %v", got)
+	}
+	if got := testutil.ToFloat64(fast) - fastBefore; got != 0 {
+		t.Errorf("expected no violation for /svc/fast, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestTracing(t *testing.T) {
+	// Given: A recording tracer provider and the W3C propagator
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(t.Context()) })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Tracing())
+	router.GET("/users/:uuid", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	// When: A request continues a trace started by the caller
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Then: One server span joins the caller's trace and records the failure
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /users/:uuid" {
+		t.Errorf("expected span GET /users/:uuid, got %q", span.Name())
+	}
+	if got := span.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the caller's trace ID, got %s", got)
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the caller's span as parent, got %s", span.Parent().SpanID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status for a 500, got %v", span.Status().Code)
+	}
+	found := false
+	for _, attr := range span.Attributes() {
+		if attr == semconv.HTTPResponseStatusCode(http.StatusInternalServerError) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected http.response.status_code 500 in %v", span.Attributes())
+	}
+}
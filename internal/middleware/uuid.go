@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"cruder/internal/apierror"
+	"cruder/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UUIDParams answers 400 invalid_parameter when one of the named path parameters
+// is not a UUID, instead of passing it on to Postgres, which fails on it with a 500
+func UUIDParams(names ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, name := range names {
+			if value, ok := c.Params.Get(name); ok && !model.IsUUID(value) {
+				AbortWithError(c, apierror.Validation("invalid_parameter",
+					name+" must be a UUID such as 3fa85f64-5717-4562-b3fc-2c963f66afa6"))
+				return
+			}
+		}
+		c.Next()
+	}
+}
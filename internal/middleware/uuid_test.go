@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUUIDParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/users/:uuid", UUIDParams("uuid"), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, tc := range []struct {
+		name   string
+		uuid   string
+		status int
+	}{
+		{"valid", "3fa85f64-5717-4562-b3fc-2c963f66afa6", http.StatusNoContent},
+		{"upper case", "3FA85F64-5717-4562-B3FC-2C963F66AFA6", http.StatusNoContent},
+		{"malformed", "not-a-uuid", http.StatusBadRequest},
+		{"without hyphens", "3fa85f6457174562b3fc2c963f66afa6", http.StatusBadRequest},
+		{"URN", "urn:uuid:3fa85f64-5717-4562-b3fc-2c963f66afa6", http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// When: Deleting a user by the UUID
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/"+tc.uuid, nil))
+
+			// Then: Only UUIDs reach the handler; the rest get invalid_parameter
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.Code)
+			}
+			if tc.status == http.StatusBadRequest {
+				var body struct{ Code string }
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != "invalid_parameter" {
+					t.Errorf("expected invalid_parameter, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Package migrations applies the schema migrations embedded in the binary. It reads
+// and writes goose's version table, so it can be mixed freely with the goose CLI
+// (make migrate-up / migrate-down).
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"log"
+
+	sqlfiles "cruder/migrations"
+
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// newProvider prepares goose for db. A session-level advisory lock makes replicas
+// that start together apply the migrations one at a time.
+func newProvider(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, db, sqlfiles.FS, goose.WithSessionLocker(locker))
+}
+
+// Up applies every pending migration and logs each one
+func Up(ctx context.Context, db *sql.DB) error {
+	provider, err := newProvider(db)
+	if err != nil {
+		return err
+	}
+	results, err := provider.Up(ctx)
+	for _, result := range results {
+		log.Printf("migration: %s", result)
+	}
+	if err != nil {
+		return err
+	}
+	version, err := provider.GetDBVersion(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("database schema at version %d", version)
+	return nil
+}
+
+// Pending returns the schema version of db and how many embedded migrations it
+// lacks. It takes no lock, so it neither waits for nor blocks a replica applying
+// migrations.
+func Pending(ctx context.Context, db *sql.DB) (int64, int, error) {
+	provider, err := newProvider(db)
+	if err != nil {
+		return 0, 0, err
+	}
+	current, _, err := provider.GetVersions(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	pending := 0
+	for _, source := range provider.ListSources() {
+		if source.Version > current {
+			pending++
+		}
+	}
+	return current, pending, nil
+}
+
+// Down rolls back the most recent migration
+func Down(ctx context.Context, db *sql.DB) error {
+	provider, err := newProvider(db)
+	if err != nil {
+		return err
+	}
+	result, err := provider.Down(ctx)
+	if result != nil {
+		log.Printf("migration: %s", result)
+	}
+	return err
+}
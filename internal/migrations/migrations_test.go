@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"database/sql"
+	"io/fs"
+	"testing"
+
+	sqlfiles "cruder/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	// Given: A database handle that is never connected
+	db, err := sql.Open("postgres", "host=localhost dbname=none")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// When: Loading the embedded migrations
+	provider, err := newProvider(db)
+
+	// Then: Every SQL file is a migration with a unique version
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	files, err := fs.Glob(sqlfiles.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sources := provider.ListSources()
+	if len(sources) == 0 || len(sources) != len(files) {
+		t.Fatalf("expected %d migrations, got %d", len(files), len(sources))
+	}
+	for i := 1; i < len(sources); i++ {
+		if sources[i].Version <= sources[i-1].Version {
+			t.Errorf("migration %s is not ordered after %s", sources[i].Path, sources[i-1].Path)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Package mirror copies a share of live requests to a shadow deployment, e.g. to
+// validate a new API version or database driver under real traffic. Responses from
+// the shadow are discarded and never affect the client.
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"cruder/internal/httpclient"
+	"cruder/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var mirroredTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "mirrored_requests_total",
+	Help: "Requests copied to the shadow deployment by result (sent, error, dropped).",
+}, []string{"result"})
+
+// Header marks mirrored requests so the shadow can tell them apart
+const Header = "X-Mirrored-Request"
+
+// hopHeaders are connection-specific and not forwarded
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Options configures a Mirror
+type Options struct {
+	// Target is the shadow base URL; the request path and query are appended
+	Target string
+	// Percentage of requests to mirror, 0-100
+	Percentage float64
+	// Methods limits mirroring to these methods; empty mirrors all
+	Methods []string
+	// Timeout bounds each mirrored request
+	Timeout time.Duration
+	// MaxInFlight caps concurrent mirrored requests; more are dropped
+	MaxInFlight int
+}
+
+// Mirror sends copies of requests to the shadow target in the background
+type Mirror struct {
+	client *httpclient.Client
+	opts   Options
+	slots  chan struct{}
+}
+
+// New creates a Mirror; client should not retry, since shadow traffic is best effort
+func New(client *httpclient.Client, opts Options) *Mirror {
+	opts.Target = strings.TrimSuffix(opts.Target, "/")
+	return &Mirror{client: client, opts: opts, slots: make(chan struct{}, opts.MaxInFlight)}
+}
+
+// Sample reports whether r is selected for mirroring
+func (m *Mirror) Sample(r *http.Request) bool {
+	if len(m.opts.Methods) > 0 && !containsFold(m.opts.Methods, r.Method) {
+		return false
+	}
+	return rand.Float64()*100 < m.opts.Percentage
+}
+
+// Mirror sends a copy of r with body to the shadow without blocking. It must be
+// called before the handler returns, as it copies the headers of r.
+func (m *Mirror) Mirror(r *http.Request, body []byte) {
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		mirroredTotal.WithLabelValues("dropped").Inc()
+		return
+	}
+
+	header := r.Header.Clone()
+	for _, h := range hopHeaders {
+		header.Del(h)
+	}
+	header.Set(Header, "1")
+	method, url := r.Method, m.opts.Target+r.URL.RequestURI()
+
+	go func() {
+		defer func() { <-m.slots }()
+		if err := m.send(method, url, header, body); err != nil {
+			mirroredTotal.WithLabelValues("error").Inc()
+			log.Printf("mirror: %s %s failed: %v", method, url, err)
+			return
+		}
+		mirroredTotal.WithLabelValues("sent").Inc()
+	}()
+}
+
+func (m *Mirror) send(method, url string, header http.Header, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	defer cancel()
+
+	var reader io.Reader
+	if len(body) > 0 {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
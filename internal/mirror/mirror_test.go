@@ -0,0 +1,73 @@
+package mirror
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/httpclient"
+)
+
+type mirrored struct {
+	method, uri, body, marker, apiKey string
+}
+
+func TestMirror_SendsCopy(t *testing.T) {
+	// Given: A shadow server and a mirror sending every request to it
+	got := make(chan mirrored, 1)
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- mirrored{r.Method, r.URL.RequestURI(), string(body), r.Header.Get(Header), r.Header.Get("X-API-Key")}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer shadow.Close()
+	client := httpclient.New("mirror", config.HTTPClientConfig{Timeout: time.Second, BreakerThreshold: 10, BreakerCooldown: time.Minute})
+	m := New(client, Options{Target: shadow.URL + "/", Percentage: 100, Timeout: time.Second, MaxInFlight: 1})
+
+	// When: A request is mirrored
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/?dry=1", nil)
+	req.Header.Set("X-API-Key", "key")
+	if !m.Sample(req) {
+		t.Fatal("expected request to be sampled")
+	}
+	m.Mirror(req, []byte(`{"username":"jdoe"}`))
+
+	// Then: The shadow receives the same method, URI, headers and body, marked as mirrored
+	select {
+	case r := <-got:
+		expected := mirrored{http.MethodPost, "/api/v1/users/?dry=1", `{"username":"jdoe"}`, "1", "key"}
+		if r != expected {
+			t.Errorf("expected %+v, got %+v", expected, r)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request was not mirrored")
+	}
+}
+
+func TestMirror_Sample(t *testing.T) {
+	tests := []struct {
+		name       string
+		percentage float64
+		methods    []string
+		method     string
+		expected   bool
+	}{
+		{"zero percent", 0, nil, http.MethodGet, false},
+		{"all requests", 100, nil, http.MethodDelete, true},
+		{"method allowed", 100, []string{"get"}, http.MethodGet, true},
+		{"method not allowed", 100, []string{"GET"}, http.MethodPost, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(nil, Options{Percentage: tt.percentage, Methods: tt.methods, MaxInFlight: 1})
+			if got := m.Sample(httptest.NewRequest(tt.method, "/", strings.NewReader(""))); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package model
+
+// AggregateBucket is the number of users sharing one value of a group_by field.
+// Key is nil for users without a value, e.g. an unset custom field.
+type AggregateBucket struct {
+	Key   *string `json:"key"`
+	Count int     `json:"count"`
+}
+
+// UserQuota is how many live users the deployment has against its limit
+type UserQuota struct {
+	Count int `json:"count"`
+	// Limit is users.max_users; absent, like Remaining, when there is none
+	Limit     int  `json:"limit,omitempty"`
+	Remaining *int `json:"remaining,omitempty"`
+}
+
+// Stats is the body of GET /api/v1/admin/stats
+type Stats struct {
+	Users UserQuota `json:"users"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// APIKey is a key accepted in the X-API-Key header, managed through the admin API.
+// The key itself is only returned once, in Key, when it is created or rotated.
+type APIKey struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Prefix string   `json:"prefix"`
+	Key    string   `json:"key,omitempty"`
+	Hash   string   `json:"-"`
+	Scopes []string `json:"scopes"`
+	Tenant string   `json:"tenant,omitempty"`
+	// ExpiresAt is nil for keys that do not expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Revoked   bool       `json:"revoked"`
+	// RotatedFrom is the ID of the key this one replaced
+	RotatedFrom string     `json:"rotated_from,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyRequest describes a new key. Scopes default to none; ExpiresAt
+// defaults to never.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes"`
+	Tenant    string     `json:"tenant"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
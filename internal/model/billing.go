@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// BillingReport is what a deployment used in one calendar month (UTC), stored so
+// finance can bill without querying the database
+type BillingReport struct {
+	// Month is the reported month as YYYY-MM
+	Month       string    `json:"month"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Partial is set on reports of the current month, generated before it ended
+	Partial bool `json:"partial"`
+	// ActiveUsers counts the users that existed at any time during the month
+	ActiveUsers int `json:"active_users"`
+	// APICalls are the month's requests per API key name, busiest first
+	APICalls      []BillingAPICalls `json:"api_calls"`
+	TotalRequests int64             `json:"total_requests"`
+	Storage       BillingStorage    `json:"storage"`
+}
+
+// BillingAPICalls are the requests made with one API key in a month
+type BillingAPICalls struct {
+	APIKey   string `json:"api_key"`
+	Requests int64  `json:"requests"`
+	Errors   int64  `json:"errors"`
+}
+
+// BillingStorage is the size of the files kept in storage when a report is generated
+type BillingStorage struct {
+	DocumentsBytes int64 `json:"documents_bytes"`
+	ExportsBytes   int64 `json:"exports_bytes"`
+	TotalBytes     int64 `json:"total_bytes"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// BulkItemResult is the outcome of one item of a best-effort bulk request
+type BulkItemResult struct {
+	// Index is the item's position in the request
+	Index int `json:"index"`
+	// UUID names the user of the item, for requests that address existing users,
+	// or the deleted user whose username a create collided with
+	UUID string `json:"uuid,omitempty"`
+	// Status is the HTTP status the item would have got as a request of its own
+	Status int `json:"status"`
+	// User is the created user, in the caller's response form
+	User any `json:"user,omitempty"`
+	// Code is the error code the item would have got, for failed items
+	Code   string       `json:"code,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// BulkResult reports every item of a best-effort bulk request, in request order
+type BulkResult struct {
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
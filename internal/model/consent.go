@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+// Consent records that a user accepted a version of a document such as the terms
+// of service or the privacy policy
+type Consent struct {
+	ID         int64     `json:"id"`
+	UserUUID   string    `json:"user_uuid"`
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	IPAddress  string    `json:"ip_address"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// ConsentRequest records acceptance of the current version of a document
+type ConsentRequest struct {
+	Document string `json:"document" binding:"required"`
+	Version  string `json:"version" binding:"required"`
+}
+
+// PendingConsent is a user who has not accepted the current version of a document,
+// with the version they last accepted, if any
+type PendingConsent struct {
+	User
+	AcceptedVersion *string    `json:"accepted_version"`
+	AcceptedAt      *time.Time `json:"accepted_at,omitempty"`
+}
+
+// PendingConsentPage is one page of users yet to accept Version of Document, by ID
+type PendingConsentPage struct {
+	Document string           `json:"document"`
+	Version  string           `json:"version"`
+	Items    []PendingConsent `json:"items"`
+	Page     int              `json:"page"`
+	PerPage  int              `json:"per_page"`
+	Total    int              `json:"total"`
+}
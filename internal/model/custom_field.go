@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// Custom field types
+const (
+	CustomFieldString  = "string"
+	CustomFieldNumber  = "number"
+	CustomFieldBoolean = "boolean"
+	CustomFieldDate    = "date"
+)
+
+// CustomField is an admin-defined attribute stored in User.CustomFields
+type CustomField struct {
+	Name       string                `json:"name" binding:"required"`
+	Type       string                `json:"type" binding:"required"`
+	Required   bool                  `json:"required"`
+	Validation CustomFieldValidation `json:"validation"`
+	CreatedAt  time.Time             `json:"created_at"`
+}
+
+// CustomFieldValidation holds the optional constraints of a custom field
+type CustomFieldValidation struct {
+	// Pattern is a regular expression string values must match
+	Pattern string `json:"pattern,omitempty"`
+	// MaxLength limits string values in characters
+	MaxLength int `json:"max_length,omitempty"`
+	// Options restricts string values to a fixed set
+	Options []string `json:"options,omitempty"`
+	// Min and Max bound number values
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
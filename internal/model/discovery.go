@@ -0,0 +1,50 @@
+package model
+
+// Discovery is the /.well-known/cruder-configuration document client SDKs
+// configure themselves from. Paths include the deployment's base path and are
+// resolved against the URL the document was fetched from.
+type Discovery struct {
+	// Version is the server build, as reported by /version
+	Version     string                `json:"version"`
+	APIVersions []DiscoveryAPIVersion `json:"api_versions"`
+	// Endpoints maps well-known names (users, login, jwks, openapi, ...) to paths;
+	// names of disabled features are left out
+	Endpoints    map[string]string     `json:"endpoints"`
+	Auth         DiscoveryAuth         `json:"auth"`
+	ContentTypes DiscoveryContentTypes `json:"content_types"`
+	RateLimit    DiscoveryRateLimit    `json:"rate_limit"`
+}
+
+// DiscoveryAPIVersion is one version of the API and where it is mounted
+type DiscoveryAPIVersion struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+	// Status is "current" or "deprecated"
+	Status string `json:"status"`
+}
+
+// DiscoveryAuth lists the accepted credentials
+type DiscoveryAuth struct {
+	// Modes are api_key (X-API-Key header), jwt and personal_token (both
+	// Authorization: Bearer)
+	Modes []string `json:"modes"`
+	// APIKeyHeader is the header API keys are sent in, when api_key is accepted
+	APIKeyHeader string `json:"api_key_header,omitempty"`
+	// RequestSigning is set when some API keys sign their requests with HMAC
+	RequestSigning bool `json:"request_signing"`
+}
+
+// DiscoveryContentTypes lists the media types the API reads and writes
+type DiscoveryContentTypes struct {
+	Requests  []string `json:"requests"`
+	Responses []string `json:"responses"`
+	// Documents are the media types accepted for user documents
+	Documents []string `json:"documents"`
+}
+
+// DiscoveryRateLimit is the per-client request budget
+type DiscoveryRateLimit struct {
+	Enabled       bool `json:"enabled"`
+	Requests      int  `json:"requests,omitempty"`
+	WindowSeconds int  `json:"window_seconds,omitempty"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Document is the metadata of a file attached to a user; the content lives in the
+// storage backend under StorageKey
+type Document struct {
+	ID          string    `json:"id"`
+	UserUUID    string    `json:"user_uuid"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	SHA256      string    `json:"sha256"`
+	StorageKey  string    `json:"-"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
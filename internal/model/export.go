@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Export statuses
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+)
+
+// Export is a CSV file of all live users. It is generated in chunks by a background
+// job; LastUserID and Chunks are the checkpoint an interrupted run resumes from.
+type Export struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	LastUserID  int64      `json:"-"`
+	Chunks      int        `json:"chunks"`
+	RowsWritten int64      `json:"rows_written"`
+	SizeBytes   int64      `json:"size_bytes"`
+	SHA256      string     `json:"sha256,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// IdempotencyKey is a stored Idempotency-Key: the request it was first sent with,
+// and the response once there is one
+type IdempotencyKey struct {
+	// Fingerprint identifies the request, so the key cannot be reused for another
+	Fingerprint string
+	// Response is nil while the first request is still running
+	Response *IdempotentResponse
+}
+
+// IdempotentResponse is the response replayed to retries of a request
+type IdempotentResponse struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
@@ -0,0 +1,21 @@
+package model
+
+// ImportReport summarizes an import of users from a file
+type ImportReport struct {
+	// Rows counts the rows read, header and blank lines aside
+	Rows     int             `json:"rows"`
+	Created  int             `json:"created"`
+	Failed   int             `json:"failed"`
+	Failures []ImportFailure `json:"failures"`
+}
+
+// ImportFailure is a row that was not imported, reported like a create request
+// of its own would have been
+type ImportFailure struct {
+	// Line is where the row starts in the file, counting from 1
+	Line   int          `json:"line"`
+	Status int          `json:"status"`
+	Code   string       `json:"code"`
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Instance is one running server process, as last reported by its heartbeat
+type Instance struct {
+	ID       string `json:"id"`
+	Hostname string `json:"hostname"`
+	Version  string `json:"version"`
+	Commit   string `json:"commit"`
+	// Jobs lists the background jobs whose cluster-wide lock the instance holds
+	Jobs       []string  `json:"jobs"`
+	StartedAt  time.Time `json:"started_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Cluster describes the live instances sharing a database
+type Cluster struct {
+	// Self is the ID of the instance answering the request
+	Self      string     `json:"self"`
+	Instances []Instance `json:"instances"`
+	// Versions counts live instances per version, to follow a rollout
+	Versions map[string]int `json:"versions"`
+	// Leaders maps each exclusive job to the instance running it
+	Leaders map[string]string `json:"leaders"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Note is an internal, admin-only annotation on a user account
+type Note struct {
+	ID        int64      `json:"id"`
+	UserUUID  string     `json:"user_uuid"`
+	Author    string     `json:"author"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
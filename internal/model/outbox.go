@@ -0,0 +1,20 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxEvent is a change to a user, recorded in the transaction that made it and
+// waiting to be published to the message bus
+type OutboxEvent struct {
+	ID int64
+	// EventID names the change across retries, so consumers can drop repeats
+	EventID  string
+	Type     string
+	UserUUID string
+	Region   string
+	// User is the user as the API returns it after the change, nil for deletions
+	User json.RawMessage
+	At   time.Time
+}
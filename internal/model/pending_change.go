@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// Pending change kinds
+const (
+	// ChangeDeleteUser deletes the user
+	ChangeDeleteUser = "delete_user"
+	// ChangeUpdateUser applies an update that changes the user's email
+	ChangeUpdateUser = "update_user"
+)
+
+// Pending change statuses
+const (
+	ChangePending  = "pending"
+	ChangeApproved = "approved"
+	ChangeRejected = "rejected"
+)
+
+// PendingChange is a sensitive mutation held until a second admin approves it
+type PendingChange struct {
+	ID       int64  `json:"id"`
+	Kind     string `json:"kind"`
+	UserUUID string `json:"user_uuid"`
+	// Payload is the requested update for update_user changes
+	Payload     *UserPatch `json:"payload,omitempty"`
+	Status      string     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	ReviewedBy  string     `json:"reviewed_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
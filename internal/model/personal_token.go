@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// PersonalToken is an access token a user created to automate as themselves. The
+// token itself is only returned once, in Token, when it is created.
+type PersonalToken struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"-"`
+	// Username is the owner's current username, filled in when a token is verified
+	Username string `json:"-"`
+	// Region is where the token was found when it was verified
+	Region     string     `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Token      string     `json:"token,omitempty"`
+	Hash       string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	Tenant     string     `json:"tenant,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatePersonalTokenRequest names a new token. Scopes default to none and may
+// only include scopes the creator holds; ExpiresAt defaults to the configured TTL.
+type CreatePersonalTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
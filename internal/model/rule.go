@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Rule kinds
+const (
+	// RuleValidate rejects a user when its expression evaluates to false
+	RuleValidate = "validate"
+	// RuleTransform replaces Field with the expression's result before saving
+	RuleTransform = "transform"
+)
+
+// Rule is an admin-defined expression evaluated on user create and update
+type Rule struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name" binding:"required"`
+	Kind string `json:"kind" binding:"required"`
+	// Field is the user field reported by a validate rule, or set by a transform
+	// rule: username, email, full_name or custom_fields.<name>
+	Field      string     `json:"field"`
+	Expression string     `json:"expression" binding:"required"`
+	Message    string     `json:"message"`
+	Disabled   bool       `json:"disabled"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
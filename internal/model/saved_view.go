@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// SavedView is a named UserQuery shared between clients, e.g. a dashboard segment
+type SavedView struct {
+	Name        string    `json:"name" binding:"required"`
+	Description string    `json:"description"`
+	Query       UserQuery `json:"query"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
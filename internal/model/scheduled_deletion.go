@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// ScheduledDeletion is a pending deletion of a user at a future date
+type ScheduledDeletion struct {
+	UserUUID    string    `json:"user_uuid"`
+	EffectiveAt time.Time `json:"effective_at"`
+	RequestedBy string    `json:"requested_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
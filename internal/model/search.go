@@ -0,0 +1,12 @@
+package model
+
+// SearchResult is one match of the global search. Type says what Item is, e.g.
+// "user" for a User, so clients can render each kind of entity their own way.
+type SearchResult struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	// Score orders results across types: 1 for an exact match of a name, down to
+	// 0.25 for a match inside one
+	Score float64 `json:"score"`
+	Item  any     `json:"item"`
+}
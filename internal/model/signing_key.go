@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// SigningKey is an RS256 key pair that signs JWTs for a while and is published in
+// the JWKS. Its private half is stored encrypted with the master key.
+type SigningKey struct {
+	ID         string `json:"kid"`
+	PublicKey  string `json:"-"` // PEM
+	PrivateKey string `json:"-"` // PEM, encrypted as an "enc:" value
+	// Status is pending, active, retiring, expired or revoked, derived when listed
+	Status string `json:"status"`
+	// ActivatesAt is when the key starts signing; until then it is only published
+	ActivatesAt time.Time  `json:"activates_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
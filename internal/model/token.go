@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Token is an issued bearer token, in the shape of an OAuth 2.0 token response
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresIn   int       `json:"expires_in"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// UsageRecord is the request count of one API key on one route and day
+type UsageRecord struct {
+	Day             time.Time `json:"day"`
+	APIKey          string    `json:"api_key"`
+	Method          string    `json:"method"`
+	Route           string    `json:"route"`
+	Requests        int64     `json:"requests"`
+	Errors          int64     `json:"errors"`
+	TotalDurationMs int64     `json:"total_duration_ms"`
+}
+
+// AvgDurationMs is the mean request latency in milliseconds
+func (r UsageRecord) AvgDurationMs() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.TotalDurationMs) / float64(r.Requests)
+}
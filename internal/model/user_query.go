@@ -0,0 +1,100 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UserQuery selects and orders users for list endpoints and saved views
+type UserQuery struct {
+	// CustomFields filters on custom field values; every entry must match
+	CustomFields map[string]string `json:"custom_fields,omitempty"`
+	// EmailDomain keeps users whose email is at this domain, ignoring case
+	EmailDomain string `json:"email_domain,omitempty"`
+	// FullName keeps users whose full name contains it, ignoring case
+	FullName string `json:"full_name,omitempty"`
+	// CreatedFrom and CreatedTo bound created_at, from inclusive and to exclusive;
+	// either may be nil
+	CreatedFrom *time.Time `json:"created_from,omitempty"`
+	CreatedTo   *time.Time `json:"created_to,omitempty"`
+	// UpdatedFrom and UpdatedTo bound updated_at the same way
+	UpdatedFrom *time.Time `json:"updated_from,omitempty"`
+	UpdatedTo   *time.Time `json:"updated_to,omitempty"`
+	// Q keeps users whose username, email or full name contains it, ignoring case
+	Q string `json:"q,omitempty"`
+	// Sort is a comma-separated list of fields, each optionally prefixed with "-"
+	// for descending order, e.g. "-created_at,username"
+	Sort string `json:"sort,omitempty"`
+	// IncludeDeleted lists soft-deleted users too; it is admin-only and never saved
+	// with a view
+	IncludeDeleted bool `json:"-"`
+}
+
+// Plain reports whether the query neither filters nor sorts, so every live user
+// can be listed as stored
+func (q UserQuery) Plain() bool {
+	return len(q.CustomFields) == 0 && q.EmailDomain == "" && q.FullName == "" &&
+		q.CreatedFrom == nil && q.CreatedTo == nil && q.UpdatedFrom == nil && q.UpdatedTo == nil &&
+		q.Q == "" && q.Sort == "" && !q.IncludeDeleted
+}
+
+// UserSortFields are the fields users can be sorted by
+var UserSortFields = map[string]bool{
+	"id":         true,
+	"username":   true,
+	"email":      true,
+	"full_name":  true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// SortKey is one ordering term of a UserQuery
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// SortError reports a Sort that cannot be applied
+type SortError struct {
+	Field  string
+	Reason string
+}
+
+func (e *SortError) Error() string {
+	return fmt.Sprintf("sort field %q %s", e.Field, e.Reason)
+}
+
+// SortKeys parses Sort; fields outside UserSortFields and fields given twice are
+// rejected with a *SortError
+func (q UserQuery) SortKeys() ([]SortKey, error) {
+	if strings.TrimSpace(q.Sort) == "" {
+		return nil, nil
+	}
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, term := range strings.Split(q.Sort, ",") {
+		term = strings.TrimSpace(term)
+		key := SortKey{Field: strings.TrimPrefix(term, "-"), Desc: strings.HasPrefix(term, "-")}
+		if !UserSortFields[key.Field] {
+			return nil, &SortError{Field: key.Field, Reason: "is not sortable, expected one of " + sortableFields()}
+		}
+		if seen[key.Field] {
+			return nil, &SortError{Field: key.Field, Reason: "is given more than once"}
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortableFields lists UserSortFields in name order, for error messages
+func sortableFields() string {
+	names := make([]string, 0, len(UserSortFields))
+	for name := range UserSortFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,17 @@
+package model
+
+// UserSearchEntry is a row of the user_search read table: the searchable user
+// columns with their normalized forms precomputed
+type UserSearchEntry struct {
+	User
+	UsernameNorm string
+	EmailNorm    string
+	FullNameNorm string
+}
+
+// UserSuggestion is an autocomplete match: just enough of a user to show in a picker
+type UserSuggestion struct {
+	UUID     string `json:"uuid"`
+	Username string `json:"username"`
+	FullName string `json:"full_name"`
+}
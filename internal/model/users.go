@@ -1,9 +1,94 @@
 package model
 
+import "time"
+
 type User struct {
 	ID       int64  `json:"id"`
 	UUID     string `json:"uuid"`                           // Task3
 	Username string `json:"username" binding:"required"`    // Task4: validation added
 	Email    string `json:"email" binding:"required,email"` // Task4: validation added
 	FullName string `json:"full_name"`
+	// CustomFields holds values of admin-defined fields, keyed by field name
+	CustomFields map[string]any `json:"custom_fields"`
+	// Region is where the user's data is kept, set from the creating request's
+	// tenant when data residency is enabled; clients cannot change it
+	Region string `json:"region,omitempty"`
+	// CreatedAt is when the user was created. UpdatedAt is when it last changed,
+	// soft deletion and restoring included, and equals CreatedAt until then. Both
+	// are omitted where they are not known, as in search results.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	// Version counts the user's changes, starting at 1; updates name the version
+	// they were made against in If-Match so concurrent edits are not lost
+	Version int64 `json:"version,omitzero"`
+	// DeletedAt is set on soft-deleted users, which only admins can list
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// EmailNorm is the email as normalized for uniqueness and lookups, set by the
+	// user service before writing; it is stored but not read back or returned
+	EmailNorm string `json:"-"`
+}
+
+// DeletedUser is a soft-deleted user in the recycle bin
+type DeletedUser struct {
+	User
+	DeletedAt time.Time `json:"deleted_at"`
+	// PurgeAt is when the account is removed for good; DaysRemaining counts down to it
+	PurgeAt       time.Time `json:"purge_at"`
+	DaysRemaining int       `json:"days_remaining"`
+}
+
+// DeletedUserPage is one page of the recycle bin, newest deletions first
+type DeletedUserPage struct {
+	Items   []DeletedUser `json:"items"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"per_page"`
+	Total   int           `json:"total"`
+}
+
+// UserPatch is a partial update of a user: absent fields are left unchanged.
+// Custom fields are merged into the stored ones, and a null value removes a field.
+type UserPatch struct {
+	Username     *string        `json:"username,omitempty"`
+	Email        *string        `json:"email,omitempty"`
+	FullName     *string        `json:"full_name,omitempty"`
+	CustomFields map[string]any `json:"custom_fields,omitempty"`
+	// Version, if set, is the version of the user the patch was made against; the
+	// update is refused when the user has changed since. It is not a change itself.
+	Version *int64 `json:"version,omitempty"`
+	// EmailNorm goes with Email, like User.EmailNorm; clients cannot set it
+	EmailNorm *string `json:"-"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p UserPatch) IsEmpty() bool {
+	return p.Username == nil && p.Email == nil && p.FullName == nil && len(p.CustomFields) == 0
+}
+
+// Apply returns user with the patch applied; user is left untouched
+func (p UserPatch) Apply(user User) User {
+	if p.Username != nil {
+		user.Username = *p.Username
+	}
+	if p.Email != nil {
+		user.Email = *p.Email
+	}
+	if p.EmailNorm != nil {
+		user.EmailNorm = *p.EmailNorm
+	}
+	if p.FullName != nil {
+		user.FullName = *p.FullName
+	}
+	merged := make(map[string]any, len(user.CustomFields)+len(p.CustomFields))
+	for name, value := range user.CustomFields {
+		merged[name] = value
+	}
+	for name, value := range p.CustomFields {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = value
+	}
+	user.CustomFields = merged
+	return user
 }
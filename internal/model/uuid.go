@@ -0,0 +1,10 @@
+package model
+
+import "github.com/google/uuid"
+
+// IsUUID reports whether s is a UUID in the hyphenated form the API returns, the
+// only form user and resource IDs are accepted in
+func IsUUID(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil && len(s) == 36
+}
@@ -0,0 +1,26 @@
+package model
+
+// Validation error codes reported in FieldError.Code
+const (
+	ValidationRequired      = "required"
+	ValidationInvalidFormat = "invalid_format"
+	ValidationTaken         = "taken"
+	ValidationReserved      = "reserved"
+	// ValidationDeleted marks a username of a deleted user, who can be restored
+	ValidationDeleted          = "deleted"
+	ValidationDomainNotAllowed = "domain_not_allowed"
+	ValidationInvalid          = "invalid"
+)
+
+// FieldError is one failed check of a user field
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationResult is the outcome of validating a user without saving it
+type ValidationResult struct {
+	Valid  bool         `json:"valid"`
+	Errors []FieldError `json:"errors"`
+}
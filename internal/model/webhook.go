@@ -0,0 +1,60 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a URL user lifecycle events are posted to. The secret deliveries are
+// signed with is only returned once, in Secret, when the webhook is created.
+type Webhook struct {
+	ID          string   `json:"id"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description,omitempty"`
+	Secret      string   `json:"secret,omitempty"`
+	// Active is false for webhooks that are kept but sent nothing
+	Active    bool       `json:"active"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// WebhookRequest registers a webhook or replaces its settings. Active defaults to
+// true.
+type WebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or still to be sent, to one webhook
+type WebhookDelivery struct {
+	ID        int64           `json:"id"`
+	WebhookID string          `json:"webhook_id"`
+	EventID   string          `json:"event_id"`
+	EventType string          `json:"event_type"`
+	Payload   json.RawMessage `json:"-"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// NextAttemptAt is set while the delivery is pending
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// LastStatus is the HTTP status of the last attempt, 0 if it got no response
+	LastStatus  int        `json:"last_status,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	FailedAt    *time.Time `json:"failed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+
+	// URL and Secret are the webhook's, set on deliveries claimed for sending
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
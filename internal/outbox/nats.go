@@ -0,0 +1,277 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// natsPublisher publishes to a NATS JetStream stream. It speaks the client
+// protocol directly: each message is published with a reply inbox, and is
+// stored once the stream acknowledges it there. The Nats-Msg-Id header carries
+// the change's ID, so the stream drops a change published again within its
+// duplicate window.
+type natsPublisher struct {
+	url     *url.URL
+	subject string
+
+	mu    sync.Mutex
+	conn  net.Conn
+	r     *bufio.Reader
+	inbox string
+	seq   int
+}
+
+func newNATSPublisher(rawURL, subject string) (*natsPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("nats: invalid url: %w", err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("nats: url scheme must be nats or tls, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New("nats: url has no host")
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &natsPublisher{url: u, subject: subject}, nil
+}
+
+// natsAck is JetStream's answer to a publish
+type natsAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+// Publish publishes the messages one at a time, each once the previous one is
+// stored, so changes to a user keep their order
+func (p *natsPublisher) Publish(ctx context.Context, messages []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return fmt.Errorf("nats: connect: %w", err)
+		}
+	}
+	deadline, _ := ctx.Deadline()
+	if err := p.conn.SetDeadline(deadline); err != nil {
+		p.disconnect()
+		return fmt.Errorf("nats: %w", err)
+	}
+	for _, m := range messages {
+		if err := p.publish(m); err != nil {
+			// The connection may hold a late acknowledgement; start afresh
+			p.disconnect()
+			return fmt.Errorf("nats: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disconnect()
+	return nil
+}
+
+func (p *natsPublisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.r = nil, nil
+	}
+}
+
+// connect opens a connection, upgrading it to TLS when the url or the server asks
+// for it, and subscribes to the inbox acknowledgements arrive at
+func (p *natsPublisher) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", p.url.Host)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	r := bufio.NewReader(conn)
+
+	line, err := readLine(r)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	op, args, _ := strings.Cut(line, " ")
+	if op != "INFO" {
+		conn.Close()
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+		Headers     bool `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(args), &info); err != nil {
+		conn.Close()
+		return fmt.Errorf("decode server info: %w", err)
+	}
+	if !info.Headers {
+		conn.Close()
+		return errors.New("server does not support headers (NATS 2.2 or later is needed)")
+	}
+	if p.url.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.url.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	options := map[string]any{
+		"verbose": false, "pedantic": false, "headers": true, "no_responders": true,
+		"name": "cruder", "lang": "go", "protocol": 1,
+	}
+	if user := p.url.User; user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+	inbox := "_INBOX." + randomToken()
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", connect, inbox); err != nil {
+		conn.Close()
+		return err
+	}
+	// The server answers PING once CONNECT and SUB are processed, or rejects them
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		if line == "PONG" {
+			break
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return errors.New(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+	p.conn, p.r, p.inbox = conn, r, inbox
+	return nil
+}
+
+// publish sends one message and waits for the stream to acknowledge it
+func (p *natsPublisher) publish(m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	p.seq++
+	reply := p.inbox + "." + strconv.Itoa(p.seq)
+	header := "NATS/1.0\r\nNats-Msg-Id: " + m.ID + "\r\n\r\n"
+	frame := fmt.Sprintf("HPUB %s %s %d %d\r\n%s%s\r\n", p.subject, reply, len(header), len(header)+len(body), header, body)
+	if _, err := io.WriteString(p.conn, frame); err != nil {
+		return err
+	}
+
+	for {
+		subject, header, payload, err := p.next()
+		if err != nil {
+			return err
+		}
+		if subject != reply {
+			continue
+		}
+		// A status header without a body: 503 means no stream stores the subject
+		if status := strings.TrimSpace(strings.TrimPrefix(firstLine(header), "NATS/1.0")); status != "" && len(payload) == 0 {
+			if strings.HasPrefix(status, "503") {
+				return fmt.Errorf("no JetStream stream stores subject %q", p.subject)
+			}
+			return fmt.Errorf("publish failed: %s", status)
+		}
+		var ack natsAck
+		if err := json.Unmarshal(payload, &ack); err != nil {
+			return fmt.Errorf("decode acknowledgement: %w", err)
+		}
+		if ack.Error != nil {
+			return fmt.Errorf("publish failed: %s (%d)", ack.Error.Description, ack.Error.Code)
+		}
+		return nil
+	}
+}
+
+// next reads until a message arrives, answering the server's pings, and returns
+// its subject, header and payload
+func (p *natsPublisher) next() (string, string, []byte, error) {
+	for {
+		line, err := readLine(p.r)
+		if err != nil {
+			return "", "", nil, err
+		}
+		op, args, _ := strings.Cut(line, " ")
+		switch op {
+		case "PING":
+			if _, err := io.WriteString(p.conn, "PONG\r\n"); err != nil {
+				return "", "", nil, err
+			}
+		case "-ERR":
+			return "", "", nil, errors.New(strings.TrimSpace(args))
+		case "MSG", "HMSG":
+			// MSG <subject> <sid> [reply] <size>; HMSG adds <header size> before <size>
+			fields := strings.Fields(args)
+			headerSize, size := 0, 0
+			if op == "HMSG" && len(fields) >= 4 {
+				headerSize, err = strconv.Atoi(fields[len(fields)-2])
+			}
+			if err == nil && len(fields) >= 3 {
+				size, err = strconv.Atoi(fields[len(fields)-1])
+			}
+			if err != nil || len(fields) < 3 || headerSize > size {
+				return "", "", nil, fmt.Errorf("malformed %s %q", op, args)
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(p.r, data); err != nil {
+				return "", "", nil, err
+			}
+			return fields[0], string(data[:headerSize]), data[headerSize:size], nil
+		}
+		// +OK, PONG and INFO updates need no answer
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\r\n")
+	return line
+}
+
+func randomToken() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
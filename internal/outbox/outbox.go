@@ -0,0 +1,113 @@
+// Package outbox publishes user changes to a message bus. The users table's
+// triggers record every change in the user_outbox table in the transaction that
+// makes it; the relay publishes recorded changes in order and removes them once
+// the bus has stored them, so every committed change is published at least once.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/httpclient"
+	"cruder/internal/metrics"
+	"cruder/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	published = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "outbox_published_total",
+		Help: "User changes published to the message bus.",
+	})
+	publishFailures = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+		Name: "outbox_publish_failures_total",
+		Help: "Batches of user changes the message bus did not accept; they are published again.",
+	})
+)
+
+// Message is what is published for a change, keyed by the user's UUID. It has the
+// shape of the events webhooks receive.
+type Message struct {
+	// ID names the change; it is the same when a change is published again
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	UserUUID string          `json:"user_uuid"`
+	User     json.RawMessage `json:"user,omitempty"`
+	At       time.Time       `json:"at"`
+	Region   string          `json:"region,omitempty"`
+}
+
+// Publisher sends messages to the bus. Publish returns nil once the bus has stored
+// every message; after an error some may have been, and they are sent again.
+type Publisher interface {
+	Publish(ctx context.Context, messages []Message) error
+	Close() error
+}
+
+// NewPublisher creates the publisher of cfg.Driver for the bus at url. The Kafka
+// publisher posts through client, whose own retries should be off, as the relay
+// publishes a failed batch again.
+func NewPublisher(cfg config.MessageBusConfig, url string, client *httpclient.Client) (Publisher, error) {
+	switch cfg.Driver {
+	case "kafka":
+		return newKafkaPublisher(client, url, cfg.Topic), nil
+	case "nats":
+		return newNATSPublisher(url, cfg.Topic)
+	default:
+		return nil, fmt.Errorf("unknown message bus driver %q", cfg.Driver)
+	}
+}
+
+// Store is the outbox; repository.OutboxRepository implements it
+type Store interface {
+	Relay(ctx context.Context, limit int, publish func([]model.OutboxEvent) error) (int, error)
+}
+
+// Relay moves recorded changes from the outbox to the bus
+type Relay struct {
+	store     Store
+	publisher Publisher
+	batchSize int
+	timeout   time.Duration
+}
+
+// NewRelay creates a relay publishing batchSize changes at a time, each batch
+// within timeout
+func NewRelay(store Store, publisher Publisher, batchSize int, timeout time.Duration) *Relay {
+	return &Relay{store: store, publisher: publisher, batchSize: batchSize, timeout: timeout}
+}
+
+// Run publishes recorded changes until the outbox is empty or another relay is
+// working on it, and returns how many it published
+func (r *Relay) Run(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n, err := r.store.Relay(ctx, r.batchSize, func(events []model.OutboxEvent) error {
+			ctx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+			return r.publisher.Publish(ctx, messages(events))
+		})
+		if err != nil {
+			publishFailures.Inc()
+			return total, err
+		}
+		published.Add(float64(n))
+		total += n
+		if n < r.batchSize {
+			return total, nil
+		}
+	}
+}
+
+func messages(events []model.OutboxEvent) []Message {
+	messages := make([]Message, len(events))
+	for i, e := range events {
+		messages[i] = Message{ID: e.EventID, Type: e.Type, UserUUID: e.UserUUID, User: e.User, At: e.At.UTC(), Region: e.Region}
+	}
+	return messages
+}
@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/httpclient"
+	"cruder/internal/model"
+)
+
+// memoryStore is an outbox in memory; a change is removed once published
+type memoryStore struct {
+	events []model.OutboxEvent
+}
+
+func (s *memoryStore) Relay(_ context.Context, limit int, publish func([]model.OutboxEvent) error) (int, error) {
+	batch := s.events[:min(limit, len(s.events))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(batch); err != nil {
+		return 0, err
+	}
+	s.events = s.events[len(batch):]
+	return len(batch), nil
+}
+
+// recordingPublisher keeps what it publishes, failing when fail is set
+type recordingPublisher struct {
+	published []Message
+	fail      bool
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, messages []Message) error {
+	if p.fail {
+		return errors.New("bus unavailable")
+	}
+	p.published = append(p.published, messages...)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func outboxEvents(n int) []model.OutboxEvent {
+	events := make([]model.OutboxEvent, n)
+	for i := range events {
+		events[i] = model.OutboxEvent{
+			ID: int64(i + 1), EventID: fmt.Sprintf("event-%d", i+1), Type: "user.updated", UserUUID: "u-1",
+			User: json.RawMessage(`{"username":"jsmith"}`), At: time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC),
+		}
+	}
+	return events
+}
+
+func TestRelay_PublishesInBatchesUntilEmpty(t *testing.T) {
+	// Given: Five recorded changes and a bus that is down
+	store := &memoryStore{events: outboxEvents(5)}
+	publisher := &recordingPublisher{fail: true}
+	relay := NewRelay(store, publisher, 2, time.Second)
+
+	// When: The relay runs while the bus is down, then again once it is back
+	if n, err := relay.Run(context.Background()); err == nil || n != 0 {
+		t.Fatalf("Run = %d, %v; want an error", n, err)
+	}
+	publisher.fail = false
+	n, err := relay.Run(context.Background())
+
+	// Then: Nothing is lost, and every change is published in order
+	if err != nil || n != 5 {
+		t.Fatalf("Run = %d, %v; want 5", n, err)
+	}
+	if len(store.events) != 0 || len(publisher.published) != 5 {
+		t.Fatalf("%d left in the outbox, %d published", len(store.events), len(publisher.published))
+	}
+	for i, m := range publisher.published {
+		if m.ID != fmt.Sprintf("event-%d", i+1) || m.UserUUID != "u-1" || string(m.User) != `{"username":"jsmith"}` {
+			t.Errorf("message %d = %+v", i, m)
+		}
+	}
+}
+
+func TestKafkaPublisher_ProducesKeyedRecords(t *testing.T) {
+	// Given: A REST proxy that stores the first record and rejects the second
+	var got struct {
+		Records []struct {
+			Key   string  `json:"key"`
+			Value Message `json:"value"`
+		} `json:"records"`
+	}
+	var path, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+		json.NewDecoder(r.Body).Decode(&got)
+		io.WriteString(w, `{"offsets":[{"partition":0,"offset":7},{"partition":0,"offset":-1,"error_code":50003,"error":"timed out"}]}`)
+	}))
+	defer srv.Close()
+	client := httpclient.New("kafka-test", config.HTTPClientConfig{Timeout: time.Second, BreakerThreshold: 100, BreakerCooldown: time.Minute})
+	publisher := newKafkaPublisher(client, srv.URL+"/", "cruder.users")
+
+	// When: Publishing two changes
+	err := publisher.Publish(context.Background(), messages(outboxEvents(2)))
+
+	// Then: Both are posted to the topic keyed by user, and the rejection is an error
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected the rejected record to fail the batch, got %v", err)
+	}
+	if path != "/topics/cruder.users" || contentType != kafkaContentType {
+		t.Errorf("posted to %s as %s", path, contentType)
+	}
+	if len(got.Records) != 2 || got.Records[0].Key != "u-1" || got.Records[1].Value.ID != "event-2" {
+		t.Errorf("unexpected records %+v", got.Records)
+	}
+}
+
+// fakeJetStream accepts one connection and acknowledges each publish with the
+// stream sequence, recording the message IDs it was given
+func fakeJetStream(t *testing.T, ids chan<- string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		io.WriteString(conn, `INFO {"server_id":"test","headers":true}`+"\r\n")
+		seq := 0
+		for {
+			line, err := readLine(r)
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			switch fields[0] {
+			case "PING":
+				io.WriteString(conn, "PONG\r\n")
+			case "HPUB":
+				headerSize, _ := strconv.Atoi(fields[3])
+				size, _ := strconv.Atoi(fields[4])
+				data := make([]byte, size+2)
+				io.ReadFull(r, data)
+				header := string(data[:headerSize])
+				ids <- strings.TrimSpace(strings.TrimPrefix(strings.Split(header, "\r\n")[1], "Nats-Msg-Id:"))
+				seq++
+				ack := fmt.Sprintf(`{"stream":"USERS","seq":%d}`, seq)
+				// A ping in between is answered by the client before the ack
+				fmt.Fprintf(conn, "PING\r\nMSG %s 1 %d\r\n%s\r\n", fields[2], len(ack), ack)
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String()
+}
+
+func TestNATSPublisher_PublishesWithMessageIDs(t *testing.T) {
+	// Given: A JetStream server
+	ids := make(chan string, 10)
+	publisher, err := newNATSPublisher(fakeJetStream(t, ids), "cruder.users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// When: Publishing two batches over the same connection
+	if err := publisher.Publish(ctx, messages(outboxEvents(2))); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := publisher.Publish(ctx, messages(outboxEvents(3)[2:])); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	// Then: Each change is acknowledged in order, deduplicated by its ID
+	close(ids)
+	var got []string
+	for id := range ids {
+		got = append(got, id)
+	}
+	if strings.Join(got, ",") != "event-1,event-2,event-3" {
+		t.Errorf("published %v", got)
+	}
+}
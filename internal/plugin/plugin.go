@@ -0,0 +1,181 @@
+// Package plugin lets compiled-in extensions add routes, middleware, validation
+// hooks and event subscribers at bootstrap without changing core packages.
+//
+// An extension registers itself from an init function and is linked in with a
+// blank import in cmd/plugins.go; it only runs when listed in plugins.enabled:
+//
+//	func init() { plugin.Register(auditTrail{}) }
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"cruder/internal/config"
+	"cruder/internal/events"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Plugin is a compiled-in extension
+type Plugin interface {
+	// Name identifies the plugin in plugins.enabled and in its route prefix
+	Name() string
+	// Setup registers the plugin's extensions; an error stops the service from starting
+	Setup(r *Registrar) error
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]Plugin)
+)
+
+// Register makes a plugin available to Load. It panics when the name is empty or
+// already taken, since that is a programming error caught at startup.
+func Register(p Plugin) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	name := p.Name()
+	if name == "" {
+		panic("plugin: Register called with an empty name")
+	}
+	if _, dup := registry[name]; dup {
+		panic("plugin: Register called twice for " + name)
+	}
+	registry[name] = p
+}
+
+// Available returns the names of all registered plugins, sorted
+func Available() []string {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return availableLocked()
+}
+
+// Host holds what the enabled plugins registered. A nil Host has no extensions.
+type Host struct {
+	bus        *events.Bus
+	names      []string
+	middleware []gin.HandlerFunc
+	routes     map[string][]func(gin.IRouter)
+	hooks      []service.ValidationHook
+}
+
+// Load sets up the plugins listed in cfg.Enabled, in that order. Subscribers are
+// attached to bus.
+func Load(cfg config.PluginsConfig, bus *events.Bus) (*Host, error) {
+	if bus == nil {
+		return nil, errors.New("plugin: Load needs an event bus")
+	}
+	h := &Host{bus: bus, routes: make(map[string][]func(gin.IRouter))}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, name := range cfg.Enabled {
+		p, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("plugin %q is enabled but not compiled in (available: %v)", name, availableLocked())
+		}
+		r := &Registrar{name: name, settings: cfg.Settings[name], host: h}
+		if err := p.Setup(r); err != nil {
+			return nil, fmt.Errorf("plugin %q: %w", name, err)
+		}
+		h.names = append(h.names, name)
+	}
+	return h, nil
+}
+
+func availableLocked() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Names returns the loaded plugins in load order
+func (h *Host) Names() []string {
+	if h == nil {
+		return nil
+	}
+	return h.names
+}
+
+// Middleware returns plugin middleware for the authenticated API routes
+func (h *Host) Middleware() []gin.HandlerFunc {
+	if h == nil {
+		return nil
+	}
+	return h.middleware
+}
+
+// ValidationHooks returns the user validation hooks added by plugins
+func (h *Host) ValidationHooks() []service.ValidationHook {
+	if h == nil {
+		return nil
+	}
+	return h.hooks
+}
+
+// MountRoutes registers each plugin's routes below group under /<plugin name>
+func (h *Host) MountRoutes(group *gin.RouterGroup) {
+	if h == nil {
+		return
+	}
+	for _, name := range h.names {
+		fns := h.routes[name]
+		if len(fns) == 0 {
+			continue
+		}
+		sub := group.Group("/" + name)
+		for _, fn := range fns {
+			fn(sub)
+		}
+	}
+}
+
+// Registrar is handed to Plugin.Setup to register extensions
+type Registrar struct {
+	name     string
+	settings map[string]string
+	host     *Host
+}
+
+// Name returns the plugin's name
+func (r *Registrar) Name() string {
+	return r.name
+}
+
+// Settings returns plugins.settings.<name> from config.yaml; never nil
+func (r *Registrar) Settings() map[string]string {
+	if r.settings == nil {
+		return map[string]string{}
+	}
+	return r.settings
+}
+
+// Routes adds routes under /api/v1/plugins/<name>, behind API key authentication
+func (r *Registrar) Routes(fn func(router gin.IRouter)) {
+	r.host.routes[r.name] = append(r.host.routes[r.name], fn)
+}
+
+// Use adds middleware to every authenticated API route, after authentication.
+// It may inspect or change the request and wrap the response writer.
+func (r *Registrar) Use(middleware ...gin.HandlerFunc) {
+	r.host.middleware = append(r.host.middleware, middleware...)
+}
+
+// AddValidationHook adds a business rule to user create and update
+func (r *Registrar) AddValidationHook(hook service.ValidationHook) {
+	r.host.hooks = append(r.host.hooks, hook)
+}
+
+// Subscribe calls fn for each committed change of eventType (or events.AllEvents).
+// Handlers run asynchronously and must not assume any ordering between events.
+func (r *Registrar) Subscribe(eventType string, fn events.Handler) {
+	r.host.bus.Subscribe(eventType, fn)
+}
@@ -0,0 +1,115 @@
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"cruder/internal/config"
+	"cruder/internal/events"
+	"cruder/internal/model"
+	"cruder/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testPlugin struct {
+	name  string
+	setup func(r *Registrar) error
+}
+
+func (p testPlugin) Name() string             { return p.name }
+func (p testPlugin) Setup(r *Registrar) error { return p.setup(r) }
+
+func TestLoad_RegistersExtensions(t *testing.T) {
+	// Given: a plugin using every extension point
+	got := make(chan events.Event, 1)
+	Register(testPlugin{name: "test-everything", setup: func(r *Registrar) error {
+		greeting := r.Settings()["greeting"]
+		r.Routes(func(router gin.IRouter) {
+			router.GET("/hello", func(c *gin.Context) { c.String(http.StatusOK, greeting) })
+		})
+		r.Use(func(c *gin.Context) {
+			c.Header("X-Plugin", r.Name())
+			c.Next()
+		})
+		r.AddValidationHook(service.ValidationHookFunc(func(user, existing *model.User) ([]model.FieldError, error) {
+			return nil, nil
+		}))
+		r.Subscribe(events.UserCreated, func(e events.Event) { got <- e })
+		return nil
+	}})
+	bus := events.NewBus()
+
+	// When
+	host, err := Load(config.PluginsConfig{
+		Enabled:  []string{"test-everything"},
+		Settings: map[string]map[string]string{"test-everything": {"greeting": "hi"}},
+	}, bus)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(host.ValidationHooks()) != 1 {
+		t.Errorf("expected one validation hook, got %d", len(host.ValidationHooks()))
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	group := router.Group("/plugins", host.Middleware()...)
+	host.MountRoutes(group)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/plugins/test-everything/hello", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "hi" {
+		t.Errorf("expected plugin route to answer hi, got %d %q", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Plugin") != "test-everything" {
+		t.Errorf("expected plugin middleware to run, got headers %v", w.Header())
+	}
+
+	bus.Publish(events.Event{Type: events.UserCreated, UserUUID: "a"})
+	bus.Wait()
+	select {
+	case e := <-got:
+		if e.UserUUID != "a" {
+			t.Errorf("expected event for user a, got %+v", e)
+		}
+	default:
+		t.Error("expected subscriber to receive the event")
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	Register(testPlugin{name: "test-failing", setup: func(r *Registrar) error {
+		return errors.New("refused")
+	}})
+
+	tests := []struct {
+		name    string
+		enabled []string
+		wantErr string
+	}{
+		{"not compiled in", []string{"missing"}, `plugin "missing" is enabled but not compiled in`},
+		{"setup fails", []string{"test-failing"}, `plugin "test-failing": refused`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(config.PluginsConfig{Enabled: tt.enabled}, events.NewBus())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestNilHost(t *testing.T) {
+	var host *Host
+	if host.Names() != nil || host.Middleware() != nil || host.ValidationHooks() != nil {
+		t.Error("expected a nil host to have no extensions")
+	}
+	host.MountRoutes(gin.New().Group("/plugins"))
+}
@@ -0,0 +1,105 @@
+// Package policy asks an external policy engine, such as Open Policy Agent, whether
+// a request is allowed, so central security can manage rules outside this codebase.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"cruder/internal/httpclient"
+)
+
+// Input describes a request for the policy engine
+type Input struct {
+	Principal *Principal        `json:"principal"` // nil for anonymous requests
+	Method    string            `json:"method"`
+	Route     string            `json:"route"` // route template, e.g. /api/v1/users/:uuid
+	Path      string            `json:"path"`
+	Params    map[string]string `json:"params"`
+	ClientIP  string            `json:"client_ip"`
+	Resource  *Resource         `json:"resource,omitempty"`
+}
+
+// Principal is the authenticated caller
+type Principal struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type"`
+	Scopes []string `json:"scopes"`
+	Tenant string   `json:"tenant,omitempty"`
+}
+
+// Resource is the object a request acts on, when the route names one
+type Resource struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Owner string `json:"owner,omitempty"` // UUID of the user that owns the resource
+}
+
+// Decision is the policy engine's answer
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Decider evaluates the authorization policy for a request
+type Decider interface {
+	Decide(ctx context.Context, input Input) (Decision, error)
+}
+
+// OPA queries a decision through the OPA data API, e.g.
+// http://localhost:8181/v1/data/cruder/authz. The rule may return a boolean or an
+// object with "allow" and an optional "reason".
+type OPA struct {
+	client *httpclient.Client
+	url    string
+}
+
+// NewOPA creates a Decider backed by the OPA rule at url
+func NewOPA(client *httpclient.Client, url string) *OPA {
+	return &OPA{client: client, url: url}
+}
+
+// Decide implements Decider. An undefined result is a denial, as in OPA itself.
+func (o *OPA) Decide(ctx context.Context, input Input) (Decision, error) {
+	body, err := json.Marshal(map[string]Input{"input": input})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	resp, err := o.client.Post(ctx, o.url, "application/json", body)
+	if err != nil {
+		return Decision{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return Decision{}, fmt.Errorf("policy engine returned status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return Decision{}, fmt.Errorf("invalid policy engine response: %w", err)
+	}
+	return parseResult(out.Result)
+}
+
+func parseResult(raw json.RawMessage) (Decision, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return Decision{Reason: "policy result is undefined"}, nil
+	}
+	var allow bool
+	if err := json.Unmarshal(raw, &allow); err == nil {
+		return Decision{Allow: allow}, nil
+	}
+	var decision Decision
+	if err := json.Unmarshal(raw, &decision); err != nil {
+		return Decision{}, fmt.Errorf("policy result must be a boolean or {allow, reason}: %w", err)
+	}
+	return decision, nil
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/httpclient"
+)
+
+func newTestOPA(t *testing.T, status int, body string, got *Input) *OPA {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input Input `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if got != nil {
+			*got = req.Input
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+
+	client := httpclient.New("policy", config.HTTPClientConfig{Timeout: time.Second, BreakerThreshold: 10, BreakerCooldown: time.Minute})
+	return NewOPA(client, srv.URL+"/v1/data/cruder/authz")
+}
+
+func TestOPA_Decide(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		expected Decision
+		wantErr  bool
+	}{
+		{"boolean allow", http.StatusOK, `{"result": true}`, Decision{Allow: true}, false},
+		{"boolean deny", http.StatusOK, `{"result": false}`, Decision{}, false},
+		{"object with reason", http.StatusOK, `{"result": {"allow": false, "reason": "tenant mismatch"}}`, Decision{Reason: "tenant mismatch"}, false},
+		{"undefined result denies", http.StatusOK, `{}`, Decision{Reason: "policy result is undefined"}, false},
+		{"unexpected result type", http.StatusOK, `{"result": "yes"}`, Decision{}, true},
+		{"server error", http.StatusInternalServerError, `{}`, Decision{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: An OPA server returning the response under test
+			opa := newTestOPA(t, tt.status, tt.body, nil)
+
+			// When: A decision is requested
+			decision, err := opa.Decide(context.Background(), Input{Method: http.MethodGet})
+
+			// Then: The result is interpreted accordingly
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if decision != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, decision)
+			}
+		})
+	}
+}
+
+func TestOPA_SendsInput(t *testing.T) {
+	// Given: An OPA server that records the input
+	var got Input
+	opa := newTestOPA(t, http.StatusOK, `{"result": true}`, &got)
+	input := Input{
+		Principal: &Principal{Name: "partner", Type: "api_key", Scopes: []string{"users:read"}, Tenant: "acme"},
+		Method:    http.MethodGet,
+		Route:     "/api/v1/users/:uuid",
+		Resource:  &Resource{Type: "user", ID: "u-1", Owner: "u-1"},
+	}
+
+	// When: A decision is requested
+	if _, err := opa.Decide(context.Background(), input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: The request attributes are wrapped in "input"
+	if got.Principal == nil || got.Principal.Tenant != "acme" {
+		t.Errorf("expected principal with tenant acme, got %+v", got.Principal)
+	}
+	if got.Route != input.Route || got.Resource == nil || got.Resource.Owner != "u-1" {
+		t.Errorf("expected route and resource to be sent, got %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+// Package ratelimit counts requests per client in fixed windows. MemoryStore keeps
+// the counters in the process, so each replica enforces the limit on its own;
+// RedisStore shares them, so the limit holds across all replicas.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store counts requests
+type Store interface {
+	// Hit counts one request for key in its current window and returns the number
+	// of requests in the window so far and the time until the window ends
+	Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error)
+}
+
+type memoryWindow struct {
+	count int64
+	ends  time.Time
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu        sync.Mutex
+	windows   map[string]*memoryWindow
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: make(map[string]*memoryWindow), now: time.Now}
+}
+
+// Hit implements Store; a window starts with the first request of a key
+func (s *MemoryStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	// Expired windows are dropped at most once per window length
+	if now.Sub(s.lastSweep) >= window {
+		for k, w := range s.windows {
+			if !now.Before(w.ends) {
+				delete(s.windows, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	w := s.windows[key]
+	if w == nil || !now.Before(w.ends) {
+		w = &memoryWindow{ends: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+	return w.count, w.ends.Sub(now), nil
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	// Given: A store whose clock is controlled by the test
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	hit := func(key string) (int64, time.Duration) {
+		count, reset, err := store.Hit(context.Background(), key, time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return count, reset
+	}
+
+	// When: Counting requests within one window
+	hit("a")
+	now = now.Add(20 * time.Second)
+	count, reset := hit("a")
+
+	// Then: They add up and the window ends a minute after the first
+	if count != 2 || reset != 40*time.Second {
+		t.Errorf("expected count 2 resetting in 40s, got %d in %v", count, reset)
+	}
+	if count, _ := hit("b"); count != 1 {
+		t.Errorf("expected keys to be counted apart, got %d", count)
+	}
+
+	// When: The window has ended
+	now = now.Add(time.Minute)
+	count, _ = hit("a")
+
+	// Then: Counting starts over and expired windows are dropped
+	if count != 1 {
+		t.Errorf("expected a new window, got count %d", count)
+	}
+	if _, ok := store.windows["b"]; ok {
+		t.Error("expected the expired window of b to be dropped")
+	}
+}
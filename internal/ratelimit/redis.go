@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces the counters in a shared Redis
+const redisKeyPrefix = "cruder:ratelimit:"
+
+// hitScript increments a counter and starts its window on the first hit, in one
+// round trip. A counter left without expiry, e.g. by an operator, gets one too.
+var hitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}
+`)
+
+// RedisStore is a Store shared by every instance connected to the same Redis
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to the Redis at url, e.g. redis://:password@redis:6379/0,
+// and checks that it answers
+func NewRedisStore(ctx context.Context, url string) (*RedisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// Hit implements Store
+func (s *RedisStore) Hit(ctx context.Context, key string, window time.Duration) (int64, time.Duration, error) {
+	result, err := hitScript.Run(ctx, s.client, []string{redisKeyPrefix + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return result[0], time.Duration(result[1]) * time.Millisecond, nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
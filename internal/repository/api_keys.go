@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey) error
+	// List returns every key, revoked and expired ones included, newest first
+	List(ctx context.Context) ([]model.APIKey, error)
+	// Get returns a key; sql.ErrNoRows when there is none
+	Get(ctx context.Context, id string) (*model.APIKey, error)
+	// FindByHash returns the unrevoked, unexpired key with the hash; sql.ErrNoRows
+	// when there is none
+	FindByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	// Rotate creates next as the replacement of the unrevoked key id, with its name,
+	// scopes, tenant and expiry, and makes the old key expire at graceUntil unless it
+	// expires sooner; sql.ErrNoRows when there is no such key
+	Rotate(ctx context.Context, id string, next *model.APIKey, graceUntil time.Time) error
+	// Revoke marks a key revoked; sql.ErrNoRows when there is none
+	Revoke(ctx context.Context, id string) error
+	// Touch records that a key was used
+	Touch(ctx context.Context, id string) error
+}
+
+type apiKeyRepository struct {
+	db DB
+}
+
+func NewAPIKeyRepository(db DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, tenant, expires_at, revoked, rotated_from, created_by, last_used_at, created_at`
+
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (*model.APIKey, error) {
+	var k model.APIKey
+	var rotatedFrom sql.NullString
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Hash, pq.Array(&k.Scopes), &k.Tenant, &k.ExpiresAt,
+		&k.Revoked, &rotatedFrom, &k.CreatedBy, &k.LastUsedAt, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	k.RotatedFrom = rotatedFrom.String
+	return &k, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, k *model.APIKey) error {
+	return insertAPIKey(ctx, r.db, k)
+}
+
+func insertAPIKey(ctx context.Context, db interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}, k *model.APIKey) error {
+	scopes := k.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return db.QueryRowContext(ctx,
+		`INSERT INTO api_keys (id, name, prefix, key_hash, scopes, tenant, expires_at, rotated_from, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, '')::uuid, $9) RETURNING created_at`,
+		k.ID, k.Name, k.Prefix, k.Hash, pq.Array(scopes), k.Tenant, k.ExpiresAt, k.RotatedFrom, k.CreatedBy).
+		Scan(&k.CreatedAt)
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Get(ctx context.Context, id string) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1`, id))
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	return scanAPIKey(r.db.QueryRowContext(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE key_hash = $1 AND NOT revoked AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`, hash))
+}
+
+func (r *apiKeyRepository) Rotate(ctx context.Context, id string, next *model.APIKey, graceUntil time.Time) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		old, err := scanAPIKey(tx.QueryRowContext(ctx,
+			`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1 AND NOT revoked FOR UPDATE`, id))
+		if err != nil {
+			return err
+		}
+		next.Name, next.Scopes, next.Tenant, next.ExpiresAt, next.RotatedFrom = old.Name, old.Scopes, old.Tenant, old.ExpiresAt, old.ID
+		if err := insertAPIKey(ctx, tx, next); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2) WHERE id = $1`, id, graceUntil)
+		return err
+	})
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked = TRUE WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *apiKeyRepository) Touch(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/migrations"
+	"cruder/internal/model"
+)
+
+// TestAPIKeyRepository_Rotate needs a database; set TEST_DATABASE_URL to run it
+func TestAPIKeyRepository_Rotate(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	if err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	const oldID, nextID = "7f0c9a52-3c1e-4d8b-9f3a-1b2c3d4e5f60", "7f0c9a52-3c1e-4d8b-9f3a-1b2c3d4e5f61"
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM api_keys WHERE id IN ($1, $2)`, oldID, nextID) })
+	repo := NewAPIKeyRepository(db)
+	oldHash, nextHash := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	// Given: A key without expiry
+	old := &model.APIKey{ID: oldID, Name: "ci", Prefix: "cak_old", Hash: oldHash, Scopes: []string{"debug"}, Tenant: "acme"}
+	if err := repo.Create(ctx, old); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// When: It is rotated with an hour of grace
+	next := &model.APIKey{ID: nextID, Prefix: "cak_new", Hash: nextHash, CreatedBy: "admin"}
+	graceUntil := time.Now().UTC().Add(time.Hour).Truncate(time.Microsecond)
+	if err := repo.Rotate(ctx, oldID, next, graceUntil); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+
+	// Then: The replacement inherits the key's settings and the old key expires
+	found, err := repo.FindByHash(ctx, nextHash)
+	if err != nil {
+		t.Fatalf("FindByHash: %v", err)
+	}
+	if found.Name != "ci" || found.Tenant != "acme" || len(found.Scopes) != 1 || found.RotatedFrom != oldID || found.ExpiresAt != nil {
+		t.Errorf("unexpected replacement %+v", found)
+	}
+	stored, err := repo.Get(ctx, oldID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.ExpiresAt == nil || !stored.ExpiresAt.Equal(graceUntil) {
+		t.Errorf("expected the old key to expire at %v, got %v", graceUntil, stored.ExpiresAt)
+	}
+
+	// And a revoked key cannot be rotated or found
+	if err := repo.Revoke(ctx, oldID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := repo.Rotate(ctx, oldID, &model.APIKey{ID: nextID}, graceUntil); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected no rows rotating a revoked key, got %v", err)
+	}
+	if _, err := repo.FindByHash(ctx, oldHash); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected a revoked key not to be found, got %v", err)
+	}
+}
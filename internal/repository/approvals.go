@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+)
+
+// ErrChangeReviewed is returned when a pending change was approved or rejected already
+var ErrChangeReviewed = errors.New("change already reviewed")
+
+type ApprovalRepository interface {
+	// List returns changes newest first; an empty status returns all of them
+	List(ctx context.Context, status string) ([]model.PendingChange, error)
+	Get(ctx context.Context, id int64) (*model.PendingChange, error)
+	Create(ctx context.Context, change *model.PendingChange) error
+	// Approve marks the change approved and applies it in one transaction. patch
+	// is the validated update for update_user changes. It returns ErrChangeReviewed
+	// when the change is no longer pending, and sql.ErrNoRows when the user is gone.
+	Approve(ctx context.Context, change *model.PendingChange, reviewer string, patch *model.UserPatch) error
+	// Reject marks the change rejected; ErrChangeReviewed when it is no longer pending
+	Reject(ctx context.Context, change *model.PendingChange, reviewer string) error
+}
+
+type approvalRepository struct {
+	db DB
+}
+
+func NewApprovalRepository(db DB) ApprovalRepository {
+	return &approvalRepository{db: db}
+}
+
+const pendingChangeColumns = `id, kind, user_uuid, payload, status, requested_by, COALESCE(reviewed_by, ''), created_at, reviewed_at`
+
+func scanPendingChange(row interface{ Scan(...any) error }, c *model.PendingChange) error {
+	var payload []byte
+	if err := row.Scan(&c.ID, &c.Kind, &c.UserUUID, &payload, &c.Status, &c.RequestedBy, &c.ReviewedBy,
+		&c.CreatedAt, &c.ReviewedAt); err != nil {
+		return err
+	}
+	if payload == nil {
+		return nil
+	}
+	return json.Unmarshal(payload, &c.Payload)
+}
+
+func (r *approvalRepository) List(ctx context.Context, status string) ([]model.PendingChange, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+pendingChangeColumns+` FROM pending_changes WHERE $1 = '' OR status = $1 ORDER BY id DESC`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var changes []model.PendingChange
+	for rows.Next() {
+		var c model.PendingChange
+		if err := scanPendingChange(rows, &c); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+func (r *approvalRepository) Get(ctx context.Context, id int64) (*model.PendingChange, error) {
+	var c model.PendingChange
+	row := r.db.QueryRowContext(ctx, `SELECT `+pendingChangeColumns+` FROM pending_changes WHERE id = $1`, id)
+	if err := scanPendingChange(row, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *approvalRepository) Create(ctx context.Context, change *model.PendingChange) error {
+	var payload []byte
+	if change.Payload != nil {
+		var err error
+		if payload, err = json.Marshal(change.Payload); err != nil {
+			return err
+		}
+	}
+	change.Status = model.ChangePending
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO pending_changes (kind, user_uuid, payload, requested_by) VALUES ($1, $2, $3, $4) RETURNING id, created_at`,
+		change.Kind, change.UserUUID, payload, change.RequestedBy).
+		Scan(&change.ID, &change.CreatedAt)
+}
+
+func (r *approvalRepository) Approve(ctx context.Context, change *model.PendingChange, reviewer string, patch *model.UserPatch) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := review(ctx, tx, change, model.ChangeApproved, reviewer); err != nil {
+			return err
+		}
+
+		switch change.Kind {
+		case model.ChangeDeleteUser:
+			var rows int
+			if err := tx.QueryRowContext(ctx, softDeleteUser, change.UserUUID).Scan(&rows); err != nil {
+				return err
+			}
+			if rows == 0 {
+				return sql.ErrNoRows
+			}
+		case model.ChangeUpdateUser:
+			query, args, err := updateUser(change.UserUUID, *patch)
+			if err != nil {
+				return err
+			}
+			if query == "" {
+				return nil
+			}
+			result, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				return sql.ErrNoRows
+			}
+		}
+		return nil
+	})
+}
+
+func (r *approvalRepository) Reject(ctx context.Context, change *model.PendingChange, reviewer string) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		return review(ctx, tx, change, model.ChangeRejected, reviewer)
+	})
+}
+
+// review moves a pending change to status; the status check makes concurrent
+// reviews of the same change fail with ErrChangeReviewed
+func review(ctx context.Context, tx *sql.Tx, change *model.PendingChange, status, reviewer string) error {
+	err := tx.QueryRowContext(ctx,
+		`UPDATE pending_changes SET status = $1, reviewed_by = $2, reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status = 'pending' RETURNING reviewed_at`,
+		status, reviewer, change.ID).
+		Scan(&change.ReviewedAt)
+	if err == sql.ErrNoRows {
+		return ErrChangeReviewed
+	}
+	if err != nil {
+		return err
+	}
+	change.Status, change.ReviewedBy = status, reviewer
+	return nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"time"
+)
+
+// BillingRepository sums what a deployment used for billing reports. Users and
+// their files are counted in every region; API usage is kept in the home database.
+type BillingRepository interface {
+	// ActiveUsers counts the users that existed at any time in [from, to): created
+	// before to and not deleted before from. Purged users are no longer counted.
+	ActiveUsers(ctx context.Context, from, to time.Time) (int, error)
+	// APICalls sums the requests of each API key on the days in [from, to), busiest first
+	APICalls(ctx context.Context, from, to time.Time) ([]model.BillingAPICalls, error)
+	// Storage sums the sizes of the stored documents and exports
+	Storage(ctx context.Context) (model.BillingStorage, error)
+}
+
+type billingRepository struct {
+	db tracedDB
+}
+
+func NewBillingRepository(db DB) BillingRepository {
+	return &billingRepository{db: tracedDB{db}}
+}
+
+func (r *billingRepository) ActiveUsers(ctx context.Context, from, to time.Time) (int, error) {
+	var total int
+	err := r.regions().Each(ctx, func(ctx context.Context) error {
+		var n int
+		if err := r.db.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM users WHERE created_at < $2 AND (deleted_at IS NULL OR deleted_at >= $1)`,
+			from, to).Scan(&n); err != nil {
+			return err
+		}
+		total += n
+		return nil
+	})
+	return total, err
+}
+
+func (r *billingRepository) APICalls(ctx context.Context, from, to time.Time) ([]model.BillingAPICalls, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT api_key, SUM(requests), SUM(errors) FROM api_usage
+		WHERE day >= $1 AND day < $2
+		GROUP BY api_key ORDER BY SUM(requests) DESC, api_key`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	calls := []model.BillingAPICalls{}
+	for rows.Next() {
+		var c model.BillingAPICalls
+		if err := rows.Scan(&c.APIKey, &c.Requests, &c.Errors); err != nil {
+			return nil, err
+		}
+		calls = append(calls, c)
+	}
+	return calls, rows.Err()
+}
+
+func (r *billingRepository) Storage(ctx context.Context) (model.BillingStorage, error) {
+	var usage model.BillingStorage
+	err := r.regions().Each(ctx, func(ctx context.Context) error {
+		var documents, exports int64
+		if err := r.db.QueryRowContext(ctx,
+			`SELECT (SELECT COALESCE(SUM(size_bytes), 0) FROM user_documents),
+				(SELECT COALESCE(SUM(size_bytes), 0) FROM user_exports)`).Scan(&documents, &exports); err != nil {
+			return err
+		}
+		usage.DocumentsBytes += documents
+		usage.ExportsBytes += exports
+		return nil
+	})
+	usage.TotalBytes = usage.DocumentsBytes + usage.ExportsBytes
+	return usage, err
+}
+
+// regions returns the regions to sum over; nil sums the one database once
+func (r *billingRepository) regions() *Regions {
+	regions, _ := r.db.DB.(*Regions)
+	return regions
+}
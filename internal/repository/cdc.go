@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// CheckCDC reports settings that keep change data capture pipelines such as Debezium
+// from consuming complete user change events. publication may be empty when the
+// connector creates its own.
+func CheckCDC(ctx context.Context, db *sql.DB, publication string) ([]string, error) {
+	var problems []string
+
+	var walLevel string
+	if err := db.QueryRowContext(ctx, `SHOW wal_level`).Scan(&walLevel); err != nil {
+		return nil, err
+	}
+	if walLevel != "logical" {
+		problems = append(problems, "wal_level is "+walLevel+", logical replication needs wal_level=logical")
+	}
+
+	// 'f' is REPLICA IDENTITY FULL; anything else omits old values of unchanged columns
+	var identity string
+	if err := db.QueryRowContext(ctx,
+		`SELECT relreplident::text FROM pg_class WHERE oid = 'users'::regclass`).Scan(&identity); err != nil {
+		return nil, err
+	}
+	if identity != "f" {
+		problems = append(problems, "users does not have REPLICA IDENTITY FULL; run the migrations with CDC_REPLICA_IDENTITY=FULL")
+	}
+
+	var hasUpdatedAt bool
+	if err := db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'updated_at')`).
+		Scan(&hasUpdatedAt); err != nil {
+		return nil, err
+	}
+	if !hasUpdatedAt {
+		problems = append(problems, "users.updated_at is missing; run the migrations")
+	}
+
+	if publication != "" {
+		var published bool
+		if err := db.QueryRowContext(ctx,
+			`SELECT EXISTS (SELECT 1 FROM pg_publication_tables WHERE pubname = $1 AND tablename = 'users')`, publication).
+			Scan(&published); err != nil {
+			return nil, err
+		}
+		if !published {
+			problems = append(problems, "publication "+publication+" does not include the users table")
+		}
+	}
+	return problems, nil
+}
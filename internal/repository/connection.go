@@ -1,8 +1,11 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -19,13 +22,27 @@ func (p *PostgresConnection) DB() *sql.DB {
 	return p.db
 }
 
-func NewPostgresConnection(dsn string) (*PostgresConnection, error) {
+// ConnectRetry makes NewPostgresConnection wait for a database that is still
+// starting, e.g. one started next to the service by docker-compose
+type ConnectRetry struct {
+	// Attempts is how many times to connect; below 2 connects once
+	Attempts int
+	// Interval is the pause after the first failed attempt, doubled after each
+	// further one
+	Interval time.Duration
+	// MaxWait caps the time spent on all attempts; zero does not
+	MaxWait time.Duration
+}
+
+// NewPostgresConnection opens dsn and connects, retrying as retry allows
+func NewPostgresConnection(dsn string, retry ConnectRetry) (*PostgresConnection, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(db.PingContext, retry, time.Now, time.Sleep); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
@@ -33,3 +50,48 @@ func NewPostgresConnection(dsn string) (*PostgresConnection, error) {
 		db: db,
 	}, nil
 }
+
+// pingWithRetry calls ping until it succeeds or retry gives up, logging every
+// failed attempt
+func pingWithRetry(ping func(context.Context) error, retry ConnectRetry, now func() time.Time, sleep func(time.Duration)) error {
+	ctx := context.Background()
+	var deadline time.Time
+	if retry.MaxWait > 0 {
+		deadline = now().Add(retry.MaxWait)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	pause := retry.Interval
+	for attempt := 1; ; attempt++ {
+		err := ping(ctx)
+		if err == nil {
+			if attempt > 1 {
+				slog.Info("connected to database", "attempt", attempt)
+			}
+			return nil
+		}
+		if attempt >= retry.Attempts {
+			return err
+		}
+		if !deadline.IsZero() {
+			left := deadline.Sub(now())
+			if left <= 0 {
+				return err
+			}
+			pause = min(pause, left)
+		}
+		slog.Warn("database not reachable, retrying", "attempt", attempt, "attempts", retry.Attempts, "retry_in", pause.String(), "error", err)
+		sleep(pause)
+		pause *= 2
+	}
+}
+
+// closeRows closes rows once they are read, logging a failure with the request's
+// context
+func closeRows(ctx context.Context, rows *sql.Rows) {
+	if err := rows.Close(); err != nil {
+		slog.ErrorContext(ctx, "failed to close rows", "error", err)
+	}
+}
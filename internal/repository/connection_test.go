@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPingWithRetry(t *testing.T) {
+	refused := errors.New("connection refused")
+
+	tests := []struct {
+		name       string
+		retry      ConnectRetry
+		failures   int // attempts that fail before the database is up
+		wantErr    bool
+		wantPauses []time.Duration
+	}{
+		{"up at once", ConnectRetry{Attempts: 3, Interval: time.Second}, 0, false, nil},
+		{"up on the third attempt", ConnectRetry{Attempts: 5, Interval: time.Second}, 2, false, []time.Duration{time.Second, 2 * time.Second}},
+		{"gives up after the last attempt", ConnectRetry{Attempts: 3, Interval: time.Second}, 10, true, []time.Duration{time.Second, 2 * time.Second}},
+		{"one attempt does not retry", ConnectRetry{Attempts: 1, Interval: time.Second}, 10, true, nil},
+		{"max wait cuts the backoff short", ConnectRetry{Attempts: 10, Interval: time.Second, MaxWait: 4 * time.Second}, 10, true, []time.Duration{time.Second, 2 * time.Second, time.Second}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: A clock that only moves while sleeping
+			clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			var pauses []time.Duration
+			attempts := 0
+			ping := func(context.Context) error {
+				attempts++
+				if attempts <= tt.failures {
+					return refused
+				}
+				return nil
+			}
+
+			// When: Connecting
+			err := pingWithRetry(ping, tt.retry, func() time.Time { return clock }, func(d time.Duration) {
+				pauses = append(pauses, d)
+				clock = clock.Add(d)
+			})
+
+			// Then: It waited between attempts, doubling the pause
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && !errors.Is(err, refused) {
+				t.Errorf("expected the last ping error, got %v", err)
+			}
+			if len(pauses) != len(tt.wantPauses) {
+				t.Fatalf("expected pauses %v, got %v", tt.wantPauses, pauses)
+			}
+			for i := range pauses {
+				if pauses[i] != tt.wantPauses[i] {
+					t.Errorf("expected pauses %v, got %v", tt.wantPauses, pauses)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+)
+
+type ConsentRepository interface {
+	// Record stores an acceptance and reports whether it is new; accepting a version
+	// again leaves the first record, which is returned, unchanged
+	Record(ctx context.Context, consent *model.Consent) (bool, error)
+	// List returns the acceptances of a user, newest first
+	List(ctx context.Context, userUUID string) ([]model.Consent, error)
+	// Pending returns live users who have not accepted version of document, by ID,
+	// and their total count
+	Pending(ctx context.Context, document, version string, limit, offset int) ([]model.PendingConsent, int, error)
+}
+
+type consentRepository struct {
+	db DB
+}
+
+func NewConsentRepository(db DB) ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+func (r *consentRepository) Record(ctx context.Context, c *model.Consent) (bool, error) {
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO user_consents (user_uuid, document, version, ip_address) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_uuid, document, version) DO NOTHING
+		RETURNING id, accepted_at`,
+		c.UserUUID, c.Document, c.Version, c.IPAddress).
+		Scan(&c.ID, &c.AcceptedAt)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+	return false, r.db.QueryRowContext(ctx,
+		`SELECT id, ip_address, accepted_at FROM user_consents WHERE user_uuid = $1 AND document = $2 AND version = $3`,
+		c.UserUUID, c.Document, c.Version).
+		Scan(&c.ID, &c.IPAddress, &c.AcceptedAt)
+}
+
+func (r *consentRepository) List(ctx context.Context, userUUID string) ([]model.Consent, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_uuid, document, version, ip_address, accepted_at FROM user_consents
+		WHERE user_uuid = $1 ORDER BY accepted_at DESC, id DESC`, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var consents []model.Consent
+	for rows.Next() {
+		var c model.Consent
+		if err := rows.Scan(&c.ID, &c.UserUUID, &c.Document, &c.Version, &c.IPAddress, &c.AcceptedAt); err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return consents, nil
+}
+
+// notAccepted restricts a query on users to those without an acceptance of $2 of document $1
+const notAccepted = `NOT EXISTS (SELECT 1 FROM user_consents c WHERE c.user_uuid = users.uuid AND c.document = $1 AND c.version = $2)`
+
+func (r *consentRepository) Pending(ctx context.Context, document, version string, limit, offset int) ([]model.PendingConsent, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE `+liveUsers+` AND `+notAccepted, document, version).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+`, latest.version, latest.accepted_at FROM users
+		LEFT JOIN LATERAL (
+			SELECT c.version, c.accepted_at FROM user_consents c
+			WHERE c.user_uuid = users.uuid AND c.document = $1 ORDER BY c.accepted_at DESC, c.id DESC LIMIT 1
+		) latest ON true
+		WHERE `+liveUsers+` AND `+notAccepted+` ORDER BY id LIMIT $3 OFFSET $4`,
+		document, version, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer closeRows(ctx, rows)
+
+	var users []model.PendingConsent
+	for rows.Next() {
+		var u model.PendingConsent
+		if err := scanUser(rows, &u.User, &u.AcceptedVersion, &u.AcceptedAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
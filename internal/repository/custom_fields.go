@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+)
+
+type CustomFieldRepository interface {
+	List(ctx context.Context) ([]model.CustomField, error)
+	Create(ctx context.Context, field *model.CustomField) error
+	// Update replaces the type and constraints of a field; sql.ErrNoRows when it does not exist
+	Update(ctx context.Context, field *model.CustomField) error
+	// Delete removes the definition and the field's value from every user
+	Delete(ctx context.Context, name string) error
+}
+
+type customFieldRepository struct {
+	db *sql.DB
+}
+
+func NewCustomFieldRepository(db *sql.DB) CustomFieldRepository {
+	return &customFieldRepository{db: db}
+}
+
+func (r *customFieldRepository) List(ctx context.Context) ([]model.CustomField, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT name, type, required, validation, created_at FROM custom_fields ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var fields []model.CustomField
+	for rows.Next() {
+		var f model.CustomField
+		var validation []byte
+		if err := rows.Scan(&f.Name, &f.Type, &f.Required, &validation, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(validation, &f.Validation); err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func (r *customFieldRepository) Create(ctx context.Context, field *model.CustomField) error {
+	validation, err := json.Marshal(field.Validation)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO custom_fields (name, type, required, validation) VALUES ($1, $2, $3, $4) RETURNING created_at`,
+		field.Name, field.Type, field.Required, validation).
+		Scan(&field.CreatedAt)
+}
+
+func (r *customFieldRepository) Update(ctx context.Context, field *model.CustomField) error {
+	validation, err := json.Marshal(field.Validation)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx,
+		`UPDATE custom_fields SET type = $1, required = $2, validation = $3 WHERE name = $4 RETURNING created_at`,
+		field.Type, field.Required, validation, field.Name).
+		Scan(&field.CreatedAt)
+}
+
+func (r *customFieldRepository) Delete(ctx context.Context, name string) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, `DELETE FROM custom_fields WHERE name = $1`, name)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+
+		_, err = tx.ExecContext(ctx,
+			`UPDATE users SET custom_fields = custom_fields - $1::text WHERE custom_fields ? $1`, name)
+		return err
+	})
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+)
+
+type DocumentRepository interface {
+	// List returns the documents of a user, newest first
+	List(ctx context.Context, userUUID string) ([]model.Document, error)
+	Get(ctx context.Context, userUUID, id string) (*model.Document, error)
+	Create(ctx context.Context, doc *model.Document) error
+	// Delete removes the metadata row; sql.ErrNoRows when it does not exist
+	Delete(ctx context.Context, userUUID, id string) error
+}
+
+type documentRepository struct {
+	db DB
+}
+
+func NewDocumentRepository(db DB) DocumentRepository {
+	return &documentRepository{db: db}
+}
+
+const documentColumns = `id, user_uuid, filename, content_type, size_bytes, sha256, storage_key, uploaded_by, created_at`
+
+func scanDocument(row interface{ Scan(...any) error }, d *model.Document) error {
+	return row.Scan(&d.ID, &d.UserUUID, &d.Filename, &d.ContentType, &d.SizeBytes, &d.SHA256, &d.StorageKey, &d.UploadedBy, &d.CreatedAt)
+}
+
+func (r *documentRepository) List(ctx context.Context, userUUID string) ([]model.Document, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+documentColumns+` FROM user_documents WHERE user_uuid = $1 ORDER BY created_at DESC`, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var docs []model.Document
+	for rows.Next() {
+		var d model.Document
+		if err := scanDocument(rows, &d); err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func (r *documentRepository) Get(ctx context.Context, userUUID, id string) (*model.Document, error) {
+	var d model.Document
+	if err := scanDocument(r.db.QueryRowContext(ctx,
+		`SELECT `+documentColumns+` FROM user_documents WHERE id = $1 AND user_uuid = $2`, id, userUUID), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *documentRepository) Create(ctx context.Context, doc *model.Document) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO user_documents (id, user_uuid, filename, content_type, size_bytes, sha256, storage_key, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`,
+		doc.ID, doc.UserUUID, doc.Filename, doc.ContentType, doc.SizeBytes, doc.SHA256, doc.StorageKey, doc.UploadedBy).
+		Scan(&doc.CreatedAt)
+}
+
+func (r *documentRepository) Delete(ctx context.Context, userUUID, id string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM user_documents WHERE id = $1 AND user_uuid = $2`, id, userUUID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
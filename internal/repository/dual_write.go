@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/metrics"
+	"cruder/internal/model"
+	"database/sql"
+	"errors"
+	"time"
+
+	"log/slog"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dualWritesTotal = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "dual_write_total",
+		Help: "User rows copied to the secondary database, by operation.",
+	}, []string{"operation"})
+	dualWriteDivergence = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+		Name: "dual_write_divergence_total",
+		Help: "Writes the secondary database missed, by operation.",
+	}, []string{"operation"})
+)
+
+// DualWriter copies user rows from the primary database to a secondary one, e.g. the
+// new cluster during a zero-downtime datastore move. The primary stays the source of
+// truth: a failed secondary write is logged as a divergence and never fails the request.
+type DualWriter struct {
+	primary   *sql.DB
+	secondary *sql.DB
+	timeout   time.Duration
+}
+
+// NewDualWriter creates a DualWriter; timeout bounds each secondary write
+func NewDualWriter(primary, secondary *sql.DB, timeout time.Duration) *DualWriter {
+	return &DualWriter{primary: primary, secondary: secondary, timeout: timeout}
+}
+
+// Sync makes the secondary copy of a user match the primary row, deleting it when
+// the user no longer exists. Rows are copied with their id, so both sides stay
+// interchangeable. Within a unit of work it waits for the commit, so it reads the
+// row as committed.
+func (w *DualWriter) Sync(ctx context.Context, operation, uuid string) {
+	afterCommit(ctx, func() { w.syncNow(ctx, operation, uuid) })
+}
+
+func (w *DualWriter) syncNow(ctx context.Context, operation, uuid string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.timeout)
+	defer cancel()
+	if err := w.sync(ctx, uuid); err != nil {
+		w.diverged(ctx, operation, uuid, err)
+		return
+	}
+	dualWritesTotal.WithLabelValues(operation).Inc()
+}
+
+func (w *DualWriter) sync(ctx context.Context, uuid string) error {
+	var u model.User
+	var customFields []byte
+	var emailNorm sql.NullString
+	var createdAt, deletedAt sql.NullTime
+	err := w.primary.QueryRowContext(ctx,
+		`SELECT id, uuid, username, email, email_norm, full_name, custom_fields, created_at, deleted_at FROM users WHERE uuid = $1`, uuid).
+		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &emailNorm, &u.FullName, &customFields, &createdAt, &deletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, err = w.secondary.ExecContext(ctx, `DELETE FROM users WHERE uuid = $1`, uuid)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = w.secondary.ExecContext(ctx,
+		`INSERT INTO users (id, uuid, username, email, email_norm, full_name, custom_fields, created_at, deleted_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET uuid = EXCLUDED.uuid, username = EXCLUDED.username, email = EXCLUDED.email,
+		email_norm = EXCLUDED.email_norm, full_name = EXCLUDED.full_name, custom_fields = EXCLUDED.custom_fields,
+		created_at = EXCLUDED.created_at, deleted_at = EXCLUDED.deleted_at`,
+		u.ID, u.UUID, u.Username, u.Email, emailNorm, u.FullName, customFields, createdAt, deletedAt)
+	return err
+}
+
+// removeCustomField drops a deleted custom field's values on the secondary
+func (w *DualWriter) removeCustomField(ctx context.Context, name string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.timeout)
+	defer cancel()
+	if _, err := w.secondary.ExecContext(ctx,
+		`UPDATE users SET custom_fields = custom_fields - $1::text WHERE custom_fields ? $1`, name); err != nil {
+		w.diverged(ctx, "delete_custom_field", "", err)
+		return
+	}
+	dualWritesTotal.WithLabelValues("delete_custom_field").Inc()
+}
+
+func (w *DualWriter) diverged(ctx context.Context, operation, uuid string, err error) {
+	dualWriteDivergence.WithLabelValues(operation).Inc()
+	slog.ErrorContext(ctx, "dual-write divergence: change not applied to secondary database",
+		"operation", operation, "user_uuid", uuid, "error", err)
+}
+
+// WithDualWrite wraps every repository that writes user rows so changes are copied
+// to the secondary database after they succeed on the primary
+func (r *Repository) WithDualWrite(w *DualWriter) {
+	r.Users = &dualWriteUsers{UserRepository: r.Users, w: w}
+	r.Approvals = &dualWriteApprovals{ApprovalRepository: r.Approvals, w: w}
+	r.ScheduledDeletions = &dualWriteScheduledDeletions{ScheduledDeletionRepository: r.ScheduledDeletions, w: w}
+	r.CustomFields = &dualWriteCustomFields{CustomFieldRepository: r.CustomFields, w: w}
+}
+
+type dualWriteUsers struct {
+	UserRepository
+	w *DualWriter
+}
+
+func (r *dualWriteUsers) Create(ctx context.Context, user *model.User) error {
+	if err := r.UserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+	r.w.Sync(ctx, "create", user.UUID)
+	return nil
+}
+
+func (r *dualWriteUsers) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	errs, err := r.UserRepository.CreateMany(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	for i, user := range users {
+		if errs[i] == nil {
+			r.w.Sync(ctx, "create", user.UUID)
+		}
+	}
+	return errs, nil
+}
+
+func (r *dualWriteUsers) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	if err := r.UserRepository.Update(ctx, uuid, patch); err != nil {
+		return err
+	}
+	r.w.Sync(ctx, "update", uuid)
+	return nil
+}
+
+func (r *dualWriteUsers) Delete(ctx context.Context, uuid string) error {
+	if err := r.UserRepository.Delete(ctx, uuid); err != nil {
+		return err
+	}
+	r.w.Sync(ctx, "delete", uuid)
+	return nil
+}
+
+func (r *dualWriteUsers) DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error) {
+	errs, err := r.UserRepository.DeleteMany(ctx, uuids, allOrNothing)
+	if err != nil {
+		return nil, err
+	}
+	for i, uuid := range uuids {
+		if errs[i] == nil {
+			r.w.Sync(ctx, "delete", uuid)
+		}
+	}
+	return errs, nil
+}
+
+func (r *dualWriteUsers) Restore(ctx context.Context, uuid string) error {
+	if err := r.UserRepository.Restore(ctx, uuid); err != nil {
+		return err
+	}
+	r.w.Sync(ctx, "restore", uuid)
+	return nil
+}
+
+func (r *dualWriteUsers) Purge(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	purged, err := r.UserRepository.Purge(ctx, before, limit)
+	for _, uuid := range purged {
+		r.w.Sync(ctx, "purge", uuid)
+	}
+	return purged, err
+}
+
+type dualWriteApprovals struct {
+	ApprovalRepository
+	w *DualWriter
+}
+
+func (r *dualWriteApprovals) Approve(ctx context.Context, change *model.PendingChange, reviewer string, patch *model.UserPatch) error {
+	if err := r.ApprovalRepository.Approve(ctx, change, reviewer, patch); err != nil {
+		return err
+	}
+	r.w.Sync(ctx, "approve_"+change.Kind, change.UserUUID)
+	return nil
+}
+
+type dualWriteScheduledDeletions struct {
+	ScheduledDeletionRepository
+	w *DualWriter
+}
+
+func (r *dualWriteScheduledDeletions) DeleteDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	deleted, err := r.ScheduledDeletionRepository.DeleteDue(ctx, now, limit)
+	for _, uuid := range deleted {
+		r.w.Sync(ctx, "scheduled_delete", uuid)
+	}
+	return deleted, err
+}
+
+type dualWriteCustomFields struct {
+	CustomFieldRepository
+	w *DualWriter
+}
+
+func (r *dualWriteCustomFields) Delete(ctx context.Context, name string) error {
+	if err := r.CustomFieldRepository.Delete(ctx, name); err != nil {
+		return err
+	}
+	r.w.removeCustomField(ctx, name)
+	return nil
+}
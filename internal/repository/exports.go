@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"time"
+)
+
+type ExportRepository interface {
+	Create(ctx context.Context, export *model.Export) error
+	Get(ctx context.Context, id string) (*model.Export, error)
+	// Unfinished returns the exports still to be generated, oldest first
+	Unfinished(ctx context.Context) ([]model.Export, error)
+	// Users returns up to limit live users with an ID above afterID, ordered by ID
+	Users(ctx context.Context, afterID int64, limit int) ([]model.User, error)
+	// Checkpoint stores the progress of a running export
+	Checkpoint(ctx context.Context, export *model.Export) error
+	// Complete marks an export as downloadable with its final size and hash
+	Complete(ctx context.Context, export *model.Export) error
+	// DeleteCompletedBefore removes exports completed before the given time and
+	// returns them so their files can be removed too
+	DeleteCompletedBefore(ctx context.Context, before time.Time) ([]model.Export, error)
+}
+
+type exportRepository struct {
+	db DB
+}
+
+func NewExportRepository(db DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+const exportColumns = `id, status, requested_by, last_user_id, chunks, rows_written, size_bytes, sha256, created_at, updated_at, completed_at`
+
+func scanExport(row interface{ Scan(...any) error }, e *model.Export) error {
+	var sha sql.NullString
+	if err := row.Scan(&e.ID, &e.Status, &e.RequestedBy, &e.LastUserID, &e.Chunks, &e.RowsWritten, &e.SizeBytes,
+		&sha, &e.CreatedAt, &e.UpdatedAt, &e.CompletedAt); err != nil {
+		return err
+	}
+	e.SHA256 = sha.String
+	return nil
+}
+
+func (r *exportRepository) Create(ctx context.Context, export *model.Export) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO user_exports (id, requested_by) VALUES ($1, $2) RETURNING status, created_at, updated_at`,
+		export.ID, export.RequestedBy).
+		Scan(&export.Status, &export.CreatedAt, &export.UpdatedAt)
+}
+
+func (r *exportRepository) Get(ctx context.Context, id string) (*model.Export, error) {
+	var e model.Export
+	if err := scanExport(r.db.QueryRowContext(ctx,
+		`SELECT `+exportColumns+` FROM user_exports WHERE id = $1`, id), &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *exportRepository) Unfinished(ctx context.Context) ([]model.Export, error) {
+	return r.list(ctx, `SELECT `+exportColumns+` FROM user_exports
+		WHERE status IN ('pending', 'running') ORDER BY created_at, id`)
+}
+
+func (r *exportRepository) Users(ctx context.Context, afterID int64, limit int) ([]model.User, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, uuid, username, email, full_name FROM users
+		WHERE id > $1 AND deleted_at IS NULL ORDER BY id LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var users []model.User
+	for rows.Next() {
+		var u model.User
+		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *exportRepository) Checkpoint(ctx context.Context, export *model.Export) error {
+	return r.db.QueryRowContext(ctx,
+		`UPDATE user_exports SET status = $2, last_user_id = $3, chunks = $4, rows_written = $5, size_bytes = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING updated_at`,
+		export.ID, export.Status, export.LastUserID, export.Chunks, export.RowsWritten, export.SizeBytes).
+		Scan(&export.UpdatedAt)
+}
+
+func (r *exportRepository) Complete(ctx context.Context, export *model.Export) error {
+	return r.db.QueryRowContext(ctx,
+		`UPDATE user_exports SET status = 'completed', size_bytes = $2, sha256 = $3,
+			updated_at = CURRENT_TIMESTAMP, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING status, updated_at, completed_at`,
+		export.ID, export.SizeBytes, export.SHA256).
+		Scan(&export.Status, &export.UpdatedAt, &export.CompletedAt)
+}
+
+func (r *exportRepository) DeleteCompletedBefore(ctx context.Context, before time.Time) ([]model.Export, error) {
+	return r.list(ctx, `DELETE FROM user_exports WHERE status = 'completed' AND completed_at < $1
+		RETURNING `+exportColumns, before)
+}
+
+func (r *exportRepository) list(ctx context.Context, query string, args ...any) ([]model.Export, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var exports []model.Export
+	for rows.Next() {
+		var e model.Export
+		if err := scanExport(rows, &e); err != nil {
+			return nil, err
+		}
+		exports = append(exports, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// idempotencyTakeover is how long a key may be held by a request that never
+// finished, e.g. because its instance crashed, before a retry may claim it
+const idempotencyTakeover = time.Minute
+
+type IdempotencyRepository interface {
+	// Claim reserves key for the request identified by fingerprint. It returns nil
+	// when the key is new, or expired after ttl; otherwise the stored key, whose
+	// Response is nil while the request that claimed it is still running.
+	Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*model.IdempotencyKey, error)
+	// Complete stores the response of the request that claimed key
+	Complete(ctx context.Context, key string, response model.IdempotentResponse) error
+	// Release gives up key, so a retry runs the request again
+	Release(ctx context.Context, key string) error
+	// Purge deletes keys older than ttl and returns how many it deleted
+	Purge(ctx context.Context, ttl time.Duration) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Claim(ctx context.Context, key, fingerprint string, ttl time.Duration) (*model.IdempotencyKey, error) {
+	// A key released between the two statements is claimed on the second attempt
+	for range 2 {
+		var claimed string
+		err := r.db.QueryRowContext(ctx,
+			`INSERT INTO idempotency_keys (key, fingerprint) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = NULL, headers = '{}',
+				body = NULL, created_at = CURRENT_TIMESTAMP
+			WHERE idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $3)
+				OR idempotency_keys.status IS NULL AND idempotency_keys.created_at < CURRENT_TIMESTAMP - make_interval(secs => $4)
+			RETURNING key`, key, fingerprint, ttl.Seconds(), idempotencyTakeover.Seconds()).Scan(&claimed)
+		if err == nil {
+			return nil, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		stored, err := r.get(ctx, key)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return stored, err
+		}
+	}
+	return nil, errors.New("idempotency key changed while claiming it")
+}
+
+func (r *idempotencyRepository) get(ctx context.Context, key string) (*model.IdempotencyKey, error) {
+	var stored model.IdempotencyKey
+	var status sql.NullInt64
+	var headers, body []byte
+	err := r.db.QueryRowContext(ctx,
+		`SELECT fingerprint, status, headers, body FROM idempotency_keys WHERE key = $1`, key).
+		Scan(&stored.Fingerprint, &status, &headers, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status.Valid {
+		stored.Response = &model.IdempotentResponse{Status: int(status.Int64), Body: body}
+		if err := json.Unmarshal(headers, &stored.Response.Headers); err != nil {
+			return nil, err
+		}
+	}
+	return &stored, nil
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, key string, response model.IdempotentResponse) error {
+	headers, err := json.Marshal(response.Headers)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET status = $2, headers = $3, body = $4 WHERE key = $1`,
+		key, response.Status, headers, response.Body)
+	return err
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1 AND status IS NULL`, key)
+	return err
+}
+
+func (r *idempotencyRepository) Purge(ctx context.Context, ttl time.Duration) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`, ttl.Seconds())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"cruder/internal/migrations"
+	"cruder/internal/model"
+)
+
+// TestIdempotencyRepository needs a database; set TEST_DATABASE_URL to run it
+func TestIdempotencyRepository(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	if err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	const key = "test:idempotency"
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM idempotency_keys WHERE key = $1`, key) })
+	repo := NewIdempotencyRepository(db)
+
+	// Given: A key claimed by a request still running
+	if stored, err := repo.Claim(ctx, key, "fp-1", time.Hour); err != nil || stored != nil {
+		t.Fatalf("expected the key to be claimed, got %+v, %v", stored, err)
+	}
+	if stored, err := repo.Claim(ctx, key, "fp-1", time.Hour); err != nil || stored == nil || stored.Response != nil {
+		t.Fatalf("expected the key to be in flight, got %+v, %v", stored, err)
+	}
+
+	// When: The request completes
+	response := model.IdempotentResponse{Status: 201, Headers: map[string]string{"Location": "/api/v1/users/id/1"}, Body: []byte(`{"id":1}`)}
+	if err := repo.Complete(ctx, key, response); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	// Then: Claims return its response until the key expires
+	stored, err := repo.Claim(ctx, key, "fp-2", time.Hour)
+	if err != nil || stored == nil || stored.Fingerprint != "fp-1" || stored.Response == nil {
+		t.Fatalf("expected the stored response, got %+v, %v", stored, err)
+	}
+	if stored.Response.Status != 201 || string(stored.Response.Body) != `{"id":1}` || stored.Response.Headers["Location"] != "/api/v1/users/id/1" {
+		t.Errorf("unexpected response %+v", stored.Response)
+	}
+	if stored, err := repo.Claim(ctx, key, "fp-2", time.Nanosecond); err != nil || stored != nil {
+		t.Errorf("expected an expired key to be claimed again, got %+v, %v", stored, err)
+	}
+}
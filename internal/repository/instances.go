@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type InstanceRepository interface {
+	// Heartbeat records instance as alive now, by the database clock
+	Heartbeat(ctx context.Context, instance *model.Instance) error
+	// List returns instances seen within ttl, oldest first
+	List(ctx context.Context, ttl time.Duration) ([]model.Instance, error)
+	// Remove deletes an instance that is shutting down
+	Remove(ctx context.Context, id string) error
+	// Prune deletes instances not seen for longer than age
+	Prune(ctx context.Context, age time.Duration) error
+}
+
+type instanceRepository struct {
+	db *sql.DB
+}
+
+func NewInstanceRepository(db *sql.DB) InstanceRepository {
+	return &instanceRepository{db: db}
+}
+
+func (r *instanceRepository) Heartbeat(ctx context.Context, i *model.Instance) error {
+	jobs := i.Jobs
+	if jobs == nil {
+		jobs = []string{}
+	}
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO instances (id, hostname, version, commit_hash, jobs, started_at) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET jobs = EXCLUDED.jobs, last_seen_at = CURRENT_TIMESTAMP
+		RETURNING last_seen_at`,
+		i.ID, i.Hostname, i.Version, i.Commit, pq.Array(jobs), i.StartedAt).
+		Scan(&i.LastSeenAt)
+}
+
+func (r *instanceRepository) List(ctx context.Context, ttl time.Duration) ([]model.Instance, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, hostname, version, commit_hash, jobs, started_at, last_seen_at FROM instances
+		WHERE last_seen_at > CURRENT_TIMESTAMP - make_interval(secs => $1)
+		ORDER BY started_at, id`, ttl.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var instances []model.Instance
+	for rows.Next() {
+		var i model.Instance
+		if err := rows.Scan(&i.ID, &i.Hostname, &i.Version, &i.Commit, pq.Array(&i.Jobs), &i.StartedAt, &i.LastSeenAt); err != nil {
+			return nil, err
+		}
+		instances = append(instances, i)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+func (r *instanceRepository) Remove(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM instances WHERE id = $1`, id)
+	return err
+}
+
+func (r *instanceRepository) Prune(ctx context.Context, age time.Duration) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM instances WHERE last_seen_at < CURRENT_TIMESTAMP - make_interval(secs => $1)`, age.Seconds())
+	return err
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+)
+
+type NoteRepository interface {
+	// List returns the notes on a user, oldest first
+	List(ctx context.Context, userUUID string) ([]model.Note, error)
+	Create(ctx context.Context, note *model.Note) error
+	// Update replaces the body of a note; sql.ErrNoRows when it does not exist
+	Update(ctx context.Context, note *model.Note) error
+	Delete(ctx context.Context, userUUID string, id int64) error
+}
+
+type noteRepository struct {
+	db DB
+}
+
+func NewNoteRepository(db DB) NoteRepository {
+	return &noteRepository{db: db}
+}
+
+func (r *noteRepository) List(ctx context.Context, userUUID string) ([]model.Note, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_uuid, author, body, created_at, updated_at FROM user_notes
+		WHERE user_uuid = $1 ORDER BY created_at, id`, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var notes []model.Note
+	for rows.Next() {
+		var n model.Note
+		if err := rows.Scan(&n.ID, &n.UserUUID, &n.Author, &n.Body, &n.CreatedAt, &n.UpdatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+func (r *noteRepository) Create(ctx context.Context, note *model.Note) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO user_notes (user_uuid, author, body) VALUES ($1, $2, $3) RETURNING id, created_at`,
+		note.UserUUID, note.Author, note.Body).
+		Scan(&note.ID, &note.CreatedAt)
+}
+
+func (r *noteRepository) Update(ctx context.Context, note *model.Note) error {
+	return r.db.QueryRowContext(ctx,
+		`UPDATE user_notes SET body = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND user_uuid = $3
+		RETURNING author, created_at, updated_at`,
+		note.Body, note.ID, note.UserUUID).
+		Scan(&note.Author, &note.CreatedAt, &note.UpdatedAt)
+}
+
+func (r *noteRepository) Delete(ctx context.Context, userUUID string, id int64) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM user_notes WHERE id = $1 AND user_uuid = $2`, id, userUUID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+// outboxLock keeps relays of the same database from publishing changes out of order
+const outboxLock = "cruder.outbox"
+
+type OutboxRepository interface {
+	// Relay hands up to limit of the oldest recorded changes to publish, in the order
+	// they were made, and removes them once publish returns nil. One relay works on
+	// a database's outbox at a time; the others get 0 without publish being called.
+	Relay(ctx context.Context, limit int, publish func([]model.OutboxEvent) error) (int, error)
+	// Capture turns on the triggers that record changes to users in the outbox
+	Capture(ctx context.Context) error
+	// Release turns them off again, so the outbox stops growing; changes
+	// already recorded stay in it
+	Release(ctx context.Context) error
+}
+
+type outboxRepository struct {
+	db DB
+}
+
+func NewOutboxRepository(db DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Relay(ctx context.Context, limit int, publish func([]model.OutboxEvent) error) (int, error) {
+	var relayed int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		relayed = 0
+		var locked bool
+		if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1))`, outboxLock).Scan(&locked); err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		events, err := outboxEvents(ctx, tx, limit)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		if err := publish(events); err != nil {
+			return err
+		}
+		ids := make([]int64, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_outbox WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return err
+		}
+		relayed = len(events)
+		return nil
+	})
+	return relayed, err
+}
+
+func outboxEvents(ctx context.Context, tx *sql.Tx, limit int) ([]model.OutboxEvent, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, event_id, event_type, user_uuid, region, "user", created_at FROM user_outbox ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var events []model.OutboxEvent
+	for rows.Next() {
+		var e model.OutboxEvent
+		var user []byte
+		if err := rows.Scan(&e.ID, &e.EventID, &e.Type, &e.UserUUID, &e.Region, &user, &e.At); err != nil {
+			return nil, err
+		}
+		e.User = user
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *outboxRepository) Capture(ctx context.Context) error {
+	return r.setCapture(ctx, true)
+}
+
+func (r *outboxRepository) Release(ctx context.Context) error {
+	return r.setCapture(ctx, false)
+}
+
+func (r *outboxRepository) setCapture(ctx context.Context, on bool) error {
+	// ALTER TABLE locks users, so it is only run when a trigger is in the other state
+	state, action := "O", "DISABLE"
+	if on {
+		state, action = "D", "ENABLE"
+	}
+	var pending bool
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'users'::regclass
+			AND tgname IN ('users_outbox_insert_delete', 'users_outbox_update') AND tgenabled = $1)`, state).
+		Scan(&pending); err != nil || !pending {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx,
+		`ALTER TABLE users `+action+` TRIGGER users_outbox_insert_delete, `+action+` TRIGGER users_outbox_update`)
+	return err
+}
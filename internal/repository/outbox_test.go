@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"cruder/internal/migrations"
+)
+
+// TestOutboxRepository_Capture needs a database; set TEST_DATABASE_URL to run it
+func TestOutboxRepository_Capture(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	if err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	repo := NewOutboxRepository(db)
+	t.Cleanup(func() { _ = repo.Release(ctx) })
+	enabled := func() int {
+		var n int
+		if err := db.QueryRow(`SELECT count(*) FROM pg_trigger WHERE tgrelid = 'users'::regclass
+			AND tgname IN ('users_outbox_insert_delete', 'users_outbox_update') AND tgenabled = 'O'`).Scan(&n); err != nil {
+			t.Fatalf("failed to read the triggers: %v", err)
+		}
+		return n
+	}
+
+	// When: Capturing is turned on, twice
+	for range 2 {
+		if err := repo.Capture(ctx); err != nil {
+			t.Fatalf("failed to enable the outbox: %v", err)
+		}
+	}
+	// Then: Both triggers are enabled
+	if n := enabled(); n != 2 {
+		t.Fatalf("expected 2 enabled triggers, got %d", n)
+	}
+
+	// When: It is released, twice
+	for range 2 {
+		if err := repo.Release(ctx); err != nil {
+			t.Fatalf("failed to disable the outbox: %v", err)
+		}
+	}
+	// Then: Both triggers are disabled again
+	if n := enabled(); n != 0 {
+		t.Fatalf("expected no enabled triggers, got %d", n)
+	}
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type PersonalTokenRepository interface {
+	Create(ctx context.Context, token *model.PersonalToken) error
+	// List returns the tokens of a user, newest first
+	List(ctx context.Context, userID int64) ([]model.PersonalToken, error)
+	Count(ctx context.Context, userID int64) (int, error)
+	// Delete removes a token of the user; sql.ErrNoRows when the user has no such token
+	Delete(ctx context.Context, userID int64, id string) error
+	// FindByHash returns the unexpired token with the hash, with its owner's current
+	// username; sql.ErrNoRows when there is none or the owner was deleted. Every
+	// region is searched, as the caller's region is not known before the token is.
+	FindByHash(ctx context.Context, hash string) (*model.PersonalToken, error)
+	// Touch records that a token was used; it writes at most once a minute per token
+	Touch(ctx context.Context, id string) error
+}
+
+type personalTokenRepository struct {
+	db DB
+}
+
+func NewPersonalTokenRepository(db DB) PersonalTokenRepository {
+	return &personalTokenRepository{db: db}
+}
+
+func (r *personalTokenRepository) Create(ctx context.Context, t *model.PersonalToken) error {
+	scopes := t.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO personal_tokens (id, user_id, name, prefix, token_hash, scopes, tenant, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING created_at`,
+		t.ID, t.UserID, t.Name, t.Prefix, t.Hash, pq.Array(scopes), t.Tenant, t.ExpiresAt).
+		Scan(&t.CreatedAt)
+}
+
+func (r *personalTokenRepository) List(ctx context.Context, userID int64) ([]model.PersonalToken, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, name, prefix, scopes, tenant, expires_at, last_used_at, created_at
+		FROM personal_tokens WHERE user_id = $1 ORDER BY created_at DESC, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	tokens := []model.PersonalToken{}
+	for rows.Next() {
+		var t model.PersonalToken
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, pq.Array(&t.Scopes), &t.Tenant,
+			&t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *personalTokenRepository) Count(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM personal_tokens WHERE user_id = $1`, userID).Scan(&n)
+	return n, err
+}
+
+func (r *personalTokenRepository) Delete(ctx context.Context, userID int64, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM personal_tokens WHERE user_id = $1 AND id = $2`, userID, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *personalTokenRepository) FindByHash(ctx context.Context, hash string) (*model.PersonalToken, error) {
+	var t model.PersonalToken
+	region, err := untilFound(ctx, r.db, func(ctx context.Context) error {
+		return r.db.QueryRowContext(ctx,
+			`SELECT t.id, t.user_id, u.username, t.name, t.prefix, t.scopes, t.tenant, t.expires_at, t.last_used_at, t.created_at
+			FROM personal_tokens t JOIN users u ON u.id = t.user_id
+			WHERE t.token_hash = $1 AND t.expires_at > CURRENT_TIMESTAMP AND u.deleted_at IS NULL`, hash).
+			Scan(&t.ID, &t.UserID, &t.Username, &t.Name, &t.Prefix, pq.Array(&t.Scopes), &t.Tenant,
+				&t.ExpiresAt, &t.LastUsedAt, &t.CreatedAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.Region = region
+	return &t, nil
+}
+
+func (r *personalTokenRepository) Touch(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE personal_tokens SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`, id)
+	return err
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type PositionRepository interface {
+	// CurrentPosition returns the primary's current WAL position, e.g. "0/16B3748"
+	CurrentPosition(ctx context.Context) (string, error)
+}
+
+type positionRepository struct {
+	db *sql.DB
+}
+
+func NewPositionRepository(db *sql.DB) PositionRepository {
+	return &positionRepository{db: db}
+}
+
+func (r *positionRepository) CurrentPosition(ctx context.Context) (string, error) {
+	var position string
+	err := r.db.QueryRowContext(ctx, `SELECT pg_current_wal_lsn()::text`).Scan(&position)
+	return position, err
+}
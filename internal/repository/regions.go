@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"cruder/internal/residency"
+)
+
+// DB is what the repositories of user data run their queries on: a *sql.DB, or
+// Regions to keep each region's users in its own database
+type DB interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Regions routes each query to the database of the region attached to its context
+// with residency.WithRegion. Contexts without a region, such as those of background
+// jobs not run through Each, use the home database.
+type Regions struct {
+	home string
+	dbs  map[string]*sql.DB
+}
+
+// NewRegions creates the router; homeDB is the database of the home region and dbs
+// are the databases of the other regions, by name
+func NewRegions(home string, homeDB *sql.DB, dbs map[string]*sql.DB) *Regions {
+	all := map[string]*sql.DB{home: homeDB}
+	for name, db := range dbs {
+		all[name] = db
+	}
+	return &Regions{home: home, dbs: all}
+}
+
+// Names returns the regions, the home region first and the others in name order
+func (r *Regions) Names() []string {
+	names := make([]string, 0, len(r.dbs))
+	for name := range r.dbs {
+		if name != r.home {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{r.home}, names...)
+}
+
+// Each calls fn once per region with ctx tagged with the region, so jobs reach the
+// users of every region; it carries on after a failure and returns every error.
+// On a nil Regions fn is called once with ctx.
+func (r *Regions) Each(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r == nil {
+		return fn(ctx)
+	}
+	var errs []error
+	for _, name := range r.Names() {
+		if err := fn(residency.WithRegion(ctx, name)); err != nil {
+			errs = append(errs, fmt.Errorf("region %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// db returns the database of ctx's region. Requests are only tagged with configured
+// regions, so an unknown one is a bug; it panics rather than let the data of one
+// region reach another region's database.
+func (r *Regions) db(ctx context.Context) *sql.DB {
+	name := residency.Region(ctx)
+	if name == "" {
+		name = r.home
+	}
+	db, ok := r.dbs[name]
+	if !ok {
+		panic(fmt.Sprintf("no database configured for region %q", name))
+	}
+	return db
+}
+
+func (r *Regions) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.db(ctx).QueryContext(ctx, query, args...)
+}
+
+func (r *Regions) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.db(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (r *Regions) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.db(ctx).ExecContext(ctx, query, args...)
+}
+
+func (r *Regions) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return r.db(ctx).BeginTx(ctx, opts)
+}
+
+// untilFound looks a row up region by region when the caller's region is not known
+// yet, e.g. a personal token during authentication. fn returns sql.ErrNoRows to
+// try the next region; the region it was found in is returned.
+func untilFound(ctx context.Context, db DB, fn func(ctx context.Context) error) (string, error) {
+	regions, ok := db.(*Regions)
+	if !ok {
+		return "", fn(ctx)
+	}
+	for _, name := range regions.Names() {
+		err := fn(residency.WithRegion(ctx, name))
+		if !errors.Is(err, sql.ErrNoRows) {
+			return name, err
+		}
+	}
+	return "", sql.ErrNoRows
+}
+
+// WithRegions moves the repositories of user data onto regions: users, their
+// notes, documents, consents, tokens, scheduled deletions, pending changes, exports,
+// the search table and the outbox, and their units of work; billing sums over every
+// region.
+// Custom field definitions, saved views, rules, API keys and operational data stay
+// in the home database.
+func (r *Repository) WithRegions(regions *Regions) {
+	r.Users = NewUserRepository(regions)
+	r.Tx = NewTxManager(regions)
+	r.UserSearch = NewUserSearchRepository(regions)
+	r.Notes = NewNoteRepository(regions)
+	r.Documents = NewDocumentRepository(regions)
+	r.Consents = NewConsentRepository(regions)
+	r.PersonalTokens = NewPersonalTokenRepository(regions)
+	r.ScheduledDeletions = NewScheduledDeletionRepository(regions)
+	r.Approvals = NewApprovalRepository(regions)
+	r.Exports = NewExportRepository(regions)
+	r.Billing = NewBillingRepository(regions)
+	r.Outbox = NewOutboxRepository(regions)
+	r.CustomFields = &regionCustomFields{CustomFieldRepository: r.CustomFields, regions: regions}
+}
+
+// regionCustomFields removes a deleted field's values from the users of the other
+// regions too; the home region's are removed with the definition
+type regionCustomFields struct {
+	CustomFieldRepository
+	regions *Regions
+}
+
+func (r *regionCustomFields) Delete(ctx context.Context, name string) error {
+	if err := r.CustomFieldRepository.Delete(ctx, name); err != nil {
+		return err
+	}
+	return r.regions.Each(ctx, func(ctx context.Context) error {
+		if residency.Region(ctx) == r.regions.home {
+			return nil
+		}
+		_, err := r.regions.ExecContext(ctx,
+			`UPDATE users SET custom_fields = custom_fields - $1::text WHERE custom_fields ? $1`, name)
+		return err
+	})
+}
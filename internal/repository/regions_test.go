@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"cruder/internal/residency"
+)
+
+func TestRegionsEach(t *testing.T) {
+	// Given: A home region and two others
+	regions := NewRegions("us", nil, map[string]*sql.DB{"eu": nil, "apac": nil})
+
+	// When: A job fails in one region
+	var visited []string
+	err := regions.Each(context.Background(), func(ctx context.Context) error {
+		visited = append(visited, residency.Region(ctx))
+		if residency.Region(ctx) == "apac" {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	// Then: Every region is visited, home first, and the failure names its region
+	if want := []string{"us", "apac", "eu"}; !slices.Equal(visited, want) {
+		t.Errorf("expected %v, got %v", want, visited)
+	}
+	if err == nil || !strings.Contains(err.Error(), "region apac") {
+		t.Errorf("expected the apac failure, got %v", err)
+	}
+}
+
+func TestRegionsEach_Nil(t *testing.T) {
+	// Given: Residency is off
+	var regions *Regions
+
+	// When
+	calls := 0
+	err := regions.Each(context.Background(), func(ctx context.Context) error {
+		calls++
+		if residency.Region(ctx) != "" {
+			t.Errorf("expected no region, got %q", residency.Region(ctx))
+		}
+		return nil
+	})
+
+	// Then: The job runs once, on the home database
+	if err != nil || calls != 1 {
+		t.Errorf("expected one call without error, got %d, %v", calls, err)
+	}
+}
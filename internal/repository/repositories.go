@@ -3,11 +3,54 @@ package repository
 import "database/sql"
 
 type Repository struct {
-	Users UserRepository
+	Users              UserRepository
+	Usage              UsageRepository
+	ScheduledDeletions ScheduledDeletionRepository
+	Notes              NoteRepository
+	Consents           ConsentRepository
+	Documents          DocumentRepository
+	Exports            ExportRepository
+	PersonalTokens     PersonalTokenRepository
+	APIKeys            APIKeyRepository
+	SigningKeys        SigningKeyRepository
+	CustomFields       CustomFieldRepository
+	SavedViews         SavedViewRepository
+	Rules              RuleRepository
+	Approvals          ApprovalRepository
+	UserSearch         UserSearchRepository
+	Positions          PositionRepository
+	Instances          InstanceRepository
+	Idempotency        IdempotencyRepository
+	Billing            BillingRepository
+	Webhooks           WebhookRepository
+	Outbox             OutboxRepository
+	// Tx runs units of work on the database of the users
+	Tx TxManager
 }
 
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{
-		Users: NewUserRepository(db),
+		Users:              NewUserRepository(db),
+		Usage:              NewUsageRepository(db),
+		ScheduledDeletions: NewScheduledDeletionRepository(db),
+		Notes:              NewNoteRepository(db),
+		Consents:           NewConsentRepository(db),
+		Documents:          NewDocumentRepository(db),
+		Exports:            NewExportRepository(db),
+		PersonalTokens:     NewPersonalTokenRepository(db),
+		APIKeys:            NewAPIKeyRepository(db),
+		SigningKeys:        NewSigningKeyRepository(db),
+		CustomFields:       NewCustomFieldRepository(db),
+		SavedViews:         NewSavedViewRepository(db),
+		Rules:              NewRuleRepository(db),
+		Approvals:          NewApprovalRepository(db),
+		UserSearch:         NewUserSearchRepository(db),
+		Positions:          NewPositionRepository(db),
+		Instances:          NewInstanceRepository(db),
+		Idempotency:        NewIdempotencyRepository(db),
+		Billing:            NewBillingRepository(db),
+		Webhooks:           NewWebhookRepository(db),
+		Outbox:             NewOutboxRepository(db),
+		Tx:                 NewTxManager(db),
 	}
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"cruder/internal/metrics"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Transactions aborted by a deadlock or serialization failure are run again up to
+// txAttempts times in total, waiting a random time of up to txRetryBase doubled
+// per attempt in between so the competing transactions do not collide again
+const (
+	txAttempts  = 4
+	txRetryBase = 20 * time.Millisecond
+)
+
+// retryableCodes are the SQLSTATEs of transactions PostgreSQL aborted only
+// because of a concurrent one; running them again is safe and usually succeeds
+var retryableCodes = map[pq.ErrorCode]string{
+	"40001": "serialization_failure",
+	"40P01": "deadlock",
+}
+
+var txRetries = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "db_transaction_retries_total",
+	Help: "Transactions run again after PostgreSQL aborted them, by reason.",
+}, []string{"reason"})
+
+// inTx runs fn in a transaction and commits it, with a statement_timeout of the
+// time left until ctx's deadline. When PostgreSQL aborts the transaction as a
+// deadlock victim or for a serialization failure, the whole transaction runs
+// again, so fn must not have effects outside tx that break when repeated. The caller only sees an error if every attempt fails. Within a unit
+// of work on db, fn runs in its transaction instead, which retries as a whole.
+func inTx(ctx context.Context, db DB, fn func(tx *sql.Tx) error) error {
+	if tx := txFor(ctx, db); tx != nil {
+		return fn(tx)
+	}
+	return retryTx(ctx, func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := fn(tx); err != nil {
+			return timedOut(err)
+		}
+		return tx.Commit()
+	})
+}
+
+// retryTx calls attempt until it succeeds, fails for a reason other than a
+// retryable conflict, runs out of attempts or ctx is done
+func retryTx(ctx context.Context, attempt func() error) error {
+	backoff := txRetryBase
+	for i := 1; ; i++ {
+		err := attempt()
+		reason, retryable := conflict(err)
+		if !retryable || i == txAttempts {
+			return err
+		}
+		txRetries.WithLabelValues(reason).Inc()
+
+		timer := time.NewTimer(rand.N(backoff) + 1)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// conflict reports whether err aborted a transaction because of a concurrent one
+func conflict(err error) (string, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", false
+	}
+	reason, ok := retryableCodes[pqErr.Code]
+	return reason, ok
+}
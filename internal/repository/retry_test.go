@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestRetryTx(t *testing.T) {
+	deadlock := &pq.Error{Code: "40P01", Message: "deadlock detected"}
+	serialization := &pq.Error{Code: "40001", Message: "could not serialize access"}
+	unique := &pq.Error{Code: "23505", Message: "duplicate key value"}
+
+	tests := []struct {
+		name     string
+		errs     []error // returned by successive attempts; nil after the list ends
+		wantErr  error
+		attempts int
+	}{
+		{"success", nil, nil, 1},
+		{"retry wins after a deadlock", []error{deadlock}, nil, 2},
+		{"retry wins after serialization failures", []error{serialization, serialization}, nil, 3},
+		{"other errors are not retried", []error{unique}, unique, 1},
+		{"gives up after the last attempt", []error{deadlock, deadlock, deadlock, deadlock, deadlock}, deadlock, txAttempts},
+		{"wrapped conflicts are retried", []error{errors.Join(errors.New("approve"), deadlock)}, nil, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retryTx(context.Background(), func() error {
+				attempts++
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if attempts != tt.attempts {
+				t.Errorf("expected %d attempts, got %d", tt.attempts, attempts)
+			}
+		})
+	}
+}
+
+func TestRetryTx_StopsWhenContextDone(t *testing.T) {
+	// Given a cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When the first attempt deadlocks
+	attempts := 0
+	err := retryTx(ctx, func() error {
+		attempts++
+		return &pq.Error{Code: "40P01"}
+	})
+
+	// Then the conflict is returned without another attempt
+	if attempts != 1 || err == nil {
+		t.Errorf("expected one failed attempt, got %d (%v)", attempts, err)
+	}
+}
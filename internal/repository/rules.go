@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+)
+
+type RuleRepository interface {
+	// List returns all rules in evaluation order
+	List(ctx context.Context) ([]model.Rule, error)
+	Create(ctx context.Context, rule *model.Rule) error
+	// Update replaces a rule; sql.ErrNoRows when it does not exist
+	Update(ctx context.Context, rule *model.Rule) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type ruleRepository struct {
+	db *sql.DB
+}
+
+func NewRuleRepository(db *sql.DB) RuleRepository {
+	return &ruleRepository{db: db}
+}
+
+func (r *ruleRepository) List(ctx context.Context) ([]model.Rule, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, name, kind, field, expression, message, disabled, created_at, updated_at FROM user_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var rules []model.Rule
+	for rows.Next() {
+		var rule model.Rule
+		if err := rows.Scan(&rule.ID, &rule.Name, &rule.Kind, &rule.Field, &rule.Expression, &rule.Message,
+			&rule.Disabled, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (r *ruleRepository) Create(ctx context.Context, rule *model.Rule) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO user_rules (name, kind, field, expression, message, disabled) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		rule.Name, rule.Kind, rule.Field, rule.Expression, rule.Message, rule.Disabled).
+		Scan(&rule.ID, &rule.CreatedAt)
+}
+
+func (r *ruleRepository) Update(ctx context.Context, rule *model.Rule) error {
+	return r.db.QueryRowContext(ctx,
+		`UPDATE user_rules SET name = $1, kind = $2, field = $3, expression = $4, message = $5, disabled = $6,
+		updated_at = CURRENT_TIMESTAMP WHERE id = $7 RETURNING created_at, updated_at`,
+		rule.Name, rule.Kind, rule.Field, rule.Expression, rule.Message, rule.Disabled, rule.ID).
+		Scan(&rule.CreatedAt, &rule.UpdatedAt)
+}
+
+func (r *ruleRepository) Delete(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM user_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"encoding/json"
+)
+
+type SavedViewRepository interface {
+	List(ctx context.Context) ([]model.SavedView, error)
+	Get(ctx context.Context, name string) (*model.SavedView, error)
+	Create(ctx context.Context, view *model.SavedView) error
+	Delete(ctx context.Context, name string) error
+}
+
+type savedViewRepository struct {
+	db *sql.DB
+}
+
+func NewSavedViewRepository(db *sql.DB) SavedViewRepository {
+	return &savedViewRepository{db: db}
+}
+
+func scanSavedView(row interface{ Scan(...any) error }, v *model.SavedView) error {
+	var query []byte
+	if err := row.Scan(&v.Name, &v.Description, &query, &v.CreatedBy, &v.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(query, &v.Query)
+}
+
+func (r *savedViewRepository) List(ctx context.Context) ([]model.SavedView, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT name, description, query, created_by, created_at FROM saved_views ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var views []model.SavedView
+	for rows.Next() {
+		var v model.SavedView
+		if err := scanSavedView(rows, &v); err != nil {
+			return nil, err
+		}
+		views = append(views, v)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return views, nil
+}
+
+func (r *savedViewRepository) Get(ctx context.Context, name string) (*model.SavedView, error) {
+	var v model.SavedView
+	err := scanSavedView(r.db.QueryRowContext(ctx,
+		`SELECT name, description, query, created_by, created_at FROM saved_views WHERE name = $1`, name), &v)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *savedViewRepository) Create(ctx context.Context, view *model.SavedView) error {
+	query, err := json.Marshal(view.Query)
+	if err != nil {
+		return err
+	}
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO saved_views (name, description, query, created_by) VALUES ($1, $2, $3, $4) RETURNING created_at`,
+		view.Name, view.Description, query, view.CreatedBy).
+		Scan(&view.CreatedAt)
+}
+
+func (r *savedViewRepository) Delete(ctx context.Context, name string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM saved_views WHERE name = $1`, name)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"time"
+)
+
+type ScheduledDeletionRepository interface {
+	// Schedule creates or replaces the pending deletion of a user
+	Schedule(ctx context.Context, deletion *model.ScheduledDeletion) error
+	Get(ctx context.Context, userUUID string) (*model.ScheduledDeletion, error)
+	// Cancel removes a pending deletion; sql.ErrNoRows when there is none
+	Cancel(ctx context.Context, userUUID string) error
+	// DeleteDue soft-deletes up to limit users whose deletion is due at now and returns their UUIDs
+	DeleteDue(ctx context.Context, now time.Time, limit int) ([]string, error)
+}
+
+type scheduledDeletionRepository struct {
+	db DB
+}
+
+func NewScheduledDeletionRepository(db DB) ScheduledDeletionRepository {
+	return &scheduledDeletionRepository{db: db}
+}
+
+func (r *scheduledDeletionRepository) Schedule(ctx context.Context, deletion *model.ScheduledDeletion) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO scheduled_deletions (user_uuid, effective_at, requested_by) VALUES ($1, $2, $3)
+		ON CONFLICT (user_uuid) DO UPDATE SET
+			effective_at = EXCLUDED.effective_at,
+			requested_by = EXCLUDED.requested_by,
+			created_at = CURRENT_TIMESTAMP
+		RETURNING created_at`,
+		deletion.UserUUID, deletion.EffectiveAt, deletion.RequestedBy).
+		Scan(&deletion.CreatedAt)
+}
+
+func (r *scheduledDeletionRepository) Get(ctx context.Context, userUUID string) (*model.ScheduledDeletion, error) {
+	var d model.ScheduledDeletion
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT user_uuid, effective_at, requested_by, created_at FROM scheduled_deletions WHERE user_uuid = $1`, userUUID).
+		Scan(&d.UserUUID, &d.EffectiveAt, &d.RequestedBy, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *scheduledDeletionRepository) Cancel(ctx context.Context, userUUID string) error {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM scheduled_deletions WHERE user_uuid = $1`, userUUID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteDue claims due rows with SKIP LOCKED so several replicas can run the job
+// concurrently, and removes the schedule and soft-deletes the user in one statement.
+func (r *scheduledDeletionRepository) DeleteDue(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`WITH due AS (
+			DELETE FROM scheduled_deletions WHERE user_uuid IN (
+				SELECT user_uuid FROM scheduled_deletions
+				WHERE effective_at <= $1 ORDER BY effective_at LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			) RETURNING user_uuid
+		)
+		UPDATE users SET deleted_at = CURRENT_TIMESTAMP
+		WHERE uuid IN (SELECT user_uuid FROM due) AND deleted_at IS NULL RETURNING uuid`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var deleted []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, uuid)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+)
+
+type SigningKeyRepository interface {
+	// List returns every key, oldest activation first
+	List(ctx context.Context) ([]model.SigningKey, error)
+	Create(ctx context.Context, key *model.SigningKey) error
+	// Revoke marks a key revoked; sql.ErrNoRows when there is no such key
+	Revoke(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+}
+
+type signingKeyRepository struct {
+	db *sql.DB
+}
+
+func NewSigningKeyRepository(db *sql.DB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+func (r *signingKeyRepository) List(ctx context.Context) ([]model.SigningKey, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT kid, public_key, private_key, activates_at, revoked_at, created_at
+		FROM signing_keys ORDER BY activates_at, kid`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	keys := []model.SigningKey{}
+	for rows.Next() {
+		var k model.SigningKey
+		if err := rows.Scan(&k.ID, &k.PublicKey, &k.PrivateKey, &k.ActivatesAt, &k.RevokedAt, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Create(ctx context.Context, k *model.SigningKey) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO signing_keys (kid, public_key, private_key, activates_at) VALUES ($1, $2, $3, $4) RETURNING created_at`,
+		k.ID, k.PublicKey, k.PrivateKey, k.ActivatesAt).
+		Scan(&k.CreatedAt)
+}
+
+func (r *signingKeyRepository) Revoke(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE signing_keys SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP) WHERE kid = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *signingKeyRepository) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM signing_keys WHERE kid = $1`, id)
+	return err
+}
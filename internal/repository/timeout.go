@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"cruder/internal/metrics"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// queryCanceled is the PostgreSQL error code of a statement cancelled by statement_timeout
+const queryCanceled = "57014"
+
+var statementTimeouts = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "db_statement_timeouts_total",
+	Help: "Queries PostgreSQL cancelled because the request's deadline passed.",
+})
+
+// querier is what read queries need from *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// withDeadline runs the queries of fn under the request deadline. Within a unit
+// of work they run in its transaction, whose statement_timeout was set when it
+// began; otherwise, with a deadline, in a transaction of inTx, which sets it, and
+// without one on db directly.
+func withDeadline(ctx context.Context, db tracedDB, fn func(conn querier) error) error {
+	if _, ok := ctx.Deadline(); !ok && txFor(ctx, db.DB) == nil {
+		return fn(db)
+	}
+	return inTx(ctx, db.DB, func(tx *sql.Tx) error {
+		return fn(tracedTx{tx})
+	})
+}
+
+// setStatementTimeout sets the statement_timeout of tx to the time left until ctx's
+// deadline, if it has one. Cancelling the context alone only asks the server to
+// stop; the timeout makes PostgreSQL give up on a slow statement by itself,
+// releasing its locks and connection even if the cancel request is lost.
+func setStatementTimeout(ctx context.Context, tx *sql.Tx) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	// statement_timeout counts whole milliseconds and 0 disables it
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 1 {
+		return context.DeadlineExceeded
+	}
+	// SET does not take bind parameters; remaining is an integer we computed
+	_, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", remaining))
+	return err
+}
+
+// timedOut counts err and says so when PostgreSQL cancelled a statement because
+// the request's deadline passed; other errors are returned as they are
+func timedOut(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == queryCanceled {
+		statementTimeouts.Inc()
+		return fmt.Errorf("query exceeded the request deadline: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"cruder/internal/tracing"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedDB records a span with the SQL text of each query run on the database.
+// Only the statement is recorded: values are bound as parameters and stay out of
+// the trace. Queries whose context carries a unit of work on the database run in
+// its transaction (see TxManager).
+type tracedDB struct {
+	DB
+}
+
+// conn is what tracedDB runs a query on: the database or the transaction of ctx
+type conn interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (db tracedDB) conn(ctx context.Context) conn {
+	if tx := txFor(ctx, db.DB); tx != nil {
+		return tx
+	}
+	return db.DB
+}
+
+func (db tracedDB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := db.conn(ctx).QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (db tracedDB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	row := db.conn(ctx).QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+func (db tracedDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	result, err := db.conn(ctx).ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+// tracedTx records a span for each query run in a transaction, like tracedDB
+type tracedTx struct {
+	*sql.Tx
+}
+
+func (tx tracedTx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	ctx, span := startQuery(ctx, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (tx tracedTx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	ctx, span := startQuery(ctx, query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+func (tx tracedTx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	ctx, span := startQuery(ctx, query)
+	result, err := tx.Tx.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+// startQuery starts a client span named after the statement's leading keyword
+func startQuery(ctx context.Context, query string) (context.Context, trace.Span) {
+	var operation string
+	if fields := strings.Fields(query); len(fields) > 0 {
+		operation = strings.ToUpper(fields[0])
+	}
+	return tracing.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(strings.TrimSpace(query)),
+	))
+}
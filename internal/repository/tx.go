@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// TxManager runs units of work: service methods that read, check and then write
+// must do so in one transaction, or a concurrent request can change what they
+// checked before they write
+type TxManager interface {
+	// InTx runs fn in a serializable transaction and commits it, with a
+	// statement_timeout of the time left until ctx's deadline. The user
+	// repository, and every transaction a repository opens on the manager's
+	// database, run in it when given the context passed to fn. When PostgreSQL
+	// aborts it because of a concurrent transaction, fn runs again, so it must not
+	// have effects outside the database; those go after InTx returns. A nested
+	// InTx joins the outer transaction.
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txManager struct {
+	db DB
+}
+
+// NewTxManager creates a TxManager for the repositories on db
+func NewTxManager(db DB) TxManager {
+	return &txManager{db: db}
+}
+
+// unitOfWork is the transaction a context carries, with the database it was
+// begun on and what waits for its commit
+type unitOfWork struct {
+	db          DB
+	tx          *sql.Tx
+	afterCommit []func()
+}
+
+type unitOfWorkKey struct{}
+
+func (m *txManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFor(ctx, m.db) != nil {
+		return fn(ctx)
+	}
+	var committed *unitOfWork
+	err := retryTx(ctx, func() error {
+		tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+		if err := setStatementTimeout(ctx, tx); err != nil {
+			return err
+		}
+
+		// Each attempt starts afresh, dropping what a failed one left to run
+		uow := &unitOfWork{db: m.db, tx: tx}
+		if err := fn(context.WithValue(ctx, unitOfWorkKey{}, uow)); err != nil {
+			return timedOut(err)
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		committed = uow
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range committed.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// txFor returns the transaction of ctx's unit of work if it runs on db
+func txFor(ctx context.Context, db DB) *sql.Tx {
+	uow, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork)
+	if !ok || uow.db != db {
+		return nil
+	}
+	return uow.tx
+}
+
+// afterCommit runs fn once ctx's unit of work has committed, or right away
+// outside one, for effects that must not see or outlive uncommitted rows
+func afterCommit(ctx context.Context, fn func()) {
+	if uow, ok := ctx.Value(unitOfWorkKey{}).(*unitOfWork); ok {
+		uow.afterCommit = append(uow.afterCommit, fn)
+		return
+	}
+	fn()
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"cruder/internal/migrations"
+	"cruder/internal/model"
+
+	_ "github.com/lib/pq"
+)
+
+func TestUnitOfWork_OtherDatabase(t *testing.T) {
+	// Given: A unit of work on one database
+	home, other := &sql.DB{}, &sql.DB{}
+	tx := &sql.Tx{}
+	ctx := context.WithValue(context.Background(), unitOfWorkKey{}, &unitOfWork{db: home, tx: tx})
+
+	// Then: Only repositories on that database join it
+	if txFor(ctx, home) != tx {
+		t.Error("expected the transaction for its own database")
+	}
+	if txFor(ctx, other) != nil {
+		t.Error("expected no transaction for another database")
+	}
+	if txFor(context.Background(), home) != nil {
+		t.Error("expected no transaction outside a unit of work")
+	}
+}
+
+func TestAfterCommit_OutsideUnitOfWork(t *testing.T) {
+	ran := false
+
+	afterCommit(context.Background(), func() { ran = true })
+
+	if !ran {
+		t.Error("expected fn to run right away")
+	}
+}
+
+// TestTxManager needs a database; set TEST_DATABASE_URL to run it
+func TestTxManager(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	ctx := context.Background()
+	if err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+	t.Cleanup(func() { _, _ = db.Exec(`DELETE FROM users WHERE username IN ('tx_rolled_back', 'tx_committed')`) })
+	repo := NewUserRepository(db)
+	tx := NewTxManager(db)
+
+	// When: A unit of work fails after creating a user
+	committed := false
+	failure := errors.New("check failed")
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &model.User{Username: "tx_rolled_back", Email: "tx_rolled_back@example.com"}); err != nil {
+			return err
+		}
+		afterCommit(ctx, func() { committed = true })
+		return failure
+	})
+
+	// Then: The user is gone and nothing waited for the commit ran
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the unit of work's error, got %v", err)
+	}
+	if _, err := repo.GetByUsername(ctx, "tx_rolled_back"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected the user to be rolled back, got %v", err)
+	}
+	if committed {
+		t.Error("expected no after-commit effects")
+	}
+
+	// When: A unit of work creates a user and reads it back
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		if err := repo.Create(ctx, &model.User{Username: "tx_committed", Email: "tx_committed@example.com"}); err != nil {
+			return err
+		}
+		_, err := repo.GetByUsername(ctx, "tx_committed")
+		afterCommit(ctx, func() { committed = true })
+		return err
+	})
+
+	// Then: It saw its own write, and the commit made it visible
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := repo.GetByUsername(ctx, "tx_committed"); err != nil {
+		t.Errorf("expected the committed user, got %v", err)
+	}
+	if !committed {
+		t.Error("expected the after-commit effects to run")
+	}
+}
+
+// TestTxManager_StatementTimeout needs a database; set TEST_DATABASE_URL to run it
+func TestTxManager_StatementTimeout(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	tx := NewTxManager(db)
+
+	// When: A unit of work runs with a deadline
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var timeout string
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		return txFor(ctx, db).QueryRowContext(ctx, `SHOW statement_timeout`).Scan(&timeout)
+	})
+
+	// Then: Its statements are bounded by the time left
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timeout == "0" || timeout == "" {
+		t.Errorf("expected a statement_timeout, got %q", timeout)
+	}
+
+	// When: The deadline passes during a statement
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	err = tx.InTx(ctx, func(ctx context.Context) error {
+		_, err := txFor(ctx, db).ExecContext(context.Background(), `SELECT pg_sleep(5)`)
+		return err
+	})
+
+	// Then: PostgreSQL cancels it by itself
+	if err == nil || !strings.Contains(err.Error(), "request deadline") {
+		t.Errorf("expected the statement to time out, got %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"time"
+)
+
+type UsageRepository interface {
+	// Add merges the counts of records into the stored daily totals
+	Add(ctx context.Context, records []model.UsageRecord) error
+	// List returns daily totals between from and to (inclusive); an empty apiKey matches all keys
+	List(ctx context.Context, from, to time.Time, apiKey string) ([]model.UsageRecord, error)
+}
+
+type usageRepository struct {
+	db *sql.DB
+}
+
+func NewUsageRepository(db *sql.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (r *usageRepository) Add(ctx context.Context, records []model.UsageRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx,
+			`INSERT INTO api_usage (day, api_key, method, route, requests, errors, total_duration_ms)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (day, api_key, method, route) DO UPDATE SET
+				requests = api_usage.requests + EXCLUDED.requests,
+				errors = api_usage.errors + EXCLUDED.errors,
+				total_duration_ms = api_usage.total_duration_ms + EXCLUDED.total_duration_ms`)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, rec := range records {
+			if _, err := stmt.ExecContext(ctx,
+				rec.Day, rec.APIKey, rec.Method, rec.Route, rec.Requests, rec.Errors, rec.TotalDurationMs); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *usageRepository) List(ctx context.Context, from, to time.Time, apiKey string) ([]model.UsageRecord, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT day, api_key, method, route, requests, errors, total_duration_ms FROM api_usage
+		WHERE day BETWEEN $1 AND $2 AND ($3 = '' OR api_key = $3)
+		ORDER BY day, api_key, route, method`, from, to, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var records []model.UsageRecord
+	for rows.Next() {
+		var rec model.UsageRecord
+		if err := rows.Scan(&rec.Day, &rec.APIKey, &rec.Method, &rec.Route, &rec.Requests, &rec.Errors, &rec.TotalDurationMs); err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
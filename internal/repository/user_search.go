@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"strings"
+)
+
+type UserSearchRepository interface {
+	// Search returns users whose normalized username, email or full name contains
+	// term; exact and prefix username matches come first
+	Search(ctx context.Context, term string, limit int) ([]model.User, error)
+	// Autocomplete returns users whose normalized username or full name starts with
+	// prefix; username matches come first
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]model.UserSuggestion, error)
+	Upsert(ctx context.Context, entry model.UserSearchEntry) error
+	Delete(ctx context.Context, uuid string) error
+	// Replace swaps the whole table for entries, read at position, in one transaction
+	Replace(ctx context.Context, entries []model.UserSearchEntry, position string) error
+	// Applied records that every change up to position has been applied
+	Applied(ctx context.Context, position string) error
+	// Covers reports whether every change up to position has been applied
+	Covers(ctx context.Context, position string) (bool, error)
+}
+
+// userSearchModel names the table in read_model_positions
+const userSearchModel = "user_search"
+
+type userSearchRepository struct {
+	db tracedDB
+}
+
+func NewUserSearchRepository(db DB) UserSearchRepository {
+	return &userSearchRepository{db: tracedDB{db}}
+}
+
+const upsertUserSearch = `INSERT INTO user_search (user_uuid, user_id, username, email, full_name,
+		username_norm, email_norm, full_name_norm, search_text)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $6 || ' ' || $7 || ' ' || $8)
+	ON CONFLICT (user_uuid) DO UPDATE SET user_id = EXCLUDED.user_id, username = EXCLUDED.username,
+		email = EXCLUDED.email, full_name = EXCLUDED.full_name, username_norm = EXCLUDED.username_norm,
+		email_norm = EXCLUDED.email_norm, full_name_norm = EXCLUDED.full_name_norm,
+		search_text = EXCLUDED.search_text, refreshed_at = CURRENT_TIMESTAMP`
+
+// likeEscaper makes LIKE wildcards in search terms match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *userSearchRepository) Search(ctx context.Context, term string, limit int) ([]model.User, error) {
+	escaped := likeEscaper.Replace(term)
+	var users []model.User
+	err := withDeadline(ctx, r.db, func(conn querier) error {
+		rows, err := conn.QueryContext(ctx,
+			`SELECT user_id, user_uuid, username, email, full_name FROM user_search
+			WHERE search_text LIKE '%' || $1 || '%'
+			ORDER BY username_norm = $2 DESC, username_norm LIKE $1 || '%' DESC, username_norm
+			LIMIT $3`, escaped, term, limit)
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, rows)
+
+		for rows.Next() {
+			var u model.User
+			if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+				return err
+			}
+			users = append(users, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (r *userSearchRepository) Autocomplete(ctx context.Context, prefix string, limit int) ([]model.UserSuggestion, error) {
+	escaped := likeEscaper.Replace(prefix)
+	var suggestions []model.UserSuggestion
+	err := withDeadline(ctx, r.db, func(conn querier) error {
+		rows, err := conn.QueryContext(ctx,
+			`SELECT user_uuid, username, full_name FROM user_search
+			WHERE username_norm LIKE $1 || '%' OR full_name_norm LIKE $1 || '%'
+			ORDER BY username_norm LIKE $1 || '%' DESC, username_norm
+			LIMIT $2`, escaped, limit)
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, rows)
+
+		for rows.Next() {
+			var s model.UserSuggestion
+			if err := rows.Scan(&s.UUID, &s.Username, &s.FullName); err != nil {
+				return err
+			}
+			suggestions = append(suggestions, s)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+func (r *userSearchRepository) Upsert(ctx context.Context, e model.UserSearchEntry) error {
+	_, err := r.db.ExecContext(ctx, upsertUserSearch,
+		e.UUID, e.ID, e.Username, e.Email, e.FullName, e.UsernameNorm, e.EmailNorm, e.FullNameNorm)
+	return err
+}
+
+func (r *userSearchRepository) Delete(ctx context.Context, uuid string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM user_search WHERE user_uuid = $1`, uuid)
+	return err
+}
+
+func (r *userSearchRepository) Replace(ctx context.Context, entries []model.UserSearchEntry, position string) error {
+	return inTx(ctx, r.db.DB, func(tx *sql.Tx) error {
+		// Searches keep reading the old rows until the transaction commits
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_search`); err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, upsertUserSearch)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = stmt.Close() }()
+
+		for _, e := range entries {
+			if _, err := stmt.ExecContext(ctx,
+				e.UUID, e.ID, e.Username, e.Email, e.FullName, e.UsernameNorm, e.EmailNorm, e.FullNameNorm); err != nil {
+				return err
+			}
+		}
+		if position != "" {
+			return applied(ctx, tx, position)
+		}
+		return nil
+	})
+}
+
+func (r *userSearchRepository) Applied(ctx context.Context, position string) error {
+	return applied(ctx, r.db, position)
+}
+
+func applied(ctx context.Context, db interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}, position string) error {
+	// Events are applied concurrently, so the position only ever moves forward
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO read_model_positions (name, position) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET position = GREATEST(read_model_positions.position, EXCLUDED.position),
+			updated_at = CURRENT_TIMESTAMP`, userSearchModel, position)
+	return err
+}
+
+func (r *userSearchRepository) Covers(ctx context.Context, position string) (bool, error) {
+	var covered bool
+	err := r.db.QueryRowContext(ctx,
+		`SELECT position >= $2::pg_lsn FROM read_model_positions WHERE name = $1`, userSearchModel, position).Scan(&covered)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return covered, err
+}
@@ -3,60 +3,188 @@ package repository
 import (
 	"context"
 	"cruder/internal/model"
+	"cruder/internal/residency"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
-	"log"
+	"github.com/lib/pq"
 )
 
 type UserRepository interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	GetByUUID(uuid string) (*model.User, error) // Task3
-	Create(user *model.User) error              // Task3
-	Update(uuid string, user *model.User) error // Task3
-	Delete(uuid string) error                   // Task3
+	GetAll(ctx context.Context) ([]model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	// GetByEmail finds the live user whose normalized email is emailNorm
+	GetByEmail(ctx context.Context, emailNorm string) (*model.User, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByUUID(ctx context.Context, uuid string) (*model.User, error) // Task3
+	// Find returns users matching every custom field filter, in the query's sort order
+	Find(ctx context.Context, query model.UserQuery) ([]model.User, error)
+	// Create inserts user; a username or email taken by a live user fails with a
+	// *DuplicateError, as do Update, Restore and the rows of CreateMany
+	Create(ctx context.Context, user *model.User) error // Task3
+	// Update writes the fields present in patch and leaves the others as stored
+	Update(ctx context.Context, uuid string, patch model.UserPatch) error
+	// Delete soft-deletes a user: the row stays, with deleted_at set, until purged
+	Delete(ctx context.Context, uuid string) error // Task3
+	// DeleteMany soft-deletes users in one transaction and returns one error per
+	// UUID, sql.ErrNoRows for users that are missing or deleted already. With
+	// allOrNothing a missing user rolls the batch back, and the other UUIDs get
+	// ErrBatchRolledBack.
+	DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error)
+	// CreateMany inserts users in one transaction, each under its own savepoint, so
+	// a row that fails only undoes itself. It returns one error per user, nil for
+	// those created.
+	CreateMany(ctx context.Context, users []*model.User) ([]error, error)
+	// Restore clears deleted_at of a soft-deleted user
+	Restore(ctx context.Context, uuid string) error
+	// Purge removes up to limit users soft-deleted before the cutoff for good and
+	// returns their UUIDs
+	Purge(ctx context.Context, before time.Time, limit int) ([]string, error)
+	// FindDeleted returns the most recently deleted user with username, or
+	// sql.ErrNoRows if no deleted user has it
+	FindDeleted(ctx context.Context, username string) (*model.DeletedUser, error)
+	// ListDeleted returns soft-deleted users, newest first, and their total count
+	ListDeleted(ctx context.Context, limit, offset int) ([]model.DeletedUser, int, error)
+	// Aggregate counts users per value of groupBy: a key of userGroupColumns or
+	// "cf.<name>" for a custom field
+	Aggregate(ctx context.Context, groupBy string) ([]model.AggregateBucket, error)
+	// Sample returns up to n users chosen uniformly at random
+	Sample(ctx context.Context, n int) ([]model.User, error)
+	// Count returns the number of live users of every region
+	Count(ctx context.Context) (int, error)
+}
+
+// ErrBatchRolledBack is returned for the users of an all-or-nothing batch that
+// were undone because another user failed
+var ErrBatchRolledBack = errors.New("batch rolled back")
+
+// ErrDuplicate is matched by every *DuplicateError
+var ErrDuplicate = errors.New("duplicate key")
+
+// DuplicateError reports a write that would give a live user the username or email
+// of another, found by the unique indexes rather than by looking first, so it also
+// catches concurrent writes
+type DuplicateError struct {
+	// Column is username or email; empty for a constraint not in uniqueUserColumns
+	Column string
+	Err    error
+}
+
+func (e *DuplicateError) Error() string {
+	if e.Column == "" {
+		return "duplicate key: " + e.Err.Error()
+	}
+	return e.Column + " already exists"
+}
+
+func (e *DuplicateError) Is(target error) bool { return target == ErrDuplicate }
+
+func (e *DuplicateError) Unwrap() error { return e.Err }
+
+// uniqueUserColumns maps the unique constraints of users to their column. The
+// *_key constraints predate uniqueness among live users only and remain on
+// databases not migrated since.
+var uniqueUserColumns = map[string]string{
+	"idx_users_username_live":   "username",
+	"idx_users_email_live":      "email",
+	"idx_users_email_norm_live": "email",
+	"users_username_key":        "username",
+	"users_email_key":           "email",
+}
+
+// duplicate returns a unique violation as a *DuplicateError and other errors as
+// they are
+func duplicate(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "23505" {
+		return err
+	}
+	return &DuplicateError{Column: uniqueUserColumns[pqErr.Constraint], Err: err}
+}
+
+// userSortExpressions are the sort fields that are not plain columns
+var userSortExpressions = map[string]string{
+	"updated_at": userUpdatedAt,
+}
+
+// userGroupColumns maps group_by values to SQL expressions; nothing else reaches the query text
+var userGroupColumns = map[string]string{
+	"created_month": `to_char(date_trunc('month', created_at), 'YYYY-MM')`,
 }
 
 type userRepository struct {
-	db *sql.DB
+	db tracedDB
 }
 
-func NewUserRepository(db *sql.DB) UserRepository {
-	return &userRepository{db: db}
+// userColumns are the columns of model.User. updated_at is NULL until the first
+// change, which the trigger of its migration records, as another bumps version.
+// version is qualified since joined tables may have one too.
+const userColumns = `id, uuid, username, email, full_name, custom_fields, region, created_at, ` + userUpdatedAt + `, users.version`
+
+// userUpdatedAt is when a user last changed, or was created if it never did
+const userUpdatedAt = `COALESCE(updated_at, created_at)`
+
+// liveUsers restricts a query to users that are not soft-deleted
+const liveUsers = `deleted_at IS NULL`
+
+// scanUser reads a row selected with userColumns, followed by any extra destinations
+func scanUser(row interface{ Scan(...any) error }, u *model.User, extra ...any) error {
+	var customFields []byte
+	// created_at has a default but no NOT NULL constraint
+	var createdAt, updatedAt sql.NullTime
+	dest := append([]any{&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName, &customFields, &u.Region, &createdAt, &updatedAt, &u.Version}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	u.CreatedAt, u.UpdatedAt = createdAt.Time, updatedAt.Time
+	return json.Unmarshal(customFields, &u.CustomFields)
 }
 
-func (r *userRepository) GetAll() ([]model.User, error) {
-	rows, err := r.db.QueryContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users`)
-	if err != nil {
-		return nil, err
+// customFieldsJSON encodes custom field values for the JSONB column
+func customFieldsJSON(values map[string]any) ([]byte, error) {
+	if values == nil {
+		return []byte("{}"), nil
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			log.Printf("failed to close rows: %v", err)
-		}
-	}()
+	return json.Marshal(values)
+}
+
+func NewUserRepository(db DB) UserRepository {
+	return &userRepository{db: tracedDB{db}}
+}
 
+func (r *userRepository) GetAll(ctx context.Context) ([]model.User, error) {
 	var users []model.User
-	for rows.Next() {
-		var u model.User
-		if err := rows.Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
-			return nil, err
+	err := withDeadline(ctx, r.db, func(conn querier) error {
+		rows, err := conn.QueryContext(ctx, `SELECT `+userColumns+` FROM users WHERE `+liveUsers)
+		if err != nil {
+			return err
 		}
-		users = append(users, u)
-	}
+		defer closeRows(ctx, rows)
 
-	if err := rows.Err(); err != nil {
+		for rows.Next() {
+			var u model.User
+			if err := scanUser(rows, &u); err != nil {
+				return err
+			}
+			users = append(users, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return users, nil
 }
 
-func (r *userRepository) GetByUsername(username string) (*model.User, error) {
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users WHERE username = $1`, username).
-		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE username = $1 AND `+liveUsers, username), &u); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, err
 		}
@@ -65,10 +193,17 @@ func (r *userRepository) GetByUsername(username string) (*model.User, error) {
 	return &u, nil
 }
 
-func (r *userRepository) GetByID(id int64) (*model.User, error) {
+func (r *userRepository) GetByEmail(ctx context.Context, emailNorm string) (*model.User, error) {
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(), `SELECT id, uuid, username, email, full_name FROM users WHERE id = $1`, id).
-		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email_norm = $1 AND `+liveUsers, emailNorm), &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	var u model.User
+	if err := scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 AND `+liveUsers, id), &u); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, err
 		}
@@ -77,11 +212,10 @@ func (r *userRepository) GetByID(id int64) (*model.User, error) {
 	return &u, nil
 }
 
-func (r *userRepository) GetByUUID(uuid string) (*model.User, error) {
+func (r *userRepository) GetByUUID(ctx context.Context, uuid string) (*model.User, error) {
 	var u model.User
-	if err := r.db.QueryRowContext(context.Background(),
-		`SELECT id, uuid, username, email, full_name FROM users WHERE uuid = $1`, uuid).
-		Scan(&u.ID, &u.UUID, &u.Username, &u.Email, &u.FullName); err != nil {
+	if err := scanUser(r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users WHERE uuid = $1 AND `+liveUsers, uuid), &u); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, err
 		}
@@ -90,26 +224,285 @@ func (r *userRepository) GetByUUID(uuid string) (*model.User, error) {
 	return &u, nil
 }
 
-func (r *userRepository) Create(user *model.User) error {
-	return r.db.QueryRowContext(context.Background(),
-		`INSERT INTO users (username, email, full_name) VALUES ($1, $2, $3) RETURNING id, uuid`,
-		user.Username, user.Email, user.FullName).
-		Scan(&user.ID, &user.UUID)
+func (r *userRepository) Create(ctx context.Context, user *model.User) error {
+	return insertUser(ctx, r.db, user)
 }
 
-func (r *userRepository) Update(uuid string, user *model.User) error {
-	_, err := r.db.ExecContext(context.Background(),
-		`UPDATE users SET username = $1, email = $2, full_name = $3 WHERE uuid = $4`,
-		user.Username, user.Email, user.FullName, uuid)
-	return err
+func (r *userRepository) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	errs := make([]error, len(users))
+	err := inTx(ctx, r.db.DB, func(sqlTx *sql.Tx) error {
+		tx := tracedTx{sqlTx}
+		for i, user := range users {
+			// A failed statement aborts the whole transaction; rolling back to the
+			// savepoint taken before the row undoes the row alone
+			if _, err := tx.ExecContext(ctx, `SAVEPOINT bulk_item`); err != nil {
+				return err
+			}
+			errs[i] = insertUser(ctx, tx, user)
+			if _, retryable := conflict(errs[i]); retryable {
+				// Deadlocks are retried with the whole transaction
+				return errs[i]
+			}
+			if errs[i] != nil {
+				if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT bulk_item`); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT bulk_item`); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+// rowQuerier runs statements returning one row, on the database or in a transaction
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// emailNorm returns norm, or for writers that did not normalize the email, such as
+// tests, the email lower-cased like the migration that added email_norm
+func emailNorm(email, norm string) string {
+	if norm != "" {
+		return norm
+	}
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
-func (r *userRepository) Delete(uuid string) error {
-	result, err := r.db.ExecContext(context.Background(),
-		`DELETE FROM users WHERE uuid = $1`, uuid)
+// insertUser creates user in the region of ctx, whatever region the client sent
+func insertUser(ctx context.Context, db rowQuerier, user *model.User) error {
+	customFields, err := customFieldsJSON(user.CustomFields)
 	if err != nil {
 		return err
 	}
+	user.Region = residency.Region(ctx)
+	return duplicate(db.QueryRowContext(ctx,
+		`INSERT INTO users (username, email, email_norm, full_name, custom_fields, region) VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, uuid, created_at, `+userUpdatedAt+`, version`,
+		user.Username, user.Email, emailNorm(user.Email, user.EmailNorm), user.FullName, customFields, user.Region).
+		Scan(&user.ID, &user.UUID, &user.CreatedAt, &user.UpdatedAt, &user.Version))
+}
+
+func (r *userRepository) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	query, args, err := updateUser(uuid, patch)
+	if err != nil || query == "" {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, query, args...)
+	return duplicate(err)
+}
+
+// updateUser builds an UPDATE of the columns present in patch; it returns an empty
+// query for an empty patch. Custom fields are merged into the stored object and
+// those set to null are removed.
+func updateUser(uuid string, patch model.UserPatch) (string, []any, error) {
+	var sets []string
+	var args []any
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf(`%s = $%d`, column, len(args)))
+	}
+	if patch.Username != nil {
+		set("username", *patch.Username)
+	}
+	if patch.Email != nil {
+		set("email", *patch.Email)
+		var norm string
+		if patch.EmailNorm != nil {
+			norm = *patch.EmailNorm
+		}
+		set("email_norm", emailNorm(*patch.Email, norm))
+	}
+	if patch.FullName != nil {
+		set("full_name", *patch.FullName)
+	}
+	if len(patch.CustomFields) > 0 {
+		values := make(map[string]any, len(patch.CustomFields))
+		removed := []string{}
+		for name, value := range patch.CustomFields {
+			if value == nil {
+				removed = append(removed, name)
+				continue
+			}
+			values[name] = value
+		}
+		sort.Strings(removed)
+		customFields, err := customFieldsJSON(values)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, customFields, pq.Array(removed))
+		sets = append(sets, fmt.Sprintf(`custom_fields = (custom_fields || $%d::jsonb) - $%d::text[]`, len(args)-1, len(args)))
+	}
+	if len(sets) == 0 {
+		return "", nil, nil
+	}
+	args = append(args, uuid)
+	return fmt.Sprintf(`UPDATE users SET %s WHERE uuid = $%d AND `+liveUsers, strings.Join(sets, ", "), len(args)), args, nil
+}
+
+func (r *userRepository) Find(ctx context.Context, q model.UserQuery) ([]model.User, error) {
+	// Field names are bound as parameters too, so no user input reaches the SQL text.
+	// The ? check lets the GIN index narrow the rows before the text comparison.
+	names := make([]string, 0, len(q.CustomFields))
+	for name := range q.CustomFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	query := `SELECT ` + userColumns + `, deleted_at FROM users WHERE TRUE`
+	if !q.IncludeDeleted {
+		query += ` AND ` + liveUsers
+	}
+	args := make([]any, 0, 2*len(names)+5)
+	for _, name := range names {
+		args = append(args, name, q.CustomFields[name])
+		query += fmt.Sprintf(` AND custom_fields ? $%[1]d AND custom_fields->>$%[1]d = $%[2]d`, len(args)-1, len(args))
+	}
+	if q.EmailDomain != "" {
+		args = append(args, "%@"+likeEscaper.Replace(q.EmailDomain))
+		query += fmt.Sprintf(` AND email ILIKE $%d`, len(args))
+	}
+	if q.FullName != "" {
+		args = append(args, "%"+likeEscaper.Replace(q.FullName)+"%")
+		query += fmt.Sprintf(` AND full_name ILIKE $%d`, len(args))
+	}
+	if q.CreatedFrom != nil {
+		args = append(args, *q.CreatedFrom)
+		query += fmt.Sprintf(` AND created_at >= $%d`, len(args))
+	}
+	if q.CreatedTo != nil {
+		args = append(args, *q.CreatedTo)
+		query += fmt.Sprintf(` AND created_at < $%d`, len(args))
+	}
+	if q.UpdatedFrom != nil {
+		args = append(args, *q.UpdatedFrom)
+		query += fmt.Sprintf(` AND `+userUpdatedAt+` >= $%d`, len(args))
+	}
+	if q.UpdatedTo != nil {
+		args = append(args, *q.UpdatedTo)
+		query += fmt.Sprintf(` AND `+userUpdatedAt+` < $%d`, len(args))
+	}
+	if q.Q != "" {
+		args = append(args, "%"+likeEscaper.Replace(q.Q)+"%")
+		query += fmt.Sprintf(` AND (username ILIKE $%[1]d OR email ILIKE $%[1]d OR full_name ILIKE $%[1]d)`, len(args))
+	}
+
+	// Sort keys are checked against model.UserSortFields, which match column names
+	// but for those in userSortExpressions
+	keys, err := q.SortKeys()
+	if err != nil {
+		return nil, err
+	}
+	order := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		column := key.Field
+		if expr, ok := userSortExpressions[column]; ok {
+			column = expr
+		}
+		if key.Desc {
+			order = append(order, column+" DESC")
+		} else {
+			order = append(order, column)
+		}
+	}
+	order = append(order, "id")
+
+	var users []model.User
+	err = withDeadline(ctx, r.db, func(conn querier) error {
+		rows, err := conn.QueryContext(ctx, query+` ORDER BY `+strings.Join(order, ", "), args...)
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, rows)
+
+		for rows.Next() {
+			var u model.User
+			var deletedAt sql.NullTime
+			if err := scanUser(rows, &u, &deletedAt); err != nil {
+				return err
+			}
+			if deletedAt.Valid {
+				u.DeletedAt = &deletedAt.Time
+			}
+			users = append(users, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// softDeleteUser marks a live user deleted and drops a deletion scheduled for it,
+// which the ON DELETE CASCADE would otherwise only remove at purge time
+const softDeleteUser = `WITH deleted AS (
+		UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE uuid = $1 AND deleted_at IS NULL RETURNING uuid
+	), unscheduled AS (
+		DELETE FROM scheduled_deletions WHERE user_uuid IN (SELECT uuid FROM deleted)
+	)
+	SELECT COUNT(*) FROM deleted`
+
+func (r *userRepository) Delete(ctx context.Context, uuid string) error {
+	var rows int
+	if err := r.db.QueryRowContext(ctx, softDeleteUser, uuid).Scan(&rows); err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *userRepository) DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error) {
+	var errs []error
+	err := inTx(ctx, r.db.DB, func(sqlTx *sql.Tx) error {
+		tx := tracedTx{sqlTx}
+		// A retried transaction starts over
+		errs = make([]error, len(uuids))
+		failed := false
+		for i, uuid := range uuids {
+			var rows int
+			if err := tx.QueryRowContext(ctx, softDeleteUser, uuid).Scan(&rows); err != nil {
+				return err
+			}
+			if rows == 0 {
+				errs[i] = sql.ErrNoRows
+				failed = true
+			}
+		}
+		if allOrNothing && failed {
+			return ErrBatchRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, ErrBatchRolledBack) {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = ErrBatchRolledBack
+			}
+		}
+		return errs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+func (r *userRepository) Restore(ctx context.Context, uuid string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET deleted_at = NULL WHERE uuid = $1 AND deleted_at IS NOT NULL`, uuid)
+	if err != nil {
+		return duplicate(err)
+	}
 	rows, err := result.RowsAffected()
 	if err != nil {
 		return err
@@ -119,3 +512,154 @@ func (r *userRepository) Delete(uuid string) error {
 	}
 	return nil
 }
+
+func (r *userRepository) Purge(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`DELETE FROM users WHERE uuid IN (
+			SELECT uuid FROM users WHERE deleted_at < $1 ORDER BY deleted_at LIMIT $2
+		) RETURNING uuid`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	var purged []string
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			return nil, err
+		}
+		purged = append(purged, uuid)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return purged, nil
+}
+
+func (r *userRepository) FindDeleted(ctx context.Context, username string) (*model.DeletedUser, error) {
+	var u model.DeletedUser
+	if err := scanUser(r.db.QueryRowContext(ctx,
+		`SELECT `+userColumns+`, deleted_at FROM users
+		WHERE username = $1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC LIMIT 1`, username), &u.User, &u.DeletedAt); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *userRepository) ListDeleted(ctx context.Context, limit, offset int) ([]model.DeletedUser, int, error) {
+	var total int
+	if err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM users WHERE deleted_at IS NOT NULL`).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+userColumns+`, deleted_at FROM users
+		WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer closeRows(ctx, rows)
+
+	var users []model.DeletedUser
+	for rows.Next() {
+		var u model.DeletedUser
+		if err := scanUser(rows, &u.User, &u.DeletedAt); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *userRepository) Aggregate(ctx context.Context, groupBy string) ([]model.AggregateBucket, error) {
+	var expr string
+	var args []any
+	if name, ok := strings.CutPrefix(groupBy, "cf."); ok {
+		expr = `custom_fields->>$1`
+		args = append(args, name)
+	} else if column, ok := userGroupColumns[groupBy]; ok {
+		expr = column
+	} else {
+		return nil, fmt.Errorf("unsupported group_by %q", groupBy)
+	}
+
+	var buckets []model.AggregateBucket
+	err := withDeadline(ctx, r.db, func(conn querier) error {
+		rows, err := conn.QueryContext(ctx,
+			`SELECT `+expr+` AS bucket, COUNT(*) FROM users WHERE `+liveUsers+` GROUP BY bucket ORDER BY bucket NULLS LAST`, args...)
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, rows)
+
+		for rows.Next() {
+			var b model.AggregateBucket
+			var key sql.NullString
+			if err := rows.Scan(&key, &b.Count); err != nil {
+				return err
+			}
+			if key.Valid {
+				b.Key = &key.String
+			}
+			buckets = append(buckets, b)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	// A nil Regions counts the one database once
+	regions, _ := r.db.DB.(*Regions)
+	var total int
+	err := regions.Each(ctx, func(ctx context.Context) error {
+		var n int
+		if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+liveUsers).Scan(&n); err != nil {
+			return err
+		}
+		total += n
+		return nil
+	})
+	return total, err
+}
+
+func (r *userRepository) Sample(ctx context.Context, n int) ([]model.User, error) {
+	// ORDER BY random() reads the whole table but gives an exact, uniform sample;
+	// the service bounds n so the sort stays a top-n heap
+	var users []model.User
+	err := withDeadline(ctx, r.db, func(conn querier) error {
+		rows, err := conn.QueryContext(ctx,
+			`SELECT `+userColumns+` FROM users WHERE `+liveUsers+` ORDER BY random() LIMIT $1`, n)
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, rows)
+
+		for rows.Next() {
+			var u model.User
+			if err := scanUser(rows, &u); err != nil {
+				return err
+			}
+			users = append(users, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"cruder/internal/model"
+
+	"github.com/lib/pq"
+)
+
+func TestUpdateUser(t *testing.T) {
+	email := "New@example.com"
+	norm := "new@example.com"
+	name := ""
+
+	tests := []struct {
+		name      string
+		patch     model.UserPatch
+		wantQuery string
+		wantArgs  int
+	}{
+		{"empty patch writes nothing", model.UserPatch{}, "", 0},
+		{"only present columns are set", model.UserPatch{Email: &email, EmailNorm: &norm, FullName: &name},
+			`UPDATE users SET email = $1, email_norm = $2, full_name = $3 WHERE uuid = $4 AND ` + liveUsers, 4},
+		{"custom fields are merged and null ones removed", model.UserPatch{CustomFields: map[string]any{"team": "core", "level": nil}},
+			`UPDATE users SET custom_fields = (custom_fields || $1::jsonb) - $2::text[] WHERE uuid = $3 AND ` + liveUsers, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			query, args, err := updateUser("u-1", tt.patch)
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if query != tt.wantQuery || len(args) != tt.wantArgs {
+				t.Errorf("expected %q with %d args, got %q with %v", tt.wantQuery, tt.wantArgs, query, args)
+			}
+			if tt.wantArgs > 0 && args[len(args)-1] != "u-1" {
+				t.Errorf("expected the uuid as last argument, got %v", args)
+			}
+			if tt.patch.Email != nil && args[1] != norm {
+				t.Errorf("expected the normalized email as second argument, got %v", args)
+			}
+		})
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	other := errors.New("connection reset")
+
+	tests := []struct {
+		name       string
+		err        error
+		wantColumn string
+		wantDup    bool
+	}{
+		{"email index", &pq.Error{Code: "23505", Constraint: "idx_users_email_live"}, "email", true},
+		{"normalized email index", &pq.Error{Code: "23505", Constraint: "idx_users_email_norm_live"}, "email", true},
+		{"username index", &pq.Error{Code: "23505", Constraint: "idx_users_username_live"}, "username", true},
+		{"constraint of an older schema", &pq.Error{Code: "23505", Constraint: "users_email_key"}, "email", true},
+		{"unknown constraint", &pq.Error{Code: "23505", Constraint: "users_uuid_key"}, "", true},
+		{"other database error", &pq.Error{Code: "23502", Column: "email"}, "", false},
+		{"other error", other, "", false},
+		{"no error", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			err := duplicate(tt.err)
+
+			// Then: Only unique violations become a *DuplicateError naming the column
+			var dup *DuplicateError
+			if errors.As(err, &dup) != tt.wantDup || errors.Is(err, ErrDuplicate) != tt.wantDup {
+				t.Fatalf("expected duplicate %v, got %v", tt.wantDup, err)
+			}
+			if !tt.wantDup {
+				if err != tt.err {
+					t.Errorf("expected the error unchanged, got %v", err)
+				}
+				return
+			}
+			if dup.Column != tt.wantColumn {
+				t.Errorf("expected column %q, got %q", tt.wantColumn, dup.Column)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected the database error to be kept, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	// List returns every webhook, oldest first
+	List(ctx context.Context) ([]model.Webhook, error)
+	// Get returns a webhook; sql.ErrNoRows when there is none
+	Get(ctx context.Context, id string) (*model.Webhook, error)
+	// Update replaces the URL, events, description and active flag of a webhook;
+	// sql.ErrNoRows when there is none
+	Update(ctx context.Context, webhook *model.Webhook) error
+	// Delete removes a webhook with its deliveries; sql.ErrNoRows when there is none
+	Delete(ctx context.Context, id string) error
+
+	// Enqueue creates a pending delivery of an event for every active webhook
+	// subscribed to its type and returns how many it created
+	Enqueue(ctx context.Context, eventID, eventType string, payload []byte) (int64, error)
+	// Claim returns up to limit pending deliveries that are due, oldest first, with
+	// their webhook's URL and secret. Their attempts are counted and their next
+	// attempt is put off by lease, so other workers skip them meanwhile.
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	// MarkDelivered records a successful attempt
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	// MarkFailed records a failed attempt; the delivery is retried at retryAt, or
+	// given up when retryAt is nil
+	MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error
+	// Deliveries returns up to limit deliveries of a webhook, newest first
+	Deliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error)
+	// PurgeDeliveries removes deliveries delivered or given up before the given time
+	PurgeDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+type webhookRepository struct {
+	db DB
+}
+
+func NewWebhookRepository(db DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+const webhookColumns = `id, url, secret, events, description, active, created_by, created_at, updated_at`
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (*model.Webhook, error) {
+	var w model.Webhook
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, pq.Array(&w.Events), &w.Description, &w.Active,
+		&w.CreatedBy, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, w *model.Webhook) error {
+	return r.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (id, url, secret, events, description, active, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING created_at`,
+		w.ID, w.URL, w.Secret, pq.Array(w.Events), w.Description, w.Active, w.CreatedBy).
+		Scan(&w.CreatedAt)
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]model.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	webhooks := []model.Webhook{}
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, *w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (r *webhookRepository) Get(ctx context.Context, id string) (*model.Webhook, error) {
+	return scanWebhook(r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1`, id))
+}
+
+func (r *webhookRepository) Update(ctx context.Context, w *model.Webhook) error {
+	updated, err := scanWebhook(r.db.QueryRowContext(ctx,
+		`UPDATE webhooks SET url = $2, events = $3, description = $4, active = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING `+webhookColumns,
+		w.ID, w.URL, pq.Array(w.Events), w.Description, w.Active))
+	if err != nil {
+		return err
+	}
+	*w = *updated
+	return nil
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *webhookRepository) Enqueue(ctx context.Context, eventID, eventType string, payload []byte) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3 FROM webhooks WHERE active AND $2 = ANY(events)`,
+		eventID, eventType, payload)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.attempts, d.next_attempt_at,
+	d.last_status, d.last_error, d.delivered_at, d.failed_at, d.created_at`
+
+func scanDelivery(row interface{ Scan(dest ...any) error }, extra ...any) (*model.WebhookDelivery, error) {
+	var d model.WebhookDelivery
+	var next time.Time
+	dest := append([]any{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &next,
+		&d.LastStatus, &d.LastError, &d.DeliveredAt, &d.FailedAt, &d.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	switch {
+	case d.DeliveredAt != nil:
+		d.Status = model.DeliveryDelivered
+	case d.FailedAt != nil:
+		d.Status = model.DeliveryFailed
+	default:
+		d.Status = model.DeliveryPending
+		d.NextAttemptAt = &next
+	}
+	return &d, nil
+}
+
+func (r *webhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`WITH due AS (
+				SELECT id FROM webhook_deliveries
+				WHERE delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= CURRENT_TIMESTAMP
+				ORDER BY next_attempt_at, id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			UPDATE webhook_deliveries d
+			SET attempts = d.attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $2)
+			FROM due, webhooks w
+			WHERE d.id = due.id AND w.id = d.webhook_id
+			RETURNING `+deliveryColumns+`, w.url, w.secret`,
+			limit, lease.Seconds())
+		if err != nil {
+			return err
+		}
+		defer closeRows(ctx, rows)
+
+		deliveries = nil
+		for rows.Next() {
+			var url, secret string
+			d, err := scanDelivery(rows, &url, &secret)
+			if err != nil {
+				return err
+			}
+			d.URL, d.Secret = url, secret
+			deliveries = append(deliveries, *d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepository) MarkDelivered(ctx context.Context, id int64, status int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET delivered_at = CURRENT_TIMESTAMP, last_status = $2, last_error = '' WHERE id = $1`,
+		id, status)
+	return err
+}
+
+func (r *webhookRepository) MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+		SET last_status = $2, last_error = $3,
+			next_attempt_at = COALESCE($4, next_attempt_at),
+			failed_at = CASE WHEN $4::timestamp IS NULL THEN CURRENT_TIMESTAMP END
+		WHERE id = $1`,
+		id, status, reason, retryAt)
+	return err
+}
+
+func (r *webhookRepository) Deliveries(ctx context.Context, webhookID string, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries d
+		WHERE d.webhook_id = $1 ORDER BY d.created_at DESC, d.id DESC LIMIT $2`,
+		webhookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer closeRows(ctx, rows)
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhookRepository) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE delivered_at < $1 OR failed_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,187 @@
+// Package reputation tells whether a client address is known for abuse, from
+// blocklist files or an external reputation service, so bot detection can flag
+// traffic from it.
+package reputation
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"cruder/internal/httpclient"
+)
+
+// Verdict is what a provider knows of an address
+type Verdict struct {
+	Listed bool `json:"listed"`
+	// Reason names the list or category the address is on, e.g. "tor-exit"
+	Reason string `json:"reason,omitempty"`
+}
+
+// Provider tells whether an address is known for abuse
+type Provider interface {
+	Check(ctx context.Context, addr netip.Addr) (Verdict, error)
+}
+
+// Chain asks its providers in order and returns the first listing
+type Chain []Provider
+
+func (c Chain) Check(ctx context.Context, addr netip.Addr) (Verdict, error) {
+	for _, p := range c {
+		verdict, err := p.Check(ctx, addr)
+		if err != nil || verdict.Listed {
+			return verdict, err
+		}
+	}
+	return Verdict{}, nil
+}
+
+// List holds the addresses and networks of blocklist files, one per line, as in
+// the Spamhaus DROP or FireHOL lists; text after # or ; is a comment. An address
+// listed is reported with the file's name as reason.
+type List struct {
+	paths []string
+
+	mu      sync.RWMutex
+	entries []listEntry
+}
+
+type listEntry struct {
+	prefix netip.Prefix
+	source string
+}
+
+// NewList loads the files at paths
+func NewList(paths []string) (*List, error) {
+	l := &List{paths: paths}
+	return l, l.Reload()
+}
+
+// Reload reads the files again, so updated lists apply without a restart. The
+// lists in use are kept when a file cannot be read.
+func (l *List) Reload() error {
+	var entries []listEntry
+	for _, path := range l.paths {
+		loaded, err := loadList(path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, loaded...)
+	}
+	l.mu.Lock()
+	l.entries = entries
+	l.mu.Unlock()
+	return nil
+}
+
+// Len returns how many addresses and networks are listed
+func (l *List) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.entries)
+}
+
+func (l *List) Check(_ context.Context, addr netip.Addr) (Verdict, error) {
+	addr = addr.Unmap()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, e := range l.entries {
+		if e.prefix.Contains(addr) {
+			return Verdict{Listed: true, Reason: e.source}, nil
+		}
+	}
+	return Verdict{}, nil
+}
+
+func loadList(path string) ([]listEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open blocklist: %w", err)
+	}
+	defer f.Close()
+
+	source := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	var entries []listEntry
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line, _, _ = strings.Cut(line, ";")
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		prefix, err := parsePrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		entries = append(entries, listEntry{prefix: prefix, source: source})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read blocklist %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// parsePrefix reads a network, or an address as the network of just itself
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// HTTP asks a reputation service with GET url?ip=ADDR, which answers
+// {"listed": true, "reason": "..."} for an address known for abuse
+type HTTP struct {
+	client *httpclient.Client
+	url    string
+}
+
+// NewHTTP creates a provider backed by the service at url
+func NewHTTP(client *httpclient.Client, url string) *HTTP {
+	return &HTTP{client: client, url: url}
+}
+
+func (h *HTTP) Check(ctx context.Context, addr netip.Addr) (Verdict, error) {
+	u, err := url.Parse(h.url)
+	if err != nil {
+		return Verdict{}, err
+	}
+	query := u.Query()
+	query.Set("ip", addr.Unmap().String())
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Verdict{}, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("reputation service returned status %d", resp.StatusCode)
+	}
+	var verdict Verdict
+	if err := json.NewDecoder(resp.Body).Decode(&verdict); err != nil {
+		return Verdict{}, fmt.Errorf("decode reputation: %w", err)
+	}
+	return verdict, nil
+}
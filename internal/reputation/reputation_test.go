@@ -0,0 +1,93 @@
+package reputation
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/httpclient"
+)
+
+func TestList(t *testing.T) {
+	// Given: A blocklist in the Spamhaus DROP format with an address and networks
+	path := filepath.Join(t.TempDir(), "drop.txt")
+	content := "; Spamhaus DROP List\n192.0.2.0/24 ; SBL1\n198.51.100.7 # single host\n\n2001:db8::/32\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := NewList([]string{path})
+	if err != nil {
+		t.Fatalf("NewList: %v", err)
+	}
+
+	// When/Then: Listed addresses are reported with the list's name, others are not
+	tests := []struct {
+		addr   string
+		listed bool
+	}{
+		{"192.0.2.44", true},
+		{"::ffff:192.0.2.44", true},
+		{"198.51.100.7", true},
+		{"198.51.100.8", false},
+		{"2001:db8::1", true},
+		{"203.0.113.1", false},
+	}
+	for _, tt := range tests {
+		verdict, err := list.Check(context.Background(), netip.MustParseAddr(tt.addr))
+		if err != nil || verdict.Listed != tt.listed || (tt.listed && verdict.Reason != "drop") {
+			t.Errorf("Check(%s) = %+v, %v; want listed %t", tt.addr, verdict, err, tt.listed)
+		}
+	}
+	if list.Len() != 3 {
+		t.Errorf("Len = %d, want 3", list.Len())
+	}
+
+	// When: The file is replaced by an invalid one
+	if err := os.WriteFile(path, []byte("not-an-address\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Then: Reloading fails and the loaded lists stay in use
+	if err := list.Reload(); err == nil {
+		t.Error("expected an error for an invalid line")
+	}
+	if list.Len() != 3 {
+		t.Errorf("Len = %d after a failed reload, want 3", list.Len())
+	}
+}
+
+func TestChain_AsksServiceAfterLists(t *testing.T) {
+	// Given: An empty list and a reputation service listing one address
+	var asked string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asked = r.URL.Query().Get("ip")
+		if asked == "203.0.113.9" {
+			_, _ = io.WriteString(w, `{"listed": true, "reason": "tor-exit"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"listed": false}`)
+	}))
+	defer srv.Close()
+	list, _ := NewList(nil)
+	client := httpclient.New("reputation-test", config.HTTPClientConfig{Timeout: time.Second, BreakerThreshold: 100, BreakerCooldown: time.Minute})
+	chain := Chain{list, NewHTTP(client, srv.URL+"/check")}
+
+	// When: Checking the listed address and another one
+	listed, err := chain.Check(context.Background(), netip.MustParseAddr("203.0.113.9"))
+	clean, _ := chain.Check(context.Background(), netip.MustParseAddr("203.0.113.10"))
+
+	// Then: The service's verdict is returned
+	if err != nil || !listed.Listed || listed.Reason != "tor-exit" {
+		t.Errorf("Check = %+v, %v; want listed as tor-exit", listed, err)
+	}
+	if clean.Listed || asked != "203.0.113.10" {
+		t.Errorf("Check = %+v after asking about %s", clean, asked)
+	}
+}
@@ -0,0 +1,30 @@
+// Package residency keeps user data in the region it belongs to. Requests are
+// tagged with the region of the caller's tenant, and the repositories of user data
+// run their queries on that region's database cluster.
+package residency
+
+import (
+	"context"
+	"regexp"
+)
+
+// nameFormat restricts region names to what fits an environment variable name
+var nameFormat = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+type regionKey struct{}
+
+// ValidName reports whether name can name a region
+func ValidName(name string) bool {
+	return nameFormat.MatchString(name)
+}
+
+// WithRegion attaches the region whose database serves ctx
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// Region returns the region attached to ctx, or "" for the home region
+func Region(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return region
+}
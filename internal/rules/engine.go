@@ -0,0 +1,250 @@
+// Package rules evaluates admin-defined user rules written in the expr language
+// (https://expr-lang.org). Expressions cannot loop, call out or touch anything
+// but the user they are given; programs are size-limited at compile time and
+// each evaluation runs under a memory budget and a time limit.
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"cruder/internal/model"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// maxNodes bounds the size of a compiled expression
+const maxNodes = 1000
+
+var errTimeout = errors.New("evaluation timed out")
+
+// Source lists the stored rules in evaluation order
+type Source interface {
+	List(ctx context.Context) ([]model.Rule, error)
+}
+
+type compiledRule struct {
+	rule    model.Rule
+	program *vm.Program
+}
+
+// Engine applies the enabled rules to users on create and update. Rules are
+// reloaded from the source every refresh interval, or right after Invalidate, so
+// changes made on another replica apply without a restart.
+type Engine struct {
+	source  Source
+	timeout time.Duration
+	refresh time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	rules    []compiledRule
+	loadedAt time.Time
+}
+
+// NewEngine creates an engine that evaluates each rule for at most timeout
+func NewEngine(source Source, timeout, refresh time.Duration) *Engine {
+	return &Engine{source: source, timeout: timeout, refresh: refresh, now: time.Now}
+}
+
+// Compile checks a rule's kind, field and expression
+func Compile(rule model.Rule) (*vm.Program, error) {
+	opts := []expr.Option{expr.Env(vars{}), expr.MaxNodes(maxNodes)}
+	switch rule.Kind {
+	case model.RuleValidate:
+		opts = append(opts, expr.AsBool())
+	case model.RuleTransform:
+		if !settable(rule.Field) {
+			return nil, fmt.Errorf("transform rules must set username, email, full_name or custom_fields.<name>, got %q", rule.Field)
+		}
+	default:
+		return nil, fmt.Errorf("unknown rule kind %q (expected validate or transform)", rule.Kind)
+	}
+	return expr.Compile(rule.Expression, opts...)
+}
+
+// Invalidate makes the next evaluation reload the rules
+func (e *Engine) Invalidate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loadedAt = time.Time{}
+}
+
+// ValidateUser implements service.ValidationHook. Transform rules run first, then
+// validate rules see the transformed values. A rule that fails to evaluate or
+// exceeds the time limit counts as violated, so a broken rule never lets a user
+// through unchecked.
+func (e *Engine) ValidateUser(user, existing *model.User) ([]model.FieldError, error) {
+	rules, err := e.load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rules: %w", err)
+	}
+	op := "update"
+	if existing == nil {
+		op = "create"
+	}
+
+	var violations []model.FieldError
+	for _, kind := range []string{model.RuleTransform, model.RuleValidate} {
+		for _, r := range rules {
+			if r.rule.Kind != kind {
+				continue
+			}
+			out, err := e.run(r.program, env(user, existing, op))
+			if err == nil && kind == model.RuleTransform {
+				err = apply(user, r.rule.Field, out)
+			}
+			if err != nil {
+				log.Printf("rule %q failed: %v", r.rule.Name, err)
+				violations = append(violations, model.FieldError{Field: reportedField(r.rule), Code: "rule_error",
+					Message: fmt.Sprintf("rule %s could not be evaluated", r.rule.Name)})
+				continue
+			}
+			if kind == model.RuleValidate && out != true {
+				message := r.rule.Message
+				if message == "" {
+					message = "violates rule " + r.rule.Name
+				}
+				violations = append(violations, model.FieldError{Field: reportedField(r.rule), Code: r.rule.Name, Message: message})
+			}
+		}
+	}
+	return violations, nil
+}
+
+func (e *Engine) load() ([]compiledRule, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.loadedAt.IsZero() && e.now().Sub(e.loadedAt) < e.refresh {
+		return e.rules, nil
+	}
+
+	// The rules are shared by all requests, so loading them belongs to none
+	stored, err := e.source.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	compiled := make([]compiledRule, 0, len(stored))
+	for _, rule := range stored {
+		if rule.Disabled {
+			continue
+		}
+		program, err := Compile(rule)
+		if err != nil {
+			// Rules are compiled when saved, so this only happens after an upgrade
+			log.Printf("skipping rule %q: %v", rule.Name, err)
+			continue
+		}
+		compiled = append(compiled, compiledRule{rule: rule, program: program})
+	}
+	e.rules = compiled
+	e.loadedAt = e.now()
+	return compiled, nil
+}
+
+// run evaluates program with the time limit. A timed-out evaluation keeps running
+// in the background until it finishes; the memory budget bounds how long that is.
+func (e *Engine) run(program *vm.Program, environment vars) (any, error) {
+	type result struct {
+		out any
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := expr.Run(program, environment)
+		done <- result{out, err}
+	}()
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-timer.C:
+		return nil, errTimeout
+	}
+}
+
+// vars is the environment of an expression: user, existing (nil on create, so use
+// existing?.username) and op ("create" or "update")
+type vars struct {
+	User     userVars  `expr:"user"`
+	Existing *userVars `expr:"existing"`
+	Op       string    `expr:"op"`
+}
+
+type userVars struct {
+	ID           int64          `expr:"id"`
+	UUID         string         `expr:"uuid"`
+	Username     string         `expr:"username"`
+	Email        string         `expr:"email"`
+	FullName     string         `expr:"full_name"`
+	CustomFields map[string]any `expr:"custom_fields"`
+}
+
+func env(user, existing *model.User, op string) vars {
+	v := vars{User: *userEnv(user), Op: op}
+	if existing != nil {
+		v.Existing = userEnv(existing)
+	}
+	return v
+}
+
+func userEnv(u *model.User) *userVars {
+	customFields := make(map[string]any, len(u.CustomFields))
+	for name, value := range u.CustomFields {
+		customFields[name] = value
+	}
+	return &userVars{ID: u.ID, UUID: u.UUID, Username: u.Username, Email: u.Email, FullName: u.FullName, CustomFields: customFields}
+}
+
+func settable(field string) bool {
+	switch field {
+	case "username", "email", "full_name":
+		return true
+	}
+	name, ok := strings.CutPrefix(field, "custom_fields.")
+	return ok && name != ""
+}
+
+// apply stores a transform result; nil removes a custom field
+func apply(user *model.User, field string, value any) error {
+	if name, ok := strings.CutPrefix(field, "custom_fields."); ok {
+		if value == nil {
+			delete(user.CustomFields, name)
+			return nil
+		}
+		if user.CustomFields == nil {
+			user.CustomFields = make(map[string]any)
+		}
+		user.CustomFields[name] = value
+		return nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("transform of %s must return a string, got %T", field, value)
+	}
+	switch field {
+	case "username":
+		user.Username = str
+	case "email":
+		user.Email = str
+	case "full_name":
+		user.FullName = str
+	}
+	return nil
+}
+
+func reportedField(rule model.Rule) string {
+	if rule.Field == "" {
+		return "user"
+	}
+	return rule.Field
+}
@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cruder/internal/model"
+)
+
+type staticSource struct {
+	rules []model.Rule
+	calls int
+}
+
+func (s *staticSource) List(_ context.Context) ([]model.Rule, error) {
+	s.calls++
+	return s.rules, nil
+}
+
+func TestEngine_TransformsThenValidates(t *testing.T) {
+	// Given: a transform that normalizes the email and a rule that relies on it
+	source := &staticSource{rules: []model.Rule{
+		{Name: "corporate_email", Kind: model.RuleValidate, Field: "email",
+			Expression: `user.email endsWith "@corp.example.com"`, Message: "use your corporate email address"},
+		{Name: "lower_email", Kind: model.RuleTransform, Field: "email", Expression: `lower(trim(user.email))`},
+		{Name: "default_team", Kind: model.RuleTransform, Field: "custom_fields.team",
+			Expression: `user.custom_fields.team ?? "unassigned"`},
+		{Name: "disabled", Kind: model.RuleValidate, Expression: `false`, Disabled: true},
+	}}
+	engine := NewEngine(source, time.Second, time.Minute)
+
+	tests := []struct {
+		name      string
+		email     string
+		wantEmail string
+		wantCodes []string
+	}{
+		{"normalized corporate address", " Jane@Corp.Example.com ", "jane@corp.example.com", nil},
+		{"private address", "jane@gmail.com", "jane@gmail.com", []string{"corporate_email"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{Username: "jane", Email: tt.email}
+
+			// When
+			violations, err := engine.ValidateUser(user, nil)
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if user.Email != tt.wantEmail {
+				t.Errorf("expected email %q, got %q", tt.wantEmail, user.Email)
+			}
+			if user.CustomFields["team"] != "unassigned" {
+				t.Errorf("expected default team, got %v", user.CustomFields["team"])
+			}
+			if len(violations) != len(tt.wantCodes) {
+				t.Fatalf("expected %v, got %+v", tt.wantCodes, violations)
+			}
+			for i, code := range tt.wantCodes {
+				if violations[i].Code != code || violations[i].Field != "email" {
+					t.Errorf("expected %s on email, got %+v", code, violations[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_OperationAndExisting(t *testing.T) {
+	// Given: usernames may not change once set
+	source := &staticSource{rules: []model.Rule{
+		{Name: "immutable_username", Kind: model.RuleValidate, Field: "username",
+			Expression: `op == "create" || user.username == existing.username`},
+	}}
+	engine := NewEngine(source, time.Second, time.Minute)
+	existing := &model.User{UUID: "a", Username: "jane"}
+
+	// When / Then
+	if v, _ := engine.ValidateUser(&model.User{Username: "anything"}, nil); len(v) != 0 {
+		t.Errorf("expected create to pass, got %+v", v)
+	}
+	if v, _ := engine.ValidateUser(&model.User{Username: "jane"}, existing); len(v) != 0 {
+		t.Errorf("expected unchanged username to pass, got %+v", v)
+	}
+	if v, _ := engine.ValidateUser(&model.User{Username: "janet"}, existing); len(v) != 1 {
+		t.Errorf("expected rename to be rejected, got %+v", v)
+	}
+}
+
+func TestEngine_FailsClosedOnEvaluationError(t *testing.T) {
+	// Given: a transform that returns a number for a string field
+	source := &staticSource{rules: []model.Rule{
+		{Name: "bad_transform", Kind: model.RuleTransform, Field: "full_name", Expression: `len(user.username)`},
+	}}
+	engine := NewEngine(source, time.Second, time.Minute)
+
+	// When
+	violations, err := engine.ValidateUser(&model.User{Username: "jane", FullName: "Jane"}, nil)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Code != "rule_error" {
+		t.Errorf("expected rule_error, got %+v", violations)
+	}
+}
+
+func TestEngine_CachesUntilInvalidated(t *testing.T) {
+	source := &staticSource{}
+	engine := NewEngine(source, time.Second, time.Hour)
+
+	_, _ = engine.ValidateUser(&model.User{}, nil)
+	_, _ = engine.ValidateUser(&model.User{}, nil)
+	if source.calls != 1 {
+		t.Errorf("expected one load within the refresh interval, got %d", source.calls)
+	}
+
+	engine.Invalidate()
+	_, _ = engine.ValidateUser(&model.User{}, nil)
+	if source.calls != 2 {
+		t.Errorf("expected a reload after Invalidate, got %d", source.calls)
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    model.Rule
+		wantErr bool
+	}{
+		{"valid validate rule", model.Rule{Kind: model.RuleValidate, Expression: `len(user.username) >= 3`}, false},
+		{"validate rule must be boolean", model.Rule{Kind: model.RuleValidate, Expression: `user.username`}, true},
+		{"syntax error", model.Rule{Kind: model.RuleValidate, Expression: `user.username ==`}, true},
+		{"unknown kind", model.Rule{Kind: "script", Expression: `true`}, true},
+		{"transform of unknown field", model.Rule{Kind: model.RuleTransform, Field: "id", Expression: `1`}, true},
+		{"unknown variable", model.Rule{Kind: model.RuleValidate, Expression: `env.HOME == ""`}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
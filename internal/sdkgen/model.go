@@ -0,0 +1,481 @@
+package sdkgen
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// API is what the clients are generated from: the named types and the operations
+// of the spec
+type API struct {
+	Title       string
+	Description string
+	Version     string
+	// BasePath is where the operations are mounted, relative to the service root
+	BasePath   string
+	Types      []*Type
+	Operations []*Operation
+}
+
+// Type is a named type. Objects have fields and may extend other types (allOf);
+// any other named schema, such as an enum, is an alias.
+type Type struct {
+	Name        string
+	Description string
+	Bases       []string
+	Fields      []Field
+	Alias       *TypeRef
+}
+
+// Field is a property of an object
+type Field struct {
+	Name        string
+	Type        *TypeRef
+	Required    bool
+	Description string
+}
+
+// Kind is the kind of a type expression
+type Kind int
+
+const (
+	KindAny Kind = iota
+	KindString
+	KindInteger
+	KindNumber
+	KindBoolean
+	// KindBinary is file content
+	KindBinary
+	KindArray
+	// KindMap is an object with arbitrary keys
+	KindMap
+	KindNamed
+	KindUnion
+)
+
+// TypeRef is a type expression. Inline objects are hoisted into named types, so
+// a TypeRef never has fields of its own.
+type TypeRef struct {
+	Kind Kind
+	// Name is the type of KindNamed
+	Name string
+	// Elem is the element of KindArray and the value of KindMap
+	Elem *TypeRef
+	// Enum lists the values a KindString may take; empty for any
+	Enum []string
+	// Format refines a KindString, e.g. uuid or email
+	Format string
+	// Example is a value of the type given by the spec; nil without one
+	Example  any
+	Variants []*TypeRef
+	Nullable bool
+}
+
+// Param is a path, query or header parameter of an operation
+type Param struct {
+	// Name is the name on the wire
+	Name string
+	// Ident is the name in snake_case, without the X- of headers
+	Ident       string
+	Type        *TypeRef
+	Required    bool
+	Description string
+}
+
+// Operation is an operation of the spec, named by its operationId
+type Operation struct {
+	ID     string
+	Method string
+	Path   string
+	// Tag groups the operation with others of its area; empty without one
+	Tag         string
+	Summary     string
+	Description string
+	// Auth lists the security schemes that authorize the operation, e.g. apiKey
+	// or bearer; empty when it needs no credentials
+	Auth       []string
+	PathParams []Param
+	Query      []Param
+	Headers    []Param
+	// Body is the request body, JSON unless Multipart; nil without one
+	Body      *TypeRef
+	Multipart bool
+	// Results are the distinct JSON bodies of the success responses
+	Results []*TypeRef
+	// NoContent is set when a success response has no body
+	NoContent bool
+	// Binary is set when the success response is a file
+	Binary bool
+}
+
+// Load reads an OpenAPI 3 document
+func Load(spec []byte) (*API, error) {
+	var doc document
+	if err := yaml.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("parse spec: %w", err)
+	}
+	if len(doc.Servers) != 1 {
+		return nil, fmt.Errorf("expected one server, got %d", len(doc.Servers))
+	}
+
+	b := &builder{doc: &doc, defined: map[string]bool{}, responses: map[string]*TypeRef{}}
+	for _, s := range doc.Components.Schemas {
+		if err := b.defineSchema(s.Key, s.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	api := &API{
+		Title:       doc.Info.Title,
+		Description: doc.Info.Description,
+		Version:     doc.Info.Version,
+		// The server URL is relative to the spec, e.g. ../api/v1
+		BasePath: path.Clean("/" + doc.Servers[0].URL),
+	}
+	ids := map[string]bool{}
+	for _, p := range doc.Paths {
+		for _, m := range []struct {
+			method string
+			op     *operation
+		}{{"GET", p.Value.Get}, {"POST", p.Value.Post}, {"PUT", p.Value.Put}, {"PATCH", p.Value.Patch}, {"DELETE", p.Value.Delete}} {
+			if m.op == nil {
+				continue
+			}
+			op, err := b.operation(m.method, p.Key, p.Value.Parameters, m.op)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", m.method, p.Key, err)
+			}
+			if ids[op.ID] {
+				return nil, fmt.Errorf("%s %s: operationId %s is used twice", m.method, p.Key, op.ID)
+			}
+			ids[op.ID] = true
+			api.Operations = append(api.Operations, op)
+		}
+	}
+	api.Types = b.types
+	return api, nil
+}
+
+type builder struct {
+	doc       *document
+	types     []*Type
+	defined   map[string]bool
+	responses map[string]*TypeRef
+}
+
+func (b *builder) operation(method, route string, shared []parameter, o *operation) (*Operation, error) {
+	if o.OperationID == "" {
+		return nil, fmt.Errorf("no operationId")
+	}
+	op := &Operation{ID: o.OperationID, Method: method, Path: route, Summary: o.Summary, Description: o.Description}
+	if len(o.Tags) > 0 {
+		op.Tag = o.Tags[0]
+	}
+	security := b.doc.Security
+	if o.Security != nil {
+		security = *o.Security
+	}
+	for _, req := range security {
+		for _, scheme := range slices.Sorted(maps.Keys(req)) {
+			if !slices.Contains(op.Auth, scheme) {
+				op.Auth = append(op.Auth, scheme)
+			}
+		}
+	}
+	name := pascal(o.OperationID)
+
+	// Parameters of the operation override those of its path
+	var params []parameter
+	for _, p := range append(slices.Clone(shared), o.Parameters...) {
+		p, err := b.parameter(p)
+		if err != nil {
+			return nil, err
+		}
+		params = slices.DeleteFunc(params, func(q parameter) bool { return q.Name == p.Name && q.In == p.In })
+		params = append(params, p)
+	}
+	for _, p := range params {
+		typ, err := b.ref(p.Schema, name+pascal(p.Name))
+		if err != nil {
+			return nil, err
+		}
+		param := Param{Name: p.Name, Ident: snake(p.Name), Type: typ, Required: p.Required, Description: p.Description}
+		switch p.In {
+		case "path":
+			op.PathParams = append(op.PathParams, param)
+		case "query":
+			op.Query = append(op.Query, param)
+		case "header":
+			param.Ident = strings.TrimPrefix(param.Ident, "x_")
+			op.Headers = append(op.Headers, param)
+		default:
+			return nil, fmt.Errorf("parameter %s: unsupported location %q", p.Name, p.In)
+		}
+	}
+
+	if o.RequestBody != nil {
+		var media mediaType
+		if m, ok := o.RequestBody.Content["application/json"]; ok {
+			media = m
+		} else if m, ok := o.RequestBody.Content["multipart/form-data"]; ok {
+			media, op.Multipart = m, true
+		} else {
+			return nil, fmt.Errorf("unsupported request body")
+		}
+		body, err := b.ref(media.Schema, name+"Request")
+		if err != nil {
+			return nil, err
+		}
+		op.Body = body
+	}
+
+	for _, r := range o.Responses {
+		if !strings.HasPrefix(r.Key, "2") {
+			continue
+		}
+		resp, component := r.Value, ""
+		if resp.Ref != "" {
+			component = strings.TrimPrefix(resp.Ref, "#/components/responses/")
+			var ok bool
+			if resp, ok = b.doc.Components.Responses[component]; !ok {
+				return nil, fmt.Errorf("unknown response %s", r.Value.Ref)
+			}
+		}
+		media, ok := resp.Content["application/json"]
+		switch {
+		case len(resp.Content) == 0:
+			op.NoContent = true
+		case !ok:
+			op.Binary = true
+		default:
+			result, err := b.result(media.Schema, component, name+"Response")
+			if err != nil {
+				return nil, err
+			}
+			if !slices.ContainsFunc(op.Results, func(t *TypeRef) bool { return typeKey(t) == typeKey(result) }) {
+				op.Results = append(op.Results, result)
+			}
+		}
+	}
+	if op.Binary && (len(op.Results) > 0 || op.NoContent) {
+		return nil, fmt.Errorf("a file response cannot be mixed with others")
+	}
+	return op, nil
+}
+
+// result returns the type of a response body. Responses of the components are
+// shared by operations, so an inline object of theirs is named after the response
+// once.
+func (b *builder) result(s *schema, component, name string) (*TypeRef, error) {
+	if component == "" {
+		return b.ref(s, name)
+	}
+	if typ, ok := b.responses[component]; ok {
+		return typ, nil
+	}
+	typ, err := b.ref(s, component)
+	if err != nil {
+		return nil, err
+	}
+	b.responses[component] = typ
+	return typ, nil
+}
+
+func (b *builder) parameter(p parameter) (parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	resolved, ok := b.doc.Components.Parameters[strings.TrimPrefix(p.Ref, "#/components/parameters/")]
+	if !ok {
+		return p, fmt.Errorf("unknown parameter %s", p.Ref)
+	}
+	return resolved, nil
+}
+
+// defineSchema names a schema of the components
+func (b *builder) defineSchema(name string, s *schema) error {
+	if isObject(s) {
+		_, err := b.define(name, s)
+		return err
+	}
+	if err := b.reserve(name); err != nil {
+		return err
+	}
+	t := &Type{Name: name, Description: s.Description}
+	b.types = append(b.types, t)
+	alias, err := b.ref(s, name+"Value")
+	t.Alias = alias
+	return err
+}
+
+// define names an object, hoisting the inline objects of its fields into types
+// named after it and the field
+func (b *builder) define(name string, s *schema) (*TypeRef, error) {
+	if err := b.reserve(name); err != nil {
+		return nil, err
+	}
+	t := &Type{Name: name, Description: s.Description}
+	b.types = append(b.types, t)
+
+	parts := []*schema{s}
+	if len(s.AllOf) > 0 {
+		parts = nil
+		for _, part := range s.AllOf {
+			if part.Ref != "" {
+				t.Bases = append(t.Bases, refName(part.Ref))
+			} else {
+				parts = append(parts, part)
+			}
+		}
+	}
+	for _, part := range parts {
+		for _, p := range part.Properties {
+			typ, err := b.ref(p.Value, name+pascal(p.Key))
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", name, p.Key, err)
+			}
+			t.Fields = append(t.Fields, Field{
+				Name:        p.Key,
+				Type:        typ,
+				Required:    slices.Contains(part.Required, p.Key),
+				Description: p.Value.Description,
+			})
+		}
+	}
+	return &TypeRef{Kind: KindNamed, Name: name}, nil
+}
+
+func (b *builder) reserve(name string) error {
+	if b.defined[name] {
+		return fmt.Errorf("type %s is defined twice", name)
+	}
+	b.defined[name] = true
+	return nil
+}
+
+// ref returns the type expression of s, defining name for it if it is an object
+func (b *builder) ref(s *schema, name string) (*TypeRef, error) {
+	if s == nil {
+		return &TypeRef{Kind: KindAny}, nil
+	}
+	if s.Ref != "" {
+		return &TypeRef{Kind: KindNamed, Name: refName(s.Ref)}, nil
+	}
+	if isObject(s) {
+		return b.define(name, s)
+	}
+
+	t := &TypeRef{Nullable: s.Nullable, Example: s.Example}
+	switch {
+	case len(s.OneOf) == 1:
+		return b.ref(s.OneOf[0], name)
+	case len(s.OneOf) > 1:
+		t.Kind = KindUnion
+		for i, variant := range s.OneOf {
+			v, err := b.ref(variant, fmt.Sprintf("%s%d", name, i+1))
+			if err != nil {
+				return nil, err
+			}
+			t.Variants = append(t.Variants, v)
+		}
+	case s.Type == "string" && s.Format == "binary":
+		t.Kind = KindBinary
+	case s.Type == "string":
+		t.Kind, t.Enum, t.Format = KindString, s.Enum, s.Format
+	case s.Type == "integer":
+		t.Kind = KindInteger
+	case s.Type == "number":
+		t.Kind = KindNumber
+	case s.Type == "boolean":
+		t.Kind = KindBoolean
+	case s.Type == "array":
+		// The items of a plural such as BulkResultResults are a BulkResultResult
+		itemName, plural := strings.CutSuffix(name, "s")
+		if !plural {
+			itemName = name + "Item"
+		}
+		elem, err := b.ref(s.Items, itemName)
+		if err != nil {
+			return nil, err
+		}
+		t.Kind, t.Elem = KindArray, elem
+	case s.Type == "object":
+		t.Kind, t.Elem = KindMap, &TypeRef{Kind: KindAny}
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			elem, err := b.ref(s.AdditionalProperties.Schema, name+"Value")
+			if err != nil {
+				return nil, err
+			}
+			t.Elem = elem
+		}
+	case s.Type == "":
+		t.Kind = KindAny
+	default:
+		return nil, fmt.Errorf("unsupported type %q", s.Type)
+	}
+	return t, nil
+}
+
+func isObject(s *schema) bool {
+	return len(s.Properties) > 0 || len(s.AllOf) > 0
+}
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// typeKey identifies a type expression, to tell distinct results apart
+func typeKey(t *TypeRef) string {
+	key := fmt.Sprintf("%d:%s:%v:%v", t.Kind, t.Name, t.Enum, t.Nullable)
+	if t.Elem != nil {
+		key += "<" + typeKey(t.Elem) + ">"
+	}
+	for _, v := range t.Variants {
+		key += "|" + typeKey(v)
+	}
+	return key
+}
+
+// pascal turns names such as listUsers, per_page or Idempotency-Key into
+// PascalCase
+func pascal(s string) string {
+	var out strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+		}
+		out.WriteRune(r)
+		upper = false
+	}
+	return out.String()
+}
+
+// snake turns names such as getUserById or Idempotency-Key into snake_case
+func snake(s string) string {
+	var out strings.Builder
+	for i, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && !strings.HasSuffix(out.String(), "_") {
+				out.WriteByte('_')
+			}
+			out.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			out.WriteRune(r)
+		default:
+			out.WriteByte('_')
+		}
+	}
+	return out.String()
+}
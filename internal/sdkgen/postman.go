@@ -0,0 +1,295 @@
+package sdkgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// postmanSchema is the collection format written, which Insomnia imports as well
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// Postman renders the API as a Postman collection. Requests are grouped in a
+// folder per tag and filled with example bodies, and use three collection
+// variables: baseUrl, pre-filled with baseURL, the root of the operations such as
+// https://users.example.com/api/v1; apiKey, sent as X-API-Key; and token, sent as
+// a bearer token to the operations that need a JWT.
+func Postman(api *API, baseURL string) ([]byte, error) {
+	types := make(map[string]*Type, len(api.Types))
+	for _, t := range api.Types {
+		types[t.Name] = t
+	}
+	ex := &exampler{types: types, seen: map[string]bool{}}
+
+	collection := pmCollection{
+		Info: pmInfo{Name: api.Title, Description: api.Description, Schema: postmanSchema},
+		Auth: &pmAuth{Type: "apikey", APIKey: []pmPair{
+			{Key: "key", Value: "X-API-Key"},
+			{Key: "value", Value: "{{apiKey}}"},
+			{Key: "in", Value: "header"},
+		}},
+		Variable: []pmPair{
+			{Key: "baseUrl", Value: baseURL},
+			{Key: "apiKey", Value: "", Description: "An API key, sent as X-API-Key"},
+			{Key: "token", Value: "", Description: "A JWT from /auth/login or a personal token, for the requests that need one"},
+		},
+		Item: []pmItem{},
+	}
+	folders := map[string]int{}
+	for _, op := range api.Operations {
+		item, err := ex.request(op)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op.ID, err)
+		}
+		if op.Tag == "" {
+			collection.Item = append(collection.Item, item)
+			continue
+		}
+		i, ok := folders[op.Tag]
+		if !ok {
+			i = len(collection.Item)
+			folders[op.Tag] = i
+			collection.Item = append(collection.Item, pmItem{Name: op.Tag})
+		}
+		collection.Item[i].Item = append(collection.Item[i].Item, item)
+	}
+
+	data, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+type pmCollection struct {
+	Info     pmInfo   `json:"info"`
+	Auth     *pmAuth  `json:"auth,omitempty"`
+	Variable []pmPair `json:"variable"`
+	Item     []pmItem `json:"item"`
+}
+
+type pmInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+type pmAuth struct {
+	Type   string   `json:"type"`
+	APIKey []pmPair `json:"apikey,omitempty"`
+	Bearer []pmPair `json:"bearer,omitempty"`
+}
+
+// pmItem is a folder with items, or a request
+type pmItem struct {
+	Name    string     `json:"name"`
+	Item    []pmItem   `json:"item,omitempty"`
+	Request *pmRequest `json:"request,omitempty"`
+}
+
+type pmRequest struct {
+	Method      string   `json:"method"`
+	Description string   `json:"description,omitempty"`
+	Auth        *pmAuth  `json:"auth,omitempty"`
+	Header      []pmPair `json:"header"`
+	URL         pmURL    `json:"url"`
+	Body        *pmBody  `json:"body,omitempty"`
+}
+
+type pmURL struct {
+	Raw      string   `json:"raw"`
+	Host     []string `json:"host"`
+	Path     []string `json:"path"`
+	Query    []pmPair `json:"query,omitempty"`
+	Variable []pmPair `json:"variable,omitempty"`
+}
+
+type pmBody struct {
+	Mode     string    `json:"mode"`
+	Raw      string    `json:"raw,omitempty"`
+	Options  *pmRawOpt `json:"options,omitempty"`
+	FormData []pmPair  `json:"formdata,omitempty"`
+}
+
+type pmRawOpt struct {
+	Raw struct {
+		Language string `json:"language"`
+	} `json:"raw"`
+}
+
+// pmPair is a variable, header, query or form parameter; optional parameters are
+// listed disabled
+type pmPair struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+func (ex *exampler) request(op *Operation) (pmItem, error) {
+	name := op.Summary
+	if name == "" {
+		name = op.ID
+	}
+	req := &pmRequest{Method: op.Method, Description: strings.TrimSpace(op.Description), Header: []pmPair{}}
+
+	// The collection authenticates with the API key; the schemes of api/openapi.yaml
+	// are apiKey and bearer
+	switch {
+	case len(op.Auth) == 0:
+		req.Auth = &pmAuth{Type: "noauth"}
+	case !slices.Contains(op.Auth, "apiKey") && slices.Contains(op.Auth, "bearer"):
+		req.Auth = &pmAuth{Type: "bearer", Bearer: []pmPair{{Key: "token", Value: "{{token}}"}}}
+	}
+
+	path := strings.Split(strings.TrimPrefix(pathParam.ReplaceAllStringFunc(op.Path, func(m string) string {
+		return ":" + m[1:len(m)-1]
+	}), "/"), "/")
+	req.URL = pmURL{Host: []string{"{{baseUrl}}"}, Path: path}
+	var query []string
+	for _, p := range op.Query {
+		q := ex.param(p)
+		req.URL.Query = append(req.URL.Query, q)
+		if !q.Disabled {
+			query = append(query, q.Key+"="+q.Value)
+		}
+	}
+	req.URL.Raw = "{{baseUrl}}/" + strings.Join(path, "/")
+	if len(query) > 0 {
+		req.URL.Raw += "?" + strings.Join(query, "&")
+	}
+	for _, p := range op.PathParams {
+		v := ex.param(p)
+		v.Disabled = false
+		req.URL.Variable = append(req.URL.Variable, v)
+	}
+	for _, p := range op.Headers {
+		req.Header = append(req.Header, ex.param(p))
+	}
+
+	switch {
+	case op.Body == nil:
+	case op.Multipart:
+		form, err := ex.form(op.Body)
+		if err != nil {
+			return pmItem{}, err
+		}
+		req.Body = &pmBody{Mode: "formdata", FormData: form}
+	default:
+		raw, err := json.MarshalIndent(ex.value(op.Body), "", "  ")
+		if err != nil {
+			return pmItem{}, err
+		}
+		opts := &pmRawOpt{}
+		opts.Raw.Language = "json"
+		req.Header = append(req.Header, pmPair{Key: "Content-Type", Value: "application/json"})
+		req.Body = &pmBody{Mode: "raw", Raw: string(raw), Options: opts}
+	}
+	return pmItem{Name: name, Request: req}, nil
+}
+
+// param lists a parameter with its example value, if the spec gives one
+func (ex *exampler) param(p Param) pmPair {
+	pair := pmPair{Key: p.Name, Description: p.Description, Disabled: !p.Required}
+	if p.Type.Example != nil {
+		pair.Value = fmt.Sprint(p.Type.Example)
+	}
+	return pair
+}
+
+// form lists the fields of a multipart body, files as file fields
+func (ex *exampler) form(body *TypeRef) ([]pmPair, error) {
+	t, ok := ex.types[body.Name]
+	if body.Kind != KindNamed || !ok {
+		return nil, fmt.Errorf("a multipart body must be an object")
+	}
+	var pairs []pmPair
+	for _, f := range ex.fields(t) {
+		pair := pmPair{Key: f.Name, Type: "text", Description: f.Description, Disabled: !f.Required}
+		if f.Type.Kind == KindBinary {
+			pair.Type = "file"
+		} else if v, ok := ex.value(f.Type).(string); ok {
+			pair.Value = v
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// exampler makes up example values of types, preferring the examples of the spec
+type exampler struct {
+	types map[string]*Type
+	// seen holds the types being filled in, to stop at recursive ones
+	seen map[string]bool
+}
+
+func (ex *exampler) value(t *TypeRef) any {
+	if t.Example != nil {
+		return t.Example
+	}
+	switch t.Kind {
+	case KindString:
+		if len(t.Enum) > 0 {
+			return t.Enum[0]
+		}
+		switch t.Format {
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+		case "date":
+			return "2026-01-01"
+		case "date-time":
+			return "2026-01-01T00:00:00Z"
+		case "uri":
+			return "https://example.com"
+		}
+		return "string"
+	case KindInteger, KindNumber:
+		return 0
+	case KindBoolean:
+		return false
+	case KindArray:
+		return []any{ex.value(t.Elem)}
+	case KindMap:
+		return ordered[any]{}
+	case KindUnion:
+		return ex.value(t.Variants[0])
+	case KindNamed:
+		named, ok := ex.types[t.Name]
+		if !ok || ex.seen[t.Name] {
+			return nil
+		}
+		ex.seen[t.Name] = true
+		defer delete(ex.seen, t.Name)
+		if named.Alias != nil {
+			return ex.value(named.Alias)
+		}
+		// Only the required fields, so the example is a minimal valid body, unless
+		// none are, as in the bodies of partial updates
+		fields := ex.fields(named)
+		required := slices.DeleteFunc(slices.Clone(fields), func(f Field) bool { return !f.Required })
+		if len(required) > 0 {
+			fields = required
+		}
+		obj := ordered[any]{}
+		for _, f := range fields {
+			obj = append(obj, entry[any]{Key: f.Name, Value: ex.value(f.Type)})
+		}
+		return obj
+	}
+	return nil
+}
+
+// fields returns the fields of an object, those of its bases first
+func (ex *exampler) fields(t *Type) []Field {
+	var fields []Field
+	for _, base := range t.Bases {
+		if b, ok := ex.types[base]; ok {
+			fields = append(fields, ex.fields(b)...)
+		}
+	}
+	return append(fields, t.Fields...)
+}
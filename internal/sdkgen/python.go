@@ -0,0 +1,452 @@
+package sdkgen
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// python renders the API as one module for Python 3.8+, using only the standard
+// library
+func python(api *API) []byte {
+	var w strings.Builder
+	fmt.Fprintf(&w, "# %s\n", generatedHeader)
+	fmt.Fprintf(&w, "\"\"\"Client of the %s API, version %s.\n\n", api.Title, api.Version)
+	w.WriteString("Pass the root URL of the service, including server.base_path, and an API key\nor a bearer token::\n\n")
+	w.WriteString("    client = Client(\"https://users.example.com\", api_key=\"...\")\n")
+	w.WriteString("    users = client.list_users(email_domain=\"example.com\")\n\"\"\"\n\n")
+	w.WriteString(pyRuntime)
+
+	for _, t := range pyOrder(api.Types) {
+		w.WriteString("\n\n")
+		pyTypeDef(&w, t)
+	}
+
+	w.WriteString("\n\n")
+	w.WriteString(strings.ReplaceAll(pyClient, "{{BASE_PATH}}", api.BasePath))
+	for _, op := range api.Operations {
+		w.WriteString("\n")
+		pyOperation(&w, op)
+	}
+	w.WriteString(pyHelpers)
+	return []byte(w.String())
+}
+
+// pyOrder puts aliases first and every type after its bases, as Python evaluates
+// them when the module loads
+func pyOrder(types []*Type) []*Type {
+	byName := make(map[string]*Type, len(types))
+	for _, t := range types {
+		byName[t.Name] = t
+	}
+	var out []*Type
+	done := map[string]bool{}
+	var visit func(t *Type)
+	visit = func(t *Type) {
+		if done[t.Name] {
+			return
+		}
+		done[t.Name] = true
+		for _, base := range t.Bases {
+			if b, ok := byName[base]; ok {
+				visit(b)
+			}
+		}
+		out = append(out, t)
+	}
+	for _, t := range types {
+		if t.Alias != nil {
+			visit(t)
+		}
+	}
+	for _, t := range types {
+		visit(t)
+	}
+	return out
+}
+
+func pyTypeDef(w *strings.Builder, t *Type) {
+	if t.Alias != nil {
+		fmt.Fprintf(w, "%s = %s\n", t.Name, pyType(t.Alias))
+		if t.Description != "" {
+			pyDocstring(w, "", t.Description)
+		}
+		return
+	}
+
+	// Fields that are not identifiers need the functional syntax, which cannot
+	// extend other types
+	if slices.ContainsFunc(t.Fields, func(f Field) bool { return !pyIsIdent(f.Name) }) && len(t.Bases) == 0 {
+		var fields []string
+		for _, f := range t.Fields {
+			fields = append(fields, fmt.Sprintf("%s: %s", strconv.Quote(f.Name), pyType(f.Type)))
+		}
+		fmt.Fprintf(w, "%s = TypedDict(%s, {%s}, total=False)\n", t.Name, strconv.Quote(t.Name), strings.Join(fields, ", "))
+		return
+	}
+
+	bases := append(slices.Clone(t.Bases), "total=False")
+	if len(t.Bases) == 0 {
+		bases = []string{"TypedDict", "total=False"}
+	}
+	fmt.Fprintf(w, "class %s(%s):\n", t.Name, strings.Join(bases, ", "))
+	if t.Description != "" {
+		pyDocstring(w, "    ", t.Description)
+		if len(t.Fields) > 0 {
+			w.WriteString("\n")
+		}
+	} else if len(t.Fields) == 0 {
+		w.WriteString("    pass\n")
+	}
+	for _, f := range t.Fields {
+		pyComment(w, "    ", f.Description)
+		fmt.Fprintf(w, "    %s: %s\n", f.Name, pyType(f.Type))
+	}
+}
+
+func pyOperation(w *strings.Builder, op *Operation) {
+	var args []string
+	for _, p := range op.PathParams {
+		args = append(args, fmt.Sprintf("%s: %s", pyIdent(p.Ident), pyType(p.Type)))
+	}
+	if op.Body != nil {
+		args = append(args, "body: "+pyType(op.Body))
+	}
+	params := append(slices.Clone(op.Query), op.Headers...)
+	if len(params) > 0 {
+		args = append(args, "*")
+	}
+	// Keyword-only parameters may be required in any order
+	for _, p := range params {
+		if p.Required {
+			args = append(args, fmt.Sprintf("%s: %s", pyIdent(p.Ident), pyType(p.Type)))
+		} else {
+			args = append(args, fmt.Sprintf("%s: Optional[%s] = None", pyIdent(p.Ident), pyType(p.Type)))
+		}
+	}
+
+	var result string
+	switch {
+	case op.Binary:
+		result = "bytes"
+	case len(op.Results) == 0:
+		result = "None"
+	default:
+		var types []string
+		for _, r := range op.Results {
+			types = append(types, pyType(r))
+		}
+		result = types[0]
+		if len(types) > 1 {
+			result = "Union[" + strings.Join(types, ", ") + "]"
+		}
+		if op.NoContent {
+			result = "Optional[" + result + "]"
+		}
+	}
+
+	if line := fmt.Sprintf("    def %s(%s) -> %s:", snake(op.ID), strings.Join(append([]string{"self"}, args...), ", "), result); len(line) <= maxLine {
+		w.WriteString(line + "\n")
+	} else {
+		fmt.Fprintf(w, "    def %s(\n        self,\n", snake(op.ID))
+		for _, a := range args {
+			fmt.Fprintf(w, "        %s,\n", a)
+		}
+		fmt.Fprintf(w, "    ) -> %s:\n", result)
+	}
+	doc := op.Summary
+	if op.Description != "" {
+		doc += "\n\n" + op.Description
+	}
+	pyDocstring(w, "        ", doc)
+
+	path := strconv.Quote(op.Path)
+	if len(op.PathParams) > 0 {
+		path = "f" + strconv.Quote(pathParam.ReplaceAllStringFunc(op.Path, func(m string) string {
+			return "{_path(" + pyIdent(snake(m[1:len(m)-1])) + ")}"
+		}))
+	}
+	call := []string{strconv.Quote(op.Method), path}
+	if len(op.Query) > 0 {
+		call = append(call, "query="+pyParams(op.Query))
+	}
+	if len(op.Headers) > 0 {
+		call = append(call, "headers="+pyParams(op.Headers))
+	}
+	if op.Body != nil && op.Multipart {
+		call = append(call, "form=body")
+	} else if op.Body != nil {
+		call = append(call, "json_body=body")
+	}
+	if op.Binary {
+		call = append(call, "binary=True")
+	}
+	if line := fmt.Sprintf("        return self._request(%s)", strings.Join(call, ", ")); len(line) <= maxLine {
+		w.WriteString(line + "\n")
+		return
+	}
+	w.WriteString("        return self._request(\n")
+	for _, arg := range call {
+		// A dict too long for its line gets one entry per line
+		if name, dict, ok := strings.Cut(arg, "={"); ok && len(arg)+14 > maxLine {
+			fmt.Fprintf(w, "            %s={\n", name)
+			for _, entry := range strings.Split(strings.TrimSuffix(dict, "}"), ", ") {
+				fmt.Fprintf(w, "                %s,\n", entry)
+			}
+			w.WriteString("            },\n")
+			continue
+		}
+		fmt.Fprintf(w, "            %s,\n", arg)
+	}
+	w.WriteString("        )\n")
+}
+
+// pyParams maps the wire names of params to their arguments
+func pyParams(params []Param) string {
+	var entries []string
+	for _, p := range params {
+		entries = append(entries, fmt.Sprintf("%s: %s", strconv.Quote(p.Name), pyIdent(p.Ident)))
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+func pyType(t *TypeRef) string {
+	var s string
+	switch t.Kind {
+	case KindString:
+		s = "str"
+		if len(t.Enum) > 0 {
+			quoted := make([]string, len(t.Enum))
+			for i, v := range t.Enum {
+				quoted[i] = strconv.Quote(v)
+			}
+			s = "Literal[" + strings.Join(quoted, ", ") + "]"
+		}
+	case KindInteger:
+		s = "int"
+	case KindNumber:
+		s = "float"
+	case KindBoolean:
+		s = "bool"
+	case KindBinary:
+		s = "FileField"
+	case KindArray:
+		s = "List[" + pyType(t.Elem) + "]"
+	case KindMap:
+		s = "Dict[str, " + pyType(t.Elem) + "]"
+	case KindNamed:
+		s = t.Name
+	case KindUnion:
+		variants := make([]string, len(t.Variants))
+		for i, v := range t.Variants {
+			variants[i] = pyType(v)
+		}
+		s = "Union[" + strings.Join(variants, ", ") + "]"
+	default:
+		s = "Any"
+	}
+	if t.Nullable {
+		s = "Optional[" + s + "]"
+	}
+	return s
+}
+
+var pyIdentPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var pyKeywords = map[string]bool{
+	"False": true, "None": true, "True": true, "and": true, "as": true, "assert": true, "async": true,
+	"await": true, "break": true, "class": true, "continue": true, "def": true, "del": true, "elif": true,
+	"else": true, "except": true, "finally": true, "for": true, "from": true, "global": true, "if": true,
+	"import": true, "in": true, "is": true, "lambda": true, "nonlocal": true, "not": true, "or": true,
+	"pass": true, "raise": true, "return": true, "try": true, "while": true, "with": true, "yield": true,
+}
+
+func pyIsIdent(name string) bool {
+	return pyIdentPattern.MatchString(name) && !pyKeywords[name]
+}
+
+// pyIdent appends an underscore to names that are keywords, such as from
+func pyIdent(name string) string {
+	if pyKeywords[name] {
+		return name + "_"
+	}
+	return name
+}
+
+func pyDocstring(w *strings.Builder, indent, text string) {
+	text = strings.TrimSpace(strings.ReplaceAll(strings.ReplaceAll(text, `\`, `\\`), `"""`, `\"\"\"`))
+	if text == "" {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(w, "%s\"\"\"%s\"\"\"\n", indent, text)
+		return
+	}
+	fmt.Fprintf(w, "%s\"\"\"%s\n", indent, lines[0])
+	for _, line := range lines[1:] {
+		fmt.Fprintf(w, "%s\n", strings.TrimRight(indent+line, " "))
+	}
+	fmt.Fprintf(w, "%s\"\"\"\n", indent)
+}
+
+func pyComment(w *strings.Builder, indent, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line != "" {
+			fmt.Fprintf(w, "%s# %s\n", indent, line)
+		}
+	}
+}
+
+// pyRuntime holds the imports and the error type
+const pyRuntime = `from __future__ import annotations
+
+import json
+import mimetypes
+import secrets
+import urllib.error
+import urllib.parse
+import urllib.request
+from typing import Any, Dict, List, Literal, Optional, Tuple, TypedDict, Union
+
+FileField = Tuple[str, bytes]
+"""A file to upload: its name, which also determines its content type, and content"""
+
+
+class ApiError(Exception):
+    """An error answer of the API; branch on code, as messages may change."""
+
+    def __init__(
+        self,
+        status: int,
+        code: str,
+        message: str,
+        details: Any = None,
+        request_id: Optional[str] = None,
+    ) -> None:
+        super().__init__(message)
+        self.status = status
+        self.code = code
+        self.message = message
+        self.details = details
+        # The X-Request-ID of the response, which also appears in the server logs
+        self.request_id = request_id
+`
+
+// pyClient opens the class of the generated methods with what they call
+const pyClient = `class Client:
+    """Calls the API; every method raises ApiError when the server answers with an error."""
+
+    def __init__(
+        self,
+        base_url: str,
+        *,
+        api_key: Optional[str] = None,
+        token: Optional[str] = None,
+        headers: Optional[Dict[str, str]] = None,
+        timeout: float = 30.0,
+    ) -> None:
+        """base_url is the root URL of the service, including server.base_path;
+        api_key is sent as X-API-Key, and token, a JWT or personal token, as
+        Authorization: Bearer."""
+        self._base_url = base_url.rstrip("/") + "{{BASE_PATH}}"
+        self._headers = dict(headers or {})
+        if api_key:
+            self._headers["X-API-Key"] = api_key
+        if token:
+            self._headers["Authorization"] = "Bearer " + token
+        self._timeout = timeout
+
+    def _request(
+        self,
+        method: str,
+        path: str,
+        *,
+        query: Optional[Dict[str, Any]] = None,
+        headers: Optional[Dict[str, Any]] = None,
+        json_body: Any = None,
+        form: Optional[Dict[str, Any]] = None,
+        binary: bool = False,
+    ) -> Any:
+        url = self._base_url + path
+        params = [(name, _param(value)) for name, value in (query or {}).items() if value is not None]
+        if params:
+            url += "?" + urllib.parse.urlencode(params)
+
+        request_headers = dict(self._headers)
+        if not binary:
+            request_headers["Accept"] = "application/json"
+        for name, value in (headers or {}).items():
+            if value is not None:
+                request_headers[name] = _param(value)
+
+        data = None
+        if json_body is not None:
+            request_headers["Content-Type"] = "application/json"
+            data = json.dumps(json_body).encode()
+        elif form is not None:
+            boundary = secrets.token_hex(16)
+            request_headers["Content-Type"] = "multipart/form-data; boundary=" + boundary
+            data = _multipart(form, boundary)
+
+        request = urllib.request.Request(url, data=data, headers=request_headers, method=method)
+        try:
+            with urllib.request.urlopen(request, timeout=self._timeout) as response:
+                body = response.read()
+        except urllib.error.HTTPError as err:
+            raise _api_error(err) from None
+        if binary:
+            return body
+        return json.loads(body) if body else None
+`
+
+// pyHelpers encode parameters and bodies and decode errors
+const pyHelpers = `
+
+def _param(value: Any) -> str:
+    if isinstance(value, bool):
+        return "true" if value else "false"
+    return str(value)
+
+
+def _path(value: Any) -> str:
+    return urllib.parse.quote(str(value), safe="")
+
+
+def _multipart(fields: Dict[str, Any], boundary: str) -> bytes:
+    parts = []
+    for name, value in fields.items():
+        if value is None:
+            continue
+        if isinstance(value, tuple):
+            filename, content = value
+            content_type = mimetypes.guess_type(filename)[0] or "application/octet-stream"
+            head = 'Content-Disposition: form-data; name="%s"; filename="%s"\r\nContent-Type: %s\r\n\r\n' % (
+                name,
+                filename.replace('"', "%22"),
+                content_type,
+            )
+        else:
+            head = 'Content-Disposition: form-data; name="%s"\r\n\r\n' % name
+            content = _param(value).encode()
+        parts.append(b"--" + boundary.encode() + b"\r\n" + head.encode() + content + b"\r\n")
+    return b"".join(parts) + b"--" + boundary.encode() + b"--\r\n"
+
+
+def _api_error(err: urllib.error.HTTPError) -> ApiError:
+    try:
+        body = json.loads(err.read())
+    except ValueError:
+        body = None
+    if not isinstance(body, dict):
+        body = {}
+    return ApiError(
+        err.code,
+        body.get("code", "unknown"),
+        body.get("message", str(err.reason)),
+        body.get("details"),
+        err.headers.get("X-Request-ID"),
+    )
+`
@@ -0,0 +1,184 @@
+package sdkgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cruder/api"
+)
+
+func TestClientsUpToDate(t *testing.T) {
+	// Given: The clients generated from the spec the server embeds
+	files, err := Generate(api.Spec, Languages)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	for name, want := range files {
+		// When: Reading the committed client
+		got, err := os.ReadFile(filepath.Join("..", "..", "clients", filepath.FromSlash(name)))
+
+		// Then: It matches what the spec generates
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("clients/%s is out of date with api/openapi.yaml; run go run ./cmd sdk generate", name)
+		}
+	}
+}
+
+const testSpec = `
+openapi: 3.0.3
+info: { title: test, version: "1" }
+servers:
+  - url: ../api/v1
+paths:
+  /things/{id}:
+    parameters:
+      - { name: id, in: path, required: true, schema: { type: integer } }
+    patch:
+      tags: [things]
+      operationId: updateThing
+      parameters:
+        - { name: from, in: query, required: true, schema: { type: string } }
+        - { name: X-Trace-Token, in: header, schema: { type: string } }
+      requestBody:
+        content:
+          application/json:
+            schema:
+              type: object
+              properties:
+                tags: { type: array, items: { type: object, properties: { name: { type: string } } } }
+      responses:
+        "200":
+          content:
+            application/json:
+              schema: { $ref: "#/components/schemas/Thing" }
+        "202":
+          content:
+            application/json:
+              schema: { $ref: "#/components/schemas/Thing" }
+        "204":
+          description: Nothing changed
+components:
+  schemas:
+    Thing:
+      type: object
+      required: [id]
+      properties:
+        id: { type: integer }
+        kind: { type: string, enum: [a, b], nullable: true }
+`
+
+func TestLoad(t *testing.T) {
+	// When: Loading a spec
+	api, err := Load([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Then: The operation has its path's parameters, and its own by location
+	op := api.Operations[0]
+	if api.BasePath != "/api/v1" || op.ID != "updateThing" || op.Method != "PATCH" {
+		t.Errorf("unexpected operation %+v in %s", op, api.BasePath)
+	}
+	if len(op.PathParams) != 1 || op.PathParams[0].Type.Kind != KindInteger || len(op.Query) != 1 || !op.Query[0].Required {
+		t.Errorf("unexpected parameters %+v %+v", op.PathParams, op.Query)
+	}
+	if len(op.Headers) != 1 || op.Headers[0].Ident != "trace_token" {
+		t.Errorf("expected the header as trace_token, got %+v", op.Headers)
+	}
+	// ...the same result once, and may answer without a body
+	if len(op.Results) != 1 || op.Results[0].Name != "Thing" || !op.NoContent {
+		t.Errorf("unexpected results %+v, no content %v", op.Results, op.NoContent)
+	}
+	// ...and its inline objects are named after the operation and field
+	var names []string
+	for _, typ := range api.Types {
+		names = append(names, typ.Name)
+	}
+	if strings.Join(names, " ") != "Thing UpdateThingRequest UpdateThingRequestTag" {
+		t.Errorf("unexpected types %v", names)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	// When: Generating the clients of a spec
+	files, err := Generate([]byte(testSpec), Languages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Then: Each has the method and types, in the idiom of its language
+	for name, want := range map[string][]string{
+		"typescript/cruder.ts": {
+			"updateThing(id: number, body: UpdateThingRequest, params: UpdateThingParams): Promise<Thing | undefined>",
+			"path: `/things/${encodeURIComponent(String(id))}`",
+			`headers: { "X-Trace-Token": params.trace_token }`,
+			`kind?: "a" | "b" | null;`,
+		},
+		"python/cruder_client.py": {
+			"from_: str,",
+			"trace_token: Optional[str] = None,",
+			") -> Optional[Thing]:",
+			`f"/things/{_path(id)}",`,
+			`query={"from": from_},`,
+			`headers={"X-Trace-Token": trace_token},`,
+			`kind: Optional[Literal["a", "b"]]`,
+		},
+	} {
+		for _, s := range want {
+			if !strings.Contains(string(files[name]), s) {
+				t.Errorf("%s lacks %q", name, s)
+			}
+		}
+	}
+
+	// When/Then: Asking for an unknown language fails
+	if _, err := Generate([]byte(testSpec), []string{"cobol"}); err == nil {
+		t.Error("expected an error for an unknown language")
+	}
+}
+
+func TestPostman(t *testing.T) {
+	// Given: A spec without security requirements
+	api, err := Load([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When: Converting it into a Postman collection
+	data, err := Postman(api, "http://localhost:8080/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection pmCollection
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	// Then: baseUrl points at the server, and the operation is in its tag's folder
+	if collection.Variable[0].Key != "baseUrl" || collection.Variable[0].Value != "http://localhost:8080/api/v1" {
+		t.Errorf("unexpected variables %+v", collection.Variable)
+	}
+	if len(collection.Item) != 1 || collection.Item[0].Name != "things" || len(collection.Item[0].Item) != 1 {
+		t.Fatalf("expected one folder with one request, got %+v", collection.Item)
+	}
+	req := collection.Item[0].Item[0].Request
+	// ...with the path parameter as a variable and only required parameters enabled
+	if req.Method != "PATCH" || req.URL.Raw != "{{baseUrl}}/things/:id?from=" || req.URL.Variable[0].Key != "id" {
+		t.Errorf("unexpected request %s %+v", req.Method, req.URL)
+	}
+	if req.Header[0].Key != "X-Trace-Token" || !req.Header[0].Disabled {
+		t.Errorf("expected the optional header disabled, got %+v", req.Header)
+	}
+	// ...an example body, and no credentials, as the spec asks for none
+	if req.Body.Raw != "{\n  \"tags\": [\n    {\n      \"name\": \"string\"\n    }\n  ]\n}" {
+		t.Errorf("unexpected body %s", req.Body.Raw)
+	}
+	if req.Auth == nil || req.Auth.Type != "noauth" {
+		t.Errorf("expected noauth, got %+v", req.Auth)
+	}
+}
@@ -0,0 +1,154 @@
+package sdkgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The subset of OpenAPI 3 that api/openapi.yaml uses. Maps whose order shows in
+// the clients are read as ordered lists, so the output follows the spec.
+
+type document struct {
+	Info struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+		Version     string `yaml:"version"`
+	} `yaml:"info"`
+	Servers []struct {
+		URL string `yaml:"url"`
+	} `yaml:"servers"`
+	Security   []requirement     `yaml:"security"`
+	Paths      ordered[pathItem] `yaml:"paths"`
+	Components struct {
+		Schemas    ordered[*schema]     `yaml:"schemas"`
+		Parameters map[string]parameter `yaml:"parameters"`
+		Responses  map[string]response  `yaml:"responses"`
+	} `yaml:"components"`
+}
+
+type pathItem struct {
+	Parameters []parameter `yaml:"parameters"`
+	Get        *operation  `yaml:"get"`
+	Post       *operation  `yaml:"post"`
+	Put        *operation  `yaml:"put"`
+	Patch      *operation  `yaml:"patch"`
+	Delete     *operation  `yaml:"delete"`
+}
+
+type operation struct {
+	OperationID string   `yaml:"operationId"`
+	Tags        []string `yaml:"tags"`
+	Summary     string   `yaml:"summary"`
+	Description string   `yaml:"description"`
+	// Security is nil when the operation takes the document's, and empty when
+	// it needs no credentials
+	Security    *[]requirement    `yaml:"security"`
+	Parameters  []parameter       `yaml:"parameters"`
+	RequestBody *requestBody      `yaml:"requestBody"`
+	Responses   ordered[response] `yaml:"responses"`
+}
+
+// requirement names a security scheme, and the scopes it needs, that satisfies
+// an operation
+type requirement map[string][]string
+
+type parameter struct {
+	Ref         string  `yaml:"$ref"`
+	Name        string  `yaml:"name"`
+	In          string  `yaml:"in"`
+	Required    bool    `yaml:"required"`
+	Description string  `yaml:"description"`
+	Schema      *schema `yaml:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `yaml:"required"`
+	Content  map[string]mediaType `yaml:"content"`
+}
+
+type response struct {
+	Ref     string               `yaml:"$ref"`
+	Content map[string]mediaType `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `yaml:"schema"`
+}
+
+type schema struct {
+	Ref                  string           `yaml:"$ref"`
+	Type                 string           `yaml:"type"`
+	Format               string           `yaml:"format"`
+	Description          string           `yaml:"description"`
+	Example              any              `yaml:"example"`
+	Enum                 []string         `yaml:"enum"`
+	Nullable             bool             `yaml:"nullable"`
+	Required             []string         `yaml:"required"`
+	Properties           ordered[*schema] `yaml:"properties"`
+	AdditionalProperties *additional      `yaml:"additionalProperties"`
+	Items                *schema          `yaml:"items"`
+	AllOf                []*schema        `yaml:"allOf"`
+	OneOf                []*schema        `yaml:"oneOf"`
+}
+
+// additional is additionalProperties, which is either true or a schema
+type additional struct {
+	Schema *schema
+}
+
+func (a *additional) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return nil
+	}
+	return node.Decode(&a.Schema)
+}
+
+// entry is a key of a YAML mapping and its value
+type entry[T any] struct {
+	Key   string
+	Value T
+}
+
+// ordered is a YAML mapping that keeps the order of its keys
+type ordered[T any] []entry[T]
+
+func (o *ordered[T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: expected a mapping", node.Line)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var value T
+		if err := node.Content[i+1].Decode(&value); err != nil {
+			return err
+		}
+		*o = append(*o, entry[T]{Key: node.Content[i].Value, Value: value})
+	}
+	return nil
+}
+
+// MarshalJSON writes the mapping as a JSON object in its order
+func (o ordered[T]) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
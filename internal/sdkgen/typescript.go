@@ -0,0 +1,336 @@
+package sdkgen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// typescript renders the API as one module for browsers and Node 18+, using the
+// global fetch
+func typescript(api *API) []byte {
+	var w strings.Builder
+	fmt.Fprintf(&w, "// %s\n//\n// Client of the %s API, version %s. Pass the root URL of the service,\n", generatedHeader, api.Title, api.Version)
+	w.WriteString("// including server.base_path, and an API key or a bearer token:\n//\n")
+	w.WriteString("//\tconst client = new Client({ baseUrl: \"https://users.example.com\", apiKey: \"...\" });\n")
+	w.WriteString("//\tconst users = await client.listUsers({ email_domain: \"example.com\" });\n\n")
+	w.WriteString(tsRuntime)
+
+	for _, t := range api.Types {
+		w.WriteString("\n")
+		tsDoc(&w, "", t.Description)
+		if t.Alias != nil {
+			fmt.Fprintf(&w, "export type %s = %s;\n", t.Name, tsType(t.Alias))
+			continue
+		}
+		extends := ""
+		if len(t.Bases) > 0 {
+			extends = " extends " + strings.Join(t.Bases, ", ")
+		}
+		fmt.Fprintf(&w, "export interface %s%s {\n", t.Name, extends)
+		for _, f := range t.Fields {
+			tsDoc(&w, "  ", f.Description)
+			fmt.Fprintf(&w, "  %s%s: %s;\n", tsKey(f.Name), optional(f.Required), tsType(f.Type))
+		}
+		w.WriteString("}\n")
+	}
+	for _, op := range api.Operations {
+		params := append(append([]Param{}, op.Query...), op.Headers...)
+		if len(params) == 0 {
+			continue
+		}
+		fmt.Fprintf(&w, "\n/** Query and header parameters of %s */\nexport interface %sParams {\n", op.ID, pascal(op.ID))
+		for _, p := range params {
+			tsDoc(&w, "  ", p.Description)
+			fmt.Fprintf(&w, "  %s%s: %s;\n", tsKey(p.Ident), optional(p.Required), tsType(p.Type))
+		}
+		w.WriteString("}\n")
+	}
+
+	w.WriteString(strings.ReplaceAll(tsClient, "{{BASE_PATH}}", api.BasePath))
+	for _, op := range api.Operations {
+		w.WriteString("\n")
+		tsOperation(&w, op)
+	}
+	w.WriteString("}\n")
+	return []byte(w.String())
+}
+
+func tsOperation(w *strings.Builder, op *Operation) {
+	doc := op.Summary
+	if op.Description != "" {
+		doc += "\n\n" + op.Description
+	}
+	tsDoc(w, "  ", doc)
+
+	var args []string
+	for _, p := range op.PathParams {
+		args = append(args, fmt.Sprintf("%s: %s", camel(p.Ident), tsType(p.Type)))
+	}
+	if op.Body != nil {
+		args = append(args, "body: "+tsType(op.Body))
+	}
+	params := append(append([]Param{}, op.Query...), op.Headers...)
+	if len(params) > 0 {
+		arg := fmt.Sprintf("params: %sParams", pascal(op.ID))
+		if !anyRequired(params) {
+			arg += " = {}"
+		}
+		args = append(args, arg)
+	}
+
+	var result string
+	switch {
+	case op.Binary:
+		result = "Blob"
+	case len(op.Results) == 0:
+		result = "void"
+	default:
+		var types []string
+		for _, r := range op.Results {
+			types = append(types, tsType(r))
+		}
+		if op.NoContent {
+			types = append(types, "undefined")
+		}
+		result = strings.Join(types, " | ")
+	}
+	fmt.Fprintf(w, "  %s(%s): Promise<%s> {\n", op.ID, strings.Join(args, ", "), result)
+
+	path := strconv.Quote(op.Path)
+	if len(op.PathParams) > 0 {
+		path = "`" + pathParam.ReplaceAllStringFunc(op.Path, func(m string) string {
+			return "${encodeURIComponent(String(" + camel(snake(m[1:len(m)-1])) + "))}"
+		}) + "`"
+	}
+	fields := []string{"method: " + strconv.Quote(op.Method), "path: " + path}
+	if len(op.Query) > 0 {
+		fields = append(fields, "query: "+tsParams(op.Query, "      "))
+	}
+	if len(op.Headers) > 0 {
+		fields = append(fields, "headers: "+tsParams(op.Headers, "      "))
+	}
+	if op.Body != nil && op.Multipart {
+		fields = append(fields, "form: body")
+	} else if op.Body != nil {
+		fields = append(fields, "json: body")
+	}
+	if op.Binary {
+		fields = append(fields, "binary: true")
+	}
+	fmt.Fprintf(w, "    return this.request(%s);\n  }\n", tsObject(fields, "    ", len("    return this.request();")))
+}
+
+// tsParams maps the wire names of params to their values
+func tsParams(params []Param, indent string) string {
+	var fields []string
+	for _, p := range params {
+		fields = append(fields, fmt.Sprintf("%s: params.%s", tsKey(p.Name), p.Ident))
+	}
+	return tsObject(fields, indent, len(indent)+len("query: ,"))
+}
+
+// tsObject renders an object literal on one line when it fits in maxLine next to
+// the used columns, and one field per line below indent otherwise
+func tsObject(fields []string, indent string, used int) string {
+	line := "{ " + strings.Join(fields, ", ") + " }"
+	if used+len(line) <= maxLine && !strings.Contains(line, "\n") {
+		return line
+	}
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s  %s,\n", indent, f)
+	}
+	return b.String() + indent + "}"
+}
+
+func tsType(t *TypeRef) string {
+	var s string
+	switch t.Kind {
+	case KindString:
+		s = "string"
+		if len(t.Enum) > 0 {
+			quoted := make([]string, len(t.Enum))
+			for i, v := range t.Enum {
+				quoted[i] = strconv.Quote(v)
+			}
+			s = strings.Join(quoted, " | ")
+		}
+	case KindInteger, KindNumber:
+		s = "number"
+	case KindBoolean:
+		s = "boolean"
+	case KindBinary:
+		s = "Blob"
+	case KindArray:
+		s = tsType(t.Elem)
+		if strings.Contains(s, " ") {
+			s = "(" + s + ")"
+		}
+		s += "[]"
+	case KindMap:
+		s = "Record<string, " + tsType(t.Elem) + ">"
+	case KindNamed:
+		s = t.Name
+	case KindUnion:
+		variants := make([]string, len(t.Variants))
+		for i, v := range t.Variants {
+			variants[i] = tsType(v)
+		}
+		s = strings.Join(variants, " | ")
+	default:
+		s = "unknown"
+	}
+	if t.Nullable {
+		s += " | null"
+	}
+	return s
+}
+
+var tsIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsKey quotes property names that are not identifiers
+func tsKey(name string) string {
+	if tsIdent.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+func tsDoc(w *strings.Builder, indent, text string) {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "*/", "*\\/"))
+	if text == "" {
+		return
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(w, "%s/** %s */\n", indent, text)
+		return
+	}
+	fmt.Fprintf(w, "%s/**\n", indent)
+	for _, line := range lines {
+		fmt.Fprintf(w, "%s%s\n", indent, strings.TrimRight(" * "+line, " "))
+	}
+	fmt.Fprintf(w, "%s */\n", indent)
+}
+
+func optional(required bool) string {
+	if required {
+		return ""
+	}
+	return "?"
+}
+
+func camel(s string) string {
+	p := pascal(s)
+	if p == "" {
+		return p
+	}
+	return strings.ToLower(p[:1]) + p[1:]
+}
+
+func anyRequired(params []Param) bool {
+	for _, p := range params {
+		if p.Required {
+			return true
+		}
+	}
+	return false
+}
+
+// pathParam matches the {param} segments of a path
+var pathParam = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// tsRuntime sends the requests of the generated methods
+const tsRuntime = `export interface ClientOptions {
+  /** Root URL of the service, including server.base_path, e.g. https://users.example.com */
+  baseUrl: string;
+  /** Sent as X-API-Key */
+  apiKey?: string;
+  /** A JWT or personal token, sent as Authorization: Bearer */
+  token?: string;
+  /** Headers sent with every request */
+  headers?: Record<string, string>;
+  /** Used instead of the global fetch, e.g. to add retries */
+  fetch?: typeof fetch;
+}
+
+/** An error answer of the API; branch on code, as messages may change. */
+export class ApiError extends globalThis.Error {
+  constructor(
+    readonly status: number,
+    readonly code: string,
+    message: string,
+    readonly details?: unknown,
+    /** The X-Request-ID of the response, which also appears in the server logs */
+    readonly requestId?: string,
+  ) {
+    super(message);
+    this.name = "ApiError";
+  }
+}
+
+type ParamValue = string | number | boolean | undefined;
+
+interface ApiRequest {
+  method: string;
+  path: string;
+  query?: Record<string, ParamValue>;
+  headers?: Record<string, ParamValue>;
+  json?: unknown;
+  form?: object;
+  binary?: boolean;
+}
+`
+
+// tsClient opens the class of the generated methods with what they call
+const tsClient = `
+/** Calls the API; every method throws an ApiError when the server answers with an error. */
+export class Client {
+  private readonly baseUrl: string;
+  private readonly fetch: typeof fetch;
+
+  constructor(private readonly options: ClientOptions) {
+    this.baseUrl = options.baseUrl.replace(/\/+$/, "") + "{{BASE_PATH}}";
+    this.fetch = options.fetch ?? globalThis.fetch.bind(globalThis);
+  }
+
+  private async request<T>(req: ApiRequest): Promise<T> {
+    const url = new URL(this.baseUrl + req.path);
+    for (const [name, value] of Object.entries(req.query ?? {})) {
+      if (value !== undefined) url.searchParams.set(name, String(value));
+    }
+
+    const headers: Record<string, string> = { ...this.options.headers };
+    if (!req.binary) headers["Accept"] = "application/json";
+    if (this.options.apiKey) headers["X-API-Key"] = this.options.apiKey;
+    if (this.options.token) headers["Authorization"] = ` + "`Bearer ${this.options.token}`" + `;
+    for (const [name, value] of Object.entries(req.headers ?? {})) {
+      if (value !== undefined) headers[name] = String(value);
+    }
+
+    let body: string | FormData | undefined;
+    if (req.json !== undefined) {
+      headers["Content-Type"] = "application/json";
+      body = JSON.stringify(req.json);
+    } else if (req.form !== undefined) {
+      const form = new FormData();
+      for (const [name, value] of Object.entries(req.form)) {
+        if (value !== undefined) form.append(name, value instanceof Blob ? value : String(value));
+      }
+      body = form;
+    }
+
+    const res = await this.fetch(url, { method: req.method, headers, body });
+    if (!res.ok) {
+      const err = await res.json().catch(() => ({}));
+      const requestId = res.headers.get("X-Request-ID") ?? undefined;
+      throw new ApiError(res.status, err.code ?? "unknown", err.message ?? res.statusText, err.details, requestId);
+    }
+    if (req.binary) return (await res.blob()) as T;
+    const text = await res.text();
+    return (text ? JSON.parse(text) : undefined) as T;
+  }
+`
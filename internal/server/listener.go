@@ -0,0 +1,96 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+
+	"cruder/internal/config"
+)
+
+// systemd passes activated sockets starting at this file descriptor
+const listenFDsStart = 3
+
+// Listen creates the listener described by cfg. With systemd activation enabled and
+// LISTEN_FDS present, the first inherited socket is used instead.
+func Listen(cfg config.ServerConfig) (net.Listener, error) {
+	if cfg.SystemdActivation {
+		ln, err := systemdListener()
+		if err != nil {
+			return nil, err
+		}
+		if ln != nil {
+			return ln, nil
+		}
+	}
+
+	switch cfg.Network {
+	case "tcp":
+		ln, err := net.Listen("tcp", cfg.Address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", cfg.Address, err)
+		}
+		return ln, nil
+	case "unix":
+		return unixListener(cfg.SocketPath, cfg.SocketMode)
+	default:
+		return nil, fmt.Errorf("unsupported server.network %q (expected tcp or unix)", cfg.Network)
+	}
+}
+
+func unixListener(path, mode string) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("server.socket_path is required when server.network is unix")
+	}
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server.socket_mode %q: %w", mode, err)
+	}
+
+	// Remove a stale socket left behind by a previous run
+	if info, err := os.Lstat(path); err == nil && info.Mode()&fs.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", path, err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, fs.FileMode(perm)); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("failed to chmod socket %s: %w", path, err)
+	}
+	return ln, nil
+}
+
+// systemdListener returns the socket passed by systemd, or nil when the process was
+// not socket-activated
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, nil
+	}
+
+	// Keep the variables from leaking into child processes
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(uintptr(listenFDsStart), "systemd-socket")
+	defer func() { _ = f.Close() }()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use systemd socket: %w", err)
+	}
+	return ln, nil
+}
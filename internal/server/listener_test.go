@@ -0,0 +1,233 @@
+package server
+
+import (
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+)
+
+// helperEnv names the helper a re-executed test binary runs instead of the tests
+const helperEnv = "CRUDER_TEST_HELPER"
+
+var helpers = map[string]func() int{
+	"systemd": systemdHelper,
+	"upgrade": upgradeHelper,
+}
+
+func TestMain(m *testing.M) {
+	if name := os.Getenv(helperEnv); name != "" {
+		os.Exit(helpers[name]())
+	}
+	os.Exit(m.Run())
+}
+
+// helperCommand re-executes the test binary to run the named helper
+func helperCommand(t *testing.T, name string) *exec.Cmd {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to resolve the test binary: %v", err)
+	}
+	cmd := exec.Command(executable)
+	cmd.Env = append(os.Environ(), helperEnv+"="+name)
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+func TestUnixListener_Permissions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cruder.sock")
+
+	// When: Listening on a socket with mode 0660
+	ln, err := unixListener(path, "0660")
+	if err != nil {
+		t.Fatalf("expected a listener, got %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	// Then: The socket exists with that mode and accepts connections
+	info, err := os.Lstat(path)
+	if err != nil {
+		t.Fatalf("expected the socket to exist: %v", err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		t.Errorf("expected a socket, got mode %s", info.Mode())
+	}
+	if perm := info.Mode().Perm(); perm != 0o660 {
+		t.Errorf("expected mode 0660, got %o", perm)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("expected to connect, got %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestUnixListener_RemovesStaleSocket(t *testing.T) {
+	// Given: A socket left behind by a previous run
+	path := filepath.Join(t.TempDir(), "cruder.sock")
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to create the stale socket: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	_ = stale.Close()
+
+	// When: Listening on the same path
+	ln, err := unixListener(path, "0600")
+
+	// Then: The stale socket is replaced
+	if err != nil {
+		t.Fatalf("expected the stale socket to be replaced, got %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("expected to connect, got %v", err)
+	}
+	_ = conn.Close()
+}
+
+func TestUnixListener_KeepsOtherFiles(t *testing.T) {
+	// Given: A regular file at the socket path
+	path := filepath.Join(t.TempDir(), "cruder.sock")
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatalf("failed to write the file: %v", err)
+	}
+
+	// When: Listening on that path
+	_, err := unixListener(path, "0600")
+
+	// Then: Listening fails and the file is left alone
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("expected the file to be kept, got %q, %v", data, err)
+	}
+}
+
+func TestUnixListener_InvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+		mode string
+	}{
+		{"missing path", "", "0660"},
+		{"mode not octal", filepath.Join(dir, "a.sock"), "0690"},
+		{"mode not a number", filepath.Join(dir, "b.sock"), "rw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ln, err := unixListener(tt.path, tt.mode); err == nil {
+				_ = ln.Close()
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestListen_UnsupportedNetwork(t *testing.T) {
+	if _, err := Listen(config.ServerConfig{Network: "udp"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestSystemdListener_NotActivated(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name      string
+		listenPID string
+		listenFDs string
+	}{
+		{"no variables", "", ""},
+		{"another process", strconv.Itoa(os.Getpid() + 1), "1"},
+		{"pid not a number", "self", "1"},
+		{"no sockets", pid, "0"},
+		{"fds not a number", pid, "one"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.listenPID)
+			t.Setenv("LISTEN_FDS", tt.listenFDs)
+
+			// When: Looking for a systemd socket
+			ln, err := systemdListener()
+
+			// Then: None is used, and the variables are left for whoever they are meant for
+			if ln != nil || err != nil {
+				t.Fatalf("expected no listener, got %v, %v", ln, err)
+			}
+			if os.Getenv("LISTEN_FDS") != tt.listenFDs {
+				t.Error("expected LISTEN_FDS to be kept")
+			}
+		})
+	}
+}
+
+func TestSystemdListener_Activated(t *testing.T) {
+	// Given: A socket passed to a process as systemd does, at fd 3 with LISTEN_FDS=1
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatalf("failed to dup the listener: %v", err)
+	}
+	cmd := helperCommand(t, "systemd")
+	cmd.Env = append(cmd.Env, "LISTEN_FDS=1")
+	cmd.ExtraFiles = []*os.File{f}
+
+	// When: The process starts, and this one stops listening
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start the helper: %v", err)
+	}
+	_ = f.Close()
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	// Then: The process serves the socket
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("expected the helper to serve the socket, got %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reply, _ := io.ReadAll(conn)
+	_ = conn.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("helper failed: %v", err)
+	}
+	if string(reply) != "ok" {
+		t.Errorf("expected ok, got %q", reply)
+	}
+}
+
+// systemdHelper serves one connection on the socket systemd would pass. systemd sets
+// LISTEN_PID once it has forked, so the helper sets it to its own pid.
+func systemdHelper() int {
+	_ = os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	ln, err := systemdListener()
+	if err != nil || ln == nil {
+		return 1
+	}
+	defer func() { _ = ln.Close() }()
+	if os.Getenv("LISTEN_PID") != "" || os.Getenv("LISTEN_FDS") != "" {
+		return 2
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		return 3
+	}
+	_, _ = conn.Write([]byte("ok"))
+	_ = conn.Close()
+	return 0
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"cruder/internal/config"
+)
+
+// Server runs the HTTP handler on the configured listener and shuts down gracefully
+// on SIGINT/SIGTERM. With graceful upgrades enabled, SIGHUP starts a new copy of the
+// binary that inherits the listening socket; this process exits once the new one is
+// ready, so no connection is refused during the restart.
+//
+// When an admin handler is given and server.admin_address is set, operational
+// endpoints are served on that second, internally-bound listener. A gRPC server
+// given with WithGRPC is served on server.grpc.address, and handed over on
+// upgrades like the others.
+type Server struct {
+	cfg   config.ServerConfig
+	http  *http.Server
+	admin *http.Server
+	grpc  GRPCServer
+}
+
+// GRPCServer is what Server needs of a *grpc.Server
+type GRPCServer interface {
+	Serve(ln net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// New creates a Server for handler; admin may be nil
+func New(cfg config.ServerConfig, handler http.Handler, admin http.Handler) *Server {
+	s := &Server{
+		cfg:  cfg,
+		http: &http.Server{Handler: handler},
+	}
+	if admin != nil && cfg.AdminAddress != "" {
+		s.admin = &http.Server{Handler: admin}
+	}
+	return s
+}
+
+// WithGRPC serves srv too, on server.grpc.address
+func (s *Server) WithGRPC(srv GRPCServer) *Server {
+	s.grpc = srv
+	return s
+}
+
+// Run listens, serves and blocks until the process is asked to stop
+func (s *Server) Run() error {
+	listeners, ready, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	servers := []func(net.Listener) error{s.http.Serve}
+	if s.admin != nil {
+		servers = append(servers, s.admin.Serve)
+	}
+	if s.grpc != nil {
+		servers = append(servers, s.grpc.Serve)
+	}
+	errCh := make(chan error, len(listeners))
+	for i, ln := range listeners {
+		log.Printf("listening on %s %s", ln.Addr().Network(), ln.Addr().String())
+		go func(serve func(net.Listener) error, ln net.Listener) {
+			errCh <- served(serve(ln))
+		}(servers[i], ln)
+	}
+	signalReady(ready)
+
+	signals := []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+	if s.cfg.GracefulUpgrade {
+		signals = append(signals, syscall.SIGHUP)
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, signals...)
+	defer signal.Stop(stop)
+
+	for {
+		select {
+		case err := <-errCh:
+			return err
+		case sig := <-stop:
+			if sig == syscall.SIGHUP {
+				log.Printf("received %s, starting graceful upgrade", sig)
+				if err := spawnUpgrade(listeners); err != nil {
+					log.Printf("graceful upgrade failed, continuing to serve: %v", err)
+					continue
+				}
+			} else {
+				log.Printf("received %s, shutting down", sig)
+			}
+			return s.Shutdown()
+		}
+	}
+}
+
+// listen reuses listeners inherited from a parent process or creates new ones.
+// The main listener always comes first, followed by the admin listener and the
+// gRPC listener if enabled.
+func (s *Server) listen() ([]net.Listener, *os.File, error) {
+	want := 1
+	if s.admin != nil {
+		want++
+	}
+	if s.grpc != nil {
+		want++
+	}
+
+	inherited, ready, err := inheritedListeners()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(inherited) > 0 {
+		if len(inherited) != want {
+			return nil, nil, fmt.Errorf("inherited %d listeners, expected %d", len(inherited), want)
+		}
+		return inherited, ready, nil
+	}
+
+	ln, err := Listen(s.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	listeners := []net.Listener{ln}
+
+	if s.admin != nil {
+		adminLn, err := net.Listen("tcp", s.cfg.AdminAddress)
+		if err != nil {
+			_ = ln.Close()
+			return nil, nil, fmt.Errorf("failed to listen on admin address %s: %w", s.cfg.AdminAddress, err)
+		}
+		listeners = append(listeners, adminLn)
+	}
+	if s.grpc != nil {
+		grpcLn, err := net.Listen("tcp", s.cfg.GRPC.Address)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, nil, fmt.Errorf("failed to listen on gRPC address %s: %w", s.cfg.GRPC.Address, err)
+		}
+		listeners = append(listeners, grpcLn)
+	}
+	return listeners, nil, nil
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests
+func (s *Server) Shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
+	defer cancel()
+
+	if s.admin != nil {
+		if err := s.admin.Shutdown(ctx); err != nil {
+			return fmt.Errorf("graceful shutdown of admin listener failed: %w", err)
+		}
+	}
+	if s.grpc != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpc.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			// Cancel the calls still running once the timeout is up
+			s.grpc.Stop()
+		}
+	}
+	if err := s.http.Shutdown(ctx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+	return nil
+}
+
+// served is the error a Serve method returned, or nil when it returned because the
+// server was shut down
+func served(err error) error {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
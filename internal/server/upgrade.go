@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// upgradeEnv carries the number of listener descriptors handed to an upgraded process.
+// The listeners start at fd 3, followed by the write end of the readiness pipe.
+const upgradeEnv = "CRUDER_UPGRADE_FDS"
+
+// upgradeReadyTimeout bounds how long the old process waits for its replacement
+const upgradeReadyTimeout = 30 * time.Second
+
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+// inheritedListeners returns listeners handed over by a parent process during a
+// graceful upgrade, together with the pipe used to report readiness back.
+// It returns nil, nil, nil when the process was started normally.
+func inheritedListeners() ([]net.Listener, *os.File, error) {
+	raw := os.Getenv(upgradeEnv)
+	if raw == "" {
+		return nil, nil, nil
+	}
+	_ = os.Unsetenv(upgradeEnv)
+
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return nil, nil, fmt.Errorf("invalid %s value %q", upgradeEnv, raw)
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		f := os.NewFile(uintptr(listenFDsStart+i), "inherited-listener-"+strconv.Itoa(i))
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to use inherited listener %d: %w", i, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	ready := os.NewFile(uintptr(listenFDsStart+n), "upgrade-ready")
+	return listeners, ready, nil
+}
+
+// signalReady tells the parent process that this process is serving
+func signalReady(ready *os.File) {
+	if ready == nil {
+		return
+	}
+	if _, err := ready.Write([]byte("ok")); err != nil {
+		log.Printf("failed to notify parent process: %v", err)
+	}
+	_ = ready.Close()
+}
+
+// spawnUpgrade starts a new copy of the current binary that inherits listeners and
+// blocks until it reports readiness. On error the caller keeps serving.
+func spawnUpgrade(listeners []net.Listener) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to resolve executable: %w", err)
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, ln := range listeners {
+		fl, ok := ln.(fileListener)
+		if !ok {
+			return fmt.Errorf("listener %s cannot be handed over", ln.Addr())
+		}
+		f, err := fl.File()
+		if err != nil {
+			return fmt.Errorf("failed to dup listener %s: %w", ln.Addr(), err)
+		}
+		files = append(files, f)
+	}
+
+	readR, readyW, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("failed to create readiness pipe: %w", err)
+	}
+	defer func() { _ = readR.Close() }()
+	files = append(files, readyW)
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), upgradeEnv+"="+strconv.Itoa(len(listeners)))
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start new process: %w", err)
+	}
+	// The child owns its copy; close ours so EOF is seen if the child dies
+	_ = readyW.Close()
+	files = files[:len(files)-1]
+
+	result := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 2)
+		_, err := io.ReadFull(readR, buf)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return errors.New("new process exited before becoming ready")
+		}
+	case <-time.After(upgradeReadyTimeout):
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.New("timed out waiting for new process to become ready")
+	}
+
+	log.Printf("new process %d is ready, handing over", cmd.Process.Pid)
+	// Unix sockets must outlive this process now that the child serves them
+	for _, ln := range listeners {
+		if ul, ok := ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	return nil
+}
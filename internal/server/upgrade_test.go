@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSpawnUpgrade_HandsOverListener(t *testing.T) {
+	// Given: A server answering "old" on a listener
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	url := "http://" + ln.Addr().String()
+	old := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "old")
+	})}
+	go func() { _ = old.Serve(ln) }()
+	// The new process runs upgradeHelper instead of the tests
+	t.Setenv(helperEnv, "upgrade")
+
+	// And: A client calling it on fresh connections throughout
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{DisableKeepAlives: true}}
+	var (
+		mu      sync.Mutex
+		refused int
+		servers = map[string]int{}
+	)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			resp, err := client.Get(url)
+			mu.Lock()
+			// A connection the old process accepted but had not read from when it shut
+			// down is closed unanswered, as http.Server.Shutdown does; only refusals count
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				refused++
+			} else if err == nil {
+				body, _ := io.ReadAll(resp.Body)
+				_ = resp.Body.Close()
+				servers[string(body)]++
+			}
+			mu.Unlock()
+		}
+	}()
+	t.Cleanup(func() {
+		if resp, err := client.Get(url + "/exit"); err == nil {
+			_ = resp.Body.Close()
+		}
+	})
+
+	// When: The listener is handed to a new process, and this one shuts down as Run does
+	time.Sleep(50 * time.Millisecond)
+	if err := spawnUpgrade([]net.Listener{ln}); err != nil {
+		close(stop)
+		<-done
+		t.Fatalf("expected the upgrade to succeed, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := old.Shutdown(ctx); err != nil {
+		t.Fatalf("failed to shut down: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	close(stop)
+	<-done
+
+	// Then: No connection was refused, and the new process took over
+	if refused > 0 {
+		t.Errorf("expected no refused connections, got %d", refused)
+	}
+	if servers["new"] == 0 {
+		t.Errorf("expected the new process to serve requests, got %v", servers)
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("expected the new process to serve after the handover, got %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if string(body) != "new" {
+		t.Errorf("expected new, got %q", body)
+	}
+}
+
+// upgradeHelper is the new process of an upgrade: it serves the inherited listener,
+// answering "new", until asked for /exit
+func upgradeHelper() int {
+	listeners, ready, err := inheritedListeners()
+	if err != nil || len(listeners) != 1 {
+		return 1
+	}
+	exit := make(chan struct{})
+	var once sync.Once
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/exit" {
+			once.Do(func() { close(exit) })
+		}
+		_, _ = io.WriteString(w, "new")
+	})}
+	go func() { _ = srv.Serve(listeners[0]) }()
+	signalReady(ready)
+
+	select {
+	case <-exit:
+	case <-time.After(time.Minute):
+		// The test is gone without asking; don't outlive it for long
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = srv.Shutdown(ctx)
+	return 0
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/invalidation"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxCachedAPIKeys bounds the verified key cache; it is emptied when full
+const maxCachedAPIKeys = 10000
+
+type APIKeyService interface {
+	// List returns every key without its secret, revoked and expired ones included
+	List(ctx context.Context) ([]model.APIKey, error)
+	// Create issues a key; the returned key carries its secret, which is not stored
+	Create(ctx context.Context, createdBy string, req model.CreateAPIKeyRequest) (*model.APIKey, error)
+	// Rotate issues a replacement of a key with the same name, scopes, tenant and
+	// expiry; the old key keeps working for the configured grace period
+	Rotate(ctx context.Context, createdBy, id string) (*model.APIKey, error)
+	Revoke(ctx context.Context, id string) error
+	// Verify returns the key an X-API-Key value belongs to, or auth.ErrInvalidAPIKey.
+	// Answers are cached; changes made through the service drop the cache.
+	Verify(ctx context.Context, key string) (*model.APIKey, error)
+	// Invalidate drops the cache; subscribe it to invalidation.APIKeys
+	Invalidate()
+}
+
+type apiKeyService struct {
+	repo   repository.APIKeyRepository
+	caches invalidation.Invalidator
+	cfg    config.ManagedKeysConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	verified map[string]cachedAPIKey
+}
+
+// cachedAPIKey is a verification answer; key is nil for hashes that matched no key
+type cachedAPIKey struct {
+	key   *model.APIKey
+	until time.Time
+}
+
+// NewAPIKeyService manages the keys stored in the database; caches, if set, tells
+// every instance to drop the keys it verified after a change
+func NewAPIKeyService(repo repository.APIKeyRepository, cfg config.ManagedKeysConfig, caches invalidation.Invalidator) APIKeyService {
+	return &apiKeyService{repo: repo, caches: caches, cfg: cfg, now: time.Now, verified: make(map[string]cachedAPIKey)}
+}
+
+func (s *apiKeyService) List(ctx context.Context) ([]model.APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *apiKeyService) Create(ctx context.Context, createdBy string, req model.CreateAPIKeyRequest) (*model.APIKey, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		return nil, ErrInvalidAPIKeyName
+	}
+	var expires *time.Time
+	if req.ExpiresAt != nil {
+		if !req.ExpiresAt.After(s.now()) {
+			return nil, ErrAPIKeyExpiryPast
+		}
+		utc := req.ExpiresAt.UTC()
+		expires = &utc
+	}
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	key, secret, err := newAPIKey(createdBy)
+	if err != nil {
+		return nil, err
+	}
+	key.Name, key.Scopes, key.Tenant, key.ExpiresAt = name, scopes, strings.TrimSpace(req.Tenant), expires
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	// Unknown hashes are cached too, so a key used before it existed must be forgotten
+	s.invalidate()
+	key.Key = secret
+	return key, nil
+}
+
+func (s *apiKeyService) Rotate(ctx context.Context, createdBy, id string) (*model.APIKey, error) {
+	key, secret, err := newAPIKey(createdBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Rotate(ctx, id, key, s.now().Add(s.cfg.RotationGrace).UTC()); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	s.invalidate()
+	key.Key = secret
+	return key, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id string) error {
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrAPIKeyNotFound
+		}
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *apiKeyService) Verify(ctx context.Context, secret string) (*model.APIKey, error) {
+	hash := auth.HashAPIKey(secret)
+	now := s.now()
+	if key, ok := s.cached(hash, now); ok {
+		if key == nil || key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+			return nil, auth.ErrInvalidAPIKey
+		}
+		return key, nil
+	}
+
+	key, err := s.repo.FindByHash(ctx, hash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		s.cache(hash, nil, now)
+		return nil, auth.ErrInvalidAPIKey
+	}
+	// Keys are looked up once per cache_ttl, so last_used_at is as precise as that;
+	// losing it is no reason to fail the request
+	if err := s.repo.Touch(ctx, key.ID); err != nil {
+		slog.WarnContext(ctx, "failed to record API key use", "api_key_id", key.ID, "error", err)
+	}
+	s.cache(hash, key, now)
+	return key, nil
+}
+
+func (s *apiKeyService) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.verified)
+}
+
+func (s *apiKeyService) cached(hash string, now time.Time) (*model.APIKey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.verified[hash]
+	if !ok || !now.Before(c.until) {
+		return nil, false
+	}
+	return c.key, true
+}
+
+func (s *apiKeyService) cache(hash string, key *model.APIKey, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.verified) >= maxCachedAPIKeys {
+		s.verified = make(map[string]cachedAPIKey)
+	}
+	s.verified[hash] = cachedAPIKey{key: key, until: now.Add(s.cfg.CacheTTL)}
+}
+
+// invalidate drops the cache of every instance, or of this one without caches
+func (s *apiKeyService) invalidate() {
+	if s.caches != nil {
+		s.caches.Invalidate(invalidation.APIKeys)
+		return
+	}
+	s.Invalidate()
+}
+
+// newAPIKey generates the secret of a key and the key it is stored as
+func newAPIKey(createdBy string) (*model.APIKey, string, error) {
+	id, err := newUUID()
+	if err != nil {
+		return nil, "", err
+	}
+	secret, hash, prefix, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	return &model.APIKey{ID: id, Prefix: prefix, Hash: hash, CreatedBy: createdBy}, secret, nil
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/model"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockAPIKeyRepository struct {
+	keys    map[string]*model.APIKey
+	now     func() time.Time
+	lookups int
+}
+
+func (m *mockAPIKeyRepository) Create(_ context.Context, key *model.APIKey) error {
+	key.CreatedAt = m.now()
+	copied := *key
+	m.keys[key.ID] = &copied
+	return nil
+}
+
+func (m *mockAPIKeyRepository) List(_ context.Context) ([]model.APIKey, error) {
+	keys := []model.APIKey{}
+	for _, k := range m.keys {
+		keys = append(keys, *k)
+	}
+	return keys, nil
+}
+
+func (m *mockAPIKeyRepository) Get(_ context.Context, id string) (*model.APIKey, error) {
+	k, ok := m.keys[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *k
+	return &copied, nil
+}
+
+func (m *mockAPIKeyRepository) FindByHash(_ context.Context, hash string) (*model.APIKey, error) {
+	m.lookups++
+	for _, k := range m.keys {
+		if k.Hash == hash && !k.Revoked && (k.ExpiresAt == nil || k.ExpiresAt.After(m.now())) {
+			copied := *k
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockAPIKeyRepository) Rotate(ctx context.Context, id string, next *model.APIKey, graceUntil time.Time) error {
+	old, ok := m.keys[id]
+	if !ok || old.Revoked {
+		return sql.ErrNoRows
+	}
+	next.Name, next.Scopes, next.Tenant, next.ExpiresAt, next.RotatedFrom = old.Name, old.Scopes, old.Tenant, old.ExpiresAt, old.ID
+	if old.ExpiresAt == nil || graceUntil.Before(*old.ExpiresAt) {
+		old.ExpiresAt = &graceUntil
+	}
+	return m.Create(ctx, next)
+}
+
+func (m *mockAPIKeyRepository) Revoke(_ context.Context, id string) error {
+	k, ok := m.keys[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	k.Revoked = true
+	return nil
+}
+
+func (m *mockAPIKeyRepository) Touch(_ context.Context, id string) error {
+	now := m.now()
+	m.keys[id].LastUsedAt = &now
+	return nil
+}
+
+// newAPIKeyTestService returns a service whose clock is moved by advancing *now
+func newAPIKeyTestService() (*apiKeyService, *mockAPIKeyRepository, *time.Time) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	repo := &mockAPIKeyRepository{keys: make(map[string]*model.APIKey), now: clock}
+	svc := NewAPIKeyService(repo, config.ManagedKeysConfig{CacheTTL: time.Minute, RotationGrace: time.Hour}, nil).(*apiKeyService)
+	svc.now = clock
+	return svc, repo, &now
+}
+
+func TestAPIKeyService_CreateAndVerify(t *testing.T) {
+	// Given
+	svc, repo, _ := newAPIKeyTestService()
+
+	// When: A key is created and used twice
+	created, err := svc.Create(context.Background(), "admin", model.CreateAPIKeyRequest{Name: " billing ", Scopes: []string{"export"}, Tenant: "acme"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	verified, err := svc.Verify(context.Background(), created.Key)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if _, err := svc.Verify(context.Background(), created.Key); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// Then: The key authenticates, only its hash is stored and the second use is cached
+	if verified.Name != "billing" || verified.Tenant != "acme" || len(verified.Scopes) != 1 || verified.Scopes[0] != "export" {
+		t.Errorf("unexpected key %+v", verified)
+	}
+	if !auth.IsManagedAPIKey(created.Key) || !strings.HasPrefix(created.Key, created.Prefix) || created.CreatedBy != "admin" {
+		t.Errorf("unexpected key %+v", created)
+	}
+	stored := repo.keys[created.ID]
+	if stored.Key != "" || stored.Hash != auth.HashAPIKey(created.Key) {
+		t.Errorf("expected only the hash to be stored, got %+v", stored)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("expected the use to be recorded")
+	}
+	if repo.lookups != 1 {
+		t.Errorf("expected 1 lookup, got %d", repo.lookups)
+	}
+	if _, err := svc.Verify(context.Background(), created.Key+"x"); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("expected invalid API key, got %v", err)
+	}
+}
+
+func TestAPIKeyService_CreateRejects(t *testing.T) {
+	svc, _, now := newAPIKeyTestService()
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name    string
+		req     model.CreateAPIKeyRequest
+		wantErr error
+	}{
+		{"empty name", model.CreateAPIKeyRequest{Name: "  "}, ErrInvalidAPIKeyName},
+		{"long name", model.CreateAPIKeyRequest{Name: strings.Repeat("k", 101)}, ErrInvalidAPIKeyName},
+		{"expired", model.CreateAPIKeyRequest{Name: "ci", ExpiresAt: &past}, ErrAPIKeyExpiryPast},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			_, err := svc.Create(context.Background(), "admin", tt.req)
+
+			// Then
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	// Given: A key verified, and so cached
+	svc, _, _ := newAPIKeyTestService()
+	created, _ := svc.Create(context.Background(), "admin", model.CreateAPIKeyRequest{Name: "ci"})
+	if _, err := svc.Verify(context.Background(), created.Key); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// When
+	err := svc.Revoke(context.Background(), created.ID)
+
+	// Then: The key is rejected at once, and unknown keys are not found
+	if err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Verify(context.Background(), created.Key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("expected a revoked key to be rejected, got %v", err)
+	}
+	if err := svc.Revoke(context.Background(), "missing"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected API key not found, got %v", err)
+	}
+}
+
+func TestAPIKeyService_Rotate(t *testing.T) {
+	// Given: A key in use
+	svc, _, now := newAPIKeyTestService()
+	old, _ := svc.Create(context.Background(), "admin", model.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"debug"}})
+	if _, err := svc.Verify(context.Background(), old.Key); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	// When: It is rotated
+	next, err := svc.Rotate(context.Background(), "root", old.ID)
+
+	// Then: Both keys work until the grace period is over
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if next.Name != "ci" || len(next.Scopes) != 1 || next.RotatedFrom != old.ID || next.CreatedBy != "root" || next.Key == old.Key {
+		t.Errorf("unexpected replacement %+v", next)
+	}
+	for _, key := range []string{old.Key, next.Key} {
+		if _, err := svc.Verify(context.Background(), key); err != nil {
+			t.Errorf("expected key to work during the grace period, got %v", err)
+		}
+	}
+	*now = now.Add(time.Hour)
+	if _, err := svc.Verify(context.Background(), old.Key); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Errorf("expected the old key to expire, got %v", err)
+	}
+	if _, err := svc.Verify(context.Background(), next.Key); err != nil {
+		t.Errorf("expected the replacement to work, got %v", err)
+	}
+	if _, err := svc.Rotate(context.Background(), "root", "missing"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("expected API key not found, got %v", err)
+	}
+}
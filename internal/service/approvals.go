@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/events"
+	"cruder/internal/logging"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/residency"
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// ApprovalService holds sensitive user mutations - deletes and email changes -
+// until an admin other than the requester approves them (maker-checker)
+type ApprovalService interface {
+	// RequestDelete records a pending delete; it returns nil when approvals are
+	// disabled and the caller should delete right away
+	RequestDelete(ctx context.Context, uuid, requestedBy string) (*model.PendingChange, error)
+	// RequestUpdate records an update that changes the email as pending, after
+	// running the update checks; it returns nil when the update needs no approval
+	RequestUpdate(ctx context.Context, uuid string, patch model.UserPatch, requestedBy string) (*model.PendingChange, error)
+	// List returns changes newest first, optionally filtered by status
+	List(ctx context.Context, status string) ([]model.PendingChange, error)
+	Get(ctx context.Context, id int64) (*model.PendingChange, error)
+	// Approve applies the change; the reviewer must not be the requester
+	Approve(ctx context.Context, id int64, reviewer string) (*model.PendingChange, error)
+	// Reject discards the change; requesters may reject their own to withdraw it
+	Reject(ctx context.Context, id int64, reviewer string) (*model.PendingChange, error)
+}
+
+type approvalService struct {
+	repo    repository.ApprovalRepository
+	users   UserService
+	enabled bool
+	events  events.Publisher
+}
+
+// NewApprovalService creates the service; with enabled false every request is
+// applied directly. Approved changes are published to publisher, which may be nil.
+func NewApprovalService(repo repository.ApprovalRepository, users UserService, enabled bool, publisher events.Publisher) ApprovalService {
+	return &approvalService{repo: repo, users: users, enabled: enabled, events: publisher}
+}
+
+func (s *approvalService) RequestDelete(ctx context.Context, uuid, requestedBy string) (*model.PendingChange, error) {
+	if !s.enabled {
+		return nil, nil
+	}
+	if _, err := s.users.GetByUUID(ctx, uuid); err != nil {
+		return nil, err
+	}
+	change := &model.PendingChange{Kind: model.ChangeDeleteUser, UserUUID: uuid, RequestedBy: requestedBy}
+	return change, s.create(ctx, change)
+}
+
+func (s *approvalService) RequestUpdate(ctx context.Context, uuid string, patch model.UserPatch, requestedBy string) (*model.PendingChange, error) {
+	if !s.enabled {
+		return nil, nil
+	}
+	existing, err := s.users.GetByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Email == nil || *patch.Email == existing.Email {
+		return nil, nil
+	}
+
+	// Store the patch as requested; it is checked again against the data at approval
+	if _, err := s.users.CheckUpdate(ctx, uuid, patch); err != nil {
+		return nil, err
+	}
+	// The version guards the request; by approval, the reviewer sees the current data
+	patch.Version = nil
+	change := &model.PendingChange{Kind: model.ChangeUpdateUser, UserUUID: uuid, Payload: &patch, RequestedBy: requestedBy}
+	return change, s.create(ctx, change)
+}
+
+func (s *approvalService) create(ctx context.Context, change *model.PendingChange) error {
+	if err := s.repo.Create(ctx, change); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrChangePending
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *approvalService) List(ctx context.Context, status string) ([]model.PendingChange, error) {
+	switch status {
+	case "", model.ChangePending, model.ChangeApproved, model.ChangeRejected:
+	default:
+		return nil, ErrInvalidStatus
+	}
+	changes, err := s.repo.List(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []model.PendingChange{}
+	}
+	return changes, nil
+}
+
+func (s *approvalService) Get(ctx context.Context, id int64) (*model.PendingChange, error) {
+	change, err := s.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrChangeNotFound
+		}
+		return nil, err
+	}
+	return change, nil
+}
+
+func (s *approvalService) Approve(ctx context.Context, id int64, reviewer string) (*model.PendingChange, error) {
+	change, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != model.ChangePending {
+		return nil, ErrChangeReviewed
+	}
+	if change.RequestedBy == reviewer {
+		return nil, ErrOwnChange
+	}
+	existing, err := s.users.GetByUUID(ctx, change.UserUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *model.User
+	if change.Kind == model.ChangeUpdateUser {
+		if change.Payload == nil {
+			return nil, errors.New("change has no payload")
+		}
+		// The stored payload is what the client sent; the checks add the normalized email
+		patch, err := s.users.CheckUpdate(ctx, change.UserUUID, *change.Payload)
+		if err != nil {
+			return nil, err
+		}
+		change.Payload = &patch
+		updated := change.Payload.Apply(*existing)
+		user = &updated
+	}
+
+	if err := s.repo.Approve(ctx, change, reviewer, change.Payload); err != nil {
+		if err == repository.ErrChangeReviewed {
+			return nil, ErrChangeReviewed
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if s.events != nil {
+		if user != nil {
+			s.events.Publish(events.Event{Type: events.UserUpdated, UserUUID: change.UserUUID, User: user, RequestID: logging.RequestID(ctx), Region: residency.Region(ctx)})
+		} else {
+			s.events.Publish(events.Event{Type: events.UserDeleted, UserUUID: change.UserUUID, RequestID: logging.RequestID(ctx), Region: residency.Region(ctx)})
+		}
+	}
+	return change, nil
+}
+
+func (s *approvalService) Reject(ctx context.Context, id int64, reviewer string) (*model.PendingChange, error) {
+	change, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Reject(ctx, change, reviewer); err != nil {
+		if err == repository.ErrChangeReviewed {
+			return nil, ErrChangeReviewed
+		}
+		return nil, err
+	}
+	return change, nil
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/events"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// mockApprovalRepository applies approved changes to the mock user repository
+type mockApprovalRepository struct {
+	changes map[int64]*model.PendingChange
+	users   *mockUserRepository
+}
+
+func (m *mockApprovalRepository) List(_ context.Context, status string) ([]model.PendingChange, error) {
+	var changes []model.PendingChange
+	for _, c := range m.changes {
+		if status == "" || c.Status == status {
+			changes = append(changes, *c)
+		}
+	}
+	return changes, nil
+}
+
+func (m *mockApprovalRepository) Get(_ context.Context, id int64) (*model.PendingChange, error) {
+	c, ok := m.changes[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (m *mockApprovalRepository) Create(_ context.Context, change *model.PendingChange) error {
+	change.ID = int64(len(m.changes) + 1)
+	change.Status = model.ChangePending
+	stored := *change
+	m.changes[change.ID] = &stored
+	return nil
+}
+
+func (m *mockApprovalRepository) review(change *model.PendingChange, status, reviewer string) error {
+	stored := m.changes[change.ID]
+	if stored.Status != model.ChangePending {
+		return repository.ErrChangeReviewed
+	}
+	now := time.Now()
+	stored.Status, stored.ReviewedBy, stored.ReviewedAt = status, reviewer, &now
+	*change = *stored
+	return nil
+}
+
+func (m *mockApprovalRepository) Approve(_ context.Context, change *model.PendingChange, reviewer string, patch *model.UserPatch) error {
+	if err := m.review(change, model.ChangeApproved, reviewer); err != nil {
+		return err
+	}
+	if change.Kind == model.ChangeDeleteUser {
+		return m.users.Delete(context.Background(), change.UserUUID)
+	}
+	return m.users.Update(context.Background(), change.UserUUID, *patch)
+}
+
+func (m *mockApprovalRepository) Reject(_ context.Context, change *model.PendingChange, reviewer string) error {
+	return m.review(change, model.ChangeRejected, reviewer)
+}
+
+func newApprovalService(enabled bool, publisher events.Publisher) (ApprovalService, *mockUserRepository) {
+	users := newMockUserRepository()
+	users.users["u-1"] = &model.User{ID: 1, UUID: "u-1", Username: "jdoe", Email: "jdoe@example.com"}
+	repo := &mockApprovalRepository{changes: make(map[int64]*model.PendingChange), users: users}
+	return NewApprovalService(repo, NewUserService(users), enabled, publisher), users
+}
+
+// Tests for ApprovalService
+func TestApprovalService_Disabled(t *testing.T) {
+	// Given: Approvals are disabled
+	svc, _ := newApprovalService(false, nil)
+
+	// When: A delete and an email change are requested
+	deleteChange, err := svc.RequestDelete(context.Background(), "u-1", "ops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	updateChange, err := svc.RequestUpdate(context.Background(), "u-1", model.UserPatch{Email: strPtr("new@example.com")}, "ops")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: Nothing is held for approval
+	if deleteChange != nil || updateChange != nil {
+		t.Errorf("expected no pending changes, got %+v and %+v", deleteChange, updateChange)
+	}
+}
+
+func TestApprovalService_RequestUpdate(t *testing.T) {
+	svc, users := newApprovalService(true, nil)
+	users.users["u-2"] = &model.User{ID: 2, UUID: "u-2", Username: "other", Email: "taken@example.com"}
+
+	tests := []struct {
+		name        string
+		patch       model.UserPatch
+		wantPending bool
+		wantErr     bool
+	}{
+		{"same email applies directly", model.UserPatch{Username: strPtr("john"), Email: strPtr("jdoe@example.com")}, false, false},
+		{"no email applies directly", model.UserPatch{FullName: strPtr("John Doe")}, false, false},
+		{"email change is held", model.UserPatch{Email: strPtr("john@example.com")}, true, false},
+		{"taken email is rejected up front", model.UserPatch{Email: strPtr("taken@example.com")}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			change, err := svc.RequestUpdate(context.Background(), "u-1", tt.patch, "ops")
+
+			// Then
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if (change != nil) != tt.wantPending {
+				t.Fatalf("expected pending %v, got %+v", tt.wantPending, change)
+			}
+			if change != nil && (change.Kind != model.ChangeUpdateUser || *change.Payload.Email != *tt.patch.Email) {
+				t.Errorf("unexpected change %+v", change)
+			}
+		})
+	}
+}
+
+func TestApprovalService_Approve(t *testing.T) {
+	// Given: A delete requested by ops
+	publisher := &recordingPublisher{}
+	svc, users := newApprovalService(true, publisher)
+	change, err := svc.RequestDelete(context.Background(), "u-1", "ops")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// When: The requester tries to approve it
+	_, err = svc.Approve(context.Background(), change.ID, "ops")
+
+	// Then: The second-admin rule applies and the user still exists
+	if err == nil || err.Error() != "cannot approve own change" {
+		t.Fatalf("expected 'cannot approve own change', got %v", err)
+	}
+	if _, ok := users.users["u-1"]; !ok {
+		t.Fatal("expected user to still exist")
+	}
+
+	// When: Another admin approves it
+	approved, err := svc.Approve(context.Background(), change.ID, "security")
+
+	// Then: The user is deleted, the change is closed and an event is published
+	if err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	if approved.Status != model.ChangeApproved || approved.ReviewedBy != "security" {
+		t.Errorf("unexpected change %+v", approved)
+	}
+	if _, ok := users.users["u-1"]; ok {
+		t.Error("expected user to be deleted")
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Type != events.UserDeleted {
+		t.Errorf("expected one user.deleted event, got %+v", publisher.events)
+	}
+
+	// When: It is approved again
+	_, err = svc.Approve(context.Background(), change.ID, "security")
+
+	// Then
+	if err == nil || err.Error() != "change already reviewed" {
+		t.Errorf("expected 'change already reviewed', got %v", err)
+	}
+}
+
+func TestApprovalService_ApproveUpdate(t *testing.T) {
+	// Given: A pending email change
+	svc, users := newApprovalService(true, nil)
+	change, err := svc.RequestUpdate(context.Background(), "u-1", model.UserPatch{Email: strPtr("john@example.com")}, "ops")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if users.users["u-1"].Email != "jdoe@example.com" {
+		t.Fatal("expected email to stay unchanged until approval")
+	}
+
+	// When
+	if _, err := svc.Approve(context.Background(), change.ID, "security"); err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+
+	// Then
+	if got := users.users["u-1"].Email; got != "john@example.com" {
+		t.Errorf("expected updated email, got %q", got)
+	}
+}
+
+func TestApprovalService_Reject(t *testing.T) {
+	// Given: A pending delete
+	svc, users := newApprovalService(true, nil)
+	change, err := svc.RequestDelete(context.Background(), "u-1", "ops")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	// When: The requester withdraws it
+	rejected, err := svc.Reject(context.Background(), change.ID, "ops")
+
+	// Then: The change is closed and the user kept
+	if err != nil {
+		t.Fatalf("reject failed: %v", err)
+	}
+	if rejected.Status != model.ChangeRejected {
+		t.Errorf("expected rejected status, got %q", rejected.Status)
+	}
+	if _, ok := users.users["u-1"]; !ok {
+		t.Error("expected user to be kept")
+	}
+	if _, err := svc.List(context.Background(), "unknown"); err == nil {
+		t.Error("expected invalid status error")
+	}
+}
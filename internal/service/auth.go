@@ -0,0 +1,55 @@
+package service
+
+import (
+	"time"
+
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/model"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against when the username is unknown, so the response time
+// does not reveal which accounts exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a password"), bcrypt.DefaultCost)
+
+type AuthService interface {
+	Login(username, password string) (*model.Token, error)
+}
+
+type authService struct {
+	accounts map[string]config.AccountConfig
+	tokens   *auth.Tokens
+}
+
+// NewAuthService issues tokens to the configured accounts
+func NewAuthService(accounts []config.AccountConfig, tokens *auth.Tokens) AuthService {
+	byName := make(map[string]config.AccountConfig, len(accounts))
+	for _, account := range accounts {
+		byName[account.Username] = account
+	}
+	return &authService{accounts: byName, tokens: tokens}
+}
+
+func (s *authService) Login(username, password string) (*model.Token, error) {
+	account, ok := s.accounts[username]
+	hash := []byte(account.PasswordHash)
+	if !ok {
+		hash = dummyHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	token, expires, err := s.tokens.Issue(account.Username, account.Scopes, account.Tenant)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Token{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(expires).Round(time.Second).Seconds()),
+		ExpiresAt:   expires,
+	}, nil
+}
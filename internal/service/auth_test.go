@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"cruder/internal/auth"
+	"cruder/internal/config"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthService_Login(t *testing.T) {
+	// Given an account with a bcrypt password hash
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := auth.NewTokens(config.JWTConfig{Algorithm: "HS256", TTL: time.Hour}, "0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAuthService([]config.AccountConfig{{Username: "ci", PasswordHash: string(hash), Scopes: []string{"admin"}}}, tokens)
+
+	// When logging in with the right password
+	token, err := svc.Login("ci", "correct horse")
+
+	// Then a bearer token for the account is issued
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if token.TokenType != "Bearer" || token.ExpiresIn != 3600 {
+		t.Errorf("unexpected token %+v", token)
+	}
+	claims, err := tokens.Verify(token.AccessToken)
+	if err != nil || claims.Subject != "ci" || len(claims.Scopes) != 1 {
+		t.Errorf("expected a valid token for ci, got %+v (%v)", claims, err)
+	}
+
+	// And wrong passwords and unknown users are rejected alike
+	for _, creds := range [][2]string{{"ci", "wrong"}, {"nobody", "correct horse"}} {
+		if _, err := svc.Login(creds[0], creds[1]); err == nil || err.Error() != "invalid credentials" {
+			t.Errorf("Login(%q): expected invalid credentials, got %v", creds[0], err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/storage"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// BillingMonthLayout is how report months are written, in keys and in the API
+const BillingMonthLayout = "2006-01"
+
+type BillingService interface {
+	// Generate computes the report of the month containing month and stores it,
+	// replacing an earlier report of that month
+	Generate(ctx context.Context, month time.Time) (*model.BillingReport, error)
+	// Get returns the stored report of the month containing month
+	Get(ctx context.Context, month time.Time) (*model.BillingReport, error)
+	// GenerateDue stores the report of the previous month unless a final one exists,
+	// and returns it if it was generated
+	GenerateDue(ctx context.Context) (*model.BillingReport, error)
+}
+
+type billingService struct {
+	repo    repository.BillingRepository
+	backend storage.Backend
+	now     func() time.Time
+}
+
+// NewBillingService creates the service; reports are kept in backend as JSON under
+// "billing/<YYYY-MM>.json"
+func NewBillingService(repo repository.BillingRepository, backend storage.Backend) BillingService {
+	return &billingService{repo: repo, backend: backend, now: time.Now}
+}
+
+func (s *billingService) Generate(ctx context.Context, month time.Time) (*model.BillingReport, error) {
+	now := s.now().UTC()
+	from := startOfMonth(month)
+	if from.After(now) {
+		return nil, ErrReportMonthInFuture
+	}
+	to := from.AddDate(0, 1, 0)
+
+	report := &model.BillingReport{Month: from.Format(BillingMonthLayout), GeneratedAt: now, Partial: now.Before(to)}
+	var err error
+	if report.ActiveUsers, err = s.repo.ActiveUsers(ctx, from, to); err != nil {
+		return nil, err
+	}
+	if report.APICalls, err = s.repo.APICalls(ctx, from, to); err != nil {
+		return nil, err
+	}
+	for _, calls := range report.APICalls {
+		report.TotalRequests += calls.Requests
+	}
+	// Files are not versioned, so storage is what is kept now rather than at the
+	// end of the month; the due report is generated just after the month ends
+	if report.Storage, err = s.repo.Storage(ctx); err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.backend.Put(ctx, billingKey(from), bytes.NewReader(body)); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *billingService) Get(ctx context.Context, month time.Time) (*model.BillingReport, error) {
+	r, err := s.backend.Open(ctx, billingKey(startOfMonth(month)))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrBillingReportNotFound
+		}
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	var report model.BillingReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (s *billingService) GenerateDue(ctx context.Context) (*model.BillingReport, error) {
+	previous := startOfMonth(s.now()).AddDate(0, -1, 0)
+	// A report generated before the month ended is replaced by the final one
+	report, err := s.Get(ctx, previous)
+	if err == nil && !report.Partial {
+		return nil, nil
+	}
+	if err != nil && !errors.Is(err, ErrBillingReportNotFound) {
+		return nil, err
+	}
+	return s.Generate(ctx, previous)
+}
+
+// startOfMonth returns midnight UTC on the first day of t's month
+func startOfMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func billingKey(month time.Time) string {
+	return "billing/" + month.Format(BillingMonthLayout) + ".json"
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/storage"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockBillingRepository struct {
+	// from and to are the period of the last ActiveUsers call
+	from, to time.Time
+	calls    int
+}
+
+func (m *mockBillingRepository) ActiveUsers(_ context.Context, from, to time.Time) (int, error) {
+	m.from, m.to = from, to
+	m.calls++
+	return 42, nil
+}
+
+func (m *mockBillingRepository) APICalls(context.Context, time.Time, time.Time) ([]model.BillingAPICalls, error) {
+	return []model.BillingAPICalls{
+		{APIKey: "partner", Requests: 900, Errors: 12},
+		{APIKey: "anonymous", Requests: 100},
+	}, nil
+}
+
+func (m *mockBillingRepository) Storage(context.Context) (model.BillingStorage, error) {
+	return model.BillingStorage{DocumentsBytes: 3000, ExportsBytes: 500, TotalBytes: 3500}, nil
+}
+
+func newBillingTestService(t *testing.T, now time.Time) (*billingService, *mockBillingRepository) {
+	t.Helper()
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	repo := &mockBillingRepository{}
+	svc := NewBillingService(repo, backend).(*billingService)
+	svc.now = func() time.Time { return now }
+	return svc, repo
+}
+
+func TestBillingService_GenerateDue(t *testing.T) {
+	// Given: It is early November and no report exists yet
+	svc, repo := newBillingTestService(t, time.Date(2026, 11, 1, 0, 30, 0, 0, time.UTC))
+
+	// When
+	report, err := svc.GenerateDue(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateDue: %v", err)
+	}
+
+	// Then: October is reported in full and stored
+	if report == nil || report.Month != "2026-10" || report.Partial || report.ActiveUsers != 42 || report.TotalRequests != 1000 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if !repo.from.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) || !repo.to.Equal(time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected period: %v to %v", repo.from, repo.to)
+	}
+	stored, err := svc.Get(context.Background(), time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Month != "2026-10" || len(stored.APICalls) != 2 || stored.Storage.TotalBytes != 3500 {
+		t.Errorf("unexpected stored report: %+v", stored)
+	}
+
+	// When: The job checks again
+	report, err = svc.GenerateDue(context.Background())
+
+	// Then: The existing report is kept
+	if err != nil || report != nil || repo.calls != 1 {
+		t.Errorf("expected no new report, got %+v, %v after %d generations", report, err, repo.calls)
+	}
+}
+
+func TestBillingService_ReplacesPartialReport(t *testing.T) {
+	// Given: A report of October generated before October ended
+	svc, repo := newBillingTestService(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC))
+	report, err := svc.Generate(context.Background(), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !report.Partial {
+		t.Errorf("expected a partial report")
+	}
+
+	// When: The job runs after the month ended
+	svc.now = func() time.Time { return time.Date(2026, 11, 1, 1, 0, 0, 0, time.UTC) }
+	report, err = svc.GenerateDue(context.Background())
+
+	// Then: The final report replaces it
+	if err != nil || report == nil || report.Partial || repo.calls != 2 {
+		t.Errorf("expected a final report, got %+v, %v", report, err)
+	}
+}
+
+func TestBillingService_Errors(t *testing.T) {
+	// Given
+	svc, _ := newBillingTestService(t, time.Date(2026, 10, 20, 0, 0, 0, 0, time.UTC))
+
+	// When: Asking for a month without a report, or one that has not started
+	_, getErr := svc.Get(context.Background(), time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	_, generateErr := svc.Generate(context.Background(), time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC))
+
+	// Then
+	if !errors.Is(getErr, ErrBillingReportNotFound) {
+		t.Errorf("expected ErrBillingReportNotFound, got %v", getErr)
+	}
+	if !errors.Is(generateErr, ErrReportMonthInFuture) {
+		t.Errorf("expected ErrReportMonthInFuture, got %v", generateErr)
+	}
+}
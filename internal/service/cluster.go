@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/version"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"time"
+)
+
+// missedHeartbeats is how many heartbeats an instance may miss before it is no
+// longer listed
+const missedHeartbeats = 3
+
+// instanceRetention is how long rows of instances that stopped without leaving
+// are kept before the heartbeat of another instance removes them
+const instanceRetention = 24 * time.Hour
+
+type ClusterService interface {
+	// Heartbeat records this instance as alive, leading the given jobs
+	Heartbeat(ctx context.Context, jobs []string) error
+	// Leave removes this instance from the listing on shutdown
+	Leave(ctx context.Context) error
+	// Members lists the live instances, their versions and the job leaders
+	Members(ctx context.Context) (*model.Cluster, error)
+}
+
+type clusterService struct {
+	repo     repository.InstanceRepository
+	self     model.Instance
+	interval time.Duration
+}
+
+// NewClusterService identifies this process as a new instance; interval is how
+// often Heartbeat is called
+func NewClusterService(repo repository.InstanceRepository, interval time.Duration) ClusterService {
+	hostname, _ := os.Hostname()
+	info := version.Get()
+	return &clusterService{
+		repo: repo,
+		self: model.Instance{
+			ID:        newInstanceID(hostname),
+			Hostname:  hostname,
+			Version:   info.Version,
+			Commit:    info.Commit,
+			StartedAt: time.Now().UTC(),
+		},
+		interval: interval,
+	}
+}
+
+func (s *clusterService) Heartbeat(ctx context.Context, jobs []string) error {
+	self := s.self
+	self.Jobs = jobs
+	if err := s.repo.Heartbeat(ctx, &self); err != nil {
+		return err
+	}
+	return s.repo.Prune(ctx, instanceRetention)
+}
+
+func (s *clusterService) Leave(ctx context.Context) error {
+	return s.repo.Remove(ctx, s.self.ID)
+}
+
+func (s *clusterService) Members(ctx context.Context) (*model.Cluster, error) {
+	instances, err := s.repo.List(ctx, missedHeartbeats*s.interval)
+	if err != nil {
+		return nil, err
+	}
+	cluster := &model.Cluster{
+		Self:      s.self.ID,
+		Instances: instances,
+		Versions:  make(map[string]int),
+		Leaders:   make(map[string]string),
+	}
+	if cluster.Instances == nil {
+		cluster.Instances = []model.Instance{}
+	}
+	// Right after a failover two instances may report the same job until the old
+	// leader's next heartbeat; the most recent report wins
+	reported := make(map[string]time.Time)
+	for _, instance := range instances {
+		cluster.Versions[instance.Version]++
+		for _, job := range instance.Jobs {
+			if instance.LastSeenAt.After(reported[job]) {
+				cluster.Leaders[job] = instance.ID
+				reported[job] = instance.LastSeenAt
+			}
+		}
+	}
+	return cluster, nil
+}
+
+// newInstanceID names a process after its host, which is the pod name on
+// Kubernetes, plus a random suffix that tells restarts apart
+func newInstanceID(hostname string) string {
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	if hostname == "" {
+		hostname = "instance"
+	}
+	return hostname + "-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"testing"
+	"time"
+)
+
+type mockInstanceRepository struct {
+	instances map[string]model.Instance
+	now       time.Time
+	ttl       time.Duration
+}
+
+func (m *mockInstanceRepository) Heartbeat(ctx context.Context, instance *model.Instance) error {
+	instance.LastSeenAt = m.now
+	m.instances[instance.ID] = *instance
+	return nil
+}
+
+func (m *mockInstanceRepository) List(ctx context.Context, ttl time.Duration) ([]model.Instance, error) {
+	m.ttl = ttl
+	var instances []model.Instance
+	for _, instance := range m.instances {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (m *mockInstanceRepository) Remove(ctx context.Context, id string) error {
+	delete(m.instances, id)
+	return nil
+}
+
+func (m *mockInstanceRepository) Prune(ctx context.Context, age time.Duration) error {
+	return nil
+}
+
+func TestClusterService_Members(t *testing.T) {
+	// Given: This instance and an older one that still reports a job it lost
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	repo := &mockInstanceRepository{instances: map[string]model.Instance{
+		"old": {ID: "old", Version: "v1.0.0", Jobs: []string{"scheduled-deletions"}, LastSeenAt: now.Add(-5 * time.Second)},
+		"new": {ID: "new", Version: "v1.1.0", Jobs: []string{"scheduled-deletions"}, LastSeenAt: now},
+	}, now: now.Add(-time.Second)}
+	svc := NewClusterService(repo, 10*time.Second)
+	if err := svc.Heartbeat(context.Background(), []string{"user-search-rebuild"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// When: Listing the cluster
+	cluster, err := svc.Members(context.Background())
+
+	// Then: Instances missing three heartbeats are excluded and the latest report leads
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.ttl != 30*time.Second {
+		t.Errorf("expected a 30s liveness window, got %v", repo.ttl)
+	}
+	if len(cluster.Instances) != 3 || cluster.Versions["v1.0.0"] != 1 || cluster.Versions["v1.1.0"] != 1 {
+		t.Errorf("unexpected instances %+v / versions %v", cluster.Instances, cluster.Versions)
+	}
+	if cluster.Leaders["scheduled-deletions"] != "new" {
+		t.Errorf("expected new to lead scheduled-deletions, got %q", cluster.Leaders["scheduled-deletions"])
+	}
+	if cluster.Leaders["user-search-rebuild"] != cluster.Self {
+		t.Errorf("expected this instance to lead user-search-rebuild, got %q", cluster.Leaders["user-search-rebuild"])
+	}
+
+	// When: This instance shuts down
+	if err := svc.Leave(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: It is no longer listed
+	if _, ok := repo.instances[cluster.Self]; ok {
+		t.Error("expected this instance to be removed")
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+)
+
+type ConsentService interface {
+	// Record stores that the user accepted the current version of a document from
+	// ip; it reports false when that acceptance was already recorded
+	Record(ctx context.Context, userUUID string, req model.ConsentRequest, ip string) (*model.Consent, bool, error)
+	List(ctx context.Context, userUUID string) ([]model.Consent, error)
+	// Pending returns one page of users who have not accepted the current version of
+	// document; pages start at 1
+	Pending(ctx context.Context, document string, page, perPage int) (*model.PendingConsentPage, error)
+}
+
+type consentService struct {
+	repo  repository.ConsentRepository
+	users repository.UserRepository
+	// current maps each document to its current version
+	current map[string]string
+}
+
+func NewConsentService(repo repository.ConsentRepository, users repository.UserRepository, current map[string]string) ConsentService {
+	return &consentService{repo: repo, users: users, current: current}
+}
+
+func (s *consentService) Record(ctx context.Context, userUUID string, req model.ConsentRequest, ip string) (*model.Consent, bool, error) {
+	version, ok := s.current[req.Document]
+	if !ok {
+		return nil, false, ErrUnknownDocument
+	}
+	// Only the version users are shown can be accepted, so a stale client cannot
+	// record consent to text the user never saw
+	if req.Version != version {
+		return nil, false, ErrConsentVersionNotCurrent
+	}
+	if err := s.userExists(ctx, userUUID); err != nil {
+		return nil, false, err
+	}
+
+	consent := &model.Consent{UserUUID: userUUID, Document: req.Document, Version: req.Version, IPAddress: ip}
+	created, err := s.repo.Record(ctx, consent)
+	if err != nil {
+		return nil, false, err
+	}
+	return consent, created, nil
+}
+
+func (s *consentService) List(ctx context.Context, userUUID string) ([]model.Consent, error) {
+	if err := s.userExists(ctx, userUUID); err != nil {
+		return nil, err
+	}
+	consents, err := s.repo.List(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if consents == nil {
+		consents = []model.Consent{}
+	}
+	return consents, nil
+}
+
+func (s *consentService) Pending(ctx context.Context, document string, page, perPage int) (*model.PendingConsentPage, error) {
+	version, ok := s.current[document]
+	if !ok {
+		return nil, ErrUnknownDocument
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPageSize
+	}
+	if perPage > MaxPageSize {
+		perPage = MaxPageSize
+	}
+
+	users, total, err := s.repo.Pending(ctx, document, version, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []model.PendingConsent{}
+	}
+	return &model.PendingConsentPage{Document: document, Version: version, Items: users, Page: page, PerPage: perPage, Total: total}, nil
+}
+
+func (s *consentService) userExists(ctx context.Context, userUUID string) error {
+	if _, err := s.users.GetByUUID(ctx, userUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"testing"
+	"time"
+)
+
+type mockConsentRepository struct {
+	consents []model.Consent
+	// pendingArgs records the arguments of the last Pending call
+	pendingArgs []any
+}
+
+func (m *mockConsentRepository) Record(_ context.Context, consent *model.Consent) (bool, error) {
+	for _, c := range m.consents {
+		if c.UserUUID == consent.UserUUID && c.Document == consent.Document && c.Version == consent.Version {
+			*consent = c
+			return false, nil
+		}
+	}
+	consent.ID = int64(len(m.consents) + 1)
+	consent.AcceptedAt = time.Now()
+	m.consents = append(m.consents, *consent)
+	return true, nil
+}
+
+func (m *mockConsentRepository) List(_ context.Context, userUUID string) ([]model.Consent, error) {
+	var consents []model.Consent
+	for _, c := range m.consents {
+		if c.UserUUID == userUUID {
+			consents = append(consents, c)
+		}
+	}
+	return consents, nil
+}
+
+func (m *mockConsentRepository) Pending(_ context.Context, document, version string, limit, offset int) ([]model.PendingConsent, int, error) {
+	m.pendingArgs = []any{document, version, limit, offset}
+	return nil, 0, nil
+}
+
+func newConsentTestService() (ConsentService, *mockConsentRepository) {
+	users := newMockUserRepository()
+	users.users["user-uuid"] = &model.User{UUID: "user-uuid", Username: "jdoe"}
+	repo := &mockConsentRepository{}
+	return NewConsentService(repo, users, map[string]string{"terms_of_service": "2026-10-01"}), repo
+}
+
+func TestConsentService_Record(t *testing.T) {
+	svc, _ := newConsentTestService()
+
+	tests := []struct {
+		name        string
+		uuid        string
+		req         model.ConsentRequest
+		wantErr     string
+		wantCreated bool
+	}{
+		{"current version", "user-uuid", model.ConsentRequest{Document: "terms_of_service", Version: "2026-10-01"}, "", true},
+		{"accepted again", "user-uuid", model.ConsentRequest{Document: "terms_of_service", Version: "2026-10-01"}, "", false},
+		{"old version", "user-uuid", model.ConsentRequest{Document: "terms_of_service", Version: "2025-01-01"}, "consent version is not current", false},
+		{"unknown document", "user-uuid", model.ConsentRequest{Document: "cookies", Version: "1"}, "unknown document", false},
+		{"unknown user", "missing", model.ConsentRequest{Document: "terms_of_service", Version: "2026-10-01"}, "users not found", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			consent, created, err := svc.Record(context.Background(), tt.uuid, tt.req, "203.0.113.7")
+
+			// Then
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Record: %v", err)
+			}
+			if created != tt.wantCreated || consent.ID != 1 || consent.IPAddress != "203.0.113.7" {
+				t.Errorf("unexpected consent %+v (created %t)", consent, created)
+			}
+		})
+	}
+}
+
+func TestConsentService_Pending(t *testing.T) {
+	// Given
+	svc, repo := newConsentTestService()
+
+	// When: Asking for the third page of an oversized page size
+	page, err := svc.Pending(context.Background(), "terms_of_service", 3, MaxPageSize+1)
+
+	// Then: The current version is looked up and the page size capped
+	if err != nil {
+		t.Fatalf("Pending: %v", err)
+	}
+	if page.Version != "2026-10-01" || page.PerPage != MaxPageSize || page.Items == nil {
+		t.Errorf("unexpected page %+v", page)
+	}
+	if repo.pendingArgs[1] != "2026-10-01" || repo.pendingArgs[3] != 2*MaxPageSize {
+		t.Errorf("unexpected query %v", repo.pendingArgs)
+	}
+	if _, err := svc.Pending(context.Background(), "cookies", 1, 10); err == nil || err.Error() != "unknown document" {
+		t.Errorf("expected unknown document, got %v", err)
+	}
+}
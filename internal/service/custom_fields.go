@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+var customFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// CustomFieldError reports a custom field value or definition that failed validation
+type CustomFieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e *CustomFieldError) Error() string {
+	return fmt.Sprintf("custom field %q %s", e.Field, e.Reason)
+}
+
+type CustomFieldService interface {
+	List(ctx context.Context) ([]model.CustomField, error)
+	Create(ctx context.Context, field *model.CustomField) error
+	Update(ctx context.Context, field *model.CustomField) error
+	Delete(ctx context.Context, name string) error
+}
+
+type customFieldService struct {
+	repo repository.CustomFieldRepository
+}
+
+func NewCustomFieldService(repo repository.CustomFieldRepository) CustomFieldService {
+	return &customFieldService{repo: repo}
+}
+
+func (s *customFieldService) List(ctx context.Context) ([]model.CustomField, error) {
+	fields, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = []model.CustomField{}
+	}
+	return fields, nil
+}
+
+func (s *customFieldService) Create(ctx context.Context, field *model.CustomField) error {
+	if err := validateDefinition(field); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, field); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrCustomFieldExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *customFieldService) Update(ctx context.Context, field *model.CustomField) error {
+	if err := validateDefinition(field); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, field); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCustomFieldNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *customFieldService) Delete(ctx context.Context, name string) error {
+	if err := s.repo.Delete(ctx, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCustomFieldNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func validateDefinition(field *model.CustomField) error {
+	if !customFieldName.MatchString(field.Name) {
+		return &CustomFieldError{Field: field.Name, Reason: "must be lower-case letters, digits and underscores, starting with a letter"}
+	}
+	switch field.Type {
+	case model.CustomFieldString, model.CustomFieldNumber, model.CustomFieldBoolean, model.CustomFieldDate:
+	default:
+		return &CustomFieldError{Field: field.Name, Reason: "has unknown type " + field.Type + " (expected string, number, boolean or date)"}
+	}
+	v := field.Validation
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return &CustomFieldError{Field: field.Name, Reason: "has an invalid pattern: " + err.Error()}
+		}
+	}
+	if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+		return &CustomFieldError{Field: field.Name, Reason: "has min greater than max"}
+	}
+	return nil
+}
+
+// validateCustomFields checks values against the definitions and normalizes them
+// (strings are trimmed); it returns a *CustomFieldError for the first problem
+func validateCustomFields(defs []model.CustomField, values map[string]any) error {
+	known := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		known[def.Name] = true
+		value, present := values[def.Name]
+		if !present || value == nil {
+			if def.Required {
+				return &CustomFieldError{Field: def.Name, Reason: "is required"}
+			}
+			delete(values, def.Name)
+			continue
+		}
+		normalized, err := validateCustomValue(def, value)
+		if err != nil {
+			return err
+		}
+		values[def.Name] = normalized
+	}
+	for name := range values {
+		if !known[name] {
+			return &CustomFieldError{Field: name, Reason: "is not defined"}
+		}
+	}
+	return nil
+}
+
+func validateCustomValue(def model.CustomField, value any) (any, error) {
+	fail := func(reason string) (any, error) {
+		return nil, &CustomFieldError{Field: def.Name, Reason: reason}
+	}
+	v := def.Validation
+
+	switch def.Type {
+	case model.CustomFieldString:
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		str = strings.TrimSpace(str)
+		if def.Required && str == "" {
+			return fail("is required")
+		}
+		if v.MaxLength > 0 && utf8.RuneCountInString(str) > v.MaxLength {
+			return fail(fmt.Sprintf("must be at most %d characters", v.MaxLength))
+		}
+		if v.Pattern != "" && !regexp.MustCompile(v.Pattern).MatchString(str) {
+			return fail("does not match the required format")
+		}
+		if len(v.Options) > 0 && !containsString(v.Options, str) {
+			return fail("must be one of " + strings.Join(v.Options, ", "))
+		}
+		return str, nil
+	case model.CustomFieldNumber:
+		num, ok := value.(float64)
+		if !ok {
+			return fail("must be a number")
+		}
+		if v.Min != nil && num < *v.Min {
+			return fail(fmt.Sprintf("must be at least %v", *v.Min))
+		}
+		if v.Max != nil && num > *v.Max {
+			return fail(fmt.Sprintf("must be at most %v", *v.Max))
+		}
+		return num, nil
+	case model.CustomFieldBoolean:
+		if _, ok := value.(bool); !ok {
+			return fail("must be true or false")
+		}
+		return value, nil
+	case model.CustomFieldDate:
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a date string (YYYY-MM-DD)")
+		}
+		if _, err := time.Parse("2006-01-02", str); err != nil {
+			return fail("must be a date string (YYYY-MM-DD)")
+		}
+		return str, nil
+	}
+	return fail("has unknown type " + def.Type)
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
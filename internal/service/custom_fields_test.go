@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"errors"
+	"testing"
+	"time"
+)
+
+type mockCustomFieldRepository struct {
+	fields []model.CustomField
+}
+
+func (m *mockCustomFieldRepository) List(_ context.Context) ([]model.CustomField, error) {
+	return m.fields, nil
+}
+
+func (m *mockCustomFieldRepository) Create(_ context.Context, field *model.CustomField) error {
+	m.fields = append(m.fields, *field)
+	return nil
+}
+
+func (m *mockCustomFieldRepository) Update(_ context.Context, field *model.CustomField) error {
+	return nil
+}
+
+func (m *mockCustomFieldRepository) Delete(_ context.Context, name string) error {
+	return nil
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func newCustomFieldUserService() (*mockUserRepository, UserService) {
+	fields := &mockCustomFieldRepository{fields: []model.CustomField{
+		{Name: "department", Type: model.CustomFieldString, Required: true, Validation: model.CustomFieldValidation{Options: []string{"sales", "support"}}},
+		{Name: "employee_id", Type: model.CustomFieldString, Validation: model.CustomFieldValidation{Pattern: `^E[0-9]{4}$`}},
+		{Name: "level", Type: model.CustomFieldNumber, Validation: model.CustomFieldValidation{Min: float(1), Max: float(5)}},
+		{Name: "remote", Type: model.CustomFieldBoolean},
+		{Name: "hired_on", Type: model.CustomFieldDate},
+	}}
+	repo := newMockUserRepository()
+	return repo, NewUserService(repo, WithCustomFields(fields))
+}
+
+func TestCreateUser_CustomFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		fields    map[string]any
+		wantField string
+	}{
+		{"valid values", map[string]any{"department": " sales ", "employee_id": "E0042", "level": 3.0, "remote": true, "hired_on": "2024-02-29"}, ""},
+		{"missing required field", map[string]any{"level": 2.0}, "department"},
+		{"unknown field", map[string]any{"department": "sales", "shoe_size": 44.0}, "shoe_size"},
+		{"value not in options", map[string]any{"department": "marketing"}, "department"},
+		{"pattern mismatch", map[string]any{"department": "sales", "employee_id": "42"}, "employee_id"},
+		{"number above max", map[string]any{"department": "sales", "level": 9.0}, "level"},
+		{"wrong type", map[string]any{"department": "sales", "remote": "yes"}, "remote"},
+		{"invalid date", map[string]any{"department": "sales", "hired_on": "2024-02-30"}, "hired_on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			_, svc := newCustomFieldUserService()
+			user := &model.User{Username: "jdoe", Email: "jdoe@example.com", CustomFields: tt.fields}
+
+			// When
+			err := svc.Create(context.Background(), user)
+
+			// Then
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if user.CustomFields["department"] != "sales" {
+					t.Errorf("expected trimmed department, got %q", user.CustomFields["department"])
+				}
+				return
+			}
+			var fieldErr *CustomFieldError
+			if !errors.As(err, &fieldErr) {
+				t.Fatalf("expected CustomFieldError, got %v", err)
+			}
+			if fieldErr.Field != tt.wantField {
+				t.Errorf("expected error for %q, got %q (%v)", tt.wantField, fieldErr.Field, err)
+			}
+		})
+	}
+}
+
+func TestUpdateUser_MergesCustomFields(t *testing.T) {
+	// Given: a user with two custom field values
+	repo, svc := newCustomFieldUserService()
+	repo.users["user-uuid"] = &model.User{UUID: "user-uuid", Username: "jdoe", Email: "jdoe@example.com",
+		CustomFields: map[string]any{"department": "sales", "level": 2.0}}
+
+	// When: changing one value and removing the other
+	update := model.UserPatch{CustomFields: map[string]any{"department": "support", "level": nil}}
+	err := svc.Update(context.Background(), "user-uuid", update)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := repo.users["user-uuid"].CustomFields
+	if got["department"] != "support" {
+		t.Errorf("expected department support, got %v", got["department"])
+	}
+	if _, ok := got["level"]; ok {
+		t.Errorf("expected level to be removed, got %v", got["level"])
+	}
+
+	// When: removing a required value
+	err = svc.Update(context.Background(), "user-uuid", model.UserPatch{CustomFields: map[string]any{"department": nil}})
+
+	// Then
+	var fieldErr *CustomFieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "department" {
+		t.Errorf("expected required department error, got %v", err)
+	}
+}
+
+func TestFind_RejectsUnknownField(t *testing.T) {
+	_, svc := newCustomFieldUserService()
+
+	_, err := svc.Find(context.Background(), model.UserQuery{CustomFields: map[string]string{"shoe_size": "44"}})
+
+	var fieldErr *CustomFieldError
+	if !errors.As(err, &fieldErr) {
+		t.Errorf("expected CustomFieldError, got %v", err)
+	}
+}
+
+func TestFind_ChecksSort(t *testing.T) {
+	_, svc := newCustomFieldUserService()
+
+	tests := []struct {
+		sort    string
+		wantErr bool
+	}{
+		{"username,-created_at", false},
+		{"password", true},
+		{"username,-username", true},
+		{"username,", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			_, err := svc.Find(context.Background(), model.UserQuery{Sort: tt.sort})
+
+			var sortErr *model.SortError
+			if errors.As(err, &sortErr) != tt.wantErr {
+				t.Errorf("expected sort error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFind_RejectsInvertedDateRange(t *testing.T) {
+	_, svc := newCustomFieldUserService()
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, -1)
+
+	_, err := svc.Find(context.Background(), model.UserQuery{CreatedFrom: &from, CreatedTo: &to})
+
+	if !errors.Is(err, ErrInvalidDateRange) {
+		t.Errorf("expected ErrInvalidDateRange, got %v", err)
+	}
+}
+
+func TestCustomFieldService_ValidatesDefinition(t *testing.T) {
+	svc := NewCustomFieldService(&mockCustomFieldRepository{})
+
+	tests := []struct {
+		name    string
+		field   model.CustomField
+		wantErr bool
+	}{
+		{"valid", model.CustomField{Name: "cost_center", Type: model.CustomFieldString}, false},
+		{"bad name", model.CustomField{Name: "Cost Center", Type: model.CustomFieldString}, true},
+		{"unknown type", model.CustomField{Name: "tags", Type: "array"}, true},
+		{"bad pattern", model.CustomField{Name: "code", Type: model.CustomFieldString, Validation: model.CustomFieldValidation{Pattern: "("}}, true},
+		{"min above max", model.CustomField{Name: "score", Type: model.CustomFieldNumber, Validation: model.CustomFieldValidation{Min: float(5), Max: float(1)}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.Create(context.Background(), &tt.field)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
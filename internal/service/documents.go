@@ -0,0 +1,233 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/storage"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is how many bytes http.DetectContentType inspects
+const sniffLen = 512
+
+// DocumentLimits restricts uploads
+type DocumentLimits struct {
+	MaxSize      int64
+	AllowedTypes []string
+}
+
+type DocumentService interface {
+	List(ctx context.Context, userUUID string) ([]model.Document, error)
+	// Upload stores content for the user. The content type is detected from the data,
+	// not taken from the client, and must be one of the allowed types.
+	Upload(ctx context.Context, userUUID, filename string, size int64, content io.Reader, uploadedBy string) (*model.Document, error)
+	// Open returns the document and its content; the caller must close the reader
+	Open(ctx context.Context, userUUID, id string) (*model.Document, io.ReadCloser, error)
+	Delete(ctx context.Context, userUUID, id string) error
+	// MaxSize is the largest accepted document in bytes
+	MaxSize() int64
+}
+
+type documentService struct {
+	repo    repository.DocumentRepository
+	users   repository.UserRepository
+	backend storage.Backend
+	limits  DocumentLimits
+}
+
+func NewDocumentService(repo repository.DocumentRepository, users repository.UserRepository, backend storage.Backend, limits DocumentLimits) DocumentService {
+	return &documentService{repo: repo, users: users, backend: backend, limits: limits}
+}
+
+func (s *documentService) List(ctx context.Context, userUUID string) ([]model.Document, error) {
+	if err := s.userExists(ctx, userUUID); err != nil {
+		return nil, err
+	}
+	docs, err := s.repo.List(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if docs == nil {
+		docs = []model.Document{}
+	}
+	return docs, nil
+}
+
+func (s *documentService) Upload(ctx context.Context, userUUID, filename string, size int64, content io.Reader, uploadedBy string) (*model.Document, error) {
+	if size > s.limits.MaxSize {
+		return nil, ErrDocumentTooLarge
+	}
+	if err := s.userExists(ctx, userUUID); err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReaderSize(content, sniffLen)
+	head, err := buffered.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	contentType, ok := s.allowedType(http.DetectContentType(head))
+	if !ok {
+		return nil, ErrUnsupportedContentType
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	doc := &model.Document{
+		ID:          id,
+		UserUUID:    userUUID,
+		Filename:    cleanFilename(filename),
+		ContentType: contentType,
+		StorageKey:  "documents/" + userUUID + "/" + id,
+		UploadedBy:  uploadedBy,
+	}
+
+	// Hash and count while streaming; the extra byte detects bodies larger than declared
+	hash := sha256.New()
+	counter := &countingWriter{}
+	limited := io.LimitReader(buffered, s.limits.MaxSize+1)
+	if err := s.backend.Put(ctx, doc.StorageKey, io.TeeReader(limited, io.MultiWriter(hash, counter))); err != nil {
+		return nil, fmt.Errorf("failed to store document: %w", err)
+	}
+	if counter.n > s.limits.MaxSize {
+		s.removeObject(ctx, doc.StorageKey)
+		return nil, ErrDocumentTooLarge
+	}
+	doc.SizeBytes = counter.n
+	doc.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if err := s.repo.Create(ctx, doc); err != nil {
+		s.removeObject(ctx, doc.StorageKey)
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (s *documentService) Open(ctx context.Context, userUUID, id string) (*model.Document, io.ReadCloser, error) {
+	doc, err := s.get(ctx, userUUID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	content, err := s.backend.Open(ctx, doc.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, errors.New("document content missing")
+		}
+		return nil, nil, err
+	}
+	return doc, content, nil
+}
+
+func (s *documentService) Delete(ctx context.Context, userUUID, id string) error {
+	doc, err := s.get(ctx, userUUID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, userUUID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDocumentNotFound
+		}
+		return err
+	}
+	s.removeObject(ctx, doc.StorageKey)
+	return nil
+}
+
+func (s *documentService) MaxSize() int64 {
+	return s.limits.MaxSize
+}
+
+func (s *documentService) get(ctx context.Context, userUUID, id string) (*model.Document, error) {
+	doc, err := s.repo.Get(ctx, userUUID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (s *documentService) userExists(ctx context.Context, userUUID string) error {
+	if _, err := s.users.GetByUUID(ctx, userUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// allowedType compares media types without parameters, e.g. "text/plain; charset=utf-8"
+func (s *documentService) allowedType(detected string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(detected)
+	if err != nil {
+		return "", false
+	}
+	for _, allowed := range s.limits.AllowedTypes {
+		if strings.EqualFold(mediaType, allowed) {
+			return detected, true
+		}
+	}
+	return "", false
+}
+
+// removeObject deletes an object whose metadata was not or is no longer stored
+func (s *documentService) removeObject(ctx context.Context, key string) {
+	if err := s.backend.Delete(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "failed to remove stored document", "key", key, "error", err)
+	}
+}
+
+// cleanFilename strips client-side directories and characters unsafe in headers
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == "/" {
+		return "document"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
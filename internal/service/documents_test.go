@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/storage"
+	"database/sql"
+	"io"
+	"strings"
+	"testing"
+)
+
+type mockDocumentRepository struct {
+	docs map[string]*model.Document
+}
+
+func (m *mockDocumentRepository) List(_ context.Context, userUUID string) ([]model.Document, error) {
+	var docs []model.Document
+	for _, d := range m.docs {
+		if d.UserUUID == userUUID {
+			docs = append(docs, *d)
+		}
+	}
+	return docs, nil
+}
+
+func (m *mockDocumentRepository) Get(_ context.Context, userUUID, id string) (*model.Document, error) {
+	d, ok := m.docs[id]
+	if !ok || d.UserUUID != userUUID {
+		return nil, sql.ErrNoRows
+	}
+	return d, nil
+}
+
+func (m *mockDocumentRepository) Create(_ context.Context, doc *model.Document) error {
+	m.docs[doc.ID] = doc
+	return nil
+}
+
+func (m *mockDocumentRepository) Delete(_ context.Context, userUUID, id string) error {
+	if _, err := m.Get(context.Background(), userUUID, id); err != nil {
+		return err
+	}
+	delete(m.docs, id)
+	return nil
+}
+
+func newDocumentTestService(t *testing.T) DocumentService {
+	t.Helper()
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	users := newMockUserRepository()
+	users.users["user-uuid"] = &model.User{UUID: "user-uuid", Username: "jdoe"}
+	return NewDocumentService(&mockDocumentRepository{docs: make(map[string]*model.Document)}, users, backend, DocumentLimits{
+		MaxSize:      1024,
+		AllowedTypes: []string{"application/pdf", "text/plain"},
+	})
+}
+
+func TestDocumentService_UploadAndOpen(t *testing.T) {
+	// Given
+	svc := newDocumentTestService(t)
+	content := "%PDF-1.7 signed contract"
+
+	// When: Uploading a PDF with a client-side path in its name
+	doc, err := svc.Upload(context.Background(), "user-uuid", `C:\scans\contract.pdf`, int64(len(content)), strings.NewReader(content), "support")
+
+	// Then: The sniffed type, size and hash are stored and the content round-trips
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.ContentType != "application/pdf" || doc.SizeBytes != int64(len(content)) || doc.Filename != "contract.pdf" {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if len(doc.SHA256) != 64 {
+		t.Errorf("expected a sha256 hex digest, got %q", doc.SHA256)
+	}
+	_, r, err := svc.Open(context.Background(), "user-uuid", doc.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = r.Close() }()
+	data, _ := io.ReadAll(r)
+	if string(data) != content {
+		t.Errorf("expected stored content, got %q", data)
+	}
+}
+
+func TestDocumentService_UploadRejections(t *testing.T) {
+	svc := newDocumentTestService(t)
+	exe := append([]byte("MZ"), bytes.Repeat([]byte{0}, 100)...)
+
+	tests := []struct {
+		name    string
+		uuid    string
+		content []byte
+		size    int64
+		wantErr string
+	}{
+		{"executable", "user-uuid", exe, int64(len(exe)), "unsupported content type"},
+		{"declared too large", "user-uuid", []byte("hello"), 2048, "document too large"},
+		{"body larger than declared", "user-uuid", bytes.Repeat([]byte("a"), 2048), 10, "document too large"},
+		{"unknown user", "missing", []byte("hello"), 5, "users not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Upload(context.Background(), tt.uuid, "file", tt.size, bytes.NewReader(tt.content), "support")
+
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected error %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import "errors"
+
+// Errors services return for requests that cannot be served as asked. Their
+// messages are passed on to clients, so they are safe to show; compare with
+// errors.Is rather than by message.
+var (
+	// Users
+	ErrUserNotFound       = errors.New("users not found")
+	ErrUsernameTaken      = errors.New("username already exists")
+	ErrEmailTaken         = errors.New("email already exists")
+	ErrVersionMismatch    = errors.New("user has changed since the given version")
+	ErrInvalidGroupBy     = errors.New("invalid group_by")
+	ErrInvalidSampleSize  = errors.New("invalid sample size")
+	ErrInvalidBulkSize    = errors.New("invalid bulk size")
+	ErrEmptySearchTerm    = errors.New("empty search term")
+	ErrUnknownSearchType  = errors.New("unknown search type")
+	ErrPrefixTooLong      = errors.New("prefix too long")
+	ErrInvalidDateRange   = errors.New("invalid date range")
+	ErrDateRangeTooLarge  = errors.New("date range too large")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrBulkRolledBack     = errors.New("not deleted, as another user of the batch could not be")
+	ErrUserQuotaExceeded  = errors.New("the maximum number of users of this deployment has been reached")
+
+	// Change approvals
+	ErrChangeNotFound = errors.New("change not found")
+	ErrChangePending  = errors.New("change already pending")
+	ErrChangeReviewed = errors.New("change already reviewed")
+	ErrOwnChange      = errors.New("cannot approve own change")
+	ErrInvalidStatus  = errors.New("invalid status")
+
+	// Scheduled deletions
+	ErrEffectiveDateNotFuture = errors.New("effective date must be in the future")
+	ErrNoDeletionScheduled    = errors.New("no deletion scheduled")
+
+	// Consents
+	ErrUnknownDocument          = errors.New("unknown document")
+	ErrConsentVersionNotCurrent = errors.New("consent version is not current")
+
+	// Documents, exports and imports
+	ErrDocumentNotFound       = errors.New("document not found")
+	ErrDocumentTooLarge       = errors.New("document too large")
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	ErrExportNotFound         = errors.New("export not found")
+	ErrExportNotReady         = errors.New("export not ready")
+	ErrUnsupportedImport      = errors.New("unsupported import format")
+	ErrInvalidImportRow       = errors.New("row cannot be read as a user")
+
+	// Notes
+	ErrNoteNotFound     = errors.New("note not found")
+	ErrNoteBodyRequired = errors.New("note body is required")
+	ErrNoteBodyTooLong  = errors.New("note body is too long")
+
+	// Custom fields, rules and saved views
+	ErrCustomFieldNotFound = errors.New("custom field not found")
+	ErrCustomFieldExists   = errors.New("custom field already exists")
+	ErrRuleNotFound        = errors.New("rule not found")
+	ErrRuleExists          = errors.New("rule already exists")
+	ErrInvalidViewName     = errors.New("invalid view name")
+	ErrSavedViewNotFound   = errors.New("saved view not found")
+	ErrSavedViewExists     = errors.New("saved view already exists")
+
+	// Personal tokens, signing keys and API keys
+	ErrTokenNotFound      = errors.New("token not found")
+	ErrTooManyTokens      = errors.New("too many tokens")
+	ErrInvalidTokenName   = errors.New("token name must be 1 to 100 characters")
+	ErrTokenExpiryPast    = errors.New("token expiry must be in the future")
+	ErrTokenExpiryTooLong = errors.New("token expiry exceeds the maximum lifetime")
+	ErrSigningKeyNotFound = errors.New("signing key not found")
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrInvalidAPIKeyName  = errors.New("API key name must be 1 to 100 characters")
+	ErrAPIKeyExpiryPast   = errors.New("API key expiry must be in the future")
+
+	// Webhooks
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrInvalidWebhookURL    = errors.New("webhook URL must be an absolute http or https URL of at most 2000 characters")
+	ErrInvalidWebhookEvents = errors.New("webhook events must be one or more of user.created, user.updated, user.deleted and user.restored")
+	ErrDescriptionTooLong   = errors.New("webhook description must be at most 200 characters")
+
+	// Billing reports
+	ErrBillingReportNotFound = errors.New("billing report not found")
+	ErrReportMonthInFuture   = errors.New("report month has not started")
+)
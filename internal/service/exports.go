@@ -0,0 +1,271 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/storage"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportHeader is the first line of every export
+var exportHeader = []string{"id", "uuid", "username", "email", "full_name"}
+
+type ExportService interface {
+	// Start queues a CSV export of all live users; the export job generates it
+	Start(ctx context.Context, requestedBy string) (*model.Export, error)
+	Get(ctx context.Context, id string) (*model.Export, error)
+	// Open returns a completed export and its content; the caller must close the
+	// reader, which also implements io.Seeker when the storage backend can seek
+	Open(ctx context.Context, id string) (*model.Export, io.ReadCloser, error)
+	// RunPending generates unfinished exports chunk by chunk until they are done or
+	// ctx expires; the next run resumes from the last checkpoint
+	RunPending(ctx context.Context) error
+	// Purge removes exports completed longer than the retention period ago and
+	// returns their IDs
+	Purge(ctx context.Context) ([]string, error)
+}
+
+type exportService struct {
+	repo      repository.ExportRepository
+	backend   storage.Backend
+	chunkRows int
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewExportService creates the service; exports are written chunkRows users at a
+// time and kept for retention once complete
+func NewExportService(repo repository.ExportRepository, backend storage.Backend, chunkRows int, retention time.Duration) ExportService {
+	return &exportService{repo: repo, backend: backend, chunkRows: chunkRows, retention: retention, now: time.Now}
+}
+
+func (s *exportService) Start(ctx context.Context, requestedBy string) (*model.Export, error) {
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	export := &model.Export{ID: id, RequestedBy: requestedBy}
+	if err := s.repo.Create(ctx, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *exportService) Get(ctx context.Context, id string) (*model.Export, error) {
+	export, err := s.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrExportNotFound
+		}
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *exportService) Open(ctx context.Context, id string) (*model.Export, io.ReadCloser, error) {
+	export, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if export.Status != model.ExportCompleted {
+		return nil, nil, ErrExportNotReady
+	}
+	content, err := s.backend.Open(ctx, exportKey(id))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, nil, errors.New("export content missing")
+		}
+		return nil, nil, err
+	}
+	return export, content, nil
+}
+
+func (s *exportService) RunPending(ctx context.Context) error {
+	exports, err := s.repo.Unfinished(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range exports {
+		if err := s.generate(ctx, &exports[i]); err != nil {
+			return fmt.Errorf("export %s: %w", exports[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *exportService) Purge(ctx context.Context) ([]string, error) {
+	exports, err := s.repo.DeleteCompletedBefore(ctx, s.now().Add(-s.retention))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(exports))
+	for i, export := range exports {
+		s.removeObject(ctx, exportKey(export.ID))
+		ids[i] = export.ID
+	}
+	return ids, nil
+}
+
+// generate writes the remaining chunks of export and then assembles the file. ctx
+// is only checked between chunks, so a stored chunk always has its checkpoint.
+func (s *exportService) generate(ctx context.Context, export *model.Export) error {
+	work := context.WithoutCancel(ctx)
+	for ctx.Err() == nil {
+		done, err := s.writeChunk(work, export)
+		if err != nil {
+			return err
+		}
+		if done {
+			return s.assemble(work, export)
+		}
+	}
+	return nil
+}
+
+// writeChunk stores the next chunkRows users after the checkpoint as one chunk and
+// advances the checkpoint; it reports whether all users have been written
+func (s *exportService) writeChunk(ctx context.Context, export *model.Export) (bool, error) {
+	users, err := s.repo.Users(ctx, export.LastUserID, s.chunkRows)
+	if err != nil {
+		return false, err
+	}
+	if len(users) == 0 && export.Chunks > 0 {
+		return true, nil
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if export.Chunks == 0 {
+		_ = w.Write(exportHeader)
+	}
+	for _, u := range users {
+		_ = w.Write([]string{strconv.FormatInt(u.ID, 10), u.UUID, csvSafe(u.Username), csvSafe(u.Email), csvSafe(u.FullName)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return false, err
+	}
+	size := int64(buf.Len())
+	if err := s.backend.Put(ctx, exportChunkKey(export.ID, export.Chunks), &buf); err != nil {
+		return false, fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	export.Status = model.ExportRunning
+	export.Chunks++
+	export.RowsWritten += int64(len(users))
+	export.SizeBytes += size
+	if len(users) > 0 {
+		export.LastUserID = users[len(users)-1].ID
+	}
+	if err := s.repo.Checkpoint(ctx, export); err != nil {
+		return false, err
+	}
+	return len(users) < s.chunkRows, nil
+}
+
+// assemble concatenates the chunks into the export file, hashing it on the way, and
+// removes the chunks. A missing chunk starts the export over.
+func (s *exportService) assemble(ctx context.Context, export *model.Export) error {
+	chunks := &chunkReader{ctx: ctx, backend: s.backend, id: export.ID, n: export.Chunks}
+	hash := sha256.New()
+	counter := &countingWriter{}
+	err := s.backend.Put(ctx, exportKey(export.ID), io.TeeReader(chunks, io.MultiWriter(hash, counter)))
+	_ = chunks.Close()
+	if errors.Is(err, storage.ErrNotFound) {
+		slog.WarnContext(ctx, "export is missing a chunk; generating it again", "export_id", export.ID)
+		export.LastUserID, export.Chunks, export.RowsWritten, export.SizeBytes = 0, 0, 0, 0
+		return s.repo.Checkpoint(ctx, export)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store export: %w", err)
+	}
+
+	export.SizeBytes = counter.n
+	export.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	if err := s.repo.Complete(ctx, export); err != nil {
+		s.removeObject(ctx, exportKey(export.ID))
+		return err
+	}
+	for i := 0; i < export.Chunks; i++ {
+		s.removeObject(ctx, exportChunkKey(export.ID, i))
+	}
+	return nil
+}
+
+// removeObject deletes a file that is no longer needed
+func (s *exportService) removeObject(ctx context.Context, key string) {
+	if err := s.backend.Delete(ctx, key); err != nil {
+		slog.ErrorContext(ctx, "failed to remove stored export", "key", key, "error", err)
+	}
+}
+
+func exportKey(id string) string {
+	return "exports/" + id + "/export.csv"
+}
+
+func exportChunkKey(id string, chunk int) string {
+	return fmt.Sprintf("exports/%s/chunk-%06d.csv", id, chunk)
+}
+
+// csvSafe keeps spreadsheets from evaluating a value as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// chunkReader reads the chunks of an export one after another, opening each only
+// once the previous one is exhausted
+type chunkReader struct {
+	ctx     context.Context
+	backend storage.Backend
+	id      string
+	n, next int
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if r.next == r.n {
+				return 0, io.EOF
+			}
+			chunk, err := r.backend.Open(r.ctx, exportChunkKey(r.id, r.next))
+			if err != nil {
+				return 0, err
+			}
+			r.current = chunk
+			r.next++
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			_ = r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
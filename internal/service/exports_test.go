@@ -0,0 +1,238 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/storage"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockExportRepository struct {
+	exports map[string]*model.Export
+	users   []model.User
+	// failUsers makes the nth call to Users fail, counting from 1
+	failUsers int
+	calls     int
+}
+
+func (m *mockExportRepository) Create(_ context.Context, export *model.Export) error {
+	export.Status = model.ExportPending
+	copied := *export
+	m.exports[export.ID] = &copied
+	return nil
+}
+
+func (m *mockExportRepository) Get(_ context.Context, id string) (*model.Export, error) {
+	export, ok := m.exports[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *export
+	return &copied, nil
+}
+
+func (m *mockExportRepository) Unfinished(context.Context) ([]model.Export, error) {
+	var exports []model.Export
+	for _, export := range m.exports {
+		if export.Status != model.ExportCompleted {
+			exports = append(exports, *export)
+		}
+	}
+	return exports, nil
+}
+
+func (m *mockExportRepository) Users(_ context.Context, afterID int64, limit int) ([]model.User, error) {
+	m.calls++
+	if m.calls == m.failUsers {
+		return nil, errors.New("connection reset")
+	}
+	var users []model.User
+	for _, u := range m.users {
+		if u.ID > afterID && len(users) < limit {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+func (m *mockExportRepository) Checkpoint(_ context.Context, export *model.Export) error {
+	copied := *export
+	m.exports[export.ID] = &copied
+	return nil
+}
+
+func (m *mockExportRepository) Complete(_ context.Context, export *model.Export) error {
+	now := time.Now()
+	export.Status = model.ExportCompleted
+	export.CompletedAt = &now
+	copied := *export
+	m.exports[export.ID] = &copied
+	return nil
+}
+
+func (m *mockExportRepository) DeleteCompletedBefore(_ context.Context, before time.Time) ([]model.Export, error) {
+	var deleted []model.Export
+	for id, export := range m.exports {
+		if export.CompletedAt != nil && export.CompletedAt.Before(before) {
+			deleted = append(deleted, *export)
+			delete(m.exports, id)
+		}
+	}
+	return deleted, nil
+}
+
+func newExportTestService(t *testing.T, users int) (*exportService, *mockExportRepository, storage.Backend) {
+	t.Helper()
+	backend, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	repo := &mockExportRepository{exports: make(map[string]*model.Export)}
+	for i := 1; i <= users; i++ {
+		repo.users = append(repo.users, model.User{
+			ID:       int64(i),
+			UUID:     fmt.Sprintf("uuid-%d", i),
+			Username: fmt.Sprintf("user%d", i),
+			Email:    fmt.Sprintf("user%d@example.com", i),
+			FullName: fmt.Sprintf("User %d", i),
+		})
+	}
+	return NewExportService(repo, backend, 2, time.Hour).(*exportService), repo, backend
+}
+
+func readExport(t *testing.T, svc ExportService, id string) string {
+	t.Helper()
+	_, content, err := svc.Open(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = content.Close() }()
+	data, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	return string(data)
+}
+
+func TestExportService_GeneratesInChunks(t *testing.T) {
+	// Given: Five users exported two at a time
+	svc, _, backend := newExportTestService(t, 5)
+	export, err := svc.Start(context.Background(), "support")
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// When
+	if err := svc.RunPending(context.Background()); err != nil {
+		t.Fatalf("RunPending: %v", err)
+	}
+
+	// Then: The export holds a header and every user once, and its chunks are gone
+	export, err = svc.Get(context.Background(), export.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if export.Status != model.ExportCompleted || export.Chunks != 3 || export.RowsWritten != 5 {
+		t.Errorf("unexpected export: %+v", export)
+	}
+	content := readExport(t, svc, export.ID)
+	lines := strings.Split(strings.TrimSpace(content), "\n")
+	if len(lines) != 6 || lines[0] != "id,uuid,username,email,full_name" || lines[5] != "5,uuid-5,user5,user5@example.com,User 5" {
+		t.Errorf("unexpected content:\n%s", content)
+	}
+	sum := sha256.Sum256([]byte(content))
+	if export.SHA256 != hex.EncodeToString(sum[:]) || export.SizeBytes != int64(len(content)) {
+		t.Errorf("size or hash does not match the content")
+	}
+	if _, err := backend.Open(context.Background(), exportChunkKey(export.ID, 0)); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("expected chunks to be removed, got %v", err)
+	}
+}
+
+func TestExportService_ResumesFromCheckpoint(t *testing.T) {
+	// Given: An export whose second chunk fails to load
+	svc, repo, _ := newExportTestService(t, 5)
+	repo.failUsers = 2
+	export, _ := svc.Start(context.Background(), "support")
+
+	// When: Running the job twice
+	if err := svc.RunPending(context.Background()); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+	if got, _ := svc.Get(context.Background(), export.ID); got.Status != model.ExportRunning || got.Chunks != 1 {
+		t.Fatalf("expected a checkpoint after the first chunk, got %+v", got)
+	}
+	if err := svc.RunPending(context.Background()); err != nil {
+		t.Fatalf("RunPending: %v", err)
+	}
+
+	// Then: The second run continues after the checkpoint without repeating users
+	content := readExport(t, svc, export.ID)
+	if strings.Count(content, "user1@example.com") != 1 || strings.Count(content, "\n") != 6 {
+		t.Errorf("unexpected content:\n%s", content)
+	}
+}
+
+func TestExportService_StopsWhenContextExpires(t *testing.T) {
+	// Given
+	svc, _, _ := newExportTestService(t, 5)
+	export, _ := svc.Start(context.Background(), "support")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When: The run has no time left
+	if err := svc.RunPending(ctx); err != nil {
+		t.Fatalf("RunPending: %v", err)
+	}
+
+	// Then: The export stays queued and cannot be downloaded yet
+	if _, _, err := svc.Open(context.Background(), export.ID); err == nil || err.Error() != "export not ready" {
+		t.Errorf("expected export not ready, got %v", err)
+	}
+}
+
+func TestExportService_EscapesFormulas(t *testing.T) {
+	// Given: A user whose name a spreadsheet would evaluate
+	svc, repo, _ := newExportTestService(t, 0)
+	repo.users = []model.User{{ID: 1, UUID: "uuid-1", Username: "jdoe", Email: "jdoe@example.com", FullName: "=HYPERLINK(\"x\")"}}
+	export, _ := svc.Start(context.Background(), "support")
+
+	// When
+	if err := svc.RunPending(context.Background()); err != nil {
+		t.Fatalf("RunPending: %v", err)
+	}
+
+	// Then
+	if content := readExport(t, svc, export.ID); !strings.Contains(content, `"'=HYPERLINK(""x"")"`) {
+		t.Errorf("expected the formula to be escaped:\n%s", content)
+	}
+}
+
+func TestExportService_Purge(t *testing.T) {
+	// Given: A completed export past its retention
+	svc, _, _ := newExportTestService(t, 1)
+	export, _ := svc.Start(context.Background(), "support")
+	if err := svc.RunPending(context.Background()); err != nil {
+		t.Fatalf("RunPending: %v", err)
+	}
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	// When
+	purged, err := svc.Purge(context.Background())
+
+	// Then
+	if err != nil || len(purged) != 1 || purged[0] != export.ID {
+		t.Fatalf("expected %s to be purged, got %v, %v", export.ID, purged, err)
+	}
+	if _, err := svc.Get(context.Background(), export.ID); err == nil || err.Error() != "export not found" {
+		t.Errorf("expected export not found, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"cruder/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Formats read by ImportService.Import
+const (
+	ImportCSV   = "csv"
+	ImportJSONL = "jsonl"
+)
+
+// maxImportLine caps one line of a JSON Lines import
+const maxImportLine = 1 << 20
+
+// ImportFileError reports an import that cannot be read past a line, such as a
+// CSV file without the required columns
+type ImportFileError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ImportFileError) Error() string {
+	return fmt.Sprintf("invalid import: line %d: %s", e.Line, e.Reason)
+}
+
+// ImportFailure is a row that was not imported, with its line in the file
+type ImportFailure struct {
+	Line int
+	Err  error
+}
+
+// ImportResult counts the rows of an import; Failures are in line order
+type ImportResult struct {
+	Rows     int
+	Created  int
+	Failures []ImportFailure
+}
+
+type ImportService interface {
+	// Import creates the users read from r, in format csv or jsonl, MaxBulkSize at
+	// a time with the checks of UserService.CreateMany. A bad row fails on its own;
+	// the import fails as a whole only when the file cannot be read.
+	Import(ctx context.Context, r io.Reader, format string) (*ImportResult, error)
+}
+
+type importService struct {
+	users UserService
+}
+
+func NewImportService(users UserService) ImportService {
+	return &importService{users: users}
+}
+
+// importReader returns the next user of a file and the line it starts on,
+// ErrInvalidImportRow for a row that is not a user, or io.EOF at the end
+type importReader func() (int, *model.User, error)
+
+func (s *importService) Import(ctx context.Context, r io.Reader, format string) (*ImportResult, error) {
+	var next importReader
+	var err error
+	switch format {
+	case ImportCSV:
+		next, err = csvImportReader(r)
+	case ImportJSONL:
+		next = jsonlImportReader(r)
+	default:
+		return nil, ErrUnsupportedImport
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	batch := make([]*model.User, 0, MaxBulkSize)
+	lines := make([]int, 0, MaxBulkSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		errs, err := s.users.CreateMany(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for i, err := range errs {
+			if err != nil {
+				result.Failures = append(result.Failures, ImportFailure{Line: lines[i], Err: err})
+			} else {
+				result.Created++
+			}
+		}
+		batch, lines = batch[:0], lines[:0]
+		return nil
+	}
+
+	for {
+		line, user, err := next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && !errors.Is(err, ErrInvalidImportRow) {
+			return nil, err
+		}
+		result.Rows++
+		if err != nil {
+			result.Failures = append(result.Failures, ImportFailure{Line: line, Err: err})
+			continue
+		}
+		// CreateMany leaves the binding rules to its callers
+		if violations := checkUserFormat(user); len(violations) > 0 {
+			result.Failures = append(result.Failures, ImportFailure{Line: line, Err: &ValidationError{Errors: violations}})
+			continue
+		}
+		batch = append(batch, user)
+		lines = append(lines, line)
+		if len(batch) == MaxBulkSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(result.Failures, func(i, j int) bool { return result.Failures[i].Line < result.Failures[j].Line })
+	return result, nil
+}
+
+// csvImportReader reads a CSV file with a header line naming its columns:
+// username and email, optionally full_name. The id and uuid columns of an export
+// are ignored, so an export can be imported elsewhere.
+func csvImportReader(r io.Reader) (importReader, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, &ImportFileError{Line: 1, Reason: "the file is empty"}
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, &ImportFileError{Line: parseErr.StartLine, Reason: parseErr.Err.Error()}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch name {
+		case "id", "uuid":
+			continue
+		case "username", "email", "full_name":
+		default:
+			return nil, &ImportFileError{Line: 1, Reason: fmt.Sprintf("unknown column %q", name)}
+		}
+		if _, dup := columns[name]; dup {
+			return nil, &ImportFileError{Line: 1, Reason: fmt.Sprintf("column %q appears twice", name)}
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"username", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, &ImportFileError{Line: 1, Reason: fmt.Sprintf("missing column %q", required)}
+		}
+	}
+
+	return func() (int, *model.User, error) {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return 0, nil, io.EOF
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			// A row with the wrong number of fields is skipped; anything else leaves
+			// the reader unsure where the next row starts
+			if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+				return parseErr.StartLine, nil, ErrInvalidImportRow
+			}
+			return 0, nil, &ImportFileError{Line: parseErr.StartLine, Reason: parseErr.Err.Error()}
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		user := &model.User{Username: record[columns["username"]], Email: record[columns["email"]]}
+		if i, ok := columns["full_name"]; ok {
+			user.FullName = record[i]
+		}
+		return line, user, nil
+	}, nil
+}
+
+// jsonlImportReader reads one user object per line, as in a create request;
+// blank lines are skipped
+func jsonlImportReader(r io.Reader) importReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+	line := 0
+	return func() (int, *model.User, error) {
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if line == 1 {
+				text = bytes.TrimPrefix(text, []byte("\ufeff"))
+			}
+			if len(text) == 0 {
+				continue
+			}
+			var user model.User
+			if err := json.Unmarshal(text, &user); err != nil {
+				return line, nil, ErrInvalidImportRow
+			}
+			return line, &user, nil
+		}
+		if err := scanner.Err(); err != nil {
+			if errors.Is(err, bufio.ErrTooLong) {
+				return 0, nil, &ImportFileError{Line: line + 1, Reason: "line longer than 1 MiB"}
+			}
+			return 0, nil, err
+		}
+		return 0, nil, io.EOF
+	}
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"cruder/internal/model"
+)
+
+func TestImport_CSV(t *testing.T) {
+	// Given: An existing user and a file with good and bad rows
+	repo := newMockUserRepository()
+	repo.users["existing-uuid"] = &model.User{UUID: "existing-uuid", Username: "existinguser", Email: "existing@example.com"}
+	service := NewImportService(NewUserService(repo))
+	file := "\ufeffUsername,email,full_name\n" +
+		"anna,anna@example.com,Anna Smith\n" +
+		"bob,not-an-email,Bob\n" +
+		"existinguser,other@example.com,Taken\n" +
+		"carol,carol@example.com\n" +
+		"dave,dave@example.com,\"Dave\nthe second\"\n" +
+		"erin,erin@example.com,Erin\n"
+
+	// When
+	result, err := service.Import(context.Background(), strings.NewReader(file), ImportCSV)
+
+	// Then: Good rows are created and failures carry their line numbers
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Rows != 6 || result.Created != 3 || len(result.Failures) != 3 {
+		t.Fatalf("expected 6 rows, 3 created and 3 failed, got %+v", result)
+	}
+	wantLines := []int{3, 4, 5}
+	for i, failure := range result.Failures {
+		if failure.Line != wantLines[i] {
+			t.Errorf("failure %d: expected line %d, got %d (%v)", i, wantLines[i], failure.Line, failure.Err)
+		}
+	}
+	var validationErr *ValidationError
+	if !errors.As(result.Failures[0].Err, &validationErr) || !errors.Is(result.Failures[1].Err, ErrUsernameTaken) ||
+		!errors.Is(result.Failures[2].Err, ErrInvalidImportRow) {
+		t.Errorf("expected invalid email, taken username and invalid row, got %+v", result.Failures)
+	}
+	if user, _ := repo.GetByUsername(context.Background(), "dave"); user == nil || user.FullName != "Dave\nthe second" {
+		t.Errorf("expected dave with a quoted multi-line name, got %+v", user)
+	}
+}
+
+func TestImport_JSONL(t *testing.T) {
+	// Given: Lines with a blank one and one that is not JSON
+	repo := newMockUserRepository()
+	service := NewImportService(NewUserService(repo))
+	file := `{"username":"anna","email":"anna@example.com"}` + "\n\n" +
+		`{"username": "bob",` + "\n" +
+		`{"username":"carol","email":"carol@example.com","full_name":"Carol"}` + "\n"
+
+	// When
+	result, err := service.Import(context.Background(), strings.NewReader(file), ImportJSONL)
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Rows != 3 || result.Created != 2 || len(result.Failures) != 1 || result.Failures[0].Line != 3 {
+		t.Errorf("expected 2 created and line 3 failed, got %+v", result)
+	}
+}
+
+func TestImport_InvalidFile(t *testing.T) {
+	service := NewImportService(NewUserService(newMockUserRepository()))
+
+	tests := []struct {
+		name   string
+		file   string
+		format string
+		want   string
+	}{
+		{"empty file", "", ImportCSV, "line 1: the file is empty"},
+		{"missing column", "username,full_name\nanna,Anna\n", ImportCSV, `line 1: missing column "email"`},
+		{"unknown column", "username,email,phone\n", ImportCSV, `line 1: unknown column "phone"`},
+		{"broken quotes", "username,email\nanna,\"anna@example.com\n", ImportCSV, "line 2:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Import(context.Background(), strings.NewReader(tt.file), tt.format)
+
+			var fileErr *ImportFileError
+			if !errors.As(err, &fileErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected a file error with %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := service.Import(context.Background(), strings.NewReader(""), "xlsx"); !errors.Is(err, ErrUnsupportedImport) {
+		t.Errorf("expected ErrUnsupportedImport, got %v", err)
+	}
+}
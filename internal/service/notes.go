@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxNoteLength bounds the size of a single note in characters
+const maxNoteLength = 10000
+
+type NoteService interface {
+	List(ctx context.Context, userUUID string) ([]model.Note, error)
+	Create(ctx context.Context, userUUID, author, body string) (*model.Note, error)
+	Update(ctx context.Context, userUUID string, id int64, body string) (*model.Note, error)
+	Delete(ctx context.Context, userUUID string, id int64) error
+}
+
+type noteService struct {
+	repo  repository.NoteRepository
+	users repository.UserRepository
+}
+
+func NewNoteService(repo repository.NoteRepository, users repository.UserRepository) NoteService {
+	return &noteService{repo: repo, users: users}
+}
+
+func (s *noteService) List(ctx context.Context, userUUID string) ([]model.Note, error) {
+	if err := s.userExists(ctx, userUUID); err != nil {
+		return nil, err
+	}
+	notes, err := s.repo.List(ctx, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if notes == nil {
+		notes = []model.Note{}
+	}
+	return notes, nil
+}
+
+func (s *noteService) Create(ctx context.Context, userUUID, author, body string) (*model.Note, error) {
+	body, err := validateNoteBody(body)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userExists(ctx, userUUID); err != nil {
+		return nil, err
+	}
+
+	note := &model.Note{UserUUID: userUUID, Author: author, Body: body}
+	if err := s.repo.Create(ctx, note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (s *noteService) Update(ctx context.Context, userUUID string, id int64, body string) (*model.Note, error) {
+	body, err := validateNoteBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	note := &model.Note{ID: id, UserUUID: userUUID, Body: body}
+	if err := s.repo.Update(ctx, note); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoteNotFound
+		}
+		return nil, err
+	}
+	return note, nil
+}
+
+func (s *noteService) Delete(ctx context.Context, userUUID string, id int64) error {
+	if err := s.repo.Delete(ctx, userUUID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *noteService) userExists(ctx context.Context, userUUID string) error {
+	if _, err := s.users.GetByUUID(ctx, userUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func validateNoteBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", ErrNoteBodyRequired
+	}
+	if utf8.RuneCountInString(body) > maxNoteLength {
+		return "", ErrNoteBodyTooLong
+	}
+	return body, nil
+}
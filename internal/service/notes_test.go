@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"strings"
+	"testing"
+)
+
+type mockNoteRepository struct {
+	notes []model.Note
+}
+
+func (m *mockNoteRepository) List(_ context.Context, userUUID string) ([]model.Note, error) {
+	var notes []model.Note
+	for _, n := range m.notes {
+		if n.UserUUID == userUUID {
+			notes = append(notes, n)
+		}
+	}
+	return notes, nil
+}
+
+func (m *mockNoteRepository) Create(_ context.Context, note *model.Note) error {
+	note.ID = int64(len(m.notes) + 1)
+	m.notes = append(m.notes, *note)
+	return nil
+}
+
+func (m *mockNoteRepository) Update(_ context.Context, note *model.Note) error {
+	return nil
+}
+
+func (m *mockNoteRepository) Delete(_ context.Context, userUUID string, id int64) error {
+	return nil
+}
+
+func TestNoteService_Create(t *testing.T) {
+	users := newMockUserRepository()
+	users.users["user-uuid"] = &model.User{UUID: "user-uuid", Username: "jdoe"}
+	svc := NewNoteService(&mockNoteRepository{}, users)
+
+	tests := []struct {
+		name    string
+		uuid    string
+		body    string
+		wantErr string
+	}{
+		{"valid note", "user-uuid", "  Called about billing  ", ""},
+		{"empty body", "user-uuid", "   ", "note body is required"},
+		{"too long", "user-uuid", strings.Repeat("x", maxNoteLength+1), "note body is too long"},
+		{"unknown user", "missing", "hello", "users not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			note, err := svc.Create(context.Background(), tt.uuid, "support", tt.body)
+
+			// Then
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if note.Body != "Called about billing" || note.Author != "support" {
+				t.Errorf("unexpected note: %+v", note)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/residency"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTokenNameLength bounds the label of a personal token in characters
+const maxTokenNameLength = 100
+
+type PersonalTokenService interface {
+	// List returns the tokens of the user with the username, without their secrets
+	List(ctx context.Context, username string) ([]model.PersonalToken, error)
+	// Create issues a token to the user; the returned token carries its secret, which
+	// is not stored
+	Create(ctx context.Context, username, tenant string, req model.CreatePersonalTokenRequest) (*model.PersonalToken, error)
+	Revoke(ctx context.Context, username, id string) error
+	// Verify returns the token a bearer token belongs to, if it is valid and its
+	// owner still exists
+	Verify(ctx context.Context, token string) (*model.PersonalToken, error)
+}
+
+type personalTokenService struct {
+	repo  repository.PersonalTokenRepository
+	users repository.UserRepository
+	cfg   config.PersonalTokensConfig
+	now   func() time.Time
+}
+
+func NewPersonalTokenService(repo repository.PersonalTokenRepository, users repository.UserRepository, cfg config.PersonalTokensConfig) PersonalTokenService {
+	return &personalTokenService{repo: repo, users: users, cfg: cfg, now: time.Now}
+}
+
+func (s *personalTokenService) List(ctx context.Context, username string) ([]model.PersonalToken, error) {
+	user, err := s.owner(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, user.ID)
+}
+
+func (s *personalTokenService) Create(ctx context.Context, username, tenant string, req model.CreatePersonalTokenRequest) (*model.PersonalToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxTokenNameLength {
+		return nil, ErrInvalidTokenName
+	}
+	now := s.now()
+	expires := now.Add(s.cfg.DefaultTTL)
+	if req.ExpiresAt != nil {
+		expires = *req.ExpiresAt
+		if !expires.After(now) {
+			return nil, ErrTokenExpiryPast
+		}
+		if expires.After(now.Add(s.cfg.MaxTTL)) {
+			return nil, ErrTokenExpiryTooLong
+		}
+	}
+
+	user, err := s.owner(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	count, err := s.repo.Count(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= s.cfg.MaxPerUser {
+		return nil, ErrTooManyTokens
+	}
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	secret, hash, prefix, err := auth.NewPersonalToken()
+	if err != nil {
+		return nil, err
+	}
+	scopes := req.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	token := &model.PersonalToken{
+		ID:        id,
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    scopes,
+		Tenant:    tenant,
+		ExpiresAt: expires.UTC(),
+	}
+	if err := s.repo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+	token.Token = secret
+	return token, nil
+}
+
+func (s *personalTokenService) Revoke(ctx context.Context, username, id string) error {
+	user, err := s.owner(ctx, username)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, user.ID, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *personalTokenService) Verify(ctx context.Context, secret string) (*model.PersonalToken, error) {
+	token, err := s.repo.FindByHash(ctx, auth.HashPersonalToken(secret))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, auth.ErrInvalidToken
+		}
+		return nil, err
+	}
+	// Losing a last-used time is no reason to fail the request
+	if err := s.repo.Touch(residency.WithRegion(ctx, token.Region), token.ID); err != nil {
+		slog.WarnContext(ctx, "failed to record personal token use", "token_id", token.ID, "error", err)
+	}
+	return token, nil
+}
+
+// owner returns the live user with the username
+func (s *personalTokenService) owner(ctx context.Context, username string) (*model.User, error) {
+	user, err := s.users.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/model"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockPersonalTokenRepository struct {
+	tokens map[string]*model.PersonalToken
+	users  *mockUserRepository
+}
+
+func (m *mockPersonalTokenRepository) Create(_ context.Context, token *model.PersonalToken) error {
+	token.CreatedAt = time.Now()
+	copied := *token
+	m.tokens[token.ID] = &copied
+	return nil
+}
+
+func (m *mockPersonalTokenRepository) List(_ context.Context, userID int64) ([]model.PersonalToken, error) {
+	tokens := []model.PersonalToken{}
+	for _, t := range m.tokens {
+		if t.UserID == userID {
+			tokens = append(tokens, *t)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *mockPersonalTokenRepository) Count(ctx context.Context, userID int64) (int, error) {
+	tokens, _ := m.List(ctx, userID)
+	return len(tokens), nil
+}
+
+func (m *mockPersonalTokenRepository) Delete(_ context.Context, userID int64, id string) error {
+	if t, ok := m.tokens[id]; !ok || t.UserID != userID {
+		return sql.ErrNoRows
+	}
+	delete(m.tokens, id)
+	return nil
+}
+
+func (m *mockPersonalTokenRepository) FindByHash(_ context.Context, hash string) (*model.PersonalToken, error) {
+	for _, t := range m.tokens {
+		if t.Hash != hash || !t.ExpiresAt.After(time.Now()) {
+			continue
+		}
+		for _, u := range m.users.users {
+			if u.ID == t.UserID {
+				copied := *t
+				copied.Username = u.Username
+				return &copied, nil
+			}
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockPersonalTokenRepository) Touch(_ context.Context, id string) error {
+	now := time.Now()
+	m.tokens[id].LastUsedAt = &now
+	return nil
+}
+
+func newPersonalTokenTestService() (*personalTokenService, *mockPersonalTokenRepository, *mockUserRepository) {
+	users := newMockUserRepository()
+	users.users["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "jdoe"}
+	users.users["uuid-2"] = &model.User{ID: 2, UUID: "uuid-2", Username: "asmith"}
+	repo := &mockPersonalTokenRepository{tokens: make(map[string]*model.PersonalToken), users: users}
+	cfg := config.PersonalTokensConfig{DefaultTTL: 24 * time.Hour, MaxTTL: 7 * 24 * time.Hour, MaxPerUser: 2}
+	return NewPersonalTokenService(repo, users, cfg).(*personalTokenService), repo, users
+}
+
+func TestPersonalTokenService_CreateAndVerify(t *testing.T) {
+	// Given
+	svc, repo, _ := newPersonalTokenTestService()
+
+	// When: jdoe creates a token and uses it
+	created, err := svc.Create(context.Background(), "jdoe", "acme", model.CreatePersonalTokenRequest{Name: " ci ", Scopes: []string{"documents"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	verified, err := svc.Verify(context.Background(), created.Token)
+
+	// Then: The token authenticates as jdoe, and only its hash is stored
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if verified.Username != "jdoe" || verified.Tenant != "acme" || len(verified.Scopes) != 1 || verified.Scopes[0] != "documents" {
+		t.Errorf("unexpected token %+v", verified)
+	}
+	if !auth.IsPersonalToken(created.Token) || !strings.HasPrefix(created.Token, created.Prefix) || created.Name != "ci" {
+		t.Errorf("unexpected token %+v", created)
+	}
+	stored := repo.tokens[created.ID]
+	if stored.Token != "" || stored.Hash != auth.HashPersonalToken(created.Token) {
+		t.Errorf("expected only the hash to be stored, got %+v", stored)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("expected the use to be recorded")
+	}
+	if d := time.Until(created.ExpiresAt); d < 23*time.Hour || d > 24*time.Hour {
+		t.Errorf("expected the default TTL, expires in %v", d)
+	}
+	if _, err := svc.Verify(context.Background(), created.Token+"x"); err == nil || err.Error() != "invalid token" {
+		t.Errorf("expected invalid token, got %v", err)
+	}
+}
+
+func TestPersonalTokenService_CreateRejects(t *testing.T) {
+	svc, _, _ := newPersonalTokenTestService()
+	past := time.Now().Add(-time.Minute)
+	tooLate := time.Now().Add(8 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		username string
+		req      model.CreatePersonalTokenRequest
+		wantErr  string
+	}{
+		{"empty name", "jdoe", model.CreatePersonalTokenRequest{Name: "  "}, "token name must be 1 to 100 characters"},
+		{"expired", "jdoe", model.CreatePersonalTokenRequest{Name: "ci", ExpiresAt: &past}, "token expiry must be in the future"},
+		{"beyond max ttl", "jdoe", model.CreatePersonalTokenRequest{Name: "ci", ExpiresAt: &tooLate}, "token expiry exceeds the maximum lifetime"},
+		{"unknown user", "ghost", model.CreatePersonalTokenRequest{Name: "ci"}, "users not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			_, err := svc.Create(context.Background(), tt.username, "", tt.req)
+
+			// Then
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPersonalTokenService_Limit(t *testing.T) {
+	// Given: jdoe holds as many tokens as allowed
+	svc, _, _ := newPersonalTokenTestService()
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Create(context.Background(), "jdoe", "", model.CreatePersonalTokenRequest{Name: "ci"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	// When
+	_, err := svc.Create(context.Background(), "jdoe", "", model.CreatePersonalTokenRequest{Name: "one more"})
+
+	// Then
+	if err == nil || err.Error() != "too many tokens" {
+		t.Errorf("expected too many tokens, got %v", err)
+	}
+}
+
+func TestPersonalTokenService_Revoke(t *testing.T) {
+	// Given: A token of jdoe
+	svc, _, users := newPersonalTokenTestService()
+	created, _ := svc.Create(context.Background(), "jdoe", "", model.CreatePersonalTokenRequest{Name: "ci"})
+
+	// When: Another user tries to revoke it, then jdoe does
+	otherErr := svc.Revoke(context.Background(), "asmith", created.ID)
+	err := svc.Revoke(context.Background(), "jdoe", created.ID)
+
+	// Then
+	if otherErr == nil || otherErr.Error() != "token not found" {
+		t.Errorf("expected token not found for another user, got %v", otherErr)
+	}
+	if err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Verify(context.Background(), created.Token); err == nil {
+		t.Error("expected a revoked token to be rejected")
+	}
+
+	// And a token of a deleted user stops working
+	other, _ := svc.Create(context.Background(), "asmith", "", model.CreatePersonalTokenRequest{Name: "ci"})
+	delete(users.users, "uuid-2")
+	if _, err := svc.Verify(context.Background(), other.Token); err == nil {
+		t.Error("expected the token of a deleted user to be rejected")
+	}
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"math"
+	"time"
+)
+
+// Recycle bin page size limits
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// purgeBatchSize bounds how many users one purge statement removes
+const purgeBatchSize = 100
+
+type RecycleBinService interface {
+	// List returns one page of soft-deleted users with their purge date; pages start at 1
+	List(ctx context.Context, page, perPage int) (*model.DeletedUserPage, error)
+	// Purge removes users deleted longer than the retention period ago for good and
+	// returns their UUIDs
+	Purge(ctx context.Context) ([]string, error)
+}
+
+type recycleBinService struct {
+	repo       repository.UserRepository
+	purgeAfter time.Duration
+	now        func() time.Time
+}
+
+// NewRecycleBinService creates the service; deleted users are purged purgeAfter their deletion
+func NewRecycleBinService(repo repository.UserRepository, purgeAfter time.Duration) RecycleBinService {
+	return &recycleBinService{repo: repo, purgeAfter: purgeAfter, now: time.Now}
+}
+
+func (s *recycleBinService) List(ctx context.Context, page, perPage int) (*model.DeletedUserPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPageSize
+	}
+	if perPage > MaxPageSize {
+		perPage = MaxPageSize
+	}
+
+	users, total, err := s.repo.ListDeleted(ctx, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	for i := range users {
+		users[i].PurgeAt = users[i].DeletedAt.Add(s.purgeAfter)
+		users[i].DaysRemaining = daysUntil(now, users[i].PurgeAt)
+	}
+	if users == nil {
+		users = []model.DeletedUser{}
+	}
+
+	return &model.DeletedUserPage{Items: users, Page: page, PerPage: perPage, Total: total}, nil
+}
+
+func (s *recycleBinService) Purge(ctx context.Context) ([]string, error) {
+	before := s.now().Add(-s.purgeAfter)
+	var purged []string
+	for {
+		batch, err := s.repo.Purge(ctx, before, purgeBatchSize)
+		purged = append(purged, batch...)
+		if err != nil || len(batch) < purgeBatchSize {
+			return purged, err
+		}
+	}
+}
+
+// daysUntil counts started days until t, so anything due within a day reports 1.
+// Past dates report 0.
+func daysUntil(now, t time.Time) int {
+	left := t.Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return int(math.Ceil(left.Hours() / 24))
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type recycleBinRepository struct {
+	*mockUserRepository
+	deleted []model.DeletedUser
+	limit   int
+	offset  int
+	before  time.Time
+	purge   []string
+}
+
+func (m *recycleBinRepository) ListDeleted(ctx context.Context, limit, offset int) ([]model.DeletedUser, int, error) {
+	m.limit, m.offset = limit, offset
+	return m.deleted, len(m.deleted), nil
+}
+
+func (m *recycleBinRepository) Purge(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	m.before = before
+	n := min(limit, len(m.purge))
+	batch := m.purge[:n]
+	m.purge = m.purge[n:]
+	return batch, nil
+}
+
+func TestRecycleBinService_List(t *testing.T) {
+	// Given: Two users deleted 1 hour and 29.5 days ago, with a 30 day retention
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	repo := &recycleBinRepository{
+		mockUserRepository: newMockUserRepository(),
+		deleted: []model.DeletedUser{
+			{User: model.User{Username: "recent"}, DeletedAt: now.Add(-time.Hour)},
+			{User: model.User{Username: "old"}, DeletedAt: now.Add(-708 * time.Hour)},
+		},
+	}
+	svc := &recycleBinService{repo: repo, purgeAfter: 30 * 24 * time.Hour, now: func() time.Time { return now }}
+
+	// When: Listing the second page with 25 items per page
+	page, err := svc.List(context.Background(), 2, 25)
+
+	// Then: The repository is paged and purge dates are computed
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.limit != 25 || repo.offset != 25 {
+		t.Errorf("expected limit 25 offset 25, got limit %d offset %d", repo.limit, repo.offset)
+	}
+	if page.Total != 2 || page.Page != 2 || page.PerPage != 25 {
+		t.Errorf("unexpected page metadata: %+v", page)
+	}
+	if got := page.Items[0].DaysRemaining; got != 30 {
+		t.Errorf("expected 30 days remaining for recent deletion, got %d", got)
+	}
+	if got := page.Items[1].DaysRemaining; got != 1 {
+		t.Errorf("expected 1 day remaining for old deletion, got %d", got)
+	}
+	if !page.Items[1].PurgeAt.Equal(now.Add(12 * time.Hour)) {
+		t.Errorf("unexpected purge date %v", page.Items[1].PurgeAt)
+	}
+}
+
+func TestRecycleBinService_ClampsPageSize(t *testing.T) {
+	repo := &recycleBinRepository{mockUserRepository: newMockUserRepository()}
+	svc := NewRecycleBinService(repo, time.Hour)
+
+	page, err := svc.List(context.Background(), 0, 10000)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if page.Page != 1 || page.PerPage != MaxPageSize || repo.offset != 0 {
+		t.Errorf("expected page 1 of %d items, got %+v (offset %d)", MaxPageSize, page, repo.offset)
+	}
+	if page.Items == nil {
+		t.Error("expected empty items slice, got nil")
+	}
+}
+
+func TestRecycleBinService_Purge(t *testing.T) {
+	// Given: More expired users than one batch holds
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	repo := &recycleBinRepository{mockUserRepository: newMockUserRepository()}
+	for i := 0; i < purgeBatchSize+1; i++ {
+		repo.purge = append(repo.purge, fmt.Sprintf("uuid-%d", i))
+	}
+	svc := &recycleBinService{repo: repo, purgeAfter: 30 * 24 * time.Hour, now: func() time.Time { return now }}
+
+	// When: Purging
+	purged, err := svc.Purge(context.Background())
+
+	// Then: Every batch is removed, using the retention cutoff
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(purged) != purgeBatchSize+1 {
+		t.Errorf("expected %d purged users, got %d", purgeBatchSize+1, len(purged))
+	}
+	if !repo.before.Equal(now.Add(-30 * 24 * time.Hour)) {
+		t.Errorf("unexpected cutoff %v", repo.before)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/invalidation"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/rules"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// RuleError reports a rule that cannot be saved because it does not compile
+type RuleError struct {
+	Reason string
+}
+
+func (e *RuleError) Error() string {
+	return "invalid rule: " + e.Reason
+}
+
+type RuleService interface {
+	List(ctx context.Context) ([]model.Rule, error)
+	Create(ctx context.Context, rule *model.Rule) error
+	Update(ctx context.Context, rule *model.Rule) error
+	Delete(ctx context.Context, id int64) error
+}
+
+type ruleService struct {
+	repo   repository.RuleRepository
+	caches invalidation.Invalidator
+}
+
+// NewRuleService manages stored rules; caches, if set, is told about every change so
+// rule engines subscribed to invalidation.Rules pick it up immediately
+func NewRuleService(repo repository.RuleRepository, caches invalidation.Invalidator) RuleService {
+	return &ruleService{repo: repo, caches: caches}
+}
+
+func (s *ruleService) List(ctx context.Context) ([]model.Rule, error) {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []model.Rule{}
+	}
+	return list, nil
+}
+
+func (s *ruleService) Create(ctx context.Context, rule *model.Rule) error {
+	if err := checkRule(rule); err != nil {
+		return err
+	}
+	if err := s.repo.Create(ctx, rule); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrRuleExists
+		}
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *ruleService) Update(ctx context.Context, rule *model.Rule) error {
+	if err := checkRule(rule); err != nil {
+		return err
+	}
+	if err := s.repo.Update(ctx, rule); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRuleNotFound
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrRuleExists
+		}
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *ruleService) Delete(ctx context.Context, id int64) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRuleNotFound
+		}
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+func (s *ruleService) invalidate() {
+	if s.caches != nil {
+		s.caches.Invalidate(invalidation.Rules)
+	}
+}
+
+func checkRule(rule *model.Rule) error {
+	rule.Name = strings.TrimSpace(rule.Name)
+	if !customFieldName.MatchString(rule.Name) {
+		return &RuleError{Reason: "name must be lower-case letters, digits and underscores, starting with a letter"}
+	}
+	if _, err := rules.Compile(*rule); err != nil {
+		return &RuleError{Reason: err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var savedViewName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type SavedViewService interface {
+	List(ctx context.Context) ([]model.SavedView, error)
+	Create(ctx context.Context, view *model.SavedView) error
+	Delete(ctx context.Context, name string) error
+	// Run executes a saved view and returns the matching users
+	Run(ctx context.Context, name string) ([]model.User, error)
+}
+
+type savedViewService struct {
+	repo   repository.SavedViewRepository
+	fields repository.CustomFieldRepository
+	users  UserService
+}
+
+func NewSavedViewService(repo repository.SavedViewRepository, fields repository.CustomFieldRepository, users UserService) SavedViewService {
+	return &savedViewService{repo: repo, fields: fields, users: users}
+}
+
+func (s *savedViewService) List(ctx context.Context) ([]model.SavedView, error) {
+	views, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if views == nil {
+		views = []model.SavedView{}
+	}
+	return views, nil
+}
+
+func (s *savedViewService) Create(ctx context.Context, view *model.SavedView) error {
+	view.Name = strings.TrimSpace(view.Name)
+	if !savedViewName.MatchString(view.Name) {
+		return ErrInvalidViewName
+	}
+	if _, err := view.Query.SortKeys(); err != nil {
+		return err
+	}
+
+	defs, err := s.fields.List(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		known[def.Name] = true
+	}
+	for name := range view.Query.CustomFields {
+		if !known[name] {
+			return &CustomFieldError{Field: name, Reason: "is not defined"}
+		}
+	}
+
+	if err := s.repo.Create(ctx, view); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrSavedViewExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *savedViewService) Delete(ctx context.Context, name string) error {
+	if err := s.repo.Delete(ctx, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSavedViewNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *savedViewService) Run(ctx context.Context, name string) ([]model.User, error) {
+	view, err := s.repo.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSavedViewNotFound
+		}
+		return nil, err
+	}
+
+	users, err := s.users.Find(ctx, view.Query)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []model.User{}
+	}
+	return users, nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"testing"
+)
+
+type mockSavedViewRepository struct {
+	views map[string]model.SavedView
+}
+
+func (m *mockSavedViewRepository) List(_ context.Context) ([]model.SavedView, error) {
+	var views []model.SavedView
+	for _, v := range m.views {
+		views = append(views, v)
+	}
+	return views, nil
+}
+
+func (m *mockSavedViewRepository) Get(_ context.Context, name string) (*model.SavedView, error) {
+	v, ok := m.views[name]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &v, nil
+}
+
+func (m *mockSavedViewRepository) Create(_ context.Context, view *model.SavedView) error {
+	m.views[view.Name] = *view
+	return nil
+}
+
+func (m *mockSavedViewRepository) Delete(_ context.Context, name string) error {
+	if _, ok := m.views[name]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.views, name)
+	return nil
+}
+
+func TestSavedViewService_Create(t *testing.T) {
+	fields := &mockCustomFieldRepository{fields: []model.CustomField{{Name: "department", Type: model.CustomFieldString}}}
+	svc := NewSavedViewService(&mockSavedViewRepository{views: map[string]model.SavedView{}}, fields, NewUserService(newMockUserRepository(), WithCustomFields(fields)))
+
+	tests := []struct {
+		name    string
+		view    model.SavedView
+		wantErr bool
+	}{
+		{"valid view", model.SavedView{Name: "sales-team", Query: model.UserQuery{CustomFields: map[string]string{"department": "sales"}, Sort: "-created_at,username"}}, false},
+		{"invalid name", model.SavedView{Name: "Sales Team"}, true},
+		{"unknown custom field", model.SavedView{Name: "tall", Query: model.UserQuery{CustomFields: map[string]string{"height": "200"}}}, true},
+		{"invalid sort", model.SavedView{Name: "by-password", Query: model.UserQuery{Sort: "password"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			err := svc.Create(context.Background(), &tt.view)
+
+			// Then
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSavedViewService_Run(t *testing.T) {
+	// Given: two users and a view selecting one of them
+	users := newMockUserRepository()
+	users.users["a"] = &model.User{UUID: "a", Username: "alice", CustomFields: map[string]any{"department": "sales"}}
+	users.users["b"] = &model.User{UUID: "b", Username: "bob", CustomFields: map[string]any{"department": "support"}}
+	fields := &mockCustomFieldRepository{fields: []model.CustomField{{Name: "department", Type: model.CustomFieldString}}}
+	views := &mockSavedViewRepository{views: map[string]model.SavedView{
+		"sales": {Name: "sales", Query: model.UserQuery{CustomFields: map[string]string{"department": "sales"}}},
+	}}
+	svc := NewSavedViewService(views, fields, NewUserService(users, WithCustomFields(fields)))
+
+	// When
+	result, err := svc.Run(context.Background(), "sales")
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result) != 1 || result[0].Username != "alice" {
+		t.Errorf("expected only alice, got %+v", result)
+	}
+
+	// When: the view does not exist
+	_, err = svc.Run(context.Background(), "missing")
+
+	// Then
+	if err == nil || err.Error() != "saved view not found" {
+		t.Errorf("expected saved view not found, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// deletionBatchSize bounds how many users one run of the deletion job removes
+const deletionBatchSize = 100
+
+type ScheduledDeletionService interface {
+	// Schedule deletes the user at effectiveAt; scheduling again replaces the date
+	Schedule(ctx context.Context, userUUID string, effectiveAt time.Time, requestedBy string) (*model.ScheduledDeletion, error)
+	Get(ctx context.Context, userUUID string) (*model.ScheduledDeletion, error)
+	Cancel(ctx context.Context, userUUID string) error
+	// RunDue deletes every user whose deletion date has passed and returns their UUIDs
+	RunDue(ctx context.Context) ([]string, error)
+}
+
+type scheduledDeletionService struct {
+	repo  repository.ScheduledDeletionRepository
+	users repository.UserRepository
+	now   func() time.Time
+}
+
+func NewScheduledDeletionService(repo repository.ScheduledDeletionRepository, users repository.UserRepository) ScheduledDeletionService {
+	return &scheduledDeletionService{repo: repo, users: users, now: time.Now}
+}
+
+func (s *scheduledDeletionService) Schedule(ctx context.Context, userUUID string, effectiveAt time.Time, requestedBy string) (*model.ScheduledDeletion, error) {
+	if !effectiveAt.After(s.now()) {
+		return nil, ErrEffectiveDateNotFuture
+	}
+	if _, err := s.users.GetByUUID(ctx, userUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	deletion := &model.ScheduledDeletion{
+		UserUUID:    userUUID,
+		EffectiveAt: effectiveAt.UTC(),
+		RequestedBy: requestedBy,
+	}
+	if err := s.repo.Schedule(ctx, deletion); err != nil {
+		return nil, err
+	}
+	return deletion, nil
+}
+
+func (s *scheduledDeletionService) Get(ctx context.Context, userUUID string) (*model.ScheduledDeletion, error) {
+	deletion, err := s.repo.Get(ctx, userUUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNoDeletionScheduled
+		}
+		return nil, err
+	}
+	return deletion, nil
+}
+
+func (s *scheduledDeletionService) Cancel(ctx context.Context, userUUID string) error {
+	if err := s.repo.Cancel(ctx, userUUID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoDeletionScheduled
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *scheduledDeletionService) RunDue(ctx context.Context) ([]string, error) {
+	var deleted []string
+	for {
+		batch, err := s.repo.DeleteDue(ctx, s.now(), deletionBatchSize)
+		deleted = append(deleted, batch...)
+		if err != nil || len(batch) < deletionBatchSize {
+			return deleted, err
+		}
+	}
+}
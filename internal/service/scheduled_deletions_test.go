@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+type mockScheduledDeletionRepository struct {
+	deletions map[string]*model.ScheduledDeletion
+	due       []string
+}
+
+func (m *mockScheduledDeletionRepository) Schedule(_ context.Context, deletion *model.ScheduledDeletion) error {
+	m.deletions[deletion.UserUUID] = deletion
+	return nil
+}
+
+func (m *mockScheduledDeletionRepository) Get(_ context.Context, userUUID string) (*model.ScheduledDeletion, error) {
+	d, ok := m.deletions[userUUID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return d, nil
+}
+
+func (m *mockScheduledDeletionRepository) Cancel(_ context.Context, userUUID string) error {
+	if _, ok := m.deletions[userUUID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.deletions, userUUID)
+	return nil
+}
+
+func (m *mockScheduledDeletionRepository) DeleteDue(_ context.Context, now time.Time, limit int) ([]string, error) {
+	n := min(limit, len(m.due))
+	batch := m.due[:n]
+	m.due = m.due[n:]
+	return batch, nil
+}
+
+func newScheduledDeletionTestService() (*scheduledDeletionService, *mockScheduledDeletionRepository, time.Time) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	users := newMockUserRepository()
+	users.users["user-uuid"] = &model.User{UUID: "user-uuid", Username: "leaver"}
+	repo := &mockScheduledDeletionRepository{deletions: make(map[string]*model.ScheduledDeletion)}
+	return &scheduledDeletionService{repo: repo, users: users, now: func() time.Time { return now }}, repo, now
+}
+
+func TestScheduledDeletionService_Schedule(t *testing.T) {
+	svc, repo, now := newScheduledDeletionTestService()
+
+	tests := []struct {
+		name        string
+		uuid        string
+		effectiveAt time.Time
+		wantErr     string
+	}{
+		{"future date", "user-uuid", now.Add(48 * time.Hour), ""},
+		{"past date", "user-uuid", now.Add(-time.Hour), "effective date must be in the future"},
+		{"unknown user", "missing", now.Add(time.Hour), "users not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			deletion, err := svc.Schedule(context.Background(), tt.uuid, tt.effectiveAt, "hr-offboarding")
+
+			// Then
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if deletion.RequestedBy != "hr-offboarding" || repo.deletions[tt.uuid] == nil {
+				t.Errorf("expected deletion to be stored, got %+v", deletion)
+			}
+		})
+	}
+}
+
+func TestScheduledDeletionService_CancelWithoutSchedule(t *testing.T) {
+	svc, _, _ := newScheduledDeletionTestService()
+
+	err := svc.Cancel(context.Background(), "user-uuid")
+
+	if err == nil || err.Error() != "no deletion scheduled" {
+		t.Errorf("expected 'no deletion scheduled', got %v", err)
+	}
+}
+
+func TestScheduledDeletionService_RunDueDrainsAllBatches(t *testing.T) {
+	// Given: More due deletions than fit in one batch
+	svc, repo, _ := newScheduledDeletionTestService()
+	for i := 0; i < deletionBatchSize+5; i++ {
+		repo.due = append(repo.due, "uuid")
+	}
+
+	// When
+	deleted, err := svc.RunDue(context.Background())
+
+	// Then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deleted) != deletionBatchSize+5 {
+		t.Errorf("expected %d deletions, got %d", deletionBatchSize+5, len(deleted))
+	}
+}
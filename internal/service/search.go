@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"cruder/internal/model"
+)
+
+// SearchTypeUser is the result type of users
+const SearchTypeUser = "user"
+
+// Searcher finds one type of entity for the global search; each searchable entity
+// contributes one, backed by its own search repository
+type Searcher interface {
+	// Type names the entity in results and in the types filter
+	Type() string
+	// Search returns up to limit matches of term, which is normalized and not empty
+	Search(ctx context.Context, term string, limit int) ([]model.SearchResult, error)
+}
+
+type SearchService interface {
+	// Search looks for term in every entity type, or in types if given, and merges
+	// the results by relevance. An unknown type is reported as ErrUnknownSearchType.
+	Search(ctx context.Context, term string, types []string, limit int) ([]model.SearchResult, error)
+}
+
+type searchService struct {
+	searchers []Searcher
+}
+
+// NewSearchService federates searchers; results of equal score keep their order
+func NewSearchService(searchers ...Searcher) SearchService {
+	return &searchService{searchers: searchers}
+}
+
+func (s *searchService) Search(ctx context.Context, term string, types []string, limit int) ([]model.SearchResult, error) {
+	term = normalizeSearch(term)
+	if term == "" {
+		return nil, ErrEmptySearchTerm
+	}
+	if limit < 1 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	for _, t := range types {
+		if !slices.ContainsFunc(s.searchers, func(searcher Searcher) bool { return searcher.Type() == t }) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSearchType, t)
+		}
+	}
+
+	results := []model.SearchResult{}
+	for _, searcher := range s.searchers {
+		if len(types) > 0 && !slices.Contains(types, searcher.Type()) {
+			continue
+		}
+		// Each type may hold the best matches, so each is asked for the full limit
+		found, err := searcher.Search(ctx, term, limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s: %w", searcher.Type(), err)
+		}
+		results = append(results, found...)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// searchScore rates how well term matches the best of names: an exact match
+// scores 1, a prefix 0.75, the start of a word 0.5 and anything else 0.25
+func searchScore(term string, names ...string) float64 {
+	best := 0.0
+	for _, name := range names {
+		name = normalizeSearch(name)
+		score := 0.0
+		switch {
+		case name == term:
+			score = 1
+		case strings.HasPrefix(name, term):
+			score = 0.75
+		case strings.Contains(" "+name, " "+term), strings.Contains(name, "@"+term), strings.Contains(name, "."+term):
+			score = 0.5
+		case strings.Contains(name, term):
+			score = 0.25
+		}
+		best = max(best, score)
+	}
+	return best
+}
+
+// userSearcher finds users through the user_search read table
+type userSearcher struct {
+	users UserSearchService
+}
+
+// NewUserSearcher makes users searchable in the global search
+func NewUserSearcher(users UserSearchService) Searcher {
+	return userSearcher{users: users}
+}
+
+func (userSearcher) Type() string { return SearchTypeUser }
+
+func (s userSearcher) Search(ctx context.Context, term string, limit int) ([]model.SearchResult, error) {
+	users, err := s.users.Search(ctx, term, limit)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]model.SearchResult, 0, len(users))
+	for _, u := range users {
+		results = append(results, model.SearchResult{
+			Type:  SearchTypeUser,
+			ID:    u.UUID,
+			Score: searchScore(term, u.Username, u.Email, u.FullName),
+			Item:  u,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"cruder/internal/model"
+)
+
+// stubSearcher answers every search with its results
+type stubSearcher struct {
+	typ     string
+	results []model.SearchResult
+	limit   int
+}
+
+func (s *stubSearcher) Type() string { return s.typ }
+
+func (s *stubSearcher) Search(ctx context.Context, term string, limit int) ([]model.SearchResult, error) {
+	s.limit = limit
+	return s.results, nil
+}
+
+func TestSearchService_MergesByScore(t *testing.T) {
+	// Given: Two entity types with interleaved scores
+	users := &stubSearcher{typ: "user", results: []model.SearchResult{
+		{Type: "user", ID: "u1", Score: 0.75}, {Type: "user", ID: "u2", Score: 0.25},
+	}}
+	groups := &stubSearcher{typ: "group", results: []model.SearchResult{
+		{Type: "group", ID: "g1", Score: 1}, {Type: "group", ID: "g2", Score: 0.25},
+	}}
+	svc := NewSearchService(users, groups)
+
+	// When
+	results, err := svc.Search(context.Background(), "smith", nil, 3)
+
+	// Then: The best matches of all types come first, ties in searcher order
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	if len(ids) != 3 || ids[0] != "g1" || ids[1] != "u1" || ids[2] != "u2" {
+		t.Errorf("expected [g1 u1 u2], got %v", ids)
+	}
+	if users.limit != 3 || groups.limit != 3 {
+		t.Errorf("expected every type to be asked for the full limit, got %d and %d", users.limit, groups.limit)
+	}
+}
+
+func TestSearchService_Types(t *testing.T) {
+	users := &stubSearcher{typ: "user", results: []model.SearchResult{{Type: "user", ID: "u1", Score: 1}}}
+	groups := &stubSearcher{typ: "group", results: []model.SearchResult{{Type: "group", ID: "g1", Score: 1}}}
+	svc := NewSearchService(users, groups)
+
+	results, err := svc.Search(context.Background(), "smith", []string{"group"}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "g1" {
+		t.Errorf("expected only the group, got %+v", results)
+	}
+
+	if _, err := svc.Search(context.Background(), "smith", []string{"tag"}, 10); !errors.Is(err, ErrUnknownSearchType) {
+		t.Errorf("expected ErrUnknownSearchType, got %v", err)
+	}
+	if _, err := svc.Search(context.Background(), "  ", nil, 10); !errors.Is(err, ErrEmptySearchTerm) {
+		t.Errorf("expected ErrEmptySearchTerm, got %v", err)
+	}
+}
+
+func TestSearchScore(t *testing.T) {
+	cases := []struct {
+		term  string
+		names []string
+		want  float64
+	}{
+		{"jdoe", []string{"JDoe", "jdoe@example.com"}, 1},
+		{"jdo", []string{"jdoe"}, 0.75},
+		{"doe", []string{"jdoe", "John Doe"}, 0.5},
+		{"example", []string{"jdoe", "jdoe@example.com"}, 0.5},
+		{"oh", []string{"jdoe", "John Doe"}, 0.25},
+		{"smith", []string{"jdoe"}, 0},
+	}
+	for _, tc := range cases {
+		if got := searchScore(tc.term, tc.names...); got != tc.want {
+			t.Errorf("searchScore(%q, %q) = %v, want %v", tc.term, tc.names, got, tc.want)
+		}
+	}
+}
@@ -1,13 +1,111 @@
 package service
 
-import "cruder/internal/repository"
+import (
+	"cruder/internal/config"
+	"cruder/internal/events"
+	"cruder/internal/invalidation"
+	"cruder/internal/repository"
+	"cruder/internal/rules"
+	"cruder/internal/storage"
+)
 
 type Service struct {
-	Users UserService
+	Users              UserService
+	Usage              UsageService
+	RecycleBin         RecycleBinService
+	ScheduledDeletions ScheduledDeletionService
+	Notes              NoteService
+	Consents           ConsentService
+	Documents          DocumentService
+	Exports            ExportService
+	Imports            ImportService
+	CustomFields       CustomFieldService
+	SavedViews         SavedViewService
+	Rules              RuleService
+	Approvals          ApprovalService
+	UserSearch         UserSearchService
+	Search             SearchService
+	Cluster            ClusterService
+	Billing            BillingService
+	// Auth is nil unless JWT login is configured
+	Auth AuthService
+	// PersonalTokens is nil unless auth.personal_tokens is enabled
+	PersonalTokens PersonalTokenService
+	// SigningKeys is nil unless auth.jwt.rotation is enabled
+	SigningKeys SigningKeyService
+	// APIKeys is nil unless auth.managed_keys is enabled
+	APIKeys APIKeyService
+	// Webhooks is nil unless webhooks are enabled
+	Webhooks WebhookService
 }
 
-func NewService(repos *repository.Repository) *Service {
+// NewService wires all services; caches carries invalidations of in-memory data
+// such as compiled rules, and userOpts add validation hooks, events and other
+// deployment-specific behaviour to the user service
+func NewService(repos *repository.Repository, cfg *config.Config, store storage.Backend, caches *invalidation.Bus, userOpts ...UserOption) *Service {
+	userOpts = append([]UserOption{WithCustomFields(repos.CustomFields), WithTransactions(repos.Tx), WithPolicy(UserPolicy{
+		ReservedUsernames:   cfg.Users.ReservedUsernames,
+		AllowedEmailDomains: cfg.Users.AllowedEmailDomains,
+		BlockedEmailDomains: cfg.Users.BlockedEmailDomains,
+		StripPlusTags:       cfg.Users.EmailNormalization.StripPlusTags,
+		StripGmailDots:      cfg.Users.EmailNormalization.StripGmailDots,
+	}), WithQuota(cfg.Users.MaxUsers)}, userOpts...)
+	// Scripted rules run after the deployment's own hooks
+	var engine *rules.Engine
+	if cfg.Rules.Enabled {
+		engine = rules.NewEngine(repos.Rules, cfg.Rules.Timeout, cfg.Rules.RefreshInterval)
+		userOpts = append(userOpts, WithValidationHooks(engine))
+		caches.Subscribe(invalidation.Rules, engine.Invalidate)
+	}
+	users := NewUserService(repos.Users, userOpts...)
+	// Approved changes are published like the user service's own
+	var publisher events.Publisher
+	if s, ok := users.(*userService); ok {
+		publisher = s.events
+	}
+	users = traceUsers(users)
+	// Read-your-writes tokens need the search table's position; skip the lookups otherwise
+	var positions repository.PositionRepository
+	if cfg.Consistency.ReadYourWrites {
+		positions = repos.Positions
+	}
+	var personalTokens PersonalTokenService
+	if cfg.Auth.PersonalTokens.Enabled {
+		personalTokens = NewPersonalTokenService(repos.PersonalTokens, repos.Users, cfg.Auth.PersonalTokens)
+	}
+	var apiKeys APIKeyService
+	if cfg.Auth.ManagedKeys.Enabled {
+		apiKeys = NewAPIKeyService(repos.APIKeys, cfg.Auth.ManagedKeys, caches)
+		caches.Subscribe(invalidation.APIKeys, apiKeys.Invalidate)
+	}
+	var webhooks WebhookService
+	if cfg.Webhooks.Enabled {
+		webhooks = NewWebhookService(repos.Webhooks)
+	}
+	userSearch := traceUserSearch(NewUserSearchService(repos.UserSearch, repos.Users, positions, cfg.Search.AutocompleteCacheTTL))
 	return &Service{
-		Users: NewUserService(repos.Users),
+		PersonalTokens:     personalTokens,
+		APIKeys:            apiKeys,
+		Webhooks:           webhooks,
+		Users:              users,
+		Usage:              NewUsageService(repos.Usage),
+		RecycleBin:         NewRecycleBinService(repos.Users, cfg.Users.PurgeAfter),
+		ScheduledDeletions: NewScheduledDeletionService(repos.ScheduledDeletions, repos.Users),
+		Notes:              NewNoteService(repos.Notes, repos.Users),
+		Consents:           NewConsentService(repos.Consents, repos.Users, cfg.Consents.Documents),
+		Documents: NewDocumentService(repos.Documents, repos.Users, store, DocumentLimits{
+			MaxSize:      int64(cfg.Documents.MaxSizeMB) << 20,
+			AllowedTypes: cfg.Documents.AllowedTypes,
+		}),
+		Exports:      NewExportService(repos.Exports, store, cfg.Exports.ChunkRows, cfg.Exports.Retention),
+		Imports:      NewImportService(users),
+		CustomFields: NewCustomFieldService(repos.CustomFields),
+		SavedViews:   NewSavedViewService(repos.SavedViews, repos.CustomFields, users),
+		Rules:        NewRuleService(repos.Rules, caches),
+		Approvals:    NewApprovalService(repos.Approvals, users, cfg.Approvals.Enabled, publisher),
+		UserSearch:   userSearch,
+		Search:       NewSearchService(NewUserSearcher(userSearch)),
+		Cluster:      NewClusterService(repos.Instances, cfg.Cluster.HeartbeatInterval),
+		Billing:      NewBillingService(repos.Billing, store),
 	}
 }
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/invalidation"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type SigningKeyService interface {
+	// List returns every stored key with its current status, oldest first
+	List(ctx context.Context) ([]model.SigningKey, error)
+	// Rotate creates the next key once the active one has signed for rotation.every,
+	// or a key that signs right away when none is active, and deletes keys no token
+	// can be verified with anymore. It returns the key created, if any.
+	Rotate(ctx context.Context) (*model.SigningKey, error)
+	// Revoke stops accepting tokens signed with a key at once; a revoked active key
+	// is replaced right away
+	Revoke(ctx context.Context, kid string) error
+	// JWKS returns the public keys tokens are verified with
+	JWKS(ctx context.Context) (auth.JWKS, error)
+}
+
+type signingKeyService struct {
+	repo      repository.SigningKeyRepository
+	tokens    *auth.Tokens
+	masterKey []byte
+	ttl       time.Duration
+	rotation  config.KeyRotationConfig
+	caches    invalidation.Invalidator
+	now       func() time.Time
+}
+
+// NewSigningKeyService manages the rotated keys of tokens; masterKey encrypts their
+// private halves, and caches, if set, tells every instance to reload them
+func NewSigningKeyService(repo repository.SigningKeyRepository, tokens *auth.Tokens, masterKey []byte, cfg config.JWTConfig, caches invalidation.Invalidator) SigningKeyService {
+	return &signingKeyService{repo: repo, tokens: tokens, masterKey: masterKey, ttl: cfg.TTL, rotation: cfg.Rotation, caches: caches, now: time.Now}
+}
+
+func (s *signingKeyService) List(ctx context.Context) ([]model.SigningKey, error) {
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auth.KeyStates(keys, s.now(), s.ttl)
+	return keys, nil
+}
+
+func (s *signingKeyService) Rotate(ctx context.Context) (*model.SigningKey, error) {
+	now := s.now()
+	keys, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	auth.KeyStates(keys, now, s.ttl)
+
+	var active, newest *model.SigningKey
+	changed := false
+	for i := range keys {
+		k := &keys[i]
+		// Revoked keys are kept until tokens signed before the revocation have expired,
+		// so listings still show them
+		if k.Status == auth.KeyExpired || k.Status == auth.KeyRevoked && now.Sub(*k.RevokedAt) > s.ttl {
+			if err := s.repo.Delete(ctx, k.ID); err != nil {
+				return nil, err
+			}
+			changed = true
+			continue
+		}
+		if k.Status == auth.KeyActive {
+			active = k
+		}
+		if k.RevokedAt == nil {
+			newest = k
+		}
+	}
+
+	var activatesAt time.Time
+	switch {
+	case active == nil:
+		activatesAt = now
+	case newest == active && !now.Before(active.ActivatesAt.Add(s.rotation.Every-s.rotation.PublishAhead)):
+		// The successor is published ahead, so it takes over about rotation.every
+		// after the active key did
+		activatesAt = now.Add(s.rotation.PublishAhead)
+	default:
+		if changed {
+			s.invalidate()
+		}
+		return nil, nil
+	}
+
+	key, err := auth.GenerateSigningKey(s.masterKey, activatesAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	key.Status = auth.KeyPending
+	if !activatesAt.After(now) {
+		key.Status = auth.KeyActive
+	}
+	return key, nil
+}
+
+func (s *signingKeyService) Revoke(ctx context.Context, kid string) error {
+	if err := s.repo.Revoke(ctx, kid); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSigningKeyNotFound
+		}
+		return err
+	}
+	s.invalidate()
+	_, err := s.Rotate(ctx)
+	return err
+}
+
+func (s *signingKeyService) JWKS(ctx context.Context) (auth.JWKS, error) {
+	return s.tokens.JWKS()
+}
+
+func (s *signingKeyService) invalidate() {
+	if s.caches != nil {
+		s.caches.Invalidate(invalidation.SigningKeys)
+	}
+}
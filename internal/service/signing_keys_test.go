@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/auth"
+	"cruder/internal/config"
+	"cruder/internal/invalidation"
+	"cruder/internal/model"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+type mockSigningKeyRepository struct {
+	keys []model.SigningKey
+}
+
+func (m *mockSigningKeyRepository) List(context.Context) ([]model.SigningKey, error) {
+	return append([]model.SigningKey(nil), m.keys...), nil
+}
+
+func (m *mockSigningKeyRepository) Create(_ context.Context, key *model.SigningKey) error {
+	key.CreatedAt = time.Now()
+	m.keys = append(m.keys, *key)
+	return nil
+}
+
+func (m *mockSigningKeyRepository) Revoke(_ context.Context, id string) error {
+	for i := range m.keys {
+		if m.keys[i].ID == id {
+			now := time.Now()
+			m.keys[i].RevokedAt = &now
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (m *mockSigningKeyRepository) Delete(_ context.Context, id string) error {
+	for i := range m.keys {
+		if m.keys[i].ID == id {
+			m.keys = append(m.keys[:i], m.keys[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func newTestSigningKeys(t *testing.T) (SigningKeyService, *auth.Tokens, *mockSigningKeyRepository) {
+	t.Helper()
+	masterKey := make([]byte, 32)
+	cfg := config.JWTConfig{Algorithm: "RS256", TTL: time.Hour, Rotation: config.KeyRotationConfig{
+		Enabled: true, Every: 30 * 24 * time.Hour, PublishAhead: time.Hour, CheckInterval: time.Minute,
+	}}
+	tokens, err := auth.NewTokens(cfg, "")
+	if err != nil {
+		t.Fatalf("NewTokens: %v", err)
+	}
+	repo := &mockSigningKeyRepository{}
+	ring := auth.NewKeyRing(repo, masterKey, cfg.TTL, cfg.Rotation.CheckInterval)
+	tokens.WithKeyRing(ring)
+	caches := invalidation.NewBus()
+	caches.Subscribe(invalidation.SigningKeys, ring.Invalidate)
+	return NewSigningKeyService(repo, tokens, masterKey, cfg, caches), tokens, repo
+}
+
+func TestSigningKeyService_Rotate(t *testing.T) {
+	// Given: A fresh deployment
+	svc, tokens, repo := newTestSigningKeys(t)
+	ctx := context.Background()
+
+	// When: Keys are rotated for the first time
+	first, err := svc.Rotate(ctx)
+
+	// Then: A key that signs right away is created and tokens carry its ID
+	if err != nil || first == nil || first.Status != auth.KeyActive {
+		t.Fatalf("expected an active key, got %+v, %v", first, err)
+	}
+	token, _, err := tokens.Issue("ci", nil, "")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if again, _ := svc.Rotate(ctx); again != nil {
+		t.Errorf("expected no rotation before the key is due, got %s", again.ID)
+	}
+
+	// Given: The key has signed for a month
+	repo.keys[0].ActivatesAt = time.Now().Add(-30 * 24 * time.Hour)
+
+	// When
+	next, err := svc.Rotate(ctx)
+
+	// Then: Its successor is published ahead and the old key keeps signing meanwhile
+	if err != nil || next == nil || next.Status != auth.KeyPending {
+		t.Fatalf("expected a pending key, got %+v, %v", next, err)
+	}
+	jwks, err := svc.JWKS(ctx)
+	if err != nil || len(jwks.Keys) != 2 {
+		t.Fatalf("expected both keys published, got %+v, %v", jwks, err)
+	}
+	if _, err := tokens.Verify(token); err != nil {
+		t.Errorf("expected the token to verify during rotation: %v", err)
+	}
+}
+
+func TestSigningKeyService_Revoke(t *testing.T) {
+	// Given: A token signed with the active key
+	svc, tokens, _ := newTestSigningKeys(t)
+	ctx := context.Background()
+	first, err := svc.Rotate(ctx)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	token, _, err := tokens.Issue("ci", nil, "")
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	// When: The key is revoked
+	if err := svc.Revoke(ctx, first.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+
+	// Then: Its tokens are rejected and a replacement signs new ones
+	if _, err := tokens.Verify(token); err == nil {
+		t.Error("expected the token of the revoked key to be rejected")
+	}
+	fresh, _, err := tokens.Issue("ci", nil, "")
+	if err != nil {
+		t.Fatalf("Issue after revocation: %v", err)
+	}
+	if _, err := tokens.Verify(fresh); err != nil {
+		t.Errorf("expected the replacement key to verify: %v", err)
+	}
+	if err := svc.Revoke(ctx, "unknown"); err == nil || err.Error() != "signing key not found" {
+		t.Errorf("expected signing key not found, got %v", err)
+	}
+}
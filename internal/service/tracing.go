@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+
+	"cruder/internal/events"
+	"cruder/internal/model"
+	"cruder/internal/tracing"
+)
+
+// tracedUserService records a span for each call to the user service, between the
+// request's span and the spans of its database queries
+type tracedUserService struct {
+	next UserService
+}
+
+// traceUsers wraps s so its calls show up in request traces
+func traceUsers(s UserService) UserService {
+	return &tracedUserService{next: s}
+}
+
+func (s *tracedUserService) GetAll(ctx context.Context) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetAll")
+	users, err := s.next.GetAll(ctx)
+	tracing.End(span, err)
+	return users, err
+}
+
+func (s *tracedUserService) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByUsername")
+	user, err := s.next.GetByUsername(ctx, username)
+	tracing.End(span, err)
+	return user, err
+}
+
+func (s *tracedUserService) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByID")
+	user, err := s.next.GetByID(ctx, id)
+	tracing.End(span, err)
+	return user, err
+}
+
+func (s *tracedUserService) GetByUUID(ctx context.Context, uuid string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetByUUID")
+	user, err := s.next.GetByUUID(ctx, uuid)
+	tracing.End(span, err)
+	return user, err
+}
+
+func (s *tracedUserService) Create(ctx context.Context, user *model.User) error {
+	ctx, span := tracing.Start(ctx, "UserService.Create")
+	err := s.next.Create(ctx, user)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedUserService) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateMany")
+	errs, err := s.next.CreateMany(ctx, users)
+	tracing.End(span, err)
+	return errs, err
+}
+
+func (s *tracedUserService) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	ctx, span := tracing.Start(ctx, "UserService.Update")
+	err := s.next.Update(ctx, uuid, patch)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedUserService) Delete(ctx context.Context, uuid string) error {
+	ctx, span := tracing.Start(ctx, "UserService.Delete")
+	err := s.next.Delete(ctx, uuid)
+	tracing.End(span, err)
+	return err
+}
+
+func (s *tracedUserService) DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteMany")
+	errs, err := s.next.DeleteMany(ctx, uuids, allOrNothing)
+	tracing.End(span, err)
+	return errs, err
+}
+
+func (s *tracedUserService) Restore(ctx context.Context, uuid string) (*model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Restore")
+	user, err := s.next.Restore(ctx, uuid)
+	tracing.End(span, err)
+	return user, err
+}
+
+func (s *tracedUserService) Find(ctx context.Context, query model.UserQuery) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Find")
+	users, err := s.next.Find(ctx, query)
+	tracing.End(span, err)
+	return users, err
+}
+
+func (s *tracedUserService) Aggregate(ctx context.Context, groupBy string) ([]model.AggregateBucket, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Aggregate")
+	buckets, err := s.next.Aggregate(ctx, groupBy)
+	tracing.End(span, err)
+	return buckets, err
+}
+
+func (s *tracedUserService) Sample(ctx context.Context, n int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Sample")
+	users, err := s.next.Sample(ctx, n)
+	tracing.End(span, err)
+	return users, err
+}
+
+func (s *tracedUserService) Quota(ctx context.Context) (model.UserQuota, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Quota")
+	quota, err := s.next.Quota(ctx)
+	tracing.End(span, err)
+	return quota, err
+}
+
+func (s *tracedUserService) Validate(ctx context.Context, user *model.User) (*model.ValidationResult, error) {
+	ctx, span := tracing.Start(ctx, "UserService.Validate")
+	result, err := s.next.Validate(ctx, user)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (s *tracedUserService) CheckUpdate(ctx context.Context, uuid string, patch model.UserPatch) (model.UserPatch, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CheckUpdate")
+	patch, err := s.next.CheckUpdate(ctx, uuid, patch)
+	tracing.End(span, err)
+	return patch, err
+}
+
+// tracedUserSearchService records a span for each search
+type tracedUserSearchService struct {
+	next UserSearchService
+}
+
+// traceUserSearch wraps s so its searches show up in request traces
+func traceUserSearch(s UserSearchService) UserSearchService {
+	return &tracedUserSearchService{next: s}
+}
+
+func (s *tracedUserSearchService) Search(ctx context.Context, term string, limit int) ([]model.User, error) {
+	ctx, span := tracing.Start(ctx, "UserSearchService.Search")
+	users, err := s.next.Search(ctx, term, limit)
+	tracing.End(span, err)
+	return users, err
+}
+
+func (s *tracedUserSearchService) Autocomplete(ctx context.Context, prefix string, limit int) ([]model.UserSuggestion, error) {
+	ctx, span := tracing.Start(ctx, "UserSearchService.Autocomplete")
+	suggestions, err := s.next.Autocomplete(ctx, prefix, limit)
+	tracing.End(span, err)
+	return suggestions, err
+}
+
+func (s *tracedUserSearchService) Consume(e events.Event) {
+	s.next.Consume(e)
+}
+
+func (s *tracedUserSearchService) Rebuild(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "UserSearchService.Rebuild")
+	err := s.next.Rebuild(ctx)
+	tracing.End(span, err)
+	return err
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"time"
+)
+
+// maxUsageRange bounds a single usage report
+const maxUsageRange = 366 * 24 * time.Hour
+
+type UsageService interface {
+	// Report returns daily usage between from and to (inclusive); an empty apiKey matches all keys
+	Report(ctx context.Context, from, to time.Time, apiKey string) ([]model.UsageRecord, error)
+}
+
+type usageService struct {
+	repo repository.UsageRepository
+}
+
+func NewUsageService(repo repository.UsageRepository) UsageService {
+	return &usageService{repo: repo}
+}
+
+func (s *usageService) Report(ctx context.Context, from, to time.Time, apiKey string) ([]model.UsageRecord, error) {
+	if to.Before(from) {
+		return nil, ErrInvalidDateRange
+	}
+	if to.Sub(from) > maxUsageRange {
+		return nil, ErrDateRangeTooLarge
+	}
+	records, err := s.repo.List(ctx, from, to, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	if records == nil {
+		records = []model.UsageRecord{}
+	}
+	return records, nil
+}
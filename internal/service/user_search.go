@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/consistency"
+	"cruder/internal/events"
+	"cruder/internal/logging"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/residency"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Search result limits
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// Autocomplete limits; pickers ask on every keystroke, so they are kept tight
+const (
+	DefaultAutocompleteLimit = 10
+	MaxAutocompleteLimit     = 20
+	// MaxAutocompletePrefix is the longest prefix accepted, in characters: the
+	// length of the longest full name
+	MaxAutocompletePrefix = 100
+	// maxCachedPrefixes bounds the autocomplete cache; it is emptied when full
+	maxCachedPrefixes = 1024
+)
+
+// consumeTimeout bounds the read and write made for one user event
+const consumeTimeout = 5 * time.Second
+
+type UserSearchService interface {
+	// Search returns users whose username, email or full name contains term, ignoring
+	// case, accents and repeated spaces. Results come from the user_search read table
+	// and may lag the users table briefly, unless ctx carries a consistency token the
+	// table has not caught up with; then the users table is searched instead.
+	Search(ctx context.Context, term string, limit int) ([]model.User, error)
+	// Autocomplete returns up to limit users whose username or full name starts with
+	// prefix, normalized like Search. Answers come from the read table, ignoring
+	// consistency tokens, and are cached for a short while.
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]model.UserSuggestion, error)
+	// Consume updates the read table for one user event; subscribe it to the event bus
+	Consume(e events.Event)
+	// Rebuild replaces the read table with the current users, repairing events missed
+	// while the process was down or dropped by the bus
+	Rebuild(ctx context.Context) error
+}
+
+type userSearchService struct {
+	repo      repository.UserSearchRepository
+	users     repository.UserRepository
+	positions repository.PositionRepository
+	// cacheTTL is how long autocomplete answers are reused; 0 disables the cache
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	suggestions map[string]cachedSuggestions
+}
+
+type cachedSuggestions struct {
+	suggestions []model.UserSuggestion
+	expires     time.Time
+}
+
+// NewUserSearchService creates the service; positions, if set, records how far the
+// read table has caught up so consistency tokens can be honoured. Autocomplete
+// answers are cached for cacheTTL, or not at all when it is 0.
+func NewUserSearchService(repo repository.UserSearchRepository, users repository.UserRepository, positions repository.PositionRepository, cacheTTL time.Duration) UserSearchService {
+	return &userSearchService{
+		repo:        repo,
+		users:       users,
+		positions:   positions,
+		cacheTTL:    cacheTTL,
+		now:         time.Now,
+		suggestions: make(map[string]cachedSuggestions),
+	}
+}
+
+func (s *userSearchService) Search(ctx context.Context, term string, limit int) ([]model.User, error) {
+	term = normalizeSearch(term)
+	if term == "" {
+		return nil, ErrEmptySearchTerm
+	}
+	if limit < 1 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	fresh, err := s.covers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var users []model.User
+	if fresh {
+		users, err = s.repo.Search(ctx, term, limit)
+	} else {
+		users, err = s.searchUsers(ctx, term, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []model.User{}
+	}
+	return users, nil
+}
+
+func (s *userSearchService) Autocomplete(ctx context.Context, prefix string, limit int) ([]model.UserSuggestion, error) {
+	prefix = normalizeSearch(prefix)
+	if prefix == "" {
+		return nil, ErrEmptySearchTerm
+	}
+	if len([]rune(prefix)) > MaxAutocompletePrefix {
+		return nil, ErrPrefixTooLong
+	}
+	if limit < 1 {
+		limit = DefaultAutocompleteLimit
+	}
+	if limit > MaxAutocompleteLimit {
+		limit = MaxAutocompleteLimit
+	}
+
+	// Regions have tables of their own, so they are cached apart
+	key := residency.Region(ctx) + "\x00" + strconv.Itoa(limit) + "\x00" + prefix
+	if suggestions, ok := s.cached(key); ok {
+		return suggestions, nil
+	}
+	suggestions, err := s.repo.Autocomplete(ctx, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	if suggestions == nil {
+		suggestions = []model.UserSuggestion{}
+	}
+	s.cache(key, suggestions)
+	return suggestions, nil
+}
+
+func (s *userSearchService) cached(key string) ([]model.UserSuggestion, bool) {
+	if s.cacheTTL <= 0 {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.suggestions[key]
+	if !ok || !s.now().Before(c.expires) {
+		return nil, false
+	}
+	return c.suggestions, true
+}
+
+func (s *userSearchService) cache(key string, suggestions []model.UserSuggestion) {
+	if s.cacheTTL <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.suggestions) >= maxCachedPrefixes {
+		s.suggestions = make(map[string]cachedSuggestions)
+	}
+	s.suggestions[key] = cachedSuggestions{suggestions: suggestions, expires: s.now().Add(s.cacheTTL)}
+}
+
+// forgetSuggestions empties the autocomplete cache, so changes made through this
+// instance show at once; those made through others show within cacheTTL
+func (s *userSearchService) forgetSuggestions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.suggestions)
+}
+
+// covers reports whether the read table includes the caller's consistency token
+func (s *userSearchService) covers(ctx context.Context) (bool, error) {
+	token := consistency.TokenFromContext(ctx)
+	if token == "" || s.positions == nil {
+		return true, nil
+	}
+	return s.repo.Covers(ctx, token)
+}
+
+// searchUsers matches like the read table does, on the users table itself
+func (s *userSearchService) searchUsers(ctx context.Context, term string, limit int) ([]model.User, error) {
+	users, err := s.users.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matches []model.UserSearchEntry
+	for i := range users {
+		e := searchEntry(&users[i])
+		if strings.Contains(e.UsernameNorm+" "+e.EmailNorm+" "+e.FullNameNorm, term) {
+			matches = append(matches, e)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i].UsernameNorm, matches[j].UsernameNorm
+		if (a == term) != (b == term) {
+			return a == term
+		}
+		if strings.HasPrefix(a, term) != strings.HasPrefix(b, term) {
+			return strings.HasPrefix(a, term)
+		}
+		return a < b
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]model.User, 0, len(matches))
+	for _, e := range matches {
+		result = append(result, e.User)
+	}
+	return result, nil
+}
+
+func (s *userSearchService) Consume(e events.Event) {
+	ctx := residency.WithRegion(logging.WithRequestID(context.Background(), e.RequestID), e.Region)
+	ctx, cancel := context.WithTimeout(ctx, consumeTimeout)
+	defer cancel()
+	if err := s.refresh(ctx, e.UserUUID); err != nil {
+		slog.ErrorContext(ctx, "failed to update search entry", "user_uuid", e.UserUUID, "event", e.Type, "error", err)
+	}
+	s.forgetSuggestions()
+}
+
+// refresh copies the current row of a user instead of the event payload, since the
+// bus delivers events concurrently and a late one must not overwrite newer data.
+// The position is taken before the read, so the copy includes every change up to it.
+func (s *userSearchService) refresh(ctx context.Context, uuid string) error {
+	position, err := s.position(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := s.users.GetByUUID(ctx, uuid)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		err = s.repo.Delete(ctx, uuid)
+	case err == nil:
+		err = s.repo.Upsert(ctx, searchEntry(user))
+	}
+	if err != nil || position == "" {
+		return err
+	}
+	return s.repo.Applied(ctx, position)
+}
+
+func (s *userSearchService) Rebuild(ctx context.Context) error {
+	position, err := s.position(ctx)
+	if err != nil {
+		return err
+	}
+	users, err := s.users.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+	entries := make([]model.UserSearchEntry, 0, len(users))
+	for i := range users {
+		entries = append(entries, searchEntry(&users[i]))
+	}
+	if err := s.repo.Replace(ctx, entries, position); err != nil {
+		return err
+	}
+	s.forgetSuggestions()
+	return nil
+}
+
+// position returns the primary's write position, or "" when positions are not tracked
+func (s *userSearchService) position(ctx context.Context) (string, error) {
+	if s.positions == nil {
+		return "", nil
+	}
+	return s.positions.CurrentPosition(ctx)
+}
+
+func searchEntry(u *model.User) model.UserSearchEntry {
+	return model.UserSearchEntry{
+		User:         model.User{ID: u.ID, UUID: u.UUID, Username: u.Username, Email: u.Email, FullName: u.FullName},
+		UsernameNorm: normalizeSearch(u.Username),
+		EmailNorm:    normalizeSearch(u.Email),
+		FullNameNorm: normalizeSearch(u.FullName),
+	}
+}
+
+// normalizeSearch lower-cases s, strips accents and collapses whitespace, so
+// "  José  Müller" and "jose muller" compare equal
+func normalizeSearch(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.Join(strings.Fields(strings.ToLower(folded)), " ")
+}
@@ -0,0 +1,298 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/consistency"
+	"cruder/internal/events"
+	"cruder/internal/model"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockUserSearchRepository struct {
+	entries  map[string]model.UserSearchEntry
+	term     string
+	limit    int
+	position string
+	covered  bool
+	// lookups counts Autocomplete calls
+	lookups int
+}
+
+func newMockUserSearchRepository() *mockUserSearchRepository {
+	return &mockUserSearchRepository{entries: make(map[string]model.UserSearchEntry)}
+}
+
+func (m *mockUserSearchRepository) Search(ctx context.Context, term string, limit int) ([]model.User, error) {
+	m.term, m.limit = term, limit
+	return nil, nil
+}
+
+func (m *mockUserSearchRepository) Autocomplete(ctx context.Context, prefix string, limit int) ([]model.UserSuggestion, error) {
+	m.term, m.limit = prefix, limit
+	m.lookups++
+	var suggestions []model.UserSuggestion
+	for _, e := range m.entries {
+		if strings.HasPrefix(e.UsernameNorm, prefix) || strings.HasPrefix(e.FullNameNorm, prefix) {
+			suggestions = append(suggestions, model.UserSuggestion{UUID: e.UUID, Username: e.Username, FullName: e.FullName})
+		}
+	}
+	return suggestions, nil
+}
+
+func (m *mockUserSearchRepository) Upsert(ctx context.Context, entry model.UserSearchEntry) error {
+	m.entries[entry.UUID] = entry
+	return nil
+}
+
+func (m *mockUserSearchRepository) Delete(ctx context.Context, uuid string) error {
+	delete(m.entries, uuid)
+	return nil
+}
+
+func (m *mockUserSearchRepository) Replace(ctx context.Context, entries []model.UserSearchEntry, position string) error {
+	m.entries = make(map[string]model.UserSearchEntry)
+	for _, e := range entries {
+		m.entries[e.UUID] = e
+	}
+	m.position = position
+	return nil
+}
+
+func (m *mockUserSearchRepository) Applied(ctx context.Context, position string) error {
+	m.position = position
+	return nil
+}
+
+func (m *mockUserSearchRepository) Covers(ctx context.Context, position string) (bool, error) {
+	return m.covered, nil
+}
+
+type mockPositionRepository struct {
+	position string
+}
+
+func (m *mockPositionRepository) CurrentPosition(ctx context.Context) (string, error) {
+	return m.position, nil
+}
+
+func TestUserSearchService_Consume(t *testing.T) {
+	// Given: A user whose event payload is older than the stored row
+	users := newMockUserRepository()
+	users.users["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "JDoe", Email: "JDoe@Example.com", FullName: "  José   Müller "}
+	search := newMockUserSearchRepository()
+	svc := NewUserSearchService(search, users, nil, 0)
+
+	// When: Consuming the update event
+	svc.Consume(events.Event{Type: events.UserUpdated, UserUUID: "uuid-1", User: &model.User{Username: "stale"}})
+
+	// Then: The stored row is copied with normalized values
+	entry, ok := search.entries["uuid-1"]
+	if !ok {
+		t.Fatal("expected search entry to be written")
+	}
+	if entry.Username != "JDoe" || entry.UsernameNorm != "jdoe" {
+		t.Errorf("unexpected username %q / %q", entry.Username, entry.UsernameNorm)
+	}
+	if entry.EmailNorm != "jdoe@example.com" {
+		t.Errorf("unexpected normalized email %q", entry.EmailNorm)
+	}
+	if entry.FullNameNorm != "jose muller" {
+		t.Errorf("unexpected normalized full name %q", entry.FullNameNorm)
+	}
+
+	// When: The user is gone by the time the delete event arrives
+	delete(users.users, "uuid-1")
+	svc.Consume(events.Event{Type: events.UserDeleted, UserUUID: "uuid-1"})
+
+	// Then: The entry is removed
+	if _, ok := search.entries["uuid-1"]; ok {
+		t.Error("expected search entry to be deleted")
+	}
+}
+
+func TestUserSearchService_Rebuild(t *testing.T) {
+	users := newMockUserRepository()
+	users.users["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "alice", Email: "alice@example.com"}
+	search := newMockUserSearchRepository()
+	search.entries["uuid-gone"] = model.UserSearchEntry{User: model.User{UUID: "uuid-gone"}}
+	svc := NewUserSearchService(search, users, nil, 0)
+
+	if err := svc.Rebuild(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(search.entries) != 1 || search.entries["uuid-1"].UsernameNorm != "alice" {
+		t.Errorf("unexpected entries after rebuild: %+v", search.entries)
+	}
+}
+
+func TestUserSearchService_Search(t *testing.T) {
+	search := newMockUserSearchRepository()
+	svc := NewUserSearchService(search, newMockUserRepository(), nil, 0)
+
+	tests := []struct {
+		name      string
+		term      string
+		limit     int
+		wantTerm  string
+		wantLimit int
+		wantErr   bool
+	}{
+		{"normalizes term", "  Ánna  SMITH ", 10, "anna smith", 10, false},
+		{"default limit", "anna", 0, "anna", DefaultSearchLimit, false},
+		{"clamps limit", "anna", 1000, "anna", MaxSearchLimit, false},
+		{"blank term", "   ", 10, "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search.term, search.limit = "", 0
+
+			users, err := svc.Search(context.Background(), tt.term, tt.limit)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if users == nil {
+				t.Error("expected empty slice, got nil")
+			}
+			if search.term != tt.wantTerm || search.limit != tt.wantLimit {
+				t.Errorf("expected term %q limit %d, got %q %d", tt.wantTerm, tt.wantLimit, search.term, search.limit)
+			}
+		})
+	}
+}
+
+func TestUserSearchService_ConsistencyToken(t *testing.T) {
+	// Given: A user the read table does not have yet
+	users := newMockUserRepository()
+	users.users["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "anna", Email: "anna@example.com"}
+	search := newMockUserSearchRepository()
+	svc := NewUserSearchService(search, users, &mockPositionRepository{position: "0/20"}, 0)
+	ctx := consistency.WithToken(context.Background(), "0/10")
+
+	// When: Searching with a token the read table has not caught up with
+	found, err := svc.Search(ctx, "ANN", 10)
+
+	// Then: The users table is searched instead
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(found) != 1 || found[0].UUID != "uuid-1" || search.term != "" {
+		t.Errorf("expected the user from the users table, got %+v (read table queried for %q)", found, search.term)
+	}
+
+	// When: The read table has applied the change
+	svc.Consume(events.Event{Type: events.UserCreated, UserUUID: "uuid-1"})
+	search.covered = true
+	if _, err := svc.Search(ctx, "ann", 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: Its position is recorded and the read table serves the search
+	if search.position != "0/20" {
+		t.Errorf("expected position 0/20 to be recorded, got %q", search.position)
+	}
+	if search.term != "ann" {
+		t.Errorf("expected the read table to be searched, got term %q", search.term)
+	}
+}
+
+func TestUserSearchService_Autocomplete(t *testing.T) {
+	search := newMockUserSearchRepository()
+	svc := NewUserSearchService(search, newMockUserRepository(), nil, 0)
+
+	tests := []struct {
+		name       string
+		prefix     string
+		limit      int
+		wantPrefix string
+		wantLimit  int
+		wantErr    error
+	}{
+		{"normalizes prefix", "  Jö ", 5, "jo", 5, nil},
+		{"default limit", "jo", 0, "jo", DefaultAutocompleteLimit, nil},
+		{"clamps limit", "jo", 1000, "jo", MaxAutocompleteLimit, nil},
+		{"blank prefix", "   ", 5, "", 0, ErrEmptySearchTerm},
+		{"long prefix", strings.Repeat("a", MaxAutocompletePrefix+1), 5, "", 0, ErrPrefixTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search.term, search.limit = "", 0
+
+			suggestions, err := svc.Autocomplete(context.Background(), tt.prefix, tt.limit)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if suggestions == nil {
+				t.Error("expected empty slice, got nil")
+			}
+			if search.term != tt.wantPrefix || search.limit != tt.wantLimit {
+				t.Errorf("expected prefix %q limit %d, got %q %d", tt.wantPrefix, tt.wantLimit, search.term, search.limit)
+			}
+		})
+	}
+}
+
+func TestUserSearchService_AutocompleteCache(t *testing.T) {
+	// Given: A cached answer for "jo"
+	users := newMockUserRepository()
+	search := newMockUserSearchRepository()
+	svc := NewUserSearchService(search, users, nil, time.Minute).(*userSearchService)
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+	if _, err := svc.Autocomplete(context.Background(), "jo", 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// When: Asking again, in another case
+	if _, err := svc.Autocomplete(context.Background(), "JO", 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: The read table is not queried again
+	if search.lookups != 1 {
+		t.Fatalf("expected 1 lookup, got %d", search.lookups)
+	}
+
+	// When: A user is created through this instance
+	users.users["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "john", Email: "john@example.com"}
+	svc.Consume(events.Event{Type: events.UserCreated, UserUUID: "uuid-1"})
+	found, err := svc.Autocomplete(context.Background(), "jo", 10)
+
+	// Then: The cache is forgotten and the new user suggested
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if search.lookups != 2 || len(found) != 1 || found[0].Username != "john" {
+		t.Errorf("expected a fresh lookup finding john, got %d lookups and %+v", search.lookups, found)
+	}
+
+	// When: The TTL has passed
+	now = now.Add(time.Minute)
+	if _, err := svc.Autocomplete(context.Background(), "jo", 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then: The answer is looked up again
+	if search.lookups != 3 {
+		t.Errorf("expected 3 lookups, got %d", search.lookups)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// UserPolicy holds deployment-specific registration rules, applied on create and
+// update and reported by UserService.Validate
+type UserPolicy struct {
+	ReservedUsernames   []string
+	AllowedEmailDomains []string
+	BlockedEmailDomains []string
+	// StripPlusTags and StripGmailDots widen email normalization, see normalizeEmail
+	StripPlusTags  bool
+	StripGmailDots bool
+}
+
+// WithPolicy enables reserved usernames, email domain rules and email normalization
+func WithPolicy(policy UserPolicy) UserOption {
+	return func(s *userService) {
+		s.policy = policy
+	}
+}
+
+// ValidationHook is a deployment-specific business rule checked before a user is
+// created or updated, after the built-in checks. existing is nil on create; on
+// update, user already holds the merged custom fields. Rule violations are
+// returned as field errors; a non-nil error aborts the request with HTTP 500.
+type ValidationHook interface {
+	ValidateUser(user, existing *model.User) ([]model.FieldError, error)
+}
+
+// ValidationHookFunc adapts a function to ValidationHook
+type ValidationHookFunc func(user, existing *model.User) ([]model.FieldError, error)
+
+// ValidateUser implements ValidationHook
+func (f ValidationHookFunc) ValidateUser(user, existing *model.User) ([]model.FieldError, error) {
+	return f(user, existing)
+}
+
+// WithValidationHooks runs hooks, in order, on every create, update and Validate call
+func WithValidationHooks(hooks ...ValidationHook) UserOption {
+	return func(s *userService) {
+		s.hooks = append(s.hooks, hooks...)
+	}
+}
+
+// runHooks collects the violations reported by every hook
+func (s *userService) runHooks(user, existing *model.User) ([]model.FieldError, error) {
+	var violations []model.FieldError
+	for _, hook := range s.hooks {
+		errs, err := hook.ValidateUser(user, existing)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, errs...)
+	}
+	return violations, nil
+}
+
+// ValidationError reports the policy violations that prevented a create or update
+type ValidationError struct {
+	Errors []model.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate runs every create check without saving the user and collects all
+// failures instead of stopping at the first one
+func (s *userService) Validate(ctx context.Context, user *model.User) (*model.ValidationResult, error) {
+	errs := checkUserFormat(user)
+	errs = append(errs, s.policy.checkUsername(user.Username)...)
+	errs = append(errs, s.policy.checkEmail(user.Email)...)
+
+	if user.Username != "" {
+		existing, err := found(s.repo.GetByUsername(ctx, user.Username))
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			errs = append(errs, model.FieldError{Field: "username", Code: model.ValidationTaken, Message: "username already exists"})
+		} else if !replacesDeleted(ctx) {
+			if _, err := s.repo.FindDeleted(ctx, user.Username); err == nil {
+				errs = append(errs, model.FieldError{Field: "username", Code: model.ValidationDeleted, Message: "username belongs to a deleted user, who can be restored"})
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+		}
+	}
+	if user.Email != "" {
+		existing, err := found(s.repo.GetByEmail(ctx, s.policy.normalizeEmail(user.Email)))
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			errs = append(errs, model.FieldError{Field: "email", Code: model.ValidationTaken, Message: "email already exists"})
+		}
+	}
+
+	if err := s.checkCustomFields(ctx, user.CustomFields); err != nil {
+		var fieldErr *CustomFieldError
+		if !errors.As(err, &fieldErr) {
+			return nil, err
+		}
+		errs = append(errs, model.FieldError{Field: "custom_fields." + fieldErr.Field, Code: model.ValidationInvalid, Message: fieldErr.Error()})
+	}
+
+	hookErrs, err := s.runHooks(user, nil)
+	if err != nil {
+		return nil, err
+	}
+	errs = append(errs, hookErrs...)
+
+	if errs == nil {
+		errs = []model.FieldError{}
+	}
+	return &model.ValidationResult{Valid: len(errs) == 0, Errors: errs}, nil
+}
+
+// checkUserFormat repeats the request binding rules for callers that skip binding
+func checkUserFormat(user *model.User) []model.FieldError {
+	var errs []model.FieldError
+	if strings.TrimSpace(user.Username) == "" {
+		errs = append(errs, model.FieldError{Field: "username", Code: model.ValidationRequired, Message: "username is required"})
+	}
+	if user.Email == "" {
+		errs = append(errs, model.FieldError{Field: "email", Code: model.ValidationRequired, Message: "email is required"})
+	} else if addr, err := mail.ParseAddress(user.Email); err != nil || addr.Address != user.Email {
+		errs = append(errs, model.FieldError{Field: "email", Code: model.ValidationInvalidFormat, Message: "email is not a valid address"})
+	}
+	return errs
+}
+
+func (p UserPolicy) checkUsername(username string) []model.FieldError {
+	for _, reserved := range p.ReservedUsernames {
+		if strings.EqualFold(username, reserved) {
+			return []model.FieldError{{Field: "username", Code: model.ValidationReserved, Message: fmt.Sprintf("username %q is reserved", username)}}
+		}
+	}
+	return nil
+}
+
+func (p UserPolicy) checkEmail(email string) []model.FieldError {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	for _, blocked := range p.BlockedEmailDomains {
+		if domainMatches(domain, blocked) {
+			return []model.FieldError{{Field: "email", Code: model.ValidationDomainNotAllowed, Message: fmt.Sprintf("email domain %s is not allowed", domain)}}
+		}
+	}
+	if len(p.AllowedEmailDomains) == 0 {
+		return nil
+	}
+	for _, allowed := range p.AllowedEmailDomains {
+		if domainMatches(domain, allowed) {
+			return nil
+		}
+	}
+	return []model.FieldError{{Field: "email", Code: model.ValidationDomainNotAllowed, Message: fmt.Sprintf("email domain %s is not allowed", domain)}}
+}
+
+// gmailDomains are the domains whose mailboxes ignore dots in the local part
+var gmailDomains = map[string]bool{"gmail.com": true, "googlemail.com": true}
+
+// normalizeEmail returns the form of email that uniqueness and lookups compare:
+// trimmed and lower-cased, without a "+tag" in the local part with StripPlusTags,
+// and for Gmail addresses without dots in the local part and at gmail.com with
+// StripGmailDots
+func (p UserPolicy) normalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if p.StripPlusTags {
+		if plus := strings.Index(local, "+"); plus > 0 {
+			local = local[:plus]
+		}
+	}
+	if p.StripGmailDots && gmailDomains[domain] {
+		local, domain = strings.ReplaceAll(local, ".", ""), "gmail.com"
+	}
+	return local + "@" + domain
+}
+
+// domainMatches reports whether domain is pattern or one of its subdomains
+func domainMatches(domain, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(pattern, "@"))
+	return domain == pattern || strings.HasSuffix(domain, "."+pattern)
+}
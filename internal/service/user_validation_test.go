@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newPolicyUserRepository() *mockUserRepository {
+	repo := newMockUserRepository()
+	repo.users["taken"] = &model.User{UUID: "taken", Username: "existinguser", Email: "existing@example.com"}
+	return repo
+}
+
+var testPolicy = UserPolicy{
+	ReservedUsernames:   []string{"admin", "root"},
+	AllowedEmailDomains: []string{"example.com"},
+	BlockedEmailDomains: []string{"spam.example.com"},
+}
+
+func TestValidate_CollectsEveryFailure(t *testing.T) {
+	service := NewUserService(newPolicyUserRepository(), WithPolicy(testPolicy))
+
+	tests := []struct {
+		name  string
+		user  model.User
+		codes map[string]string
+	}{
+		{"valid user", model.User{Username: "newuser", Email: "new@dev.example.com"}, map[string]string{}},
+		{"missing fields", model.User{}, map[string]string{"username": model.ValidationRequired, "email": model.ValidationRequired}},
+		{"bad email format", model.User{Username: "newuser", Email: "not-an-email"}, map[string]string{"email": model.ValidationInvalidFormat}},
+		{"reserved username", model.User{Username: "Admin", Email: "new@example.com"}, map[string]string{"username": model.ValidationReserved}},
+		{"domain outside allowlist", model.User{Username: "newuser", Email: "new@other.org"}, map[string]string{"email": model.ValidationDomainNotAllowed}},
+		{"blocked subdomain", model.User{Username: "newuser", Email: "new@spam.example.com"}, map[string]string{"email": model.ValidationDomainNotAllowed}},
+		{"taken username and email", model.User{Username: "existinguser", Email: "existing@example.com"}, map[string]string{"username": model.ValidationTaken, "email": model.ValidationTaken}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			result, err := service.Validate(context.Background(), &tt.user)
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Valid != (len(tt.codes) == 0) {
+				t.Errorf("expected valid=%v, got %+v", len(tt.codes) == 0, result)
+			}
+			if len(result.Errors) != len(tt.codes) {
+				t.Fatalf("expected %d errors, got %+v", len(tt.codes), result.Errors)
+			}
+			for _, fe := range result.Errors {
+				if tt.codes[fe.Field] != fe.Code {
+					t.Errorf("expected %s code %q, got %q", fe.Field, tt.codes[fe.Field], fe.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_DoesNotPersist(t *testing.T) {
+	// Given
+	repo := newPolicyUserRepository()
+	service := NewUserService(repo, WithPolicy(testPolicy))
+
+	// When
+	if _, err := service.Validate(context.Background(), &model.User{Username: "newuser", Email: "new@example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Then
+	if len(repo.users) != 1 {
+		t.Errorf("expected repository to be unchanged, got %d users", len(repo.users))
+	}
+}
+
+func TestCreateUser_AppliesPolicy(t *testing.T) {
+	service := NewUserService(newPolicyUserRepository(), WithPolicy(testPolicy))
+
+	// When: creating a reserved username
+	err := service.Create(context.Background(), &model.User{Username: "root", Email: "root@example.com"})
+
+	// Then
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Errors[0].Code != model.ValidationReserved {
+		t.Errorf("expected reserved username error, got %v", err)
+	}
+
+	// When: reusing an email address
+	err = service.Create(context.Background(), &model.User{Username: "other", Email: "existing@example.com"})
+
+	// Then
+	if err == nil || err.Error() != "email already exists" {
+		t.Errorf("expected email already exists, got %v", err)
+	}
+}
+
+func TestValidationHooks(t *testing.T) {
+	// Given: a hook that requires corporate addresses and records its calls
+	var calls []*model.User
+	corporate := ValidationHookFunc(func(user, existing *model.User) ([]model.FieldError, error) {
+		calls = append(calls, existing)
+		if user.Email != "" && !strings.HasSuffix(user.Email, "@corp.example.com") {
+			return []model.FieldError{{Field: "email", Code: "corporate_email", Message: "use your corporate email address"}}, nil
+		}
+		return nil, nil
+	})
+	repo := newPolicyUserRepository()
+	service := NewUserService(repo, WithValidationHooks(corporate))
+
+	// When: creating users
+	errCreate := service.Create(context.Background(), &model.User{Username: "outsider", Email: "me@gmail.com"})
+	errOK := service.Create(context.Background(), &model.User{Username: "insider", Email: "me@corp.example.com"})
+
+	// Then: the hook rejects the first and allows the second
+	var validationErr *ValidationError
+	if !errors.As(errCreate, &validationErr) || validationErr.Errors[0].Code != "corporate_email" {
+		t.Errorf("expected corporate_email violation, got %v", errCreate)
+	}
+	if errOK != nil {
+		t.Errorf("expected create to succeed, got %v", errOK)
+	}
+
+	// When: updating, the hook sees the stored user
+	calls = nil
+	err := service.Update(context.Background(), "taken", model.UserPatch{Email: strPtr("existing@corp.example.com")})
+
+	// Then
+	if err != nil {
+		t.Fatalf("expected update to succeed, got %v", err)
+	}
+	if len(calls) != 1 || calls[0] == nil || calls[0].UUID != "taken" {
+		t.Errorf("expected hook to receive the existing user, got %+v", calls)
+	}
+
+	// When: validating, hook violations are reported with the rest
+	result, err := service.Validate(context.Background(), &model.User{Username: "someone", Email: "someone@gmail.com"})
+
+	// Then
+	if err != nil || result.Valid || result.Errors[0].Code != "corporate_email" {
+		t.Errorf("expected corporate_email in validation result, got %+v, %v", result, err)
+	}
+}
+
+func TestValidationHooks_ErrorAborts(t *testing.T) {
+	failing := ValidationHookFunc(func(user, existing *model.User) ([]model.FieldError, error) {
+		return nil, errors.New("rules service unavailable")
+	})
+	repo := newMockUserRepository()
+	service := NewUserService(repo, WithValidationHooks(failing))
+
+	err := service.Create(context.Background(), &model.User{Username: "newuser", Email: "new@example.com"})
+
+	if err == nil || err.Error() != "rules service unavailable" {
+		t.Errorf("expected hook error, got %v", err)
+	}
+	if len(repo.users) != 0 {
+		t.Errorf("expected no user to be created, got %d", len(repo.users))
+	}
+}
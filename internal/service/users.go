@@ -1,105 +1,653 @@
 package service
 
 import (
+	"context"
+	"cruder/internal/events"
+	"cruder/internal/logging"
 	"cruder/internal/model"
 	"cruder/internal/repository"
+	"cruder/internal/residency"
 	"database/sql"
 	"errors"
+	"math"
+	"strings"
 )
 
+// Sample size limits for UserService.Sample
+const (
+	DefaultSampleSize = 100
+	MaxSampleSize     = 1000
+)
+
+// MaxBulkSize caps the users created by one UserService.CreateMany call, or
+// deleted by one UserService.DeleteMany call
+const MaxBulkSize = 500
+
 type UserService interface {
-	GetAll() ([]model.User, error)
-	GetByUsername(username string) (*model.User, error)
-	GetByID(id int64) (*model.User, error)
-	GetByUUID(uuid string) (*model.User, error) // Task3
-	Create(user *model.User) error              // Task3
-	Update(uuid string, user *model.User) error // Task3
-	Delete(uuid string) error                   // Task3
+	GetAll(ctx context.Context) ([]model.User, error)
+	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	GetByID(ctx context.Context, id int64) (*model.User, error)
+	GetByUUID(ctx context.Context, uuid string) (*model.User, error) // Task3
+	Create(ctx context.Context, user *model.User) error              // Task3
+	// CreateMany creates each user that passes the checks of Create, best effort: it
+	// returns one error per user, nil for those created, and fails as a whole only
+	// when the batch could not be written at all
+	CreateMany(ctx context.Context, users []*model.User) ([]error, error)
+	// Update applies patch to the user; fields absent from it keep their values
+	Update(ctx context.Context, uuid string, patch model.UserPatch) error
+	Delete(ctx context.Context, uuid string) error // Task3
+	// DeleteMany soft-deletes users in one transaction and returns one error per
+	// UUID, ErrUserNotFound for those that do not exist. With allOrNothing one such
+	// UUID keeps every user, and the others get ErrBulkRolledBack.
+	DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error)
+	// Restore brings back a soft-deleted user, unless its username or email has
+	// been taken since
+	Restore(ctx context.Context, uuid string) (*model.User, error)
+	// Find returns users matching every filter of the query, in its sort order. A
+	// sort on fields outside model.UserSortFields fails with a *model.SortError.
+	Find(ctx context.Context, query model.UserQuery) ([]model.User, error)
+	// Aggregate counts users per created_month or per value of a custom field (cf.<name>)
+	Aggregate(ctx context.Context, groupBy string) ([]model.AggregateBucket, error)
+	// Sample returns n users chosen uniformly at random; n is at most MaxSampleSize
+	Sample(ctx context.Context, n int) ([]model.User, error)
+	// Validate runs the create checks without saving and reports every failure
+	Validate(ctx context.Context, user *model.User) (*model.ValidationResult, error)
+	// CheckUpdate runs the checks of Update without saving and returns patch as
+	// Update would write it, with the normalized email
+	CheckUpdate(ctx context.Context, uuid string, patch model.UserPatch) (model.UserPatch, error)
+	// Quota returns the live users of the deployment and its limit
+	Quota(ctx context.Context) (model.UserQuota, error)
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo   repository.UserRepository
+	fields repository.CustomFieldRepository
+	policy UserPolicy
+	hooks  []ValidationHook
+	events events.Publisher
+	tx     repository.TxManager
+	// maxUsers caps the live users; 0 sets no limit
+	maxUsers int
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+// UserOption configures optional behaviour of the user service
+type UserOption func(*userService)
+
+// WithCustomFields validates users' custom field values against the admin-defined
+// definitions. Without it, users carrying custom fields are rejected.
+func WithCustomFields(repo repository.CustomFieldRepository) UserOption {
+	return func(s *userService) {
+		s.fields = repo
+	}
 }
 
-func (s *userService) GetAll() ([]model.User, error) {
-	return s.repo.GetAll()
+// WithEvents publishes user.created, user.updated, user.deleted and user.restored
+// after each change
+func WithEvents(publisher events.Publisher) UserOption {
+	return func(s *userService) {
+		s.events = publisher
+	}
 }
 
-func (s *userService) GetByUsername(username string) (*model.User, error) {
-	user, err := s.repo.GetByUsername(username)
+// WithTransactions runs the checks and the write of Create and Update in one
+// transaction, so two concurrent requests cannot both pass the uniqueness checks.
+// Without it they run on their own and the unique indexes catch what slips through.
+func WithTransactions(tx repository.TxManager) UserOption {
+	return func(s *userService) {
+		s.tx = tx
+	}
+}
+
+// WithQuota rejects creating or restoring users once the deployment has maxUsers
+// live users, with ErrUserQuotaExceeded; 0 sets no limit. The count is read in the
+// unit of work of the write; without WithTransactions, or for users of other
+// residency regions, it is read apart from it, so concurrent requests may
+// overshoot the limit slightly.
+func WithQuota(maxUsers int) UserOption {
+	return func(s *userService) {
+		s.maxUsers = maxUsers
+	}
+}
+
+func NewUserService(repo repository.UserRepository, opts ...UserOption) UserService {
+	s := &userService{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *userService) GetAll(ctx context.Context) ([]model.User, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *userService) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := s.repo.GetByUsername(ctx, username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("users not found") // Task2
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound // Task2
 		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *userService) GetByID(id int64) (*model.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s *userService) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("users not found") // Task2
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound // Task2
 		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *userService) GetByUUID(uuid string) (*model.User, error) {
-	user, err := s.repo.GetByUUID(uuid)
+func (s *userService) GetByUUID(ctx context.Context, uuid string) (*model.User, error) {
+	user, err := s.repo.GetByUUID(ctx, uuid)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("users not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s *userService) Create(user *model.User) error {
+func (s *userService) Create(ctx context.Context, user *model.User) error {
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.checkCreate(ctx, user); err != nil {
+			return err
+		}
+		if err := s.checkQuota(ctx, 1); err != nil {
+			return err
+		}
+		return uniqueViolation(s.repo.Create(ctx, user))
+	})
+	if err != nil {
+		return err
+	}
+	s.publish(ctx, events.UserCreated, user.UUID, user)
+	return nil
+}
+
+// inTx runs fn as one unit of work when the service has a TxManager
+func (s *userService) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.InTx(ctx, fn)
+}
+
+func (s *userService) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	if len(users) == 0 || len(users) > MaxBulkSize {
+		return nil, ErrInvalidBulkSize
+	}
+
+	// The checks, the quota and the inserts are one unit of work, as in Create, so
+	// concurrent batches cannot together exceed the quota. A retried unit of work
+	// starts over.
+	var errs []error
+	var created []int
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		errs = make([]error, len(users))
+		created = created[:0]
+		valid := make([]*model.User, 0, len(users))
+		positions := make([]int, 0, len(users))
+		for i, user := range users {
+			if errs[i] = s.checkCreate(ctx, user); errs[i] == nil {
+				valid = append(valid, user)
+				positions = append(positions, i)
+			}
+		}
+		if len(valid) == 0 {
+			return nil
+		}
+		// Users beyond the quota are rejected, in batch order
+		remaining, err := s.remaining(ctx)
+		if err != nil {
+			return err
+		}
+		if len(valid) > remaining {
+			for _, i := range positions[remaining:] {
+				errs[i] = ErrUserQuotaExceeded
+			}
+			valid, positions = valid[:remaining], positions[:remaining]
+			if len(valid) == 0 {
+				return nil
+			}
+		}
+
+		// Users passing the checks can still clash with each other or with a concurrent
+		// create; the repository reports those rows without failing the others
+		results, err := s.repo.CreateMany(ctx, valid)
+		if err != nil {
+			return err
+		}
+		for j, err := range results {
+			i := positions[j]
+			if err != nil {
+				errs[i] = uniqueViolation(err)
+				continue
+			}
+			created = append(created, i)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range created {
+		s.publish(ctx, events.UserCreated, users[i].UUID, users[i])
+	}
+	return errs, nil
+}
+
+// checkCreate runs the checks of Create: unique username and email, registration
+// policy, custom fields and validation hooks
+func (s *userService) checkCreate(ctx context.Context, user *model.User) error {
 	// validate uniq username
-	existingUser, _ := s.repo.GetByUsername(user.Username)
+	existingUser, err := found(s.repo.GetByUsername(ctx, user.Username))
+	if err != nil {
+		return err
+	}
+	if existingUser != nil {
+		return ErrUsernameTaken
+	}
+	user.EmailNorm = s.policy.normalizeEmail(user.Email)
+	existingUser, err = found(s.repo.GetByEmail(ctx, user.EmailNorm))
+	if err != nil {
+		return err
+	}
 	if existingUser != nil {
-		return errors.New("username already exists")
+		return ErrEmailTaken
+	}
+	// A returning user usually wants the deleted account back, so the caller is
+	// told about it unless it asked for a new account
+	if !replacesDeleted(ctx) {
+		deleted, err := s.repo.FindDeleted(ctx, user.Username)
+		if err == nil {
+			return &DeletedUserError{User: *deleted}
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	violations := append(s.policy.checkUsername(user.Username), s.policy.checkEmail(user.Email)...)
+	if len(violations) > 0 {
+		return &ValidationError{Errors: violations}
 	}
 
-	return s.repo.Create(user)
+	if err := s.checkCustomFields(ctx, user.CustomFields); err != nil {
+		return err
+	}
+	return s.checkHooks(user, nil)
 }
 
-func (s *userService) Update(uuid string, user *model.User) error {
-	// check that user exists
-	existingUser, err := s.repo.GetByUUID(uuid)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("users not found")
+func (s *userService) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	var updated *model.User
+	patch = s.normalizePatch(patch)
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		if updated, err = s.checkUpdate(ctx, uuid, patch); err != nil || patch.IsEmpty() {
+			return err
 		}
+		return uniqueViolation(s.repo.Update(ctx, uuid, patch))
+	})
+	if err != nil || patch.IsEmpty() {
 		return err
 	}
+	s.publish(ctx, events.UserUpdated, uuid, updated)
+	return nil
+}
 
+func (s *userService) CheckUpdate(ctx context.Context, uuid string, patch model.UserPatch) (model.UserPatch, error) {
+	patch = s.normalizePatch(patch)
+	_, err := s.checkUpdate(ctx, uuid, patch)
+	return patch, err
+}
+
+// normalizePatch sets the normalized email of a patch that changes the email
+func (s *userService) normalizePatch(patch model.UserPatch) model.UserPatch {
+	if patch.Email != nil {
+		norm := s.policy.normalizeEmail(*patch.Email)
+		patch.EmailNorm = &norm
+	}
+	return patch
+}
+
+// checkUpdate validates patch against the stored user and returns the user as the
+// update would leave it
+func (s *userService) checkUpdate(ctx context.Context, uuid string, patch model.UserPatch) (*model.User, error) {
 	// check that user exists
+	existingUser, err := s.repo.GetByUUID(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	// In a unit of work, a concurrent update either committed first, changing the
+	// version, or makes this transaction retry and then fail here
+	if patch.Version != nil && *patch.Version != existingUser.Version {
+		return nil, ErrVersionMismatch
+	}
+	user := patch.Apply(*existingUser)
+	if errs := checkUserFormat(&user); len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+
+	// check that username and email stay unique
 	if user.Username != existingUser.Username {
-		userByName, _ := s.repo.GetByUsername(user.Username)
+		userByName, err := found(s.repo.GetByUsername(ctx, user.Username))
+		if err != nil {
+			return nil, err
+		}
 		if userByName != nil && userByName.UUID != uuid {
-			return errors.New("username already exists")
+			return nil, ErrUsernameTaken
+		}
+	}
+	if user.Email != existingUser.Email {
+		userByEmail, err := found(s.repo.GetByEmail(ctx, s.policy.normalizeEmail(user.Email)))
+		if err != nil {
+			return nil, err
+		}
+		if userByEmail != nil && userByEmail.UUID != uuid {
+			return nil, ErrEmailTaken
+		}
+	}
+
+	// Policy rules apply to changed values only, so existing accounts stay editable
+	var violations []model.FieldError
+	if user.Username != existingUser.Username {
+		violations = append(violations, s.policy.checkUsername(user.Username)...)
+	}
+	if user.Email != existingUser.Email {
+		violations = append(violations, s.policy.checkEmail(user.Email)...)
+	}
+	if len(violations) > 0 {
+		return nil, &ValidationError{Errors: violations}
+	}
+
+	if err := s.checkCustomFields(ctx, user.CustomFields); err != nil {
+		return nil, err
+	}
+	if err := s.checkHooks(&user, existingUser); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *userService) Delete(ctx context.Context, uuid string) error {
+	err := s.repo.Delete(ctx, uuid)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	s.publish(ctx, events.UserDeleted, uuid, nil)
+	return nil
+}
+
+func (s *userService) DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error) {
+	if len(uuids) == 0 || len(uuids) > MaxBulkSize {
+		return nil, ErrInvalidBulkSize
+	}
+
+	errs := make([]error, len(uuids))
+	wellFormed := make([]string, 0, len(uuids))
+	positions := make([]int, 0, len(uuids))
+	for i, uuid := range uuids {
+		if !model.IsUUID(uuid) {
+			errs[i] = ErrUserNotFound
+			continue
 		}
+		wellFormed = append(wellFormed, uuid)
+		positions = append(positions, i)
+	}
+	if len(wellFormed) < len(uuids) && allOrNothing {
+		for _, i := range positions {
+			errs[i] = ErrBulkRolledBack
+		}
+		return errs, nil
+	}
+	if len(wellFormed) == 0 {
+		return errs, nil
+	}
+
+	deleted, err := s.repo.DeleteMany(ctx, wellFormed, allOrNothing)
+	if err != nil {
+		return nil, err
+	}
+	for j, err := range deleted {
+		i := positions[j]
+		switch {
+		case err == nil:
+			s.publish(ctx, events.UserDeleted, uuids[i], nil)
+		case errors.Is(err, sql.ErrNoRows):
+			errs[i] = ErrUserNotFound
+		case errors.Is(err, repository.ErrBatchRolledBack):
+			errs[i] = ErrBulkRolledBack
+		default:
+			errs[i] = err
+		}
+	}
+	return errs, nil
+}
+
+func (s *userService) Restore(ctx context.Context, uuid string) (*model.User, error) {
+	err := s.inTx(ctx, func(ctx context.Context) error {
+		if err := s.checkQuota(ctx, 1); err != nil {
+			return err
+		}
+		if err := s.repo.Restore(ctx, uuid); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return uniqueViolation(err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.GetByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(ctx, events.UserRestored, uuid, user)
+	return user, nil
+}
+
+func (s *userService) Quota(ctx context.Context) (model.UserQuota, error) {
+	count, err := s.repo.Count(ctx)
+	if err != nil {
+		return model.UserQuota{}, err
+	}
+	quota := model.UserQuota{Count: count, Limit: s.maxUsers}
+	if s.maxUsers > 0 {
+		remaining := max(s.maxUsers-count, 0)
+		quota.Remaining = &remaining
+	}
+	return quota, nil
+}
+
+// checkQuota fails with ErrUserQuotaExceeded when fewer than n users may be added
+func (s *userService) checkQuota(ctx context.Context, n int) error {
+	remaining, err := s.remaining(ctx)
+	if err != nil {
+		return err
+	}
+	if remaining < n {
+		return ErrUserQuotaExceeded
+	}
+	return nil
+}
+
+// remaining returns how many users may still be added, MaxInt without a quota
+func (s *userService) remaining(ctx context.Context) (int, error) {
+	if s.maxUsers == 0 {
+		return math.MaxInt, nil
+	}
+	quota, err := s.Quota(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return *quota.Remaining, nil
+}
+
+// DeletedUserError reports a create whose username belongs to a soft-deleted user,
+// who can be restored instead; see WithReplaceDeleted
+type DeletedUserError struct {
+	User model.DeletedUser
+}
+
+func (e *DeletedUserError) Error() string {
+	return "username belongs to a deleted user, who can be restored"
+}
+
+type replaceDeletedKey struct{}
+
+// WithReplaceDeleted makes creates under ctx give new accounts the usernames of
+// deleted users rather than fail with a *DeletedUserError
+func WithReplaceDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replaceDeletedKey{}, true)
+}
+
+func replacesDeleted(ctx context.Context) bool {
+	replace, _ := ctx.Value(replaceDeletedKey{}).(bool)
+	return replace
+}
+
+// found returns the user of a lookup, or nil when there is none. Other errors are
+// returned: in a unit of work, a serialization failure must reach the retry, and
+// anything run after it would fail on the aborted transaction instead.
+func found(user *model.User, err error) (*model.User, error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
 	}
+	return user, err
+}
 
-	return s.repo.Update(uuid, user)
+// uniqueViolation reports a clash with a live user's username or email the way
+// Create's checks do; other errors are returned as they are
+func uniqueViolation(err error) error {
+	var dup *repository.DuplicateError
+	if !errors.As(err, &dup) {
+		return err
+	}
+	switch dup.Column {
+	case "email":
+		return ErrEmailTaken
+	case "username":
+		return ErrUsernameTaken
+	}
+	return err
 }
 
-func (s *userService) Delete(uuid string) error {
-	err := s.repo.Delete(uuid)
+func (s *userService) Find(ctx context.Context, query model.UserQuery) ([]model.User, error) {
+	if _, err := query.SortKeys(); err != nil {
+		return nil, err
+	}
+	if query.CreatedFrom != nil && query.CreatedTo != nil && !query.CreatedFrom.Before(*query.CreatedTo) {
+		return nil, ErrInvalidDateRange
+	}
+	if query.UpdatedFrom != nil && query.UpdatedTo != nil && !query.UpdatedFrom.Before(*query.UpdatedTo) {
+		return nil, ErrInvalidDateRange
+	}
+	defs, err := s.customFieldDefinitions(ctx)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return errors.New("users not found")
+		return nil, err
+	}
+	known := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		known[def.Name] = true
+	}
+	for name := range query.CustomFields {
+		if !known[name] {
+			return nil, &CustomFieldError{Field: name, Reason: "is not defined"}
+		}
+	}
+	return s.repo.Find(ctx, query)
+}
+
+func (s *userService) Aggregate(ctx context.Context, groupBy string) ([]model.AggregateBucket, error) {
+	if name, ok := strings.CutPrefix(groupBy, "cf."); ok {
+		defs, err := s.customFieldDefinitions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defined := false
+		for _, def := range defs {
+			if def.Name == name {
+				defined = true
+			}
 		}
+		if !defined {
+			return nil, &CustomFieldError{Field: name, Reason: "is not defined"}
+		}
+	} else if groupBy != "created_month" {
+		return nil, ErrInvalidGroupBy
+	}
+
+	buckets, err := s.repo.Aggregate(ctx, groupBy)
+	if err != nil {
+		return nil, err
+	}
+	if buckets == nil {
+		buckets = []model.AggregateBucket{}
+	}
+	return buckets, nil
+}
+
+func (s *userService) Sample(ctx context.Context, n int) ([]model.User, error) {
+	if n < 1 || n > MaxSampleSize {
+		return nil, ErrInvalidSampleSize
+	}
+	users, err := s.repo.Sample(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	if users == nil {
+		users = []model.User{}
+	}
+	return users, nil
+}
+
+func (s *userService) checkCustomFields(ctx context.Context, values map[string]any) error {
+	defs, err := s.customFieldDefinitions(ctx)
+	if err != nil {
+		return err
+	}
+	if len(defs) == 0 && len(values) == 0 {
+		return nil
+	}
+	return validateCustomFields(defs, values)
+}
+
+func (s *userService) publish(ctx context.Context, eventType, uuid string, user *model.User) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.Event{Type: eventType, UserUUID: uuid, User: user, RequestID: logging.RequestID(ctx), Region: residency.Region(ctx)})
+}
+
+func (s *userService) checkHooks(user, existing *model.User) error {
+	violations, err := s.runHooks(user, existing)
+	if err != nil {
 		return err
 	}
+	if len(violations) > 0 {
+		return &ValidationError{Errors: violations}
+	}
 	return nil
 }
+
+func (s *userService) customFieldDefinitions(ctx context.Context) ([]model.CustomField, error) {
+	if s.fields == nil {
+		return nil, nil
+	}
+	return s.fields.List(ctx)
+}
@@ -1,23 +1,32 @@
 package service
 
 import (
+	"context"
+	"cruder/internal/events"
 	"cruder/internal/model"
+	"cruder/internal/repository"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Mock repository for testing
 type mockUserRepository struct {
-	users map[string]*model.User
+	users   map[string]*model.User
+	deleted map[string]*model.User
 }
 
 func newMockUserRepository() *mockUserRepository {
 	return &mockUserRepository{
-		users: make(map[string]*model.User),
+		users:   make(map[string]*model.User),
+		deleted: make(map[string]*model.User),
 	}
 }
 
-func (m *mockUserRepository) GetAll() ([]model.User, error) {
+func (m *mockUserRepository) GetAll(ctx context.Context) ([]model.User, error) {
 	var users []model.User
 	for _, user := range m.users {
 		users = append(users, *user)
@@ -25,7 +34,7 @@ func (m *mockUserRepository) GetAll() ([]model.User, error) {
 	return users, nil
 }
 
-func (m *mockUserRepository) GetByUsername(username string) (*model.User, error) {
+func (m *mockUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
 	for _, user := range m.users {
 		if user.Username == username {
 			return user, nil
@@ -34,7 +43,22 @@ func (m *mockUserRepository) GetByUsername(username string) (*model.User, error)
 	return nil, sql.ErrNoRows
 }
 
-func (m *mockUserRepository) GetByID(id int64) (*model.User, error) {
+// GetByEmail compares normalized emails; users stored without one fall back to the
+// lower-cased email, like the repository
+func (m *mockUserRepository) GetByEmail(ctx context.Context, emailNorm string) (*model.User, error) {
+	for _, user := range m.users {
+		norm := user.EmailNorm
+		if norm == "" {
+			norm = strings.ToLower(user.Email)
+		}
+		if norm == emailNorm {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockUserRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
 	for _, user := range m.users {
 		if user.ID == id {
 			return user, nil
@@ -43,7 +67,7 @@ func (m *mockUserRepository) GetByID(id int64) (*model.User, error) {
 	return nil, sql.ErrNoRows
 }
 
-func (m *mockUserRepository) GetByUUID(uuid string) (*model.User, error) {
+func (m *mockUserRepository) GetByUUID(ctx context.Context, uuid string) (*model.User, error) {
 	user, exists := m.users[uuid]
 	if !exists {
 		return nil, sql.ErrNoRows
@@ -51,7 +75,7 @@ func (m *mockUserRepository) GetByUUID(uuid string) (*model.User, error) {
 	return user, nil
 }
 
-func (m *mockUserRepository) Create(user *model.User) error {
+func (m *mockUserRepository) Create(ctx context.Context, user *model.User) error {
 	// Generate UUID for test
 	if user.UUID == "" {
 		user.UUID = "test-uuid-" + user.Username
@@ -63,23 +87,149 @@ func (m *mockUserRepository) Create(user *model.User) error {
 	return nil
 }
 
-func (m *mockUserRepository) Update(uuid string, user *model.User) error {
-	if _, exists := m.users[uuid]; !exists {
+// CreateMany fails rows clashing with a live user like the unique indexes would
+func (m *mockUserRepository) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	errs := make([]error, len(users))
+	for i, user := range users {
+		for _, live := range m.users {
+			if live.Username == user.Username {
+				errs[i] = &repository.DuplicateError{Column: "username"}
+			} else if live.Email == user.Email {
+				errs[i] = &repository.DuplicateError{Column: "email"}
+			}
+		}
+		if errs[i] == nil {
+			errs[i] = m.Create(ctx, user)
+		}
+	}
+	return errs, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func (m *mockUserRepository) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	user, exists := m.users[uuid]
+	if !exists {
 		return sql.ErrNoRows
 	}
-	user.UUID = uuid
-	m.users[uuid] = user
+	updated := patch.Apply(*user)
+	m.users[uuid] = &updated
 	return nil
 }
 
-func (m *mockUserRepository) Delete(uuid string) error {
-	if _, exists := m.users[uuid]; !exists {
+func (m *mockUserRepository) Delete(ctx context.Context, uuid string) error {
+	user, exists := m.users[uuid]
+	if !exists {
 		return sql.ErrNoRows
 	}
 	delete(m.users, uuid)
+	m.deleted[uuid] = user
 	return nil
 }
 
+func (m *mockUserRepository) DeleteMany(ctx context.Context, uuids []string, allOrNothing bool) ([]error, error) {
+	errs := make([]error, len(uuids))
+	failed := false
+	for i, uuid := range uuids {
+		if _, exists := m.users[uuid]; !exists {
+			errs[i] = sql.ErrNoRows
+			failed = true
+		}
+	}
+	for i, uuid := range uuids {
+		switch {
+		case errs[i] != nil:
+		case allOrNothing && failed:
+			errs[i] = repository.ErrBatchRolledBack
+		default:
+			m.deleted[uuid] = m.users[uuid]
+			delete(m.users, uuid)
+		}
+	}
+	return errs, nil
+}
+
+func (m *mockUserRepository) Restore(ctx context.Context, uuid string) error {
+	user, exists := m.deleted[uuid]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	for _, live := range m.users {
+		if live.Username == user.Username {
+			return &repository.DuplicateError{Column: "username"}
+		}
+		if live.Email == user.Email {
+			return &repository.DuplicateError{Column: "email"}
+		}
+	}
+	delete(m.deleted, uuid)
+	m.users[uuid] = user
+	return nil
+}
+
+func (m *mockUserRepository) Purge(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) FindDeleted(ctx context.Context, username string) (*model.DeletedUser, error) {
+	for _, user := range m.deleted {
+		if user.Username == username {
+			return &model.DeletedUser{User: *user, DeletedAt: time.Now()}, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *mockUserRepository) ListDeleted(ctx context.Context, limit, offset int) ([]model.DeletedUser, int, error) {
+	return nil, 0, nil
+}
+
+func (m *mockUserRepository) Aggregate(ctx context.Context, groupBy string) ([]model.AggregateBucket, error) {
+	counts := make(map[string]int)
+	for _, user := range m.users {
+		counts[fmt.Sprint(user.CustomFields[strings.TrimPrefix(groupBy, "cf.")])]++
+	}
+	var buckets []model.AggregateBucket
+	for key, count := range counts {
+		key := key
+		buckets = append(buckets, model.AggregateBucket{Key: &key, Count: count})
+	}
+	return buckets, nil
+}
+
+func (m *mockUserRepository) Sample(ctx context.Context, n int) ([]model.User, error) {
+	var users []model.User
+	for _, user := range m.users {
+		if len(users) == n {
+			break
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+func (m *mockUserRepository) Count(ctx context.Context) (int, error) {
+	return len(m.users), nil
+}
+
+func (m *mockUserRepository) Find(ctx context.Context, query model.UserQuery) ([]model.User, error) {
+	var users []model.User
+	for _, user := range m.users {
+		matches := true
+		for name, value := range query.CustomFields {
+			if fmt.Sprint(user.CustomFields[name]) != value {
+				matches = false
+			}
+		}
+		if matches {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
 // Tests for Create
 func TestCreateUser_Success(t *testing.T) {
 	// Given: Empty repository
@@ -93,7 +243,7 @@ func TestCreateUser_Success(t *testing.T) {
 	}
 
 	// When: Creating a new user
-	err := service.Create(newUser)
+	err := service.Create(context.Background(), newUser)
 
 	// Then: User should be created successfully
 	if err != nil {
@@ -127,7 +277,7 @@ func TestCreateUser_DuplicateUsername(t *testing.T) {
 	}
 
 	// When: Trying to create user with duplicate username
-	err := service.Create(newUser)
+	err := service.Create(context.Background(), newUser)
 
 	// Then: Should return error
 	if err == nil {
@@ -138,6 +288,183 @@ func TestCreateUser_DuplicateUsername(t *testing.T) {
 	}
 }
 
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	// Given: Repository with a user holding the email
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	repo.users["existing-uuid"] = &model.User{UUID: "existing-uuid", Username: "existinguser", Email: "existing@example.com"}
+
+	// When: Creating another user with the same email
+	err := service.Create(context.Background(), &model.User{Username: "newuser", Email: "existing@example.com"})
+
+	// Then: The email is reported as taken, not the username
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+	if len(repo.users) != 1 {
+		t.Errorf("expected no user created, got %d users", len(repo.users))
+	}
+}
+
+func TestCreateMany_PartialFailure(t *testing.T) {
+	// Given: An existing user and a publisher
+	repo := newMockUserRepository()
+	repo.users["existing-uuid"] = &model.User{UUID: "existing-uuid", Username: "existinguser", Email: "existing@example.com"}
+	publisher := &recordingPublisher{}
+	service := NewUserService(repo, WithEvents(publisher), WithPolicy(UserPolicy{ReservedUsernames: []string{"admin"}}))
+
+	// When: Creating a batch with a taken username, a reserved one and a clash
+	// within the batch itself
+	users := []*model.User{
+		{Username: "anna", Email: "anna@example.com"},
+		{Username: "existinguser", Email: "new@example.com"},
+		{Username: "admin", Email: "admin@example.com"},
+		{Username: "bob", Email: "bob@example.com"},
+		{Username: "bob", Email: "bobby@example.com"},
+	}
+	errs, err := service.CreateMany(context.Background(), users)
+
+	// Then: Each item reports its own outcome and only the good ones are created
+	if err != nil {
+		t.Fatalf("expected the batch to succeed, got %v", err)
+	}
+	if errs[0] != nil || errs[3] != nil {
+		t.Errorf("expected anna and the first bob to be created, got %v and %v", errs[0], errs[3])
+	}
+	if errs[1] == nil || errs[1].Error() != "username already exists" {
+		t.Errorf("expected 'username already exists' for the taken username, got %v", errs[1])
+	}
+	var validationErr *ValidationError
+	if !errors.As(errs[2], &validationErr) {
+		t.Errorf("expected a validation error for the reserved username, got %v", errs[2])
+	}
+	if errs[4] == nil || errs[4].Error() != "username already exists" {
+		t.Errorf("expected 'username already exists' for the clash within the batch, got %v", errs[4])
+	}
+	if len(repo.users) != 3 || len(publisher.events) != 2 {
+		t.Errorf("expected 2 users created and published, got %d users and %d events", len(repo.users)-1, len(publisher.events))
+	}
+}
+
+func TestCreateMany_Size(t *testing.T) {
+	service := NewUserService(newMockUserRepository())
+
+	for _, n := range []int{0, MaxBulkSize + 1} {
+		users := make([]*model.User, n)
+		if _, err := service.CreateMany(context.Background(), users); err == nil || err.Error() != "invalid bulk size" {
+			t.Errorf("%d users: expected 'invalid bulk size', got %v", n, err)
+		}
+	}
+}
+
+func TestUserQuota(t *testing.T) {
+	// Given: A deployment allowed 3 users, with 1 already
+	repo := newMockUserRepository()
+	repo.users["existing-uuid"] = &model.User{UUID: "existing-uuid", Username: "existinguser", Email: "existing@example.com"}
+	service := NewUserService(repo, WithQuota(3))
+
+	// When: Creating a batch of 3
+	errs, err := service.CreateMany(context.Background(), []*model.User{
+		{Username: "anna", Email: "anna@example.com"},
+		{Username: "bob", Email: "bob@example.com"},
+		{Username: "carol", Email: "carol@example.com"},
+	})
+
+	// Then: Users are created in batch order until the quota is reached
+	if err != nil {
+		t.Fatalf("expected the batch to succeed, got %v", err)
+	}
+	if errs[0] != nil || errs[1] != nil || !errors.Is(errs[2], ErrUserQuotaExceeded) {
+		t.Errorf("expected the third user over the quota, got %v", errs)
+	}
+
+	// When: Creating or restoring one more
+	createErr := service.Create(context.Background(), &model.User{Username: "dave", Email: "dave@example.com"})
+	repo.deleted["uuid-gone"] = &model.User{UUID: "uuid-gone", Username: "gone", Email: "gone@example.com"}
+	_, restoreErr := service.Restore(context.Background(), "uuid-gone")
+
+	// Then: Both are rejected, and the usage is reported
+	if !errors.Is(createErr, ErrUserQuotaExceeded) || !errors.Is(restoreErr, ErrUserQuotaExceeded) {
+		t.Errorf("expected quota errors, got %v and %v", createErr, restoreErr)
+	}
+	quota, err := service.Quota(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if quota.Count != 3 || quota.Limit != 3 || quota.Remaining == nil || *quota.Remaining != 0 {
+		t.Errorf("unexpected quota %+v", quota)
+	}
+
+	// And without a quota nothing is counted against
+	quota, _ = NewUserService(repo).Quota(context.Background())
+	if quota.Count != 3 || quota.Limit != 0 || quota.Remaining != nil {
+		t.Errorf("unexpected unlimited quota %+v", quota)
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	const (
+		anna = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+		bob  = "6fa459ea-ee8a-3ca4-894e-db77e160355e"
+		gone = "16fd2706-8baf-433b-82eb-8c7fada847da"
+	)
+
+	tests := []struct {
+		name         string
+		uuids        []string
+		allOrNothing bool
+		want         []error
+		wantDeleted  int
+	}{
+		{"all exist", []string{anna, bob}, true, []error{nil, nil}, 2},
+		{"all or nothing keeps everyone", []string{anna, gone, bob}, true,
+			[]error{ErrBulkRolledBack, ErrUserNotFound, ErrBulkRolledBack}, 0},
+		{"partial deletes the others", []string{anna, gone, bob}, false,
+			[]error{nil, ErrUserNotFound, nil}, 2},
+		{"malformed uuid is not found", []string{"not-a-uuid", anna}, false,
+			[]error{ErrUserNotFound, nil}, 1},
+		{"malformed uuid rolls back", []string{"not-a-uuid", anna}, true,
+			[]error{ErrUserNotFound, ErrBulkRolledBack}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given: Two existing users
+			repo := newMockUserRepository()
+			repo.users[anna] = &model.User{UUID: anna, Username: "anna"}
+			repo.users[bob] = &model.User{UUID: bob, Username: "bob"}
+			publisher := &recordingPublisher{}
+			service := NewUserService(repo, WithEvents(publisher))
+
+			// When
+			errs, err := service.DeleteMany(context.Background(), tt.uuids, tt.allOrNothing)
+
+			// Then
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range tt.want {
+				if errs[i] != tt.want[i] {
+					t.Errorf("item %d: expected %v, got %v", i, tt.want[i], errs[i])
+				}
+			}
+			if len(repo.deleted) != tt.wantDeleted || len(publisher.events) != tt.wantDeleted {
+				t.Errorf("expected %d users deleted and published, got %d and %d", tt.wantDeleted, len(repo.deleted), len(publisher.events))
+			}
+		})
+	}
+}
+
+func TestDeleteMany_Size(t *testing.T) {
+	service := NewUserService(newMockUserRepository())
+
+	for _, n := range []int{0, MaxBulkSize + 1} {
+		if _, err := service.DeleteMany(context.Background(), make([]string, n), false); !errors.Is(err, ErrInvalidBulkSize) {
+			t.Errorf("%d uuids: expected ErrInvalidBulkSize, got %v", n, err)
+		}
+	}
+}
+
 // Tests for Update
 func TestUpdateUser_Success(t *testing.T) {
 	// Given: Repository with existing user
@@ -153,21 +480,17 @@ func TestUpdateUser_Success(t *testing.T) {
 	}
 	repo.users["test-uuid"] = existingUser
 
-	updatedUser := &model.User{
-		Username: "newusername",
-		Email:    "new@example.com",
-		FullName: "New Name",
-	}
+	patch := model.UserPatch{Username: strPtr("newusername"), Email: strPtr("new@example.com"), FullName: strPtr("New Name")}
 
 	// When: Updating the user
-	err := service.Update("test-uuid", updatedUser)
+	err := service.Update(context.Background(), "test-uuid", patch)
 
 	// Then: User should be updated successfully
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
-	user, _ := repo.GetByUUID("test-uuid")
+	user, _ := repo.GetByUUID(context.Background(), "test-uuid")
 	if user.Username != "newusername" {
 		t.Errorf("expected username 'newusername', got %s", user.Username)
 	}
@@ -178,14 +501,8 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	repo := newMockUserRepository()
 	service := NewUserService(repo)
 
-	updatedUser := &model.User{
-		Username: "newusername",
-		Email:    "new@example.com",
-		FullName: "New Name",
-	}
-
 	// When: Trying to update non-existent user
-	err := service.Update("non-existent-uuid", updatedUser)
+	err := service.Update(context.Background(), "non-existent-uuid", model.UserPatch{Username: strPtr("newusername")})
 
 	// Then: Should return error
 	if err == nil {
@@ -196,6 +513,86 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	}
 }
 
+func TestUpdateUser_DuplicateEmail(t *testing.T) {
+	// Given: Two users
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	repo.users["uuid-1"] = &model.User{UUID: "uuid-1", Username: "first", Email: "first@example.com"}
+	repo.users["uuid-2"] = &model.User{UUID: "uuid-2", Username: "second", Email: "second@example.com"}
+
+	// When: Giving the second the first's email, then keeping its own
+	err := service.Update(context.Background(), "uuid-2", model.UserPatch{Email: strPtr("first@example.com")})
+	unchanged := service.Update(context.Background(), "uuid-2", model.UserPatch{Email: strPtr("second@example.com")})
+
+	// Then: Only another user's email is taken
+	if !errors.Is(err, ErrEmailTaken) {
+		t.Errorf("expected ErrEmailTaken, got %v", err)
+	}
+	if repo.users["uuid-2"].Email != "second@example.com" {
+		t.Errorf("expected the email unchanged, got %s", repo.users["uuid-2"].Email)
+	}
+	if unchanged != nil {
+		t.Errorf("expected the user's own email to be accepted, got %v", unchanged)
+	}
+}
+
+func TestUpdateUser_Version(t *testing.T) {
+	// Given: A user at version 3
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	repo.users["uuid-1"] = &model.User{UUID: "uuid-1", Username: "jdoe", Email: "jdoe@example.com", Version: 3}
+	stale, current := int64(2), int64(3)
+
+	// When: Updating against an older version, then against the current one
+	staleErr := service.Update(context.Background(), "uuid-1", model.UserPatch{FullName: strPtr("Stale"), Version: &stale})
+	currentErr := service.Update(context.Background(), "uuid-1", model.UserPatch{FullName: strPtr("Current"), Version: &current})
+
+	// Then: Only the update made against the current version is applied
+	if !errors.Is(staleErr, ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", staleErr)
+	}
+	if currentErr != nil {
+		t.Errorf("unexpected error: %v", currentErr)
+	}
+	if repo.users["uuid-1"].FullName != "Current" {
+		t.Errorf("expected full name Current, got %q", repo.users["uuid-1"].FullName)
+	}
+}
+
+func TestUpdateUser_Partial(t *testing.T) {
+	// Given: An existing user
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	repo.users["test-uuid"] = &model.User{
+		ID:       1,
+		UUID:     "test-uuid",
+		Username: "jdoe",
+		Email:    "old@example.com",
+		FullName: "John Doe",
+	}
+
+	// When: Only the email is sent
+	err := service.Update(context.Background(), "test-uuid", model.UserPatch{Email: strPtr("new@example.com")})
+
+	// Then: The other fields keep their values
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	user := repo.users["test-uuid"]
+	if user.Email != "new@example.com" || user.Username != "jdoe" || user.FullName != "John Doe" {
+		t.Errorf("expected only the email to change, got %+v", user)
+	}
+
+	// When: A present field is blanked
+	err = service.Update(context.Background(), "test-uuid", model.UserPatch{Username: strPtr(" ")})
+
+	// Then: The merged user is checked like a new one
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Errors[0].Field != "username" {
+		t.Errorf("expected a username validation error, got %v", err)
+	}
+}
+
 // Tests for Delete
 func TestDeleteUser_Success(t *testing.T) {
 	// Given: Repository with existing user
@@ -212,14 +609,14 @@ func TestDeleteUser_Success(t *testing.T) {
 	repo.users["test-uuid"] = existingUser
 
 	// When: Deleting the user
-	err := service.Delete("test-uuid")
+	err := service.Delete(context.Background(), "test-uuid")
 
 	// Then: User should be deleted successfully
 	if err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
-	_, err = repo.GetByUUID("test-uuid")
+	_, err = repo.GetByUUID(context.Background(), "test-uuid")
 	if err != sql.ErrNoRows {
 		t.Error("expected user to be deleted")
 	}
@@ -231,7 +628,7 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	service := NewUserService(repo)
 
 	// When: Trying to delete non-existent user
-	err := service.Delete("non-existent-uuid")
+	err := service.Delete(context.Background(), "non-existent-uuid")
 
 	// Then: Should return error
 	if err == nil {
@@ -242,6 +639,125 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	}
 }
 
+func TestRestoreUser(t *testing.T) {
+	// Given: A deleted user whose email has been taken since
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	repo.deleted["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "anna", Email: "anna@example.com"}
+	repo.users["uuid-2"] = &model.User{ID: 2, UUID: "uuid-2", Username: "anna2", Email: "anna@example.com"}
+
+	// When: Restoring the user
+	_, err := service.Restore(context.Background(), "uuid-1")
+
+	// Then: The conflict is reported
+	if err == nil || err.Error() != "email already exists" {
+		t.Fatalf("expected 'email already exists', got %v", err)
+	}
+
+	// When: The email is free again
+	delete(repo.users, "uuid-2")
+	user, err := service.Restore(context.Background(), "uuid-1")
+
+	// Then: The user is back
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Username != "anna" {
+		t.Errorf("expected restored user anna, got %+v", user)
+	}
+
+	// When: Restoring a user that is not deleted
+	_, err = service.Restore(context.Background(), "uuid-1")
+
+	// Then: It is not found
+	if err == nil || err.Error() != "users not found" {
+		t.Errorf("expected 'users not found', got %v", err)
+	}
+}
+
+func TestCreateUser_NormalizedEmail(t *testing.T) {
+	// Given: A user, and a policy stripping plus tags and Gmail dots
+	repo := newMockUserRepository()
+	service := NewUserService(repo, WithPolicy(UserPolicy{StripPlusTags: true, StripGmailDots: true}))
+	if err := service.Create(context.Background(), &model.User{Username: "anna", Email: "Anna.Smith@gmail.com"}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		email string
+		want  error
+	}{
+		{"annasmith@gmail.com", ErrEmailTaken},
+		{"anna.smith+shop@googlemail.com", ErrEmailTaken},
+		{"ANNA.SMITH@GMAIL.COM", ErrEmailTaken},
+		{"anna.smith@example.com", nil},
+	}
+	for i, tt := range tests {
+		// When: Creating another user with a variant of the email
+		err := service.Create(context.Background(), &model.User{Username: fmt.Sprintf("other%d", i), Email: tt.email})
+
+		// Then
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.email, tt.want, err)
+		}
+	}
+
+	// Then: The email is stored as entered
+	user, err := service.GetByUsername(context.Background(), "anna")
+	if err != nil || user.Email != "Anna.Smith@gmail.com" || user.EmailNorm != "annasmith@gmail.com" {
+		t.Errorf("unexpected user %+v, %v", user, err)
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy UserPolicy
+		email  string
+		want   string
+	}{
+		{"lower-cased by default", UserPolicy{}, " Anna.Smith+x@GMAIL.com ", "anna.smith+x@gmail.com"},
+		{"plus tags stripped", UserPolicy{StripPlusTags: true}, "anna+news@example.com", "anna@example.com"},
+		{"a leading plus is kept", UserPolicy{StripPlusTags: true}, "+anna@example.com", "+anna@example.com"},
+		{"gmail dots stripped", UserPolicy{StripGmailDots: true}, "a.n.na@googlemail.com", "anna@gmail.com"},
+		{"other domains keep dots", UserPolicy{StripGmailDots: true}, "a.nna@example.com", "a.nna@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.normalizeEmail(tt.email); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCreateUser_DeletedUsername(t *testing.T) {
+	// Given: A deleted user named anna
+	repo := newMockUserRepository()
+	service := NewUserService(repo)
+	repo.deleted["uuid-1"] = &model.User{ID: 1, UUID: "uuid-1", Username: "anna", Email: "anna@example.com"}
+
+	// When: Creating another anna
+	err := service.Create(context.Background(), &model.User{Username: "anna", Email: "anna.new@example.com"})
+
+	// Then: The deleted account is offered for restore
+	var deletedErr *DeletedUserError
+	if !errors.As(err, &deletedErr) || deletedErr.User.UUID != "uuid-1" {
+		t.Fatalf("expected a DeletedUserError naming uuid-1, got %v", err)
+	}
+
+	// When: The caller asks for a new account
+	err = service.Create(WithReplaceDeleted(context.Background()), &model.User{Username: "anna", Email: "anna.new@example.com"})
+
+	// Then: It is created next to the deleted one
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.users) != 1 || repo.deleted["uuid-1"] == nil {
+		t.Errorf("expected one live and one deleted anna, got %d live", len(repo.users))
+	}
+}
+
 // Tests for GetByUsername
 func TestGetByUsername_Success(t *testing.T) {
 	// Given: Repository with existing user
@@ -258,7 +774,7 @@ func TestGetByUsername_Success(t *testing.T) {
 	repo.users["test-uuid"] = existingUser
 
 	// When: Getting user by username
-	user, err := service.GetByUsername("testuser")
+	user, err := service.GetByUsername(context.Background(), "testuser")
 
 	// Then: Should return the user
 	if err != nil {
@@ -278,7 +794,7 @@ func TestGetByUsername_NotFound(t *testing.T) {
 	service := NewUserService(repo)
 
 	// When: Getting non-existent user
-	user, err := service.GetByUsername("nonexistent")
+	user, err := service.GetByUsername(context.Background(), "nonexistent")
 
 	// Then: Should return error
 	if err == nil {
@@ -308,7 +824,7 @@ func TestGetByID_Success(t *testing.T) {
 	repo.users["test-uuid"] = existingUser
 
 	// When: Getting user by ID
-	user, err := service.GetByID(1)
+	user, err := service.GetByID(context.Background(), 1)
 
 	// Then: Should return the user
 	if err != nil {
@@ -328,7 +844,7 @@ func TestGetByID_NotFound(t *testing.T) {
 	service := NewUserService(repo)
 
 	// When: Getting non-existent user
-	user, err := service.GetByID(999)
+	user, err := service.GetByID(context.Background(), 999)
 
 	// Then: Should return error
 	if err == nil {
@@ -366,7 +882,7 @@ func TestGetAll_Success(t *testing.T) {
 	repo.users["uuid-2"] = user2
 
 	// When: Getting all users
-	users, err := service.GetAll()
+	users, err := service.GetAll(context.Background())
 
 	// Then: Should return all users
 	if err != nil {
@@ -383,7 +899,7 @@ func TestGetAll_Empty(t *testing.T) {
 	service := NewUserService(repo)
 
 	// When: Getting all users
-	users, err := service.GetAll()
+	users, err := service.GetAll(context.Background())
 
 	// Then: Should return empty list
 	if err != nil {
@@ -393,3 +909,323 @@ func TestGetAll_Empty(t *testing.T) {
 		t.Errorf("expected 0 users, got %d", len(users))
 	}
 }
+
+// Tests for Aggregate
+func TestAggregate_GroupBy(t *testing.T) {
+	// Given: a repository with a custom field definition
+	_, service := newCustomFieldUserService()
+
+	tests := []struct {
+		name    string
+		groupBy string
+		wantErr string
+	}{
+		{"created month", "created_month", ""},
+		{"defined custom field", "cf.department", ""},
+		{"undefined custom field", "cf.shoe_size", `custom field "shoe_size" is not defined`},
+		{"column outside allowlist", "email", "invalid group_by"},
+		// Users have no status or tags; these are only counted as custom fields
+		{"status", "status", "invalid group_by"},
+		{"tag", "tag", "invalid group_by"},
+		{"missing group_by", "", "invalid group_by"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When: Aggregating
+			buckets, err := service.Aggregate(context.Background(), tt.groupBy)
+
+			// Then: Should count or reject the field
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("expected error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if buckets == nil {
+				t.Error("expected empty buckets, got nil")
+			}
+		})
+	}
+}
+
+// Tests for Sample
+func TestSample_Size(t *testing.T) {
+	// Given: a repository with three users
+	repo := newMockUserRepository()
+	for _, name := range []string{"a", "b", "c"} {
+		repo.users[name] = &model.User{UUID: name, Username: name}
+	}
+	service := NewUserService(repo)
+
+	tests := []struct {
+		name    string
+		n       int
+		want    int
+		wantErr bool
+	}{
+		{"smaller than table", 2, 2, false},
+		{"larger than table", 10, 3, false},
+		{"zero", 0, 0, true},
+		{"above maximum", MaxSampleSize + 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When: Sampling
+			users, err := service.Sample(context.Background(), tt.n)
+
+			// Then: Should return at most n users or reject the size
+			if tt.wantErr {
+				if err == nil || err.Error() != "invalid sample size" {
+					t.Errorf("expected invalid sample size, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if len(users) != tt.want {
+				t.Errorf("expected %d users, got %d", tt.want, len(users))
+			}
+		})
+	}
+}
+
+type recordingPublisher struct {
+	events []events.Event
+}
+
+func (p *recordingPublisher) Publish(e events.Event) {
+	p.events = append(p.events, e)
+}
+
+// Tests for events
+func TestUserService_PublishesEvents(t *testing.T) {
+	// Given
+	repo := newMockUserRepository()
+	publisher := &recordingPublisher{}
+	service := NewUserService(repo, WithEvents(publisher))
+
+	// When: creating, updating and deleting a user
+	user := &model.User{Username: "jdoe", Email: "jdoe@example.com"}
+	if err := service.Create(context.Background(), user); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := service.Update(context.Background(), user.UUID, model.UserPatch{Email: strPtr("john@example.com")}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if err := service.Delete(context.Background(), user.UUID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	// A failed change publishes nothing
+	_ = service.Delete(context.Background(), "missing")
+
+	// Then
+	want := []string{events.UserCreated, events.UserUpdated, events.UserDeleted}
+	if len(publisher.events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), publisher.events)
+	}
+	for i, e := range publisher.events {
+		if e.Type != want[i] || e.UserUUID != user.UUID {
+			t.Errorf("event %d: expected %s for %s, got %+v", i, want[i], user.UUID, e)
+		}
+	}
+	if publisher.events[1].User.Email != "john@example.com" {
+		t.Errorf("expected updated email in event, got %+v", publisher.events[1].User)
+	}
+}
+
+type txKey struct{}
+
+// mockTxManager marks the context of each unit of work and fails its commit with
+// commitErr
+type mockTxManager struct {
+	commitErr error
+	units     int
+}
+
+func (m *mockTxManager) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.units++
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		return err
+	}
+	return m.commitErr
+}
+
+// txCheckingRepository records the lookups and writes made outside a unit of work
+type txCheckingRepository struct {
+	*mockUserRepository
+	outside []string
+}
+
+func (r *txCheckingRepository) check(ctx context.Context, method string) {
+	if ctx.Value(txKey{}) == nil {
+		r.outside = append(r.outside, method)
+	}
+}
+
+func (r *txCheckingRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	r.check(ctx, "GetByUsername")
+	return r.mockUserRepository.GetByUsername(ctx, username)
+}
+
+func (r *txCheckingRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	r.check(ctx, "GetByEmail")
+	return r.mockUserRepository.GetByEmail(ctx, email)
+}
+
+func (r *txCheckingRepository) Create(ctx context.Context, user *model.User) error {
+	r.check(ctx, "Create")
+	return r.mockUserRepository.Create(ctx, user)
+}
+
+func (r *txCheckingRepository) CreateMany(ctx context.Context, users []*model.User) ([]error, error) {
+	r.check(ctx, "CreateMany")
+	return r.mockUserRepository.CreateMany(ctx, users)
+}
+
+func (r *txCheckingRepository) Count(ctx context.Context) (int, error) {
+	r.check(ctx, "Count")
+	return r.mockUserRepository.Count(ctx)
+}
+
+func (r *txCheckingRepository) Update(ctx context.Context, uuid string, patch model.UserPatch) error {
+	r.check(ctx, "Update")
+	return r.mockUserRepository.Update(ctx, uuid, patch)
+}
+
+func TestUserService_Transactions(t *testing.T) {
+	// Given: A service running its units of work through a TxManager
+	repo := &txCheckingRepository{mockUserRepository: newMockUserRepository()}
+	tx := &mockTxManager{}
+	publisher := &recordingPublisher{}
+	service := NewUserService(repo, WithTransactions(tx), WithEvents(publisher), WithQuota(10))
+
+	// When: Creating a user, changing its username and creating a batch
+	user := &model.User{Username: "jdoe", Email: "jdoe@example.com"}
+	if err := service.Create(context.Background(), user); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if err := service.Update(context.Background(), user.UUID, model.UserPatch{Username: strPtr("john")}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if _, err := service.CreateMany(context.Background(), []*model.User{{Username: "anna", Email: "anna@example.com"}}); err != nil {
+		t.Fatalf("bulk create failed: %v", err)
+	}
+
+	// Then: The checks, the quota count and the writes ran in one unit of work each
+	if tx.units != 3 {
+		t.Errorf("expected 3 units of work, got %d", tx.units)
+	}
+	if len(repo.outside) > 0 {
+		t.Errorf("expected every lookup and write in a transaction, got %v outside", repo.outside)
+	}
+
+	// When: The commit fails
+	tx.commitErr = errors.New("could not serialize access")
+	err := service.Create(context.Background(), &model.User{Username: "asmith", Email: "asmith@example.com"})
+
+	// Then: Create fails and announces nothing
+	if !errors.Is(err, tx.commitErr) {
+		t.Errorf("expected the commit error, got %v", err)
+	}
+	if len(publisher.events) != 3 {
+		t.Errorf("expected only the 3 committed changes published, got %+v", publisher.events)
+	}
+}
+
+func TestCreateUser_ConcurrentDuplicate(t *testing.T) {
+	// Given: A concurrent create won the race past the checks, so the insert hits
+	// the unique index
+	repo := &conflictingRepository{mockUserRepository: newMockUserRepository()}
+	service := NewUserService(repo)
+
+	// When
+	err := service.Create(context.Background(), &model.User{Username: "jdoe", Email: "jdoe@example.com"})
+
+	// Then: The caller gets the same answer as if the check had caught it
+	if !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("expected ErrUsernameTaken, got %v", err)
+	}
+}
+
+// conflictingRepository fails inserts like the unique index on live usernames
+type conflictingRepository struct {
+	*mockUserRepository
+}
+
+func (r *conflictingRepository) Create(ctx context.Context, user *model.User) error {
+	return &repository.DuplicateError{Column: "username"}
+}
+
+// failingLookupRepository fails every lookup by username or email with err
+type failingLookupRepository struct {
+	*mockUserRepository
+	err error
+}
+
+func (r *failingLookupRepository) GetByUsername(ctx context.Context, username string) (*model.User, error) {
+	return nil, r.err
+}
+
+func (r *failingLookupRepository) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	return nil, r.err
+}
+
+func TestUserChecks_ReturnLookupErrors(t *testing.T) {
+	// Given: Lookups failing as in a transaction PostgreSQL aborted
+	repo := &failingLookupRepository{mockUserRepository: newMockUserRepository(), err: errors.New("could not serialize access")}
+	repo.users["uuid-jdoe"] = &model.User{UUID: "uuid-jdoe", Username: "jdoe", Email: "jdoe@example.com", Version: 1}
+	service := NewUserService(repo)
+
+	// When: Creating, updating and validating users
+	createErr := service.Create(context.Background(), &model.User{Username: "asmith", Email: "asmith@example.com"})
+	updateErr := service.Update(context.Background(), "uuid-jdoe", model.UserPatch{Username: strPtr("john")})
+	_, validateErr := service.Validate(context.Background(), &model.User{Username: "asmith", Email: "asmith@example.com"})
+
+	// Then: The lookup error is returned rather than taken for a free name
+	for name, err := range map[string]error{"create": createErr, "update": updateErr, "validate": validateErr} {
+		if !errors.Is(err, repo.err) {
+			t.Errorf("%s: expected the lookup error, got %v", name, err)
+		}
+	}
+}
+
+// wrappingRepository wraps the errors of lookups, as a tracing layer may
+type wrappingRepository struct {
+	*mockUserRepository
+}
+
+func (r *wrappingRepository) GetByUUID(ctx context.Context, uuid string) (*model.User, error) {
+	user, err := r.mockUserRepository.GetByUUID(ctx, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("get user by uuid: %w", err)
+	}
+	return user, nil
+}
+
+func (r *wrappingRepository) GetByID(ctx context.Context, id int64) (*model.User, error) {
+	user, err := r.mockUserRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get user by id: %w", err)
+	}
+	return user, nil
+}
+
+func TestGetUser_WrappedNotFound(t *testing.T) {
+	// Given: A repository wrapping sql.ErrNoRows
+	service := NewUserService(&wrappingRepository{mockUserRepository: newMockUserRepository()})
+
+	// When: Looking up users that do not exist
+	_, byUUID := service.GetByUUID(context.Background(), "1b4e28ba-2fa1-11d2-883f-0016d3cca427")
+	_, byID := service.GetByID(context.Background(), 42)
+
+	// Then: Both are reported as not found
+	if !errors.Is(byUUID, ErrUserNotFound) || !errors.Is(byID, ErrUserNotFound) {
+		t.Errorf("expected ErrUserNotFound, got %v and %v", byUUID, byID)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"cruder/internal/repository"
+	"cruder/internal/webhook"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxWebhookURLLength         = 2000
+	maxWebhookDescriptionLength = 200
+	// webhookDeliveriesShown bounds the deliveries listed for a webhook
+	webhookDeliveriesShown = 100
+)
+
+type WebhookService interface {
+	// List returns every webhook without its secret
+	List(ctx context.Context) ([]model.Webhook, error)
+	Get(ctx context.Context, id string) (*model.Webhook, error)
+	// Create registers a webhook; the returned webhook carries its signing secret,
+	// which is not returned again
+	Create(ctx context.Context, createdBy string, req model.WebhookRequest) (*model.Webhook, error)
+	// Update replaces the URL, events, description and active flag of a webhook;
+	// its secret stays the same
+	Update(ctx context.Context, id string, req model.WebhookRequest) (*model.Webhook, error)
+	// Delete removes a webhook; its pending deliveries are not sent
+	Delete(ctx context.Context, id string) error
+	// Deliveries returns the latest deliveries of a webhook, newest first
+	Deliveries(ctx context.Context, id string) ([]model.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo repository.WebhookRepository
+}
+
+// NewWebhookService manages the webhooks user events are delivered to; the
+// deliveries themselves are queued and sent by package webhook
+func NewWebhookService(repo repository.WebhookRepository) WebhookService {
+	return &webhookService{repo: repo}
+}
+
+func (s *webhookService) List(ctx context.Context) ([]model.Webhook, error) {
+	webhooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *webhookService) Get(ctx context.Context, id string) (*model.Webhook, error) {
+	w, err := s.repo.Get(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+func (s *webhookService) Create(ctx context.Context, createdBy string, req model.WebhookRequest) (*model.Webhook, error) {
+	w, err := checkWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+	if w.ID, err = newUUID(); err != nil {
+		return nil, err
+	}
+	if w.Secret, err = webhook.NewSecret(); err != nil {
+		return nil, err
+	}
+	w.CreatedBy = createdBy
+	if err := s.repo.Create(ctx, w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (s *webhookService) Update(ctx context.Context, id string, req model.WebhookRequest) (*model.Webhook, error) {
+	w, err := checkWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+	w.ID = id
+	if err := s.repo.Update(ctx, w); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, id string) ([]model.WebhookDelivery, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.Deliveries(ctx, id, webhookDeliveriesShown)
+}
+
+// checkWebhook validates a request and returns the webhook it describes
+func checkWebhook(req model.WebhookRequest) (*model.Webhook, error) {
+	target := strings.TrimSpace(req.URL)
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(target) > maxWebhookURLLength {
+		return nil, ErrInvalidWebhookURL
+	}
+	if len(req.Events) == 0 {
+		return nil, ErrInvalidWebhookEvents
+	}
+	subscribed := make([]string, 0, len(req.Events))
+	for _, e := range req.Events {
+		if !webhook.Subscribed(e) {
+			return nil, ErrInvalidWebhookEvents
+		}
+		if !containsString(subscribed, e) {
+			subscribed = append(subscribed, e)
+		}
+	}
+	description := strings.TrimSpace(req.Description)
+	if utf8.RuneCountInString(description) > maxWebhookDescriptionLength {
+		return nil, ErrDescriptionTooLong
+	}
+	active := req.Active == nil || *req.Active
+	return &model.Webhook{URL: target, Events: subscribed, Description: description, Active: active}, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"cruder/internal/model"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockWebhookRepository struct {
+	webhooks map[string]*model.Webhook
+}
+
+func (m *mockWebhookRepository) Create(_ context.Context, w *model.Webhook) error {
+	copied := *w
+	m.webhooks[w.ID] = &copied
+	return nil
+}
+
+func (m *mockWebhookRepository) List(_ context.Context) ([]model.Webhook, error) {
+	webhooks := []model.Webhook{}
+	for _, w := range m.webhooks {
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, nil
+}
+
+func (m *mockWebhookRepository) Get(_ context.Context, id string) (*model.Webhook, error) {
+	w, ok := m.webhooks[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *w
+	return &copied, nil
+}
+
+func (m *mockWebhookRepository) Update(_ context.Context, w *model.Webhook) error {
+	stored, ok := m.webhooks[w.ID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	stored.URL, stored.Events, stored.Description, stored.Active = w.URL, w.Events, w.Description, w.Active
+	*w = *stored
+	return nil
+}
+
+func (m *mockWebhookRepository) Delete(_ context.Context, id string) error {
+	if _, ok := m.webhooks[id]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *mockWebhookRepository) Enqueue(context.Context, string, string, []byte) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockWebhookRepository) Claim(context.Context, int, time.Duration) ([]model.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *mockWebhookRepository) MarkDelivered(context.Context, int64, int) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) MarkFailed(context.Context, int64, int, string, *time.Time) error {
+	return nil
+}
+
+func (m *mockWebhookRepository) Deliveries(context.Context, string, int) ([]model.WebhookDelivery, error) {
+	return []model.WebhookDelivery{}, nil
+}
+
+func (m *mockWebhookRepository) PurgeDeliveries(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestWebhookService_Create(t *testing.T) {
+	// Given: A webhook service
+	repo := &mockWebhookRepository{webhooks: map[string]*model.Webhook{}}
+	svc := NewWebhookService(repo)
+	ctx := context.Background()
+
+	// When: Registering a webhook for the same event twice over
+	created, err := svc.Create(ctx, "admin", model.WebhookRequest{
+		URL:    " https://hooks.example.com/users ",
+		Events: []string{"user.created", "user.deleted", "user.created"},
+	})
+
+	// Then: It is active, subscribed once per event and returned with its secret once
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if created.URL != "https://hooks.example.com/users" || len(created.Events) != 2 || !created.Active || created.CreatedBy != "admin" {
+		t.Errorf("unexpected webhook %+v", created)
+	}
+	if !strings.HasPrefix(created.Secret, "whsec_") {
+		t.Errorf("expected a secret, got %q", created.Secret)
+	}
+	if repo.webhooks[created.ID].Secret != created.Secret {
+		t.Error("secret not stored")
+	}
+	got, err := svc.Get(ctx, created.ID)
+	if err != nil || got.Secret != "" {
+		t.Errorf("Get = %+v, %v; want the webhook without its secret", got, err)
+	}
+	list, _ := svc.List(ctx)
+	if len(list) != 1 || list[0].Secret != "" {
+		t.Errorf("List = %+v; want the webhook without its secret", list)
+	}
+}
+
+func TestWebhookService_RejectsInvalidWebhooks(t *testing.T) {
+	svc := NewWebhookService(&mockWebhookRepository{webhooks: map[string]*model.Webhook{}})
+	tests := []struct {
+		name string
+		req  model.WebhookRequest
+		want error
+	}{
+		{"relative URL", model.WebhookRequest{URL: "/hooks", Events: []string{"user.created"}}, ErrInvalidWebhookURL},
+		{"other scheme", model.WebhookRequest{URL: "ftp://example.com", Events: []string{"user.created"}}, ErrInvalidWebhookURL},
+		{"no events", model.WebhookRequest{URL: "https://example.com", Events: []string{}}, ErrInvalidWebhookEvents},
+		{"unknown event", model.WebhookRequest{URL: "https://example.com", Events: []string{"user.viewed"}}, ErrInvalidWebhookEvents},
+		{"long description", model.WebhookRequest{URL: "https://example.com", Events: []string{"user.created"}, Description: strings.Repeat("x", 201)}, ErrDescriptionTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When: Registering the webhook
+			_, err := svc.Create(context.Background(), "admin", tt.req)
+
+			// Then: It is rejected with the matching error
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestWebhookService_UpdateKeepsSecret(t *testing.T) {
+	// Given: A registered webhook
+	repo := &mockWebhookRepository{webhooks: map[string]*model.Webhook{}}
+	svc := NewWebhookService(repo)
+	ctx := context.Background()
+	created, err := svc.Create(ctx, "admin", model.WebhookRequest{URL: "https://example.com", Events: []string{"user.created"}})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// When: Pausing it and changing its events
+	inactive := false
+	updated, err := svc.Update(ctx, created.ID, model.WebhookRequest{URL: "https://example.com", Events: []string{"user.updated"}, Active: &inactive})
+
+	// Then: The settings change, the stored secret does not and is not returned
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if updated.Active || updated.Events[0] != "user.updated" || updated.Secret != "" {
+		t.Errorf("unexpected webhook %+v", updated)
+	}
+	if repo.webhooks[created.ID].Secret != created.Secret {
+		t.Error("secret changed")
+	}
+	if _, err := svc.Update(ctx, "missing", model.WebhookRequest{URL: "https://example.com", Events: []string{"user.created"}}); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocal_PutOpenDelete(t *testing.T) {
+	// Given: A local backend in a temporary directory
+	backend, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := context.Background()
+
+	// When: Storing and reading an object
+	if err := backend.Put(ctx, "documents/u1/d1", strings.NewReader("contract")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	r, err := backend.Open(ctx, "documents/u1/d1")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	_ = r.Close()
+
+	// Then: The content round-trips and is gone after Delete
+	if string(data) != "contract" {
+		t.Errorf("expected %q, got %q", "contract", data)
+	}
+	if err := backend.Delete(ctx, "documents/u1/d1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := backend.Open(ctx, "documents/u1/d1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestLocal_RejectsEscapingKeys(t *testing.T) {
+	backend, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+
+	for _, key := range []string{"../etc/passwd", "/abs", "a//b", "a/./b", ""} {
+		if err := backend.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
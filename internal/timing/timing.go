@@ -0,0 +1,108 @@
+package timing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase is the accumulated time spent in one part of request processing
+type Phase struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"-"`
+	Millis   float64       `json:"duration_ms"`
+	Count    int           `json:"count"`
+}
+
+// Recorder collects phase timings for a single request. It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	start  time.Time
+	phases []Phase
+}
+
+type recorderKey struct{}
+
+// NewRecorder creates a recorder for a request that started at start
+func NewRecorder(start time.Time) *Recorder {
+	return &Recorder{start: start}
+}
+
+// WithRecorder attaches r to ctx so lower layers can report timings
+func WithRecorder(ctx context.Context, r *Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// FromContext returns the recorder attached to ctx, or nil when timing is off
+func FromContext(ctx context.Context) *Recorder {
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// Track starts timing phase name and returns the function that stops it.
+// It is a no-op when ctx carries no recorder:
+//
+//	defer timing.Track(ctx, "service")()
+func Track(ctx context.Context, name string) func() {
+	r := FromContext(ctx)
+	if r == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { r.Add(name, time.Since(start)) }
+}
+
+// Add accumulates d into phase name
+func (r *Recorder) Add(name string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.phases {
+		if r.phases[i].Name == name {
+			r.phases[i].Duration += d
+			r.phases[i].Count++
+			return
+		}
+	}
+	r.phases = append(r.phases, Phase{Name: name, Duration: d, Count: 1})
+}
+
+// Total is the time elapsed since the request started
+func (r *Recorder) Total() time.Duration {
+	return time.Since(r.start)
+}
+
+// Phases returns a snapshot of the recorded phases, including the running total
+func (r *Recorder) Phases() []Phase {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := time.Since(r.start)
+	out := make([]Phase, 0, len(r.phases)+1)
+	out = append(out, Phase{Name: "total", Duration: total, Count: 1})
+	out = append(out, r.phases...)
+	for i := range out {
+		out[i].Millis = float64(out[i].Duration.Microseconds()) / 1000
+	}
+	return out
+}
+
+// Header formats the phases using Server-Timing syntax:
+// "total;dur=12.3, middleware;dur=0.4, sql;dur=3.1;desc=\"3 queries\""
+func (r *Recorder) Header() string {
+	phases := r.Phases()
+	parts := make([]string, 0, len(phases))
+	for _, p := range phases {
+		part := fmt.Sprintf("%s;dur=%.3f", p.Name, p.Millis)
+		if p.Count > 1 {
+			part += fmt.Sprintf(";desc=\"%d calls\"", p.Count)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,162 @@
+package tuning
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"cruder/internal/config"
+
+	"go.uber.org/automaxprocs/maxprocs"
+)
+
+// cgroup files holding the container memory limit (v2 first, then v1)
+var memoryLimitFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// Settings describes the effective runtime settings after Apply
+type Settings struct {
+	GOMAXPROCS        int    `json:"gomaxprocs"`
+	GOMAXPROCSSource  string `json:"gomaxprocs_source"`
+	GOGC              int    `json:"gogc"`
+	GOGCSource        string `json:"gogc_source"`
+	MemoryLimit       int64  `json:"memory_limit_bytes"`
+	MemoryLimitSource string `json:"memory_limit_source"`
+	NumCPU            int    `json:"num_cpu"`
+	GoVersion         string `json:"go_version"`
+}
+
+var effective Settings
+
+// Apply configures GOMAXPROCS, GOGC and GOMEMLIMIT from configuration.
+// Values set through the standard GOMAXPROCS/GOGC/GOMEMLIMIT environment
+// variables always take precedence over config.yaml.
+func Apply(cfg config.RuntimeConfig) (Settings, error) {
+	s := Settings{NumCPU: runtime.NumCPU(), GoVersion: runtime.Version()}
+
+	switch {
+	case os.Getenv("GOMAXPROCS") != "":
+		s.GOMAXPROCSSource = "env"
+	case cfg.GOMAXPROCS > 0:
+		runtime.GOMAXPROCS(cfg.GOMAXPROCS)
+		s.GOMAXPROCSSource = "config"
+	default:
+		if _, err := maxprocs.Set(); err != nil {
+			return s, fmt.Errorf("failed to set GOMAXPROCS from CPU quota: %w", err)
+		}
+		s.GOMAXPROCSSource = "cgroup"
+	}
+	s.GOMAXPROCS = runtime.GOMAXPROCS(0)
+
+	switch {
+	case os.Getenv("GOGC") != "":
+		s.GOGCSource = "env"
+	case cfg.GOGC != 0:
+		debug.SetGCPercent(cfg.GOGC)
+		s.GOGCSource = "config"
+	default:
+		s.GOGCSource = "default"
+	}
+	s.GOGC = debug.SetGCPercent(-1)
+	debug.SetGCPercent(s.GOGC)
+
+	switch {
+	case os.Getenv("GOMEMLIMIT") != "":
+		s.MemoryLimitSource = "env"
+	case cfg.MemoryLimit != "":
+		limit, err := ParseByteSize(cfg.MemoryLimit)
+		if err != nil {
+			return s, fmt.Errorf("invalid runtime.memory_limit: %w", err)
+		}
+		debug.SetMemoryLimit(limit)
+		s.MemoryLimitSource = "config"
+	default:
+		if limit, ok := containerMemoryLimit(); ok {
+			debug.SetMemoryLimit(int64(float64(limit) * cfg.MemoryLimitRatio))
+			s.MemoryLimitSource = "cgroup"
+		} else {
+			s.MemoryLimitSource = "unlimited"
+		}
+	}
+	s.MemoryLimit = debug.SetMemoryLimit(-1)
+
+	effective = s
+	return s, nil
+}
+
+// Effective returns the settings recorded by the last Apply call
+func Effective() Settings {
+	return effective
+}
+
+// Log writes the effective runtime settings to the standard logger
+func Log(s Settings) {
+	limit := "unlimited"
+	if s.MemoryLimit != math.MaxInt64 {
+		limit = fmt.Sprintf("%dMiB", s.MemoryLimit>>20)
+	}
+	log.Printf("runtime settings: GOMAXPROCS=%d (%s, %d CPUs visible) GOGC=%d (%s) GOMEMLIMIT=%s (%s) %s",
+		s.GOMAXPROCS, s.GOMAXPROCSSource, s.NumCPU,
+		s.GOGC, s.GOGCSource,
+		limit, s.MemoryLimitSource,
+		s.GoVersion)
+}
+
+// ParseByteSize parses sizes like "512MiB", "1GiB", "200MB" or a plain byte count
+func ParseByteSize(value string) (int64, error) {
+	v := strings.TrimSpace(value)
+	units := []struct {
+		suffix string
+		factor int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30},
+		{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000},
+		{"B", 1},
+	}
+
+	factor := int64(1)
+	for _, u := range units {
+		if strings.HasSuffix(v, u.suffix) {
+			factor = u.factor
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid byte size %q", value)
+	}
+	if n > math.MaxInt64/factor {
+		return 0, fmt.Errorf("byte size %q is too large", value)
+	}
+	return n * factor, nil
+}
+
+// containerMemoryLimit reads the cgroup memory limit, if one is set
+func containerMemoryLimit() (int64, bool) {
+	for _, path := range memoryLimitFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		raw := strings.TrimSpace(string(data))
+		if raw == "max" {
+			return 0, false
+		}
+		limit, err := strconv.ParseInt(raw, 10, 64)
+		// cgroup v1 reports a huge value when unlimited
+		if err != nil || limit <= 0 || limit >= 1<<62 {
+			return 0, false
+		}
+		return limit, true
+	}
+	return 0, false
+}
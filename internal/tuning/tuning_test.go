@@ -0,0 +1,40 @@
+package tuning
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{"1024", 1024, false},
+		{"512MiB", 512 << 20, false},
+		{"1GiB", 1 << 30, false},
+		{"200MB", 200 * 1000 * 1000, false},
+		{" 64 KiB ", 64 << 10, false},
+		{"", 0, true},
+		{"-5MiB", 0, true},
+		{"lots", 0, true},
+		{"9999999999GiB", 0, true},
+		{"8589934591GiB", 8589934591 << 30, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseByteSize(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+		})
+	}
+}
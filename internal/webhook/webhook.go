@@ -0,0 +1,131 @@
+// Package webhook delivers user lifecycle events to the URLs registered at
+// /api/v1/webhooks. Events are queued in the database as one delivery per
+// subscribed webhook and sent by a background worker, which retries failed
+// deliveries with exponential backoff.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"cruder/internal/events"
+	"cruder/internal/metrics"
+	"cruder/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Headers sent with every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// SecretPrefix starts every webhook signing secret
+const SecretPrefix = "whsec_"
+
+// enqueueTimeout bounds queueing the deliveries of one event
+const enqueueTimeout = 5 * time.Second
+
+// Events lists the event types webhooks can subscribe to
+var Events = []string{events.UserCreated, events.UserUpdated, events.UserDeleted, events.UserRestored}
+
+var enqueueFailed = promauto.With(metrics.Registry).NewCounter(prometheus.CounterOpts{
+	Name: "webhook_enqueue_failed_total",
+	Help: "User events that could not be queued for webhooks.",
+})
+
+// Store keeps the deliveries of webhooks; repository.WebhookRepository implements it
+type Store interface {
+	Enqueue(ctx context.Context, eventID, eventType string, payload []byte) (int64, error)
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error
+	PurgeDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Payload is the body of a delivery: the event, with an ID that is the same for
+// every webhook it is sent to and across retries, so receivers can drop repeats
+type Payload struct {
+	ID string `json:"id"`
+	events.Event
+}
+
+// Sign returns the X-Webhook-Signature of a body sent at a Unix timestamp:
+// "sha256=" followed by hex(HMAC-SHA256(secret, TIMESTAMP + "." + body))
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSecret generates the secret a webhook's deliveries are signed with
+func NewSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return SecretPrefix + base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// Subscribed reports whether eventType is one webhooks can subscribe to
+func Subscribed(eventType string) bool {
+	for _, e := range Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Enqueue returns a handler that queues every user event for the webhooks
+// subscribed to its type. Once queued, an event survives restarts and is retried
+// until delivered; an event that cannot be queued is logged, counted and lost.
+func Enqueue(store Store) events.Handler {
+	return func(e events.Event) {
+		if !Subscribed(e.Type) {
+			return
+		}
+		if err := enqueue(store, e); err != nil {
+			enqueueFailed.Inc()
+			log.Printf("webhook: failed to queue %s of user %s: %v", e.Type, e.UserUUID, err)
+		}
+	}
+}
+
+func enqueue(store Store, e events.Event) error {
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(Payload{ID: id, Event: e})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), enqueueTimeout)
+	defer cancel()
+	_, err = store.Enqueue(ctx, id, e.Type, payload)
+	return err
+}
+
+func newEventID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/httpclient"
+	"cruder/internal/metrics"
+	"cruder/internal/model"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// maxErrorLength bounds the reason of a failed attempt kept with the delivery
+const maxErrorLength = 500
+
+var deliveryAttempts = promauto.With(metrics.Registry).NewCounterVec(prometheus.CounterOpts{
+	Name: "webhook_delivery_attempts_total",
+	Help: "Webhook delivery attempts, by result (delivered, retrying, failed).",
+}, []string{"result"})
+
+// Worker sends queued deliveries, retrying failed ones with exponential backoff
+type Worker struct {
+	store  Store
+	client *httpclient.Client
+	cfg    config.WebhooksConfig
+	now    func() time.Time
+}
+
+// NewWorker creates a worker that sends deliveries through client. The client's
+// own retries should be off, as the worker retries with its own backoff.
+func NewWorker(store Store, client *httpclient.Client, cfg config.WebhooksConfig) *Worker {
+	return &Worker{store: store, client: client, cfg: cfg, now: time.Now}
+}
+
+// Run sends due deliveries until none are left or ctx is done, then removes the
+// deliveries finished longer than the retention ago. It returns how many
+// deliveries it attempted.
+func (w *Worker) Run(ctx context.Context) (int, error) {
+	attempted := 0
+	// A claim lasts as long as sending the whole batch may take
+	lease := time.Duration(w.cfg.BatchSize) * w.cfg.Timeout
+	for ctx.Err() == nil {
+		deliveries, err := w.store.Claim(ctx, w.cfg.BatchSize, lease)
+		if err != nil {
+			return attempted, err
+		}
+		for _, d := range deliveries {
+			if err := w.attempt(ctx, d); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(deliveries) < w.cfg.BatchSize {
+			break
+		}
+	}
+
+	purged, err := w.store.PurgeDeliveries(ctx, w.now().Add(-w.cfg.Retention).UTC())
+	if purged > 0 {
+		log.Printf("webhook: removed %d finished deliveries", purged)
+	}
+	return attempted, err
+}
+
+// attempt sends one delivery and records the outcome; the error is that of
+// recording it
+func (w *Worker) attempt(ctx context.Context, d model.WebhookDelivery) error {
+	status, err := w.send(ctx, d)
+	if err == nil {
+		deliveryAttempts.WithLabelValues("delivered").Inc()
+		return w.store.MarkDelivered(ctx, d.ID, status)
+	}
+
+	reason := err.Error()
+	if len(reason) > maxErrorLength {
+		reason = reason[:maxErrorLength]
+	}
+	if d.Attempts >= w.cfg.MaxAttempts {
+		deliveryAttempts.WithLabelValues("failed").Inc()
+		log.Printf("webhook: gave up delivery %d of %s to webhook %s after %d attempts: %s",
+			d.ID, d.EventType, d.WebhookID, d.Attempts, reason)
+		return w.store.MarkFailed(ctx, d.ID, status, reason, nil)
+	}
+	deliveryAttempts.WithLabelValues("retrying").Inc()
+	retryAt := w.now().Add(w.backoff(d.Attempts)).UTC()
+	return w.store.MarkFailed(ctx, d.ID, status, reason, &retryAt)
+}
+
+// send posts the payload of a delivery and returns the response status, 0 when
+// there was no response. Any status but 2xx is an error.
+func (w *Worker) send(ctx context.Context, d model.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := w.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, timestamp, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts: BackoffBase
+// doubled for every attempt after the first, at most BackoffMax
+func (w *Worker) backoff(attempts int) time.Duration {
+	wait := w.cfg.BackoffBase
+	for i := 1; i < attempts && wait < w.cfg.BackoffMax; i++ {
+		wait *= 2
+	}
+	return min(wait, w.cfg.BackoffMax)
+}
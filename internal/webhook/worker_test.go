@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"cruder/internal/config"
+	"cruder/internal/events"
+	"cruder/internal/httpclient"
+	"cruder/internal/model"
+)
+
+// memoryStore keeps deliveries in memory, claiming the ones due at now
+type memoryStore struct {
+	mu         sync.Mutex
+	now        time.Time
+	deliveries []*model.WebhookDelivery
+	subscribed map[string]string // event type -> URL
+}
+
+func (s *memoryStore) Enqueue(_ context.Context, eventID, eventType string, payload []byte) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	url, ok := s.subscribed[eventType]
+	if !ok {
+		return 0, nil
+	}
+	next := s.now
+	s.deliveries = append(s.deliveries, &model.WebhookDelivery{
+		ID: int64(len(s.deliveries) + 1), EventID: eventID, EventType: eventType, Payload: payload,
+		Status: model.DeliveryPending, NextAttemptAt: &next, URL: url, Secret: "whsec_test",
+	})
+	return 1, nil
+}
+
+func (s *memoryStore) Claim(_ context.Context, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var claimed []model.WebhookDelivery
+	for _, d := range s.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if d.Status == model.DeliveryPending && !d.NextAttemptAt.After(s.now) {
+			d.Attempts++
+			next := s.now.Add(lease)
+			d.NextAttemptAt = &next
+			claimed = append(claimed, *d)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) MarkDelivered(_ context.Context, id int64, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.Status, d.LastStatus, d.NextAttemptAt = model.DeliveryDelivered, status, nil
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(_ context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.deliveries[id-1]
+	d.LastStatus, d.LastError = status, reason
+	if retryAt == nil {
+		d.Status, d.NextAttemptAt = model.DeliveryFailed, nil
+		return nil
+	}
+	d.NextAttemptAt = retryAt
+	return nil
+}
+
+func (s *memoryStore) PurgeDeliveries(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *memoryStore) delivery(id int64) model.WebhookDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.deliveries[id-1]
+}
+
+func testWorker(store *memoryStore) *Worker {
+	w := NewWorker(store, httpclient.New("webhook-test", config.HTTPClientConfig{Timeout: time.Second, BreakerThreshold: 100, BreakerCooldown: time.Minute}), config.WebhooksConfig{
+		BatchSize:   10,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		BackoffBase: time.Minute,
+		BackoffMax:  90 * time.Second,
+		Retention:   time.Hour,
+	})
+	w.now = func() time.Time { return store.now }
+	return w
+}
+
+func TestWorker_DeliversSignedPayload(t *testing.T) {
+	// Given: A webhook subscribed to user.created and a created user
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	store := &memoryStore{now: time.Now(), subscribed: map[string]string{events.UserCreated: srv.URL}}
+	Enqueue(store)(events.Event{Type: events.UserCreated, UserUUID: "u-1", User: &model.User{Username: "jsmith"}})
+	Enqueue(store)(events.Event{Type: events.UserDeleted, UserUUID: "u-1"})
+
+	// When: The worker runs
+	attempted, err := testWorker(store).Run(context.Background())
+
+	// Then: Only the subscribed event is posted, signed with the webhook's secret
+	if err != nil || attempted != 1 {
+		t.Fatalf("Run = %d, %v; want 1 attempt", attempted, err)
+	}
+	timestamp, _ := strconv.ParseInt(got.Header.Get(TimestampHeader), 10, 64)
+	if want := Sign("whsec_test", timestamp, body); got.Header.Get(SignatureHeader) != want {
+		t.Errorf("signature %q, want %q", got.Header.Get(SignatureHeader), want)
+	}
+	if got.Header.Get(EventHeader) != events.UserCreated || got.Header.Get(DeliveryHeader) != "1" {
+		t.Errorf("unexpected headers %v", got.Header)
+	}
+	if d := store.delivery(1); d.Status != model.DeliveryDelivered || d.LastStatus != http.StatusNoContent {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+}
+
+func TestWorker_RetriesWithBackoffThenGivesUp(t *testing.T) {
+	// Given: A webhook whose endpoint always fails
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	store := &memoryStore{now: time.Now(), subscribed: map[string]string{events.UserUpdated: srv.URL}}
+	worker := testWorker(store)
+	Enqueue(store)(events.Event{Type: events.UserUpdated, UserUUID: "u-1"})
+	start := store.now
+
+	// When: The worker runs after each backoff
+	var retries []time.Duration
+	for range 3 {
+		if _, err := worker.Run(context.Background()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		d := store.delivery(1)
+		if d.NextAttemptAt == nil {
+			break
+		}
+		retries = append(retries, d.NextAttemptAt.Sub(store.now))
+		store.now = *d.NextAttemptAt
+	}
+
+	// Then: The wait doubles up to backoff_max and the third failure gives up
+	if len(retries) != 2 || retries[0] != time.Minute || retries[1] != 90*time.Second {
+		t.Errorf("retries after %v, want [1m0s 1m30s]", retries)
+	}
+	d := store.delivery(1)
+	if d.Status != model.DeliveryFailed || d.Attempts != 3 || d.LastStatus != http.StatusBadGateway {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+	if store.now.Sub(start) != 150*time.Second {
+		t.Errorf("gave up after %v, want 2m30s", store.now.Sub(start))
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Given: A known secret, timestamp and body
+	// When: Signing them
+	got := Sign("secret", 1700000000, []byte(`{"id":"1"}`))
+
+	// Then: The signature is the HMAC-SHA256 of "timestamp.body"
+	const want = "sha256=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54"
+	if got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
@@ -1,5 +1,18 @@
 -- +goose Up
 -- +goose StatementBegin
+-- gen_random_uuid() is built into PostgreSQL 13 and later; older servers get it
+-- from pgcrypto, which needs a role allowed to create extensions
+DO $$
+BEGIN
+    IF to_regproc('gen_random_uuid') IS NULL THEN
+        CREATE EXTENSION IF NOT EXISTS pgcrypto;
+    END IF;
+EXCEPTION
+    WHEN insufficient_privilege THEN
+        RAISE EXCEPTION 'gen_random_uuid() is missing (PostgreSQL before 13) and role % may not create the pgcrypto extension: have a superuser run CREATE EXTENSION pgcrypto in this database, then run the migrations again', current_user;
+    WHEN undefined_file THEN
+        RAISE EXCEPTION 'gen_random_uuid() is missing (PostgreSQL before 13) and the pgcrypto extension is not installed on the server: install the PostgreSQL contrib package, then run the migrations again';
+END $$;
 ALTER TABLE users ADD COLUMN uuid UUID DEFAULT gen_random_uuid() UNIQUE;
 UPDATE users SET uuid = gen_random_uuid() WHERE uuid IS NULL;
 ALTER TABLE users ALTER COLUMN uuid SET NOT NULL;
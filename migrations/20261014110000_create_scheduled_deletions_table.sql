@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scheduled_deletions (
+    user_uuid UUID PRIMARY KEY REFERENCES users (uuid) ON DELETE CASCADE,
+    effective_at TIMESTAMP NOT NULL,
+    requested_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_scheduled_deletions_effective_at ON scheduled_deletions (effective_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS scheduled_deletions;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS saved_views (
+    name VARCHAR(63) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    query JSONB NOT NULL DEFAULT '{}',
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS saved_views;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(63) UNIQUE NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    field VARCHAR(100) NOT NULL DEFAULT '',
+    expression TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    disabled BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_rules;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pending_changes (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    user_uuid UUID NOT NULL,
+    payload JSONB,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    requested_by VARCHAR(255) NOT NULL,
+    reviewed_by VARCHAR(255),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP
+);
+
+-- At most one change of each kind may wait for review per user
+CREATE UNIQUE INDEX IF NOT EXISTS pending_changes_one_pending_idx
+    ON pending_changes (user_uuid, kind) WHERE status = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pending_changes;
+-- +goose StatementEnd
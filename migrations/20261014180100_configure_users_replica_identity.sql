@@ -0,0 +1,10 @@
+-- Change data capture (e.g. Debezium) needs the old row values in update and delete
+-- events. Run goose with CDC_REPLICA_IDENTITY=FULL to log them; the default keeps
+-- PostgreSQL's DEFAULT (primary key only). Run "goose redo" after changing it.
+
+-- +goose Up
+-- +goose ENVSUB ON
+ALTER TABLE users REPLICA IDENTITY ${CDC_REPLICA_IDENTITY:-DEFAULT};
+
+-- +goose Down
+ALTER TABLE users REPLICA IDENTITY DEFAULT;
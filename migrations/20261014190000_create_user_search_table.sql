@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION
+    WHEN insufficient_privilege THEN
+        RAISE EXCEPTION 'role % may not create the pg_trgm extension: have a superuser run CREATE EXTENSION pg_trgm in this database, then run the migrations again', current_user;
+    WHEN undefined_file THEN
+        RAISE EXCEPTION 'the pg_trgm extension is not installed on the server: install the PostgreSQL contrib package, then run the migrations again';
+END $$;
+
+-- Read model behind /api/v1/users/search, maintained by the service from user
+-- events and rebuilt periodically; never written by requests directly
+CREATE TABLE IF NOT EXISTS user_search (
+    user_uuid UUID PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    username VARCHAR(50) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    full_name VARCHAR(100) NOT NULL DEFAULT '',
+    -- Lower-cased, accent-free values with single spaces, computed by the service
+    username_norm TEXT NOT NULL,
+    email_norm TEXT NOT NULL,
+    full_name_norm TEXT NOT NULL,
+    search_text TEXT NOT NULL,
+    refreshed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_search_text ON user_search USING GIN (search_text gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_search;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Highest primary WAL position each read model has applied; reads carrying a
+-- newer consistency token bypass the read model
+CREATE TABLE IF NOT EXISTS read_model_positions (
+    name VARCHAR(63) PRIMARY KEY,
+    position PG_LSN NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS read_model_positions;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- One row per running server process, refreshed by its heartbeat
+CREATE TABLE IF NOT EXISTS instances (
+    id TEXT PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    version VARCHAR(255) NOT NULL,
+    commit_hash VARCHAR(64) NOT NULL,
+    jobs TEXT[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_instances_last_seen_at ON instances (last_seen_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS instances;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- CSV exports of all users; last_user_id and chunks checkpoint generation so an
+-- interrupted export resumes where it stopped
+CREATE TABLE IF NOT EXISTS user_exports (
+    id UUID PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    requested_by VARCHAR(100) NOT NULL,
+    last_user_id BIGINT NOT NULL DEFAULT 0,
+    chunks INTEGER NOT NULL DEFAULT 0,
+    rows_written BIGINT NOT NULL DEFAULT 0,
+    size_bytes BIGINT NOT NULL DEFAULT 0,
+    sha256 CHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_user_exports_status ON user_exports (status, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_exports;
+-- +goose StatementEnd
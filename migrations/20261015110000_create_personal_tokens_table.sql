@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Access tokens users create for automation. Only a SHA-256 hash of each token is
+-- kept; prefix is its first characters, so users can tell their tokens apart.
+CREATE TABLE IF NOT EXISTS personal_tokens (
+    id UUID PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    tenant VARCHAR(100) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_personal_tokens_user_id ON personal_tokens (user_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS personal_tokens;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Acceptance of a version of a legal document, such as the terms of service, by a
+-- user; ip_address is the client address the acceptance was recorded from
+CREATE TABLE IF NOT EXISTS user_consents (
+    id BIGSERIAL PRIMARY KEY,
+    user_uuid UUID NOT NULL REFERENCES users (uuid) ON DELETE CASCADE,
+    document VARCHAR(50) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    accepted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_uuid, document, version)
+);
+CREATE INDEX IF NOT EXISTS idx_user_consents_document ON user_consents (document, version);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_consents;
+-- +goose StatementEnd
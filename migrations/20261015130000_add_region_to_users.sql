@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Region whose database keeps the user's data; empty for users created before data
+-- residency was enabled, who live in the home region's database
+ALTER TABLE users ADD COLUMN IF NOT EXISTS region VARCHAR(32) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS region;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- RS256 keys that sign JWTs, replaced on a schedule. private_key is encrypted with
+-- the master key; activates_at is when a key takes over signing from the one before.
+CREATE TABLE IF NOT EXISTS signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    public_key TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    activates_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS signing_keys;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Progress of the data migrations in internal/datamigrations, written with each
+-- batch so an interrupted run resumes after the last committed one
+CREATE TABLE IF NOT EXISTS data_migrations (
+    name VARCHAR(100) PRIMARY KEY,
+    -- Key of the last row looked at; batches continue after it
+    cursor BIGINT NOT NULL DEFAULT 0,
+    rows_seen BIGINT NOT NULL DEFAULT 0,
+    rows_changed BIGINT NOT NULL DEFAULT 0,
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS data_migrations;
+-- +goose StatementEnd
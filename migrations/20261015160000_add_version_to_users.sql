@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Counts the changes of a user, so clients can send the version they edited in
+-- If-Match and be refused when someone changed the user meanwhile
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+-- Bumps the version on every change, including writes that bypass the service
+CREATE OR REPLACE FUNCTION bump_version() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.version = OLD.version + 1;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_bump_version BEFORE UPDATE ON users
+    FOR EACH ROW EXECUTE FUNCTION bump_version();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS users_bump_version ON users;
+DROP FUNCTION IF EXISTS bump_version();
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
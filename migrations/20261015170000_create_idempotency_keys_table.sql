@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Responses of requests sent with an Idempotency-Key, replayed to retries of the
+-- same request. key is scoped to the caller; status is NULL while the first
+-- request is still running.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(400) PRIMARY KEY,
+    fingerprint CHAR(64) NOT NULL,
+    status INTEGER,
+    headers JSONB NOT NULL DEFAULT '{}',
+    body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys (created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Prefix lookups behind /api/v1/users/autocomplete; text_pattern_ops lets LIKE
+-- 'prefix%' use the index whatever the database collation
+CREATE INDEX IF NOT EXISTS idx_user_search_username_prefix ON user_search (username_norm text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_user_search_full_name_prefix ON user_search (full_name_norm text_pattern_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_user_search_full_name_prefix;
+DROP INDEX IF EXISTS idx_user_search_username_prefix;
+-- +goose StatementEnd
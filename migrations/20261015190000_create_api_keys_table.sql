@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- API keys created through the admin API. Only a SHA-256 hash of each key is kept;
+-- prefix is its first characters, so admins can tell keys apart.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    tenant VARCHAR(100) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    -- rotated_from is the key this one replaced
+    rotated_from UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    created_by VARCHAR(100) NOT NULL DEFAULT '',
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS api_keys;
+-- +goose StatementEnd
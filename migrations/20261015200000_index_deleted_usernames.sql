@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Creating a user looks for a deleted account with the same username to offer its
+-- restore; the live-user unique index does not cover deleted rows
+CREATE INDEX IF NOT EXISTS idx_users_username_deleted ON users (username, deleted_at DESC) WHERE deleted_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_username_deleted;
+-- +goose StatementEnd
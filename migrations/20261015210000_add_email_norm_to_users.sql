@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- email keeps the address as entered; email_norm is what uniqueness and lookups
+-- use, normalized by the service under users.email_normalization. Existing emails
+-- are lower-cased; a live user whose result clashes with an older live user's is
+-- left without email_norm, to be resolved by hand. The backfill changes nothing
+-- clients see, so it neither bumps versions nor updated_at.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_norm VARCHAR(100);
+ALTER TABLE users DISABLE TRIGGER users_set_updated_at;
+ALTER TABLE users DISABLE TRIGGER users_bump_version;
+UPDATE users u SET email_norm = n.norm
+FROM (
+    SELECT id, lower(trim(email)) AS norm, deleted_at,
+           row_number() OVER (PARTITION BY lower(trim(email)), deleted_at IS NULL ORDER BY id) AS rank
+    FROM users
+) n
+WHERE u.id = n.id AND (n.deleted_at IS NOT NULL OR n.rank = 1);
+ALTER TABLE users ENABLE TRIGGER users_bump_version;
+ALTER TABLE users ENABLE TRIGGER users_set_updated_at;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_norm_live ON users (email_norm) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_users_email_norm_live;
+ALTER TABLE users DROP COLUMN IF EXISTS email_norm;
+-- +goose StatementEnd
@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Changes to users waiting to be published to the message bus. A row is written
+-- by the users_outbox trigger in the transaction of the change, so a committed
+-- change is never lost, and removed once the relay has published it.
+CREATE TABLE IF NOT EXISTS user_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    event_type VARCHAR(50) NOT NULL,
+    user_uuid UUID NOT NULL,
+    region VARCHAR(32) NOT NULL DEFAULT '',
+    -- user is the row as the API returns it, NULL for deletions
+    "user" JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE OR REPLACE FUNCTION users_outbox() RETURNS TRIGGER AS $$
+DECLARE
+    change TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        change := 'user.created';
+    ELSIF TG_OP = 'DELETE' THEN
+        -- Purging a user from the recycle bin removes what was already deleted
+        IF OLD.deleted_at IS NOT NULL THEN
+            RETURN NULL;
+        END IF;
+        INSERT INTO user_outbox (event_type, user_uuid, region) VALUES ('user.deleted', OLD.uuid, OLD.region);
+        RETURN NULL;
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        change := 'user.deleted';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        change := 'user.restored';
+    ELSIF NEW.deleted_at IS NULL THEN
+        change := 'user.updated';
+    ELSE
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO user_outbox (event_type, user_uuid, region, "user")
+    VALUES (change, NEW.uuid, NEW.region, CASE WHEN change = 'user.deleted' THEN NULL ELSE jsonb_build_object(
+        'id', NEW.id,
+        'uuid', NEW.uuid,
+        'username', NEW.username,
+        'email', NEW.email,
+        'full_name', COALESCE(NEW.full_name, ''),
+        'custom_fields', NEW.custom_fields,
+        'created_at', to_char(NEW.created_at, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'updated_at', to_char(COALESCE(NEW.updated_at, NEW.created_at), 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'),
+        'version', NEW.version
+    ) || CASE WHEN NEW.region <> '' THEN jsonb_build_object('region', NEW.region) ELSE '{}' END END);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Only changes a client can see are published; columns kept for the service's own
+-- use, such as email_norm, do not count
+CREATE TRIGGER users_outbox_insert_delete AFTER INSERT OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION users_outbox();
+CREATE TRIGGER users_outbox_update AFTER UPDATE ON users
+    FOR EACH ROW
+    WHEN ((OLD.username, OLD.email, OLD.full_name, OLD.custom_fields, OLD.region, OLD.deleted_at)
+        IS DISTINCT FROM (NEW.username, NEW.email, NEW.full_name, NEW.custom_fields, NEW.region, NEW.deleted_at))
+    EXECUTE FUNCTION users_outbox();
+
+-- Changes are only recorded while the message bus is enabled; the service enables
+-- the triggers at startup
+ALTER TABLE users DISABLE TRIGGER users_outbox_insert_delete;
+ALTER TABLE users DISABLE TRIGGER users_outbox_update;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS users_outbox_update ON users;
+DROP TRIGGER IF EXISTS users_outbox_insert_delete ON users;
+DROP FUNCTION IF EXISTS users_outbox();
+DROP TABLE IF EXISTS user_outbox;
+-- +goose StatementEnd
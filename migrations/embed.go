@@ -0,0 +1,10 @@
+// Package migrations embeds the goose SQL migrations so the binary can apply them
+// without the files on disk; see internal/migrations.
+package migrations
+
+import "embed"
+
+// FS holds every migration of this directory
+//
+//go:embed *.sql
+var FS embed.FS
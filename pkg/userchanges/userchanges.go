@@ -0,0 +1,135 @@
+// Package userchanges carries compact "user changed" notifications from the
+// service to sibling services sharing its PostgreSQL database, over LISTEN/NOTIFY,
+// so they can drop the users they cache without an event bus. A notification
+// names the user and what happened to it; subscribers read the user themselves.
+//
+//	sub, err := userchanges.Subscribe(dsn, func(c userchanges.Change) {
+//		if c.Type == userchanges.Resync {
+//			cache.Clear()
+//			return
+//		}
+//		cache.Delete(c.UUID)
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer sub.Close()
+package userchanges
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the PostgreSQL notification channel the changes are sent on
+const Channel = "cruder_user_changed"
+
+// Change types
+const (
+	Created  = "created"
+	Updated  = "updated"
+	Deleted  = "deleted"
+	Restored = "restored"
+	// Resync is delivered after the subscriber's connection was re-established:
+	// changes may have been missed meanwhile, so every cached user is suspect
+	Resync = "resync"
+)
+
+// Change is one notification. It is sent once the change has been committed.
+type Change struct {
+	Type string `json:"type"`
+	// UUID is the user's UUID; empty for Resync
+	UUID string `json:"uuid,omitempty"`
+	// Region is the data residency region of the user, "" for the home region
+	Region string    `json:"region,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Execer runs a statement; *sql.DB, *sql.Conn and *sql.Tx are Execers
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Notify sends c to every subscriber on db's database. Sent in a transaction, it
+// is delivered when the transaction commits, and not at all if it rolls back.
+func Notify(ctx context.Context, db Execer, c Change) error {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, Channel, string(payload))
+	return err
+}
+
+// Subscriber receives changes until it is closed
+type Subscriber struct {
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// Subscribe calls handle with every change sent on the database dsn names, over a
+// connection of its own. handle runs on one goroutine, in the order the changes
+// were committed, and should return quickly. The connection is re-established
+// when lost, and handle then gets a Resync. Connection poolers in transaction
+// mode do not pass LISTEN through; dsn must reach PostgreSQL or a session-mode
+// pool.
+func Subscribe(dsn string, handle func(Change)) (*Subscriber, error) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("user changes listener: %v", err)
+		}
+	})
+	if err := listener.Listen(Channel); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	s := &Subscriber{listener: listener, done: make(chan struct{})}
+	go s.receive(handle)
+	return s, nil
+}
+
+// Close stops the subscription and waits for handle to return
+func (s *Subscriber) Close() error {
+	err := s.listener.Close()
+	<-s.done
+	return err
+}
+
+func (s *Subscriber) receive(handle func(Change)) {
+	defer close(s.done)
+	for {
+		select {
+		case n, ok := <-s.listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				handle(Change{Type: Resync, At: time.Now().UTC()})
+				continue
+			}
+			change, ok := decode(n.Extra)
+			if !ok {
+				log.Printf("user changes listener: ignoring malformed notification %q", n.Extra)
+				continue
+			}
+			handle(change)
+		case <-time.After(90 * time.Second):
+			// Detects a silently dropped connection so it gets re-established
+			go func() { _ = s.listener.Ping() }()
+		}
+	}
+}
+
+// decode reads a notification's payload; ok is false for one that is not a change
+func decode(payload string) (Change, bool) {
+	var c Change
+	if err := json.Unmarshal([]byte(payload), &c); err != nil || c.Type == "" {
+		return Change{}, false
+	}
+	return c, true
+}
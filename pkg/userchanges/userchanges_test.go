@@ -0,0 +1,78 @@
+package userchanges
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func TestDecode(t *testing.T) {
+	at := time.Date(2026, 10, 15, 8, 0, 0, 0, time.UTC)
+	payload, err := json.Marshal(Change{Type: Updated, UUID: "a", Region: "eu", At: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		payload string
+		ok      bool
+	}{
+		{"change", string(payload), true},
+		{"not JSON", "rules", false},
+		{"no type", `{"uuid":"a"}`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			change, ok := decode(tc.payload)
+			if ok != tc.ok {
+				t.Fatalf("expected ok %t, got %t", tc.ok, ok)
+			}
+			if ok && (change != Change{Type: Updated, UUID: "a", Region: "eu", At: at}) {
+				t.Errorf("unexpected change %+v", change)
+			}
+		})
+	}
+}
+
+// TestSubscribe needs a database; set TEST_DATABASE_URL to run it
+func TestSubscribe(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("failed to open the database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Given: A subscriber
+	received := make(chan Change, 1)
+	sub, err := Subscribe(url, func(c Change) { received <- c })
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer func() { _ = sub.Close() }()
+
+	// When: A change is sent
+	sent := Change{Type: Deleted, UUID: "0f8fad5b-d9cb-469f-a165-70867728950e", At: time.Now().UTC().Truncate(time.Second)}
+	if err := Notify(context.Background(), db, sent); err != nil {
+		t.Fatalf("failed to notify: %v", err)
+	}
+
+	// Then: The subscriber receives it
+	select {
+	case got := <-received:
+		if !got.At.Equal(sent.At) || got.Type != sent.Type || got.UUID != sent.UUID {
+			t.Errorf("expected %+v, got %+v", sent, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change received")
+	}
+}